@@ -12,11 +12,11 @@ import * as common from '@grafana/schema';
 
 export const pluginVersion = "11.5.0-pre";
 
-export type BucketAggregation = (DateHistogram | Histogram | Terms | Filters | GeoHashGrid | Nested);
+export type BucketAggregation = (DateHistogram | Histogram | Terms | MultiTerms | Composite | Filters | GeoHashGrid | Nested);
 
 export type MetricAggregation = (Count | PipelineMetricAggregation | MetricAggregationWithSettings);
 
-export type BucketAggregationType = ('terms' | 'filters' | 'geohash_grid' | 'date_histogram' | 'histogram' | 'nested');
+export type BucketAggregationType = ('terms' | 'multi_terms' | 'composite' | 'filters' | 'geohash_grid' | 'date_histogram' | 'histogram' | 'nested');
 
 export interface BaseBucketAggregation {
   id: string;
@@ -86,6 +86,55 @@ export interface TermsSettings {
   size?: string;
 }
 
+export interface MultiTerms extends BaseBucketAggregation {
+  settings?: {
+    fields?: Array<string>;
+    order?: TermsOrder;
+    size?: string;
+    min_doc_count?: string;
+    orderBy?: string;
+  };
+  type: 'multi_terms';
+}
+
+export interface MultiTermsSettings {
+  fields?: Array<string>;
+  min_doc_count?: string;
+  order?: TermsOrder;
+  orderBy?: string;
+  size?: string;
+}
+
+export const defaultMultiTermsSettings: Partial<MultiTermsSettings> = {
+  fields: [],
+};
+
+export interface Composite extends BaseBucketAggregation {
+  settings?: {
+    sources?: Array<CompositeSource>;
+    size?: string;
+  };
+  type: 'composite';
+}
+
+export interface CompositeSettings {
+  size?: string;
+  sources?: Array<CompositeSource>;
+}
+
+export const defaultCompositeSettings: Partial<CompositeSettings> = {
+  sources: [],
+};
+
+export type CompositeSourceType = ('terms' | 'histogram' | 'date_histogram');
+
+export interface CompositeSource {
+  field: string;
+  interval?: string;
+  missing_bucket?: boolean;
+  type?: CompositeSourceType;
+}
+
 export interface Filters extends BaseBucketAggregation {
   settings?: {
     filters?: Array<Filter>;
@@ -1,8 +1,10 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -22,7 +24,15 @@ import (
 	"github.com/grafana/grafana/pkg/web"
 )
 
-const defaultAnnotationsLimit = 100
+const (
+	defaultAnnotationsLimit = 100
+	maxAnnotationsExport    = 10000
+	// maxAnnotationsImportSize is the maximum size of an import, enough for an export of maxAnnotationsExport
+	// annotations of a few kilobytes each.
+	maxAnnotationsImportSize = 64 * 1024 * 1024
+	// annotationsTruncatedHeader is set to true on exports that don't include every matching annotation.
+	annotationsTruncatedHeader = "X-Annotations-Truncated"
+)
 
 // swagger:route GET /annotations annotations getAnnotations
 //
@@ -35,6 +45,11 @@ const defaultAnnotationsLimit = 100
 // 401: unauthorisedError
 // 500: internalServerError
 func (hs *HTTPServer) GetAnnotations(c *contextmodel.ReqContext) response.Response {
+	timeMatch, err := annotations.ParseTimeMatch(c.Query("timeMatch"))
+	if err != nil {
+		return response.Err(err)
+	}
+
 	query := &annotations.ItemQuery{
 		From:         c.QueryInt64("from"),
 		To:           c.QueryInt64("to"),
@@ -48,6 +63,7 @@ func (hs *HTTPServer) GetAnnotations(c *contextmodel.ReqContext) response.Respon
 		Tags:         c.QueryStrings("tags"),
 		Type:         c.Query("type"),
 		MatchAny:     c.QueryBool("matchAny"),
+		TimeMatch:    timeMatch,
 		SignedInUser: c.SignedInUser,
 	}
 	if query.Limit == 0 {
@@ -452,6 +468,215 @@ func (hs *HTTPServer) MassDeleteAnnotations(c *contextmodel.ReqContext) response
 	return response.Success("Annotations deleted")
 }
 
+// swagger:route GET /annotations/export annotations exportAnnotations
+//
+// Export annotations.
+//
+// Exports the user created annotations matching the query as NDJSON, one annotation per line. Dashboards are referenced by UID, so that the annotations can be imported into another Grafana instance. The X-Annotations-Truncated header is set to true when more annotations match the query than the limit.
+//
+// Produces:
+// - application/x-ndjson
+//
+// Responses:
+// 200: exportAnnotationsResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 500: internalServerError
+func (hs *HTTPServer) ExportAnnotations(c *contextmodel.ReqContext) response.Response {
+	timeMatch, err := annotations.ParseTimeMatch(c.Query("timeMatch"))
+	if err != nil {
+		return response.Err(err)
+	}
+
+	query := &annotations.ItemQuery{
+		From:         c.QueryInt64("from"),
+		To:           c.QueryInt64("to"),
+		OrgID:        c.SignedInUser.GetOrgID(),
+		DashboardUID: c.Query("dashboardUID"),
+		PanelID:      c.QueryInt64("panelId"),
+		Limit:        c.QueryInt64WithDefault("limit", maxAnnotationsExport),
+		Tags:         c.QueryStrings("tags"),
+		Type:         "annotation",
+		MatchAny:     c.QueryBool("matchAny"),
+		TimeMatch:    timeMatch,
+		SignedInUser: c.SignedInUser,
+	}
+	if query.Limit <= 0 {
+		return response.Error(http.StatusBadRequest, "limit must be greater than 0", nil)
+	}
+	if query.Limit > maxAnnotationsExport {
+		query.Limit = maxAnnotationsExport
+	}
+
+	if query.DashboardUID != "" {
+		dq := dashboards.GetDashboardQuery{UID: query.DashboardUID, OrgID: c.SignedInUser.GetOrgID()}
+		dqResult, err := hs.DashboardService.GetDashboard(c.Req.Context(), &dq)
+		if err != nil {
+			return response.Error(http.StatusBadRequest, "Invalid dashboard UID in annotation request", err)
+		}
+		query.DashboardID = dqResult.ID
+	}
+
+	// Fetch one more annotation than the limit to know whether the export is truncated.
+	limit := query.Limit
+	query.Limit++
+	items, err := hs.annotationsRepo.Find(c.Req.Context(), query)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get annotations", err)
+	}
+	truncated := int64(len(items)) > limit
+	if truncated {
+		items = items[:limit]
+	}
+
+	exported := make([]annotations.ExportedItem, 0, len(items))
+	dashboardUIDs := make(map[int64]string)
+	for _, item := range items {
+		dashboardUID := ""
+		if item.DashboardID != 0 {
+			uid, ok := dashboardUIDs[item.DashboardID]
+			if !ok {
+				query := dashboards.GetDashboardQuery{ID: item.DashboardID, OrgID: c.SignedInUser.GetOrgID()}
+				queryResult, err := hs.DashboardService.GetDashboard(c.Req.Context(), &query)
+				if err != nil && !errors.Is(err, dashboards.ErrDashboardNotFound) {
+					return response.Error(http.StatusInternalServerError, "Failed to get dashboard", err)
+				}
+				if queryResult != nil {
+					uid = queryResult.UID
+				}
+				dashboardUIDs[item.DashboardID] = uid
+			}
+			if uid == "" {
+				// The dashboard was deleted, the annotation can't be referenced anymore.
+				continue
+			}
+			dashboardUID = uid
+		}
+
+		exported = append(exported, annotations.ExportedItem{
+			DashboardUID: dashboardUID,
+			PanelID:      item.PanelID,
+			Time:         item.Time,
+			TimeEnd:      item.TimeEnd,
+			Text:         item.Text,
+			Tags:         item.Tags,
+			Data:         item.Data,
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := annotations.WriteExport(&buf, exported); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to export annotations", err)
+	}
+
+	return response.Respond(http.StatusOK, buf.Bytes()).
+		SetHeader("Content-Type", "application/x-ndjson").
+		SetHeader("Content-Disposition", `attachment; filename="annotations.ndjson"`).
+		SetHeader(annotationsTruncatedHeader, strconv.FormatBool(truncated))
+}
+
+// swagger:route POST /annotations/import annotations importAnnotations
+//
+// Import annotations.
+//
+// Imports annotations exported as NDJSON, up to the size of an export. Dashboard UIDs can be remapped with remapDashboardUID=<old UID>:<new UID>, for dashboards that have another UID in this Grafana instance. Either all annotations are imported, or none.
+//
+// Consumes:
+// - application/x-ndjson
+//
+// Responses:
+// 200: importAnnotationsResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) ImportAnnotations(c *contextmodel.ReqContext) response.Response {
+	remap := make(map[string]string)
+	for _, m := range c.QueryStrings("remapDashboardUID") {
+		from, to, ok := strings.Cut(m, ":")
+		if !ok {
+			return response.Error(http.StatusBadRequest, "remapDashboardUID must be <old UID>:<new UID>", nil)
+		}
+		remap[from] = to
+	}
+
+	body := http.MaxBytesReader(c.Resp, c.Req.Body, maxAnnotationsImportSize)
+	items, err := annotations.ReadExport(body, maxAnnotationsExport)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return response.Error(http.StatusRequestEntityTooLarge, fmt.Sprintf("Annotations export is larger than %d bytes", maxBytesErr.Limit), err)
+		}
+		return response.Error(http.StatusBadRequest, "Invalid annotations export", err)
+	}
+
+	userID, _ := identity.UserIdentifier(c.GetID())
+	dashboardIDs := make(map[string]int64)
+	checked := make(map[int64]bool)
+	toSave := make([]annotations.Item, 0, len(items))
+	for _, item := range items {
+		dashboardUID := item.DashboardUID
+		if uid, ok := remap[dashboardUID]; ok {
+			dashboardUID = uid
+		}
+
+		var dashboardID int64
+		if dashboardUID != "" {
+			id, ok := dashboardIDs[dashboardUID]
+			if !ok {
+				query := dashboards.GetDashboardQuery{OrgID: c.SignedInUser.GetOrgID(), UID: dashboardUID}
+				queryResult, err := hs.DashboardService.GetDashboard(c.Req.Context(), &query)
+				if err != nil {
+					if errors.Is(err, dashboards.ErrDashboardNotFound) {
+						return response.Error(http.StatusBadRequest, fmt.Sprintf("Dashboard %s not found", dashboardUID), err)
+					}
+					return response.Error(http.StatusInternalServerError, "Failed to get dashboard", err)
+				}
+				id = queryResult.ID
+				dashboardIDs[dashboardUID] = id
+			}
+			dashboardID = id
+		}
+
+		if !checked[dashboardID] {
+			if canSave, err := hs.canCreateAnnotation(c, dashboardID); err != nil || !canSave {
+				if !hs.Features.IsEnabled(c.Req.Context(), featuremgmt.FlagAnnotationPermissionUpdate) {
+					return dashboardGuardianResponse(err)
+				} else if err != nil {
+					return response.Error(http.StatusInternalServerError, "Error while checking annotation permissions", err)
+				} else {
+					return response.Error(http.StatusForbidden, "Access denied to save the annotations", nil)
+				}
+			}
+			checked[dashboardID] = true
+		}
+
+		toSave = append(toSave, annotations.Item{
+			OrgID:       c.SignedInUser.GetOrgID(),
+			UserID:      userID,
+			DashboardID: dashboardID,
+			PanelID:     item.PanelID,
+			Epoch:       item.Time,
+			EpochEnd:    item.TimeEnd,
+			Text:        item.Text,
+			Tags:        item.Tags,
+			Data:        item.Data,
+		})
+	}
+
+	if err := hs.annotationsRepo.SaveMany(c.Req.Context(), toSave); err != nil {
+		if errors.Is(err, annotations.ErrTimerangeMissing) {
+			return response.Error(http.StatusBadRequest, "Failed to import annotations", err)
+		}
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to import annotations", err)
+	}
+
+	return response.JSON(http.StatusOK, util.DynMap{
+		"message":  "Annotations imported",
+		"imported": len(toSave),
+	})
+}
+
 // swagger:route GET /annotations/{annotation_id} annotations getAnnotationByID
 //
 // Get Annotation by ID.
@@ -782,6 +1007,70 @@ type GetAnnotationsParams struct {
 	// in:query
 	// required:false
 	MatchAny bool `json:"matchAny"`
+	// How to match region annotations against the time range
+	// in:query
+	// required:false
+	// Description:
+	// * `overlap` - annotations overlapping the time range
+	// * `start` - annotations starting in the time range
+	// * `within` - annotations starting and ending in the time range
+	// enum: overlap,start,within
+	// default: overlap
+	TimeMatch string `json:"timeMatch"`
+}
+
+// swagger:parameters exportAnnotations
+type ExportAnnotationsParams struct {
+	// Export annotations after specific epoch datetime in milliseconds.
+	// in:query
+	// required:false
+	From int64 `json:"from"`
+	// Export annotations before specific epoch datetime in milliseconds.
+	// in:query
+	// required:false
+	To int64 `json:"to"`
+	// Export the annotations of a specific dashboard
+	// in:query
+	// required:false
+	DashboardUID string `json:"dashboardUID"`
+	// Export the annotations of a specific panel
+	// in:query
+	// required:false
+	PanelID int64 `json:"panelId"`
+	// Max number of annotations exported.
+	// in:query
+	// required:false
+	// default: 10000
+	Limit int64 `json:"limit"`
+	// Export the annotations with these tags.
+	// in:query
+	// required:false
+	// type: array
+	// collectionFormat: multi
+	Tags []string `json:"tags"`
+	// Match any or all tags
+	// in:query
+	// required:false
+	MatchAny bool `json:"matchAny"`
+	// How to match region annotations against the time range
+	// in:query
+	// required:false
+	// enum: overlap,start,within
+	// default: overlap
+	TimeMatch string `json:"timeMatch"`
+}
+
+// swagger:parameters importAnnotations
+type ImportAnnotationsParams struct {
+	// Maps the dashboard UIDs of the export to the dashboard UIDs of this instance, as <old UID>:<new UID>.
+	// in:query
+	// required:false
+	// type: array
+	// collectionFormat: multi
+	RemapDashboardUID []string `json:"remapDashboardUID"`
+	// in:body
+	// required:true
+	Body []annotations.ExportedItem `json:"body"`
 }
 
 // swagger:parameters getAnnotationTags
@@ -845,6 +1134,28 @@ type GetAnnotationsResponse struct {
 	Body []*annotations.ItemDTO `json:"body"`
 }
 
+// swagger:response exportAnnotationsResponse
+type ExportAnnotationsResponse struct {
+	// Set to true when more annotations match the query than the limit.
+	Truncated bool `json:"X-Annotations-Truncated"`
+	// The annotations, one per line
+	// in: body
+	Body []annotations.ExportedItem `json:"body"`
+}
+
+// swagger:response importAnnotationsResponse
+type ImportAnnotationsResponse struct {
+	// in: body
+	Body struct {
+		// Imported is the number of imported annotations.
+		// required: true
+		Imported int64 `json:"imported"`
+		// Message Message of the imported annotations.
+		// required: true
+		Message string `json:"message"`
+	} `json:"body"`
+}
+
 // swagger:response getAnnotationByIDResponse
 type GetAnnotationByIDResponse struct {
 	// The response message
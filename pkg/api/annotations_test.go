@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
@@ -336,6 +337,73 @@ func TestAPI_Annotations(t *testing.T) {
 			expectedCode: http.StatusForbidden,
 			permissions:  []accesscontrol.Permission{{Action: accesscontrol.ActionAnnotationsCreate, Scope: accesscontrol.ScopeAnnotationsTypeDashboard}},
 		},
+		{
+			desc:         "should be able to export annotations with correct permission",
+			path:         "/api/annotations/export",
+			method:       http.MethodGet,
+			expectedCode: http.StatusOK,
+			permissions:  []accesscontrol.Permission{{Action: accesscontrol.ActionAnnotationsRead, Scope: accesscontrol.ScopeAnnotationsAll}},
+		},
+		{
+			desc:         "should not be able to export annotations without correct permission",
+			path:         "/api/annotations/export",
+			method:       http.MethodGet,
+			expectedCode: http.StatusForbidden,
+			permissions:  []accesscontrol.Permission{},
+		},
+		{
+			desc:         "should not be able to export annotations with an invalid time match",
+			path:         "/api/annotations/export?timeMatch=end",
+			method:       http.MethodGet,
+			expectedCode: http.StatusBadRequest,
+			permissions:  []accesscontrol.Permission{{Action: accesscontrol.ActionAnnotationsRead, Scope: accesscontrol.ScopeAnnotationsAll}},
+		},
+		{
+			desc:         "should not be able to export annotations with a limit that is not positive",
+			path:         "/api/annotations/export?limit=-1",
+			method:       http.MethodGet,
+			expectedCode: http.StatusBadRequest,
+			permissions:  []accesscontrol.Permission{{Action: accesscontrol.ActionAnnotationsRead, Scope: accesscontrol.ScopeAnnotationsAll}},
+		},
+		{
+			desc:         "should not be able to export annotations with a limit of 0",
+			path:         "/api/annotations/export?limit=0",
+			method:       http.MethodGet,
+			expectedCode: http.StatusBadRequest,
+			permissions:  []accesscontrol.Permission{{Action: accesscontrol.ActionAnnotationsRead, Scope: accesscontrol.ScopeAnnotationsAll}},
+		},
+		{
+			desc:         "should not be able to import more annotations than can be exported",
+			path:         "/api/annotations/import",
+			body:         strings.Repeat("{\"time\": 1000, \"text\": \"deploy\"}\n", maxAnnotationsExport+1),
+			method:       http.MethodPost,
+			expectedCode: http.StatusBadRequest,
+			permissions:  []accesscontrol.Permission{{Action: accesscontrol.ActionAnnotationsCreate, Scope: accesscontrol.ScopeAnnotationsTypeOrganization}},
+		},
+		{
+			desc:         "should be able to import organization annotations with correct permission",
+			path:         "/api/annotations/import",
+			body:         "{\"time\": 1000, \"text\": \"deploy\"}\n{\"time\": 2000, \"timeEnd\": 3000, \"text\": \"outage\"}\n",
+			method:       http.MethodPost,
+			expectedCode: http.StatusOK,
+			permissions:  []accesscontrol.Permission{{Action: accesscontrol.ActionAnnotationsCreate, Scope: accesscontrol.ScopeAnnotationsTypeOrganization}},
+		},
+		{
+			desc:         "should not be able to import organization annotations without correct permission",
+			path:         "/api/annotations/import",
+			body:         "{\"time\": 1000, \"text\": \"deploy\"}\n",
+			method:       http.MethodPost,
+			expectedCode: http.StatusForbidden,
+			permissions:  []accesscontrol.Permission{{Action: accesscontrol.ActionAnnotationsCreate, Scope: accesscontrol.ScopeAnnotationsTypeDashboard}},
+		},
+		{
+			desc:         "should not be able to import invalid annotations",
+			path:         "/api/annotations/import",
+			body:         "{\"text\": \"deploy\"}\n",
+			method:       http.MethodPost,
+			expectedCode: http.StatusBadRequest,
+			permissions:  []accesscontrol.Permission{{Action: accesscontrol.ActionAnnotationsCreate, Scope: accesscontrol.ScopeAnnotationsTypeOrganization}},
+		},
 		{
 			desc:         "should be able to mass delete dashboard annotations with correct permission",
 			path:         "/api/annotations/mass-delete",
@@ -421,6 +489,51 @@ func TestAPI_Annotations(t *testing.T) {
 	}
 }
 
+// limitedAnnotationsRepo returns up to total organization annotations, honoring the query limit.
+type limitedAnnotationsRepo struct {
+	annotations.Repository
+	total int
+}
+
+func (r *limitedAnnotationsRepo) Find(_ context.Context, query *annotations.ItemQuery) ([]*annotations.ItemDTO, error) {
+	items := make([]*annotations.ItemDTO, 0, r.total)
+	for i := 0; i < r.total && int64(i) < query.Limit; i++ {
+		items = append(items, &annotations.ItemDTO{ID: int64(i + 1), Text: fmt.Sprintf("annotation %d", i+1)})
+	}
+	return items, nil
+}
+
+func TestAPI_ExportAnnotations_Truncated(t *testing.T) {
+	server := SetupAPITestServer(t, func(hs *HTTPServer) {
+		hs.Cfg = setting.NewCfg()
+		hs.annotationsRepo = &limitedAnnotationsRepo{Repository: annotationstest.NewFakeAnnotationsRepo(), total: 3}
+		hs.Features = featuremgmt.WithFeatures()
+		hs.AccessControl = acimpl.ProvideAccessControl(featuremgmt.WithFeatures(), zanzana.NewNoopClient())
+	})
+	permissions := []accesscontrol.Permission{{Action: accesscontrol.ActionAnnotationsRead, Scope: accesscontrol.ScopeAnnotationsAll}}
+
+	for _, tt := range []struct {
+		limit     int
+		exported  int
+		truncated string
+	}{
+		{limit: 2, exported: 2, truncated: "true"},
+		{limit: 3, exported: 3, truncated: "false"},
+	} {
+		t.Run(fmt.Sprintf("limit %d", tt.limit), func(t *testing.T) {
+			req := webtest.RequestWithSignedInUser(server.NewGetRequest(fmt.Sprintf("/api/annotations/export?limit=%d", tt.limit)), authedUserWithPermissions(1, 1, permissions))
+			res, err := server.Send(req)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, tt.truncated, res.Header.Get(annotationsTruncatedHeader))
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.exported, strings.Count(string(body), "\n"))
+			require.NoError(t, res.Body.Close())
+		})
+	}
+}
+
 func TestService_AnnotationTypeScopeResolver(t *testing.T) {
 	rootDashUID := "root-dashboard"
 	folderDashUID := "folder-dashboard"
@@ -1051,13 +1051,76 @@ func (hs *HTTPServer) CalculateDashboardDiff(c *contextmodel.ReqContext) respons
 		return response.Error(http.StatusInternalServerError, "Unable to compute diff", err)
 	}
 
-	if options.DiffType == dashdiffs.DiffDelta {
+	if options.DiffType == dashdiffs.DiffDelta || options.DiffType == dashdiffs.DiffSemantic {
 		return response.Respond(http.StatusOK, result.Delta).SetHeader("Content-Type", "application/json")
 	}
 
 	return response.Respond(http.StatusOK, result.Delta).SetHeader("Content-Type", "text/html")
 }
 
+// swagger:route POST /dashboards/merge dashboards mergeDashboards
+//
+// Perform a three-way merge of two dashboards changed from the same base version.
+//
+// Responses:
+// 200: mergeDashboardsResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) MergeDashboards(c *contextmodel.ReqContext) response.Response {
+	ctx, span := tracer.Start(c.Req.Context(), "api.MergeDashboards")
+	defer span.End()
+	c.Req = c.Req.WithContext(ctx)
+
+	cmd := dtos.MergeDashboardsCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	data := make([]*simplejson.Json, 0, 3)
+	checked := map[int64]bool{}
+	for _, target := range []dtos.CalculateDiffTarget{cmd.Base, cmd.Mine, cmd.Theirs} {
+		if target.DashboardId != 0 && !checked[target.DashboardId] {
+			g, err := guardian.New(c.Req.Context(), target.DashboardId, c.SignedInUser.GetOrgID(), c.SignedInUser)
+			if err != nil {
+				return response.Err(err)
+			}
+			if canSave, err := g.CanSave(); err != nil || !canSave {
+				return dashboardGuardianResponse(err)
+			}
+			checked[target.DashboardId] = true
+		}
+
+		if target.UnsavedDashboard != nil {
+			data = append(data, target.UnsavedDashboard)
+			continue
+		}
+		if target.DashboardId == 0 {
+			return response.Error(http.StatusBadRequest, "Either a dashboard ID and version or an unsaved dashboard is required", nil)
+		}
+		res, err := hs.dashboardVersionService.Get(c.Req.Context(), &dashver.GetDashboardVersionQuery{
+			DashboardID: target.DashboardId,
+			Version:     target.Version,
+			OrgID:       c.SignedInUser.GetOrgID(),
+		})
+		if err != nil {
+			if errors.Is(err, dashver.ErrDashboardVersionNotFound) {
+				return response.Error(http.StatusNotFound, "Dashboard version not found", err)
+			}
+			return response.Error(http.StatusInternalServerError, "Unable to merge dashboards", err)
+		}
+		data = append(data, res.Data)
+	}
+
+	result, err := dashdiffs.MergeDashboards(data[0], data[1], data[2])
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Unable to merge dashboards", err)
+	}
+	return response.JSON(http.StatusOK, result)
+}
+
 // swagger:route POST /dashboards/id/{DashboardID}/restore dashboard_versions restoreDashboardVersionByID
 //
 // Restore a dashboard to a given dashboard version.
@@ -1296,11 +1359,19 @@ type CalcDashboardDiffParams struct {
 		// Description:
 		// * `basic`
 		// * `json`
-		// Enum: basic,json
+		// * `semantic`
+		// Enum: basic,json,semantic
 		DiffType string `json:"diffType" binding:"Required"`
 	}
 }
 
+// swagger:parameters mergeDashboards
+type MergeDashboardsParams struct {
+	// in:body
+	// required:true
+	Body dtos.MergeDashboardsCommand
+}
+
 // swagger:response dashboardResponse
 type DashboardResponse struct {
 	// The response message
@@ -1376,6 +1447,12 @@ type CalculateDashboardDiffResponse struct {
 	Body []byte `json:"body"`
 }
 
+// swagger:response mergeDashboardsResponse
+type MergeDashboardsResponse struct {
+	// in: body
+	Body dashdiffs.MergeResult `json:"body"`
+}
+
 // swagger:response getHomeDashboardResponse
 type GetHomeDashboardResponse struct {
 	// in: body
@@ -58,6 +58,12 @@ type CalculateDiffTarget struct {
 	UnsavedDashboard *simplejson.Json `json:"unsavedDashboard"`
 }
 
+type MergeDashboardsCommand struct {
+	Base   CalculateDiffTarget `json:"base" binding:"Required"`
+	Mine   CalculateDiffTarget `json:"mine" binding:"Required"`
+	Theirs CalculateDiffTarget `json:"theirs" binding:"Required"`
+}
+
 type RestoreDashboardVersionCommand struct {
 	Version int `json:"version" binding:"Required"`
 }
@@ -924,6 +924,16 @@ func (hs *HTTPServer) configureTLS() error {
 		CipherSuites: tlsCiphers,
 	}
 
+	if hs.Cfg.MTLSAuth.Enabled && hs.Cfg.MTLSAuth.ClientCACert != "" {
+		clientCAs, err := hs.Cfg.MTLSAuth.ClientCAs()
+		if err != nil {
+			return err
+		}
+		// Client certificates are optional, requests without one use the other authentication methods.
+		tlsCfg.ClientCAs = clientCAs
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
 	hs.httpSrv.TLSConfig = tlsCfg
 
 	if hs.Cfg.Protocol == setting.HTTP2Scheme {
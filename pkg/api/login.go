@@ -228,7 +228,8 @@ func (hs *HTTPServer) LoginAPIPing(c *contextmodel.ReqContext) response.Response
 }
 
 func (hs *HTTPServer) LoginPost(c *contextmodel.ReqContext) response.Response {
-	identity, err := hs.authnService.Login(c.Req.Context(), authn.ClientForm, &authn.Request{HTTPRequest: c.Req})
+	req := &authn.Request{HTTPRequest: c.Req}
+	identity, err := hs.authnService.Login(c.Req.Context(), authn.ClientForm, req)
 	if err != nil {
 		tokenErr := &auth.CreateTokenErr{}
 		if errors.As(err, &tokenErr) {
@@ -238,6 +239,11 @@ func (hs *HTTPServer) LoginPost(c *contextmodel.ReqContext) response.Response {
 	}
 
 	metrics.MApiLoginPost.Inc()
+	// A second factor enrolled by the login returns its recovery codes, they can't be retrieved again.
+	if codes := req.GetMeta(authn.MetaKeyTOTPRecoveryCodes); codes != "" {
+		return authn.HandleLoginResponseWithResult(c.Req, c.Resp, hs.Cfg, identity, hs.ValidateRedirectTo, hs.Features,
+			map[string]any{"recoveryCodes": strings.Fields(codes)})
+	}
 	return authn.HandleLoginResponse(c.Req, c.Resp, hs.Cfg, identity, hs.ValidateRedirectTo, hs.Features)
 }
 
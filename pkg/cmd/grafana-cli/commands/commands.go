@@ -144,6 +144,11 @@ var adminCommands = []*cli.Command{
 				Usage:  "Migrates passwords from unsecured fields to secure_json_data field. Return ok unless there is an error. Safe to execute multiple times.",
 				Action: runDbCommand(datamigrations.EncryptDatasourcePasswords),
 			},
+			{
+				Name:   "kvstore-to-blob",
+				Usage:  "Moves the values of the key/value store from the database to the blob bucket configured in the [kvstore] section. Returns ok unless there is an error. Safe to execute multiple times.",
+				Action: runDbCommand(datamigrations.MigrateKVStoreToBlob),
+			},
 		},
 	},
 	{
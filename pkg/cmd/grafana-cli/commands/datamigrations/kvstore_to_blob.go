@@ -0,0 +1,41 @@
+package datamigrations
+
+import (
+	"context"
+	"errors"
+
+	"github.com/fatih/color"
+
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/logger"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// MigrateKVStoreToBlob moves the values of the kv_store table to the blob bucket configured
+// in the [kvstore] section.
+func MigrateKVStoreToBlob(c utils.CommandLine, cfg *setting.Cfg, sqlStore db.DB) error {
+	if cfg.KVStore.BlobURL == "" {
+		return errors.New("blob_url must be set in the [kvstore] section")
+	}
+
+	migrated, err := kvstore.MigrateToBlob(context.Background(), sqlStore, cfg.KVStore)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("\n")
+	if migrated > 0 {
+		logger.Infof("%s Migrated %d kvstore values to the blob bucket\n", color.GreenString("✔"), migrated)
+	} else {
+		logger.Infof("%s All kvstore values are already stored in the blob bucket\n", color.GreenString("✔"))
+	}
+	logger.Info("\n")
+
+	if cfg.KVStore.Type != setting.KVStoreTypeBlob {
+		logger.Warn("Warning: set type = blob in the [kvstore] section, values migrated to the blob bucket can't be " +
+			"read from the database")
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package dashdiffs
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+// MergeConflict is a value changed differently by both sides of a merge. Absent values are nil.
+type MergeConflict struct {
+	Path   string `json:"path"`
+	Base   any    `json:"base"`
+	Mine   any    `json:"mine"`
+	Theirs any    `json:"theirs"`
+}
+
+// MergeResult is the result of a three-way merge of dashboards.
+type MergeResult struct {
+	// Dashboard is the merged dashboard. Conflicting values are taken from mine.
+	Dashboard *simplejson.Json `json:"dashboard"`
+	Conflicts []MergeConflict  `json:"conflicts"`
+}
+
+// absent stands for a key missing from an object, to tell it apart from a null value.
+type absent struct{}
+
+// MergeDashboards merges the changes made to a dashboard in mine and theirs since base, the version both
+// started from. Panels, queries and variables are matched by ID, ref ID and name, so that changes to
+// different panels never conflict. When both sides add a different panel with the same ID, both are kept
+// and the panel of mine gets a new ID. The merged dashboard has the version of theirs, so it can be saved
+// over it.
+func MergeDashboards(baseData, mineData, theirsData *simplejson.Json) (*MergeResult, error) {
+	base, err := normalize(baseData)
+	if err != nil {
+		return nil, err
+	}
+	mine, err := normalize(mineData)
+	if err != nil {
+		return nil, err
+	}
+	theirs, err := normalize(theirsData)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &merger{maxPanelID: max(maxPanelID(base), maxPanelID(mine), maxPanelID(theirs))}
+	merged := m.mergeObjects("", base, mine, theirs)
+	for k := range dashboardMetadata {
+		if v, ok := theirs[k]; ok {
+			merged[k] = v
+		}
+	}
+	if m.conflicts == nil {
+		m.conflicts = []MergeConflict{}
+	}
+	return &MergeResult{
+		Dashboard: simplejson.NewFromAny(merged),
+		Conflicts: m.conflicts,
+	}, nil
+}
+
+type merger struct {
+	conflicts []MergeConflict
+	// maxPanelID is the highest panel ID used by any side, from which the panels given a new ID are numbered.
+	maxPanelID int64
+}
+
+func (m *merger) mergeValues(path string, base, mine, theirs any) any {
+	switch {
+	case reflect.DeepEqual(mine, theirs):
+		return mine
+	case reflect.DeepEqual(base, mine):
+		return theirs
+	case reflect.DeepEqual(base, theirs):
+		return mine
+	}
+
+	mineMap, mineIsMap := mine.(map[string]any)
+	theirsMap, theirsIsMap := theirs.(map[string]any)
+	if mineIsMap && theirsIsMap {
+		baseMap, _ := base.(map[string]any)
+		return m.mergeObjects(path, baseMap, mineMap, theirsMap)
+	}
+
+	mineList, mineIsList := mine.([]any)
+	theirsList, theirsIsList := theirs.([]any)
+	if keyFn := listKeyFunc(path); keyFn != nil && mineIsList && theirsIsList {
+		baseList, _ := base.([]any)
+		return m.mergeLists(path, keyFn, baseList, mineList, theirsList)
+	}
+
+	m.conflict(path, base, mine, theirs)
+	return mine
+}
+
+func (m *merger) mergeObjects(path string, base, mine, theirs map[string]any) map[string]any {
+	merged := make(map[string]any, len(theirs))
+	keys := unionKeys(unionKeysMap(base, mine), theirs)
+	for _, k := range keys {
+		v := m.mergeValues(joinPath(path, k), lookup(base, k), lookup(mine, k), lookup(theirs, k))
+		if _, ok := v.(absent); !ok {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// mergeLists merges lists of panels, queries or variables, matching their items by key. Items keep the
+// order of theirs, and the items added by mine are appended.
+func (m *merger) mergeLists(path string, keyFn func(item any, index int) string, base, mine, theirs []any) []any {
+	baseByKey, _ := indexList(keyFn, base)
+	mineByKey, mineOrder := indexList(keyFn, mine)
+	theirsByKey, theirsOrder := indexList(keyFn, theirs)
+
+	merged := make([]any, 0, len(theirs))
+	for _, k := range theirsOrder {
+		itemPath := fmt.Sprintf("%s[%s]", path, k)
+		b, inBase := baseByKey[k]
+		t := theirsByKey[k]
+		if mi, inMine := mineByKey[k]; inMine {
+			if !inBase && !reflect.DeepEqual(mi, t) {
+				merged = append(merged, m.addedByBoth(path, itemPath, mi, t)...)
+				continue
+			}
+			if !inBase {
+				b = absent{}
+			}
+			merged = append(merged, m.mergeValues(itemPath, b, mi, t))
+			continue
+		}
+		if !inBase {
+			// Added by theirs.
+			merged = append(merged, t)
+			continue
+		}
+		// Deleted by mine.
+		if !reflect.DeepEqual(b, t) {
+			// Theirs changed it, keep it.
+			m.conflict(itemPath, b, absent{}, t)
+			merged = append(merged, t)
+		}
+	}
+	for _, k := range mineOrder {
+		if _, inTheirs := theirsByKey[k]; inTheirs {
+			continue
+		}
+		itemPath := fmt.Sprintf("%s[%s]", path, k)
+		mi := mineByKey[k]
+		b, inBase := baseByKey[k]
+		if !inBase {
+			// Added by mine.
+			merged = append(merged, mi)
+			continue
+		}
+		// Deleted by theirs.
+		if !reflect.DeepEqual(b, mi) {
+			// Mine changed it, keep it.
+			m.conflict(itemPath, b, mi, absent{})
+			merged = append(merged, mi)
+		}
+	}
+	return merged
+}
+
+// addedByBoth returns the items to keep when mine and theirs both added a different item with the same key.
+// Panels with an ID are both kept, and the panel of mine gets a new ID. Other items conflict as a whole, and
+// mine is kept.
+func (m *merger) addedByBoth(path, itemPath string, mine, theirs any) []any {
+	if panel, ok := mine.(map[string]any); ok && isPanelList(path) {
+		if _, hasID := panel["id"].(float64); hasID {
+			renumbered := maps.Clone(panel)
+			m.maxPanelID++
+			renumbered["id"] = float64(m.maxPanelID)
+			return []any{theirs, renumbered}
+		}
+	}
+	m.conflict(itemPath, absent{}, mine, theirs)
+	return []any{mine}
+}
+
+func (m *merger) conflict(path string, base, mine, theirs any) {
+	m.conflicts = append(m.conflicts, MergeConflict{
+		Path:   path,
+		Base:   present(base),
+		Mine:   present(mine),
+		Theirs: present(theirs),
+	})
+}
+
+// listKeyFunc returns how the items of the list at path are matched, or nil if the list is merged as a whole value.
+func listKeyFunc(path string) func(item any, index int) string {
+	switch {
+	case isPanelList(path):
+		return func(item any, index int) string {
+			panel, _ := item.(map[string]any)
+			return panelKey(panel, index)
+		}
+	case strings.HasSuffix(path, ".targets"):
+		return fieldKey("refId")
+	case path == "templating.list":
+		return fieldKey("name")
+	}
+	return nil
+}
+
+func isPanelList(path string) bool {
+	return path == "panels" || strings.HasSuffix(path, ".panels")
+}
+
+// maxPanelID returns the highest ID of the panels of a dashboard, including the panels of collapsed rows.
+func maxPanelID(obj map[string]any) int64 {
+	var maxID int64
+	panels, _ := obj["panels"].([]any)
+	for _, p := range panels {
+		panel, ok := p.(map[string]any)
+		if !ok {
+			continue
+		}
+		if id, ok := panel["id"].(float64); ok {
+			maxID = max(maxID, int64(id))
+		}
+		maxID = max(maxID, maxPanelID(panel))
+	}
+	return maxID
+}
+
+func fieldKey(field string) func(item any, index int) string {
+	return func(item any, index int) string {
+		obj, _ := item.(map[string]any)
+		if v, ok := obj[field].(string); ok && v != "" {
+			return v
+		}
+		return "#" + strconv.Itoa(index)
+	}
+}
+
+func indexList(keyFn func(item any, index int) string, list []any) (map[string]any, []string) {
+	byKey := make(map[string]any, len(list))
+	order := make([]string, 0, len(list))
+	for i, item := range list {
+		k := keyFn(item, i)
+		if _, ok := byKey[k]; ok {
+			// Keep duplicated keys apart.
+			k = k + "#" + strconv.Itoa(i)
+		}
+		byKey[k] = item
+		order = append(order, k)
+	}
+	return byKey, order
+}
+
+func lookup(m map[string]any, key string) any {
+	if v, ok := m[key]; ok {
+		return v
+	}
+	return absent{}
+}
+
+func present(v any) any {
+	if _, ok := v.(absent); ok {
+		return nil
+	}
+	return v
+}
+
+func unionKeysMap(a, b map[string]any) map[string]any {
+	keys := make(map[string]any, len(a)+len(b))
+	for k := range a {
+		keys[k] = nil
+	}
+	for k := range b {
+		keys[k] = nil
+	}
+	return keys
+}
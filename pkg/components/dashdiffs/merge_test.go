@@ -0,0 +1,146 @@
+package dashdiffs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mergeBaseJSON = `{
+	"id": 1,
+	"uid": "abc",
+	"version": 3,
+	"title": "Dashboard",
+	"panels": [
+		{"id": 1, "title": "CPU", "targets": [{"refId": "A", "expr": "cpu"}]},
+		{"id": 2, "title": "Memory"},
+		{"id": 3, "title": "Disk"}
+	],
+	"templating": {"list": [{"name": "env", "query": "dev,prod"}]}
+}`
+
+func TestMergeDashboards(t *testing.T) {
+	t.Run("changes to different panels are merged", func(t *testing.T) {
+		mine := mustJSON(t, `{
+			"id": 1, "uid": "abc", "version": 3, "title": "Dashboard",
+			"panels": [
+				{"id": 1, "title": "CPU usage", "targets": [{"refId": "A", "expr": "cpu"}, {"refId": "B", "expr": "load"}]},
+				{"id": 2, "title": "Memory"},
+				{"id": 3, "title": "Disk"},
+				{"id": 4, "title": "Network"}
+			],
+			"templating": {"list": [{"name": "env", "query": "dev,prod"}]}
+		}`)
+		theirs := mustJSON(t, `{
+			"id": 1, "uid": "abc", "version": 4, "title": "Dashboard",
+			"panels": [
+				{"id": 1, "title": "CPU", "targets": [{"refId": "A", "expr": "cpu_usage"}]},
+				{"id": 2, "title": "Memory", "description": "RSS"}
+			],
+			"templating": {"list": [{"name": "env", "query": "dev,prod,staging"}]}
+		}`)
+
+		result, err := MergeDashboards(mustJSON(t, mergeBaseJSON), mine, theirs)
+		require.NoError(t, err)
+		assert.Empty(t, result.Conflicts)
+
+		merged, err := result.Dashboard.Encode()
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"id": 1, "uid": "abc", "version": 4, "title": "Dashboard",
+			"panels": [
+				{"id": 1, "title": "CPU usage", "targets": [{"refId": "A", "expr": "cpu_usage"}, {"refId": "B", "expr": "load"}]},
+				{"id": 2, "title": "Memory", "description": "RSS"},
+				{"id": 4, "title": "Network"}
+			],
+			"templating": {"list": [{"name": "env", "query": "dev,prod,staging"}]}
+		}`, string(merged))
+	})
+
+	t.Run("conflicting changes keep mine and are reported", func(t *testing.T) {
+		mine := mustJSON(t, `{
+			"id": 1, "uid": "abc", "version": 3, "title": "Mine",
+			"panels": [
+				{"id": 1, "title": "CPU", "targets": [{"refId": "A", "expr": "cpu"}]},
+				{"id": 3, "title": "Disk usage"}
+			],
+			"templating": {"list": [{"name": "env", "query": "dev,prod"}]}
+		}`)
+		theirs := mustJSON(t, `{
+			"id": 1, "uid": "abc", "version": 4, "title": "Theirs",
+			"panels": [
+				{"id": 1, "title": "CPU", "targets": [{"refId": "A", "expr": "cpu"}]},
+				{"id": 2, "title": "Memory", "unit": "bytes"}
+			],
+			"templating": {"list": [{"name": "env", "query": "dev,prod"}]}
+		}`)
+
+		result, err := MergeDashboards(mustJSON(t, mergeBaseJSON), mine, theirs)
+		require.NoError(t, err)
+		assert.Equal(t, "Mine", result.Dashboard.Get("title").MustString())
+		assert.Equal(t, 4, result.Dashboard.Get("version").MustInt())
+
+		conflicts := map[string]MergeConflict{}
+		for _, c := range result.Conflicts {
+			conflicts[c.Path] = c
+		}
+		require.Len(t, conflicts, 3)
+		assert.Equal(t, MergeConflict{Path: "title", Base: "Dashboard", Mine: "Mine", Theirs: "Theirs"}, conflicts["title"])
+		// Deleted by mine, modified by theirs.
+		assert.Nil(t, conflicts["panels[panel-2]"].Mine)
+		assert.NotNil(t, conflicts["panels[panel-2]"].Theirs)
+		// Modified by mine, deleted by theirs.
+		assert.NotNil(t, conflicts["panels[panel-3]"].Mine)
+		assert.Nil(t, conflicts["panels[panel-3]"].Theirs)
+
+		// The modified panels are kept.
+		assert.Len(t, result.Dashboard.Get("panels").MustArray(), 3)
+	})
+
+	t.Run("different items added by both sides with the same key", func(t *testing.T) {
+		mine := mustJSON(t, `{
+			"id": 1, "uid": "abc", "version": 3, "title": "Dashboard",
+			"panels": [
+				{"id": 1, "title": "CPU", "targets": [{"refId": "A", "expr": "cpu"}]},
+				{"id": 2, "title": "Memory"},
+				{"id": 3, "title": "Disk"},
+				{"id": 4, "title": "Network"}
+			],
+			"templating": {"list": [{"name": "env", "query": "dev,prod"}, {"name": "region", "query": "eu"}]}
+		}`)
+		theirs := mustJSON(t, `{
+			"id": 1, "uid": "abc", "version": 4, "title": "Dashboard",
+			"panels": [
+				{"id": 1, "title": "CPU", "targets": [{"refId": "A", "expr": "cpu"}]},
+				{"id": 2, "title": "Memory"},
+				{"id": 3, "title": "Disk"},
+				{"id": 4, "title": "Latency"}
+			],
+			"templating": {"list": [{"name": "env", "query": "dev,prod"}, {"name": "region", "query": "us"}]}
+		}`)
+
+		result, err := MergeDashboards(mustJSON(t, mergeBaseJSON), mine, theirs)
+		require.NoError(t, err)
+
+		// Both panels are kept, and the panel of mine gets a new ID.
+		merged, err := result.Dashboard.Get("panels").Encode()
+		require.NoError(t, err)
+		assert.JSONEq(t, `[
+			{"id": 1, "title": "CPU", "targets": [{"refId": "A", "expr": "cpu"}]},
+			{"id": 2, "title": "Memory"},
+			{"id": 3, "title": "Disk"},
+			{"id": 4, "title": "Latency"},
+			{"id": 5, "title": "Network"}
+		]`, string(merged))
+
+		// Other items conflict as a whole.
+		require.Len(t, result.Conflicts, 1)
+		assert.Equal(t, MergeConflict{
+			Path:   "templating.list[region]",
+			Mine:   map[string]any{"name": "region", "query": "eu"},
+			Theirs: map[string]any{"name": "region", "query": "us"},
+		}, result.Conflicts[0])
+		assert.Equal(t, "eu", result.Dashboard.GetPath("templating", "list").GetIndex(1).Get("query").MustString())
+	})
+}
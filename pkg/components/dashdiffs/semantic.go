@@ -0,0 +1,385 @@
+package dashdiffs
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+// Kinds of changes reported by the semantic diff.
+const (
+	SemanticChangeAdded    = "added"
+	SemanticChangeRemoved  = "removed"
+	SemanticChangeModified = "modified"
+)
+
+// dashboardMetadata are the dashboard properties that change on every save and are not reported.
+var dashboardMetadata = map[string]bool{
+	"id":      true,
+	"version": true,
+}
+
+// SemanticDiff is a structured diff of two dashboards, in which panels, queries and variables
+// are matched by their identity rather than by their position in the dashboard JSON.
+type SemanticDiff struct {
+	// Dashboard are the changes to the dashboard properties other than panels and variables.
+	Dashboard []ValueChange    `json:"dashboard,omitempty"`
+	Panels    []PanelChange    `json:"panels,omitempty"`
+	Variables []VariableChange `json:"variables,omitempty"`
+}
+
+// ValueChange is a change to the value at a path, such as fieldConfig.defaults.unit.
+type ValueChange struct {
+	Path   string `json:"path"`
+	Change string `json:"change"`
+	Old    any    `json:"old,omitempty"`
+	New    any    `json:"new,omitempty"`
+}
+
+// PanelChange describes how a panel changed. Panels are matched by ID, panels of collapsed rows included.
+type PanelChange struct {
+	Key    string `json:"key"`
+	ID     int64  `json:"id,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Change string `json:"change"`
+	// Moved is set if the position of the panel changed, or if it was moved to another row.
+	Moved       bool           `json:"moved,omitempty"`
+	OldGridPos  map[string]any `json:"oldGridPos,omitempty"`
+	NewGridPos  map[string]any `json:"newGridPos,omitempty"`
+	Targets     []TargetChange `json:"targets,omitempty"`
+	FieldConfig []ValueChange  `json:"fieldConfig,omitempty"`
+	Options     []ValueChange  `json:"options,omitempty"`
+	// Properties are the changes to the other properties of the panel.
+	Properties []ValueChange `json:"properties,omitempty"`
+}
+
+// TargetChange describes how a query of a panel changed. Queries are matched by ref ID.
+type TargetChange struct {
+	RefID   string        `json:"refId"`
+	Change  string        `json:"change"`
+	Changes []ValueChange `json:"changes,omitempty"`
+}
+
+// VariableChange describes how a template variable changed. Variables are matched by name.
+type VariableChange struct {
+	Name    string        `json:"name"`
+	Change  string        `json:"change"`
+	Changes []ValueChange `json:"changes,omitempty"`
+}
+
+// Empty returns true if the dashboards are semantically identical.
+func (d *SemanticDiff) Empty() bool {
+	return len(d.Dashboard) == 0 && len(d.Panels) == 0 && len(d.Variables) == 0
+}
+
+// CalculateSemanticDiff computes the semantic diff of two dashboards.
+func CalculateSemanticDiff(baseData, newData *simplejson.Json) (*SemanticDiff, error) {
+	base, err := normalize(baseData)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := normalize(newData)
+	if err != nil {
+		return nil, err
+	}
+
+	oldVars, oldOrder := variables(base)
+	newVars, newOrder := variables(updated)
+	result := &SemanticDiff{
+		Panels:    diffPanels(flattenPanels(base), flattenPanels(updated)),
+		Variables: diffVariables(oldVars, oldOrder, newVars, newOrder),
+	}
+
+	keys := unionKeys(base, updated)
+	for _, k := range keys {
+		if k == "panels" || k == "templating" || dashboardMetadata[k] {
+			continue
+		}
+		result.Dashboard = append(result.Dashboard, diffValues(k, base[k], updated[k])...)
+	}
+	// Changes to templating other than its list of variables.
+	baseTemplating, _ := base["templating"].(map[string]any)
+	newTemplating, _ := updated["templating"].(map[string]any)
+	for _, k := range unionKeys(baseTemplating, newTemplating) {
+		if k == "list" {
+			continue
+		}
+		result.Dashboard = append(result.Dashboard, diffValues("templating."+k, baseTemplating[k], newTemplating[k])...)
+	}
+	return result, nil
+}
+
+// normalize decodes a dashboard into plain maps, slices and float64 numbers, so that values
+// decoded from different sources can be compared.
+func normalize(data *simplejson.Json) (map[string]any, error) {
+	if data == nil {
+		return map[string]any{}, nil
+	}
+	b, err := data.Encode()
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	if m == nil {
+		m = map[string]any{}
+	}
+	return m, nil
+}
+
+// panelEntry is a panel along with the key of the collapsed row it belongs to, if any.
+type panelEntry struct {
+	key    string
+	panel  map[string]any
+	parent string
+}
+
+// panelKey identifies a panel. Panels without an ID, which are rare, are identified by their position.
+func panelKey(panel map[string]any, index int) string {
+	if id, ok := panel["id"].(float64); ok {
+		return "panel-" + strconv.FormatInt(int64(id), 10)
+	}
+	if lib, ok := panel["libraryPanel"].(map[string]any); ok {
+		if uid, ok := lib["uid"].(string); ok && uid != "" {
+			return "library-panel-" + uid
+		}
+	}
+	return "index-" + strconv.Itoa(index)
+}
+
+func flattenPanels(dashboard map[string]any) []panelEntry {
+	var entries []panelEntry
+	panels, _ := dashboard["panels"].([]any)
+	for i, p := range panels {
+		panel, ok := p.(map[string]any)
+		if !ok {
+			continue
+		}
+		key := panelKey(panel, i)
+		entries = append(entries, panelEntry{key: key, panel: panel})
+		// Collapsed rows hold their panels.
+		children, _ := panel["panels"].([]any)
+		for j, c := range children {
+			child, ok := c.(map[string]any)
+			if !ok {
+				continue
+			}
+			entries = append(entries, panelEntry{key: panelKey(child, j), panel: child, parent: key})
+		}
+	}
+	return entries
+}
+
+func diffPanels(base, updated []panelEntry) []PanelChange {
+	baseByKey := make(map[string]panelEntry, len(base))
+	for _, e := range base {
+		baseByKey[e.key] = e
+	}
+	newByKey := make(map[string]panelEntry, len(updated))
+	for _, e := range updated {
+		newByKey[e.key] = e
+	}
+
+	var changes []PanelChange
+	for _, e := range base {
+		if _, ok := newByKey[e.key]; !ok {
+			changes = append(changes, newPanelChange(e, SemanticChangeRemoved))
+		}
+	}
+	for _, e := range updated {
+		old, ok := baseByKey[e.key]
+		if !ok {
+			c := newPanelChange(e, SemanticChangeAdded)
+			c.NewGridPos, _ = e.panel["gridPos"].(map[string]any)
+			changes = append(changes, c)
+			continue
+		}
+		if c, changed := diffPanel(old, e); changed {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+func newPanelChange(e panelEntry, change string) PanelChange {
+	c := PanelChange{Key: e.key, Change: change}
+	if id, ok := e.panel["id"].(float64); ok {
+		c.ID = int64(id)
+	}
+	c.Title, _ = e.panel["title"].(string)
+	return c
+}
+
+func diffPanel(old, updated panelEntry) (PanelChange, bool) {
+	c := newPanelChange(updated, SemanticChangeModified)
+
+	oldPos, _ := old.panel["gridPos"].(map[string]any)
+	newPos, _ := updated.panel["gridPos"].(map[string]any)
+	if !reflect.DeepEqual(oldPos, newPos) || old.parent != updated.parent {
+		c.OldGridPos = oldPos
+		c.NewGridPos = newPos
+		c.Moved = old.parent != updated.parent || oldPos["x"] != newPos["x"] || oldPos["y"] != newPos["y"]
+	}
+
+	c.Targets = diffTargets(old.panel["targets"], updated.panel["targets"])
+	c.FieldConfig = diffValues("fieldConfig", old.panel["fieldConfig"], updated.panel["fieldConfig"])
+	c.Options = diffValues("options", old.panel["options"], updated.panel["options"])
+	for _, k := range unionKeys(old.panel, updated.panel) {
+		switch k {
+		case "gridPos", "targets", "fieldConfig", "options", "panels":
+			continue
+		}
+		c.Properties = append(c.Properties, diffValues(k, old.panel[k], updated.panel[k])...)
+	}
+
+	changed := c.OldGridPos != nil || c.NewGridPos != nil || len(c.Targets) > 0 || len(c.FieldConfig) > 0 ||
+		len(c.Options) > 0 || len(c.Properties) > 0
+	return c, changed
+}
+
+// refIDs returns the queries of a panel by ref ID, and the ref IDs in order.
+func refIDs(targets any) (map[string]any, []string) {
+	list, _ := targets.([]any)
+	byRefID := make(map[string]any, len(list))
+	order := make([]string, 0, len(list))
+	for i, t := range list {
+		target, ok := t.(map[string]any)
+		if !ok {
+			continue
+		}
+		refID, _ := target["refId"].(string)
+		if refID == "" {
+			refID = "#" + strconv.Itoa(i)
+		}
+		byRefID[refID] = target
+		order = append(order, refID)
+	}
+	return byRefID, order
+}
+
+func diffTargets(oldTargets, newTargets any) []TargetChange {
+	oldByRefID, oldOrder := refIDs(oldTargets)
+	newByRefID, newOrder := refIDs(newTargets)
+
+	var changes []TargetChange
+	for _, refID := range oldOrder {
+		if _, ok := newByRefID[refID]; !ok {
+			changes = append(changes, TargetChange{RefID: refID, Change: SemanticChangeRemoved})
+		}
+	}
+	for _, refID := range newOrder {
+		old, ok := oldByRefID[refID]
+		if !ok {
+			changes = append(changes, TargetChange{RefID: refID, Change: SemanticChangeAdded})
+			continue
+		}
+		if values := diffValues("", old, newByRefID[refID]); len(values) > 0 {
+			changes = append(changes, TargetChange{RefID: refID, Change: SemanticChangeModified, Changes: values})
+		}
+	}
+	return changes
+}
+
+// variables returns the template variables of a dashboard by name, and the names in order.
+func variables(dashboard map[string]any) (map[string]any, []string) {
+	templating, _ := dashboard["templating"].(map[string]any)
+	list, _ := templating["list"].([]any)
+	byName := make(map[string]any, len(list))
+	order := make([]string, 0, len(list))
+	for i, v := range list {
+		variable, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := variable["name"].(string)
+		if name == "" {
+			name = "#" + strconv.Itoa(i)
+		}
+		byName[name] = variable
+		order = append(order, name)
+	}
+	return byName, order
+}
+
+func diffVariables(oldVars map[string]any, oldOrder []string, newVars map[string]any, newOrder []string) []VariableChange {
+	var changes []VariableChange
+	for _, name := range oldOrder {
+		if _, ok := newVars[name]; !ok {
+			changes = append(changes, VariableChange{Name: name, Change: SemanticChangeRemoved})
+		}
+	}
+	for _, name := range newOrder {
+		old, ok := oldVars[name]
+		if !ok {
+			changes = append(changes, VariableChange{Name: name, Change: SemanticChangeAdded})
+			continue
+		}
+		// The current value and options of a variable change when it is refreshed and are not reported.
+		oldVar, _ := old.(map[string]any)
+		newVar, _ := newVars[name].(map[string]any)
+		var values []ValueChange
+		for _, k := range unionKeys(oldVar, newVar) {
+			if k == "current" || k == "options" {
+				continue
+			}
+			values = append(values, diffValues(k, oldVar[k], newVar[k])...)
+		}
+		if len(values) > 0 {
+			changes = append(changes, VariableChange{Name: name, Change: SemanticChangeModified, Changes: values})
+		}
+	}
+	return changes
+}
+
+// diffValues returns the changes between two JSON values. Objects are compared key by key, other
+// values, arrays included, as a whole.
+func diffValues(path string, old, updated any) []ValueChange {
+	if reflect.DeepEqual(old, updated) {
+		return nil
+	}
+	oldMap, oldIsMap := old.(map[string]any)
+	newMap, newIsMap := updated.(map[string]any)
+	if oldIsMap && newIsMap {
+		var changes []ValueChange
+		for _, k := range unionKeys(oldMap, newMap) {
+			changes = append(changes, diffValues(joinPath(path, k), oldMap[k], newMap[k])...)
+		}
+		return changes
+	}
+	switch {
+	case old == nil:
+		return []ValueChange{{Path: path, Change: SemanticChangeAdded, New: updated}}
+	case updated == nil:
+		return []ValueChange{{Path: path, Change: SemanticChangeRemoved, Old: old}}
+	default:
+		return []ValueChange{{Path: path, Change: SemanticChangeModified, Old: old, New: updated}}
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return fmt.Sprintf("%s.%s", path, key)
+}
+
+// unionKeys returns the keys of both maps, sorted.
+func unionKeys(a, b map[string]any) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
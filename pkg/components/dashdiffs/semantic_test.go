@@ -0,0 +1,116 @@
+package dashdiffs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+func TestCalculateSemanticDiff(t *testing.T) {
+	base := mustJSON(t, `{
+		"id": 1,
+		"version": 3,
+		"title": "Dashboard",
+		"panels": [
+			{"id": 1, "title": "CPU", "type": "timeseries", "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8},
+			 "targets": [{"refId": "A", "expr": "cpu"}, {"refId": "B", "expr": "load"}],
+			 "fieldConfig": {"defaults": {"unit": "percent"}}},
+			{"id": 2, "title": "Memory", "type": "timeseries", "gridPos": {"x": 12, "y": 0, "w": 12, "h": 8}},
+			{"id": 3, "title": "Row", "type": "row", "collapsed": true, "panels": [
+				{"id": 4, "title": "Disk", "type": "stat", "gridPos": {"x": 0, "y": 9, "w": 6, "h": 4}}
+			]}
+		],
+		"templating": {"list": [
+			{"name": "env", "type": "custom", "query": "dev,prod", "current": {"value": "dev"}}
+		]}
+	}`)
+	updated := mustJSON(t, `{
+		"id": 1,
+		"version": 4,
+		"title": "Dashboard",
+		"panels": [
+			{"id": 2, "title": "Memory", "type": "timeseries", "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8}},
+			{"id": 1, "title": "CPU", "type": "timeseries", "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8},
+			 "targets": [{"refId": "B", "expr": "load"}, {"refId": "A", "expr": "cpu_usage"}],
+			 "fieldConfig": {"defaults": {"unit": "short"}}},
+			{"id": 3, "title": "Row", "type": "row", "collapsed": true, "panels": [
+				{"id": 5, "title": "Network", "type": "stat", "gridPos": {"x": 0, "y": 9, "w": 6, "h": 4}}
+			]}
+		],
+		"templating": {"list": [
+			{"name": "env", "type": "custom", "query": "dev,prod", "current": {"value": "prod"}}
+		]}
+	}`)
+
+	diff, err := CalculateSemanticDiff(base, updated)
+	require.NoError(t, err)
+
+	assert.Empty(t, diff.Dashboard)
+	assert.Empty(t, diff.Variables, "the current value of a variable is not a change")
+
+	byKey := map[string]PanelChange{}
+	for _, p := range diff.Panels {
+		byKey[p.Key] = p
+	}
+	require.Len(t, byKey, 4)
+
+	cpu := byKey["panel-1"]
+	assert.Equal(t, SemanticChangeModified, cpu.Change)
+	assert.False(t, cpu.Moved, "reordering the panels is not a move")
+	require.Len(t, cpu.Targets, 1)
+	assert.Equal(t, "A", cpu.Targets[0].RefID)
+	require.Len(t, cpu.FieldConfig, 1)
+	assert.Equal(t, "fieldConfig.defaults.unit", cpu.FieldConfig[0].Path)
+	assert.Equal(t, "percent", cpu.FieldConfig[0].Old)
+	assert.Equal(t, "short", cpu.FieldConfig[0].New)
+
+	memory := byKey["panel-2"]
+	assert.True(t, memory.Moved)
+	assert.Empty(t, memory.Properties)
+
+	assert.Equal(t, SemanticChangeRemoved, byKey["panel-4"].Change)
+	assert.Equal(t, SemanticChangeAdded, byKey["panel-5"].Change)
+	assert.Equal(t, "Network", byKey["panel-5"].Title)
+}
+
+func TestCalculateSemanticDiffVariables(t *testing.T) {
+	base := mustJSON(t, `{"templating": {"list": [{"name": "env", "query": "dev,prod"}, {"name": "host", "query": "a"}]}}`)
+	updated := mustJSON(t, `{"templating": {"list": [{"name": "env", "query": "dev,prod,staging"}, {"name": "region", "query": "eu"}]}}`)
+
+	diff, err := CalculateSemanticDiff(base, updated)
+	require.NoError(t, err)
+	assert.Empty(t, diff.Panels)
+	require.Len(t, diff.Variables, 3)
+
+	changes := map[string]string{}
+	for _, v := range diff.Variables {
+		changes[v.Name] = v.Change
+	}
+	assert.Equal(t, map[string]string{
+		"env":    SemanticChangeModified,
+		"host":   SemanticChangeRemoved,
+		"region": SemanticChangeAdded,
+	}, changes)
+}
+
+func TestCalculateDiffSemantic(t *testing.T) {
+	base := mustJSON(t, `{"title": "A", "version": 1}`)
+
+	_, err := CalculateDiff(context.Background(), &Options{DiffType: DiffSemantic}, base, mustJSON(t, `{"title": "A", "version": 2}`))
+	require.ErrorIs(t, err, ErrNilDiff)
+
+	result, err := CalculateDiff(context.Background(), &Options{DiffType: ParseDiffType("semantic")}, base, mustJSON(t, `{"title": "B", "version": 2}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"dashboard": [{"path": "title", "change": "modified", "old": "A", "new": "B"}]}`, string(result.Delta))
+}
+
+func mustJSON(t *testing.T, data string) *simplejson.Json {
+	t.Helper()
+	j, err := simplejson.NewJson([]byte(data))
+	require.NoError(t, err)
+	return j
+}
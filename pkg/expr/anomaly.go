@@ -0,0 +1,151 @@
+package expr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/expr/mathexp"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+)
+
+const (
+	defaultAnomalySeasons    = 4
+	defaultAnomalyDeviations = 3
+	defaultAnomalyAlpha      = 0.5
+	defaultAnomalyBeta       = 0.1
+	defaultAnomalyGamma      = 0.3
+
+	// AnomalyBandLabel is the label added to the baseline and band series returned by the anomaly command
+	// to tell them apart from the score series, which keeps the labels of the input series.
+	AnomalyBandLabel = "anomaly_band"
+)
+
+// AnomalyCommand compares each series against its seasonal baseline and returns the anomaly score of each point,
+// optionally along with the baseline and the upper and lower bands.
+type AnomalyCommand struct {
+	ReferenceVar string
+	RefID        string
+	Options      mathexp.SeasonalOptions
+	Bands        bool
+}
+
+// NewAnomalyCommand creates a new AnomalyCommand.
+func NewAnomalyCommand(refID, referenceVar string, options mathexp.SeasonalOptions, bands bool) (*AnomalyCommand, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	return &AnomalyCommand{
+		RefID:        refID,
+		ReferenceVar: referenceVar,
+		Options:      options,
+		Bands:        bands,
+	}, nil
+}
+
+// UnmarshalAnomalyCommand creates an AnomalyCommand from Grafana's frontend query.
+func UnmarshalAnomalyCommand(rn *rawNode) (*AnomalyCommand, error) {
+	q := AnomalyQuery{}
+	if err := json.Unmarshal(rn.QueryRaw, &q); err != nil {
+		return nil, fmt.Errorf("failed to parse the anomaly command: %w", err)
+	}
+	return newAnomalyCommandFromQuery(rn.RefID, q)
+}
+
+func newAnomalyCommandFromQuery(refID string, q AnomalyQuery) (*AnomalyCommand, error) {
+	referenceVar, err := getReferenceVar(q.Expression, refID)
+	if err != nil {
+		return nil, err
+	}
+	if q.Season == "" {
+		return nil, fmt.Errorf("no season specified in anomaly command for refId %v", refID)
+	}
+	season, err := gtime.ParseDuration(q.Season)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse season '%v' of anomaly command: %w", q.Season, err)
+	}
+
+	options := mathexp.SeasonalOptions{
+		Algorithm:  q.Algorithm,
+		Season:     season,
+		Seasons:    q.Seasons,
+		Deviations: q.Deviations,
+		Alpha:      q.Alpha,
+		Beta:       q.Beta,
+		Gamma:      q.Gamma,
+	}
+	if options.Algorithm == "" {
+		options.Algorithm = mathexp.SeasonalMedian
+	}
+	if options.Seasons == 0 {
+		options.Seasons = defaultAnomalySeasons
+	}
+	if options.Deviations == 0 {
+		options.Deviations = defaultAnomalyDeviations
+	}
+	if options.Alpha == 0 {
+		options.Alpha = defaultAnomalyAlpha
+	}
+	if options.Beta == 0 {
+		options.Beta = defaultAnomalyBeta
+	}
+	if options.Gamma == 0 {
+		options.Gamma = defaultAnomalyGamma
+	}
+	return NewAnomalyCommand(refID, referenceVar, options, q.Bands)
+}
+
+// NeedsVars returns the variable names (refIds) that are dependencies
+// to execute the command and allows the command to fulfill the Command interface.
+func (ac *AnomalyCommand) NeedsVars() []string {
+	return []string{ac.ReferenceVar}
+}
+
+// Execute runs the command and returns the results or an error if the command
+// failed to execute.
+func (ac *AnomalyCommand) Execute(ctx context.Context, _ time.Time, vars mathexp.Vars, tracer tracing.Tracer) (mathexp.Results, error) {
+	_, span := tracer.Start(ctx, "SSE.ExecuteAnomaly")
+	defer span.End()
+
+	newRes := mathexp.Results{}
+	for _, val := range vars[ac.ReferenceVar].Values {
+		switch v := val.(type) {
+		case mathexp.Series:
+			bands, err := v.SeasonalBands(ac.RefID, ac.Options)
+			if err != nil {
+				return newRes, err
+			}
+			newRes.Values = append(newRes.Values, bands.Score)
+			if ac.Bands {
+				newRes.Values = append(newRes.Values,
+					withBandLabel(bands.Baseline, "baseline"),
+					withBandLabel(bands.Upper, "upper"),
+					withBandLabel(bands.Lower, "lower"),
+				)
+			}
+		case mathexp.NoData:
+			newRes.Values = append(newRes.Values, v.New())
+		default:
+			return newRes, fmt.Errorf("can only detect anomalies in type series, got type %v", val.Type())
+		}
+	}
+	return newRes, nil
+}
+
+func (ac *AnomalyCommand) Type() string {
+	return TypeAnomaly.String()
+}
+
+func withBandLabel(s mathexp.Series, band string) mathexp.Series {
+	labels := data.Labels{}
+	for k, v := range s.GetLabels() {
+		labels[k] = v
+	}
+	labels[AnomalyBandLabel] = band
+	s.SetLabels(labels)
+	return s
+}
@@ -0,0 +1,142 @@
+package expr
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/expr/mathexp"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+func TestUnmarshalAnomalyCommand(t *testing.T) {
+	cases := []struct {
+		description   string
+		query         string
+		expectedError string
+		assert        func(*testing.T, *AnomalyCommand)
+	}{
+		{
+			description: "defaults",
+			query:       `{"expression": "$A", "season": "1w"}`,
+			assert: func(t *testing.T, cmd *AnomalyCommand) {
+				require.Equal(t, "A", cmd.ReferenceVar)
+				require.Equal(t, mathexp.SeasonalOptions{
+					Algorithm:  mathexp.SeasonalMedian,
+					Season:     7 * 24 * time.Hour,
+					Seasons:    defaultAnomalySeasons,
+					Deviations: defaultAnomalyDeviations,
+					Alpha:      defaultAnomalyAlpha,
+					Beta:       defaultAnomalyBeta,
+					Gamma:      defaultAnomalyGamma,
+				}, cmd.Options)
+				require.False(t, cmd.Bands)
+			},
+		},
+		{
+			description: "holt-winters with bands",
+			query:       `{"expression": "B", "season": "1d", "algorithm": "holt_winters", "alpha": 0.2, "deviations": 2, "bands": true}`,
+			assert: func(t *testing.T, cmd *AnomalyCommand) {
+				require.Equal(t, mathexp.SeasonalHoltWinters, cmd.Options.Algorithm)
+				require.Equal(t, 0.2, cmd.Options.Alpha)
+				require.Equal(t, 2.0, cmd.Options.Deviations)
+				require.True(t, cmd.Bands)
+			},
+		},
+		{
+			description:   "missing season",
+			query:         `{"expression": "$A"}`,
+			expectedError: "no season specified",
+		},
+		{
+			description:   "bad season",
+			query:         `{"expression": "$A", "season": "weekly"}`,
+			expectedError: "failed to parse season",
+		},
+		{
+			description:   "unknown algorithm",
+			query:         `{"expression": "$A", "season": "1d", "algorithm": "prophet"}`,
+			expectedError: "not supported",
+		},
+		{
+			description:   "smoothing factor out of range",
+			query:         `{"expression": "$A", "season": "1d", "algorithm": "holt_winters", "gamma": 1.5}`,
+			expectedError: "gamma must be in the range",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			var qmap = make(map[string]any)
+			require.NoError(t, json.Unmarshal([]byte(tc.query), &qmap))
+
+			cmd, err := UnmarshalAnomalyCommand(&rawNode{
+				RefID:    "C",
+				Query:    qmap,
+				QueryRaw: []byte(tc.query),
+			})
+			if tc.expectedError != "" {
+				require.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			tc.assert(t, cmd)
+		})
+	}
+}
+
+func TestAnomalyExecute(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	series := mathexp.NewSeries("A", data.Labels{"host": "a"}, 3)
+	series.SetPoint(0, start, util.Pointer(1.0))
+	series.SetPoint(1, start.Add(time.Hour), util.Pointer(1.0))
+	series.SetPoint(2, start.Add(2*time.Hour), util.Pointer(5.0))
+
+	cmd, err := NewAnomalyCommand("B", "A", mathexp.SeasonalOptions{
+		Algorithm:  mathexp.SeasonalMedian,
+		Season:     time.Hour,
+		Seasons:    1,
+		Deviations: 3,
+	}, true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"A"}, cmd.NeedsVars())
+
+	t.Run("returns the score and the bands", func(t *testing.T) {
+		res, err := cmd.Execute(context.Background(), time.Now(), mathexp.Vars{
+			"A": mathexp.Results{Values: mathexp.Values{series}},
+		}, tracing.InitializeTracerForTest())
+		require.NoError(t, err)
+		require.Len(t, res.Values, 4)
+
+		score := res.Values[0].(mathexp.Series)
+		require.Equal(t, data.Labels{"host": "a"}, score.GetLabels())
+		require.Nil(t, score.GetValue(0))
+		require.Equal(t, 0.0, *score.GetValue(1))
+
+		for i, band := range []string{"baseline", "upper", "lower"} {
+			s := res.Values[i+1].(mathexp.Series)
+			require.Equal(t, data.Labels{"host": "a", AnomalyBandLabel: band}, s.GetLabels())
+			require.Equal(t, 1.0, *s.GetValue(2))
+		}
+	})
+
+	t.Run("passes no data through", func(t *testing.T) {
+		res, err := cmd.Execute(context.Background(), time.Now(), mathexp.Vars{
+			"A": mathexp.Results{Values: mathexp.Values{mathexp.NewNoData()}},
+		}, tracing.InitializeTracerForTest())
+		require.NoError(t, err)
+		require.IsType(t, mathexp.NoData{}, res.Values[0])
+	})
+
+	t.Run("rejects numbers", func(t *testing.T) {
+		_, err := cmd.Execute(context.Background(), time.Now(), mathexp.Vars{
+			"A": mathexp.Results{Values: mathexp.Values{mathexp.NewNumber("A", nil)}},
+		}, tracing.InitializeTracerForTest())
+		require.Error(t, err)
+	})
+}
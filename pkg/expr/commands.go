@@ -82,24 +82,26 @@ func (gm *MathCommand) Type() string {
 
 // ReduceCommand is an expression command for reduction of a timeseries such as a min, mean, or max.
 type ReduceCommand struct {
-	Reducer      mathexp.ReducerID
-	VarToReduce  string
-	refID        string
-	seriesMapper mathexp.ReduceMapper
+	Reducer       mathexp.ReducerID
+	ReducerParams []float64
+	VarToReduce   string
+	refID         string
+	seriesMapper  mathexp.ReduceMapper
 }
 
-// NewReduceCommand creates a new ReduceCMD.
-func NewReduceCommand(refID string, reducer mathexp.ReducerID, varToReduce string, mapper mathexp.ReduceMapper) (*ReduceCommand, error) {
-	_, err := mathexp.GetReduceFunc(reducer)
+// NewReduceCommand creates a new ReduceCMD. Parameters are required by some reducers, such as the percentile.
+func NewReduceCommand(refID string, reducer mathexp.ReducerID, varToReduce string, mapper mathexp.ReduceMapper, params ...float64) (*ReduceCommand, error) {
+	err := mathexp.ValidateReducer(reducer, params)
 	if err != nil {
 		return nil, err
 	}
 
 	return &ReduceCommand{
-		Reducer:      reducer,
-		VarToReduce:  varToReduce,
-		refID:        refID,
-		seriesMapper: mapper,
+		Reducer:       reducer,
+		ReducerParams: params,
+		VarToReduce:   varToReduce,
+		refID:         refID,
+		seriesMapper:  mapper,
 	}, nil
 }
 
@@ -125,6 +127,21 @@ func UnmarshalReduceCommand(rn *rawNode) (*ReduceCommand, error) {
 	}
 	redFunc := mathexp.ReducerID(strings.ToLower(redString))
 
+	var params []float64
+	if rawParams, ok := rn.Query["reducerParams"]; ok && rawParams != nil {
+		list, ok := rawParams.([]any)
+		if !ok {
+			return nil, fmt.Errorf("expected reducerParams to be an array of numbers, got %T", rawParams)
+		}
+		for _, p := range list {
+			f, ok := p.(float64)
+			if !ok {
+				return nil, fmt.Errorf("expected reducerParams to be an array of numbers, got element of type %T", p)
+			}
+			params = append(params, f)
+		}
+	}
+
 	var mapper mathexp.ReduceMapper = nil
 	settings, ok := rn.Query["settings"]
 	if ok {
@@ -154,7 +171,7 @@ func UnmarshalReduceCommand(rn *rawNode) (*ReduceCommand, error) {
 			return nil, fmt.Errorf("field settings must be an object, got %T for refId %v", s, rn.RefID)
 		}
 	}
-	return NewReduceCommand(rn.RefID, redFunc, varToReduce, mapper)
+	return NewReduceCommand(rn.RefID, redFunc, varToReduce, mapper, params...)
 }
 
 // NeedsVars returns the variable names (refIds) that are dependencies
@@ -175,7 +192,7 @@ func (gr *ReduceCommand) Execute(ctx context.Context, _ time.Time, vars mathexp.
 	for i, val := range vars[gr.VarToReduce].Values {
 		switch v := val.(type) {
 		case mathexp.Series:
-			num, err := v.Reduce(gr.refID, gr.Reducer, gr.seriesMapper)
+			num, err := v.Reduce(gr.refID, gr.Reducer, gr.seriesMapper, gr.ReducerParams...)
 			if err != nil {
 				return newRes, err
 			}
@@ -214,24 +231,32 @@ func (gr *ReduceCommand) Type() string {
 type ResampleCommand struct {
 	Window        time.Duration
 	VarToResample string
-	Downsampler   mathexp.ReducerID
+	Downsampler   mathexp.Downsampler
 	Upsampler     mathexp.Upsampler
+	Alignment     mathexp.ResampleAlignment
 	TimeRange     TimeRange
 	refID         string
 }
 
-// NewResampleCommand creates a new ResampleCMD.
-func NewResampleCommand(refID, rawWindow, varToResample string, downsampler mathexp.ReducerID, upsampler mathexp.Upsampler, tr TimeRange) (*ResampleCommand, error) {
-	// TODO: validate reducer here, before execution
+// NewResampleCommand creates a new ResampleCMD. An empty alignment aligns the windows on the query time range.
+func NewResampleCommand(refID, rawWindow, varToResample string, downsampler mathexp.Downsampler, upsampler mathexp.Upsampler, alignment mathexp.ResampleAlignment, tr TimeRange) (*ResampleCommand, error) {
 	window, err := gtime.ParseDuration(rawWindow)
 	if err != nil {
 		return nil, fmt.Errorf(`failed to parse resample "window" duration field %q: %w`, window, err)
 	}
+	switch alignment {
+	case "":
+		alignment = mathexp.ResampleAlignmentRange
+	case mathexp.ResampleAlignmentRange, mathexp.ResampleAlignmentClock:
+	default:
+		return nil, fmt.Errorf("resample alignment '%s' is not supported. Supported only: [%s,%s]", alignment, mathexp.ResampleAlignmentRange, mathexp.ResampleAlignmentClock)
+	}
 	return &ResampleCommand{
 		Window:        window,
 		VarToResample: varToResample,
 		Downsampler:   downsampler,
 		Upsampler:     upsampler,
+		Alignment:     alignment,
 		TimeRange:     tr,
 		refID:         refID,
 	}, nil
@@ -280,10 +305,19 @@ func UnmarshalResampleCommand(rn *rawNode) (*ResampleCommand, error) {
 		return nil, fmt.Errorf("expected resample downsampler to be a string, got type %T", upsampler)
 	}
 
+	alignment := ""
+	if rawAlignment, ok := rn.Query["alignment"]; ok {
+		alignment, ok = rawAlignment.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected resample alignment to be a string, got type %T", rawAlignment)
+		}
+	}
+
 	return NewResampleCommand(rn.RefID, window,
 		varToResample,
-		mathexp.ReducerID(downsampler),
+		mathexp.Downsampler(downsampler),
 		mathexp.Upsampler(upsampler),
+		mathexp.ResampleAlignment(alignment),
 		rn.TimeRange)
 }
 
@@ -300,6 +334,9 @@ func (gr *ResampleCommand) Execute(ctx context.Context, now time.Time, vars math
 	defer span.End()
 	newRes := mathexp.Results{}
 	timeRange := gr.TimeRange.AbsoluteTime(now)
+	if gr.Alignment == mathexp.ResampleAlignmentClock {
+		timeRange.From = mathexp.AlignToClock(timeRange.From, gr.Window)
+	}
 	for _, val := range vars[gr.VarToResample].Values {
 		if val == nil {
 			continue
@@ -343,6 +380,8 @@ const (
 	TypeThreshold
 	// TypeSQL is the CMDType for running SQL expressions
 	TypeSQL
+	// TypeAnomaly is the CMDType for detecting deviations from a seasonal baseline
+	TypeAnomaly
 )
 
 func (gt CommandType) String() string {
@@ -359,6 +398,8 @@ func (gt CommandType) String() string {
 		return "threshold"
 	case TypeSQL:
 		return "sql"
+	case TypeAnomaly:
+		return "anomaly"
 	default:
 		return "unknown"
 	}
@@ -379,6 +420,8 @@ func ParseCommandType(s string) (CommandType, error) {
 		return TypeThreshold, nil
 	case "sql":
 		return TypeSQL, nil
+	case "anomaly":
+		return TypeAnomaly, nil
 	default:
 		return TypeUnknown, fmt.Errorf("'%v' is not a recognized expression type", s)
 	}
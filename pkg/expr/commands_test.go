@@ -99,6 +99,66 @@ func Test_UnmarshalReduceCommand_Settings(t *testing.T) {
 	}
 }
 
+func Test_UnmarshalReduceCommand_Params(t *testing.T) {
+	var tests = []struct {
+		name           string
+		query          string
+		isError        bool
+		expectedParams []float64
+	}{
+		{
+			name:  "reducer without parameters",
+			query: `{ "expression" : "$A", "reducer": "stddev" }`,
+		},
+		{
+			name:           "percentile with a parameter",
+			query:          `{ "expression" : "$A", "reducer": "percentile", "reducerParams": [95] }`,
+			expectedParams: []float64{95},
+		},
+		{
+			name:    "error when percentile has no parameter",
+			query:   `{ "expression" : "$A", "reducer": "percentile" }`,
+			isError: true,
+		},
+		{
+			name:    "error when percentile is out of range",
+			query:   `{ "expression" : "$A", "reducer": "percentile", "reducerParams": [-1] }`,
+			isError: true,
+		},
+		{
+			name:    "error when parameters are not numbers",
+			query:   `{ "expression" : "$A", "reducer": "percentile", "reducerParams": ["95"] }`,
+			isError: true,
+		},
+		{
+			name:    "error when a reducer does not accept parameters",
+			query:   `{ "expression" : "$A", "reducer": "rate", "reducerParams": [1] }`,
+			isError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var qmap = make(map[string]any)
+			require.NoError(t, json.Unmarshal([]byte(test.query), &qmap))
+
+			cmd, err := UnmarshalReduceCommand(&rawNode{
+				RefID:     "A",
+				Query:     qmap,
+				TimeRange: RelativeTimeRange{},
+			})
+
+			if test.isError {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, test.expectedParams, cmd.ReducerParams)
+		})
+	}
+}
+
 func TestReduceExecute(t *testing.T) {
 	varToReduce := util.GenerateShortUID()
 
@@ -212,7 +272,12 @@ func TestReduceExecute(t *testing.T) {
 
 func randomReduceFunc() mathexp.ReducerID {
 	res := mathexp.GetSupportedReduceFuncs()
-	return res[rand.Intn(len(res))]
+	for {
+		r := res[rand.Intn(len(res))]
+		if r != mathexp.ReducerPercentile { // requires a parameter
+			return r
+		}
+	}
 }
 
 func TestResampleCommand_Execute(t *testing.T) {
@@ -221,7 +286,7 @@ func TestResampleCommand_Execute(t *testing.T) {
 		From: -10 * time.Second,
 		To:   0,
 	}
-	cmd, err := NewResampleCommand(util.GenerateShortUID(), "1s", varToReduce, "sum", "pad", tr)
+	cmd, err := NewResampleCommand(util.GenerateShortUID(), "1s", varToReduce, "sum", "pad", "", tr)
 	require.NoError(t, err)
 
 	var tests = []struct {
@@ -273,3 +338,85 @@ func TestResampleCommand_Execute(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestResampleCommand_Alignment(t *testing.T) {
+	varToResample := util.GenerateShortUID()
+	tr := RelativeTimeRange{
+		From: -10 * time.Minute,
+		To:   0,
+	}
+	now := time.Unix(1_700_000_123, 0)
+
+	t.Run("clock alignment starts windows on wall-clock boundaries", func(t *testing.T) {
+		cmd, err := NewResampleCommand(util.GenerateShortUID(), "1m", varToResample, "mean", "linear", mathexp.ResampleAlignmentClock, tr)
+		require.NoError(t, err)
+
+		result, err := cmd.Execute(context.Background(), now, mathexp.Vars{
+			varToResample: mathexp.Results{Values: mathexp.Values{mathexp.NewSeries(varToResample, nil, 0)}},
+		}, tracing.InitializeTracerForTest())
+		require.NoError(t, err)
+		require.Len(t, result.Values, 1)
+		series := result.Values[0].(mathexp.Series)
+		require.Positive(t, series.Len())
+		for i := 0; i < series.Len(); i++ {
+			require.Zero(t, series.GetTime(i).Unix()%60)
+		}
+	})
+
+	t.Run("range alignment is the default", func(t *testing.T) {
+		cmd, err := NewResampleCommand(util.GenerateShortUID(), "1m", varToResample, "mean", "pad", "", tr)
+		require.NoError(t, err)
+		require.Equal(t, mathexp.ResampleAlignmentRange, cmd.Alignment)
+	})
+
+	t.Run("should fail on unknown alignment", func(t *testing.T) {
+		_, err := NewResampleCommand(util.GenerateShortUID(), "1m", varToResample, "mean", "pad", "hourly", tr)
+		require.Error(t, err)
+	})
+}
+
+func TestResampleCommand_UnknownSamplers(t *testing.T) {
+	varToResample := util.GenerateShortUID()
+	tr := RelativeTimeRange{
+		From: -3 * time.Minute,
+		To:   0,
+	}
+	now := time.Unix(1_700_000_040, 0)
+	execute := func(t *testing.T, cmd *ResampleCommand, points ...time.Time) error {
+		t.Helper()
+		series := mathexp.NewSeries(varToResample, nil, len(points))
+		for i, p := range points {
+			series.SetPoint(i, p, util.Pointer(float64(i)))
+		}
+		_, err := cmd.Execute(context.Background(), now, mathexp.Vars{
+			varToResample: mathexp.Results{Values: mathexp.Values{series}},
+		}, tracing.InitializeTracerForTest())
+		return err
+	}
+	// One point in each window, so that neither the downsampler nor the upsampler is used.
+	onePerWindow := []time.Time{now.Add(-3 * time.Minute), now.Add(-2 * time.Minute), now.Add(-time.Minute), now}
+
+	t.Run("unused unknown upsampler and downsampler still evaluate", func(t *testing.T) {
+		cmd, err := NewResampleCommand(util.GenerateShortUID(), "1m", varToResample, "percentile", "cubic", "", tr)
+		require.NoError(t, err)
+
+		require.NoError(t, execute(t, cmd, onePerWindow...))
+	})
+
+	t.Run("unknown upsampler fails when a window needs to be upsampled", func(t *testing.T) {
+		cmd, err := NewResampleCommand(util.GenerateShortUID(), "1m", varToResample, "mean", "cubic", "", tr)
+		require.NoError(t, err)
+
+		require.ErrorContains(t, execute(t, cmd, now), "upsampling cubic not implemented")
+	})
+
+	t.Run("rate and percentile fail when a window needs to be downsampled", func(t *testing.T) {
+		for _, downsampler := range []mathexp.Downsampler{"rate", "percentile"} {
+			cmd, err := NewResampleCommand(util.GenerateShortUID(), "1m", varToResample, downsampler, "pad", "", tr)
+			require.NoError(t, err)
+
+			err = execute(t, cmd, append(onePerWindow, now.Add(-90*time.Second))...)
+			require.ErrorContains(t, err, "downsampling "+string(downsampler)+" not implemented")
+		}
+	})
+}
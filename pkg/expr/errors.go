@@ -93,3 +93,21 @@ func makeUnexpectedNodeTypeError(refID, nodeType string) error {
 
 	return UnexpectedNodeTypeError.Build(data)
 }
+
+var SQLError = errutil.BadRequest("sse.sqlError").MustTemplate(
+	"failed to execute SQL expression [{{ .Public.refId }}]: {{ .Error }}",
+	errutil.WithPublic(
+		"failed to execute SQL expression [{{ .Public.refId }}]: {{ .Public.error }}",
+	))
+
+func MakeSQLError(refID string, err error) error {
+	data := errutil.TemplateData{
+		Public: map[string]any{
+			"refId": refID,
+			"error": err.Error(),
+		},
+		Error: err,
+	}
+
+	return SQLError.Build(data)
+}
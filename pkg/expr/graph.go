@@ -13,6 +13,7 @@ import (
 	"gonum.org/v1/gonum/graph/topo"
 
 	"github.com/grafana/grafana/pkg/expr/mathexp"
+	"github.com/grafana/grafana/pkg/expr/sql"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 )
 
@@ -246,6 +247,7 @@ func buildNodeRegistry(g *simple.DirectedGraph) map[string]Node {
 // buildGraph creates a new graph populated with nodes for every query.
 func (s *Service) buildGraph(req *Request) (*simple.DirectedGraph, error) {
 	dp := simple.NewDirectedGraph()
+	dsNodes := []*DSNode{}
 
 	for i, query := range req.Queries {
 		if query.DataSource == nil || query.DataSource.UID == "" {
@@ -280,6 +282,9 @@ func (s *Service) buildGraph(req *Request) (*simple.DirectedGraph, error) {
 			node, err = s.buildDSNode(dp, rn, req)
 		case TypeCMDNode:
 			node, err = buildCMDNode(rn, s.features)
+			if err == nil {
+				s.applySQLLimits(node.(*CMDNode))
+			}
 		case TypeMLNode:
 			if s.features.IsEnabledGlobally(featuremgmt.FlagMlExpressions) {
 				node, err = s.buildMLNode(dp, rn, req)
@@ -298,6 +303,13 @@ func (s *Service) buildGraph(req *Request) (*simple.DirectedGraph, error) {
 		}
 
 		dp.AddNode(node)
+		if dsNode, ok := node.(*DSNode); ok {
+			dsNodes = append(dsNodes, dsNode)
+		}
+	}
+
+	if err := addGraphiteRefQueries(dsNodes); err != nil {
+		return nil, err
 	}
 	return dp, nil
 }
@@ -371,6 +383,20 @@ func GetCommandsFromPipeline[T Command](pipeline DataPipeline) []T {
 	return results
 }
 
+// applySQLLimits configures SQL expressions with the limits from the server configuration.
+func (s *Service) applySQLLimits(node *CMDNode) {
+	cmd, ok := node.Command.(*SQLCommand)
+	if !ok || s.cfg == nil {
+		return
+	}
+	cmd.SetLimits(sql.Limits{
+		MaxInputRows:   s.cfg.SQLExpressionMaxInputRows,
+		MaxOutputRows:  s.cfg.SQLExpressionMaxOutputRows,
+		MaxMemoryBytes: s.cfg.SQLExpressionMaxMemoryBytes,
+		Timeout:        s.cfg.SQLExpressionTimeout,
+	})
+}
+
 func hasSqlExpression(dp DataPipeline) bool {
 	for _, node := range dp {
 		if node.NodeType() == TypeCMDNode {
@@ -1,7 +1,9 @@
 package mathexp
 
 import (
+	"fmt"
 	"math"
+	"time"
 
 	"github.com/grafana/grafana/pkg/expr/mathexp/parse"
 )
@@ -68,6 +70,31 @@ var builtins = map[string]parse.Func{
 		VariantReturn: true,
 		F:             floor,
 	},
+	"shift": {
+		Args:   []parse.ReturnType{parse.TypeSeriesSet, parse.TypeDuration},
+		Return: parse.TypeSeriesSet,
+		F:      shift,
+	},
+	"moving_avg": {
+		Args:   []parse.ReturnType{parse.TypeSeriesSet, parse.TypeDuration},
+		Return: parse.TypeSeriesSet,
+		F:      movingAvg,
+	},
+	"diff": {
+		Args:   []parse.ReturnType{parse.TypeSeriesSet},
+		Return: parse.TypeSeriesSet,
+		F:      diff,
+	},
+	"cumsum": {
+		Args:   []parse.ReturnType{parse.TypeSeriesSet},
+		Return: parse.TypeSeriesSet,
+		F:      cumsum,
+	},
+	"rate": {
+		Args:   []parse.ReturnType{parse.TypeSeriesSet},
+		Return: parse.TypeSeriesSet,
+		F:      rate,
+	},
 }
 
 // abs returns the absolute value for each result in NumberSet, SeriesSet, or Scalar
@@ -307,3 +334,126 @@ func floor(e *State, varSet Results) (Results, error) {
 	}
 	return newRes, nil
 }
+
+// shift moves each point of each series in SeriesSet forward in time by d.
+func shift(e *State, varSet Results, d time.Duration) (Results, error) {
+	return perSeries(e, varSet, "shift", func(s Series, newSeries Series) {
+		for i := 0; i < s.Len(); i++ {
+			t, f := s.GetPoint(i)
+			newSeries.AppendPoint(t.Add(d), f)
+		}
+	})
+}
+
+// movingAvg returns, for each point of each series in SeriesSet, the mean of the points in the window
+// (t-window, t]. Null and NaN points are ignored, and the value is null if the window has no other points.
+func movingAvg(e *State, varSet Results, window time.Duration) (Results, error) {
+	if window <= 0 {
+		return Results{}, fmt.Errorf("moving_avg window must be greater than zero, got %v", window)
+	}
+	return perSeries(e, varSet, "moving_avg", func(s Series, newSeries Series) {
+		start := 0
+		for i := 0; i < s.Len(); i++ {
+			t, _ := s.GetPoint(i)
+			for {
+				st, _ := s.GetPoint(start)
+				if st.After(t.Add(-window)) {
+					break
+				}
+				start++
+			}
+			sum, count := 0.0, 0
+			for j := start; j <= i; j++ {
+				_, f := s.GetPoint(j)
+				if f == nil || math.IsNaN(*f) {
+					continue
+				}
+				sum += *f
+				count++
+			}
+			if count == 0 {
+				newSeries.AppendPoint(t, nil)
+				continue
+			}
+			avg := sum / float64(count)
+			newSeries.AppendPoint(t, &avg)
+		}
+	})
+}
+
+// diff returns the difference between each point and the previous point of each series in SeriesSet.
+// The first point is dropped, and the value is null if either point is null.
+func diff(e *State, varSet Results) (Results, error) {
+	return perSeries(e, varSet, "diff", func(s Series, newSeries Series) {
+		for i := 1; i < s.Len(); i++ {
+			_, prev := s.GetPoint(i - 1)
+			t, f := s.GetPoint(i)
+			if prev == nil || f == nil {
+				newSeries.AppendPoint(t, nil)
+				continue
+			}
+			d := *f - *prev
+			newSeries.AppendPoint(t, &d)
+		}
+	})
+}
+
+// cumsum returns the running total of each series in SeriesSet. Null points stay null and do not
+// contribute to the total.
+func cumsum(e *State, varSet Results) (Results, error) {
+	return perSeries(e, varSet, "cumsum", func(s Series, newSeries Series) {
+		sum := 0.0
+		for i := 0; i < s.Len(); i++ {
+			t, f := s.GetPoint(i)
+			if f == nil {
+				newSeries.AppendPoint(t, nil)
+				continue
+			}
+			sum += *f
+			total := sum
+			newSeries.AppendPoint(t, &total)
+		}
+	})
+}
+
+// rate returns the per-second rate of change between each point and the previous point of each series
+// in SeriesSet. A decrease is treated as a counter reset, so the new value is used as the increase.
+// The first point is dropped, and the value is null if either point is null.
+func rate(e *State, varSet Results) (Results, error) {
+	return perSeries(e, varSet, "rate", func(s Series, newSeries Series) {
+		for i := 1; i < s.Len(); i++ {
+			prevT, prev := s.GetPoint(i - 1)
+			t, f := s.GetPoint(i)
+			elapsed := t.Sub(prevT).Seconds()
+			if prev == nil || f == nil || elapsed <= 0 {
+				newSeries.AppendPoint(t, nil)
+				continue
+			}
+			increase := *f - *prev
+			if increase < 0 {
+				increase = *f
+			}
+			r := increase / elapsed
+			newSeries.AppendPoint(t, &r)
+		}
+	})
+}
+
+// perSeries calls seriesF with each series in varSet and a new empty series with the same labels to write
+// the result to. NoData is passed through, any other type of value is an error.
+func perSeries(e *State, varSet Results, name string, seriesF func(s Series, newSeries Series)) (Results, error) {
+	newRes := Results{}
+	for _, res := range varSet.Values {
+		switch v := res.(type) {
+		case Series:
+			newSeries := NewSeries(e.RefID, v.GetLabels(), 0)
+			seriesF(v, newSeries)
+			newRes.Values = append(newRes.Values, newSeries)
+		case NoData:
+			newRes.Values = append(newRes.Values, v.New())
+		default:
+			return newRes, fmt.Errorf("%s can only be applied to a series, got type %s", name, res.Type())
+		}
+	}
+	return newRes, nil
+}
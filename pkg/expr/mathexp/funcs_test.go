@@ -5,8 +5,10 @@ import (
 	"testing"
 	"time"
 
-	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
 )
 
 func TestAbsFunc(t *testing.T) {
@@ -140,3 +142,136 @@ func TestIsNumberFunc(t *testing.T) {
 		})
 	}
 }
+
+func TestSeriesFuncs(t *testing.T) {
+	series := makeSeries("", data.Labels{"host": "a"},
+		tp{time.Unix(0, 0), float64Pointer(1)},
+		tp{time.Unix(60, 0), float64Pointer(4)},
+		tp{time.Unix(120, 0), nil},
+		tp{time.Unix(180, 0), float64Pointer(10)},
+		tp{time.Unix(240, 0), float64Pointer(4)},
+	)
+	var tests = []struct {
+		name      string
+		expr      string
+		vars      Vars
+		newErrIs  require.ErrorAssertionFunc
+		execErrIs require.ErrorAssertionFunc
+		results   Results
+	}{
+		{
+			name:      "shift moves points forward",
+			expr:      "shift($A, 1h)",
+			vars:      Vars{"A": resultValuesNoErr(series)},
+			newErrIs:  require.NoError,
+			execErrIs: require.NoError,
+			results: resultValuesNoErr(makeSeries("", data.Labels{"host": "a"},
+				tp{time.Unix(3600, 0), float64Pointer(1)},
+				tp{time.Unix(3660, 0), float64Pointer(4)},
+				tp{time.Unix(3720, 0), nil},
+				tp{time.Unix(3780, 0), float64Pointer(10)},
+				tp{time.Unix(3840, 0), float64Pointer(4)},
+			)),
+		},
+		{
+			name:      "moving_avg ignores nulls",
+			expr:      "moving_avg($A, 2m)",
+			vars:      Vars{"A": resultValuesNoErr(series)},
+			newErrIs:  require.NoError,
+			execErrIs: require.NoError,
+			results: resultValuesNoErr(makeSeries("", data.Labels{"host": "a"},
+				tp{time.Unix(0, 0), float64Pointer(1)},
+				tp{time.Unix(60, 0), float64Pointer(2.5)},
+				tp{time.Unix(120, 0), float64Pointer(4)},
+				tp{time.Unix(180, 0), float64Pointer(10)},
+				tp{time.Unix(240, 0), float64Pointer(7)},
+			)),
+		},
+		{
+			name:      "diff drops the first point",
+			expr:      "diff($A)",
+			vars:      Vars{"A": resultValuesNoErr(series)},
+			newErrIs:  require.NoError,
+			execErrIs: require.NoError,
+			results: resultValuesNoErr(makeSeries("", data.Labels{"host": "a"},
+				tp{time.Unix(60, 0), float64Pointer(3)},
+				tp{time.Unix(120, 0), nil},
+				tp{time.Unix(180, 0), nil},
+				tp{time.Unix(240, 0), float64Pointer(-6)},
+			)),
+		},
+		{
+			name:      "cumsum keeps nulls",
+			expr:      "cumsum($A)",
+			vars:      Vars{"A": resultValuesNoErr(series)},
+			newErrIs:  require.NoError,
+			execErrIs: require.NoError,
+			results: resultValuesNoErr(makeSeries("", data.Labels{"host": "a"},
+				tp{time.Unix(0, 0), float64Pointer(1)},
+				tp{time.Unix(60, 0), float64Pointer(5)},
+				tp{time.Unix(120, 0), nil},
+				tp{time.Unix(180, 0), float64Pointer(15)},
+				tp{time.Unix(240, 0), float64Pointer(19)},
+			)),
+		},
+		{
+			name:      "rate handles counter resets",
+			expr:      "rate($A)",
+			vars:      Vars{"A": resultValuesNoErr(series)},
+			newErrIs:  require.NoError,
+			execErrIs: require.NoError,
+			results: resultValuesNoErr(makeSeries("", data.Labels{"host": "a"},
+				tp{time.Unix(60, 0), float64Pointer(0.05)},
+				tp{time.Unix(120, 0), nil},
+				tp{time.Unix(180, 0), nil},
+				tp{time.Unix(240, 0), float64Pointer(4.0 / 60)},
+			)),
+		},
+		{
+			name:      "functions compose with math",
+			expr:      "$A - shift($A, 0s)",
+			vars:      Vars{"A": resultValuesNoErr(makeSeries("", nil, tp{time.Unix(0, 0), float64Pointer(2)}))},
+			newErrIs:  require.NoError,
+			execErrIs: require.NoError,
+			results:   resultValuesNoErr(makeSeries("", nil, tp{time.Unix(0, 0), float64Pointer(0)})),
+		},
+		{
+			name:      "no data is passed through",
+			expr:      "diff($A)",
+			vars:      Vars{"A": resultValuesNoErr(NewNoData())},
+			newErrIs:  require.NoError,
+			execErrIs: require.NoError,
+			results:   resultValuesNoErr(NewNoData()),
+		},
+		{
+			name:      "number input is an error",
+			expr:      "cumsum($A)",
+			vars:      Vars{"A": resultValuesNoErr(makeNumber("", nil, float64Pointer(1)))},
+			newErrIs:  require.NoError,
+			execErrIs: require.Error,
+		},
+		{
+			name:     "window must be a duration",
+			expr:     "moving_avg($A, 5)",
+			newErrIs: require.Error,
+		},
+		{
+			name:     "bad duration",
+			expr:     "shift($A, 5q)",
+			newErrIs: require.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := New(tt.expr)
+			tt.newErrIs(t, err)
+			if e != nil {
+				res, err := e.Execute("", tt.vars, tracing.InitializeTracerForTest())
+				tt.execErrIs(t, err)
+				if tt.results.Values != nil {
+					require.Equal(t, tt.results, res)
+				}
+			}
+		})
+	}
+}
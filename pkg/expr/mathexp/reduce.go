@@ -10,6 +10,9 @@ import (
 
 type ReducerFunc = func(fv *Float64Field) *float64
 
+// SeriesReducerFunc is a reducer that needs the timestamps of the series as well as its values.
+type SeriesReducerFunc = func(s Series) *float64
+
 // The reducer function
 // +enum
 type ReducerID string
@@ -22,11 +25,24 @@ const (
 	ReducerCount  ReducerID = "count"
 	ReducerLast   ReducerID = "last"
 	ReducerMedian ReducerID = "median"
+	// The first value
+	ReducerFirst ReducerID = "first"
+	// Population standard deviation
+	ReducerStdDev ReducerID = "stddev"
+	// Difference between the max and the min values
+	ReducerRange ReducerID = "range"
+	// Difference between the last and the first values
+	ReducerDelta ReducerID = "delta"
+	// Per-second rate of increase of a counter between the first and the last points. Any decrease is treated as a counter reset, use delta for gauges
+	ReducerRate ReducerID = "rate"
+	// Percentile of the values, the percentile (0-100) is given as the only parameter
+	ReducerPercentile ReducerID = "percentile"
 )
 
 // GetSupportedReduceFuncs returns collection of supported function names
 func GetSupportedReduceFuncs() []ReducerID {
-	return []ReducerID{ReducerSum, ReducerMean, ReducerMin, ReducerMax, ReducerCount, ReducerLast, ReducerMedian}
+	return []ReducerID{ReducerSum, ReducerMean, ReducerMin, ReducerMax, ReducerCount, ReducerLast, ReducerMedian,
+		ReducerFirst, ReducerStdDev, ReducerRange, ReducerDelta, ReducerRate, ReducerPercentile}
 }
 
 func Sum(fv *Float64Field) *float64 {
@@ -126,7 +142,154 @@ func Median(fv *Float64Field) *float64 {
 	}
 }
 
-func GetReduceFunc(rFunc ReducerID) (ReducerFunc, error) {
+func First(fv *Float64Field) *float64 {
+	var f float64
+	if fv.Len() == 0 {
+		f = math.NaN()
+		return &f
+	}
+	return fv.GetValue(0)
+}
+
+func StdDev(fv *Float64Field) *float64 {
+	values, ok := numbers(fv)
+	if !ok || len(values) == 0 {
+		nan := math.NaN()
+		return &nan
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	f := math.Sqrt(variance / float64(len(values)))
+	return &f
+}
+
+func Range(fv *Float64Field) *float64 {
+	minV, maxV := Min(fv), Max(fv)
+	f := *maxV - *minV
+	return &f
+}
+
+func Delta(fv *Float64Field) *float64 {
+	if fv.Len() < 2 {
+		nan := math.NaN()
+		return &nan
+	}
+	first, last := fv.GetValue(0), fv.GetValue(fv.Len()-1)
+	if first == nil || last == nil {
+		nan := math.NaN()
+		return &nan
+	}
+	f := *last - *first
+	return &f
+}
+
+// Rate returns the per-second rate of increase of the series between its first and its last point.
+// The series is expected to be a counter: a decrease between two consecutive points is considered a counter reset.
+func Rate(s Series) *float64 {
+	nan := math.NaN()
+	if s.Len() < 2 {
+		return &nan
+	}
+	var increase float64
+	prev := s.GetValue(0)
+	if prev == nil || math.IsNaN(*prev) {
+		return &nan
+	}
+	for i := 1; i < s.Len(); i++ {
+		v := s.GetValue(i)
+		if v == nil || math.IsNaN(*v) {
+			return &nan
+		}
+		if *v < *prev {
+			increase += *v
+		} else {
+			increase += *v - *prev
+		}
+		prev = v
+	}
+	seconds := s.GetTime(s.Len() - 1).Sub(s.GetTime(0)).Seconds()
+	if seconds <= 0 {
+		return &nan
+	}
+	f := increase / seconds
+	return &f
+}
+
+// Percentile returns a reducer for the given percentile (0-100) that interpolates linearly between the closest ranks.
+func Percentile(p float64) ReducerFunc {
+	return func(fv *Float64Field) *float64 {
+		values, ok := numbers(fv)
+		if !ok || len(values) == 0 {
+			nan := math.NaN()
+			return &nan
+		}
+		sort.Float64s(values)
+		rank := p / 100 * float64(len(values)-1)
+		lower := int(math.Floor(rank))
+		upper := int(math.Ceil(rank))
+		f := values[lower] + (values[upper]-values[lower])*(rank-float64(lower))
+		return &f
+	}
+}
+
+// numbers returns the values of the field, or false if any of them is null or NaN.
+func numbers(fv *Float64Field) ([]float64, bool) {
+	values := make([]float64, 0, fv.Len())
+	for i := 0; i < fv.Len(); i++ {
+		v := fv.GetValue(i)
+		if v == nil || math.IsNaN(*v) {
+			return nil, false
+		}
+		values = append(values, *v)
+	}
+	return values, true
+}
+
+// ValidateReducer returns an error if the reducer does not exist or if its parameters are invalid.
+func ValidateReducer(rFunc ReducerID, params []float64) error {
+	_, err := getSeriesReduceFunc(rFunc, params)
+	return err
+}
+
+// getSeriesReduceFunc returns a reducer that works on a whole series, which is needed by reducers that use timestamps.
+func getSeriesReduceFunc(rFunc ReducerID, params []float64) (SeriesReducerFunc, error) {
+	if rFunc == ReducerRate {
+		if len(params) != 0 {
+			return nil, fmt.Errorf("reduction %v does not accept parameters", rFunc)
+		}
+		return Rate, nil
+	}
+	reduceFunc, err := GetReduceFunc(rFunc, params...)
+	if err != nil {
+		return nil, err
+	}
+	return func(s Series) *float64 {
+		floatField := Float64Field(*s.Frame.Fields[seriesTypeValIdx])
+		return reduceFunc(&floatField)
+	}, nil
+}
+
+// GetReduceFunc returns the reducer for values. Reducers that need timestamps, such as rate, are only available through Series.Reduce.
+func GetReduceFunc(rFunc ReducerID, params ...float64) (ReducerFunc, error) {
+	if rFunc == ReducerPercentile {
+		if len(params) != 1 {
+			return nil, fmt.Errorf("reduction %v requires exactly one parameter, got %d", rFunc, len(params))
+		}
+		if params[0] < 0 || params[0] > 100 || math.IsNaN(params[0]) {
+			return nil, fmt.Errorf("reduction %v requires a parameter between 0 and 100, got %v", rFunc, params[0])
+		}
+		return Percentile(params[0]), nil
+	}
+	if len(params) != 0 {
+		return nil, fmt.Errorf("reduction %v does not accept parameters", rFunc)
+	}
 	switch rFunc {
 	case ReducerSum:
 		return Sum, nil
@@ -142,15 +305,25 @@ func GetReduceFunc(rFunc ReducerID) (ReducerFunc, error) {
 		return Last, nil
 	case ReducerMedian:
 		return Median, nil
+	case ReducerFirst:
+		return First, nil
+	case ReducerStdDev:
+		return StdDev, nil
+	case ReducerRange:
+		return Range, nil
+	case ReducerDelta:
+		return Delta, nil
+	case ReducerRate:
+		return nil, fmt.Errorf("reduction %v requires timestamps and can only be applied to a series", rFunc)
 	default:
 		return nil, fmt.Errorf("reduction %v not implemented", rFunc)
 	}
 }
 
-// Reduce turns the Series into a Number based on the given reduction function
+// Reduce turns the Series into a Number based on the given reduction function and its parameters
 // if ReduceMapper is defined it applies it to the provided series and performs reduction of the resulting series.
 // Otherwise, the reduction operation is done against the original series.
-func (s Series) Reduce(refID string, rFunc ReducerID, mapper ReduceMapper) (Number, error) {
+func (s Series) Reduce(refID string, rFunc ReducerID, mapper ReduceMapper, params ...float64) (Number, error) {
 	var l data.Labels
 	if s.GetLabels() != nil {
 		l = s.GetLabels().Copy()
@@ -161,13 +334,11 @@ func (s Series) Reduce(refID string, rFunc ReducerID, mapper ReduceMapper) (Numb
 	if mapper != nil {
 		series = mapSeries(s, mapper)
 	}
-	fVec := series.Frame.Fields[seriesTypeValIdx]
-	floatField := Float64Field(*fVec)
-	reduceFunc, err := GetReduceFunc(rFunc)
+	reduceFunc, err := getSeriesReduceFunc(rFunc, params)
 	if err != nil {
 		return number, fmt.Errorf("invalid expression '%s': %w", refID, err)
 	}
-	f = reduceFunc(&floatField)
+	f = reduceFunc(series)
 	if f != nil && mapper != nil {
 		f = mapper.MapOutput(f)
 	}
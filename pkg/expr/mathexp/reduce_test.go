@@ -543,3 +543,141 @@ func sortedFloat64(f []float64) []float64 {
 	sort.Float64s(f)
 	return f
 }
+
+var seriesCounter = Vars{
+	"A": resultValuesNoErr(
+		makeSeries("temp", nil,
+			tp{time.Unix(0, 0), float64Pointer(2)},
+			tp{time.Unix(10, 0), float64Pointer(6)},
+			tp{time.Unix(20, 0), float64Pointer(1)},
+			tp{time.Unix(30, 0), float64Pointer(4)},
+			tp{time.Unix(40, 0), float64Pointer(12)},
+		),
+	),
+}
+
+func TestSeriesReduceExtended(t *testing.T) {
+	var tests = []struct {
+		name    string
+		red     ReducerID
+		params  []float64
+		vars    Vars
+		errIs   require.ErrorAssertionFunc
+		results Results
+	}{
+		{
+			name:    "first",
+			red:     ReducerFirst,
+			vars:    seriesCounter,
+			errIs:   require.NoError,
+			results: resultValuesNoErr(makeNumber("", nil, float64Pointer(2))),
+		},
+		{
+			name:    "first empty series",
+			red:     ReducerFirst,
+			vars:    seriesEmpty,
+			errIs:   require.NoError,
+			results: resultValuesNoErr(makeNumber("", nil, NaN)),
+		},
+		{
+			name:    "stddev",
+			red:     ReducerStdDev,
+			vars:    seriesCounter,
+			errIs:   require.NoError,
+			results: resultValuesNoErr(makeNumber("", nil, float64Pointer(math.Sqrt(15.2)))),
+		},
+		{
+			name:    "stddev series with a nil value",
+			red:     ReducerStdDev,
+			vars:    seriesWithNil,
+			errIs:   require.NoError,
+			results: resultValuesNoErr(makeNumber("", nil, NaN)),
+		},
+		{
+			name:    "range",
+			red:     ReducerRange,
+			vars:    seriesCounter,
+			errIs:   require.NoError,
+			results: resultValuesNoErr(makeNumber("", nil, float64Pointer(11))),
+		},
+		{
+			name:    "delta",
+			red:     ReducerDelta,
+			vars:    seriesCounter,
+			errIs:   require.NoError,
+			results: resultValuesNoErr(makeNumber("", nil, float64Pointer(10))),
+		},
+		{
+			name:    "rate accounts for counter resets",
+			red:     ReducerRate,
+			vars:    seriesCounter,
+			errIs:   require.NoError,
+			results: resultValuesNoErr(makeNumber("", nil, float64Pointer(16.0/40))),
+		},
+		{
+			name:    "rate of a single point",
+			red:     ReducerRate,
+			vars:    seriesWithNil,
+			errIs:   require.NoError,
+			results: resultValuesNoErr(makeNumber("", nil, NaN)),
+		},
+		{
+			name:    "percentile",
+			red:     ReducerPercentile,
+			params:  []float64{87.5},
+			vars:    seriesCounter,
+			errIs:   require.NoError,
+			results: resultValuesNoErr(makeNumber("", nil, float64Pointer(9))),
+		},
+		{
+			name:    "percentile 0 is the min",
+			red:     ReducerPercentile,
+			params:  []float64{0},
+			vars:    seriesCounter,
+			errIs:   require.NoError,
+			results: resultValuesNoErr(makeNumber("", nil, float64Pointer(1))),
+		},
+		{
+			name:  "percentile without parameter will error",
+			red:   ReducerPercentile,
+			vars:  seriesCounter,
+			errIs: require.Error,
+		},
+		{
+			name:   "percentile out of range will error",
+			red:    ReducerPercentile,
+			params: []float64{101},
+			vars:   seriesCounter,
+			errIs:  require.Error,
+		},
+		{
+			name:   "parameters for a reducer without parameters will error",
+			red:    ReducerSum,
+			params: []float64{1},
+			vars:   seriesCounter,
+			errIs:  require.Error,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := Results{}
+			seriesSet := tt.vars["A"]
+			for _, series := range seriesSet.Values {
+				ns, err := series.Value().(*Series).Reduce("", tt.red, nil, tt.params...)
+				tt.errIs(t, err)
+				if err != nil {
+					return
+				}
+				results.Values = append(results.Values, ns)
+			}
+			opt := cmp.Comparer(func(x, y float64) bool {
+				return (math.IsNaN(x) && math.IsNaN(y)) || x == y
+			})
+			options := append([]cmp.Option{opt}, data.FrameTestCompareOptions()...)
+			if diff := cmp.Diff(tt.results, results, options...); diff != "" {
+				t.Errorf("Result mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -2,11 +2,48 @@ package mathexp
 
 import (
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 )
 
+// The downsample function
+// +enum
+type Downsampler string
+
+const (
+	DownsamplerSum    Downsampler = "sum"
+	DownsamplerMean   Downsampler = "mean"
+	DownsamplerMin    Downsampler = "min"
+	DownsamplerMax    Downsampler = "max"
+	DownsamplerCount  Downsampler = "count"
+	DownsamplerLast   Downsampler = "last"
+	DownsamplerMedian Downsampler = "median"
+	// The first value
+	DownsamplerFirst Downsampler = "first"
+	// Population standard deviation
+	DownsamplerStdDev Downsampler = "stddev"
+	// Difference between the max and the min values
+	DownsamplerRange Downsampler = "range"
+	// Difference between the last and the first values
+	DownsamplerDelta Downsampler = "delta"
+)
+
+// GetSupportedDownsamplers returns collection of supported downsampler names. Reducers that need timestamps or
+// parameters, such as rate and percentile, cannot be used to downsample.
+func GetSupportedDownsamplers() []Downsampler {
+	return []Downsampler{DownsamplerSum, DownsamplerMean, DownsamplerMin, DownsamplerMax, DownsamplerCount, DownsamplerLast,
+		DownsamplerMedian, DownsamplerFirst, DownsamplerStdDev, DownsamplerRange, DownsamplerDelta}
+}
+
+func getDownsampleFunc(downsampler Downsampler) (ReducerFunc, error) {
+	if !slices.Contains(GetSupportedDownsamplers(), downsampler) {
+		return nil, fmt.Errorf("downsampling %v not implemented", downsampler)
+	}
+	return GetReduceFunc(ReducerID(downsampler))
+}
+
 // The upsample function
 // +enum
 type Upsampler string
@@ -20,17 +57,63 @@ const (
 
 	// Do not fill values (nill)
 	UpsamplerFillNA Upsampler = "fillna"
+
+	// Interpolate linearly between the previous and the next values
+	UpsamplerLinear Upsampler = "linear"
+
+	// Use the value of the closest point in time, either before or after
+	UpsamplerNearest Upsampler = "nearest"
+
+	// Do not fill values, same as fillna
+	UpsamplerNull Upsampler = "null"
 )
 
+// GetSupportedUpsamplers returns collection of supported upsampler names
+func GetSupportedUpsamplers() []Upsampler {
+	return []Upsampler{UpsamplerPad, UpsamplerBackfill, UpsamplerFillNA, UpsamplerLinear, UpsamplerNearest, UpsamplerNull}
+}
+
+// How the resampled windows are aligned
+// +enum
+type ResampleAlignment string
+
+const (
+	// Windows are aligned on the start of the query time range
+	ResampleAlignmentRange ResampleAlignment = "range"
+
+	// Windows are aligned on wall-clock boundaries, i.e. multiples of the window since the Unix epoch
+	ResampleAlignmentClock ResampleAlignment = "clock"
+)
+
+// AlignToClock returns the first time at or after t that is a multiple of interval since the Unix epoch.
+func AlignToClock(t time.Time, interval time.Duration) time.Time {
+	if interval <= 0 {
+		return t
+	}
+	ns := t.UnixNano()
+	rem := ns % int64(interval)
+	if rem == 0 {
+		return t
+	}
+	if rem < 0 {
+		rem += int64(interval)
+	}
+	return time.Unix(0, ns-rem+int64(interval)).In(t.Location())
+}
+
 // Resample turns the Series into a Number based on the given reduction function
-func (s Series) Resample(refID string, interval time.Duration, downsampler ReducerID, upsampler Upsampler, from, to time.Time) (Series, error) {
+func (s Series) Resample(refID string, interval time.Duration, downsampler Downsampler, upsampler Upsampler, from, to time.Time) (Series, error) {
 	newSeriesLength := int(float64(to.Sub(from).Nanoseconds()) / float64(interval.Nanoseconds()))
 	if newSeriesLength <= 0 {
 		return s, fmt.Errorf("the series cannot be sampled further; the time range is shorter than the interval")
 	}
 	resampled := NewSeries(refID, s.GetLabels(), newSeriesLength+1)
+	// An unknown downsampler or upsampler only fails if it is needed, so that stored queries with a value that is
+	// never used still evaluate.
+	downsampleFunc, downsampleErr := getDownsampleFunc(downsampler)
 	bookmark := 0
 	var lastSeen *float64
+	var lastSeenTime time.Time
 	idx := 0
 	t := from
 	for !t.After(to) && idx <= newSeriesLength {
@@ -47,6 +130,7 @@ func (s Series) Resample(refID string, interval time.Duration, downsampler Reduc
 			bookmark++
 			sIdx++
 			lastSeen = v
+			lastSeenTime = st
 			vals = append(vals, v)
 		}
 		var value *float64
@@ -64,32 +148,43 @@ func (s Series) Resample(refID string, interval time.Duration, downsampler Reduc
 				} else {
 					_, value = s.GetPoint(sIdx)
 				}
-			case UpsamplerFillNA:
+			case UpsamplerFillNA, UpsamplerNull:
 				value = nil
+			case UpsamplerLinear:
+				if lastSeen != nil && sIdx < s.Len() {
+					nextTime, next := s.GetPoint(sIdx)
+					if next != nil {
+						ratio := float64(t.Sub(lastSeenTime)) / float64(nextTime.Sub(lastSeenTime))
+						v := *lastSeen + (*next-*lastSeen)*ratio
+						value = &v
+					}
+				}
+			case UpsamplerNearest:
+				switch {
+				case sIdx == s.Len():
+					value = lastSeen
+				case bookmark == 0: // nothing seen yet
+					_, value = s.GetPoint(sIdx)
+				default:
+					nextTime, next := s.GetPoint(sIdx)
+					if nextTime.Sub(t) < t.Sub(lastSeenTime) {
+						value = next
+					} else {
+						value = lastSeen
+					}
+				}
 			default:
 				return s, fmt.Errorf("upsampling %v not implemented", upsampler)
 			}
 		} else if len(vals) == 1 {
 			value = vals[0]
 		} else { // downsampling
+			if downsampleErr != nil {
+				return s, downsampleErr
+			}
 			fVec := data.NewField("", s.GetLabels(), vals)
 			ff := Float64Field(*fVec)
-			var tmp *float64
-			switch downsampler {
-			case ReducerSum:
-				tmp = Sum(&ff)
-			case ReducerMean:
-				tmp = Avg(&ff)
-			case ReducerMin:
-				tmp = Min(&ff)
-			case ReducerMax:
-				tmp = Max(&ff)
-			case ReducerLast:
-				tmp = Last(&ff)
-			default:
-				return s, fmt.Errorf("downsampling %v not implemented", downsampler)
-			}
-			value = tmp
+			value = downsampleFunc(&ff)
 		}
 		resampled.SetPoint(idx, t, value)
 		t = t.Add(interval)
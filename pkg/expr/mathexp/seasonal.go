@@ -0,0 +1,285 @@
+package mathexp
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// SeasonalAlgorithm is the method used to compute the expected (baseline) value of each point of a series.
+// +enum
+type SeasonalAlgorithm string
+
+const (
+	// The median of the values at the same time in the previous seasons
+	SeasonalMedian SeasonalAlgorithm = "median"
+	// Additive Holt-Winters (triple exponential smoothing). The series should be regularly sampled
+	SeasonalHoltWinters SeasonalAlgorithm = "holt_winters"
+)
+
+// madScale makes the median absolute deviation an estimate of the standard deviation of normally distributed data.
+const madScale = 1.4826
+
+// SeasonalOptions configures the computation of seasonal bands.
+type SeasonalOptions struct {
+	Algorithm SeasonalAlgorithm
+	// Season is the length of one seasonal cycle, e.g. 1d or 1w.
+	Season time.Duration
+	// Seasons is the number of previous seasons the median algorithm looks at.
+	Seasons int
+	// Deviations is the width of the bands, in the same unit as the score.
+	Deviations float64
+	// Alpha, Beta and Gamma are the smoothing factors of the level, trend and seasonal components of the
+	// Holt-Winters algorithm. Each must be in the range (0, 1].
+	Alpha, Beta, Gamma float64
+}
+
+// Validate returns an error if the options cannot be used to compute seasonal bands.
+func (o SeasonalOptions) Validate() error {
+	if o.Season <= 0 {
+		return fmt.Errorf("season must be greater than zero, got %v", o.Season)
+	}
+	if o.Deviations <= 0 {
+		return fmt.Errorf("deviations must be greater than zero, got %v", o.Deviations)
+	}
+	switch o.Algorithm {
+	case SeasonalMedian:
+		if o.Seasons < 1 {
+			return fmt.Errorf("seasons must be at least 1, got %d", o.Seasons)
+		}
+	case SeasonalHoltWinters:
+		for name, v := range map[string]float64{"alpha": o.Alpha, "beta": o.Beta, "gamma": o.Gamma} {
+			if v <= 0 || v > 1 {
+				return fmt.Errorf("%s must be in the range (0, 1], got %v", name, v)
+			}
+		}
+	default:
+		return fmt.Errorf("seasonal algorithm %q is not supported, must be one of [%s, %s]", o.Algorithm, SeasonalMedian, SeasonalHoltWinters)
+	}
+	return nil
+}
+
+// SeasonalBands holds the result of comparing a series against its seasonal baseline.
+// All series have the points of the input series. A point is null where there is not enough history
+// to compute it.
+type SeasonalBands struct {
+	// Baseline is the expected value of each point.
+	Baseline Series
+	// Upper and Lower are the baseline plus and minus Deviations times the expected spread.
+	Upper Series
+	Lower Series
+	// Score is the distance from the baseline in units of the expected spread. The point is outside
+	// the bands when the absolute score is greater than Deviations.
+	Score Series
+}
+
+// SeasonalBands computes the seasonal baseline, the bands around it, and the anomaly score of each point of the series.
+func (s Series) SeasonalBands(refID string, opts SeasonalOptions) (SeasonalBands, error) {
+	if err := opts.Validate(); err != nil {
+		return SeasonalBands{}, err
+	}
+
+	times, values := sortedPoints(s)
+	baseline := make([]*float64, len(times))
+	spread := make([]*float64, len(times))
+
+	switch opts.Algorithm {
+	case SeasonalMedian:
+		seasonalMedian(times, values, opts, baseline, spread)
+	case SeasonalHoltWinters:
+		step := medianStep(times)
+		if step > 0 {
+			period := int(math.Round(float64(opts.Season) / float64(step)))
+			if period < 2 {
+				return SeasonalBands{}, fmt.Errorf("season %v must be at least two times the interval of the series (%v)", opts.Season, step)
+			}
+			holtWinters(values, period, opts, baseline, spread)
+		}
+	}
+
+	bands := SeasonalBands{
+		Baseline: NewSeries(refID, s.GetLabels(), len(times)),
+		Upper:    NewSeries(refID, s.GetLabels(), len(times)),
+		Lower:    NewSeries(refID, s.GetLabels(), len(times)),
+		Score:    NewSeries(refID, s.GetLabels(), len(times)),
+	}
+	for i, t := range times {
+		bands.Baseline.SetPoint(i, t, baseline[i])
+		var upper, lower, score *float64
+		if baseline[i] != nil && spread[i] != nil {
+			b, sp := *baseline[i], *spread[i]
+			upper = float64Ptr(b + opts.Deviations*sp)
+			lower = float64Ptr(b - opts.Deviations*sp)
+			if values[i] != nil && !math.IsNaN(*values[i]) {
+				score = float64Ptr(anomalyScore(*values[i], b, sp))
+			}
+		}
+		bands.Upper.SetPoint(i, t, upper)
+		bands.Lower.SetPoint(i, t, lower)
+		bands.Score.SetPoint(i, t, score)
+	}
+	return bands, nil
+}
+
+// anomalyScore returns the distance of v from the baseline in units of spread. When there is no spread at all
+// any deviation is infinitely unlikely.
+func anomalyScore(v, baseline, spread float64) float64 {
+	d := v - baseline
+	if spread == 0 {
+		switch {
+		case d > 0:
+			return math.Inf(1)
+		case d < 0:
+			return math.Inf(-1)
+		default:
+			return 0
+		}
+	}
+	return d / spread
+}
+
+// seasonalMedian sets the baseline of each point to the median of the values at the same time in
+// the previous seasons, and the spread to the scaled median absolute deviation of those values.
+func seasonalMedian(times []time.Time, values []*float64, opts SeasonalOptions, baseline, spread []*float64) {
+	tolerance := medianStep(times) / 2
+	for i, t := range times {
+		past := make([]float64, 0, opts.Seasons)
+		for k := 1; k <= opts.Seasons; k++ {
+			idx, ok := nearestPoint(times, t.Add(-time.Duration(k)*opts.Season), tolerance)
+			if !ok || values[idx] == nil || math.IsNaN(*values[idx]) {
+				continue
+			}
+			past = append(past, *values[idx])
+		}
+		if len(past) == 0 {
+			continue
+		}
+		m := median(past)
+		deviations := make([]float64, len(past))
+		for j, v := range past {
+			deviations[j] = math.Abs(v - m)
+		}
+		baseline[i] = float64Ptr(m)
+		spread[i] = float64Ptr(madScale * median(deviations))
+	}
+}
+
+// holtWinters sets the baseline of each point to the one step ahead forecast of the additive Holt-Winters model,
+// and the spread to the root mean square of the previous forecast errors. The first season is used to initialize
+// the model, and the spread is only available once a full season of errors has been seen.
+func holtWinters(values []*float64, period int, opts SeasonalOptions, baseline, spread []*float64) {
+	if len(values) < 2*period {
+		return
+	}
+	first, second := meanOf(values[:period]), meanOf(values[period:2*period])
+	level := first
+	trend := (second - first) / float64(period)
+	seasonal := make([]float64, period)
+	for i := 0; i < period; i++ {
+		if v := values[i]; v != nil && !math.IsNaN(*v) {
+			seasonal[i] = *v - first
+		}
+	}
+
+	sumSq, errCount := 0.0, 0
+	for i := period; i < len(values); i++ {
+		si := i % period
+		forecast := level + trend + seasonal[si]
+		baseline[i] = float64Ptr(forecast)
+		if errCount >= period {
+			spread[i] = float64Ptr(math.Sqrt(sumSq / float64(errCount)))
+		}
+
+		v := values[i]
+		if v == nil || math.IsNaN(*v) {
+			level += trend
+			continue
+		}
+		prevLevel := level
+		level = opts.Alpha*(*v-seasonal[si]) + (1-opts.Alpha)*(level+trend)
+		trend = opts.Beta*(level-prevLevel) + (1-opts.Beta)*trend
+		seasonal[si] = opts.Gamma*(*v-level) + (1-opts.Gamma)*seasonal[si]
+
+		residual := *v - forecast
+		sumSq += residual * residual
+		errCount++
+	}
+}
+
+// sortedPoints returns the points of the series in ascending time order.
+func sortedPoints(s Series) ([]time.Time, []*float64) {
+	idx := make([]int, s.Len())
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return s.GetTime(idx[a]).Before(s.GetTime(idx[b])) })
+
+	times := make([]time.Time, len(idx))
+	values := make([]*float64, len(idx))
+	for i, j := range idx {
+		times[i], values[i] = s.GetPoint(j)
+	}
+	return times, values
+}
+
+// medianStep returns the median interval between consecutive points, or zero if there are fewer than two points.
+func medianStep(times []time.Time) time.Duration {
+	if len(times) < 2 {
+		return 0
+	}
+	steps := make([]float64, 0, len(times)-1)
+	for i := 1; i < len(times); i++ {
+		steps = append(steps, float64(times[i].Sub(times[i-1])))
+	}
+	return time.Duration(median(steps))
+}
+
+// nearestPoint returns the index of the point closest to t if it is within tolerance of t.
+func nearestPoint(times []time.Time, t time.Time, tolerance time.Duration) (int, bool) {
+	i := sort.Search(len(times), func(i int) bool { return !times[i].Before(t) })
+	best, bestDist := -1, time.Duration(math.MaxInt64)
+	for _, j := range []int{i - 1, i} {
+		if j < 0 || j >= len(times) {
+			continue
+		}
+		d := times[j].Sub(t)
+		if d < 0 {
+			d = -d
+		}
+		if d < bestDist {
+			best, bestDist = j, d
+		}
+	}
+	return best, best >= 0 && bestDist <= tolerance
+}
+
+// median returns the median of fv, which must not be empty. fv is sorted in place.
+func median(fv []float64) float64 {
+	sort.Float64s(fv)
+	mid := len(fv) / 2
+	if len(fv)%2 == 0 {
+		return (fv[mid-1] + fv[mid]) / 2
+	}
+	return fv[mid]
+}
+
+// meanOf returns the mean of the non-null, non-NaN values, or zero if there are none.
+func meanOf(values []*float64) float64 {
+	sum, count := 0.0, 0
+	for _, v := range values {
+		if v == nil || math.IsNaN(*v) {
+			continue
+		}
+		sum += *v
+		count++
+	}
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,97 @@
+package mathexp
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// dailySeries returns an hourly series of the given number of days that repeats the same daily
+// pattern: 10 during the day (08:00-20:00) and 2 during the night.
+func dailySeries(days int) Series {
+	s := NewSeries("A", nil, days*24)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < days*24; i++ {
+		v := 2.0
+		if h := i % 24; h >= 8 && h < 20 {
+			v = 10
+		}
+		s.SetPoint(i, start.Add(time.Duration(i)*time.Hour), float64Pointer(v))
+	}
+	return s
+}
+
+func TestSeriesSeasonalBands(t *testing.T) {
+	t.Run("median of past seasons", func(t *testing.T) {
+		s := dailySeries(4)
+		// the last point is at 23:00, where 2 is expected
+		s.SetPoint(s.Len()-1, s.GetTime(s.Len()-1), float64Pointer(50))
+		// make the history at 22:00 vary so it has a spread
+		s.SetPoint(22, s.GetTime(22), float64Pointer(1))
+		s.SetPoint(24+22, s.GetTime(24+22), float64Pointer(3))
+
+		bands, err := s.SeasonalBands("B", SeasonalOptions{Algorithm: SeasonalMedian, Season: 24 * time.Hour, Seasons: 3, Deviations: 3})
+		require.NoError(t, err)
+		require.Equal(t, s.Len(), bands.Score.Len())
+
+		// no history during the first day
+		require.Nil(t, bands.Baseline.GetValue(0))
+		require.Nil(t, bands.Score.GetValue(0))
+
+		// constant history: no deviation means a score of zero, any deviation is infinite
+		last := s.Len() - 1
+		require.Equal(t, 2.0, *bands.Baseline.GetValue(last))
+		require.Equal(t, 2.0, *bands.Upper.GetValue(last))
+		require.True(t, math.IsInf(*bands.Score.GetValue(last), 1))
+		require.Equal(t, 0.0, *bands.Score.GetValue(last - 24))
+
+		// history of 1, 3 and 2 at 22:00
+		idx := 3*24 + 22
+		require.Equal(t, 2.0, *bands.Baseline.GetValue(idx))
+		require.InDelta(t, 2+3*madScale, *bands.Upper.GetValue(idx), 1e-9)
+		require.Equal(t, 0.0, *bands.Score.GetValue(idx))
+	})
+
+	t.Run("holt-winters learns the seasonal pattern", func(t *testing.T) {
+		s := dailySeries(5)
+		last := s.Len() - 1
+		s.SetPoint(last, s.GetTime(last), float64Pointer(30))
+
+		bands, err := s.SeasonalBands("B", SeasonalOptions{Algorithm: SeasonalHoltWinters, Season: 24 * time.Hour, Deviations: 3, Alpha: 0.5, Beta: 0.1, Gamma: 0.3})
+		require.NoError(t, err)
+
+		// the first season initializes the model, the second one the spread
+		require.Nil(t, bands.Baseline.GetValue(23))
+		require.NotNil(t, bands.Baseline.GetValue(24))
+		require.Nil(t, bands.Score.GetValue(47))
+
+		require.InDelta(t, 2.0, *bands.Baseline.GetValue(last), 0.5)
+		require.InDelta(t, 10.0, *bands.Baseline.GetValue(last - 12), 0.5)
+		require.Greater(t, *bands.Score.GetValue(last), 3.0)
+		require.Less(t, math.Abs(*bands.Score.GetValue(last - 1)), 3.0)
+	})
+
+	t.Run("holt-winters without two seasons has no baseline", func(t *testing.T) {
+		bands, err := dailySeries(1).SeasonalBands("B", SeasonalOptions{Algorithm: SeasonalHoltWinters, Season: 24 * time.Hour, Deviations: 3, Alpha: 0.5, Beta: 0.1, Gamma: 0.3})
+		require.NoError(t, err)
+		for i := 0; i < bands.Baseline.Len(); i++ {
+			require.Nil(t, bands.Baseline.GetValue(i))
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		for name, opts := range map[string]SeasonalOptions{
+			"unknown algorithm":      {Algorithm: "prophet", Season: time.Hour, Deviations: 3},
+			"no season":              {Algorithm: SeasonalMedian, Seasons: 1, Deviations: 3},
+			"no seasons":             {Algorithm: SeasonalMedian, Season: time.Hour, Deviations: 3},
+			"no deviations":          {Algorithm: SeasonalMedian, Season: time.Hour, Seasons: 1},
+			"alpha out of range":     {Algorithm: SeasonalHoltWinters, Season: time.Hour, Deviations: 3, Alpha: 2, Beta: 0.1, Gamma: 0.1},
+			"season shorter than 2x": {Algorithm: SeasonalHoltWinters, Season: time.Hour, Deviations: 3, Alpha: 0.5, Beta: 0.1, Gamma: 0.1},
+		} {
+			_, err := dailySeries(2).SeasonalBands("B", opts)
+			require.Error(t, err, name)
+		}
+	})
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -158,6 +160,8 @@ func buildCMDNode(rn *rawNode, toggles featuremgmt.FeatureToggles) (*CMDNode, er
 		node.Command, err = UnmarshalThresholdCommand(rn, toggles)
 	case TypeSQL:
 		node.Command, err = UnmarshalSQLCommand(rn)
+	case TypeAnomaly:
+		node.Command, err = UnmarshalAnomalyCommand(rn)
 	default:
 		return nil, fmt.Errorf("expression command type '%v' in expression '%v' not implemented", commandType, rn.RefID)
 	}
@@ -185,6 +189,9 @@ type DSNode struct {
 	intervalMS int64
 	maxDP      int64
 	request    Request
+
+	// refQueries are hidden queries sent along with the query, see addGraphiteRefQueries
+	refQueries []backend.DataQuery
 }
 
 func (dn *DSNode) String() string {
@@ -247,6 +254,65 @@ func (s *Service) buildDSNode(dp *simple.DirectedGraph, rn *rawNode, req *Reques
 	return dsNode, nil
 }
 
+// graphiteTargetRefRegex matches the references of Graphite targets to the targets of other queries, e.g. #A.
+var graphiteTargetRefRegex = regexp.MustCompile(`#[A-Z]`)
+
+// addGraphiteRefQueries adds the other Graphite queries of the same data source as hidden queries to the Graphite
+// queries that reference other queries. Graphite resolves the references to the queries of the same request only,
+// and the queries are not always sent together. The queries resolved by the frontend have a targetFull.
+func addGraphiteRefQueries(nodes []*DSNode) error {
+	for _, dn := range nodes {
+		if dn.datasource.Type != datasources.DS_GRAPHITE {
+			continue
+		}
+		var model struct {
+			Target     string  `json:"target"`
+			TargetFull *string `json:"targetFull"`
+		}
+		if err := json.Unmarshal(dn.query, &model); err != nil {
+			return err
+		}
+		if model.TargetFull != nil || !graphiteTargetRefRegex.MatchString(model.Target) {
+			continue
+		}
+
+		for _, other := range nodes {
+			if other == dn || other.datasource.UID != dn.datasource.UID || other.orgID != dn.orgID {
+				continue
+			}
+			var query map[string]any
+			if err := json.Unmarshal(other.query, &query); err != nil {
+				return err
+			}
+			query["hide"] = true
+			hidden, err := json.Marshal(query)
+			if err != nil {
+				return err
+			}
+			dn.refQueries = append(dn.refQueries, backend.DataQuery{
+				RefID:     other.refID,
+				JSON:      hidden,
+				QueryType: other.queryType,
+			})
+		}
+	}
+	return nil
+}
+
+// appendRefQueries appends the hidden queries of the node that are not part of the queries yet.
+func (dn *DSNode) appendRefQueries(queries []backend.DataQuery, now time.Time) []backend.DataQuery {
+	for _, ref := range dn.refQueries {
+		if slices.ContainsFunc(queries, func(q backend.DataQuery) bool { return q.RefID == ref.RefID }) {
+			continue
+		}
+		ref.MaxDataPoints = dn.maxDP
+		ref.Interval = time.Duration(int64(time.Millisecond) * dn.intervalMS)
+		ref.TimeRange = dn.timeRange.AbsoluteTime(now)
+		queries = append(queries, ref)
+	}
+	return queries
+}
+
 // executeDSNodesGrouped groups datasource node queries by the datasource instance, and then sends them
 // in a single request with one or more queries to the datasource.
 func executeDSNodesGrouped(ctx context.Context, now time.Time, vars mathexp.Vars, s *Service, nodes []*DSNode) {
@@ -300,6 +366,9 @@ func executeDSNodesGrouped(ctx context.Context, now time.Time, vars mathexp.Vars
 					QueryType:     dn.queryType,
 				})
 			}
+			for _, dn := range nodeGroup {
+				req.Queries = dn.appendRefQueries(req.Queries, now)
+			}
 
 			instrument := func(e error, rt string) {
 				respStatus := "success"
@@ -375,6 +444,7 @@ func (dn *DSNode) Execute(ctx context.Context, now time.Time, _ mathexp.Vars, s
 		},
 		Headers: dn.request.Headers,
 	}
+	req.Queries = dn.appendRefQueries(req.Queries, now)
 
 	responseType := "unknown"
 	respStatus := "success"
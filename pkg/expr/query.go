@@ -29,6 +29,9 @@ const (
 
 	// SQL query via DuckDB
 	QueryTypeSQL QueryType = "sql"
+
+	// Seasonal anomaly detection
+	QueryTypeAnomaly QueryType = "anomaly"
 )
 
 type MathQuery struct {
@@ -43,6 +46,9 @@ type ReduceQuery struct {
 	// The reducer
 	Reducer mathexp.ReducerID `json:"reducer"`
 
+	// Parameters of the reducer, such as the percentile (0-100) for the percentile reducer
+	ReducerParams []float64 `json:"reducerParams,omitempty"`
+
 	// Reducer Options
 	Settings *ReduceSettings `json:"settings,omitempty"`
 }
@@ -56,10 +62,13 @@ type ResampleQuery struct {
 	Window string `json:"window" jsonschema:"minLength=1,example=1d,example=10m"`
 
 	// The downsample function
-	Downsampler mathexp.ReducerID `json:"downsampler"`
+	Downsampler mathexp.Downsampler `json:"downsampler"`
 
 	// The upsample function
 	Upsampler mathexp.Upsampler `json:"upsampler"`
+
+	// How the windows are aligned, on the query time range (default) or on wall-clock boundaries
+	Alignment mathexp.ResampleAlignment `json:"alignment,omitempty"`
 }
 
 type ThresholdQuery struct {
@@ -70,6 +79,36 @@ type ThresholdQuery struct {
 	Conditions []ThresholdConditionJSON `json:"conditions"`
 }
 
+// QueryType = anomaly
+type AnomalyQuery struct {
+	// Reference to single query result
+	Expression string `json:"expression" jsonschema:"minLength=1,example=$A"`
+
+	// How the baseline is computed (default median)
+	Algorithm mathexp.SeasonalAlgorithm `json:"algorithm,omitempty"`
+
+	// The length of one seasonal cycle
+	Season string `json:"season" jsonschema:"minLength=1,example=1d,example=1w"`
+
+	// Number of previous seasons used by the median algorithm (default 4)
+	Seasons int `json:"seasons,omitempty"`
+
+	// Width of the bands in standard deviations (default 3)
+	Deviations float64 `json:"deviations,omitempty"`
+
+	// Holt-Winters smoothing factor of the level (default 0.5)
+	Alpha float64 `json:"alpha,omitempty"`
+
+	// Holt-Winters smoothing factor of the trend (default 0.1)
+	Beta float64 `json:"beta,omitempty"`
+
+	// Holt-Winters smoothing factor of the seasonal component (default 0.3)
+	Gamma float64 `json:"gamma,omitempty"`
+
+	// Also return the baseline and the upper and lower bands, labeled with anomaly_band
+	Bands bool `json:"bands,omitempty"`
+}
+
 type ClassicQuery struct {
 	Conditions []classic.ConditionJSON `json:"conditions"`
 }
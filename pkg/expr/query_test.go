@@ -22,9 +22,12 @@ func TestQueryTypeDefinitions(t *testing.T) {
 				CodePath:    "./",
 			}},
 			Enums: []reflect.Type{
-				reflect.TypeOf(mathexp.ReducerSum),   // pick an example value (not the root)
+				reflect.TypeOf(mathexp.ReducerSum), // pick an example value (not the root)
+				reflect.TypeOf(mathexp.DownsamplerSum),
 				reflect.TypeOf(mathexp.UpsamplerPad), // pick an example value (not the root)
-				reflect.TypeOf(ReduceModeDrop),       // pick an example value (not the root)
+				reflect.TypeOf(mathexp.ResampleAlignmentRange),
+				reflect.TypeOf(mathexp.SeasonalMedian),
+				reflect.TypeOf(ReduceModeDrop), // pick an example value (not the root)
 				reflect.TypeOf(ThresholdIsAbove),
 				reflect.TypeOf(classic.ConditionOperatorAnd),
 			},
@@ -74,7 +77,7 @@ func TestQueryTypeDefinitions(t *testing.T) {
 					SaveModel: data.AsUnstructured(ResampleQuery{
 						Expression:  "$A",
 						Window:      "1d",
-						Downsampler: mathexp.ReducerLast,
+						Downsampler: mathexp.DownsamplerLast,
 						Upsampler:   mathexp.UpsamplerPad,
 					}),
 				},
@@ -120,6 +123,31 @@ func TestQueryTypeDefinitions(t *testing.T) {
 				},
 			},
 		},
+		schemabuilder.QueryTypeInfo{
+			Discriminators: data.NewDiscriminators("type", QueryTypeAnomaly),
+			GoType:         reflect.TypeOf(&AnomalyQuery{}),
+			Examples: []data.QueryExample{
+				{
+					Name: "compare to the median of the last four weeks",
+					SaveModel: data.AsUnstructured(AnomalyQuery{
+						Expression: "$A",
+						Algorithm:  mathexp.SeasonalMedian,
+						Season:     "1w",
+						Seasons:    4,
+					}),
+				},
+				{
+					Name: "daily holt-winters baseline with bands",
+					SaveModel: data.AsUnstructured(AnomalyQuery{
+						Expression: "$A",
+						Algorithm:  mathexp.SeasonalHoltWinters,
+						Season:     "1d",
+						Deviations: 2,
+						Bands:      true,
+					}),
+				},
+			},
+		},
 		schemabuilder.QueryTypeInfo{
 			Discriminators: data.NewDiscriminators("type", QueryTypeThreshold),
 			GoType:         reflect.TypeOf(&ThresholdQuery{}),
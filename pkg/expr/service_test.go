@@ -4,7 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,6 +18,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+	"github.com/grafana/grafana/pkg/infra/httpclient"
 	"github.com/grafana/grafana/pkg/infra/tracing"
 	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/services/datasources"
@@ -24,6 +29,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/pluginstore"
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/tsdb/graphite"
 )
 
 func TestService(t *testing.T) {
@@ -184,6 +190,81 @@ func TestDSQueryError(t *testing.T) {
 	require.Equal(t, fp(42), resp.Responses["C"].Frames[0].Fields[0].At(0))
 }
 
+func TestGraphiteTargetReferences(t *testing.T) {
+	var requestedTargets [][]string
+	graphiteServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		requestedTargets = append(requestedTargets, r.Form["target"])
+		var series []string
+		for _, target := range r.Form["target"] {
+			refID := target[len(target)-3 : len(target)-2]
+			series = append(series, fmt.Sprintf(`{"target": "series %s", "datapoints": [[1, 1]]}`, refID))
+		}
+		_, _ = w.Write([]byte("[" + strings.Join(series, ",") + "]"))
+	}))
+	defer graphiteServer.Close()
+
+	pCtxProvider := plugincontext.ProvideService(setting.NewCfg(), nil, &pluginstore.FakePluginStore{
+		PluginList: []pluginstore.Plugin{
+			{JSONData: plugins.JSONData{ID: datasources.DS_GRAPHITE}},
+		},
+	}, &datafakes.FakeCacheService{}, &datafakes.FakeDataSourceService{}, nil, pluginconfig.NewFakePluginRequestConfigProvider())
+
+	graphiteDS := &datasources.DataSource{OrgID: 1, UID: "graphite", Type: datasources.DS_GRAPHITE, URL: graphiteServer.URL}
+	queries := []Query{
+		{
+			RefID:      "A",
+			DataSource: graphiteDS,
+			JSON:       json.RawMessage(`{"target": "app.*.requests.count"}`),
+			TimeRange:  RelativeTimeRange{From: -10 * time.Minute},
+		},
+		{
+			RefID:      "B",
+			DataSource: graphiteDS,
+			JSON:       json.RawMessage(`{"target": "sumSeries(#A)"}`),
+			TimeRange:  RelativeTimeRange{From: -10 * time.Minute},
+		},
+		{
+			RefID:      "C",
+			DataSource: dataSourceModel(),
+			JSON:       json.RawMessage(`{"datasource": {"uid": "__expr__", "type": "__expr__"}, "type": "reduce", "expression": "B", "reducer": "last"}`),
+		},
+	}
+
+	for name, features := range map[string]featuremgmt.FeatureToggles{
+		"queries sent one per request":   featuremgmt.WithFeatures(),
+		"queries grouped by data source": featuremgmt.WithFeatures(featuremgmt.FlagSseGroupByDatasource),
+	} {
+		t.Run(name, func(t *testing.T) {
+			requestedTargets = nil
+			s := Service{
+				cfg:          setting.NewCfg(),
+				dataService:  graphite.ProvideService(httpclient.NewProvider(), tracing.InitializeTracerForTest()),
+				pCtxProvider: pCtxProvider,
+				features:     features,
+				tracer:       tracing.InitializeTracerForTest(),
+				metrics:      newMetrics(nil),
+				converter: &ResultConverter{
+					Features: features,
+					Tracer:   tracing.InitializeTracerForTest(),
+				},
+			}
+
+			pl, err := s.BuildPipeline(&Request{Queries: queries, User: &user.SignedInUser{}})
+			require.NoError(t, err)
+			res, err := s.ExecutePipeline(context.Background(), time.Now(), pl)
+			require.NoError(t, err)
+
+			for _, refID := range []string{"A", "B", "C"} {
+				require.NoError(t, res.Responses[refID].Error, refID)
+				require.NotEmpty(t, res.Responses[refID].Frames, refID)
+			}
+			require.Contains(t, slices.Concat(requestedTargets...), `aliasSub(sumSeries(app.*.requests.count),"(^.*$)","\1 B")`)
+			require.Len(t, slices.Concat(requestedTargets...), 2)
+		})
+	}
+}
+
 func fp(f float64) *float64 {
 	return &f
 }
@@ -1,22 +1,350 @@
 package sql
 
 import (
+	"context"
+	dbsql "database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/mattn/go-sqlite3"
 )
 
+const (
+	driverName = "sqlite3_sql_expressions"
+
+	// pageSize is the page size of the in-memory database, used to turn
+	// the memory limit into a page count.
+	pageSize = 4096
+
+	// sqliteRecursive is the authorizer action code for recursive CTEs,
+	// which is not exported by the sqlite3 driver.
+	sqliteRecursive = 33
+)
+
+const (
+	// DefaultMaxInputRows is the default maximum number of rows that can be loaded into the database
+	// across all input tables.
+	DefaultMaxInputRows = 100_000
+	// DefaultMaxOutputRows is the default maximum number of rows a query may return.
+	DefaultMaxOutputRows = 100_000
+	// DefaultMaxMemoryBytes is the default maximum size of the in-memory database and of the returned frame.
+	DefaultMaxMemoryBytes = 256 * 1024 * 1024
+	// DefaultTimeout is the default maximum duration of a single query.
+	DefaultTimeout = 10 * time.Second
+)
+
+var (
+	// ErrInputRowLimit is returned when the input frames contain more rows than allowed.
+	ErrInputRowLimit = errors.New("input row limit exceeded")
+	// ErrOutputRowLimit is returned when the query returns more rows than allowed.
+	ErrOutputRowLimit = errors.New("output row limit exceeded")
+	// ErrMemoryLimit is returned when the database or the result exceeds the memory limit.
+	ErrMemoryLimit = errors.New("memory limit exceeded")
+	// ErrTimeout is returned when the query does not complete in time.
+	ErrTimeout = errors.New("query timed out")
+	// ErrNotAllowed is returned when the query tries to do anything other than reading the input tables.
+	ErrNotAllowed = errors.New("only SELECT statements over the input tables are allowed")
+)
+
+// Limits bounds the resources a single SQL expression may use.
+// A zero or negative value disables the corresponding limit.
+type Limits struct {
+	MaxInputRows   int64
+	MaxOutputRows  int64
+	MaxMemoryBytes int64
+	Timeout        time.Duration
+}
+
+// DefaultLimits returns the limits used when none are configured.
+func DefaultLimits() Limits {
+	return Limits{
+		MaxInputRows:   DefaultMaxInputRows,
+		MaxOutputRows:  DefaultMaxOutputRows,
+		MaxMemoryBytes: DefaultMaxMemoryBytes,
+		Timeout:        DefaultTimeout,
+	}
+}
+
+var registerDriver sync.Once
+
+// DB is an embedded, in-memory SQL engine (SQLite) that loads data frames as tables.
+// Each DB is independent and should be used for a single query.
 type DB struct {
+	limits Limits
 }
 
+// NewInMemoryDB creates a DB with the default limits.
+func NewInMemoryDB() *DB {
+	return &DB{limits: DefaultLimits()}
+}
+
+// NewInMemoryDBWithLimits creates a DB that enforces the given limits.
+func NewInMemoryDBWithLimits(limits Limits) *DB {
+	return &DB{limits: limits}
+}
+
+// RunCommands executes the commands in order against an empty database and returns the rows
+// of the last command as a JSON array of objects.
 func (db *DB) RunCommands(commands []string) (string, error) {
-	return "", errors.New("not implemented")
+	ctx, cancel := db.context(context.Background())
+	defer cancel()
+
+	conn, closeFn, err := db.open(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer closeFn()
+
+	if err := db.restrict(ctx, conn); err != nil {
+		return "", err
+	}
+
+	result := []map[string]any{}
+	for _, cmd := range commands {
+		rows, err := conn.QueryContext(ctx, cmd)
+		if err != nil {
+			return "", db.wrapError(ctx, err)
+		}
+		columns, values, err := db.readRows(rows)
+		if err != nil {
+			return "", db.wrapError(ctx, err)
+		}
+		result = make([]map[string]any, 0, len(values))
+		for _, row := range values {
+			obj := make(map[string]any, len(columns))
+			for i, c := range columns {
+				obj[c.name] = row[i]
+			}
+			result = append(result, obj)
+		}
+	}
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
 }
 
-func (db *DB) QueryFramesInto(name string, query string, frames []*data.Frame, f *data.Frame) error {
-	return errors.New("not implemented")
+// QueryFramesInto loads the frames as tables named after their RefID, runs the query and
+// writes the resulting fields into f, which is named after name.
+func (db *DB) QueryFramesInto(ctx context.Context, name string, query string, frames []*data.Frame, f *data.Frame) error {
+	ctx, cancel := db.context(ctx)
+	defer cancel()
+
+	tables, err := framesToTables(frames)
+	if err != nil {
+		return err
+	}
+
+	if db.limits.MaxInputRows > 0 {
+		var total int64
+		for _, t := range tables {
+			total += int64(len(t.rows))
+		}
+		if total > db.limits.MaxInputRows {
+			return fmt.Errorf("%w: the input tables have %d rows, the limit is %d", ErrInputRowLimit, total, db.limits.MaxInputRows)
+		}
+	}
+
+	conn, closeFn, err := db.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	for _, t := range tables {
+		if err := t.load(ctx, conn); err != nil {
+			return db.wrapError(ctx, fmt.Errorf("failed to load table %q: %w", t.name, err))
+		}
+	}
+
+	if err := db.restrict(ctx, conn); err != nil {
+		return err
+	}
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return db.wrapError(ctx, err)
+	}
+	columns, values, err := db.readRows(rows)
+	if err != nil {
+		return db.wrapError(ctx, err)
+	}
+
+	f.Name = name
+	f.Fields = rowsToFields(columns, values)
+	return nil
 }
 
-func NewInMemoryDB() *DB {
-	return &DB{}
+func (db *DB) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if db.limits.Timeout > 0 {
+		return context.WithTimeout(ctx, db.limits.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// open creates a private in-memory database and returns its only connection.
+func (db *DB) open(ctx context.Context) (*dbsql.Conn, func(), error) {
+	registerDriver.Do(func() {
+		dbsql.Register(driverName, &sqlite3.SQLiteDriver{ConnectHook: connectHook})
+	})
+
+	sqlDB, err := dbsql.Open(driverName, ":memory:")
+	if err != nil {
+		return nil, nil, err
+	}
+	// Every connection to ":memory:" is a different database, so there must be exactly one.
+	sqlDB.SetMaxOpenConns(1)
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		_ = sqlDB.Close()
+		return nil, nil, err
+	}
+
+	pragmas := []string{fmt.Sprintf("PRAGMA page_size = %d", pageSize)}
+	if db.limits.MaxMemoryBytes > 0 {
+		pages := db.limits.MaxMemoryBytes / pageSize
+		if pages < 1 {
+			pages = 1
+		}
+		pragmas = append(pragmas, fmt.Sprintf("PRAGMA max_page_count = %d", pages))
+	}
+	for _, p := range pragmas {
+		if _, err := conn.ExecContext(ctx, p); err != nil {
+			_ = conn.Close()
+			_ = sqlDB.Close()
+			return nil, nil, err
+		}
+	}
+
+	return conn, func() {
+		_ = conn.Close()
+		_ = sqlDB.Close()
+	}, nil
+}
+
+// restrict installs an authorizer so that only read-only statements can run from now on.
+func (db *DB) restrict(ctx context.Context, conn *dbsql.Conn) error {
+	if _, err := conn.ExecContext(ctx, "PRAGMA query_only = 1"); err != nil {
+		return err
+	}
+	return conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection type %T", driverConn)
+		}
+		c.RegisterAuthorizer(func(action int, _, _, _ string) int {
+			switch action {
+			case sqlite3.SQLITE_SELECT, sqlite3.SQLITE_READ, sqlite3.SQLITE_FUNCTION, sqliteRecursive:
+				return sqlite3.SQLITE_OK
+			default:
+				return sqlite3.SQLITE_DENY
+			}
+		})
+		return nil
+	})
+}
+
+// readRows reads all rows, enforcing the output row and memory limits, and closes rows.
+func (db *DB) readRows(rows *dbsql.Rows) ([]column, [][]any, error) {
+	defer func() { _ = rows.Close() }()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, nil, err
+	}
+	columns := make([]column, len(types))
+	for i, t := range types {
+		columns[i] = column{name: t.Name()}
+	}
+
+	var (
+		values [][]any
+		size   int64
+	)
+	for rows.Next() {
+		if db.limits.MaxOutputRows > 0 && int64(len(values)) >= db.limits.MaxOutputRows {
+			return nil, nil, fmt.Errorf("%w: the query returned more than %d rows", ErrOutputRowLimit, db.limits.MaxOutputRows)
+		}
+		row := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range row {
+			ptrs[i] = &row[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, nil, err
+		}
+		for _, v := range row {
+			size += valueSize(v)
+		}
+		if db.limits.MaxMemoryBytes > 0 && size > db.limits.MaxMemoryBytes {
+			return nil, nil, fmt.Errorf("%w: the query result is larger than %d bytes", ErrMemoryLimit, db.limits.MaxMemoryBytes)
+		}
+		values = append(values, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	return columns, values, nil
+}
+
+// wrapError turns engine errors caused by the limits into the matching sentinel errors.
+func (db *DB) wrapError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %s", ErrTimeout, db.limits.Timeout)
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code {
+		case sqlite3.ErrFull:
+			return fmt.Errorf("%w: the input tables are larger than %d bytes", ErrMemoryLimit, db.limits.MaxMemoryBytes)
+		case sqlite3.ErrAuth:
+			return fmt.Errorf("%w: %s", ErrNotAllowed, sqliteErr.Error())
+		case sqlite3.ErrReadonly:
+			return ErrNotAllowed
+		}
+	}
+	return err
+}
+
+// connectHook registers a few math functions that are not compiled into the sqlite3 driver by default
+// and prevents attaching other databases.
+func connectHook(conn *sqlite3.SQLiteConn) error {
+	conn.SetLimit(sqlite3.SQLITE_LIMIT_ATTACHED, 0)
+
+	funcs := map[string]any{
+		"sqrt":  math.Sqrt,
+		"pow":   math.Pow,
+		"power": math.Pow,
+		"exp":   math.Exp,
+		"ln":    math.Log,
+		"log10": math.Log10,
+		"log2":  math.Log2,
+		"floor": math.Floor,
+		"ceil":  math.Ceil,
+	}
+	for name, fn := range funcs {
+		if err := conn.RegisterFunc(name, fn, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func valueSize(v any) int64 {
+	switch v := v.(type) {
+	case string:
+		return int64(len(v))
+	case []byte:
+		return int64(len(v))
+	default:
+		return 8
+	}
 }
@@ -0,0 +1,117 @@
+package sql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryFramesInto(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	a := data.NewFrame("",
+		data.NewField("time", nil, []time.Time{t0, t0.Add(time.Minute), t0.Add(2 * time.Minute)}),
+		data.NewField("host", nil, []string{"a", "b", "a"}),
+		data.NewField("cpu", nil, []float64{1, 2, 3}),
+	)
+	a.RefID = "A"
+	b := data.NewFrame("",
+		data.NewField("host", nil, []string{"a", "b"}),
+		data.NewField("team", nil, []string{"x", "y"}),
+	)
+	b.RefID = "B"
+
+	t.Run("join and group by", func(t *testing.T) {
+		out := &data.Frame{}
+		err := NewInMemoryDB().QueryFramesInto(context.Background(), "C", `
+			SELECT B.team, sum(A.cpu) AS total, count(*) AS n
+			FROM A JOIN B ON A.host = B.host
+			GROUP BY B.team
+			ORDER BY B.team`, []*data.Frame{a, b}, out)
+		require.NoError(t, err)
+
+		require.Equal(t, "C", out.Name)
+		require.Len(t, out.Fields, 3)
+		require.Equal(t, 2, out.Rows())
+		require.Equal(t, data.FieldTypeNullableString, out.Fields[0].Type())
+		require.Equal(t, data.FieldTypeNullableFloat64, out.Fields[1].Type())
+		require.Equal(t, data.FieldTypeNullableInt64, out.Fields[2].Type())
+		total, _ := out.Fields[1].ConcreteAt(0)
+		require.Equal(t, 4.0, total)
+	})
+
+	t.Run("window functions and time columns", func(t *testing.T) {
+		out := &data.Frame{}
+		err := NewInMemoryDB().QueryFramesInto(context.Background(), "C", `
+			SELECT time, sum(cpu) OVER (ORDER BY time) AS running
+			FROM A ORDER BY time`, []*data.Frame{a}, out)
+		require.NoError(t, err)
+
+		require.Equal(t, data.FieldTypeNullableTime, out.Fields[0].Type())
+		ts, _ := out.Fields[0].ConcreteAt(1)
+		require.Equal(t, t0.Add(time.Minute), ts)
+		running, _ := out.Fields[1].ConcreteAt(2)
+		require.Equal(t, 6.0, running)
+	})
+
+	t.Run("series labels become columns", func(t *testing.T) {
+		s1 := data.NewFrame("",
+			data.NewField("time", nil, []time.Time{t0}),
+			data.NewField("value", data.Labels{"host": "a"}, []float64{1}),
+		)
+		s1.RefID = "S"
+		s2 := data.NewFrame("",
+			data.NewField("time", nil, []time.Time{t0}),
+			data.NewField("value", data.Labels{"host": "b"}, []float64{2}),
+		)
+		s2.RefID = "S"
+
+		out := &data.Frame{}
+		err := NewInMemoryDB().QueryFramesInto(context.Background(), "C", `SELECT host, value FROM S ORDER BY host`, []*data.Frame{s1, s2}, out)
+		require.NoError(t, err)
+		require.Equal(t, 2, out.Rows())
+		host, _ := out.Fields[0].ConcreteAt(1)
+		require.Equal(t, "b", host)
+	})
+
+	t.Run("writes are not allowed", func(t *testing.T) {
+		for _, q := range []string{
+			`DELETE FROM A`,
+			`INSERT INTO A (cpu) VALUES (1)`,
+			`ATTACH DATABASE 'file.db' AS other`,
+			`PRAGMA query_only = 0`,
+		} {
+			err := NewInMemoryDB().QueryFramesInto(context.Background(), "C", q, []*data.Frame{a}, &data.Frame{})
+			require.Error(t, err, q)
+		}
+	})
+
+	t.Run("input row limit", func(t *testing.T) {
+		db := NewInMemoryDBWithLimits(Limits{MaxInputRows: 2})
+		err := db.QueryFramesInto(context.Background(), "C", `SELECT * FROM A`, []*data.Frame{a}, &data.Frame{})
+		require.ErrorIs(t, err, ErrInputRowLimit)
+	})
+
+	t.Run("output row limit", func(t *testing.T) {
+		db := NewInMemoryDBWithLimits(Limits{MaxOutputRows: 2})
+		err := db.QueryFramesInto(context.Background(), "C", `SELECT * FROM A`, []*data.Frame{a}, &data.Frame{})
+		require.ErrorIs(t, err, ErrOutputRowLimit)
+	})
+
+	t.Run("memory limit", func(t *testing.T) {
+		db := NewInMemoryDBWithLimits(Limits{MaxMemoryBytes: 64})
+		err := db.QueryFramesInto(context.Background(), "C", `SELECT cpu, host, time FROM A`, []*data.Frame{a}, &data.Frame{})
+		require.ErrorIs(t, err, ErrMemoryLimit)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		db := NewInMemoryDBWithLimits(Limits{Timeout: 50 * time.Millisecond})
+		err := db.QueryFramesInto(context.Background(), "C", `
+			WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n)
+			SELECT count(*) FROM n`, []*data.Frame{a}, &data.Frame{})
+		require.ErrorIs(t, err, ErrTimeout)
+	})
+}
@@ -0,0 +1,322 @@
+package sql
+
+import (
+	"context"
+	dbsql "database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqlType is the storage class used for a column of an input table.
+type sqlType string
+
+const (
+	typeInteger   sqlType = "INTEGER"
+	typeReal      sqlType = "REAL"
+	typeText      sqlType = "TEXT"
+	typeBoolean   sqlType = "BOOLEAN"
+	typeTimestamp sqlType = "TIMESTAMP"
+)
+
+// table is an input table built from one or more frames that share a RefID.
+type table struct {
+	name    string
+	columns []string
+	types   []sqlType
+	rows    [][]any
+}
+
+// column describes a column of a query result.
+type column struct {
+	name string
+}
+
+// framesToTables groups frames by RefID and turns each group into a table. Labels of fields
+// become text columns so that series with different labels end up as rows of the same table.
+func framesToTables(frames []*data.Frame) ([]*table, error) {
+	tables := []*table{}
+	byName := map[string]*table{}
+	for _, f := range frames {
+		if f == nil {
+			continue
+		}
+		if f.RefID == "" {
+			return nil, fmt.Errorf("frame %q has no refId and cannot be used as a table", f.Name)
+		}
+		t, ok := byName[f.RefID]
+		if !ok {
+			t = &table{name: f.RefID}
+			byName[f.RefID] = t
+			tables = append(tables, t)
+		}
+		if err := t.appendFrame(f); err != nil {
+			return nil, fmt.Errorf("failed to convert frame for table %q: %w", f.RefID, err)
+		}
+	}
+	return tables, nil
+}
+
+func (t *table) columnIndex(name string, typ sqlType) (int, error) {
+	for i, c := range t.columns {
+		if !strings.EqualFold(c, name) {
+			continue
+		}
+		if t.types[i] != typ {
+			return 0, fmt.Errorf("column %q has type %s in one frame and %s in another", name, t.types[i], typ)
+		}
+		return i, nil
+	}
+	t.columns = append(t.columns, name)
+	t.types = append(t.types, typ)
+	for i := range t.rows {
+		t.rows[i] = append(t.rows[i], nil)
+	}
+	return len(t.columns) - 1, nil
+}
+
+func (t *table) appendFrame(f *data.Frame) error {
+	rowLen, err := f.RowLen()
+	if err != nil {
+		return err
+	}
+
+	labels := data.Labels{}
+	for _, field := range f.Fields {
+		for k, v := range field.Labels {
+			labels[k] = v
+		}
+	}
+	labelKeys := make([]string, 0, len(labels))
+	for k := range labels {
+		labelKeys = append(labelKeys, k)
+	}
+	sort.Strings(labelKeys)
+
+	fieldIdx := make([]int, len(f.Fields))
+	seen := map[string]int{}
+	for i, field := range f.Fields {
+		name := field.Name
+		if name == "" {
+			name = "value"
+		}
+		key := strings.ToLower(name)
+		if n := seen[key]; n > 0 {
+			name = fmt.Sprintf("%s_%d", name, n)
+		}
+		seen[key]++
+		typ, err := fieldSQLType(field.Type())
+		if err != nil {
+			return fmt.Errorf("field %q: %w", name, err)
+		}
+		if fieldIdx[i], err = t.columnIndex(name, typ); err != nil {
+			return err
+		}
+	}
+	labelIdx := make([]int, len(labelKeys))
+	for i, k := range labelKeys {
+		if labelIdx[i], err = t.columnIndex(k, typeText); err != nil {
+			return err
+		}
+	}
+
+	for r := 0; r < rowLen; r++ {
+		row := make([]any, len(t.columns))
+		for i, field := range f.Fields {
+			v, _ := field.ConcreteAt(r)
+			if tm, ok := v.(time.Time); ok {
+				v = tm.UTC()
+			}
+			row[fieldIdx[i]] = v
+		}
+		for i, k := range labelKeys {
+			row[labelIdx[i]] = labels[k]
+		}
+		t.rows = append(t.rows, row)
+	}
+	return nil
+}
+
+// load creates the table and inserts its rows in a single transaction.
+func (t *table) load(ctx context.Context, conn *dbsql.Conn) error {
+	defs := make([]string, len(t.columns))
+	placeholders := make([]string, len(t.columns))
+	for i, c := range t.columns {
+		defs[i] = fmt.Sprintf("%s %s", quoteIdentifier(c), t.types[i])
+		placeholders[i] = "?"
+	}
+	if len(defs) == 0 {
+		return fmt.Errorf("table has no columns")
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s (%s)", quoteIdentifier(t.name), strings.Join(defs, ", "))); err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf("INSERT INTO %s VALUES (%s)", quoteIdentifier(t.name), strings.Join(placeholders, ", ")))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for _, row := range t.rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func fieldSQLType(ft data.FieldType) (sqlType, error) {
+	switch ft.NonNullableType() {
+	case data.FieldTypeInt8, data.FieldTypeInt16, data.FieldTypeInt32, data.FieldTypeInt64,
+		data.FieldTypeUint8, data.FieldTypeUint16, data.FieldTypeUint32, data.FieldTypeUint64:
+		return typeInteger, nil
+	case data.FieldTypeFloat32, data.FieldTypeFloat64:
+		return typeReal, nil
+	case data.FieldTypeString, data.FieldTypeEnum, data.FieldTypeJSON:
+		return typeText, nil
+	case data.FieldTypeBool:
+		return typeBoolean, nil
+	case data.FieldTypeTime:
+		return typeTimestamp, nil
+	default:
+		return "", fmt.Errorf("unsupported field type %s", ft)
+	}
+}
+
+// rowsToFields builds nullable fields from a query result. SQLite is dynamically typed, so the
+// type of each field is inferred from the values returned for its column.
+func rowsToFields(columns []column, rows [][]any) data.Fields {
+	fields := make(data.Fields, len(columns))
+	for i, c := range columns {
+		values := make([]any, len(rows))
+		for r, row := range rows {
+			values[r] = row[i]
+		}
+		fields[i] = valuesToField(c, values)
+	}
+	return fields
+}
+
+func valuesToField(c column, values []any) *data.Field {
+	var hasBool, hasInt, hasFloat, hasText, hasTime, hasBytes bool
+	for _, v := range values {
+		switch v.(type) {
+		case bool:
+			hasBool = true
+		case int64:
+			hasInt = true
+		case float64:
+			hasFloat = true
+		case string:
+			hasText = true
+		case []byte:
+			hasBytes = true
+		case time.Time:
+			hasTime = true
+		}
+	}
+
+	other := hasBytes || hasBool
+	switch {
+	case (hasTime || hasText) && !hasInt && !hasFloat && !other:
+		if times, ok := parseTimes(values); ok {
+			return data.NewField(c.name, nil, times)
+		}
+	case hasBool && !hasInt && !hasFloat && !hasText && !hasTime && !hasBytes:
+		out := make([]*bool, len(values))
+		for i, v := range values {
+			if b, ok := v.(bool); ok {
+				out[i] = &b
+			}
+		}
+		return data.NewField(c.name, nil, out)
+	case hasInt && !hasFloat && !hasText && !hasTime && !other:
+		out := make([]*int64, len(values))
+		for i, v := range values {
+			if n, ok := v.(int64); ok {
+				out[i] = &n
+			}
+		}
+		return data.NewField(c.name, nil, out)
+	case (hasInt || hasFloat) && !hasText && !hasTime && !other:
+		out := make([]*float64, len(values))
+		for i, v := range values {
+			switch n := v.(type) {
+			case int64:
+				f := float64(n)
+				out[i] = &f
+			case float64:
+				out[i] = &n
+			}
+		}
+		return data.NewField(c.name, nil, out)
+	}
+
+	out := make([]*string, len(values))
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		var s string
+		switch v := v.(type) {
+		case string:
+			s = v
+		case []byte:
+			s = string(v)
+		case time.Time:
+			s = v.UTC().Format(time.RFC3339Nano)
+		default:
+			s = fmt.Sprintf("%v", v)
+		}
+		out[i] = &s
+	}
+	return data.NewField(c.name, nil, out)
+}
+
+// parseTimes converts a column to timestamps when every non-null value is either a time
+// or a string in one of the formats the sqlite3 driver writes timestamps in.
+func parseTimes(values []any) ([]*time.Time, bool) {
+	out := make([]*time.Time, len(values))
+	for i, v := range values {
+		switch v := v.(type) {
+		case nil:
+		case time.Time:
+			t := v.UTC()
+			out[i] = &t
+		case string:
+			t, ok := parseTimestamp(v)
+			if !ok {
+				return nil, false
+			}
+			out[i] = &t
+		default:
+			return nil, false
+		}
+	}
+	return out, true
+}
+
+func parseTimestamp(s string) (time.Time, bool) {
+	s = strings.TrimSuffix(s, "Z")
+	for _, format := range sqlite3.SQLiteTimestampFormats {
+		if t, err := time.ParseInLocation(format, s, time.UTC); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
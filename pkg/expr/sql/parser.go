@@ -1,82 +1,296 @@
 package sql
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
 
 	"github.com/grafana/grafana/pkg/infra/log"
-	"github.com/jeremywohl/flatten"
 )
 
+var logger = log.New("sql_expr")
+
+type tokenKind int
+
 const (
-	TABLE_NAME    = "table_name"
-	ERROR         = ".error"
-	ERROR_MESSAGE = ".error_message"
+	tokenKeyword tokenKind = iota // bare word, may be a keyword or an identifier
+	tokenQuotedIdentifier
+	tokenString
+	tokenNumber
+	tokenPunct
 )
 
-var logger = log.New("sql_expr")
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func (t token) is(keyword string) bool {
+	return t.kind == tokenKeyword && strings.EqualFold(t.text, keyword)
+}
+
+func (t token) isPunct(p string) bool {
+	return t.kind == tokenPunct && t.text == p
+}
+
+func (t token) isIdentifier() bool {
+	return t.kind == tokenQuotedIdentifier || (t.kind == tokenKeyword && !reservedWords[strings.ToUpper(t.text)])
+}
+
+// reservedWords are keywords that cannot start a table reference.
+var reservedWords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "HAVING": true, "ORDER": true,
+	"LIMIT": true, "UNION": true, "EXCEPT": true, "INTERSECT": true, "WINDOW": true, "JOIN": true,
+	"ON": true, "USING": true, "WITH": true, "VALUES": true, "AS": true, "LEFT": true, "RIGHT": true,
+	"FULL": true, "INNER": true, "OUTER": true, "CROSS": true, "NATURAL": true, "LATERAL": true,
+}
+
+// fromListTerminators end the list of table references of a FROM clause.
+var fromListTerminators = map[string]bool{
+	"WHERE": true, "GROUP": true, "HAVING": true, "ORDER": true, "LIMIT": true,
+	"UNION": true, "EXCEPT": true, "INTERSECT": true, "WINDOW": true, "SELECT": true,
+}
 
 // TablesList returns a list of tables for the sql statement
 func TablesList(rawSQL string) ([]string, error) {
-	db := NewInMemoryDB()
-	rawSQL = strings.Replace(rawSQL, "'", "''", -1)
-	cmd := fmt.Sprintf("SELECT json_serialize_sql('%s')", rawSQL)
-	ret, err := db.RunCommands([]string{cmd})
+	tokens, err := tokenize(rawSQL)
 	if err != nil {
-		logger.Error("error serializing sql", "error", err.Error(), "sql", rawSQL, "cmd", cmd)
-		return nil, fmt.Errorf("error serializing sql: %s", err.Error())
+		logger.Error("error tokenizing sql", "error", err.Error(), "sql", rawSQL)
+		return nil, fmt.Errorf("error in sql: %w", err)
+	}
+	if err := validateSyntax(rawSQL); err != nil {
+		return nil, err
 	}
 
-	ast := []map[string]any{}
-	err = json.Unmarshal([]byte(ret), &ast)
-	if err != nil {
-		logger.Error("error converting json sql to ast", "error", err.Error(), "ret", ret)
-		return nil, fmt.Errorf("error converting json to ast: %s", err.Error())
+	ctes := cteNames(tokens)
+	tables := []string{}
+	for _, t := range tableReferences(tokens) {
+		if ctes[strings.ToLower(t)] || existsInList(t, tables) {
+			continue
+		}
+		tables = append(tables, t)
 	}
+	sort.Strings(tables)
+
+	logger.Debug("tables found in sql", "tables", tables)
 
-	return tablesFromAST(ast)
+	return tables, nil
 }
 
-// tablesFromAST returns a list of tables from the ast
-func tablesFromAST(ast []map[string]any) ([]string, error) {
-	flat, err := flatten.Flatten(ast[0], "", flatten.DotStyle)
+// validateSyntax asks the engine to compile the statement against an empty database.
+// Errors about missing tables or columns are expected at this point and ignored.
+func validateSyntax(rawSQL string) error {
+	db := NewInMemoryDB()
+	ctx, cancel := db.context(context.Background())
+	defer cancel()
+
+	conn, closeFn, err := db.open(ctx)
 	if err != nil {
-		logger.Error("error flattening ast", "error", err.Error(), "ast", ast)
-		return nil, fmt.Errorf("error flattening ast: %s", err.Error())
+		return err
+	}
+	defer closeFn()
+
+	stmt, err := conn.PrepareContext(ctx, rawSQL)
+	if err == nil {
+		_ = stmt.Close()
+		return nil
 	}
+	msg := err.Error()
+	if strings.Contains(msg, "syntax error") || strings.Contains(msg, "incomplete input") || strings.Contains(msg, "unrecognized token") {
+		logger.Error("error in sql", "error", msg, "sql", rawSQL)
+		return fmt.Errorf("error in sql: %s", msg)
+	}
+	return nil
+}
 
+// tableReferences walks the tokens and returns every name used as a table in a FROM or JOIN clause.
+func tableReferences(tokens []token) []string {
 	tables := []string{}
-	for k, v := range flat {
-		if strings.HasSuffix(k, ERROR) {
-			v, ok := v.(bool)
-			if ok && v {
-				logger.Error("error in sql", "error", k)
-				return nil, astError(k, flat)
+	depth := 0
+	// fromList records, per parenthesis depth, whether we are inside the table list of a FROM clause.
+	fromList := map[int]bool{}
+	expectTable := false
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.isPunct("("):
+			depth++
+			// A parenthesis directly after FROM/JOIN is either a subquery or a nested join.
+			if expectTable && i+1 < len(tokens) && (tokens[i+1].is("SELECT") || tokens[i+1].is("WITH") || tokens[i+1].is("VALUES")) {
+				expectTable = false
+			}
+			if expectTable {
+				fromList[depth] = true
 			}
+		case t.isPunct(")"):
+			delete(fromList, depth)
+			depth--
+			expectTable = false
+		case t.isPunct(";"):
+			fromList = map[int]bool{}
+			expectTable = false
+		case t.isPunct(","):
+			expectTable = fromList[depth]
+		case t.is("FROM"):
+			fromList[depth] = true
+			expectTable = true
+		case t.is("JOIN"):
+			expectTable = true
+		case t.kind == tokenKeyword && fromListTerminators[strings.ToUpper(t.text)]:
+			delete(fromList, depth)
+			expectTable = false
+		case expectTable && t.isIdentifier():
+			expectTable = false
+			name := t.text
+			// schema.table
+			for i+2 < len(tokens) && tokens[i+1].isPunct(".") && tokens[i+2].isIdentifier() {
+				name = tokens[i+2].text
+				i += 2
+			}
+			// table-valued functions such as json_each(...) are not tables
+			if i+1 < len(tokens) && tokens[i+1].isPunct("(") {
+				continue
+			}
+			tables = append(tables, name)
+		default:
+			expectTable = false
+		}
+	}
+	return tables
+}
+
+// cteNames returns the lower-cased names of the common table expressions defined in the statement,
+// which look like "name AS (" or "name(col, ...) AS (".
+func cteNames(tokens []token) map[string]bool {
+	names := map[string]bool{}
+	for i, t := range tokens {
+		if !t.is("AS") {
+			continue
+		}
+		next := i + 1
+		if next < len(tokens) && tokens[next].is("NOT") {
+			next++
+		}
+		if next < len(tokens) && tokens[next].is("MATERIALIZED") {
+			next++
 		}
-		if strings.Contains(k, TABLE_NAME) {
-			table, ok := v.(string)
-			if ok && !existsInList(table, tables) {
-				tables = append(tables, v.(string))
+		if next >= len(tokens) || !tokens[next].isPunct("(") || i == 0 {
+			continue
+		}
+		prev := i - 1
+		if tokens[prev].isPunct(")") {
+			// skip the column list
+			for prev >= 0 && !tokens[prev].isPunct("(") {
+				prev--
 			}
+			prev--
+		}
+		if prev >= 0 && tokens[prev].isIdentifier() {
+			names[strings.ToLower(tokens[prev].text)] = true
 		}
 	}
-	sort.Strings(tables)
+	return names
+}
 
-	logger.Debug("tables found in sql", "tables", tables)
+// tokenize splits a statement into tokens, dropping whitespace and comments. Only the syntax needed
+// to find table references is recognised; validating the statement is left to the engine.
+func tokenize(s string) ([]token, error) {
+	tokens := []token{}
+	statements := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(s[i:], "--"):
+			end := strings.IndexByte(s[i:], '\n')
+			if end < 0 {
+				i = len(s)
+			} else {
+				i += end + 1
+			}
+		case strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return nil, errors.New("unterminated comment")
+			}
+			i += end + 4
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			var b strings.Builder
+			j := i + 1
+			for {
+				if j >= len(s) {
+					return nil, fmt.Errorf("unterminated quote starting at position %d", i)
+				}
+				if s[j] == closing {
+					// a doubled quote is an escaped quote
+					if closing != ']' && j+1 < len(s) && s[j+1] == closing {
+						b.WriteByte(closing)
+						j += 2
+						continue
+					}
+					break
+				}
+				b.WriteByte(s[j])
+				j++
+			}
+			kind := tokenQuotedIdentifier
+			if c == '\'' {
+				kind = tokenString
+			}
+			tokens = append(tokens, token{kind: kind, text: b.String()})
+			i = j + 1
+		case isIdentifierStart(c):
+			j := i + 1
+			for j < len(s) && isIdentifierPart(s[j]) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenKeyword, text: s[i:j]})
+			i = j
+		case c >= '0' && c <= '9':
+			j := i + 1
+			for j < len(s) && (isIdentifierPart(s[j]) || s[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: s[i:j]})
+			i = j
+		default:
+			if c == ';' {
+				statements++
+			}
+			tokens = append(tokens, token{kind: tokenPunct, text: string(c)})
+			i++
+		}
+	}
 
-	return tables, nil
+	// Only a single statement (optionally terminated by a semicolon) is supported.
+	if statements > 0 {
+		last := len(tokens) - 1
+		for last >= 0 && tokens[last].isPunct(";") {
+			last--
+		}
+		for _, t := range tokens[:last+1] {
+			if t.isPunct(";") {
+				return nil, errors.New("only a single statement is supported")
+			}
+		}
+	}
+	return tokens, nil
 }
 
-func astError(k string, flat map[string]any) error {
-	key := strings.Replace(k, ERROR, "", 1)
-	message, ok := flat[key+ERROR_MESSAGE]
-	if !ok {
-		message = "unknown error in sql"
-	}
-	return fmt.Errorf("error in sql: %s", message)
+func isIdentifierStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isIdentifierPart(c byte) bool {
+	return isIdentifierStart(c) || (c >= '0' && c <= '9') || c == '$'
 }
 
 func existsInList(table string, list []string) bool {
@@ -19,6 +19,7 @@ type SQLCommand struct {
 	query       string
 	varsToQuery []string
 	refID       string
+	limits      sql.Limits
 }
 
 // NewSQLCommand creates a new SQLCommand.
@@ -44,6 +45,7 @@ func NewSQLCommand(refID, rawSQL string) (*SQLCommand, error) {
 		query:       rawSQL,
 		varsToQuery: tables,
 		refID:       refID,
+		limits:      sql.DefaultLimits(),
 	}, nil
 }
 
@@ -77,7 +79,7 @@ func (gr *SQLCommand) NeedsVars() []string {
 // Execute runs the command and returns the results or an error if the command
 // failed to execute.
 func (gr *SQLCommand) Execute(ctx context.Context, now time.Time, vars mathexp.Vars, tracer tracing.Tracer) (mathexp.Results, error) {
-	_, span := tracer.Start(ctx, "SSE.ExecuteSQL")
+	ctx, span := tracer.Start(ctx, "SSE.ExecuteSQL")
 	defer span.End()
 
 	allFrames := []*data.Frame{}
@@ -93,14 +95,14 @@ func (gr *SQLCommand) Execute(ctx context.Context, now time.Time, vars mathexp.V
 
 	rsp := mathexp.Results{}
 
-	db := sql.NewInMemoryDB()
+	db := sql.NewInMemoryDBWithLimits(gr.limits)
 	var frame = &data.Frame{}
 
 	logger.Debug("Executing query", "query", gr.query, "frames", len(allFrames))
-	err := db.QueryFramesInto(gr.refID, gr.query, allFrames, frame)
+	err := db.QueryFramesInto(ctx, gr.refID, gr.query, allFrames, frame)
 	if err != nil {
 		logger.Error("Failed to query frames", "error", err.Error())
-		rsp.Error = err
+		rsp.Error = MakeSQLError(gr.refID, err)
 		return rsp, nil
 	}
 	logger.Debug("Done Executing query", "query", gr.query, "rows", frame.Rows())
@@ -111,6 +113,7 @@ func (gr *SQLCommand) Execute(ctx context.Context, now time.Time, vars mathexp.V
 		rsp.Values = mathexp.Values{
 			mathexp.NoData{Frame: frame},
 		}
+		return rsp, nil
 	}
 
 	rsp.Values = mathexp.Values{
@@ -120,6 +123,11 @@ func (gr *SQLCommand) Execute(ctx context.Context, now time.Time, vars mathexp.V
 	return rsp, nil
 }
 
+// SetLimits overrides the resource limits used when the query is executed.
+func (gr *SQLCommand) SetLimits(limits sql.Limits) {
+	gr.limits = limits
+}
+
 func (gr *SQLCommand) Type() string {
 	return TypeSQL.String()
 }
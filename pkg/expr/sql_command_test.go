@@ -1,12 +1,20 @@
 package expr
 
 import (
+	"context"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/expr/mathexp"
+	"github.com/grafana/grafana/pkg/expr/sql"
+	"github.com/grafana/grafana/pkg/infra/tracing"
 )
 
 func TestNewCommand(t *testing.T) {
-	t.Skip()
 	cmd, err := NewSQLCommand("a", "select a from foo, bar")
 	if err != nil && strings.Contains(err.Error(), "feature is not enabled") {
 		return
@@ -25,3 +33,48 @@ func TestNewCommand(t *testing.T) {
 		return
 	}
 }
+
+func TestSQLCommandExecute(t *testing.T) {
+	frame := data.NewFrame("",
+		data.NewField("host", nil, []string{"a", "b"}),
+		data.NewField("value", nil, []float64{1, 2}),
+	)
+	vars := mathexp.Vars{
+		"A": mathexp.Results{Values: mathexp.Values{mathexp.TableData{Frame: frame}}},
+	}
+
+	t.Run("returns a table", func(t *testing.T) {
+		cmd, err := NewSQLCommand("B", "SELECT host, value * 2 AS doubled FROM A WHERE value > 1")
+		require.NoError(t, err)
+		require.Equal(t, []string{"A"}, cmd.NeedsVars())
+
+		res, err := cmd.Execute(context.Background(), time.Now(), vars, tracing.InitializeTracerForTest())
+		require.NoError(t, err)
+		require.NoError(t, res.Error)
+		require.Len(t, res.Values, 1)
+		table, ok := res.Values[0].(mathexp.TableData)
+		require.True(t, ok)
+		require.Equal(t, "B", table.Frame.RefID)
+		require.Equal(t, 1, table.Frame.Rows())
+	})
+
+	t.Run("returns no data when there are no rows", func(t *testing.T) {
+		cmd, err := NewSQLCommand("B", "SELECT * FROM A WHERE value > 10")
+		require.NoError(t, err)
+
+		res, err := cmd.Execute(context.Background(), time.Now(), vars, tracing.InitializeTracerForTest())
+		require.NoError(t, err)
+		require.Len(t, res.Values, 1)
+		require.IsType(t, mathexp.NoData{}, res.Values[0])
+	})
+
+	t.Run("reports limit errors", func(t *testing.T) {
+		cmd, err := NewSQLCommand("B", "SELECT * FROM A")
+		require.NoError(t, err)
+		cmd.SetLimits(sql.Limits{MaxOutputRows: 1})
+
+		res, err := cmd.Execute(context.Background(), time.Now(), vars, tracing.InitializeTracerForTest())
+		require.NoError(t, err)
+		require.ErrorIs(t, res.Error, sql.ErrOutputRowLimit)
+	})
+}
@@ -187,3 +187,56 @@ type FileStorage interface {
 
 	close() error
 }
+
+var (
+	ErrVersionNotFound = errors.New("file version not found")
+	ErrNotInTrash      = errors.New("file is not in the trash")
+)
+
+// FileVersion is a version of a file kept by a VersionedFileStorage.
+type FileVersion struct {
+	// Version identifies the version of the file. Versions sort in the order they were created.
+	Version  string
+	FullPath string
+	MimeType string
+	Size     int64
+	// ContentsHash is the SHA-256 hash of the contents. Versions with the same hash share their contents.
+	ContentsHash string
+	Properties   map[string]string
+	Created      time.Time
+}
+
+// TrashedFile is a deleted file kept in the trash of a VersionedFileStorage, with its last version.
+type TrashedFile struct {
+	FileVersion
+	Deleted time.Time
+}
+
+type VersioningOptions struct {
+	// MaxVersions is the number of versions kept for each file. All versions are kept if it is 0.
+	MaxVersions int
+	// TrashRetention is the duration deleted files are kept in the trash. Deleted files are kept until
+	// they are restored if it is 0.
+	TrashRetention time.Duration
+}
+
+// VersionedFileStorage is a FileStorage keeping the versions of the files. Deleted files are moved to a trash,
+// from which they can be restored.
+type VersionedFileStorage interface {
+	FileStorage
+
+	// ListVersions lists the versions of a file, the most recent first.
+	ListVersions(ctx context.Context, path string) ([]*FileVersion, error)
+	// GetVersion returns a version of a file with its contents.
+	GetVersion(ctx context.Context, path string, version string) (*File, bool, error)
+	// RestoreVersion makes a version the current version of a file. The restored file is saved as a new version.
+	RestoreVersion(ctx context.Context, path string, version string) error
+
+	// ListTrash lists the deleted files of a folder and its subfolders.
+	ListTrash(ctx context.Context, folderPath string) ([]*TrashedFile, error)
+	// RestoreFromTrash restores a deleted file with its last version.
+	RestoreFromTrash(ctx context.Context, path string) error
+	// PurgeTrash permanently deletes the files kept in the trash for longer than the trash retention, and returns
+	// the number of deleted files.
+	PurgeTrash(ctx context.Context) (int, error)
+}
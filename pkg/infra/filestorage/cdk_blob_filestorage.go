@@ -253,6 +253,10 @@ func (c cdkBlobStorage) DeleteFolder(ctx context.Context, folderPath string, opt
 
 		path := obj.Key
 		lowerPath := strings.ToLower(path)
+		if isHistoryKey(lowerPath) {
+			continue
+		}
+
 		if obj.IsDir {
 			iterators = append([]*blob.ListIterator{c.bucket.List(&blob.ListOptions{
 				Prefix:    lowerPath,
@@ -318,6 +322,10 @@ func (c cdkBlobStorage) list(ctx context.Context, folderPath string, paging *Pag
 
 		path := obj.Key
 		lowerPath := strings.ToLower(path)
+		if isHistoryKey(lowerPath) {
+			continue
+		}
+
 		allowed := options.Filter.IsAllowed(lowerPath)
 
 		if obj.IsDir && recursive && !visitedFolders[lowerPath] {
@@ -0,0 +1,298 @@
+package filestorage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"gocloud.dev/blob"
+	"gocloud.dev/gcerrors"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+const (
+	// historyFolder holds the versions and the trash at the root of the bucket. It is hidden from the storage.
+	historyFolder = ".___gf_history___"
+
+	// contentsGracePeriod protects the contents written by a version being added from the garbage collection.
+	contentsGracePeriod = time.Hour
+)
+
+// cdkBlobHistory keeps the versions in the bucket of the storage:
+//   - contents/<hash> holds the contents, shared by all the versions with the same contents hash
+//   - versions/<path>/<version> holds a JSON encoded FileVersion
+//   - trash/<path> holds a JSON encoded TrashedFile
+type cdkBlobHistory struct {
+	log    log.Logger
+	bucket *blob.Bucket
+	now    func() time.Time
+}
+
+// NewVersionedCdkBlobStorage returns a storage like NewCdkBlobStorage, keeping the versions and the deleted files
+// in the bucket.
+func NewVersionedCdkBlobStorage(log log.Logger, bucket *blob.Bucket, rootFolder string, filter PathFilter, opts VersioningOptions) VersionedFileStorage {
+	return newVersionedWrapper(log, &cdkBlobStorage{
+		log:    log,
+		bucket: bucket,
+	}, &cdkBlobHistory{
+		log:    log,
+		bucket: bucket,
+		now:    time.Now,
+	}, filter, rootFolder, opts)
+}
+
+func isHistoryKey(lowerPath string) bool {
+	return strings.HasPrefix(lowerPath, historyFolder+Delimiter)
+}
+
+func contentsKey(contentsHash string) string {
+	return historyFolder + "/contents/" + contentsHash
+}
+
+func versionsPrefix(path string) string {
+	return historyFolder + "/versions/" + url.PathEscape(strings.ToLower(path)) + Delimiter
+}
+
+func trashKey(path string) string {
+	return historyFolder + "/trash/" + url.PathEscape(strings.ToLower(path))
+}
+
+func (h cdkBlobHistory) readJSON(ctx context.Context, key string, v any) (bool, error) {
+	data, err := h.bucket.ReadAll(ctx, key)
+	if err != nil {
+		if gcerrors.Code(err) == gcerrors.NotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, json.Unmarshal(data, v)
+}
+
+func (h cdkBlobHistory) writeJSON(ctx context.Context, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return h.bucket.WriteAll(ctx, key, data, &blob.WriterOptions{ContentType: "application/json"})
+}
+
+func (h cdkBlobHistory) delete(ctx context.Context, key string) error {
+	if err := h.bucket.Delete(ctx, key); err != nil && gcerrors.Code(err) != gcerrors.NotFound {
+		return err
+	}
+	return nil
+}
+
+// listKeys lists the keys with a prefix in lexicographical order.
+func (h cdkBlobHistory) listKeys(ctx context.Context, prefix string) ([]*blob.ListObject, error) {
+	var objects []*blob.ListObject
+	iter := h.bucket.List(&blob.ListOptions{Prefix: prefix})
+	for {
+		obj, err := iter.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, obj)
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+	return objects, nil
+}
+
+func (h cdkBlobHistory) latestVersion(ctx context.Context, path string) (*FileVersion, error) {
+	objects, err := h.listKeys(ctx, versionsPrefix(path))
+	if err != nil || len(objects) == 0 {
+		return nil, err
+	}
+
+	version := &FileVersion{}
+	found, err := h.readJSON(ctx, objects[len(objects)-1].Key, version)
+	if err != nil || !found {
+		return nil, err
+	}
+	return version, nil
+}
+
+func (h cdkBlobHistory) addVersion(ctx context.Context, version *FileVersion, contents []byte, maxVersions int) error {
+	// the contents are written even if they exist, so that the garbage collection doesn't delete them
+	if err := h.bucket.WriteAll(ctx, contentsKey(version.ContentsHash), contents, nil); err != nil {
+		return err
+	}
+
+	prefix := versionsPrefix(version.FullPath)
+	if err := h.writeJSON(ctx, prefix+version.Version, version); err != nil {
+		return err
+	}
+
+	if maxVersions <= 0 {
+		return nil
+	}
+
+	objects, err := h.listKeys(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(objects)-maxVersions; i++ {
+		if err := h.delete(ctx, objects[i].Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h cdkBlobHistory) listVersions(ctx context.Context, path string) ([]*FileVersion, error) {
+	objects, err := h.listKeys(ctx, versionsPrefix(path))
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]*FileVersion, 0, len(objects))
+	for i := len(objects) - 1; i >= 0; i-- {
+		version := &FileVersion{}
+		found, err := h.readJSON(ctx, objects[i].Key, version)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			versions = append(versions, version)
+		}
+	}
+	return versions, nil
+}
+
+func (h cdkBlobHistory) getVersion(ctx context.Context, path string, version string) (*FileVersion, []byte, error) {
+	v := &FileVersion{}
+	found, err := h.readJSON(ctx, versionsPrefix(path)+url.PathEscape(version), v)
+	if err != nil || !found {
+		return nil, nil, err
+	}
+
+	contents, err := h.bucket.ReadAll(ctx, contentsKey(v.ContentsHash))
+	if err != nil {
+		return nil, nil, err
+	}
+	return v, contents, nil
+}
+
+func (h cdkBlobHistory) trash(ctx context.Context, path string, deleted time.Time) error {
+	latest, err := h.latestVersion(ctx, path)
+	if err != nil || latest == nil {
+		return err
+	}
+	return h.writeJSON(ctx, trashKey(path), &TrashedFile{FileVersion: *latest, Deleted: deleted})
+}
+
+func (h cdkBlobHistory) untrash(ctx context.Context, path string) error {
+	return h.delete(ctx, trashKey(path))
+}
+
+func (h cdkBlobHistory) getTrashed(ctx context.Context, path string) (*TrashedFile, error) {
+	trashed := &TrashedFile{}
+	found, err := h.readJSON(ctx, trashKey(path), trashed)
+	if err != nil || !found {
+		return nil, err
+	}
+	return trashed, nil
+}
+
+func (h cdkBlobHistory) listTrash(ctx context.Context, folderPrefix string) ([]*TrashedFile, error) {
+	objects, err := h.listKeys(ctx, historyFolder+"/trash/")
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]*TrashedFile, 0)
+	for _, obj := range objects {
+		trashed := &TrashedFile{}
+		found, err := h.readJSON(ctx, obj.Key, trashed)
+		if err != nil {
+			return nil, err
+		}
+		if found && hasPathPrefix(trashed.FullPath, folderPrefix) {
+			files = append(files, trashed)
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].FullPath < files[j].FullPath
+	})
+	return files, nil
+}
+
+func (h cdkBlobHistory) purge(ctx context.Context, folderPrefix string, deletedBefore time.Time) (int, error) {
+	trashed, err := h.listTrash(ctx, folderPrefix)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, f := range trashed {
+		if !f.Deleted.Before(deletedBefore) {
+			continue
+		}
+
+		objects, err := h.listKeys(ctx, versionsPrefix(f.FullPath))
+		if err != nil {
+			return purged, err
+		}
+		for _, obj := range objects {
+			if err := h.delete(ctx, obj.Key); err != nil {
+				return purged, err
+			}
+		}
+		if err := h.delete(ctx, trashKey(f.FullPath)); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	// contents of the versions deleted above the maximum number of versions are collected here too
+	return purged, h.collectContents(ctx)
+}
+
+// collectContents deletes the contents no longer referenced by any version.
+func (h cdkBlobHistory) collectContents(ctx context.Context) error {
+	collectBefore := h.now().Add(-contentsGracePeriod)
+
+	versions, err := h.listKeys(ctx, historyFolder+"/versions/")
+	if err != nil {
+		return err
+	}
+
+	referenced := make(map[string]bool)
+	for _, obj := range versions {
+		version := &FileVersion{}
+		found, err := h.readJSON(ctx, obj.Key, version)
+		if err != nil {
+			return err
+		}
+		if found {
+			referenced[contentsKey(version.ContentsHash)] = true
+		}
+	}
+
+	contents, err := h.listKeys(ctx, historyFolder+"/contents/")
+	if err != nil {
+		return err
+	}
+	for _, obj := range contents {
+		if referenced[obj.Key] || !obj.ModTime.Before(collectBefore) {
+			continue
+		}
+		if err := h.delete(ctx, obj.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
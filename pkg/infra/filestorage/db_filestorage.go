@@ -231,7 +231,7 @@ func (s dbFileStorage) Upsert(ctx context.Context, cmd *UpsertFileCommand) error
 			}
 		}
 
-		if len(cmd.Properties) != 0 {
+		if cmd.Properties != nil {
 			if err = upsertProperties(s.db.GetDialect(), sess, now, cmd, pathHash); err != nil {
 				if rollbackErr := sess.Rollback(); rollbackErr != nil {
 					s.log.Error("Failed while rolling back upsert", "path", cmd.Path)
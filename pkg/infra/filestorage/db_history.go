@@ -0,0 +1,348 @@
+package filestorage
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+type fileVersion struct {
+	Id           int64      `xorm:"pk autoincr 'id'"`
+	Path         string     `xorm:"path"`
+	PathHash     string     `xorm:"path_hash"`
+	Version      string     `xorm:"'version'"`
+	ContentsHash string     `xorm:"contents_hash"`
+	Size         int64      `xorm:"size"`
+	MimeType     string     `xorm:"mime_type"`
+	Properties   string     `xorm:"properties"`
+	Created      time.Time  `xorm:"created"`
+	Trashed      *time.Time `xorm:"trashed"`
+}
+
+// fileContent holds the contents of the versions, shared by all the versions with the same contents hash.
+type fileContent struct {
+	Id           int64     `xorm:"pk autoincr 'id'"`
+	ContentsHash string    `xorm:"contents_hash"`
+	Contents     []byte    `xorm:"contents"`
+	Size         int64     `xorm:"size"`
+	Created      time.Time `xorm:"created"`
+}
+
+// dbHistory keeps the versions in the file_version table, and their contents in the file_content table.
+type dbHistory struct {
+	db  db.DB
+	log log.Logger
+}
+
+// NewVersionedDbStorage returns a storage like NewDbStorage, keeping the versions and the deleted files in the
+// database.
+func NewVersionedDbStorage(log log.Logger, db db.DB, filter PathFilter, rootFolder string, opts VersioningOptions) VersionedFileStorage {
+	return newVersionedWrapper(log, &dbFileStorage{
+		log: log,
+		db:  db,
+	}, &dbHistory{
+		log: log,
+		db:  db,
+	}, filter, rootFolder, opts)
+}
+
+func (v *fileVersion) toFileVersion() (*FileVersion, error) {
+	props := make(map[string]string)
+	if v.Properties != "" {
+		if err := json.Unmarshal([]byte(v.Properties), &props); err != nil {
+			return nil, err
+		}
+	}
+
+	return &FileVersion{
+		Version:      v.Version,
+		FullPath:     v.Path,
+		MimeType:     v.MimeType,
+		Size:         v.Size,
+		ContentsHash: v.ContentsHash,
+		Properties:   props,
+		Created:      v.Created,
+	}, nil
+}
+
+func (h dbHistory) findVersions(sess *db.Session, path string) ([]*fileVersion, error) {
+	pathHash, err := createPathHash(path)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]*fileVersion, 0)
+	err = sess.Table("file_version").Where("path_hash = ?", pathHash).Desc("version").Find(&versions)
+	return versions, err
+}
+
+func (h dbHistory) latestVersion(ctx context.Context, path string) (*FileVersion, error) {
+	var latest *FileVersion
+	err := h.db.WithDbSession(ctx, func(sess *db.Session) error {
+		pathHash, err := createPathHash(path)
+		if err != nil {
+			return err
+		}
+
+		version := &fileVersion{}
+		exists, err := sess.Table("file_version").Where("path_hash = ?", pathHash).Desc("version").Limit(1).Get(version)
+		if err != nil || !exists {
+			return err
+		}
+
+		latest, err = version.toFileVersion()
+		return err
+	})
+	return latest, err
+}
+
+func (h dbHistory) addVersion(ctx context.Context, version *FileVersion, contents []byte, maxVersions int) error {
+	pathHash, err := createPathHash(version.FullPath)
+	if err != nil {
+		return err
+	}
+
+	props, err := json.Marshal(version.Properties)
+	if err != nil {
+		return err
+	}
+
+	return h.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		exists, err := sess.Table("file_content").Where("contents_hash = ?", version.ContentsHash).Exist()
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			if contents == nil {
+				contents = make([]byte, 0)
+			}
+			if _, err := sess.Insert(&fileContent{
+				ContentsHash: version.ContentsHash,
+				Contents:     contents,
+				Size:         int64(len(contents)),
+				Created:      version.Created,
+			}); err != nil {
+				return err
+			}
+		}
+
+		if _, err := sess.Insert(&fileVersion{
+			Path:         version.FullPath,
+			PathHash:     pathHash,
+			Version:      version.Version,
+			ContentsHash: version.ContentsHash,
+			Size:         version.Size,
+			MimeType:     version.MimeType,
+			Properties:   string(props),
+			Created:      version.Created,
+		}); err != nil {
+			return err
+		}
+
+		if maxVersions <= 0 {
+			return nil
+		}
+
+		versions, err := h.findVersions(sess, version.FullPath)
+		if err != nil || len(versions) <= maxVersions {
+			return err
+		}
+		return h.deleteVersions(sess, versions[maxVersions:])
+	})
+}
+
+// deleteVersions deletes versions, and the contents no longer referenced by any version.
+func (h dbHistory) deleteVersions(sess *db.Session, versions []*fileVersion) error {
+	ids := make([]any, 0, len(versions))
+	contentsHashes := make(map[string]bool)
+	for _, v := range versions {
+		ids = append(ids, v.Id)
+		contentsHashes[v.ContentsHash] = true
+	}
+
+	if _, err := sess.Table("file_version").In("id", ids...).Delete(&fileVersion{}); err != nil {
+		return err
+	}
+
+	for contentsHash := range contentsHashes {
+		if _, err := sess.Exec("DELETE FROM file_content WHERE contents_hash = ? AND NOT EXISTS (SELECT 1 FROM file_version WHERE file_version.contents_hash = file_content.contents_hash)", contentsHash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h dbHistory) listVersions(ctx context.Context, path string) ([]*FileVersion, error) {
+	result := make([]*FileVersion, 0)
+	err := h.db.WithDbSession(ctx, func(sess *db.Session) error {
+		versions, err := h.findVersions(sess, path)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range versions {
+			version, err := v.toFileVersion()
+			if err != nil {
+				return err
+			}
+			result = append(result, version)
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (h dbHistory) getVersion(ctx context.Context, path string, version string) (*FileVersion, []byte, error) {
+	var result *FileVersion
+	var contents []byte
+	err := h.db.WithDbSession(ctx, func(sess *db.Session) error {
+		pathHash, err := createPathHash(path)
+		if err != nil {
+			return err
+		}
+
+		v := &fileVersion{}
+		exists, err := sess.Table("file_version").Where("path_hash = ? AND version = ?", pathHash, version).Get(v)
+		if err != nil || !exists {
+			return err
+		}
+
+		content := &fileContent{}
+		if _, err := sess.Table("file_content").Where("contents_hash = ?", v.ContentsHash).Get(content); err != nil {
+			return err
+		}
+
+		contents = content.Contents
+		if contents == nil {
+			contents = make([]byte, 0)
+		}
+		result, err = v.toFileVersion()
+		return err
+	})
+	return result, contents, err
+}
+
+func (h dbHistory) trash(ctx context.Context, path string, deleted time.Time) error {
+	pathHash, err := createPathHash(path)
+	if err != nil {
+		return err
+	}
+
+	return h.db.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Exec("UPDATE file_version SET trashed = ? WHERE path_hash = ?", deleted, pathHash)
+		return err
+	})
+}
+
+func (h dbHistory) untrash(ctx context.Context, path string) error {
+	pathHash, err := createPathHash(path)
+	if err != nil {
+		return err
+	}
+
+	return h.db.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Exec("UPDATE file_version SET trashed = NULL WHERE path_hash = ? AND trashed IS NOT NULL", pathHash)
+		return err
+	})
+}
+
+func (h dbHistory) getTrashed(ctx context.Context, path string) (*TrashedFile, error) {
+	var result *TrashedFile
+	err := h.db.WithDbSession(ctx, func(sess *db.Session) error {
+		pathHash, err := createPathHash(path)
+		if err != nil {
+			return err
+		}
+
+		v := &fileVersion{}
+		exists, err := sess.Table("file_version").Where("path_hash = ? AND trashed IS NOT NULL", pathHash).Desc("version").Limit(1).Get(v)
+		if err != nil || !exists {
+			return err
+		}
+
+		version, err := v.toFileVersion()
+		if err != nil {
+			return err
+		}
+		result = &TrashedFile{FileVersion: *version, Deleted: *v.Trashed}
+		return nil
+	})
+	return result, err
+}
+
+// findTrashed returns the versions of the files of a folder in the trash, by path hash, the most recent first.
+func (h dbHistory) findTrashed(sess *db.Session, folderPrefix string) (map[string][]*fileVersion, error) {
+	versions := make([]*fileVersion, 0)
+	err := sess.Table("file_version").
+		Where("trashed IS NOT NULL").
+		Where("LOWER(path) LIKE ?", strings.ToLower(folderPrefix)+"%").
+		Desc("version").
+		Find(&versions)
+	if err != nil {
+		return nil, err
+	}
+
+	byPathHash := make(map[string][]*fileVersion)
+	for _, v := range versions {
+		byPathHash[v.PathHash] = append(byPathHash[v.PathHash], v)
+	}
+	return byPathHash, nil
+}
+
+func (h dbHistory) listTrash(ctx context.Context, folderPrefix string) ([]*TrashedFile, error) {
+	result := make([]*TrashedFile, 0)
+	err := h.db.WithDbSession(ctx, func(sess *db.Session) error {
+		trashed, err := h.findTrashed(sess, folderPrefix)
+		if err != nil {
+			return err
+		}
+
+		for _, versions := range trashed {
+			version, err := versions[0].toFileVersion()
+			if err != nil {
+				return err
+			}
+			result = append(result, &TrashedFile{FileVersion: *version, Deleted: *versions[0].Trashed})
+		}
+		return nil
+	})
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].FullPath < result[j].FullPath
+	})
+	return result, err
+}
+
+func (h dbHistory) purge(ctx context.Context, folderPrefix string, deletedBefore time.Time) (int, error) {
+	purged := 0
+	err := h.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		trashed, err := h.findTrashed(sess, folderPrefix)
+		if err != nil {
+			return err
+		}
+
+		// deletion times are compared here, as their format depends on the database
+		var expired []*fileVersion
+		for _, versions := range trashed {
+			if versions[0].Trashed.Before(deletedBefore) {
+				expired = append(expired, versions...)
+				purged++
+			}
+		}
+
+		if len(expired) == 0 {
+			return nil
+		}
+		return h.deleteVersions(sess, expired)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
@@ -0,0 +1,363 @@
+package filestorage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// historyStore keeps the versions and the trash of a versioned storage. Paths are rooted paths, and are matched
+// case-insensitively like in the storages.
+type historyStore interface {
+	// latestVersion returns nil if the file has no versions.
+	latestVersion(ctx context.Context, path string) (*FileVersion, error)
+	// addVersion adds a version and deletes the oldest versions of the file above maxVersions.
+	addVersion(ctx context.Context, version *FileVersion, contents []byte, maxVersions int) error
+	listVersions(ctx context.Context, path string) ([]*FileVersion, error)
+	// getVersion returns nil if the version doesn't exist.
+	getVersion(ctx context.Context, path string, version string) (*FileVersion, []byte, error)
+
+	trash(ctx context.Context, path string, deleted time.Time) error
+	untrash(ctx context.Context, path string) error
+	// getTrashed returns nil if the file is not in the trash.
+	getTrashed(ctx context.Context, path string) (*TrashedFile, error)
+	listTrash(ctx context.Context, folderPrefix string) ([]*TrashedFile, error)
+	// purge deletes the versions of the files of a folder moved to the trash before deletedBefore, and returns
+	// the number of purged files.
+	purge(ctx context.Context, folderPrefix string, deletedBefore time.Time) (int, error)
+}
+
+func createVersionContentsHash(contents []byte) string {
+	hash := sha256.Sum256(contents)
+	return hex.EncodeToString(hash[:])
+}
+
+// newVersionID returns a version ID sorting in the order the versions are created.
+func newVersionID(now time.Time) string {
+	return fmt.Sprintf("%019d-%s", now.UnixNano(), util.GenerateShortUID())
+}
+
+// versionedStorage records a version of the files written to the wrapped storage, and moves the deleted files to
+// the trash.
+type versionedStorage struct {
+	wrapped FileStorage
+	history historyStore
+	opts    VersioningOptions
+	now     func() time.Time
+}
+
+func (s *versionedStorage) Get(ctx context.Context, path string, options *GetFileOptions) (*File, bool, error) {
+	return s.wrapped.Get(ctx, path, options)
+}
+
+func (s *versionedStorage) List(ctx context.Context, folderPath string, paging *Paging, options *ListOptions) (*ListResponse, error) {
+	return s.wrapped.List(ctx, folderPath, paging, options)
+}
+
+func (s *versionedStorage) CreateFolder(ctx context.Context, path string) error {
+	return s.wrapped.CreateFolder(ctx, path)
+}
+
+func (s *versionedStorage) Upsert(ctx context.Context, cmd *UpsertFileCommand) error {
+	if err := s.wrapped.Upsert(ctx, cmd); err != nil {
+		return err
+	}
+	_, err := s.recordVersion(ctx, cmd.Path)
+	return err
+}
+
+// recordVersion adds a version for the current state of a file, unless it is identical to the latest version.
+// The file is removed from the trash, as it exists again. It returns false if the file doesn't exist.
+func (s *versionedStorage) recordVersion(ctx context.Context, path string) (bool, error) {
+	file, found, err := s.wrapped.Get(ctx, path, &GetFileOptions{WithContents: true})
+	if err != nil || !found {
+		return false, err
+	}
+
+	latest, err := s.history.latestVersion(ctx, path)
+	if err != nil {
+		return true, err
+	}
+
+	contentsHash := createVersionContentsHash(file.Contents)
+	if latest == nil || latest.ContentsHash != contentsHash || latest.MimeType != file.MimeType || !maps.Equal(latest.Properties, file.Properties) {
+		now := s.now()
+		version := &FileVersion{
+			Version:      newVersionID(now),
+			FullPath:     file.FullPath,
+			MimeType:     file.MimeType,
+			Size:         int64(len(file.Contents)),
+			ContentsHash: contentsHash,
+			Properties:   file.Properties,
+			Created:      now,
+		}
+		if err := s.history.addVersion(ctx, version, file.Contents, s.opts.MaxVersions); err != nil {
+			return true, err
+		}
+	}
+
+	return true, s.history.untrash(ctx, path)
+}
+
+func (s *versionedStorage) Delete(ctx context.Context, path string) error {
+	// the file can have been written before versioning was enabled
+	found, err := s.recordVersion(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	if err := s.wrapped.Delete(ctx, path); err != nil {
+		return err
+	}
+
+	if !found {
+		return nil
+	}
+	return s.history.trash(ctx, path, s.now())
+}
+
+func (s *versionedStorage) DeleteFolder(ctx context.Context, folderPath string, options *DeleteFolderOptions) error {
+	if !options.Force {
+		return s.wrapped.DeleteFolder(ctx, folderPath, options)
+	}
+
+	paths, err := s.listFiles(ctx, folderPath, options.AccessFilter)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if _, err := s.recordVersion(ctx, path); err != nil {
+			return err
+		}
+	}
+
+	if err := s.wrapped.DeleteFolder(ctx, folderPath, options); err != nil {
+		return err
+	}
+
+	now := s.now()
+	for _, path := range paths {
+		if err := s.history.trash(ctx, path, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listFiles lists the paths of the files of a folder and its subfolders.
+func (s *versionedStorage) listFiles(ctx context.Context, folderPath string, filter PathFilter) ([]string, error) {
+	var paths []string
+	paging := &Paging{Limit: 100}
+	for {
+		resp, err := s.wrapped.List(ctx, folderPath, paging, &ListOptions{
+			Recursive: true,
+			WithFiles: true,
+			Filter:    filter,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range resp.Files {
+			paths = append(paths, f.FullPath)
+		}
+		if !resp.HasMore || resp.LastPath == "" || resp.LastPath == paging.After {
+			return paths, nil
+		}
+		paging = &Paging{Limit: paging.Limit, After: resp.LastPath}
+	}
+}
+
+func (s *versionedStorage) close() error {
+	return s.wrapped.close()
+}
+
+// versionedWrapper is the wrapper of a versioned storage. It validates, roots and filters the paths of the
+// versioning operations like the wrapper does for the FileStorage operations.
+type versionedWrapper struct {
+	*wrapper
+	versioned *versionedStorage
+}
+
+var (
+	_ VersionedFileStorage = (*versionedWrapper)(nil) // versionedWrapper implements VersionedFileStorage
+)
+
+func newVersionedWrapper(log log.Logger, wrapped FileStorage, history historyStore, pathFilter PathFilter, rootFolder string, opts VersioningOptions) VersionedFileStorage {
+	versioned := &versionedStorage{
+		wrapped: wrapped,
+		history: history,
+		opts:    opts,
+		now:     time.Now,
+	}
+
+	return &versionedWrapper{
+		wrapper:   newWrapper(log, versioned, pathFilter, rootFolder),
+		versioned: versioned,
+	}
+}
+
+// rootedFilePath validates a path and returns it with the root. It returns false if the path is not allowed.
+func (b versionedWrapper) rootedFilePath(path string) (string, bool, error) {
+	if err := b.validatePath(path); err != nil {
+		return "", false, err
+	}
+
+	rootedPath := b.addRoot(path)
+	if rootedPath == b.rootFolder || !b.filter.IsAllowed(rootedPath) {
+		return "", false, nil
+	}
+	return rootedPath, true, nil
+}
+
+func (b versionedWrapper) ListVersions(ctx context.Context, path string) ([]*FileVersion, error) {
+	rootedPath, allowed, err := b.rootedFilePath(path)
+	if err != nil || !allowed {
+		return nil, err
+	}
+
+	versions, err := b.versioned.history.listVersions(ctx, rootedPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range versions {
+		v.FullPath = b.removeRoot(v.FullPath)
+	}
+	return versions, nil
+}
+
+func (b versionedWrapper) GetVersion(ctx context.Context, path string, version string) (*File, bool, error) {
+	rootedPath, allowed, err := b.rootedFilePath(path)
+	if err != nil || !allowed {
+		return nil, false, err
+	}
+
+	v, contents, err := b.versioned.history.getVersion(ctx, rootedPath, version)
+	if err != nil || v == nil {
+		return nil, false, err
+	}
+
+	fullPath := b.removeRoot(v.FullPath)
+	return &File{
+		Contents: contents,
+		FileMetadata: FileMetadata{
+			Name:       getName(fullPath),
+			FullPath:   fullPath,
+			MimeType:   v.MimeType,
+			Modified:   v.Created,
+			Created:    v.Created,
+			Size:       v.Size,
+			Properties: v.Properties,
+		},
+	}, true, nil
+}
+
+func (b versionedWrapper) RestoreVersion(ctx context.Context, path string, version string) error {
+	file, found, err := b.GetVersion(ctx, path, version)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrVersionNotFound
+	}
+
+	return b.restore(ctx, path, file.MimeType, file.Contents, file.Properties)
+}
+
+func (b versionedWrapper) restore(ctx context.Context, path string, mimeType string, contents []byte, properties map[string]string) error {
+	// properties are replaced even if the restored version has none
+	if properties == nil {
+		properties = map[string]string{}
+	}
+
+	return b.Upsert(ctx, &UpsertFileCommand{
+		Path:       path,
+		MimeType:   mimeType,
+		Contents:   contents,
+		Properties: properties,
+	})
+}
+
+// trashPrefix returns the rooted prefix of the files of a folder.
+func (b versionedWrapper) trashPrefix(folderPath string) string {
+	if folderPath == Delimiter {
+		return b.rootFolder
+	}
+	return b.addRoot(folderPath) + Delimiter
+}
+
+func (b versionedWrapper) ListTrash(ctx context.Context, folderPath string) ([]*TrashedFile, error) {
+	if err := b.validatePath(folderPath); err != nil {
+		return nil, err
+	}
+
+	trashed, err := b.versioned.history.listTrash(ctx, b.trashPrefix(folderPath))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]*TrashedFile, 0, len(trashed))
+	for _, f := range trashed {
+		if !b.filter.IsAllowed(f.FullPath) {
+			continue
+		}
+		f.FullPath = b.removeRoot(f.FullPath)
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+func (b versionedWrapper) RestoreFromTrash(ctx context.Context, path string) error {
+	rootedPath, allowed, err := b.rootedFilePath(path)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrNotInTrash
+	}
+
+	trashed, err := b.versioned.history.getTrashed(ctx, rootedPath)
+	if err != nil {
+		return err
+	}
+	if trashed == nil {
+		return ErrNotInTrash
+	}
+
+	_, contents, err := b.versioned.history.getVersion(ctx, rootedPath, trashed.Version)
+	if err != nil {
+		return err
+	}
+
+	// the file is removed from the trash once it exists again
+	return b.restore(ctx, b.removeRoot(trashed.FullPath), trashed.MimeType, contents, trashed.Properties)
+}
+
+func (b versionedWrapper) PurgeTrash(ctx context.Context) (int, error) {
+	if b.versioned.opts.TrashRetention <= 0 {
+		return 0, nil
+	}
+
+	deletedBefore := b.versioned.now().Add(-b.versioned.opts.TrashRetention)
+	purged, err := b.versioned.history.purge(ctx, b.rootFolder, deletedBefore)
+	if err != nil {
+		return purged, err
+	}
+
+	if purged > 0 {
+		b.log.Info("Purged files from the trash", "root", b.rootFolder, "count", purged)
+	}
+	return purged, nil
+}
+
+// hasPathPrefix returns true if a rooted path belongs to the folder of the rooted prefix, ignoring the casing.
+func hasPathPrefix(path string, folderPrefix string) bool {
+	return strings.HasPrefix(strings.ToLower(path), strings.ToLower(folderPrefix))
+}
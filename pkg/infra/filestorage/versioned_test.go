@@ -0,0 +1,257 @@
+package filestorage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/memblob"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+func TestIntegrationVersionedFileStorage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	testLogger := log.New("versionedStorageTest")
+	opts := VersioningOptions{MaxVersions: 3, TrashRetention: time.Hour}
+
+	type setup struct {
+		storage *versionedWrapper
+		// contentsCount returns the number of contents kept by the history
+		contentsCount func() int
+	}
+
+	backends := []struct {
+		name  string
+		setup func(t *testing.T) setup
+	}{
+		{
+			name: "db",
+			setup: func(t *testing.T) setup {
+				sqlStore := db.InitTestDB(t)
+				return setup{
+					storage: NewVersionedDbStorage(testLogger, sqlStore, nil, "/5/dashboards/", opts).(*versionedWrapper),
+					contentsCount: func() int {
+						var count int64
+						err := sqlStore.WithDbSession(ctx, func(sess *db.Session) error {
+							var err error
+							count, err = sess.Table("file_content").Count()
+							return err
+						})
+						require.NoError(t, err)
+						return int(count)
+					},
+				}
+			},
+		},
+		{
+			name: "cdk",
+			setup: func(t *testing.T) setup {
+				bucket := memblob.OpenBucket(nil)
+				t.Cleanup(func() { _ = bucket.Close() })
+				return setup{
+					storage: NewVersionedCdkBlobStorage(testLogger, bucket, "", nil, opts).(*versionedWrapper),
+					contentsCount: func() int {
+						count := 0
+						iter := bucket.List(&blob.ListOptions{Prefix: historyFolder + "/contents/"})
+						for {
+							if _, err := iter.Next(ctx); err != nil {
+								break
+							}
+							count++
+						}
+						return count
+					},
+				}
+			},
+		},
+	}
+
+	upsert := func(t *testing.T, s VersionedFileStorage, path string, contents string, props map[string]string) {
+		t.Helper()
+		require.NoError(t, s.Upsert(ctx, &UpsertFileCommand{Path: path, Contents: []byte(contents), Properties: props}))
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			t.Run("versions are recorded and restored", func(t *testing.T) {
+				s := backend.setup(t).storage
+
+				upsert(t, s, "/folder/file.json", "v1", map[string]string{"a": "1"})
+				upsert(t, s, "/folder/file.json", "v1", map[string]string{"a": "1"})
+				upsert(t, s, "/folder/file.json", "v2", nil)
+
+				versions, err := s.ListVersions(ctx, "/folder/file.json")
+				require.NoError(t, err)
+				require.Len(t, versions, 2)
+				require.Equal(t, "/folder/file.json", versions[0].FullPath)
+				require.Equal(t, int64(2), versions[0].Size)
+				require.Equal(t, createVersionContentsHash([]byte("v2")), versions[0].ContentsHash)
+				require.Equal(t, map[string]string{"a": "1"}, versions[1].Properties)
+
+				file, found, err := s.GetVersion(ctx, "/folder/file.json", versions[1].Version)
+				require.NoError(t, err)
+				require.True(t, found)
+				require.Equal(t, "v1", string(file.Contents))
+
+				_, found, err = s.GetVersion(ctx, "/folder/file.json", "unknown")
+				require.NoError(t, err)
+				require.False(t, found)
+				require.ErrorIs(t, s.RestoreVersion(ctx, "/folder/file.json", "unknown"), ErrVersionNotFound)
+
+				require.NoError(t, s.RestoreVersion(ctx, "/folder/file.json", versions[1].Version))
+				current, _, err := s.Get(ctx, "/folder/file.json", nil)
+				require.NoError(t, err)
+				require.Equal(t, "v1", string(current.Contents))
+				require.Equal(t, map[string]string{"a": "1"}, current.Properties)
+
+				versions, err = s.ListVersions(ctx, "/folder/file.json")
+				require.NoError(t, err)
+				require.Len(t, versions, 3)
+			})
+
+			t.Run("oldest versions are deleted above the maximum", func(t *testing.T) {
+				setup := backend.setup(t)
+				s := setup.storage
+
+				for _, contents := range []string{"v1", "v2", "v3", "v4", "v5"} {
+					upsert(t, s, "/file.txt", contents, nil)
+				}
+
+				versions, err := s.ListVersions(ctx, "/file.txt")
+				require.NoError(t, err)
+				require.Len(t, versions, 3)
+				_, found, err := s.GetVersion(ctx, "/file.txt", versions[2].Version)
+				require.NoError(t, err)
+				require.True(t, found)
+			})
+
+			t.Run("identical contents are stored once", func(t *testing.T) {
+				setup := backend.setup(t)
+				s := setup.storage
+
+				upsert(t, s, "/a.txt", "same", nil)
+				upsert(t, s, "/b.txt", "same", nil)
+				upsert(t, s, "/nested/c.txt", "same", map[string]string{"k": "v"})
+				require.Equal(t, 1, setup.contentsCount())
+			})
+
+			t.Run("deleted files are restored from the trash", func(t *testing.T) {
+				s := backend.setup(t).storage
+
+				upsert(t, s, "/folder/file.txt", "contents", map[string]string{"a": "1"})
+				require.NoError(t, s.Delete(ctx, "/folder/file.txt"))
+
+				_, found, err := s.Get(ctx, "/folder/file.txt", nil)
+				require.NoError(t, err)
+				require.False(t, found)
+
+				trashed, err := s.ListTrash(ctx, "/")
+				require.NoError(t, err)
+				require.Len(t, trashed, 1)
+				require.Equal(t, "/folder/file.txt", trashed[0].FullPath)
+				require.False(t, trashed[0].Deleted.IsZero())
+
+				trashed, err = s.ListTrash(ctx, "/other")
+				require.NoError(t, err)
+				require.Empty(t, trashed)
+
+				require.NoError(t, s.RestoreFromTrash(ctx, "/folder/file.txt"))
+				file, found, err := s.Get(ctx, "/folder/file.txt", nil)
+				require.NoError(t, err)
+				require.True(t, found)
+				require.Equal(t, "contents", string(file.Contents))
+				require.Equal(t, map[string]string{"a": "1"}, file.Properties)
+
+				trashed, err = s.ListTrash(ctx, "/")
+				require.NoError(t, err)
+				require.Empty(t, trashed)
+				require.ErrorIs(t, s.RestoreFromTrash(ctx, "/folder/file.txt"), ErrNotInTrash)
+
+				// restoring the same contents doesn't add a version
+				versions, err := s.ListVersions(ctx, "/folder/file.txt")
+				require.NoError(t, err)
+				require.Len(t, versions, 1)
+			})
+
+			t.Run("files of force deleted folders are moved to the trash", func(t *testing.T) {
+				s := backend.setup(t).storage
+
+				upsert(t, s, "/folder/a.txt", "a", nil)
+				upsert(t, s, "/folder/nested/b.txt", "b", nil)
+				upsert(t, s, "/other.txt", "other", nil)
+				require.NoError(t, s.DeleteFolder(ctx, "/folder", &DeleteFolderOptions{Force: true}))
+
+				trashed, err := s.ListTrash(ctx, "/folder")
+				require.NoError(t, err)
+				require.Len(t, trashed, 2)
+				require.Equal(t, "/folder/a.txt", trashed[0].FullPath)
+				require.Equal(t, "/folder/nested/b.txt", trashed[1].FullPath)
+			})
+
+			t.Run("expired files are purged from the trash", func(t *testing.T) {
+				setup := backend.setup(t)
+				s := setup.storage
+
+				upsert(t, s, "/old.txt", "old", nil)
+				upsert(t, s, "/recent.txt", "recent", nil)
+				require.NoError(t, s.Delete(ctx, "/old.txt"))
+
+				s.versioned.now = func() time.Time { return time.Now().Add(30 * time.Minute) }
+				require.NoError(t, s.Delete(ctx, "/recent.txt"))
+
+				purged, err := s.PurgeTrash(ctx)
+				require.NoError(t, err)
+				require.Equal(t, 0, purged)
+
+				s.versioned.now = func() time.Time { return time.Now().Add(80 * time.Minute) }
+				if history, ok := s.versioned.history.(*cdkBlobHistory); ok {
+					history.now = s.versioned.now
+				}
+				purged, err = s.PurgeTrash(ctx)
+				require.NoError(t, err)
+				require.Equal(t, 1, purged)
+
+				trashed, err := s.ListTrash(ctx, "/")
+				require.NoError(t, err)
+				require.Len(t, trashed, 1)
+				require.Equal(t, "/recent.txt", trashed[0].FullPath)
+
+				versions, err := s.ListVersions(ctx, "/old.txt")
+				require.NoError(t, err)
+				require.Empty(t, versions)
+				require.Equal(t, 1, setup.contentsCount())
+			})
+
+			t.Run("history is hidden from the storage", func(t *testing.T) {
+				s := backend.setup(t).storage
+
+				upsert(t, s, "/file.txt", "contents", nil)
+				upsert(t, s, "/file.txt", "updated", nil)
+
+				resp, err := s.List(ctx, "/", nil, &ListOptions{Recursive: true, WithFiles: true, WithFolders: true})
+				require.NoError(t, err)
+				paths := make([]string, 0, len(resp.Files))
+				for _, f := range resp.Files {
+					if !f.IsFolder() {
+						paths = append(paths, f.FullPath)
+					}
+					require.NotContains(t, f.FullPath, historyFolder)
+				}
+				require.Equal(t, []string{"/file.txt"}, paths)
+
+				require.NoError(t, s.DeleteFolder(ctx, "/", &DeleteFolderOptions{Force: true}))
+				trashed, err := s.ListTrash(ctx, "/")
+				require.NoError(t, err)
+				require.Len(t, trashed, 1)
+				require.NoError(t, s.RestoreFromTrash(ctx, "/file.txt"))
+			})
+		})
+	}
+}
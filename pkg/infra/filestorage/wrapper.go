@@ -51,7 +51,7 @@ func (w wrappedPathFilter) asSQLFilter() accesscontrol.SQLFilter {
 	return sqlFilter
 }
 
-func newWrapper(log log.Logger, wrapped FileStorage, pathFilter PathFilter, rootFolder string) FileStorage {
+func newWrapper(log log.Logger, wrapped FileStorage, pathFilter PathFilter, rootFolder string) *wrapper {
 	var wrappedPathFilter PathFilter
 	if pathFilter != nil {
 		wrappedPathFilter = wrapPathFilter(pathFilter, rootFolder)
@@ -0,0 +1,291 @@
+package kvstore
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gocloud.dev/blob"
+	"gocloud.dev/gcerrors"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/setting"
+
+	// Supported drivers
+	_ "gocloud.dev/blob/azureblob"
+	_ "gocloud.dev/blob/fileblob"
+	_ "gocloud.dev/blob/gcsblob"
+	_ "gocloud.dev/blob/memblob"
+	_ "gocloud.dev/blob/s3blob"
+)
+
+// kvStoreBlob provides a key/value store with the values stored in a blob bucket. The kv_store table of the
+// Grafana database is the index of the keys. Values not migrated to the bucket yet are read from the table.
+type kvStoreBlob struct {
+	log      log.Logger
+	sqlStore db.DB
+	index    *kvStoreSQL
+	bucket   *blob.Bucket
+}
+
+func newKVStoreBlob(sqlStore db.DB, bucket *blob.Bucket) *kvStoreBlob {
+	logger := log.New("infra.kvstore.blob")
+	return &kvStoreBlob{
+		log:      logger,
+		sqlStore: sqlStore,
+		index:    &kvStoreSQL{sqlStore: sqlStore, log: logger},
+		bucket:   bucket,
+	}
+}
+
+func openBucket(ctx context.Context, cfg setting.KVStoreSettings) (*blob.Bucket, error) {
+	bucket, err := blob.OpenBucket(ctx, cfg.BlobURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open kvstore bucket: %w", err)
+	}
+	if cfg.BlobPrefix != "" {
+		bucket = blob.PrefixedBucket(bucket, cfg.BlobPrefix)
+	}
+	return bucket, nil
+}
+
+// newBlobKey returns a new key for a value in the bucket. Every write of a value uses a new key, so that concurrent
+// writes never overwrite the value referenced by the index.
+func newBlobKey(orgId int64, namespace string, key string) string {
+	return path.Join(strconv.FormatInt(orgId, 10), url.PathEscape(namespace), url.PathEscape(key), uuid.NewString())
+}
+
+// Get an item from the store
+func (kv *kvStoreBlob) Get(ctx context.Context, orgId int64, namespace string, key string) (string, bool, error) {
+	// The value can be replaced between the read of the index and the read of the bucket, so read the index again
+	// once if the value is missing.
+	for attempt := 0; ; attempt++ {
+		item := Item{
+			OrgId:     &orgId,
+			Namespace: &namespace,
+			Key:       &key,
+		}
+		var itemFound bool
+		err := kv.sqlStore.WithDbSession(ctx, func(dbSession *db.Session) error {
+			var err error
+			itemFound, err = dbSession.Get(&item)
+			return err
+		})
+		if err != nil || !itemFound {
+			return "", false, err
+		}
+
+		value, err := kv.readValue(ctx, item)
+		if gcerrors.Code(err) == gcerrors.NotFound && attempt == 0 {
+			continue
+		}
+		return kv.valueOrMissing(item, value, err)
+	}
+}
+
+func (kv *kvStoreBlob) readValue(ctx context.Context, item Item) (string, error) {
+	if item.BlobKey == "" {
+		return item.Value, nil
+	}
+
+	value, err := kv.bucket.ReadAll(ctx, item.BlobKey)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// valueOrMissing handles values referenced by the index but missing in the bucket as not found.
+func (kv *kvStoreBlob) valueOrMissing(item Item, value string, err error) (string, bool, error) {
+	if err == nil {
+		return value, true, nil
+	}
+	if gcerrors.Code(err) == gcerrors.NotFound {
+		kv.log.Warn("kvstore value missing in bucket", "orgId", *item.OrgId, "namespace", *item.Namespace, "key", *item.Key, "blobKey", item.BlobKey)
+		return "", false, nil
+	}
+	return "", false, err
+}
+
+// Set an item in the store. The value is written to the bucket before the index, so that the index never
+// references a missing value.
+func (kv *kvStoreBlob) Set(ctx context.Context, orgId int64, namespace string, key string, value string) error {
+	objectKey := newBlobKey(orgId, namespace, key)
+	if err := kv.bucket.WriteAll(ctx, objectKey, []byte(value), nil); err != nil {
+		kv.log.Debug("error writing kvstore value to bucket", "orgId", orgId, "namespace", namespace, "key", key, "err", err)
+		return err
+	}
+
+	var previous string
+	err := kv.sqlStore.WithTransactionalDbSession(ctx, func(dbSession *db.Session) error {
+		item := Item{
+			OrgId:     &orgId,
+			Namespace: &namespace,
+			Key:       &key,
+		}
+
+		has, err := dbSession.Get(&item)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if has {
+			previous = item.BlobKey
+			_, err = dbSession.Exec("UPDATE kv_store SET value = ?, blob_key = ?, updated = ? WHERE id = ?", "", objectKey, now, item.Id)
+			return err
+		}
+
+		item.BlobKey = objectKey
+		item.Created = now
+		item.Updated = now
+		_, err = dbSession.Insert(&item)
+		return err
+	})
+	if err != nil {
+		kv.log.Debug("error updating kvstore index", "orgId", orgId, "namespace", namespace, "key", key, "err", err)
+		kv.deleteBlob(ctx, objectKey)
+		return err
+	}
+
+	kv.deleteBlob(ctx, previous)
+	return nil
+}
+
+// Del deletes an item from the store. The index is deleted first, so that a failure leaves an unreferenced value
+// in the bucket rather than a reference to a missing value.
+func (kv *kvStoreBlob) Del(ctx context.Context, orgId int64, namespace string, key string) error {
+	var objectKey string
+	err := kv.sqlStore.WithTransactionalDbSession(ctx, func(dbSession *db.Session) error {
+		item := Item{
+			OrgId:     &orgId,
+			Namespace: &namespace,
+			Key:       &key,
+		}
+
+		has, err := dbSession.Get(&item)
+		if err != nil || !has {
+			return err
+		}
+
+		objectKey = item.BlobKey
+		_, err = dbSession.Exec("DELETE FROM kv_store WHERE id = ?", item.Id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	kv.deleteBlob(ctx, objectKey)
+	return nil
+}
+
+// deleteBlob deletes a value that is no longer referenced by the index. Failures only leave an unreferenced value
+// in the bucket, so they are logged.
+func (kv *kvStoreBlob) deleteBlob(ctx context.Context, objectKey string) {
+	if objectKey == "" {
+		return
+	}
+	if err := kv.bucket.Delete(ctx, objectKey); err != nil && gcerrors.Code(err) != gcerrors.NotFound {
+		kv.log.Warn("Failed to delete kvstore value from bucket", "blobKey", objectKey, "err", err)
+	}
+}
+
+// Keys get all keys for a given namespace and keyPrefix. To query for all
+// organizations the constant 'kvstore.AllOrganizations' can be passed as orgId.
+func (kv *kvStoreBlob) Keys(ctx context.Context, orgId int64, namespace string, keyPrefix string) ([]Key, error) {
+	return kv.index.Keys(ctx, orgId, namespace, keyPrefix)
+}
+
+// GetAll get all items a given namespace and org. To query for all
+// organizations the constant 'kvstore.AllOrganizations' can be passed as orgId.
+// The map result is like map[orgId]map[key]value
+func (kv *kvStoreBlob) GetAll(ctx context.Context, orgId int64, namespace string) (map[int64]map[string]string, error) {
+	var results []Item
+	err := kv.sqlStore.WithDbSession(ctx, func(dbSession *db.Session) error {
+		query := dbSession.Where("namespace = ?", namespace)
+		if orgId != AllOrganizations {
+			query.And("org_id = ?", orgId)
+		}
+
+		return query.Find(&results)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items := map[int64]map[string]string{}
+	for _, r := range results {
+		value, err := kv.readValue(ctx, r)
+		value, found, err := kv.valueOrMissing(r, value, err)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+		if _, ok := items[*r.OrgId]; !ok {
+			items[*r.OrgId] = map[string]string{}
+		}
+		items[*r.OrgId][*r.Key] = value
+	}
+
+	return items, nil
+}
+
+// MigrateToBlob moves the values stored in the kv_store table to the bucket configured in cfg, and returns the
+// number of migrated values. Values changed during the migration are skipped, so it is safe to execute multiple times.
+func MigrateToBlob(ctx context.Context, sqlStore db.DB, cfg setting.KVStoreSettings) (int, error) {
+	bucket, err := openBucket(ctx, cfg)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = bucket.Close() }()
+
+	return newKVStoreBlob(sqlStore, bucket).migrate(ctx)
+}
+
+func (kv *kvStoreBlob) migrate(ctx context.Context) (int, error) {
+	var items []Item
+	err := kv.sqlStore.WithDbSession(ctx, func(dbSession *db.Session) error {
+		return dbSession.Where("blob_key IS NULL OR blob_key = ?", "").Find(&items)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for _, item := range items {
+		objectKey := newBlobKey(*item.OrgId, *item.Namespace, *item.Key)
+		if err := kv.bucket.WriteAll(ctx, objectKey, []byte(item.Value), nil); err != nil {
+			return migrated, err
+		}
+
+		var affected int64
+		err := kv.sqlStore.WithDbSession(ctx, func(dbSession *db.Session) error {
+			res, err := dbSession.Exec("UPDATE kv_store SET value = ?, blob_key = ? WHERE id = ? AND value = ? AND (blob_key IS NULL OR blob_key = ?)",
+				"", objectKey, item.Id, item.Value, "")
+			if err != nil {
+				return err
+			}
+			affected, err = res.RowsAffected()
+			return err
+		})
+		if err != nil {
+			return migrated, err
+		}
+		if affected == 0 {
+			kv.deleteBlob(ctx, objectKey)
+			kv.log.Info("kvstore value changed during migration, skipping", "orgId", *item.OrgId, "namespace", *item.Namespace, "key", *item.Key)
+			continue
+		}
+		migrated++
+	}
+
+	return migrated, nil
+}
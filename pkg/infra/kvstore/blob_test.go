@@ -0,0 +1,137 @@
+package kvstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/memblob"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+)
+
+func createTestableKVStoreBlob(t *testing.T) (*kvStoreBlob, *kvStoreSQL) {
+	t.Helper()
+
+	sqlStore := db.InitTestDB(t)
+	bucket := memblob.OpenBucket(nil)
+	t.Cleanup(func() { _ = bucket.Close() })
+
+	kv := newKVStoreBlob(sqlStore, bucket)
+	return kv, kv.index
+}
+
+func bucketKeys(t *testing.T, bucket *blob.Bucket) []string {
+	t.Helper()
+
+	var keys []string
+	iter := bucket.List(nil)
+	for {
+		obj, err := iter.Next(context.Background())
+		if err != nil {
+			break
+		}
+		keys = append(keys, obj.Key)
+	}
+	return keys
+}
+
+func TestIntegrationKVStoreBlob(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+
+	t.Run("values are stored in the bucket", func(t *testing.T) {
+		kv, _ := createTestableKVStoreBlob(t)
+
+		require.NoError(t, kv.Set(ctx, 1, "plugin/test", "key/1", "value1"))
+		require.NoError(t, kv.Set(ctx, 1, "plugin/test", "key/1", "value2"))
+
+		value, ok, err := kv.Get(ctx, 1, "plugin/test", "key/1")
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, "value2", value)
+
+		// the previous value is deleted from the bucket, and the index doesn't hold the value
+		keys := bucketKeys(t, kv.bucket)
+		require.Len(t, keys, 1)
+		assert.Contains(t, keys[0], "1/plugin%2Ftest/key%2F1/")
+
+		var item Item
+		err = kv.sqlStore.WithDbSession(ctx, func(sess *db.Session) error {
+			_, err := sess.Where("namespace = ?", "plugin/test").Get(&item)
+			return err
+		})
+		require.NoError(t, err)
+		require.Empty(t, item.Value)
+		require.Equal(t, keys[0], item.BlobKey)
+
+		items, err := kv.GetAll(ctx, AllOrganizations, "plugin/test")
+		require.NoError(t, err)
+		require.Equal(t, map[int64]map[string]string{1: {"key/1": "value2"}}, items)
+
+		keyList, err := kv.Keys(ctx, 1, "plugin/test", "key")
+		require.NoError(t, err)
+		require.Equal(t, []Key{{OrgId: 1, Namespace: "plugin/test", Key: "key/1"}}, keyList)
+
+		require.NoError(t, kv.Del(ctx, 1, "plugin/test", "key/1"))
+		_, ok, err = kv.Get(ctx, 1, "plugin/test", "key/1")
+		require.NoError(t, err)
+		require.False(t, ok)
+		require.Empty(t, bucketKeys(t, kv.bucket))
+	})
+
+	t.Run("values are migrated from the database", func(t *testing.T) {
+		kv, sqlKV := createTestableKVStoreBlob(t)
+
+		require.NoError(t, sqlKV.Set(ctx, 1, "testing", "key1", "value1"))
+		require.NoError(t, sqlKV.Set(ctx, 2, "testing", "key2", "value2"))
+
+		// values not migrated yet are read from the database
+		value, ok, err := kv.Get(ctx, 1, "testing", "key1")
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, "value1", value)
+
+		migrated, err := kv.migrate(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 2, migrated)
+		require.Len(t, bucketKeys(t, kv.bucket), 2)
+
+		items, err := kv.GetAll(ctx, AllOrganizations, "testing")
+		require.NoError(t, err)
+		require.Equal(t, map[int64]map[string]string{1: {"key1": "value1"}, 2: {"key2": "value2"}}, items)
+
+		// migrating again doesn't migrate anything
+		migrated, err = kv.migrate(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 0, migrated)
+		require.Len(t, bucketKeys(t, kv.bucket), 2)
+	})
+
+	t.Run("the database store doesn't read values of the bucket", func(t *testing.T) {
+		kv, sqlKV := createTestableKVStoreBlob(t)
+
+		require.NoError(t, kv.Set(ctx, 1, "testing", "key1", "value1"))
+
+		_, _, err := sqlKV.Get(ctx, 1, "testing", "key1")
+		require.ErrorIs(t, err, errValueInBlob)
+		_, err = sqlKV.GetAll(ctx, 1, "testing")
+		require.ErrorIs(t, err, errValueInBlob)
+
+		// a value set in the database replaces the value of the bucket
+		require.NoError(t, sqlKV.Set(ctx, 1, "testing", "key1", "value2"))
+		value, ok, err := sqlKV.Get(ctx, 1, "testing", "key1")
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, "value2", value)
+
+		value, ok, err = kv.Get(ctx, 1, "testing", "key1")
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, "value2", value)
+	})
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 const (
@@ -12,6 +13,7 @@ const (
 	AllOrganizations = -1
 )
 
+// ProvideService returns a KVStore backed by the Grafana database.
 func ProvideService(sqlStore db.DB) KVStore {
 	return &kvStoreSQL{
 		sqlStore: sqlStore,
@@ -19,6 +21,20 @@ func ProvideService(sqlStore db.DB) KVStore {
 	}
 }
 
+// ProvideStore returns the KVStore configured in the [kvstore] section. With the blob type, the values are stored
+// in a blob bucket and the Grafana database only holds the index of the keys.
+func ProvideStore(sqlStore db.DB, cfg *setting.Cfg) (KVStore, error) {
+	if cfg.KVStore.Type != setting.KVStoreTypeBlob {
+		return ProvideService(sqlStore), nil
+	}
+
+	bucket, err := openBucket(context.Background(), cfg.KVStore)
+	if err != nil {
+		return nil, err
+	}
+	return newKVStoreBlob(sqlStore, bucket), nil
+}
+
 // KVStore is an interface for k/v store.
 type KVStore interface {
 	Get(ctx context.Context, orgId int64, namespace string, key string) (string, bool, error)
@@ -11,6 +11,8 @@ type Item struct {
 	Namespace *string
 	Key       *string
 	Value     string
+	// BlobKey is the key of the value in the blob bucket, if the value is stored in a bucket.
+	BlobKey string
 
 	Created time.Time
 	Updated time.Time
@@ -2,6 +2,7 @@ package kvstore
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,6 +10,9 @@ import (
 	"github.com/grafana/grafana/pkg/infra/log"
 )
 
+// errValueInBlob is returned for values moved to a blob bucket, which can't be read without the bucket.
+var errValueInBlob = errors.New("kvstore value is stored in the blob bucket, enable the blob backend to read it")
+
 // kvStoreSQL provides a key/value store backed by the Grafana database
 type kvStoreSQL struct {
 	log      log.Logger
@@ -34,6 +38,9 @@ func (kv *kvStoreSQL) Get(ctx context.Context, orgId int64, namespace string, ke
 			kv.log.Debug("kvstore value not found", "orgId", orgId, "namespace", namespace, "key", key)
 			return nil
 		}
+		if item.BlobKey != "" {
+			return fmt.Errorf("%w: orgId=%d namespace=%q key=%q", errValueInBlob, orgId, namespace, key)
+		}
 		itemFound = true
 		kv.log.Debug("got kvstore value", "orgId", orgId, "namespace", namespace, "key", key, "value", item.Value)
 		return nil
@@ -57,7 +64,7 @@ func (kv *kvStoreSQL) Set(ctx context.Context, orgId int64, namespace string, ke
 			return err
 		}
 
-		if has && item.Value == value {
+		if has && item.BlobKey == "" && item.Value == value {
 			kv.log.Debug("kvstore value not changed", "orgId", orgId, "namespace", namespace, "key", key, "value", value)
 			return nil
 		}
@@ -66,7 +73,8 @@ func (kv *kvStoreSQL) Set(ctx context.Context, orgId int64, namespace string, ke
 		item.Updated = time.Now()
 
 		if has {
-			_, err = dbSession.Exec("UPDATE kv_store SET value = ?, updated = ? WHERE id = ?", item.Value, item.Updated, item.Id)
+			// The value replaces the value of the blob bucket, if any.
+			_, err = dbSession.Exec("UPDATE kv_store SET value = ?, blob_key = NULL, updated = ? WHERE id = ?", item.Value, item.Updated, item.Id)
 			if err != nil {
 				kv.log.Debug("error updating kvstore value", "orgId", orgId, "namespace", namespace, "key", key, "value", value, "err", err)
 			} else {
@@ -123,14 +131,20 @@ func (kv *kvStoreSQL) GetAll(ctx context.Context, orgId int64, namespace string)
 
 		return query.Find(&results)
 	})
+	if err != nil {
+		return nil, err
+	}
 
 	items := map[int64]map[string]string{}
 	for _, r := range results {
+		if r.BlobKey != "" {
+			return nil, fmt.Errorf("%w: orgId=%d namespace=%q key=%q", errValueInBlob, *r.OrgId, *r.Namespace, *r.Key)
+		}
 		if _, ok := items[*r.OrgId]; !ok {
 			items[*r.OrgId] = map[string]string{}
 		}
 		items[*r.OrgId][*r.Key] = r.Value
 	}
 
-	return items, err
+	return items, nil
 }
@@ -0,0 +1,148 @@
+package serverlock
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// The keys of a lock share a hash tag, so that the scripts can be run in cluster mode.
+// The lock key holds the fencing token of the server holding the lock, and the token key
+// the last fencing token issued for the lock.
+
+// lockAtIntervalScript acquires the lock if it wasn't acquired during the last interval.
+// KEYS: execution key, token key. ARGV: now in ms, interval in ms.
+var lockAtIntervalScript = redis.NewScript(`
+local last = tonumber(redis.call('GET', KEYS[1]))
+if last and tonumber(ARGV[1]) - last < tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1])
+return redis.call('INCR', KEYS[2])
+`)
+
+// lockScript acquires the lock if it isn't held.
+// KEYS: lock key, token key. ARGV: expiration in ms.
+var lockScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return 0
+end
+local token = redis.call('INCR', KEYS[2])
+redis.call('SET', KEYS[1], token, 'PX', ARGV[1])
+return token
+`)
+
+// renewScript extends the lock if it is still held with the same token.
+// KEYS: lock key. ARGV: token, expiration in ms.
+var renewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript deletes the lock if it is still held with the same token.
+// KEYS: lock key. ARGV: token.
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// redisBackend stores the locks in redis. Locks expire after their maxInterval unless they are renewed,
+// and every acquisition of a lock gets a new fencing token.
+type redisBackend struct {
+	client redis.UniversalClient
+	prefix string
+	now    func() time.Time
+}
+
+func newRedisBackend(cfg setting.ServerLockSettings) (*redisBackend, error) {
+	var tlsConfig *tls.Config
+	if cfg.RedisTLSEnabled {
+		var err error
+		tlsConfig, err = cfg.RedisTLSConfig.GetTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	opts := &redis.UniversalOptions{
+		Addrs:     strings.Split(cfg.RedisAddr, ","),
+		Username:  cfg.RedisUsername,
+		Password:  cfg.RedisPassword,
+		DB:        cfg.RedisDB,
+		TLSConfig: tlsConfig,
+	}
+
+	var client redis.UniversalClient
+	if cfg.RedisClusterModeEnabled {
+		client = redis.NewClusterClient(opts.Cluster())
+	} else {
+		client = redis.NewClient(opts.Simple())
+	}
+
+	return &redisBackend{client: client, prefix: cfg.RedisPrefix, now: time.Now}, nil
+}
+
+func (b *redisBackend) key(actionName, suffix string) string {
+	return b.prefix + "{" + actionName + "}:" + suffix
+}
+
+func (b *redisBackend) lockAtInterval(ctx context.Context, actionName string, maxInterval time.Duration) (*lease, error) {
+	keys := []string{b.key(actionName, "execution"), b.key(actionName, "token")}
+	token, err := lockAtIntervalScript.Run(ctx, b.client, keys, b.now().UnixMilli(), maxInterval.Milliseconds()).Int64()
+	if err != nil || token == 0 {
+		return nil, err
+	}
+	return &lease{actionName: actionName, token: token}, nil
+}
+
+func (b *redisBackend) lock(ctx context.Context, actionName string, maxInterval time.Duration) (*lease, error) {
+	keys := []string{b.key(actionName, "lock"), b.key(actionName, "token")}
+	token, err := lockScript.Run(ctx, b.client, keys, expiration(maxInterval)).Int64()
+	if err != nil {
+		return nil, err
+	}
+	if token == 0 {
+		return nil, &ServerLockExistsError{actionName: actionName}
+	}
+	return &lease{actionName: actionName, token: token}, nil
+}
+
+func (b *redisBackend) renew(ctx context.Context, l *lease, maxInterval time.Duration) error {
+	keys := []string{b.key(l.actionName, "lock")}
+	renewed, err := renewScript.Run(ctx, b.client, keys, strconv.FormatInt(l.token, 10), expiration(maxInterval)).Int64()
+	if err != nil {
+		return err
+	}
+	if renewed == 0 {
+		return errLeaseLost
+	}
+	return nil
+}
+
+func (b *redisBackend) release(ctx context.Context, l *lease) error {
+	keys := []string{b.key(l.actionName, "lock")}
+	released, err := releaseScript.Run(ctx, b.client, keys, strconv.FormatInt(l.token, 10)).Int64()
+	if err != nil {
+		return err
+	}
+	if released == 0 {
+		return errors.New("lock was not held anymore: " + l.actionName)
+	}
+	return nil
+}
+
+// expiration returns the expiration of a lock in ms. Redis rejects expirations lower than 1 ms.
+func expiration(maxInterval time.Duration) int64 {
+	return max(maxInterval.Milliseconds(), 1)
+}
@@ -0,0 +1,139 @@
+package serverlock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func createTestableRedisServerLock(t *testing.T) (*ServerLockService, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	backend, err := newRedisBackend(setting.ServerLockSettings{
+		Type:        setting.ServerLockTypeRedis,
+		RedisAddr:   mr.Addr(),
+		RedisPrefix: "test:",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = backend.client.Close() })
+
+	return &ServerLockService{
+		backend: backend,
+		tracer:  tracing.InitializeTracerForTest(),
+		log:     log.New("test-logger"),
+	}, mr
+}
+
+func TestRedisServerLock_LockAndExecute(t *testing.T) {
+	sl, _ := createTestableRedisServerLock(t)
+	ctx := context.Background()
+
+	var tokens []int64
+	fn := func(ctx context.Context) {
+		token, ok := FencingToken(ctx)
+		require.True(t, ok)
+		tokens = append(tokens, token)
+	}
+
+	require.NoError(t, sl.LockAndExecute(ctx, "test-operation", time.Hour, fn))
+	require.NoError(t, sl.LockAndExecute(ctx, "test-operation", time.Hour, fn))
+	require.Equal(t, []int64{1}, tokens)
+
+	// the last execution is older than the interval
+	require.NoError(t, sl.LockAndExecute(ctx, "test-operation", -time.Millisecond, fn))
+	require.Equal(t, []int64{1, 2}, tokens)
+}
+
+func TestRedisServerLock_LockExecuteAndRelease(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("lock is released after execution", func(t *testing.T) {
+		sl, mr := createTestableRedisServerLock(t)
+
+		var tokens []int64
+		fn := func(ctx context.Context) {
+			assert.True(t, mr.Exists("test:{test-operation}:lock"))
+			token, _ := FencingToken(ctx)
+			tokens = append(tokens, token)
+		}
+
+		require.NoError(t, sl.LockExecuteAndRelease(ctx, "test-operation", time.Hour, fn))
+		require.NoError(t, sl.LockExecuteAndRelease(ctx, "test-operation", time.Hour, fn))
+		require.Equal(t, []int64{1, 2}, tokens)
+		require.False(t, mr.Exists("test:{test-operation}:lock"))
+	})
+
+	t.Run("lock held by another server is not acquired", func(t *testing.T) {
+		sl, _ := createTestableRedisServerLock(t)
+
+		l, err := sl.backend.lock(ctx, "test-operation", time.Hour)
+		require.NoError(t, err)
+
+		executed := false
+		err = sl.LockExecuteAndRelease(ctx, "test-operation", time.Hour, func(context.Context) { executed = true })
+		var lockedErr *ServerLockExistsError
+		require.ErrorAs(t, err, &lockedErr)
+		require.False(t, executed)
+
+		require.NoError(t, sl.backend.release(ctx, l))
+	})
+
+	t.Run("expired lock is acquired with a new token", func(t *testing.T) {
+		sl, mr := createTestableRedisServerLock(t)
+
+		stale, err := sl.backend.lock(ctx, "test-operation", time.Minute)
+		require.NoError(t, err)
+		mr.FastForward(time.Minute)
+
+		l, err := sl.backend.lock(ctx, "test-operation", time.Minute)
+		require.NoError(t, err)
+		require.Greater(t, l.token, stale.token)
+
+		// the server that lost the lock can neither renew nor release it
+		require.ErrorIs(t, sl.backend.(leaseRenewer).renew(ctx, stale, time.Minute), errLeaseLost)
+		require.Error(t, sl.backend.release(ctx, stale))
+		require.True(t, mr.Exists("test:{test-operation}:lock"))
+	})
+}
+
+func TestRedisServerLock_LeaseRenewal(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("lock is renewed while the function runs", func(t *testing.T) {
+		sl, mr := createTestableRedisServerLock(t)
+
+		err := sl.LockExecuteAndRelease(ctx, "test-operation", 300*time.Millisecond, func(ctx context.Context) {
+			mr.FastForward(250 * time.Millisecond)
+			assert.Eventually(t, func() bool {
+				return mr.TTL("test:{test-operation}:lock") > 200*time.Millisecond
+			}, time.Second, 10*time.Millisecond)
+			assert.NoError(t, ctx.Err())
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("context is canceled when the lock is lost", func(t *testing.T) {
+		sl, mr := createTestableRedisServerLock(t)
+
+		err := sl.LockExecuteAndRelease(ctx, "test-operation", 300*time.Millisecond, func(ctx context.Context) {
+			// another server takes over the lock
+			mr.Set("test:{test-operation}:lock", "100")
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Second):
+				t.Error("context was not canceled")
+			}
+		})
+		require.NoError(t, err)
+	})
+}
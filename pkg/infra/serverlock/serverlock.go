@@ -14,25 +14,78 @@ import (
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/tracing"
-	"github.com/grafana/grafana/pkg/services/sqlstore"
-	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
-func ProvideService(sqlStore db.DB, tracer tracing.Tracer) *ServerLockService {
-	return &ServerLockService{
-		SQLStore: sqlStore,
-		tracer:   tracer,
-		log:      log.New("infra.lockservice"),
+func ProvideService(sqlStore db.DB, tracer tracing.Tracer, cfg *setting.Cfg) (*ServerLockService, error) {
+	logger := log.New("infra.lockservice")
+
+	var backend lockBackend = &sqlBackend{SQLStore: sqlStore, tracer: tracer, log: logger}
+	if cfg.ServerLock.Type == setting.ServerLockTypeRedis {
+		redisBackend, err := newRedisBackend(cfg.ServerLock)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create redis server lock backend: %w", err)
+		}
+		backend = redisBackend
 	}
+
+	return &ServerLockService{
+		backend: backend,
+		tracer:  tracer,
+		log:     logger,
+	}, nil
 }
 
 // ServerLockService allows servers in HA mode to claim a lock and execute a function if the server was granted the lock
 // It exposes 2 services LockAndExecute and LockExecuteAndRelease, which are intended to be used independently, don't mix
 // them up (ie, use the same actionName for both of them).
 type ServerLockService struct {
-	SQLStore db.DB
-	tracer   tracing.Tracer
-	log      log.Logger
+	backend lockBackend
+	tracer  tracing.Tracer
+	log     log.Logger
+}
+
+// lockBackend stores the locks of the ServerLockService.
+type lockBackend interface {
+	// lockAtInterval acquires the lock of actionName if it was not acquired during the last maxInterval.
+	// It returns a nil lease if the lock was not acquired.
+	lockAtInterval(ctx context.Context, actionName string, maxInterval time.Duration) (*lease, error)
+	// lock acquires the lock of actionName until it is released, or considered dead after maxInterval.
+	// It returns a ServerLockExistsError if another server holds the lock.
+	lock(ctx context.Context, actionName string, maxInterval time.Duration) (*lease, error)
+	// release releases a lock acquired with lock.
+	release(ctx context.Context, l *lease) error
+}
+
+// leaseRenewer is implemented by the backends able to extend a lock while its function runs,
+// so that long-running functions don't lose their lock after maxInterval.
+type leaseRenewer interface {
+	// renew extends the lease for maxInterval. It returns errLeaseLost if the lock is no longer held.
+	renew(ctx context.Context, l *lease, maxInterval time.Duration) error
+}
+
+var errLeaseLost = errors.New("server lock lease lost")
+
+// lease is a lock held by this server.
+type lease struct {
+	actionName string
+	// token is the fencing token of the lock, increasing with every acquisition of the lock.
+	// It is 0 if the backend doesn't provide fencing tokens.
+	token int64
+}
+
+type leaseContextKey struct{}
+
+// FencingToken returns the fencing token of the lock held by the function executed by the ServerLockService.
+// Fencing tokens increase with every acquisition of a lock, so that storages written by the function can reject
+// writes made with an older token by a server that lost the lock. Only LockAndExecute and the redis backend provide
+// fencing tokens.
+func FencingToken(ctx context.Context) (int64, bool) {
+	l, ok := ctx.Value(leaseContextKey{}).(*lease)
+	if !ok || l.token == 0 {
+		return 0, false
+	}
+	return l.token, true
 }
 
 // LockAndExecute try to create a lock for this server and only executes the
@@ -47,29 +100,16 @@ func (sl *ServerLockService) LockAndExecute(ctx context.Context, actionName stri
 	ctxLogger := sl.log.FromContext(ctx)
 	ctxLogger.Debug("Start LockAndExecute", "actionName", actionName)
 
-	// gets or creates a lockable row
-	rowLock, err := sl.getOrCreate(ctx, actionName)
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, fmt.Sprintf("failed to getOrCreate serverlock: %v", err))
-		return err
-	}
-
-	// avoid execution if last lock happened less than `maxInterval` ago
-	if sl.isLockWithinInterval(rowLock, maxInterval) {
-		return nil
-	}
-
-	// try to get lock based on rowLock version
-	acquiredLock, err := sl.acquireLock(ctx, rowLock)
+	l, err := sl.backend.lockAtInterval(ctx, actionName, maxInterval)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, fmt.Sprintf("failed to acquire serverlock: %v", err))
 		return err
 	}
 
+	acquiredLock := l != nil
 	if acquiredLock {
-		sl.executeFunc(ctx, actionName, fn)
+		sl.executeFunc(context.WithValue(ctx, leaseContextKey{}, l), actionName, fn)
 	}
 
 	ctxLogger.Debug("LockAndExecute finished", "actionName", actionName, "acquiredLock", acquiredLock, "duration", time.Since(start))
@@ -77,68 +117,13 @@ func (sl *ServerLockService) LockAndExecute(ctx context.Context, actionName stri
 	return nil
 }
 
-func (sl *ServerLockService) acquireLock(ctx context.Context, serverLock *serverLock) (bool, error) {
-	ctx, span := sl.tracer.Start(ctx, "ServerLockService.acquireLock")
-	defer span.End()
-	var result bool
-
-	err := sl.SQLStore.WithDbSession(ctx, func(dbSession *db.Session) error {
-		newVersion := serverLock.Version + 1
-		sql := `UPDATE server_lock SET
-			version = ?,
-			last_execution = ?
-		WHERE
-			operation_uid = ? AND version = ?`
-
-		res, err := dbSession.Exec(sql, newVersion, time.Now().Unix(),
-			serverLock.OperationUID, serverLock.Version)
-		if err != nil {
-			return err
-		}
-
-		affected, err := res.RowsAffected()
-		result = affected == 1
-
-		return err
-	})
-
-	return result, err
-}
-
-func (sl *ServerLockService) getOrCreate(ctx context.Context, actionName string) (*serverLock, error) {
-	ctx, span := sl.tracer.Start(ctx, "ServerLockService.getOrCreate")
-	defer span.End()
-
-	var result *serverLock
-	err := sl.SQLStore.WithTransactionalDbSession(ctx, func(dbSession *db.Session) error {
-		sqlRes := &serverLock{}
-		has, err := dbSession.SQL("SELECT * FROM server_lock WHERE operation_uid = ?",
-			actionName).Get(sqlRes)
-		if err != nil {
-			return err
-		}
-
-		if has {
-			result = sqlRes
-			return nil
-		}
-
-		lockRow := &serverLock{
-			OperationUID:  actionName,
-			LastExecution: 0,
-		}
-		result, err = sl.createLock(ctx, lockRow, dbSession)
-		return err
-	})
-
-	return result, err
-}
-
 // LockExecuteAndRelease Creates the lock, executes the func, and then release the locks. The locking mechanism is
 // based on the UNIQUE constraint of the actionName in the database (column  operation_uid), so a new process can not insert
 // a new operation if already exists one. The parameter 'maxInterval' is a timeout safeguard, if the LastExecution in the
 // database is older than maxInterval, we will assume the lock as timeouted. The 'maxInterval' parameter should be so long
 // that is impossible for 2 processes to run at the same time.
+// Backends supporting lease renewal, such as redis, keep renewing the lock while 'fn' runs, and cancel the context of 'fn'
+// if the lock is lost.
 func (sl *ServerLockService) LockExecuteAndRelease(ctx context.Context, actionName string, maxInterval time.Duration, fn func(ctx context.Context)) error {
 	start := time.Now()
 	ctx, span := sl.tracer.Start(ctx, "ServerLockService.LockExecuteAndRelease")
@@ -148,7 +133,7 @@ func (sl *ServerLockService) LockExecuteAndRelease(ctx context.Context, actionNa
 	ctxLogger := sl.log.FromContext(ctx)
 	ctxLogger.Debug("Start LockExecuteAndRelease", "actionName", actionName)
 
-	err := sl.acquireForRelease(ctx, actionName, maxInterval)
+	l, err := sl.backend.lock(ctx, actionName, maxInterval)
 	// could not get the lock, returning
 	if err != nil {
 		span.RecordError(err)
@@ -156,9 +141,9 @@ func (sl *ServerLockService) LockExecuteAndRelease(ctx context.Context, actionNa
 		return err
 	}
 
-	sl.executeFunc(ctx, actionName, fn)
+	sl.executeWithLease(ctx, l, maxInterval, fn)
 
-	err = sl.releaseLock(ctx, actionName)
+	err = sl.backend.release(ctx, l)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, fmt.Sprintf("failed to release serverlock: %v", err))
@@ -192,9 +177,11 @@ func (sl *ServerLockService) LockExecuteAndReleaseWithRetries(ctx context.Contex
 
 	lockChecks := 0
 
+	var l *lease
 	for {
 		lockChecks++
-		err := sl.acquireForRelease(ctx, actionName, timeConfig.MaxInterval)
+		var err error
+		l, err = sl.backend.lock(ctx, actionName, timeConfig.MaxInterval)
 		// could not get the lock
 		if err != nil {
 			var lockedErr *ServerLockExistsError
@@ -223,9 +210,9 @@ func (sl *ServerLockService) LockExecuteAndReleaseWithRetries(ctx context.Contex
 		break
 	}
 
-	sl.executeFunc(ctx, actionName, fn)
+	sl.executeWithLease(ctx, l, timeConfig.MaxInterval, fn)
 
-	if err := sl.releaseLock(ctx, actionName); err != nil {
+	if err := sl.backend.release(ctx, l); err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, fmt.Sprintf("failed to release serverlock: %v", err))
 		ctxLogger.Error("Failed to release the lock", "error", err)
@@ -241,103 +228,6 @@ func lockWait(minWait time.Duration, maxWait time.Duration) time.Duration {
 	return time.Duration(rand.Int63n(int64(maxWait-minWait)) + int64(minWait))
 }
 
-// acquireForRelease will check if the lock is already on the database, if it is, will check with maxInterval if it is
-// timeouted. Returns nil error if the lock was acquired correctly
-func (sl *ServerLockService) acquireForRelease(ctx context.Context, actionName string, maxInterval time.Duration) error {
-	ctx, span := sl.tracer.Start(ctx, "ServerLockService.acquireForRelease")
-	defer span.End()
-
-	// getting the lock - as the action name has a Unique constraint, this will fail if the lock is already on the database
-	err := sl.SQLStore.WithTransactionalDbSession(ctx, func(dbSession *db.Session) error {
-		// we need to find if the lock is in the database
-		result := &serverLock{}
-		sqlRaw := `SELECT * FROM server_lock WHERE operation_uid = ?`
-		if sl.SQLStore.GetDBType() == migrator.MySQL || sl.SQLStore.GetDBType() == migrator.Postgres {
-			sqlRaw += ` FOR UPDATE`
-		}
-
-		has, err := dbSession.SQL(
-			sqlRaw,
-			actionName).Get(result)
-		if err != nil {
-			return err
-		}
-
-		ctxLogger := sl.log.FromContext(ctx)
-
-		if has {
-			if sl.isLockWithinInterval(result, maxInterval) {
-				return &ServerLockExistsError{actionName: actionName}
-			}
-			// lock has timed out, so we update the timestamp
-			result.LastExecution = time.Now().Unix()
-			res, err := dbSession.Exec("UPDATE server_lock SET last_execution = ? WHERE operation_uid = ?",
-				result.LastExecution, actionName)
-			if err != nil {
-				return err
-			}
-
-			affected, err := res.RowsAffected()
-			if err != nil {
-				ctxLogger.Error("Error getting rows affected", "actionName", actionName, "error", err)
-			}
-
-			if affected != 1 {
-				ctxLogger.Error("Expected rows affected to be 1 if there was no error", "actionName", actionName, "rowsAffected", affected)
-			}
-
-			return nil
-		}
-
-		// lock not found, creating it
-		lock := &serverLock{
-			OperationUID:  actionName,
-			LastExecution: time.Now().Unix(),
-		}
-		_, err = sl.createLock(ctx, lock, dbSession)
-		return err
-	})
-
-	return err
-}
-
-// releaseLock will delete the row at the database. This is only intended to be used within the scope of LockExecuteAndRelease
-// method, but not as to manually release a Lock
-func (sl *ServerLockService) releaseLock(ctx context.Context, actionName string) error {
-	ctx, span := sl.tracer.Start(ctx, "ServerLockService.releaseLock")
-	defer span.End()
-
-	err := sl.SQLStore.WithDbSession(ctx, func(dbSession *db.Session) error {
-		sql := `DELETE FROM server_lock WHERE operation_uid=? `
-
-		res, err := dbSession.Exec(sql, actionName)
-		if err != nil {
-			return err
-		}
-		affected, err := res.RowsAffected()
-		if err != nil {
-			sl.log.FromContext(ctx).Debug("Error getting rows affected", "actionName", actionName, "error", err)
-		}
-
-		if affected != 1 {
-			sl.log.FromContext(ctx).Debug("Error releasing lock", "actionName", actionName, "rowsAffected", affected)
-		}
-		return nil
-	})
-
-	return err
-}
-
-func (sl *ServerLockService) isLockWithinInterval(lock *serverLock, maxInterval time.Duration) bool {
-	if lock.LastExecution != 0 {
-		lastExecutionTime := time.Unix(lock.LastExecution, 0)
-		if time.Since(lastExecutionTime) < maxInterval {
-			return true
-		}
-	}
-	return false
-}
-
 func (sl *ServerLockService) executeFunc(ctx context.Context, actionName string, fn func(ctx context.Context)) {
 	start := time.Now()
 	ctx, span := sl.tracer.Start(ctx, "ServerLockService.executeFunc")
@@ -351,53 +241,52 @@ func (sl *ServerLockService) executeFunc(ctx context.Context, actionName string,
 	ctxLogger.Debug("Execution finished", "actionName", actionName, "duration", time.Since(start))
 }
 
-func (sl *ServerLockService) createLock(ctx context.Context,
-	lockRow *serverLock, dbSession *sqlstore.DBSession,
-) (*serverLock, error) {
-	affected := int64(1)
-	rawSQL := `INSERT INTO server_lock (operation_uid, last_execution, version) VALUES (?, ?, ?)`
-	if sl.SQLStore.GetDBType() == migrator.Postgres {
-		rawSQL += ` ON CONFLICT DO NOTHING RETURNING id`
-		var id int64
-		_, err := dbSession.SQL(rawSQL, lockRow.OperationUID, lockRow.LastExecution, 0).Get(&id)
-		if err != nil {
-			return nil, err
-		}
-		if id == 0 {
-			// Considering the default isolation level (READ COMMITTED), an entry could be added to the table
-			// between the SELECT and the INSERT. And inserting a row with the same operation_uid would violate the unique
-			// constraint. In this case, the ON CONFLICT DO NOTHING clause will prevent generating an error.
-			// And the returning id will be 0 which means that there wasn't any row inserted (another server has the lock),
-			// therefore we return the ServerLockExistsError.
-			// https://www.postgresql.org/docs/current/transaction-iso.html#XACT-READ-COMMITTED
-			return nil, &ServerLockExistsError{actionName: lockRow.OperationUID}
-		}
-		lockRow.Id = id
-	} else {
-		res, err := dbSession.Exec(
-			rawSQL,
-			lockRow.OperationUID, lockRow.LastExecution, 0)
-		if err != nil {
-			return nil, err
-		}
-		lastID, err := res.LastInsertId()
-		if err != nil {
-			sl.log.FromContext(ctx).Error("Error getting last insert id", "actionName", lockRow.OperationUID, "error", err)
-		}
-		lockRow.Id = lastID
+// executeWithLease executes fn while renewing its lease, if the backend supports it. The context of fn is canceled
+// if the lease is lost.
+func (sl *ServerLockService) executeWithLease(ctx context.Context, l *lease, maxInterval time.Duration, fn func(ctx context.Context)) {
+	ctx = context.WithValue(ctx, leaseContextKey{}, l)
 
-		affected, err = res.RowsAffected()
-		if err != nil {
-			sl.log.FromContext(ctx).Error("Error getting rows affected", "actionName", lockRow.OperationUID, "error", err)
-		}
+	renewer, ok := sl.backend.(leaseRenewer)
+	renewInterval := maxInterval / 3
+	if !ok || renewInterval <= 0 {
+		sl.executeFunc(ctx, l.actionName, fn)
+		return
 	}
 
-	if affected != 1 || lockRow.Id == 0 {
-		sl.log.FromContext(ctx).Error("Expected rows affected to be 1 if there was no error",
-			"actionName", lockRow.OperationUID,
-			"rowsAffected", affected,
-			"lockRow ID", lockRow.Id)
-	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		sl.renewLease(ctx, renewer, l, maxInterval, renewInterval, cancel)
+	}()
+
+	sl.executeFunc(ctx, l.actionName, fn)
+	cancel()
+	<-renewed
+}
+
+func (sl *ServerLockService) renewLease(ctx context.Context, renewer leaseRenewer, l *lease, maxInterval, renewInterval time.Duration, cancel context.CancelFunc) {
+	ticker := time.NewTicker(renewInterval)
+	defer ticker.Stop()
 
-	return lockRow, nil
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := renewer.renew(ctx, l, maxInterval)
+			if err == nil || ctx.Err() != nil {
+				continue
+			}
+			if errors.Is(err, errLeaseLost) {
+				sl.log.FromContext(ctx).Warn("Server lock lost, canceling execution", "actionName", l.actionName)
+				cancel()
+				return
+			}
+			// the lock is still held until it expires, so try again at the next tick
+			sl.log.FromContext(ctx).Warn("Failed to renew server lock", "actionName", l.actionName, "error", err)
+		}
+	}
 }
@@ -18,20 +18,30 @@ func TestMain(m *testing.M) {
 	testsuite.Run(m)
 }
 
-func createTestableServerLock(t *testing.T) *ServerLockService {
+func createTestableSQLBackend(t *testing.T) *sqlBackend {
 	t.Helper()
 
 	store := db.InitTestDB(t)
 
-	return &ServerLockService{
+	return &sqlBackend{
 		SQLStore: store,
 		tracer:   tracing.InitializeTracerForTest(),
 		log:      log.New("test-logger"),
 	}
 }
 
+func createTestableServerLock(t *testing.T) *ServerLockService {
+	t.Helper()
+
+	return &ServerLockService{
+		backend: createTestableSQLBackend(t),
+		tracer:  tracing.InitializeTracerForTest(),
+		log:     log.New("test-logger"),
+	}
+}
+
 func TestServerLock(t *testing.T) {
-	sl := createTestableServerLock(t)
+	sl := createTestableSQLBackend(t)
 	operationUID := "test-operation"
 
 	first, err := sl.getOrCreate(context.Background(), operationUID)
@@ -69,7 +79,7 @@ func TestLockAndRelease(t *testing.T) {
 	operationUID := "test-operation-release"
 
 	t.Run("create lock and then release it", func(t *testing.T) {
-		sl := createTestableServerLock(t)
+		sl := createTestableSQLBackend(t)
 		duration := time.Hour * 5
 
 		err := sl.acquireForRelease(context.Background(), operationUID, duration)
@@ -87,7 +97,7 @@ func TestLockAndRelease(t *testing.T) {
 	})
 
 	t.Run("try to acquire a lock which is already locked, get error", func(t *testing.T) {
-		sl := createTestableServerLock(t)
+		sl := createTestableSQLBackend(t)
 		duration := time.Hour * 5
 
 		err := sl.acquireForRelease(context.Background(), operationUID, duration)
@@ -102,7 +112,7 @@ func TestLockAndRelease(t *testing.T) {
 	})
 
 	t.Run("lock already exists but is timeouted", func(t *testing.T) {
-		sl := createTestableServerLock(t)
+		sl := createTestableSQLBackend(t)
 		pastLastExec := time.Now().Add(-time.Hour).Unix()
 		lock := serverLock{
 			OperationUID:  operationUID,
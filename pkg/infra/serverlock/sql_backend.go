@@ -0,0 +1,256 @@
+package serverlock
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+// sqlBackend stores the locks in the server_lock table of the Grafana database.
+type sqlBackend struct {
+	SQLStore db.DB
+	tracer   tracing.Tracer
+	log      log.Logger
+}
+
+func (b *sqlBackend) lockAtInterval(ctx context.Context, actionName string, maxInterval time.Duration) (*lease, error) {
+	// gets or creates a lockable row
+	rowLock, err := b.getOrCreate(ctx, actionName)
+	if err != nil {
+		return nil, err
+	}
+
+	// avoid execution if last lock happened less than `maxInterval` ago
+	if b.isLockWithinInterval(rowLock, maxInterval) {
+		return nil, nil
+	}
+
+	// try to get lock based on rowLock version
+	acquiredLock, err := b.acquireLock(ctx, rowLock)
+	if err != nil || !acquiredLock {
+		return nil, err
+	}
+
+	return &lease{actionName: actionName, token: rowLock.Version + 1}, nil
+}
+
+func (b *sqlBackend) lock(ctx context.Context, actionName string, maxInterval time.Duration) (*lease, error) {
+	if err := b.acquireForRelease(ctx, actionName, maxInterval); err != nil {
+		return nil, err
+	}
+	return &lease{actionName: actionName}, nil
+}
+
+func (b *sqlBackend) release(ctx context.Context, l *lease) error {
+	return b.releaseLock(ctx, l.actionName)
+}
+
+func (b *sqlBackend) acquireLock(ctx context.Context, serverLock *serverLock) (bool, error) {
+	ctx, span := b.tracer.Start(ctx, "ServerLockService.acquireLock")
+	defer span.End()
+	var result bool
+
+	err := b.SQLStore.WithDbSession(ctx, func(dbSession *db.Session) error {
+		newVersion := serverLock.Version + 1
+		sql := `UPDATE server_lock SET
+			version = ?,
+			last_execution = ?
+		WHERE
+			operation_uid = ? AND version = ?`
+
+		res, err := dbSession.Exec(sql, newVersion, time.Now().Unix(),
+			serverLock.OperationUID, serverLock.Version)
+		if err != nil {
+			return err
+		}
+
+		affected, err := res.RowsAffected()
+		result = affected == 1
+
+		return err
+	})
+
+	return result, err
+}
+
+func (b *sqlBackend) getOrCreate(ctx context.Context, actionName string) (*serverLock, error) {
+	ctx, span := b.tracer.Start(ctx, "ServerLockService.getOrCreate")
+	defer span.End()
+
+	var result *serverLock
+	err := b.SQLStore.WithTransactionalDbSession(ctx, func(dbSession *db.Session) error {
+		sqlRes := &serverLock{}
+		has, err := dbSession.SQL("SELECT * FROM server_lock WHERE operation_uid = ?",
+			actionName).Get(sqlRes)
+		if err != nil {
+			return err
+		}
+
+		if has {
+			result = sqlRes
+			return nil
+		}
+
+		lockRow := &serverLock{
+			OperationUID:  actionName,
+			LastExecution: 0,
+		}
+		result, err = b.createLock(ctx, lockRow, dbSession)
+		return err
+	})
+
+	return result, err
+}
+
+// acquireForRelease will check if the lock is already on the database, if it is, will check with maxInterval if it is
+// timeouted. Returns nil error if the lock was acquired correctly
+func (b *sqlBackend) acquireForRelease(ctx context.Context, actionName string, maxInterval time.Duration) error {
+	ctx, span := b.tracer.Start(ctx, "ServerLockService.acquireForRelease")
+	defer span.End()
+
+	// getting the lock - as the action name has a Unique constraint, this will fail if the lock is already on the database
+	err := b.SQLStore.WithTransactionalDbSession(ctx, func(dbSession *db.Session) error {
+		// we need to find if the lock is in the database
+		result := &serverLock{}
+		sqlRaw := `SELECT * FROM server_lock WHERE operation_uid = ?`
+		if b.SQLStore.GetDBType() == migrator.MySQL || b.SQLStore.GetDBType() == migrator.Postgres {
+			sqlRaw += ` FOR UPDATE`
+		}
+
+		has, err := dbSession.SQL(
+			sqlRaw,
+			actionName).Get(result)
+		if err != nil {
+			return err
+		}
+
+		ctxLogger := b.log.FromContext(ctx)
+
+		if has {
+			if b.isLockWithinInterval(result, maxInterval) {
+				return &ServerLockExistsError{actionName: actionName}
+			}
+			// lock has timed out, so we update the timestamp
+			result.LastExecution = time.Now().Unix()
+			res, err := dbSession.Exec("UPDATE server_lock SET last_execution = ? WHERE operation_uid = ?",
+				result.LastExecution, actionName)
+			if err != nil {
+				return err
+			}
+
+			affected, err := res.RowsAffected()
+			if err != nil {
+				ctxLogger.Error("Error getting rows affected", "actionName", actionName, "error", err)
+			}
+
+			if affected != 1 {
+				ctxLogger.Error("Expected rows affected to be 1 if there was no error", "actionName", actionName, "rowsAffected", affected)
+			}
+
+			return nil
+		}
+
+		// lock not found, creating it
+		lock := &serverLock{
+			OperationUID:  actionName,
+			LastExecution: time.Now().Unix(),
+		}
+		_, err = b.createLock(ctx, lock, dbSession)
+		return err
+	})
+
+	return err
+}
+
+// releaseLock will delete the row at the database. This is only intended to be used within the scope of LockExecuteAndRelease
+// method, but not as to manually release a Lock
+func (b *sqlBackend) releaseLock(ctx context.Context, actionName string) error {
+	ctx, span := b.tracer.Start(ctx, "ServerLockService.releaseLock")
+	defer span.End()
+
+	err := b.SQLStore.WithDbSession(ctx, func(dbSession *db.Session) error {
+		sql := `DELETE FROM server_lock WHERE operation_uid=? `
+
+		res, err := dbSession.Exec(sql, actionName)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			b.log.FromContext(ctx).Debug("Error getting rows affected", "actionName", actionName, "error", err)
+		}
+
+		if affected != 1 {
+			b.log.FromContext(ctx).Debug("Error releasing lock", "actionName", actionName, "rowsAffected", affected)
+		}
+		return nil
+	})
+
+	return err
+}
+
+func (b *sqlBackend) isLockWithinInterval(lock *serverLock, maxInterval time.Duration) bool {
+	if lock.LastExecution != 0 {
+		lastExecutionTime := time.Unix(lock.LastExecution, 0)
+		if time.Since(lastExecutionTime) < maxInterval {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *sqlBackend) createLock(ctx context.Context,
+	lockRow *serverLock, dbSession *sqlstore.DBSession,
+) (*serverLock, error) {
+	affected := int64(1)
+	rawSQL := `INSERT INTO server_lock (operation_uid, last_execution, version) VALUES (?, ?, ?)`
+	if b.SQLStore.GetDBType() == migrator.Postgres {
+		rawSQL += ` ON CONFLICT DO NOTHING RETURNING id`
+		var id int64
+		_, err := dbSession.SQL(rawSQL, lockRow.OperationUID, lockRow.LastExecution, 0).Get(&id)
+		if err != nil {
+			return nil, err
+		}
+		if id == 0 {
+			// Considering the default isolation level (READ COMMITTED), an entry could be added to the table
+			// between the SELECT and the INSERT. And inserting a row with the same operation_uid would violate the unique
+			// constraint. In this case, the ON CONFLICT DO NOTHING clause will prevent generating an error.
+			// And the returning id will be 0 which means that there wasn't any row inserted (another server has the lock),
+			// therefore we return the ServerLockExistsError.
+			// https://www.postgresql.org/docs/current/transaction-iso.html#XACT-READ-COMMITTED
+			return nil, &ServerLockExistsError{actionName: lockRow.OperationUID}
+		}
+		lockRow.Id = id
+	} else {
+		res, err := dbSession.Exec(
+			rawSQL,
+			lockRow.OperationUID, lockRow.LastExecution, 0)
+		if err != nil {
+			return nil, err
+		}
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			b.log.FromContext(ctx).Error("Error getting last insert id", "actionName", lockRow.OperationUID, "error", err)
+		}
+		lockRow.Id = lastID
+
+		affected, err = res.RowsAffected()
+		if err != nil {
+			b.log.FromContext(ctx).Error("Error getting rows affected", "actionName", lockRow.OperationUID, "error", err)
+		}
+	}
+
+	if affected != 1 || lockRow.Id == 0 {
+		b.log.FromContext(ctx).Error("Expected rows affected to be 1 if there was no error",
+			"actionName", lockRow.OperationUID,
+			"rowsAffected", affected,
+			"lockRow ID", lockRow.Id)
+	}
+
+	return lockRow, nil
+}
@@ -10,7 +10,9 @@ import (
 
 var (
 	ErrTimerangeMissing     = errors.New("missing timerange")
+	ErrTooManyExportedItems = errors.New("too many annotations")
 	ErrBaseTagLimitExceeded = errutil.BadRequest("annotations.tag-limit-exceeded", errutil.WithPublicMessage("Tags length exceeds the maximum allowed."))
+	ErrInvalidTimeMatch     = errutil.BadRequest("annotations.invalid-time-match", errutil.WithPublicMessage("Time match must be one of overlap, start or within."))
 )
 
 //go:generate mockery --name Repository --structname FakeAnnotationsRepo --inpackage --filename annotations_repository_mock.go
@@ -23,7 +25,7 @@ type Repository interface {
 	FindTags(ctx context.Context, query *TagsQuery) (FindTagsResult, error)
 }
 
-// Cleaner is responsible for cleaning up old annotations
+// Cleaner is responsible for cleaning up old annotations, except those of the excluded organizations
 type Cleaner interface {
-	Run(ctx context.Context, cfg *setting.Cfg) (int64, int64, error)
+	Run(ctx context.Context, cfg *setting.Cfg, excludedOrgIDs []int64) (int64, int64, error)
 }
@@ -2,6 +2,8 @@ package annotationsimpl
 
 import (
 	"context"
+	"strconv"
+	"strings"
 
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/infra/log"
@@ -30,23 +32,35 @@ const (
 // from the annotation_tag table. Cleanup actions are performed in batches
 // so that no query takes too long to complete.
 //
+// The annotations of the excluded organizations are left alone, and do not
+// count towards the maximum number of annotations.
+//
 // Returns the number of annotation and annotation_tag rows deleted. If an
 // error occurs, it returns the number of rows affected so far.
-func (cs *CleanupServiceImpl) Run(ctx context.Context, cfg *setting.Cfg) (int64, int64, error) {
+func (cs *CleanupServiceImpl) Run(ctx context.Context, cfg *setting.Cfg, excludedOrgIDs []int64) (int64, int64, error) {
+	orgFilter := ""
+	if len(excludedOrgIDs) > 0 {
+		ids := make([]string, 0, len(excludedOrgIDs))
+		for _, id := range excludedOrgIDs {
+			ids = append(ids, strconv.FormatInt(id, 10))
+		}
+		orgFilter = " AND org_id NOT IN (" + strings.Join(ids, ",") + ")"
+	}
+
 	var totalCleanedAnnotations int64
-	affected, err := cs.store.CleanAnnotations(ctx, cfg.AlertingAnnotationCleanupSetting, alertAnnotationType)
+	affected, err := cs.store.CleanAnnotations(ctx, cfg.AlertingAnnotationCleanupSetting, alertAnnotationType+orgFilter)
 	totalCleanedAnnotations += affected
 	if err != nil {
 		return totalCleanedAnnotations, 0, err
 	}
 
-	affected, err = cs.store.CleanAnnotations(ctx, cfg.APIAnnotationCleanupSettings, apiAnnotationType)
+	affected, err = cs.store.CleanAnnotations(ctx, cfg.APIAnnotationCleanupSettings, apiAnnotationType+orgFilter)
 	totalCleanedAnnotations += affected
 	if err != nil {
 		return totalCleanedAnnotations, 0, err
 	}
 
-	affected, err = cs.store.CleanAnnotations(ctx, cfg.DashboardAnnotationCleanupSettings, dashboardAnnotationType)
+	affected, err = cs.store.CleanAnnotations(ctx, cfg.DashboardAnnotationCleanupSettings, dashboardAnnotationType+orgFilter)
 	totalCleanedAnnotations += affected
 	if err != nil {
 		return totalCleanedAnnotations, 0, err
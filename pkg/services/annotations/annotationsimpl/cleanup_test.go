@@ -28,6 +28,7 @@ func TestIntegrationAnnotationCleanUp(t *testing.T) {
 		createOldAnnotationsNum int
 
 		cfg                           *setting.Cfg
+		excludedOrgIDs                []int64
 		alertAnnotationCount          int64
 		annotationCleanupJobBatchSize int
 		dashboardAnnotationCount      int64
@@ -64,6 +65,22 @@ func TestIntegrationAnnotationCleanUp(t *testing.T) {
 			APIAnnotationCount:       5,
 			affectedAnnotations:      6,
 		},
+		{
+			name:                          "should not remove annotations of excluded organizations",
+			createAnnotationsNum:          21,
+			createOldAnnotationsNum:       6,
+			annotationCleanupJobBatchSize: 1,
+			cfg: &setting.Cfg{
+				AlertingAnnotationCleanupSetting:   settingsFn(time.Hour*48, 3),
+				DashboardAnnotationCleanupSettings: settingsFn(time.Hour*48, 3),
+				APIAnnotationCleanupSettings:       settingsFn(time.Hour*48, 3),
+			},
+			excludedOrgIDs:           []int64{1},
+			alertAnnotationCount:     7,
+			dashboardAnnotationCount: 7,
+			APIAnnotationCount:       7,
+			affectedAnnotations:      0,
+		},
 		{
 			name:                          "should only keep three annotations",
 			createAnnotationsNum:          15,
@@ -129,7 +146,7 @@ func TestIntegrationAnnotationCleanUp(t *testing.T) {
 			cfg := setting.NewCfg()
 			cfg.AnnotationCleanupJobBatchSize = int64(test.annotationCleanupJobBatchSize)
 			cleaner := ProvideCleanupService(fakeSQL, cfg)
-			affectedAnnotations, affectedAnnotationTags, err := cleaner.Run(context.Background(), test.cfg)
+			affectedAnnotations, affectedAnnotationTags, err := cleaner.Run(context.Background(), test.cfg, test.excludedOrgIDs)
 			require.NoError(t, err)
 
 			assert.Equal(t, test.affectedAnnotations, affectedAnnotations)
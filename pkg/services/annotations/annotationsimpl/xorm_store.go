@@ -112,24 +112,27 @@ func (r *xormRepositoryImpl) AddMany(ctx context.Context, items []annotations.It
 		}
 	}
 
-	return r.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
-		// We can batch-insert every annotation with no tags. If an annotation has tags, we need the ID.
-		opts := sqlstore.NativeSettingsForDialect(r.db.GetDialect())
-		if _, err := sess.BulkInsert("annotation", hasNoTags, opts); err != nil {
-			return err
-		}
-
-		for i, item := range hasTags {
-			if _, err := sess.Table("annotation").Insert(item); err != nil {
+	// Either all the annotations are saved, or none of them.
+	return r.db.InTransaction(ctx, func(ctx context.Context) error {
+		return r.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+			// We can batch-insert every annotation with no tags. If an annotation has tags, we need the ID.
+			opts := sqlstore.NativeSettingsForDialect(r.db.GetDialect())
+			if _, err := sess.BulkInsert("annotation", hasNoTags, opts); err != nil {
 				return err
 			}
-			itemWithID := &hasTags[i]
-			if err := r.ensureTags(ctx, itemWithID.ID, itemWithID.Tags); err != nil {
-				return err
+
+			for i, item := range hasTags {
+				if _, err := sess.Table("annotation").Insert(item); err != nil {
+					return err
+				}
+				itemWithID := &hasTags[i]
+				if err := r.ensureTags(ctx, itemWithID.ID, itemWithID.Tags); err != nil {
+					return err
+				}
 			}
-		}
 
-		return nil
+			return nil
+		})
 	})
 }
 
@@ -305,8 +308,17 @@ func (r *xormRepositoryImpl) Get(ctx context.Context, query annotations.ItemQuer
 		}
 
 		if query.From > 0 && query.To > 0 {
-			sql.WriteString(` AND a.epoch <= ? AND a.epoch_end >= ?`)
-			params = append(params, query.To, query.From)
+			switch query.TimeMatch {
+			case annotations.TimeMatchStart:
+				sql.WriteString(` AND a.epoch >= ? AND a.epoch <= ?`)
+				params = append(params, query.From, query.To)
+			case annotations.TimeMatchWithin:
+				sql.WriteString(` AND a.epoch >= ? AND a.epoch_end <= ?`)
+				params = append(params, query.From, query.To)
+			default:
+				sql.WriteString(` AND a.epoch <= ? AND a.epoch_end >= ?`)
+				params = append(params, query.To, query.From)
+			}
 		}
 
 		if query.Type == "alert" {
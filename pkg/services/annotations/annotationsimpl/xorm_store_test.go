@@ -216,6 +216,24 @@ func TestIntegrationAnnotations(t *testing.T) {
 			assert.Len(t, inserted, count)
 		})
 
+		t.Run("Batch-insert saves all annotations or none", func(t *testing.T) {
+			items := []annotations.Item{
+				{OrgID: 102, Type: "batch", Epoch: 12},
+				{ID: 100002, OrgID: 102, Type: "batch", Epoch: 12, Tags: []string{"type:test"}},
+				// Fails because of the duplicated ID, after the other annotations are inserted.
+				{ID: 100002, OrgID: 102, Type: "batch", Epoch: 12, Tags: []string{"type:test"}},
+			}
+
+			err := store.AddMany(context.Background(), items)
+
+			require.Error(t, err)
+			query := annotations.ItemQuery{OrgID: 102, SignedInUser: testUser}
+			accRes := &annotation_ac.AccessResources{CanAccessOrgAnnotations: true}
+			inserted, err := store.Get(context.Background(), query, accRes)
+			require.NoError(t, err)
+			assert.Empty(t, inserted)
+		})
+
 		t.Run("Can query for annotation by id", func(t *testing.T) {
 			items, err := store.Get(context.Background(), annotations.ItemQuery{
 				OrgID:        1,
@@ -248,6 +266,30 @@ func TestIntegrationAnnotations(t *testing.T) {
 			assert.Empty(t, items)
 		})
 
+		t.Run("Should match region annotations by time match", func(t *testing.T) {
+			accRes := &annotation_ac.AccessResources{SkipAccessControlFilter: true}
+			find := func(from, to int64, timeMatch annotations.TimeMatch) []*annotations.ItemDTO {
+				items, err := store.Get(context.Background(), annotations.ItemQuery{
+					OrgID:        1,
+					DashboardID:  dashboard2.ID,
+					From:         from,
+					To:           to,
+					TimeMatch:    timeMatch,
+					SignedInUser: testUser,
+				}, accRes)
+				require.NoError(t, err)
+				return items
+			}
+
+			// annotation2 is a region from 20 to 21.
+			assert.Len(t, find(21, 30, ""), 1)
+			assert.Len(t, find(21, 30, annotations.TimeMatchOverlap), 1)
+			assert.Empty(t, find(21, 30, annotations.TimeMatchStart))
+			assert.Len(t, find(15, 20, annotations.TimeMatchStart), 1)
+			assert.Len(t, find(19, 21, annotations.TimeMatchWithin), 1)
+			assert.Empty(t, find(19, 20, annotations.TimeMatchWithin))
+		})
+
 		t.Run("Should not find one when tag filter does not match", func(t *testing.T) {
 			accRes := &annotation_ac.AccessResources{
 				Dashboards:               map[string]int64{"foo": 1},
@@ -13,6 +13,6 @@ func NewFakeCleaner() *fakeCleaner {
 	return &fakeCleaner{}
 }
 
-func (f *fakeCleaner) Run(ctx context.Context, cfg *setting.Cfg) (int64, int64, error) {
+func (f *fakeCleaner) Run(ctx context.Context, cfg *setting.Cfg, excludedOrgIDs []int64) (int64, int64, error) {
 	return 0, 0, nil
 }
@@ -0,0 +1,66 @@
+package annotations
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+// maxExportLineSize is the maximum size of an exported annotation.
+const maxExportLineSize = 1024 * 1024
+
+// ExportedItem is an annotation in the NDJSON export format, one annotation per line.
+// Dashboards are referenced by UID so that annotations can be imported into another instance.
+type ExportedItem struct {
+	DashboardUID string           `json:"dashboardUID,omitempty"`
+	PanelID      int64            `json:"panelId,omitempty"`
+	Time         int64            `json:"time"`
+	TimeEnd      int64            `json:"timeEnd,omitempty"`
+	Text         string           `json:"text"`
+	Tags         []string         `json:"tags,omitempty"`
+	Data         *simplejson.Json `json:"data,omitempty"`
+}
+
+// WriteExport writes the annotations to w as NDJSON.
+func WriteExport(w io.Writer, items []ExportedItem) error {
+	enc := json.NewEncoder(w)
+	for _, item := range items {
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadExport reads NDJSON annotations written by WriteExport. Empty lines are ignored. It fails if there are
+// more than maxItems annotations.
+func ReadExport(r io.Reader, maxItems int) ([]ExportedItem, error) {
+	items := make([]ExportedItem, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxExportLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		if len(items) == maxItems {
+			return nil, fmt.Errorf("line %d: %w, the maximum is %d", line, ErrTooManyExportedItems, maxItems)
+		}
+		var item ExportedItem
+		if err := json.Unmarshal(data, &item); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if item.Time == 0 && item.TimeEnd == 0 {
+			return nil, fmt.Errorf("line %d: %w", line, ErrTimerangeMissing)
+		}
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
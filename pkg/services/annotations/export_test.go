@@ -0,0 +1,63 @@
+package annotations
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+func TestExport(t *testing.T) {
+	t.Run("round trips annotations", func(t *testing.T) {
+		items := []ExportedItem{
+			{DashboardUID: "abc", PanelID: 2, Time: 10, TimeEnd: 20, Text: "deploy", Tags: []string{"deploy", "version:1.2"}, Data: simplejson.NewFromAny(map[string]any{"sha": "f00"})},
+			{Time: 30, Text: "org annotation"},
+		}
+
+		var buf bytes.Buffer
+		require.NoError(t, WriteExport(&buf, items))
+		assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+
+		read, err := ReadExport(&buf, 10)
+		require.NoError(t, err)
+		assert.Equal(t, items, read)
+	})
+
+	t.Run("ignores empty lines", func(t *testing.T) {
+		read, err := ReadExport(strings.NewReader("\n{\"time\": 1, \"text\": \"a\"}\n\n"), 1)
+		require.NoError(t, err)
+		assert.Len(t, read, 1)
+	})
+
+	t.Run("reports the invalid line", func(t *testing.T) {
+		_, err := ReadExport(strings.NewReader("{\"time\": 1, \"text\": \"a\"}\n{\"text\": \"no time\"}\n"), 10)
+		require.ErrorIs(t, err, ErrTimerangeMissing)
+		assert.Contains(t, err.Error(), "line 2")
+
+		_, err = ReadExport(strings.NewReader("{\"time\": 1,"), 10)
+		assert.ErrorContains(t, err, "line 1")
+	})
+
+	t.Run("limits the number of annotations", func(t *testing.T) {
+		_, err := ReadExport(strings.NewReader("{\"time\": 1, \"text\": \"a\"}\n{\"time\": 2, \"text\": \"b\"}\n"), 1)
+		require.ErrorIs(t, err, ErrTooManyExportedItems)
+		assert.Contains(t, err.Error(), "line 2")
+	})
+}
+
+func TestParseTimeMatch(t *testing.T) {
+	m, err := ParseTimeMatch("")
+	require.NoError(t, err)
+	assert.Equal(t, TimeMatchOverlap, m)
+
+	m, err = ParseTimeMatch("within")
+	require.NoError(t, err)
+	assert.Equal(t, TimeMatchWithin, m)
+
+	_, err = ParseTimeMatch("end")
+	assert.ErrorIs(t, err, ErrInvalidTimeMatch)
+}
@@ -18,12 +18,37 @@ type ItemQuery struct {
 	Tags         []string `json:"tags"`
 	Type         string   `json:"type"`
 	MatchAny     bool     `json:"matchAny"`
+	// TimeMatch is how annotations are matched against the time range [From, To]. Defaults to TimeMatchOverlap.
+	TimeMatch    TimeMatch `json:"timeMatch"`
 	SignedInUser identity.Requester
 
 	Limit int64 `json:"limit"`
 	Page  int64
 }
 
+// TimeMatch is how the time range of a query matches region annotations.
+type TimeMatch string
+
+const (
+	// TimeMatchOverlap matches the annotations overlapping the time range.
+	TimeMatchOverlap TimeMatch = "overlap"
+	// TimeMatchStart matches the annotations starting in the time range.
+	TimeMatchStart TimeMatch = "start"
+	// TimeMatchWithin matches the annotations that start and end in the time range.
+	TimeMatchWithin TimeMatch = "within"
+)
+
+// ParseTimeMatch returns the TimeMatch named by s, or TimeMatchOverlap if s is empty.
+func ParseTimeMatch(s string) (TimeMatch, error) {
+	switch m := TimeMatch(s); m {
+	case "":
+		return TimeMatchOverlap, nil
+	case TimeMatchOverlap, TimeMatchStart, TimeMatchWithin:
+		return m, nil
+	}
+	return "", ErrInvalidTimeMatch.Errorf("invalid time match %q", s)
+}
+
 // TagsQuery is the query for a tags search.
 type TagsQuery struct {
 	OrgID int64  `json:"orgId"`
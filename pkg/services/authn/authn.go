@@ -30,12 +30,16 @@ const (
 	ClientProxy        = "auth.client.proxy"
 	ClientSAML         = "auth.client.saml"
 	ClientPasswordless = "auth.client.passwordless"
+	ClientMTLS         = "auth.client.mtls"
 )
 
 const (
 	MetaKeyUsername            = "username"
 	MetaKeyAuthModule          = "authModule"
 	MetaKeyIsLogin             = "isLogin"
+	MetaKeyTOTPCode            = "totpCode"
+	MetaKeyTOTPSecret          = "totpSecret"
+	MetaKeyTOTPRecoveryCodes   = "totpRecoveryCodes"
 	defaultRedirectToCookieKey = "redirect_to"
 )
 
@@ -253,7 +257,15 @@ type RedirectValidator func(url string) error
 
 // HandleLoginResponse is a utility function to perform common operations after a successful login and returns response.NormalResponse
 func HandleLoginResponse(r *http.Request, w http.ResponseWriter, cfg *setting.Cfg, identity *Identity, validator RedirectValidator, features featuremgmt.FeatureToggles) *response.NormalResponse {
+	return HandleLoginResponseWithResult(r, w, cfg, identity, validator, features, nil)
+}
+
+// HandleLoginResponseWithResult is HandleLoginResponse with additional fields in the response body
+func HandleLoginResponseWithResult(r *http.Request, w http.ResponseWriter, cfg *setting.Cfg, identity *Identity, validator RedirectValidator, features featuremgmt.FeatureToggles, fields map[string]any) *response.NormalResponse {
 	result := map[string]any{"message": "Logged in"}
+	for k, v := range fields {
+		result[k] = v
+	}
 	result["redirectUrl"] = handleLogin(r, w, cfg, identity, validator, features, "")
 	return response.JSON(http.StatusOK, result)
 }
@@ -23,6 +23,10 @@ type Form struct {
 type loginForm struct {
 	Username string `json:"user" binding:"Required"`
 	Password string `json:"password" binding:"Required"`
+	// OTP is the code of the second factor, or a recovery code.
+	OTP string `json:"otp"`
+	// TOTPSecret is the secret enrolled on login, when the second factor is required.
+	TOTPSecret string `json:"totpSecret"`
 }
 
 func (c *Form) Name() string {
@@ -34,6 +38,8 @@ func (c *Form) Authenticate(ctx context.Context, r *authn.Request) (*authn.Ident
 	if err := web.Bind(r.HTTPRequest, &form); err != nil {
 		return nil, errBadForm.Errorf("failed to parse request: %w", err)
 	}
+	r.SetMeta(authn.MetaKeyTOTPCode, form.OTP)
+	r.SetMeta(authn.MetaKeyTOTPSecret, form.TOTPSecret)
 	return c.client.AuthenticatePassword(ctx, r, form.Username, form.Password)
 }
 
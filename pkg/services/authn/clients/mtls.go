@@ -0,0 +1,322 @@
+package clients
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	"github.com/grafana/authlib/claims"
+
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/login/social/connectors"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+var _ authn.ContextAwareClient = new(MTLS)
+
+var (
+	errMTLSInvalidCertificate = errutil.Unauthorized(
+		"mtls.invalid-certificate", errutil.WithPublicMessage("Failed to verify client certificate"))
+	errMTLSMissingAttribute = errutil.Unauthorized(
+		"mtls.missing-attribute", errutil.WithPublicMessage("Missing login and email in client certificate"))
+	errMTLSInvalidRole = errutil.Forbidden(
+		"mtls.invalid-role", errutil.WithPublicMessage("Invalid role in client certificate"))
+	errMTLSServiceAccountNotFound = errutil.Unauthorized(
+		"mtls.service-account-not-found", errutil.WithPublicMessage("Service account of client certificate not found"))
+)
+
+func ProvideMTLS(cfg *setting.Cfg, userService user.Service, orgRoleMapper *connectors.OrgRoleMapper) (*MTLS, error) {
+	roots, err := cfg.MTLSAuth.ClientCAs()
+	if err != nil {
+		return nil, err
+	}
+
+	// The certificate in the header is public, only the proxy verifying the TLS connection can be trusted.
+	if cfg.MTLSAuth.HeaderName != "" && cfg.MTLSAuth.Whitelist == "" {
+		return nil, fmt.Errorf("a whitelist of proxies is required to read client certificates from header %q", cfg.MTLSAuth.HeaderName)
+	}
+	list, err := parseAcceptList(cfg.MTLSAuth.Whitelist)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MTLS{
+		cfg:           cfg,
+		log:           log.New(authn.ClientMTLS),
+		userService:   userService,
+		orgRoleMapper: orgRoleMapper,
+		orgMapping:    orgRoleMapper.ParseOrgMappingSettings(context.Background(), cfg.MTLSAuth.OrgMapping, cfg.MTLSAuth.RoleAttributeStrict),
+		roots:         roots,
+		acceptedIPs:   list,
+	}, nil
+}
+
+// MTLS authenticates requests by a client certificate issued by a trusted certificate authority.
+// The certificate is read from the TLS connection, or from a header set by a proxy terminating TLS.
+type MTLS struct {
+	cfg           *setting.Cfg
+	log           log.Logger
+	userService   user.Service
+	orgRoleMapper *connectors.OrgRoleMapper
+	orgMapping    connectors.MappingConfiguration
+	roots         *x509.CertPool
+	acceptedIPs   []*net.IPNet
+}
+
+func (c *MTLS) Name() string {
+	return authn.ClientMTLS
+}
+
+func (c *MTLS) Authenticate(ctx context.Context, r *authn.Request) (*authn.Identity, error) {
+	cert, err := c.verifiedCertificate(r)
+	if err != nil {
+		c.log.FromContext(ctx).Debug("Failed to verify client certificate", "error", err)
+		return nil, errMTLSInvalidCertificate.Errorf("failed to verify client certificate: %w", err)
+	}
+	attributes := certificateAttributes(cert)
+
+	if path := c.cfg.MTLSAuth.ServiceAccountAttributePath; path != "" {
+		serviceAccount, err := util.SearchJSONForStringAttr(path, attributes)
+		if err != nil {
+			return nil, err
+		}
+		if serviceAccount != "" {
+			return c.serviceAccountIdentity(ctx, serviceAccount)
+		}
+	}
+
+	id := &authn.Identity{
+		AuthenticatedBy: login.MTLSAuthModule,
+		OrgRoles:        map[int64]org.RoleType{},
+		ClientParams: authn.ClientParams{
+			SyncUser:        true,
+			FetchSyncedUser: true,
+			SyncPermissions: true,
+			SyncOrgRoles:    !c.cfg.MTLSAuth.SkipOrgRoleSync,
+			AllowSignUp:     c.cfg.MTLSAuth.AutoSignUp,
+		},
+	}
+
+	if id.Login, err = searchAttribute(c.cfg.MTLSAuth.LoginAttributePath, attributes); err != nil {
+		return nil, err
+	}
+	if id.Email, err = searchAttribute(c.cfg.MTLSAuth.EmailAttributePath, attributes); err != nil {
+		return nil, err
+	}
+	if id.Name, err = searchAttribute(c.cfg.MTLSAuth.NameAttributePath, attributes); err != nil {
+		return nil, err
+	}
+
+	if id.Login == "" && id.Email == "" {
+		c.log.FromContext(ctx).Debug("Failed to get login and email from client certificate", "subject", cert.Subject.String())
+		return nil, errMTLSMissingAttribute.Errorf("missing login and email in client certificate %q", cert.Subject.String())
+	}
+	if id.Login != "" {
+		id.AuthID = id.Login
+		id.ClientParams.LookUpParams.Login = &id.Login
+	}
+	if id.Email != "" {
+		if id.AuthID == "" {
+			id.AuthID = id.Email
+		}
+		id.ClientParams.LookUpParams.Email = &id.Email
+	}
+
+	if !c.cfg.MTLSAuth.SkipOrgRoleSync {
+		role, isGrafanaAdmin, err := c.extractRoleAndAdmin(attributes)
+		if err != nil {
+			return nil, err
+		}
+		if c.cfg.MTLSAuth.RoleAttributeStrict && !role.IsValid() {
+			return nil, errMTLSInvalidRole.Errorf("invalid role in client certificate: %s", role)
+		}
+
+		var orgs []string
+		if path := c.cfg.MTLSAuth.OrgAttributePath; path != "" {
+			if orgs, err = util.SearchJSONForStringSliceAttr(path, attributes); err != nil {
+				return nil, err
+			}
+		}
+		id.OrgRoles = c.orgRoleMapper.MapOrgRoles(c.orgMapping, orgs, role)
+
+		if c.cfg.MTLSAuth.AllowAssignGrafanaAdmin {
+			id.IsGrafanaAdmin = &isGrafanaAdmin
+		}
+	}
+
+	return id, nil
+}
+
+func (c *MTLS) IsEnabled() bool {
+	return c.cfg.MTLSAuth.Enabled
+}
+
+func (c *MTLS) Test(ctx context.Context, r *authn.Request) bool {
+	if r.HTTPRequest == nil {
+		return false
+	}
+	if r.HTTPRequest.TLS != nil && len(r.HTTPRequest.TLS.PeerCertificates) > 0 {
+		return true
+	}
+	return c.cfg.MTLSAuth.HeaderName != "" && r.HTTPRequest.Header.Get(c.cfg.MTLSAuth.HeaderName) != ""
+}
+
+func (c *MTLS) Priority() uint {
+	return 45
+}
+
+// verifiedCertificate returns the client certificate of the request, once verified against the
+// configured certificate authorities.
+func (c *MTLS) verifiedCertificate(r *authn.Request) (*x509.Certificate, error) {
+	certs, err := c.peerCertificates(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no client certificate")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = certs[0].Verify(x509.VerifyOptions{
+		Roots:         c.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return certs[0], nil
+}
+
+func (c *MTLS) peerCertificates(r *authn.Request) ([]*x509.Certificate, error) {
+	if r.HTTPRequest.TLS != nil && len(r.HTTPRequest.TLS.PeerCertificates) > 0 {
+		return r.HTTPRequest.TLS.PeerCertificates, nil
+	}
+
+	if c.cfg.MTLSAuth.HeaderName == "" {
+		return nil, nil
+	}
+	value := r.HTTPRequest.Header.Get(c.cfg.MTLSAuth.HeaderName)
+	if value == "" {
+		return nil, nil
+	}
+	if !isAcceptedIP(r, c.acceptedIPs) {
+		return nil, errors.New("request ip is not in the configured accept list")
+	}
+	data, err := url.QueryUnescape(value)
+	if err != nil {
+		return nil, err
+	}
+	return parseCertificates([]byte(data))
+}
+
+func (c *MTLS) serviceAccountIdentity(ctx context.Context, serviceAccount string) (*authn.Identity, error) {
+	usr, err := c.userService.GetByLogin(ctx, &user.GetUserByLoginQuery{LoginOrEmail: serviceAccount})
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil, errMTLSServiceAccountNotFound.Errorf("service account %q not found", serviceAccount)
+		}
+		return nil, err
+	}
+	if !usr.IsServiceAccount {
+		return nil, errMTLSServiceAccountNotFound.Errorf("%q is not a service account", serviceAccount)
+	}
+
+	return &authn.Identity{
+		ID:              strconv.FormatInt(usr.ID, 10),
+		Type:            claims.TypeServiceAccount,
+		OrgID:           usr.OrgID,
+		AuthenticatedBy: login.MTLSAuthModule,
+		ClientParams:    authn.ClientParams{FetchSyncedUser: true, SyncPermissions: true},
+	}, nil
+}
+
+func (c *MTLS) extractRoleAndAdmin(attributes map[string]any) (org.RoleType, bool, error) {
+	role, err := searchAttribute(c.cfg.MTLSAuth.RoleAttributePath, attributes)
+	if err != nil || role == "" {
+		return "", false, err
+	}
+	if role == roleGrafanaAdmin {
+		return org.RoleAdmin, true, nil
+	}
+	return org.RoleType(role), false, nil
+}
+
+// certificateAttributes returns the attributes of a certificate searched by the attribute paths.
+func certificateAttributes(cert *x509.Certificate) map[string]any {
+	uris := make([]string, 0, len(cert.URIs))
+	for _, u := range cert.URIs {
+		uris = append(uris, u.String())
+	}
+	ips := make([]string, 0, len(cert.IPAddresses))
+	for _, ip := range cert.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+
+	return map[string]any{
+		"subject": map[string]any{
+			"commonName":         cert.Subject.CommonName,
+			"serialNumber":       cert.Subject.SerialNumber,
+			"organization":       toAnySlice(cert.Subject.Organization),
+			"organizationalUnit": toAnySlice(cert.Subject.OrganizationalUnit),
+		},
+		"issuer": map[string]any{
+			"commonName":   cert.Issuer.CommonName,
+			"organization": toAnySlice(cert.Issuer.Organization),
+		},
+		"serialNumber":   cert.SerialNumber.String(),
+		"dnsNames":       toAnySlice(cert.DNSNames),
+		"emailAddresses": toAnySlice(cert.EmailAddresses),
+		"uris":           toAnySlice(uris),
+		"ipAddresses":    toAnySlice(ips),
+	}
+}
+
+func toAnySlice(values []string) []any {
+	result := make([]any, 0, len(values))
+	for _, v := range values {
+		result = append(result, v)
+	}
+	return result
+}
+
+func searchAttribute(path string, attributes map[string]any) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	return util.SearchJSONForStringAttr(path, attributes)
+}
+
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
@@ -0,0 +1,320 @@
+package clients
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/claims"
+	"github.com/grafana/grafana/pkg/login/social/connectors"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/org/orgtest"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func (ca *testCA) issue(t *testing.T, subject pkix.Name, emails []string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:   big.NewInt(2),
+		Subject:        subject,
+		EmailAddresses: emails,
+		NotBefore:      time.Now().Add(-time.Hour),
+		NotAfter:       time.Now().Add(time.Hour),
+		KeyUsage:       x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func tlsRequest(cert *x509.Certificate) *authn.Request {
+	return &authn.Request{HTTPRequest: &http.Request{
+		Header: http.Header{},
+		TLS:    &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+	}}
+}
+
+func headerRequest(cert *x509.Certificate, remoteAddr string) *authn.Request {
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	return &authn.Request{HTTPRequest: &http.Request{
+		Header:     http.Header{"X-Client-Cert": {url.QueryEscape(string(data))}},
+		RemoteAddr: remoteAddr,
+	}}
+}
+
+func TestMTLS_Authenticate(t *testing.T) {
+	ca := newTestCA(t)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0o600))
+
+	userCert := ca.issue(t, pkix.Name{CommonName: "jdoe", OrganizationalUnit: []string{"admins"}}, []string{"jdoe@example.org"})
+	serviceCert := ca.issue(t, pkix.Name{CommonName: "sa-1-backup", OrganizationalUnit: []string{"services"}}, nil)
+	untrustedCert := newTestCA(t).issue(t, pkix.Name{CommonName: "jdoe"}, nil)
+
+	type testCase struct {
+		desc             string
+		req              *authn.Request
+		setting          func(s *setting.AuthMTLSSettings)
+		expectedUser     *user.User
+		expectedIdentity *authn.Identity
+		expectedErr      error
+	}
+
+	boolPtr := func(b bool) *bool { return &b }
+
+	tests := []testCase{
+		{
+			desc: "should map the client certificate to a user",
+			req:  tlsRequest(userCert),
+			setting: func(s *setting.AuthMTLSSettings) {
+				s.RoleAttributePath = "contains(subject.organizationalUnit, 'admins') && 'GrafanaAdmin' || 'Viewer'"
+				s.AllowAssignGrafanaAdmin = true
+			},
+			expectedIdentity: &authn.Identity{
+				Login:           "jdoe",
+				Email:           "jdoe@example.org",
+				AuthID:          "jdoe",
+				AuthenticatedBy: login.MTLSAuthModule,
+				OrgRoles:        map[int64]org.RoleType{1: org.RoleAdmin},
+				IsGrafanaAdmin:  boolPtr(true),
+				ClientParams: authn.ClientParams{
+					SyncUser:        true,
+					FetchSyncedUser: true,
+					SyncPermissions: true,
+					SyncOrgRoles:    true,
+					AllowSignUp:     true,
+					LookUpParams: login.UserLookupParams{
+						Login: stringPtr("jdoe"),
+						Email: stringPtr("jdoe@example.org"),
+					},
+				},
+			},
+		},
+		{
+			desc: "should map the organizational units with the org mapping",
+			req:  tlsRequest(userCert),
+			setting: func(s *setting.AuthMTLSSettings) {
+				s.OrgAttributePath = "subject.organizationalUnit"
+				s.OrgMapping = []string{"admins:2:Editor"}
+			},
+			expectedIdentity: &authn.Identity{
+				Login:           "jdoe",
+				Email:           "jdoe@example.org",
+				AuthID:          "jdoe",
+				AuthenticatedBy: login.MTLSAuthModule,
+				OrgRoles:        map[int64]org.RoleType{2: org.RoleEditor},
+				ClientParams: authn.ClientParams{
+					SyncUser:        true,
+					FetchSyncedUser: true,
+					SyncPermissions: true,
+					SyncOrgRoles:    true,
+					AllowSignUp:     true,
+					LookUpParams: login.UserLookupParams{
+						Login: stringPtr("jdoe"),
+						Email: stringPtr("jdoe@example.org"),
+					},
+				},
+			},
+		},
+		{
+			desc: "should map the client certificate to a service account",
+			req:  tlsRequest(serviceCert),
+			setting: func(s *setting.AuthMTLSSettings) {
+				s.ServiceAccountAttributePath = "contains(subject.organizationalUnit, 'services') && subject.commonName || ''"
+			},
+			expectedUser: &user.User{ID: 3, OrgID: 2, Login: "sa-1-backup", IsServiceAccount: true},
+			expectedIdentity: &authn.Identity{
+				ID:              "3",
+				Type:            claims.TypeServiceAccount,
+				OrgID:           2,
+				AuthenticatedBy: login.MTLSAuthModule,
+				ClientParams:    authn.ClientParams{FetchSyncedUser: true, SyncPermissions: true},
+			},
+		},
+		{
+			desc: "should fail when the service account is a user",
+			req:  tlsRequest(serviceCert),
+			setting: func(s *setting.AuthMTLSSettings) {
+				s.ServiceAccountAttributePath = "subject.commonName"
+			},
+			expectedUser: &user.User{ID: 3, Login: "sa-1-backup"},
+			expectedErr:  errMTLSServiceAccountNotFound,
+		},
+		{
+			desc:        "should fail when the certificate is not issued by the client CA",
+			req:         tlsRequest(untrustedCert),
+			expectedErr: errMTLSInvalidCertificate,
+		},
+		{
+			desc: "should fail when the certificate has no login or email",
+			req:  tlsRequest(userCert),
+			setting: func(s *setting.AuthMTLSSettings) {
+				s.LoginAttributePath = "subject.serialNumber"
+				s.EmailAttributePath = ""
+			},
+			expectedErr: errMTLSMissingAttribute,
+		},
+		{
+			desc: "should fail when the role is invalid and strict",
+			req:  tlsRequest(userCert),
+			setting: func(s *setting.AuthMTLSSettings) {
+				s.RoleAttributePath = "'Owner'"
+				s.RoleAttributeStrict = true
+			},
+			expectedErr: errMTLSInvalidRole,
+		},
+		{
+			desc: "should read the certificate from the header of an accepted proxy",
+			req:  headerRequest(userCert, "10.0.0.1:3000"),
+			setting: func(s *setting.AuthMTLSSettings) {
+				s.HeaderName = "X-Client-Cert"
+				s.Whitelist = "10.0.0.1"
+				s.SkipOrgRoleSync = true
+			},
+			expectedIdentity: &authn.Identity{
+				Login:           "jdoe",
+				Email:           "jdoe@example.org",
+				AuthID:          "jdoe",
+				AuthenticatedBy: login.MTLSAuthModule,
+				OrgRoles:        map[int64]org.RoleType{},
+				ClientParams: authn.ClientParams{
+					SyncUser:        true,
+					FetchSyncedUser: true,
+					SyncPermissions: true,
+					AllowSignUp:     true,
+					LookUpParams: login.UserLookupParams{
+						Login: stringPtr("jdoe"),
+						Email: stringPtr("jdoe@example.org"),
+					},
+				},
+			},
+		},
+		{
+			desc: "should fail when the header is set by another host",
+			req:  headerRequest(userCert, "10.0.0.2:3000"),
+			setting: func(s *setting.AuthMTLSSettings) {
+				s.HeaderName = "X-Client-Cert"
+				s.Whitelist = "10.0.0.1"
+			},
+			expectedErr: errMTLSInvalidCertificate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			cfg := setting.NewCfg()
+			cfg.MTLSAuth = setting.AuthMTLSSettings{
+				Enabled:            true,
+				ClientCACert:       caFile,
+				LoginAttributePath: "subject.commonName",
+				EmailAttributePath: "emailAddresses[0]",
+				AutoSignUp:         true,
+			}
+			if tt.setting != nil {
+				tt.setting(&cfg.MTLSAuth)
+			}
+
+			userService := &usertest.FakeUserService{ExpectedUser: tt.expectedUser}
+			if tt.expectedUser == nil {
+				userService.ExpectedError = user.ErrUserNotFound
+			}
+			c, err := ProvideMTLS(cfg, userService, connectors.ProvideOrgRoleMapper(cfg, orgtest.NewOrgServiceFake()))
+			require.NoError(t, err)
+
+			identity, err := c.Authenticate(context.Background(), tt.req)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, identity)
+				return
+			}
+			require.NoError(t, err)
+			assert.EqualValues(t, tt.expectedIdentity, identity)
+		})
+	}
+}
+
+func TestMTLS_Test(t *testing.T) {
+	ca := newTestCA(t)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0o600))
+	cert := ca.issue(t, pkix.Name{CommonName: "jdoe"}, nil)
+
+	cfg := setting.NewCfg()
+	cfg.MTLSAuth = setting.AuthMTLSSettings{Enabled: true, ClientCACert: caFile, HeaderName: "X-Client-Cert", Whitelist: "10.0.0.1"}
+	c, err := ProvideMTLS(cfg, &usertest.FakeUserService{}, connectors.ProvideOrgRoleMapper(cfg, orgtest.NewOrgServiceFake()))
+	require.NoError(t, err)
+
+	assert.True(t, c.Test(context.Background(), tlsRequest(cert)))
+	assert.True(t, c.Test(context.Background(), headerRequest(cert, "10.0.0.1:3000")))
+	assert.False(t, c.Test(context.Background(), &authn.Request{HTTPRequest: &http.Request{Header: http.Header{}}}))
+	assert.False(t, c.Test(context.Background(), &authn.Request{}))
+}
+
+func TestProvideMTLS(t *testing.T) {
+	ca := newTestCA(t)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0o600))
+
+	t.Run("should require a client CA", func(t *testing.T) {
+		cfg := setting.NewCfg()
+		_, err := ProvideMTLS(cfg, &usertest.FakeUserService{}, connectors.ProvideOrgRoleMapper(cfg, orgtest.NewOrgServiceFake()))
+		require.Error(t, err)
+	})
+
+	t.Run("should require a whitelist to read the certificate from a header", func(t *testing.T) {
+		cfg := setting.NewCfg()
+		cfg.MTLSAuth = setting.AuthMTLSSettings{ClientCACert: caFile, HeaderName: "X-Client-Cert"}
+		_, err := ProvideMTLS(cfg, &usertest.FakeUserService{}, connectors.ProvideOrgRoleMapper(cfg, orgtest.NewOrgServiceFake()))
+		require.Error(t, err)
+	})
+}
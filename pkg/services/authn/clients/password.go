@@ -3,12 +3,13 @@ package clients
 import (
 	"context"
 	"errors"
+	"net"
 
 	"github.com/grafana/grafana/pkg/apimachinery/errutil"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/authn"
 	"github.com/grafana/grafana/pkg/services/loginattempt"
-	"github.com/grafana/grafana/pkg/web"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 var (
@@ -18,20 +19,22 @@ var (
 
 var _ authn.PasswordClient = new(Password)
 
-func ProvidePassword(loginAttempts loginattempt.Service, clients ...authn.PasswordClient) *Password {
-	return &Password{loginAttempts, clients, log.New("authn.password")}
+func ProvidePassword(cfg *setting.Cfg, loginAttempts loginattempt.Service, clients ...authn.PasswordClient) *Password {
+	logger := log.New("authn.password")
+	return &Password{loginAttempts, loginattempt.ParseNetworks(cfg.BruteForceLoginProtectionTrustedProxies, logger), clients, logger}
 }
 
 type Password struct {
-	loginAttempts loginattempt.Service
-	clients       []authn.PasswordClient
-	log           log.Logger
+	loginAttempts  loginattempt.Service
+	trustedProxies []*net.IPNet
+	clients        []authn.PasswordClient
+	log            log.Logger
 }
 
 func (c *Password) AuthenticatePassword(ctx context.Context, r *authn.Request, username, password string) (*authn.Identity, error) {
 	r.SetMeta(authn.MetaKeyUsername, username)
 
-	ok, err := c.loginAttempts.Validate(ctx, username)
+	ok, err := c.loginAttempts.Validate(ctx, username, remoteAddr(r, c.trustedProxies))
 	if err != nil {
 		return nil, err
 	}
@@ -57,7 +60,7 @@ func (c *Password) AuthenticatePassword(ctx context.Context, r *authn.Request, u
 	}
 
 	if errors.Is(clientErrs, errInvalidPassword) {
-		_ = c.loginAttempts.Add(ctx, username, web.RemoteAddr(r.HTTPRequest))
+		_ = c.loginAttempts.Add(ctx, username, remoteAddr(r, c.trustedProxies))
 	}
 
 	return nil, errPasswordAuthFailed.Errorf("failed to authenticate identity: %w", clientErrs)
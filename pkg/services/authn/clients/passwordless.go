@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"strconv"
 	"time"
 
@@ -40,7 +41,9 @@ const passwordlessKeyPrefix = "passwordless-%s"
 var _ authn.RedirectClient = new(Passwordless)
 
 func ProvidePasswordless(cfg *setting.Cfg, loginAttempts loginattempt.Service, userService user.Service, tempUserService tempuser.Service, notificationService notifications.Service, cache remotecache.CacheStorage) *Passwordless {
-	return &Passwordless{cfg, loginAttempts, userService, tempUserService, notificationService, cache, log.New("authn.passwordless")}
+	logger := log.New("authn.passwordless")
+	trustedProxies := loginattempt.ParseNetworks(cfg.BruteForceLoginProtectionTrustedProxies, logger)
+	return &Passwordless{cfg, loginAttempts, trustedProxies, userService, tempUserService, notificationService, cache, logger}
 }
 
 type PasswordlessCacheCodeEntry struct {
@@ -57,6 +60,7 @@ type PasswordlessCacheEmailEntry struct {
 type Passwordless struct {
 	cfg                 *setting.Cfg
 	loginAttempts       loginattempt.Service
+	trustedProxies      []*net.IPNet
 	userService         user.Service
 	tempUserService     tempuser.Service
 	notificationService notifications.Service
@@ -105,8 +109,7 @@ func (c *Passwordless) RedirectURL(ctx context.Context, r *authn.Request) (*auth
 		return nil, err
 	}
 
-	// TODO: add IP address validation
-	ok, err := c.loginAttempts.Validate(ctx, form.Email)
+	ok, err := c.loginAttempts.Validate(ctx, form.Email, remoteAddr(r, c.trustedProxies))
 	if err != nil {
 		return nil, err
 	}
@@ -115,7 +118,7 @@ func (c *Passwordless) RedirectURL(ctx context.Context, r *authn.Request) (*auth
 		return nil, errPasswordlessClientTooManyLoginAttempts.Errorf("too many consecutive incorrect login attempts for user - login for user temporarily blocked")
 	}
 
-	err = c.loginAttempts.Add(ctx, form.Email, web.RemoteAddr(r.HTTPRequest))
+	err = c.loginAttempts.Add(ctx, form.Email, remoteAddr(r, c.trustedProxies))
 	if err != nil {
 		return nil, err
 	}
@@ -264,7 +267,7 @@ func (c *Passwordless) authenticatePasswordless(ctx context.Context, r *authn.Re
 		return nil, errPasswordlessClientInvalidConfirmationCode
 	}
 
-	ok, err := c.loginAttempts.Validate(ctx, codeEntry.Email)
+	ok, err := c.loginAttempts.Validate(ctx, codeEntry.Email, remoteAddr(r, c.trustedProxies))
 	if err != nil {
 		return nil, err
 	}
@@ -190,7 +190,12 @@ func (c *Proxy) Hook(ctx context.Context, id *authn.Identity, r *authn.Request)
 }
 
 func (c *Proxy) isAllowedIP(r *authn.Request) bool {
-	if len(c.acceptedIPs) == 0 {
+	return isAcceptedIP(r, c.acceptedIPs)
+}
+
+// isAcceptedIP returns true if the request comes from a network of the list, or the list is empty.
+func isAcceptedIP(r *authn.Request, acceptedIPs []*net.IPNet) bool {
+	if len(acceptedIPs) == 0 {
 		return true
 	}
 
@@ -200,7 +205,7 @@ func (c *Proxy) isAllowedIP(r *authn.Request) bool {
 	}
 
 	ip := net.ParseIP(host)
-	for _, v := range c.acceptedIPs {
+	for _, v := range acceptedIPs {
 		if v.Contains(ip) {
 			return true
 		}
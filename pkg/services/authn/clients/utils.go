@@ -1,6 +1,10 @@
 package clients
 
 import (
+	"net"
+
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/loginattempt"
 	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/setting"
 )
@@ -24,3 +28,12 @@ func getRoles(cfg *setting.Cfg, extract roleExtractor) (map[int64]org.RoleType,
 
 	return orgRoles, isGrafanaAdmin, nil
 }
+
+// remoteAddr returns the IP address of the client of the request, or an empty string if unknown.
+// Forwarded headers are only read from trusted proxies.
+func remoteAddr(r *authn.Request, trustedProxies []*net.IPNet) string {
+	if r.HTTPRequest == nil {
+		return ""
+	}
+	return loginattempt.RemoteAddr(r.HTTPRequest, trustedProxies)
+}
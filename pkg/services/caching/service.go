@@ -2,8 +2,24 @@ package caching
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/services/contexthandler"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util/proxyutil"
 )
 
 const (
@@ -13,8 +29,15 @@ const (
 	StatusBypass   = "BYPASS"
 	StatusError    = "ERROR"
 	StatusDisabled = "DISABLED"
+
+	// XCacheSkipHeader can be set on a request to bypass the cache.
+	XCacheSkipHeader = "X-Cache-Skip"
 )
 
+// userHeaders are the forwarded headers that identify the user without being credentials of the data source.
+// Their values are part of the cache key, so that responses are only shared by the requests of the same user.
+var userHeaders = []string{"Cookie", proxyutil.UserHeaderName, "X-Grafana-Id"}
+
 type CacheQueryResponseFn func(context.Context, *backend.QueryDataResponse)
 type CacheResourceResponseFn func(context.Context, *backend.CallResourceResponse)
 
@@ -35,10 +58,6 @@ type CachedResourceDataResponse struct {
 	UpdateCacheFn CacheResourceResponseFn
 }
 
-func ProvideCachingService() *OSSCachingService {
-	return &OSSCachingService{}
-}
-
 type CachingService interface {
 	// HandleQueryRequest uses a QueryDataRequest to check the cache for any existing results for that query.
 	// If none are found, it should return false and a CachedQueryDataResponse with an UpdateCacheFn which can be used to update the results cache after the fact.
@@ -49,16 +68,273 @@ type CachingService interface {
 	HandleResourceRequest(context.Context, *backend.CallResourceRequest) (bool, CachedResourceDataResponse)
 }
 
-// Implementation of interface - does nothing
+// relativeRangeSteps is the number of steps relative time ranges are rounded to in query cache keys.
+const relativeRangeSteps = 100
+
+// OSSCachingService caches query and resource responses in the remote cache.
+// It does nothing unless query caching is enabled in the configuration.
 type OSSCachingService struct {
+	settings setting.QueryCachingSettings
+	cache    remotecache.CacheStorage
+	log      log.Logger
+	now      func() time.Time
+}
+
+func ProvideCachingService(cfg *setting.Cfg, cache remotecache.CacheStorage) *OSSCachingService {
+	return &OSSCachingService{
+		settings: cfg.QueryCaching,
+		cache:    cache,
+		log:      log.New("query-caching"),
+		now:      time.Now,
+	}
+}
+
+// queryCacheKey is hashed to make the cache key of a query request.
+type queryCacheKey struct {
+	OrgID       int64
+	DataSource  string
+	Updated     time.Time
+	UserHeaders map[string]string
+	Queries     []queryCacheKeyItem
+}
+
+type queryCacheKeyItem struct {
+	RefID         string
+	QueryType     string
+	MaxDataPoints int64
+	Interval      time.Duration
+	From          time.Time
+	To            time.Time
+	JSON          json.RawMessage
+}
+
+// resourceCacheKey is hashed to make the cache key of a resource request.
+type resourceCacheKey struct {
+	OrgID       int64
+	PluginID    string
+	DataSource  string
+	Updated     time.Time
+	UserHeaders map[string]string
+	Path        string
+	URL         string
 }
 
 func (s *OSSCachingService) HandleQueryRequest(ctx context.Context, req *backend.QueryDataRequest) (bool, CachedQueryDataResponse) {
-	return false, CachedQueryDataResponse{}
+	if !s.settings.Enabled || req == nil || req.PluginContext.DataSourceInstanceSettings == nil {
+		return false, CachedQueryDataResponse{}
+	}
+	ds := req.PluginContext.DataSourceInstanceSettings
+	ttl := s.settings.DataSourceTTL(ds.UID, ds.Type)
+	if ttl <= 0 {
+		setCacheStatus(ctx, StatusDisabled)
+		return false, CachedQueryDataResponse{}
+	}
+	if bypassCache(ctx, slices.Collect(maps.Keys(req.Headers))) {
+		setCacheStatus(ctx, StatusBypass)
+		return false, CachedQueryDataResponse{}
+	}
+
+	key := queryCacheKey{
+		OrgID:       req.PluginContext.OrgID,
+		DataSource:  ds.UID,
+		Updated:     ds.Updated,
+		UserHeaders: userHeaderValues(req.Headers),
+		Queries:     make([]queryCacheKeyItem, 0, len(req.Queries)),
+	}
+	now := time.Now()
+	if s.now != nil {
+		now = s.now()
+	}
+	for _, q := range req.Queries {
+		from, to := cacheKeyTimeRange(q.TimeRange, now)
+		key.Queries = append(key.Queries, queryCacheKeyItem{
+			RefID:         q.RefID,
+			QueryType:     q.QueryType,
+			MaxDataPoints: q.MaxDataPoints,
+			Interval:      q.Interval,
+			From:          from,
+			To:            to,
+			JSON:          q.JSON,
+		})
+	}
+	cacheKey, err := hashKey("query", key)
+	if err != nil {
+		s.log.FromContext(ctx).Error("Failed to build query cache key", "error", err)
+		setCacheStatus(ctx, StatusError)
+		return false, CachedQueryDataResponse{}
+	}
+
+	value, err := s.cache.Get(ctx, cacheKey)
+	switch {
+	case err == nil:
+		resp := &backend.QueryDataResponse{}
+		if err := json.Unmarshal(value, resp); err == nil {
+			setCacheStatus(ctx, StatusHit)
+			return true, CachedQueryDataResponse{Response: resp}
+		}
+		s.log.FromContext(ctx).Warn("Failed to decode cached query response", "error", err)
+	case !errors.Is(err, remotecache.ErrCacheItemNotFound):
+		s.log.FromContext(ctx).Error("Failed to read query response from the cache", "error", err)
+		setCacheStatus(ctx, StatusError)
+		return false, CachedQueryDataResponse{}
+	}
+
+	setCacheStatus(ctx, StatusMiss)
+	return false, CachedQueryDataResponse{
+		UpdateCacheFn: func(ctx context.Context, resp *backend.QueryDataResponse) {
+			if resp == nil {
+				return
+			}
+			// Errors may be transient, so responses with errors are not cached.
+			for _, r := range resp.Responses {
+				if r.Error != nil {
+					return
+				}
+			}
+			value, err := json.Marshal(resp)
+			if err != nil {
+				s.log.FromContext(ctx).Error("Failed to encode query response", "error", err)
+				return
+			}
+			s.set(ctx, cacheKey, value, ttl)
+		},
+	}
+}
+
+// cacheKeyTimeRange returns the time range of a query cache key. Absolute time ranges are kept as they are.
+// Time ranges that end now are relative and move with every request, so they are rounded to a small fraction
+// of their length, which lets the requests made a few moments apart share a cache entry.
+func cacheKeyTimeRange(tr backend.TimeRange, now time.Time) (time.Time, time.Time) {
+	step := tr.Duration() / relativeRangeSteps
+	if step < time.Second || now.Sub(tr.To).Abs() > step {
+		return tr.From, tr.To
+	}
+	return tr.From.Truncate(step), tr.To.Truncate(step)
 }
 
 func (s *OSSCachingService) HandleResourceRequest(ctx context.Context, req *backend.CallResourceRequest) (bool, CachedResourceDataResponse) {
-	return false, CachedResourceDataResponse{}
+	if !s.settings.Enabled || req == nil || req.Method != http.MethodGet {
+		return false, CachedResourceDataResponse{}
+	}
+	ttl := s.settings.ResourcesTTL
+	key := resourceCacheKey{
+		OrgID:       req.PluginContext.OrgID,
+		PluginID:    req.PluginContext.PluginID,
+		UserHeaders: userHeaderValues(req.Headers),
+		Path:        req.Path,
+		URL:         req.URL,
+	}
+	if ds := req.PluginContext.DataSourceInstanceSettings; ds != nil {
+		if dsTTL, ok := s.settings.DataSourceTTLs[ds.UID]; ok && dsTTL == 0 {
+			ttl = 0
+		}
+		key.DataSource = ds.UID
+		key.Updated = ds.Updated
+	}
+	if ttl <= 0 {
+		setCacheStatus(ctx, StatusDisabled)
+		return false, CachedResourceDataResponse{}
+	}
+	if bypassCache(ctx, slices.Collect(maps.Keys(req.Headers))) {
+		setCacheStatus(ctx, StatusBypass)
+		return false, CachedResourceDataResponse{}
+	}
+
+	cacheKey, err := hashKey("resource", key)
+	if err != nil {
+		s.log.FromContext(ctx).Error("Failed to build resource cache key", "error", err)
+		setCacheStatus(ctx, StatusError)
+		return false, CachedResourceDataResponse{}
+	}
+
+	value, err := s.cache.Get(ctx, cacheKey)
+	switch {
+	case err == nil:
+		resp := &backend.CallResourceResponse{}
+		if err := json.Unmarshal(value, resp); err == nil {
+			setCacheStatus(ctx, StatusHit)
+			return true, CachedResourceDataResponse{Response: resp}
+		}
+		s.log.FromContext(ctx).Warn("Failed to decode cached resource response", "error", err)
+	case !errors.Is(err, remotecache.ErrCacheItemNotFound):
+		s.log.FromContext(ctx).Error("Failed to read resource response from the cache", "error", err)
+		setCacheStatus(ctx, StatusError)
+		return false, CachedResourceDataResponse{}
+	}
+
+	setCacheStatus(ctx, StatusMiss)
+	var responses atomic.Int32
+	return false, CachedResourceDataResponse{
+		UpdateCacheFn: func(ctx context.Context, resp *backend.CallResourceResponse) {
+			// Only single successful responses are cached, streamed responses can't be replayed.
+			if responses.Add(1) > 1 {
+				if err := s.cache.Delete(ctx, cacheKey); err != nil && !errors.Is(err, remotecache.ErrCacheItemNotFound) {
+					s.log.FromContext(ctx).Error("Failed to delete streamed resource response from the cache", "error", err)
+				}
+				return
+			}
+			if resp == nil || resp.Status < http.StatusOK || resp.Status >= http.StatusMultipleChoices {
+				return
+			}
+			value, err := json.Marshal(resp)
+			if err != nil {
+				s.log.FromContext(ctx).Error("Failed to encode resource response", "error", err)
+				return
+			}
+			s.set(ctx, cacheKey, value, ttl)
+		},
+	}
+}
+
+func (s *OSSCachingService) set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	if s.settings.MaxValueSize > 0 && len(value) > s.settings.MaxValueSize {
+		s.log.FromContext(ctx).Debug("Response is too big to be cached", "size", len(value), "maxSize", s.settings.MaxValueSize)
+		return
+	}
+	if err := s.cache.Set(ctx, key, value, ttl); err != nil {
+		s.log.FromContext(ctx).Error("Failed to write response to the cache", "error", err)
+	}
+}
+
+// bypassCache returns true if the request asks to skip the cache, or if it is made with the credentials
+// of the user, in which case the response can't be shared with other users.
+func bypassCache(ctx context.Context, headers []string) bool {
+	for _, name := range headers {
+		if http.CanonicalHeaderKey(name) == "Authorization" || http.CanonicalHeaderKey(name) == "X-Id-Token" {
+			return true
+		}
+	}
+	if reqCtx := contexthandler.FromContext(ctx); reqCtx != nil && reqCtx.Req != nil {
+		return reqCtx.Req.Header.Get(XCacheSkipHeader) == "true"
+	}
+	return false
+}
+
+// userHeaderValues returns the values of the user headers among the forwarded headers.
+func userHeaderValues[V string | []string](headers map[string]V) map[string]string {
+	values := make(map[string]string)
+	for name, value := range headers {
+		name = http.CanonicalHeaderKey(name)
+		if slices.Contains(userHeaders, name) {
+			values[name] = fmt.Sprint(value)
+		}
+	}
+	return values
+}
+
+func setCacheStatus(ctx context.Context, status string) {
+	if reqCtx := contexthandler.FromContext(ctx); reqCtx != nil && reqCtx.Resp != nil {
+		reqCtx.Resp.Header().Set(XCacheHeader, status)
+	}
+}
+
+func hashKey(kind string, key any) (string, error) {
+	b, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return "query-caching-" + kind + "-" + hex.EncodeToString(sum[:]), nil
 }
 
 var _ CachingService = &OSSCachingService{}
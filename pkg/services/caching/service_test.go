@@ -0,0 +1,260 @@
+package caching
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/services/contexthandler/ctxkey"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+func newRequestContext(t *testing.T, header http.Header) (context.Context, *contextmodel.ReqContext) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/ds/query", nil)
+	req.Header = header
+	reqCtx := &contextmodel.ReqContext{
+		Context: &web.Context{
+			Req:  req,
+			Resp: web.NewResponseWriter(req.Method, httptest.NewRecorder()),
+		},
+	}
+	return ctxkey.Set(context.Background(), reqCtx), reqCtx
+}
+
+func newQueryRequest(from, to time.Time, expr string) *backend.QueryDataRequest {
+	return &backend.QueryDataRequest{
+		PluginContext: backend.PluginContext{
+			OrgID: 1,
+			DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{
+				UID:  "prom",
+				Type: "prometheus",
+			},
+		},
+		Queries: []backend.DataQuery{{
+			RefID:     "A",
+			TimeRange: backend.TimeRange{From: from, To: to},
+			JSON:      json.RawMessage(`{"expr":"` + expr + `"}`),
+		}},
+	}
+}
+
+func TestOSSCachingService_HandleQueryRequest(t *testing.T) {
+	cfg := setting.NewCfg()
+	cfg.QueryCaching = setting.QueryCachingSettings{
+		Enabled:        true,
+		TTL:            time.Minute,
+		DataSourceTTLs: map[string]time.Duration{"disabled": 0},
+	}
+	cache := remotecache.NewFakeCacheStorage()
+	s := ProvideCachingService(cfg, cache)
+
+	to := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	// Time ranges ending at to are relative.
+	s.now = func() time.Time { return to }
+	resp := &backend.QueryDataResponse{Responses: backend.Responses{
+		"A": {Frames: data.Frames{data.NewFrame("up", data.NewField("value", nil, []float64{1}))}},
+	}}
+
+	t.Run("misses and caches the response", func(t *testing.T) {
+		ctx, reqCtx := newRequestContext(t, http.Header{})
+		hit, cr := s.HandleQueryRequest(ctx, newQueryRequest(to.Add(-time.Hour), to, "up"))
+		require.False(t, hit)
+		require.Equal(t, StatusMiss, reqCtx.Resp.Header().Get(XCacheHeader))
+		require.NotNil(t, cr.UpdateCacheFn)
+		cr.UpdateCacheFn(ctx, resp)
+		require.Len(t, cache.Storage, 1)
+	})
+
+	t.Run("hits for a relative time range that moved by less than a step", func(t *testing.T) {
+		ctx, reqCtx := newRequestContext(t, http.Header{})
+		later := to.Add(10 * time.Second)
+		s.now = func() time.Time { return later }
+		t.Cleanup(func() { s.now = func() time.Time { return to } })
+		hit, cr := s.HandleQueryRequest(ctx, newQueryRequest(later.Add(-time.Hour), later, "up"))
+		require.True(t, hit)
+		require.Equal(t, StatusHit, reqCtx.Resp.Header().Get(XCacheHeader))
+		require.Len(t, cr.Response.Responses["A"].Frames, 1)
+		require.Equal(t, "up", cr.Response.Responses["A"].Frames[0].Name)
+	})
+
+	t.Run("misses for a different time range or query", func(t *testing.T) {
+		ctx, _ := newRequestContext(t, http.Header{})
+		later := to.Add(time.Minute)
+		s.now = func() time.Time { return later }
+		t.Cleanup(func() { s.now = func() time.Time { return to } })
+		hit, _ := s.HandleQueryRequest(ctx, newQueryRequest(later.Add(-time.Hour), later, "up"))
+		require.False(t, hit)
+		hit, _ = s.HandleQueryRequest(ctx, newQueryRequest(to.Add(-time.Hour), to, "down"))
+		require.False(t, hit)
+	})
+
+	t.Run("misses for different absolute time ranges in the same TTL window", func(t *testing.T) {
+		ctx, _ := newRequestContext(t, http.Header{})
+		s.now = func() time.Time { return to.Add(24 * time.Hour) }
+		t.Cleanup(func() { s.now = func() time.Time { return to } })
+
+		first := to.Add(5 * time.Second)
+		hit, cr := s.HandleQueryRequest(ctx, newQueryRequest(first.Add(-time.Hour), first, "absolute"))
+		require.False(t, hit)
+		cr.UpdateCacheFn(ctx, resp)
+
+		second := to.Add(15 * time.Second)
+		hit, _ = s.HandleQueryRequest(ctx, newQueryRequest(second.Add(-time.Hour), second, "absolute"))
+		require.False(t, hit, "an absolute time range should not share the cache entry of another one")
+
+		hit, _ = s.HandleQueryRequest(ctx, newQueryRequest(first.Add(-time.Hour), first, "absolute"))
+		require.True(t, hit)
+	})
+
+	t.Run("does not cache responses with errors", func(t *testing.T) {
+		ctx, _ := newRequestContext(t, http.Header{})
+		cached := len(cache.Storage)
+		_, cr := s.HandleQueryRequest(ctx, newQueryRequest(to.Add(-time.Hour), to, "error"))
+		cr.UpdateCacheFn(ctx, &backend.QueryDataResponse{Responses: backend.Responses{
+			"A": backend.ErrDataResponse(backend.StatusBadRequest, "bad query"),
+		}})
+		require.Len(t, cache.Storage, cached)
+	})
+
+	t.Run("is bypassed when asked or when the user's credentials are forwarded", func(t *testing.T) {
+		ctx, reqCtx := newRequestContext(t, http.Header{XCacheSkipHeader: []string{"true"}})
+		hit, cr := s.HandleQueryRequest(ctx, newQueryRequest(to.Add(-time.Hour), to, "up"))
+		require.False(t, hit)
+		require.Nil(t, cr.UpdateCacheFn)
+		require.Equal(t, StatusBypass, reqCtx.Resp.Header().Get(XCacheHeader))
+
+		ctx, reqCtx = newRequestContext(t, http.Header{})
+		req := newQueryRequest(to.Add(-time.Hour), to, "up")
+		req.Headers = map[string]string{"Authorization": "Bearer token"}
+		hit, _ = s.HandleQueryRequest(ctx, req)
+		require.False(t, hit)
+		require.Equal(t, StatusBypass, reqCtx.Resp.Header().Get(XCacheHeader))
+	})
+
+	t.Run("does not share responses between users with different cookies", func(t *testing.T) {
+		userRequest := func(cookie string) *backend.QueryDataRequest {
+			req := newQueryRequest(to.Add(-time.Hour), to, "per_user")
+			req.Headers = map[string]string{"Cookie": cookie}
+			return req
+		}
+
+		ctx, _ := newRequestContext(t, http.Header{})
+		hit, cr := s.HandleQueryRequest(ctx, userRequest("session=alice"))
+		require.False(t, hit)
+		cr.UpdateCacheFn(ctx, resp)
+
+		ctx, reqCtx := newRequestContext(t, http.Header{})
+		hit, _ = s.HandleQueryRequest(ctx, userRequest("session=bob"))
+		require.False(t, hit, "the response cached for a user should not be returned to another one")
+		require.Equal(t, StatusMiss, reqCtx.Resp.Header().Get(XCacheHeader))
+
+		ctx, _ = newRequestContext(t, http.Header{})
+		hit, _ = s.HandleQueryRequest(ctx, userRequest("session=alice"))
+		require.True(t, hit)
+	})
+
+	t.Run("is disabled by a data source TTL of 0", func(t *testing.T) {
+		ctx, reqCtx := newRequestContext(t, http.Header{})
+		req := newQueryRequest(to.Add(-time.Hour), to, "up")
+		req.PluginContext.DataSourceInstanceSettings.UID = "disabled"
+		hit, cr := s.HandleQueryRequest(ctx, req)
+		require.False(t, hit)
+		require.Nil(t, cr.UpdateCacheFn)
+		require.Equal(t, StatusDisabled, reqCtx.Resp.Header().Get(XCacheHeader))
+	})
+
+	t.Run("does nothing when caching is disabled", func(t *testing.T) {
+		ctx, reqCtx := newRequestContext(t, http.Header{})
+		hit, cr := (&OSSCachingService{}).HandleQueryRequest(ctx, newQueryRequest(to.Add(-time.Hour), to, "up"))
+		require.False(t, hit)
+		require.Nil(t, cr.UpdateCacheFn)
+		require.Empty(t, reqCtx.Resp.Header().Get(XCacheHeader))
+	})
+}
+
+func TestOSSCachingService_HandleResourceRequest(t *testing.T) {
+	cfg := setting.NewCfg()
+	cfg.QueryCaching = setting.QueryCachingSettings{
+		Enabled:      true,
+		ResourcesTTL: time.Minute,
+	}
+	cache := remotecache.NewFakeCacheStorage()
+	s := ProvideCachingService(cfg, cache)
+
+	newRequest := func(method string) *backend.CallResourceRequest {
+		return &backend.CallResourceRequest{
+			PluginContext: backend.PluginContext{
+				OrgID:                      1,
+				PluginID:                   "prometheus",
+				DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{UID: "prom"},
+			},
+			Path:   "api/v1/labels",
+			Method: method,
+			URL:    "api/v1/labels?match[]=up",
+		}
+	}
+
+	t.Run("caches a single successful response", func(t *testing.T) {
+		ctx, reqCtx := newRequestContext(t, http.Header{})
+		hit, cr := s.HandleResourceRequest(ctx, newRequest(http.MethodGet))
+		require.False(t, hit)
+		require.Equal(t, StatusMiss, reqCtx.Resp.Header().Get(XCacheHeader))
+		cr.UpdateCacheFn(ctx, &backend.CallResourceResponse{Status: http.StatusOK, Body: []byte(`["job"]`)})
+
+		ctx, reqCtx = newRequestContext(t, http.Header{})
+		hit, cr = s.HandleResourceRequest(ctx, newRequest(http.MethodGet))
+		require.True(t, hit)
+		require.Equal(t, StatusHit, reqCtx.Resp.Header().Get(XCacheHeader))
+		require.Equal(t, []byte(`["job"]`), cr.Response.Body)
+	})
+
+	t.Run("does not cache streamed responses", func(t *testing.T) {
+		cache.Storage = map[string][]byte{}
+		s.cache = cache
+		ctx, _ := newRequestContext(t, http.Header{})
+		_, cr := s.HandleResourceRequest(ctx, newRequest(http.MethodGet))
+		cr.UpdateCacheFn(ctx, &backend.CallResourceResponse{Status: http.StatusOK, Body: []byte("1")})
+		cr.UpdateCacheFn(ctx, &backend.CallResourceResponse{Status: http.StatusOK, Body: []byte("2")})
+		require.Empty(t, cache.Storage)
+	})
+
+	t.Run("does not share responses between users with different cookies", func(t *testing.T) {
+		userRequest := func(cookie string) *backend.CallResourceRequest {
+			req := newRequest(http.MethodGet)
+			req.Headers = map[string][]string{"Cookie": {cookie}}
+			return req
+		}
+
+		ctx, _ := newRequestContext(t, http.Header{})
+		hit, cr := s.HandleResourceRequest(ctx, userRequest("session=alice"))
+		require.False(t, hit)
+		cr.UpdateCacheFn(ctx, &backend.CallResourceResponse{Status: http.StatusOK, Body: []byte(`["alice"]`)})
+
+		ctx, _ = newRequestContext(t, http.Header{})
+		hit, _ = s.HandleResourceRequest(ctx, userRequest("session=bob"))
+		require.False(t, hit, "the response cached for a user should not be returned to another one")
+
+		ctx, _ = newRequestContext(t, http.Header{})
+		hit, cr = s.HandleResourceRequest(ctx, userRequest("session=alice"))
+		require.True(t, hit)
+		require.Equal(t, []byte(`["alice"]`), cr.Response.Body)
+	})
+
+	t.Run("only caches GET requests", func(t *testing.T) {
+		ctx, _ := newRequestContext(t, http.Header{})
+		hit, cr := s.HandleResourceRequest(ctx, newRequest(http.MethodPost))
+		require.False(t, hit)
+		require.Nil(t, cr.UpdateCacheFn)
+	})
+}
@@ -0,0 +1,30 @@
+package cleanup
+
+import (
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+)
+
+func (srv *CleanUpService) registerAPIEndpoints() {
+	if srv.routeRegister == nil {
+		return
+	}
+	srv.routeRegister.Group("/api/admin/cleanup", func(cleanup routing.RouteRegister) {
+		cleanup.Get("/jobs", middleware.ReqGrafanaAdmin, routing.Wrap(srv.getJobsHandler))
+		cleanup.Post("/dry-run", middleware.ReqGrafanaAdmin, routing.Wrap(srv.dryRunHandler))
+	})
+}
+
+// getJobsHandler returns the result of the last run of each cleanup job.
+func (srv *CleanUpService) getJobsHandler(c *contextmodel.ReqContext) response.Response {
+	return response.JSON(http.StatusOK, srv.LastResults())
+}
+
+// dryRunHandler applies the retention policies in dry-run mode and returns what they would delete.
+func (srv *CleanUpService) dryRunHandler(c *contextmodel.ReqContext) response.Response {
+	return response.JSON(http.StatusOK, srv.DryRun(c.Req.Context()))
+}
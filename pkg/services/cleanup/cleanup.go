@@ -7,11 +7,14 @@ import (
 	"io/fs"
 	"os"
 	"path"
+	"sort"
 	"strconv"
+	"sync"
 	"time"
 
 	"go.opentelemetry.io/otel/attribute"
 
+	"github.com/grafana/grafana/pkg/api/routing"
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/serverlock"
@@ -41,12 +44,53 @@ type CleanUpService struct {
 	tempUserService           tempuser.Service
 	annotationCleaner         annotations.Cleaner
 	dashboardService          dashboards.DashboardService
+	routeRegister             routing.RouteRegister
+
+	retentionJobs     map[string]retentionJob
+	retentionPolicies []setting.RetentionPolicy
+
+	resultsMtx  sync.Mutex
+	lastResults map[string]JobResult
+}
+
+// Values of JobResult.BuiltIn.
+const (
+	// builtInRan means that the built-in job ran and its deletions are included in the affected rows.
+	builtInRan = "ran"
+	// builtInNotEvaluated means that the built-in job did not run because of the dry-run mode, so the affected
+	// rows only include the retention policies and not what the built-in job would have deleted.
+	builtInNotEvaluated = "not_evaluated"
+	// builtInDisabled means that the built-in job is disabled, for example because short URLs never expire.
+	builtInDisabled = "disabled"
+)
+
+// JobResult is the result of the last run of a cleanup job.
+type JobResult struct {
+	Job        string    `json:"job"`
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMs int64     `json:"durationMs"`
+	DryRun     bool      `json:"dryRun"`
+	// BuiltIn tells whether the built-in job ran, and so whether Affected includes its deletions.
+	BuiltIn  string `json:"builtIn"`
+	Affected int64  `json:"affected"`
+	Error    string `json:"error,omitempty"`
+	// Orgs are the results of the retention policies of the job.
+	Orgs []OrgResult `json:"orgs,omitempty"`
+}
+
+// OrgResult is the result of the retention policy of an organization.
+type OrgResult struct {
+	OrgID    int64  `json:"orgId"`
+	MaxAge   string `json:"maxAge"`
+	Affected int64  `json:"affected"`
+	Error    string `json:"error,omitempty"`
 }
 
 func ProvideService(cfg *setting.Cfg, serverLockService *serverlock.ServerLockService,
 	shortURLService shorturls.Service, sqlstore db.DB, queryHistoryService queryhistory.Service,
 	dashboardVersionService dashver.Service, dashSnapSvc dashboardsnapshots.Service, deleteExpiredImageService *image.DeleteExpiredService,
-	tempUserService tempuser.Service, tracer tracing.Tracer, annotationCleaner annotations.Cleaner, dashboardService dashboards.DashboardService) *CleanUpService {
+	tempUserService tempuser.Service, tracer tracing.Tracer, annotationCleaner annotations.Cleaner, dashboardService dashboards.DashboardService,
+	routeRegister routing.RouteRegister) *CleanUpService {
 	s := &CleanUpService{
 		Cfg:                       cfg,
 		ServerLockService:         serverLockService,
@@ -61,13 +105,21 @@ func ProvideService(cfg *setting.Cfg, serverLockService *serverlock.ServerLockSe
 		tracer:                    tracer,
 		annotationCleaner:         annotationCleaner,
 		dashboardService:          dashboardService,
+		routeRegister:             routeRegister,
+		retentionJobs:             newRetentionJobs(sqlstore, dashboardService, cfg.DashboardVersionsToKeep),
+		lastResults:               make(map[string]JobResult),
 	}
+	s.retentionPolicies = s.validRetentionPolicies(cfg.Cleanup.RetentionPolicies)
+	s.registerAPIEndpoints()
 	return s
 }
 
 type cleanUpJob struct {
 	name string
-	fn   func(context.Context)
+	fn   func(context.Context) (int64, error)
+	// retention is the name of the retention policies applied after the job, if any. The job leaves alone the
+	// organizations that have a policy, except for snapshots which still expire when their creator chose.
+	retention string
 }
 
 func (j cleanUpJob) String() string {
@@ -97,19 +149,24 @@ func (srv *CleanUpService) clean(ctx context.Context) {
 	defer cancelFn()
 
 	cleanupJobs := []cleanUpJob{
-		{"clean up temporary files", srv.cleanUpTmpFiles},
-		{"delete expired snapshots", srv.deleteExpiredSnapshots},
-		{"delete expired dashboard versions", srv.deleteExpiredDashboardVersions},
-		{"delete expired images", srv.deleteExpiredImages},
-		{"cleanup old annotations", srv.cleanUpOldAnnotations},
-		{"expire old user invites", srv.expireOldUserInvites},
-		{"delete stale query history", srv.deleteStaleQueryHistory},
-		{"expire old email verifications", srv.expireOldVerifications},
-		{"cleanup trash dashboards", srv.cleanUpTrashDashboards},
+		{"clean up temporary files", srv.cleanUpTmpFiles, ""},
+		{"delete expired snapshots", srv.deleteExpiredSnapshots, retentionDashboardSnapshots},
+		{"delete expired dashboard versions", srv.deleteExpiredDashboardVersions, retentionDashboardVersions},
+		{"delete expired images", srv.deleteExpiredImages, ""},
+		{"cleanup old annotations", srv.cleanUpOldAnnotations, retentionAnnotations},
+		{"expire old user invites", srv.expireOldUserInvites, ""},
+		{"delete stale query history", srv.deleteStaleQueryHistory, retentionQueryHistory},
+		{"expire old email verifications", srv.expireOldVerifications, ""},
+		{"cleanup trash dashboards", srv.cleanUpTrashDashboards, retentionTrashDashboards},
+		{"delete expired alert state history", srv.deleteExpiredAlertStateHistory, retentionAlertStateHistory},
+		{"delete expired recording rule samples", srv.deleteExpiredRecordingSamples, retentionRecordingSamples},
 	}
 
 	if srv.Cfg.ShortLinkExpiration > 0 {
-		cleanupJobs = append(cleanupJobs, cleanUpJob{"delete stale short URLs", srv.deleteStaleShortURLs})
+		cleanupJobs = append(cleanupJobs, cleanUpJob{"delete stale short URLs", srv.deleteStaleShortURLs, retentionShortURLs})
+	} else {
+		// Short URLs never expire, but their retention policies still apply.
+		cleanupJobs = append(cleanupJobs, cleanUpJob{"delete stale short URLs", nil, retentionShortURLs})
 	}
 
 	logger := srv.log.FromContext(ctx)
@@ -121,48 +178,119 @@ func (srv *CleanUpService) clean(ctx context.Context) {
 			return
 		}
 		ctx, span := srv.tracer.Start(ctx, j.name)
-		j.fn(ctx)
+		result := srv.runJob(ctx, j, srv.Cfg.Cleanup.DryRun)
 		span.End()
+		srv.recordResult(result)
 	}
 
 	logger.Info("Completed cleanup jobs", "duration", time.Since(start))
 }
 
-func (srv *CleanUpService) cleanUpOldAnnotations(ctx context.Context) {
+// runJob runs a cleanup job followed by its retention policies. In dry-run mode, the job itself is not evaluated
+// and the retention policies only count what they would delete.
+func (srv *CleanUpService) runJob(ctx context.Context, j cleanUpJob, dryRun bool) JobResult {
+	start := time.Now()
+	result := JobResult{
+		Job:       j.name,
+		StartedAt: start,
+		DryRun:    dryRun,
+		BuiltIn:   builtInRan,
+	}
+	switch {
+	case dryRun:
+		result.BuiltIn = builtInNotEvaluated
+	case j.fn == nil:
+		result.BuiltIn = builtInDisabled
+	default:
+		affected, err := j.fn(ctx)
+		result.Affected = affected
+		if err != nil {
+			result.Error = err.Error()
+		}
+	}
+	if j.retention != "" {
+		result.Orgs = srv.applyRetentionPolicies(ctx, j.retention, dryRun)
+		for _, o := range result.Orgs {
+			result.Affected += o.Affected
+		}
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+	return result
+}
+
+func (srv *CleanUpService) recordResult(result JobResult) {
+	srv.resultsMtx.Lock()
+	defer srv.resultsMtx.Unlock()
+	srv.lastResults[result.Job] = result
+}
+
+// LastResults returns the result of the last run of each cleanup job, sorted by job name.
+func (srv *CleanUpService) LastResults() []JobResult {
+	srv.resultsMtx.Lock()
+	defer srv.resultsMtx.Unlock()
+	results := make([]JobResult, 0, len(srv.lastResults))
+	for _, r := range srv.lastResults {
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Job < results[j].Job
+	})
+	return results
+}
+
+// DryRun applies the retention policies of every job in dry-run mode and returns what they would delete.
+func (srv *CleanUpService) DryRun(ctx context.Context) []JobResult {
+	jobs := make([]string, 0, len(srv.retentionJobs))
+	for name := range srv.retentionJobs {
+		jobs = append(jobs, name)
+	}
+	sort.Strings(jobs)
+
+	results := make([]JobResult, 0, len(jobs))
+	for _, name := range jobs {
+		results = append(results, srv.runJob(ctx, cleanUpJob{name: name, retention: name}, true))
+	}
+	return results
+}
+
+func (srv *CleanUpService) cleanUpOldAnnotations(ctx context.Context) (int64, error) {
 	logger := srv.log.FromContext(ctx)
-	affected, affectedTags, err := srv.annotationCleaner.Run(ctx, srv.Cfg)
+	affected, affectedTags, err := srv.annotationCleaner.Run(ctx, srv.Cfg, srv.policyOrgs(retentionAnnotations))
 	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
 		logger.Error("failed to clean up old annotations", "error", err)
-	} else {
-		logger.Debug("Deleted excess annotations", "annotations affected", affected, "annotation tags affected", affectedTags)
+		return affected, err
 	}
+	logger.Debug("Deleted excess annotations", "annotations affected", affected, "annotation tags affected", affectedTags)
+	return affected, nil
 }
 
-func (srv *CleanUpService) cleanUpTmpFiles(ctx context.Context) {
+func (srv *CleanUpService) cleanUpTmpFiles(ctx context.Context) (int64, error) {
 	folders := []string{
 		srv.Cfg.ImagesDir,
 		srv.Cfg.CSVsDir,
 		srv.Cfg.PDFsDir,
 	}
 
+	var deleted int64
 	for _, f := range folders {
 		ctx, span := srv.tracer.Start(ctx, "delete stale files in temporary directory")
 		span.SetAttributes(attribute.String("directory", f))
-		srv.cleanUpTmpFolder(ctx, f)
+		deleted += srv.cleanUpTmpFolder(ctx, f)
 		span.End()
 	}
+	return deleted, nil
 }
 
-func (srv *CleanUpService) cleanUpTmpFolder(ctx context.Context, folder string) {
+func (srv *CleanUpService) cleanUpTmpFolder(ctx context.Context, folder string) int64 {
 	logger := srv.log.FromContext(ctx)
 	if _, err := os.Stat(folder); os.IsNotExist(err) {
-		return
+		return 0
 	}
 
 	files, err := os.ReadDir(folder)
 	if err != nil {
 		logger.Error("Problem reading dir", "folder", folder, "error", err)
-		return
+		return 0
 	}
 
 	var toDelete []fs.DirEntry
@@ -189,6 +317,7 @@ func (srv *CleanUpService) cleanUpTmpFolder(ctx context.Context, folder string)
 	}
 
 	logger.Debug("Found old rendered file to delete", "folder", folder, "deleted", len(toDelete), "kept", len(files))
+	return int64(len(toDelete))
 }
 
 func (srv *CleanUpService) shouldCleanupTempFile(filemtime time.Time, now time.Time) bool {
@@ -199,39 +328,43 @@ func (srv *CleanUpService) shouldCleanupTempFile(filemtime time.Time, now time.T
 	return filemtime.Add(srv.Cfg.TempDataLifetime).Before(now)
 }
 
-func (srv *CleanUpService) deleteExpiredSnapshots(ctx context.Context) {
+func (srv *CleanUpService) deleteExpiredSnapshots(ctx context.Context) (int64, error) {
 	logger := srv.log.FromContext(ctx)
 	cmd := dashboardsnapshots.DeleteExpiredSnapshotsCommand{}
 	if err := srv.dashboardSnapshotService.DeleteExpiredSnapshots(ctx, &cmd); err != nil {
 		logger.Error("Failed to delete expired snapshots", "error", err.Error())
-	} else {
-		logger.Debug("Deleted expired snapshots", "rows affected", cmd.DeletedRows)
+		return 0, err
 	}
+	logger.Debug("Deleted expired snapshots", "rows affected", cmd.DeletedRows)
+	return cmd.DeletedRows, nil
 }
 
-func (srv *CleanUpService) deleteExpiredDashboardVersions(ctx context.Context) {
+func (srv *CleanUpService) deleteExpiredDashboardVersions(ctx context.Context) (int64, error) {
 	logger := srv.log.FromContext(ctx)
-	cmd := dashver.DeleteExpiredVersionsCommand{}
+	cmd := dashver.DeleteExpiredVersionsCommand{ExcludedOrgIDs: srv.policyOrgs(retentionDashboardVersions)}
 	if err := srv.dashboardVersionService.DeleteExpired(ctx, &cmd); err != nil {
 		logger.Error("Failed to delete expired dashboard versions", "error", err.Error())
-	} else {
-		logger.Debug("Deleted old/expired dashboard versions", "rows affected", cmd.DeletedRows)
+		return 0, err
 	}
+	logger.Debug("Deleted old/expired dashboard versions", "rows affected", cmd.DeletedRows)
+	return cmd.DeletedRows, nil
 }
 
-func (srv *CleanUpService) deleteExpiredImages(ctx context.Context) {
+func (srv *CleanUpService) deleteExpiredImages(ctx context.Context) (int64, error) {
 	logger := srv.log.FromContext(ctx)
 	if !srv.Cfg.UnifiedAlerting.IsEnabled() {
-		return
+		return 0, nil
 	}
-	if rowsAffected, err := srv.deleteExpiredImageService.DeleteExpired(ctx); err != nil {
+	rowsAffected, err := srv.deleteExpiredImageService.DeleteExpired(ctx)
+	if err != nil {
 		logger.Error("Failed to delete expired images", "error", err.Error())
-	} else {
-		logger.Debug("Deleted expired images", "rows affected", rowsAffected)
+		return 0, err
 	}
+	logger.Debug("Deleted expired images", "rows affected", rowsAffected)
+	return rowsAffected, nil
 }
 
-func (srv *CleanUpService) expireOldUserInvites(ctx context.Context) {
+func (srv *CleanUpService) expireOldUserInvites(ctx context.Context) (int64, error) {
 	logger := srv.log.FromContext(ctx)
 	maxInviteLifetime := srv.Cfg.UserInviteMaxLifetime
 
@@ -241,12 +374,13 @@ func (srv *CleanUpService) expireOldUserInvites(ctx context.Context) {
 
 	if err := srv.tempUserService.ExpireOldUserInvites(ctx, &cmd); err != nil {
 		logger.Error("Problem expiring user invites", "error", err.Error())
-	} else {
-		logger.Debug("Expired user invites", "rows affected", cmd.NumExpired)
+		return 0, err
 	}
+	logger.Debug("Expired user invites", "rows affected", cmd.NumExpired)
+	return cmd.NumExpired, nil
 }
 
-func (srv *CleanUpService) expireOldVerifications(ctx context.Context) {
+func (srv *CleanUpService) expireOldVerifications(ctx context.Context) (int64, error) {
 	logger := srv.log.FromContext(ctx)
 	maxVerificationLifetime := srv.Cfg.VerificationEmailMaxLifetime
 
@@ -256,33 +390,40 @@ func (srv *CleanUpService) expireOldVerifications(ctx context.Context) {
 
 	if err := srv.tempUserService.ExpireOldVerifications(ctx, &cmd); err != nil {
 		logger.Error("Problem expiring email verifications", "error", err.Error())
-	} else {
-		logger.Debug("Expired email verifications", "rows affected", cmd.NumExpired)
+		return 0, err
 	}
+	logger.Debug("Expired email verifications", "rows affected", cmd.NumExpired)
+	return cmd.NumExpired, nil
 }
 
-func (srv *CleanUpService) deleteStaleShortURLs(ctx context.Context) {
+func (srv *CleanUpService) deleteStaleShortURLs(ctx context.Context) (int64, error) {
 	logger := srv.log.FromContext(ctx)
 	cmd := shorturls.DeleteShortUrlCommand{
-		OlderThan: time.Now().Add(-time.Duration(srv.Cfg.ShortLinkExpiration*24) * time.Hour),
+		OlderThan:      time.Now().Add(-time.Duration(srv.Cfg.ShortLinkExpiration*24) * time.Hour),
+		ExcludedOrgIDs: srv.policyOrgs(retentionShortURLs),
 	}
 	if err := srv.ShortURLService.DeleteStaleShortURLs(ctx, &cmd); err != nil {
 		logger.Error("Problem deleting stale short urls", "error", err.Error())
-	} else {
-		logger.Debug("Deleted short urls", "rows affected", cmd.NumDeleted)
+		return 0, err
 	}
+	logger.Debug("Deleted short urls", "rows affected", cmd.NumDeleted)
+	return cmd.NumDeleted, nil
 }
 
-func (srv *CleanUpService) deleteStaleQueryHistory(ctx context.Context) {
+func (srv *CleanUpService) deleteStaleQueryHistory(ctx context.Context) (int64, error) {
 	logger := srv.log.FromContext(ctx)
 	// Delete query history from 14+ days ago with exception of starred queries
 	maxQueryHistoryLifetime := time.Hour * 24 * 14
 	olderThan := time.Now().Add(-maxQueryHistoryLifetime).Unix()
-	rowsCount, err := srv.QueryHistoryService.DeleteStaleQueriesInQueryHistory(ctx, olderThan)
+	var affected int64
+	var errs []error
+	rowsCount, err := srv.QueryHistoryService.DeleteStaleQueriesInQueryHistory(ctx, olderThan, srv.policyOrgs(retentionQueryHistory))
 	if err != nil {
 		logger.Error("Problem deleting stale query history", "error", err.Error())
+		errs = append(errs, err)
 	} else {
 		logger.Debug("Deleted stale query history", "rows affected", rowsCount)
+		affected += int64(rowsCount)
 	}
 
 	// Enforce 200k limit for query_history table
@@ -290,8 +431,10 @@ func (srv *CleanUpService) deleteStaleQueryHistory(ctx context.Context) {
 	rowsCount, err = srv.QueryHistoryService.EnforceRowLimitInQueryHistory(ctx, queryHistoryLimit, false)
 	if err != nil {
 		logger.Error("Problem with enforcing row limit for query_history", "error", err.Error())
+		errs = append(errs, err)
 	} else {
 		logger.Debug("Enforced row limit for query_history", "rows affected", rowsCount)
+		affected += int64(rowsCount)
 	}
 
 	// Enforce 150k limit for query_history_star table
@@ -299,17 +442,57 @@ func (srv *CleanUpService) deleteStaleQueryHistory(ctx context.Context) {
 	rowsCount, err = srv.QueryHistoryService.EnforceRowLimitInQueryHistory(ctx, queryHistoryStarLimit, true)
 	if err != nil {
 		logger.Error("Problem with enforcing row limit for query_history_star", "error", err.Error())
+		errs = append(errs, err)
 	} else {
 		logger.Debug("Enforced row limit for query_history_star", "rows affected", rowsCount)
+		affected += int64(rowsCount)
 	}
+	return affected, errors.Join(errs...)
 }
 
-func (srv *CleanUpService) cleanUpTrashDashboards(ctx context.Context) {
+func (srv *CleanUpService) cleanUpTrashDashboards(ctx context.Context) (int64, error) {
 	logger := srv.log.FromContext(ctx)
-	affected, err := srv.dashboardService.CleanUpDeletedDashboards(ctx)
+	affected, err := srv.dashboardService.CleanUpDeletedDashboards(ctx, srv.policyOrgs(retentionTrashDashboards))
 	if err != nil {
 		logger.Error("Problem cleaning up deleted dashboards", "error", err)
-	} else {
-		logger.Debug("Cleaned up deleted dashboards", "dashboards affected", affected)
+		return 0, err
+	}
+	logger.Debug("Cleaned up deleted dashboards", "dashboards affected", affected)
+	return affected, nil
+}
+
+// deleteExpiredAlertStateHistory deletes the alert state history recorded by the SQL state history backend that is
+// older than the configured retention.
+func (srv *CleanUpService) deleteExpiredAlertStateHistory(ctx context.Context) (int64, error) {
+	logger := srv.log.FromContext(ctx)
+	retention := srv.Cfg.UnifiedAlerting.StateHistory.SQLRetention
+	if retention <= 0 {
+		return 0, nil
+	}
+
+	affected, err := newStateHistoryRetentionJob(srv.store, true, srv.policyOrgs(retentionAlertStateHistory)).apply(ctx, 0, time.Now().Add(-retention), false)
+	if err != nil {
+		logger.Error("Problem deleting expired alert state history", "error", err)
+		return affected, err
+	}
+	logger.Debug("Deleted expired alert state history", "rows affected", affected)
+	return affected, nil
+}
+
+// deleteExpiredRecordingSamples deletes the samples written by the SQL target of recording rules that are older than
+// the configured retention.
+func (srv *CleanUpService) deleteExpiredRecordingSamples(ctx context.Context) (int64, error) {
+	logger := srv.log.FromContext(ctx)
+	settings := srv.Cfg.UnifiedAlerting.RecordingRules.SQL
+	if !settings.Enabled || settings.Retention <= 0 {
+		return 0, nil
+	}
+
+	affected, err := newRecordingSampleRetentionJob(srv.store, true, srv.policyOrgs(retentionRecordingSamples)).apply(ctx, 0, time.Now().Add(-settings.Retention), false)
+	if err != nil {
+		logger.Error("Problem deleting expired recording rule samples", "error", err)
+		return affected, err
 	}
+	logger.Debug("Deleted expired recording rule samples", "rows affected", affected)
+	return affected, nil
 }
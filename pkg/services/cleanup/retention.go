@@ -0,0 +1,313 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// retentionBatchSize is the number of rows deleted at once by the retention policies.
+const retentionBatchSize = 1000
+
+// Names of the jobs that support per-organization retention policies.
+const (
+	retentionAnnotations        = "annotations"
+	retentionDashboardVersions  = "dashboard_versions"
+	retentionDashboardSnapshots = "dashboard_snapshots"
+	retentionShortURLs          = "short_urls"
+	retentionQueryHistory       = "query_history"
+	retentionTrashDashboards    = "trash_dashboards"
+	retentionAlertStateHistory  = "alert_state_history"
+	retentionRecordingSamples   = "recording_rule_samples"
+)
+
+// retentionJob deletes the data of an organization that was created before a cutoff.
+type retentionJob interface {
+	// apply deletes the data of the organization created before the cutoff and returns the number of deleted items.
+	// If dryRun is set, nothing is deleted and the number of items that would have been deleted is returned.
+	apply(ctx context.Context, orgID int64, cutoff time.Time, dryRun bool) (int64, error)
+}
+
+// childTable is a table whose rows reference the rows deleted by a sqlRetentionJob and are deleted along with them.
+type childTable struct {
+	table        string
+	column       string
+	parentColumn string
+}
+
+// sqlRetentionJob deletes the rows of a table that match a condition, in batches.
+type sqlRetentionJob struct {
+	store db.DB
+	table string
+	// where is the condition selecting the rows to delete. It is given the arguments returned by args.
+	where    string
+	args     func(orgID int64, cutoff time.Time) []any
+	children []childTable
+}
+
+func (j *sqlRetentionJob) apply(ctx context.Context, orgID int64, cutoff time.Time, dryRun bool) (int64, error) {
+	args := j.args(orgID, cutoff)
+	if dryRun {
+		var count int64
+		err := j.store.WithDbSession(ctx, func(sess *db.Session) error {
+			_, err := sess.SQL("SELECT COUNT(*) FROM "+j.table+" WHERE "+j.where, args...).Get(&count)
+			return err
+		})
+		return count, err
+	}
+
+	var deleted int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+		var batch int64
+		err := j.store.InTransaction(ctx, func(ctx context.Context) error {
+			return j.store.WithDbSession(ctx, func(sess *db.Session) error {
+				ids := make([]int64, 0, retentionBatchSize)
+				err := sess.SQL(fmt.Sprintf("SELECT id FROM %s WHERE %s ORDER BY id LIMIT %d", j.table, j.where, retentionBatchSize), args...).Find(&ids)
+				if err != nil || len(ids) == 0 {
+					return err
+				}
+				in := "(?" + strings.Repeat(",?", len(ids)-1) + ")"
+				idArgs := make([]any, 0, len(ids))
+				for _, id := range ids {
+					idArgs = append(idArgs, id)
+				}
+				for _, c := range j.children {
+					sql := fmt.Sprintf("DELETE FROM %s WHERE %s IN (SELECT %s FROM %s WHERE id IN %s)", c.table, c.column, c.parentColumn, j.table, in)
+					if _, err := sess.Exec(append([]any{sql}, idArgs...)...); err != nil {
+						return err
+					}
+				}
+				res, err := sess.Exec(append([]any{"DELETE FROM " + j.table + " WHERE id IN " + in}, idArgs...)...)
+				if err != nil {
+					return err
+				}
+				batch, err = res.RowsAffected()
+				return err
+			})
+		})
+		deleted += batch
+		if err != nil || batch < retentionBatchSize {
+			return deleted, err
+		}
+	}
+}
+
+// trashRetentionJob permanently deletes the dashboards of an organization that were moved to the trash before the cutoff.
+type trashRetentionJob struct {
+	store            db.DB
+	dashboardService dashboards.DashboardService
+}
+
+func (j *trashRetentionJob) apply(ctx context.Context, orgID int64, cutoff time.Time, dryRun bool) (int64, error) {
+	var deleted []*dashboards.Dashboard
+	err := j.store.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Where("org_id = ? AND deleted IS NOT NULL AND deleted < ?", orgID, cutoff).Cols("id", "uid", "org_id").Find(&deleted)
+	})
+	if err != nil || dryRun {
+		return int64(len(deleted)), err
+	}
+
+	var count int64
+	for _, d := range deleted {
+		if err := j.dashboardService.DeleteDashboard(ctx, d.ID, d.UID, d.OrgID); err != nil {
+			return count, fmt.Errorf("failed to delete dashboard %s: %w", d.UID, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// stateHistoryRetentionJob deletes the alert state history recorded by the SQL state history backend, and then the
+// labels of the alert instances that have no history left.
+type stateHistoryRetentionJob struct {
+	history *sqlRetentionJob
+}
+
+// newStateHistoryRetentionJob returns a stateHistoryRetentionJob for the organization given to apply, or for all
+// organizations but the excluded ones if allOrgs is set.
+func newStateHistoryRetentionJob(store db.DB, allOrgs bool, excludedOrgIDs []int64) *stateHistoryRetentionJob {
+	history := &sqlRetentionJob{
+		store: store,
+		table: "alert_state_history",
+		where: "org_id = ? AND evaluated_at < ?",
+		args: func(orgID int64, cutoff time.Time) []any {
+			return []any{orgID, cutoff.UnixMilli()}
+		},
+	}
+	if allOrgs {
+		excluded, excludedArgs := notInOrgs(excludedOrgIDs)
+		history.where = "evaluated_at < ?" + excluded
+		history.args = func(_ int64, cutoff time.Time) []any {
+			return append([]any{cutoff.UnixMilli()}, excludedArgs...)
+		}
+	}
+	return &stateHistoryRetentionJob{history: history}
+}
+
+func (j *stateHistoryRetentionJob) apply(ctx context.Context, orgID int64, cutoff time.Time, dryRun bool) (int64, error) {
+	deleted, err := j.history.apply(ctx, orgID, cutoff, dryRun)
+	if err != nil || dryRun || deleted == 0 {
+		return deleted, err
+	}
+
+	err = j.history.store.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Exec("DELETE FROM alert_state_history_label WHERE NOT EXISTS (SELECT 1 FROM alert_state_history h WHERE h.org_id = alert_state_history_label.org_id AND h.fingerprint = alert_state_history_label.fingerprint)")
+		return err
+	})
+	return deleted, err
+}
+
+// newRecordingSampleRetentionJob returns a job that deletes the samples written by the SQL target of recording rules
+// for the organization given to apply, or for all organizations but the excluded ones if allOrgs is set.
+func newRecordingSampleRetentionJob(store db.DB, allOrgs bool, excludedOrgIDs []int64) *sqlRetentionJob {
+	job := &sqlRetentionJob{
+		store: store,
+		table: "alert_recording_rule_sample",
+		where: "org_id = ? AND timestamp < ?",
+		args: func(orgID int64, cutoff time.Time) []any {
+			return []any{orgID, cutoff.UnixMilli()}
+		},
+	}
+	if allOrgs {
+		excluded, excludedArgs := notInOrgs(excludedOrgIDs)
+		job.where = "timestamp < ?" + excluded
+		job.args = func(_ int64, cutoff time.Time) []any {
+			return append([]any{cutoff.UnixMilli()}, excludedArgs...)
+		}
+	}
+	return job
+}
+
+// notInOrgs returns a condition on the org_id column that excludes the given organizations, and its arguments.
+func notInOrgs(orgIDs []int64) (string, []any) {
+	if len(orgIDs) == 0 {
+		return "", nil
+	}
+	args := make([]any, 0, len(orgIDs))
+	for _, id := range orgIDs {
+		args = append(args, id)
+	}
+	return " AND org_id NOT IN (?" + strings.Repeat(",?", len(orgIDs)-1) + ")", args
+}
+
+// newRetentionJobs returns the retention jobs by name. The dashboard versions job always keeps the versionsToKeep most
+// recent versions of each dashboard, like the built-in job does.
+func newRetentionJobs(store db.DB, dashboardService dashboards.DashboardService, versionsToKeep int) map[string]retentionJob {
+	if versionsToKeep < 1 {
+		versionsToKeep = 1
+	}
+	unixSeconds := func(orgID int64, cutoff time.Time) []any {
+		return []any{orgID, cutoff.Unix()}
+	}
+	return map[string]retentionJob{
+		retentionAnnotations: &sqlRetentionJob{
+			store: store,
+			table: "annotation",
+			where: "org_id = ? AND epoch < ?",
+			args: func(orgID int64, cutoff time.Time) []any {
+				return []any{orgID, cutoff.UnixMilli()}
+			},
+			children: []childTable{{table: "annotation_tag", column: "annotation_id", parentColumn: "id"}},
+		},
+		retentionDashboardVersions: &sqlRetentionJob{
+			store: store,
+			table: "dashboard_version",
+			// The current version of a dashboard and the most recent versions to keep are always kept.
+			where: "created < ? AND dashboard_id IN (SELECT d.id FROM dashboard d WHERE d.org_id = ? AND d.version > dashboard_version.version)" +
+				" AND (SELECT COUNT(*) FROM dashboard_version v WHERE v.dashboard_id = dashboard_version.dashboard_id AND v.version > dashboard_version.version) >= ?",
+			args: func(orgID int64, cutoff time.Time) []any {
+				return []any{cutoff, orgID, versionsToKeep}
+			},
+		},
+		retentionDashboardSnapshots: &sqlRetentionJob{
+			store: store,
+			table: "dashboard_snapshot",
+			where: "org_id = ? AND created < ?",
+			args: func(orgID int64, cutoff time.Time) []any {
+				return []any{orgID, cutoff}
+			},
+		},
+		retentionShortURLs: &sqlRetentionJob{
+			store: store,
+			table: "short_url",
+			where: "org_id = ? AND created_at < ?",
+			args:  unixSeconds,
+		},
+		retentionQueryHistory: &sqlRetentionJob{
+			store: store,
+			table: "query_history",
+			// Starred queries are kept, as they are by the built-in job.
+			where: "org_id = ? AND created_at < ? AND uid NOT IN (SELECT query_uid FROM query_history_star)",
+			args:  unixSeconds,
+			children: []childTable{
+				{table: "query_history_details", column: "query_history_item_uid", parentColumn: "uid"},
+			},
+		},
+		retentionTrashDashboards: &trashRetentionJob{
+			store:            store,
+			dashboardService: dashboardService,
+		},
+		retentionAlertStateHistory: newStateHistoryRetentionJob(store, false, nil),
+		retentionRecordingSamples:  newRecordingSampleRetentionJob(store, false, nil),
+	}
+}
+
+// validRetentionPolicies returns the policies whose job supports retention policies and logs the others.
+func (srv *CleanUpService) validRetentionPolicies(policies []setting.RetentionPolicy) []setting.RetentionPolicy {
+	valid := make([]setting.RetentionPolicy, 0, len(policies))
+	for _, p := range policies {
+		if _, ok := srv.retentionJobs[p.Job]; !ok {
+			srv.log.Error("Ignoring retention policy for unknown job", "job", p.Job, "orgId", p.OrgID)
+			continue
+		}
+		valid = append(valid, p)
+	}
+	return valid
+}
+
+// policyOrgs returns the organizations that have a retention policy for a job. The built-in job leaves their data
+// alone, so that their policy replaces the global retention rather than adding to it.
+func (srv *CleanUpService) policyOrgs(job string) []int64 {
+	var orgIDs []int64
+	for _, p := range srv.retentionPolicies {
+		if p.Job == job {
+			orgIDs = append(orgIDs, p.OrgID)
+		}
+	}
+	return orgIDs
+}
+
+// applyRetentionPolicies applies the retention policies of a job to each organization that has one.
+func (srv *CleanUpService) applyRetentionPolicies(ctx context.Context, job string, dryRun bool) []OrgResult {
+	logger := srv.log.FromContext(ctx)
+	now := time.Now()
+
+	var results []OrgResult
+	for _, p := range srv.retentionPolicies {
+		if p.Job != job {
+			continue
+		}
+		affected, err := srv.retentionJobs[job].apply(ctx, p.OrgID, now.Add(-p.MaxAge), dryRun)
+		result := OrgResult{
+			OrgID:    p.OrgID,
+			MaxAge:   p.MaxAge.String(),
+			Affected: affected,
+		}
+		if err != nil {
+			logger.Error("Failed to apply retention policy", "job", job, "orgId", p.OrgID, "error", err)
+			result.Error = err.Error()
+		} else {
+			logger.Debug("Applied retention policy", "job", job, "orgId", p.OrgID, "dryRun", dryRun, "affected", affected)
+		}
+		results = append(results, result)
+	}
+	return results
+}
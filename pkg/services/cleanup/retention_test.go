@@ -0,0 +1,346 @@
+package cleanup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/annotations"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	dashver "github.com/grafana/grafana/pkg/services/dashboardversion"
+	"github.com/grafana/grafana/pkg/services/shorturls"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/tests/testsuite"
+)
+
+func TestMain(m *testing.M) {
+	testsuite.Run(m)
+}
+
+func TestIntegrationRetentionPolicies(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	store := db.InitTestDB(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	err := store.WithDbSession(ctx, func(sess *db.Session) error {
+		for _, a := range []annotations.Item{
+			{OrgID: 1, Epoch: now.Add(-48 * time.Hour).UnixMilli()},
+			{OrgID: 1, Epoch: now.Add(-time.Hour).UnixMilli()},
+			{OrgID: 2, Epoch: now.Add(-48 * time.Hour).UnixMilli()},
+		} {
+			if _, err := sess.Insert(&a); err != nil {
+				return err
+			}
+		}
+		for i, u := range []shorturls.ShortUrl{
+			{OrgId: 1, CreatedAt: now.Add(-48 * time.Hour).Unix()},
+			{OrgId: 1, CreatedAt: now.Add(-48 * time.Hour).Unix(), LastSeenAt: now.Unix()},
+		} {
+			u.Uid = string(rune('a' + i))
+			if _, err := sess.Insert(&u); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	cfg := setting.NewCfg()
+	srv := &CleanUpService{
+		log:           log.New("cleanup"),
+		Cfg:           cfg,
+		retentionJobs: newRetentionJobs(store, nil, 1),
+		lastResults:   make(map[string]JobResult),
+	}
+	srv.retentionPolicies = srv.validRetentionPolicies([]setting.RetentionPolicy{
+		{Job: retentionAnnotations, OrgID: 1, MaxAge: 24 * time.Hour},
+		{Job: retentionShortURLs, OrgID: 1, MaxAge: 24 * time.Hour},
+		{Job: retentionDashboardVersions, OrgID: 3, MaxAge: time.Hour},
+		{Job: retentionDashboardSnapshots, OrgID: 3, MaxAge: time.Hour},
+		{Job: retentionQueryHistory, OrgID: 3, MaxAge: time.Hour},
+		{Job: retentionTrashDashboards, OrgID: 3, MaxAge: time.Hour},
+		{Job: "unknown", OrgID: 1, MaxAge: time.Hour},
+	})
+	require.Len(t, srv.retentionPolicies, 6, "policies of unknown jobs should be ignored")
+
+	count := func(bean any, orgID int64) int64 {
+		var n int64
+		err := store.WithDbSession(ctx, func(sess *db.Session) error {
+			var err error
+			n, err = sess.Where("org_id = ?", orgID).Count(bean)
+			return err
+		})
+		require.NoError(t, err)
+		return n
+	}
+
+	t.Run("dry run reports what would be deleted", func(t *testing.T) {
+		results := srv.DryRun(ctx)
+		byJob := make(map[string]JobResult)
+		for _, r := range results {
+			byJob[r.Job] = r
+		}
+		require.Len(t, byJob, len(srv.retentionJobs))
+
+		require.True(t, byJob[retentionAnnotations].DryRun)
+		require.Equal(t, builtInNotEvaluated, byJob[retentionAnnotations].BuiltIn, "dry runs should not report the built-in jobs as deleting nothing")
+		require.Equal(t, []OrgResult{{OrgID: 1, MaxAge: "24h0m0s", Affected: 1}}, byJob[retentionAnnotations].Orgs)
+		require.Equal(t, int64(2), byJob[retentionShortURLs].Affected)
+		for _, job := range []string{retentionDashboardVersions, retentionDashboardSnapshots, retentionQueryHistory, retentionTrashDashboards} {
+			require.Equal(t, []OrgResult{{OrgID: 3, MaxAge: "1h0m0s"}}, byJob[job].Orgs, job)
+		}
+
+		require.Equal(t, int64(2), count(&annotations.Item{}, 1))
+		require.Equal(t, int64(2), count(&shorturls.ShortUrl{}, 1))
+		require.Empty(t, srv.LastResults(), "dry runs should not be recorded as job runs")
+	})
+
+	t.Run("deletes old data of the organizations with a policy", func(t *testing.T) {
+		for _, job := range []cleanUpJob{
+			{name: "cleanup old annotations", retention: retentionAnnotations},
+			{name: "delete stale short URLs", retention: retentionShortURLs},
+		} {
+			srv.recordResult(srv.runJob(ctx, job, false))
+		}
+
+		require.Equal(t, int64(1), count(&annotations.Item{}, 1))
+		require.Equal(t, int64(1), count(&annotations.Item{}, 2), "organizations without a policy should be kept")
+		require.Equal(t, int64(0), count(&shorturls.ShortUrl{}, 1))
+
+		results := srv.LastResults()
+		require.Len(t, results, 2)
+		require.Equal(t, "cleanup old annotations", results[0].Job)
+		require.Equal(t, int64(1), results[0].Affected)
+		require.False(t, results[0].DryRun)
+		require.Equal(t, builtInDisabled, results[0].BuiltIn)
+		require.Equal(t, "delete stale short URLs", results[1].Job)
+		require.Equal(t, int64(2), results[1].Affected)
+	})
+}
+
+func TestIntegrationAlertStateHistoryRetention(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	store := db.InitTestDB(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	err := store.WithDbSession(ctx, func(sess *db.Session) error {
+		for _, h := range []struct {
+			orgID       int64
+			fingerprint string
+			evaluatedAt time.Time
+		}{
+			{1, "old", now.Add(-48 * time.Hour)},
+			{1, "recent", now.Add(-48 * time.Hour)},
+			{1, "recent", now.Add(-time.Hour)},
+			{2, "old", now.Add(-48 * time.Hour)},
+		} {
+			if _, err := sess.Exec("INSERT INTO alert_state_history (org_id, rule_uid, folder_uid, fingerprint, previous_state, current_state, state_values, evaluated_at) VALUES (?, 'rule', 'folder', ?, 'Normal', 'Alerting', '{}', ?)",
+				h.orgID, h.fingerprint, h.evaluatedAt.UnixMilli()); err != nil {
+				return err
+			}
+			if _, err := sess.Exec("DELETE FROM alert_state_history_label WHERE org_id = ? AND fingerprint = ?", h.orgID, h.fingerprint); err != nil {
+				return err
+			}
+			if _, err := sess.Exec("INSERT INTO alert_state_history_label (org_id, fingerprint, name, value) VALUES (?, ?, 'instance', ?)", h.orgID, h.fingerprint, h.fingerprint); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	count := func(table string, orgID int64) int64 {
+		var n int64
+		err := store.WithDbSession(ctx, func(sess *db.Session) error {
+			_, err := sess.SQL("SELECT COUNT(*) FROM "+table+" WHERE org_id = ?", orgID).Get(&n)
+			return err
+		})
+		require.NoError(t, err)
+		return n
+	}
+
+	t.Run("retention policy deletes the history and the labels of an organization", func(t *testing.T) {
+		job := newStateHistoryRetentionJob(store, false, nil)
+
+		affected, err := job.apply(ctx, 1, now.Add(-24*time.Hour), true)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), affected)
+		require.Equal(t, int64(3), count("alert_state_history", 1))
+
+		affected, err = job.apply(ctx, 1, now.Add(-24*time.Hour), false)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), affected)
+		require.Equal(t, int64(1), count("alert_state_history", 1))
+		require.Equal(t, int64(1), count("alert_state_history_label", 1), "labels of instances with history should be kept")
+		require.Equal(t, int64(1), count("alert_state_history", 2))
+		require.Equal(t, int64(1), count("alert_state_history_label", 2))
+	})
+
+	t.Run("built-in job leaves the organizations with a policy alone", func(t *testing.T) {
+		cfg := setting.NewCfg()
+		cfg.UnifiedAlerting.StateHistory.SQLRetention = 24 * time.Hour
+		srv := &CleanUpService{log: log.New("cleanup"), Cfg: cfg, store: store, retentionPolicies: []setting.RetentionPolicy{
+			{Job: retentionAlertStateHistory, OrgID: 2, MaxAge: 72 * time.Hour},
+		}}
+
+		affected, err := srv.deleteExpiredAlertStateHistory(ctx)
+		require.NoError(t, err)
+		require.Equal(t, int64(0), affected)
+		require.Equal(t, int64(1), count("alert_state_history", 2))
+	})
+
+	t.Run("built-in job deletes the history of all organizations older than the retention", func(t *testing.T) {
+		cfg := setting.NewCfg()
+		cfg.UnifiedAlerting.StateHistory.SQLRetention = 24 * time.Hour
+		srv := &CleanUpService{log: log.New("cleanup"), Cfg: cfg, store: store}
+
+		affected, err := srv.deleteExpiredAlertStateHistory(ctx)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), affected)
+		require.Equal(t, int64(1), count("alert_state_history", 1))
+		require.Equal(t, int64(0), count("alert_state_history", 2))
+		require.Equal(t, int64(0), count("alert_state_history_label", 2))
+	})
+}
+
+func TestIntegrationRecordingSampleRetention(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	store := db.InitTestDB(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	err := store.WithDbSession(ctx, func(sess *db.Session) error {
+		for _, s := range []struct {
+			orgID int64
+			ts    time.Time
+		}{
+			{1, now.Add(-48 * time.Hour)},
+			{1, now.Add(-time.Hour)},
+			{2, now.Add(-48 * time.Hour)},
+		} {
+			if _, err := sess.Exec("INSERT INTO alert_recording_rule_sample (org_id, metric, labels, labels_hash, timestamp, value) VALUES (?, 'metric', '{}', 'hash', ?, 1)",
+				s.orgID, s.ts.UnixMilli()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	count := func(orgID int64) int64 {
+		var n int64
+		err := store.WithDbSession(ctx, func(sess *db.Session) error {
+			_, err := sess.SQL("SELECT COUNT(*) FROM alert_recording_rule_sample WHERE org_id = ?", orgID).Get(&n)
+			return err
+		})
+		require.NoError(t, err)
+		return n
+	}
+
+	t.Run("built-in job does nothing if the sql target is disabled", func(t *testing.T) {
+		cfg := setting.NewCfg()
+		cfg.UnifiedAlerting.RecordingRules.SQL.Retention = 24 * time.Hour
+		srv := &CleanUpService{log: log.New("cleanup"), Cfg: cfg, store: store}
+
+		affected, err := srv.deleteExpiredRecordingSamples(ctx)
+		require.NoError(t, err)
+		require.Equal(t, int64(0), affected)
+		require.Equal(t, int64(2), count(1))
+	})
+
+	t.Run("retention policy deletes the samples of an organization", func(t *testing.T) {
+		job := newRecordingSampleRetentionJob(store, false, nil)
+
+		affected, err := job.apply(ctx, 1, now.Add(-24*time.Hour), false)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), affected)
+		require.Equal(t, int64(1), count(1))
+		require.Equal(t, int64(1), count(2))
+	})
+
+	t.Run("built-in job leaves the organizations with a policy alone", func(t *testing.T) {
+		cfg := setting.NewCfg()
+		cfg.UnifiedAlerting.RecordingRules.SQL = setting.RecordingRuleSQLSettings{Enabled: true, Retention: 24 * time.Hour}
+		srv := &CleanUpService{log: log.New("cleanup"), Cfg: cfg, store: store, retentionPolicies: []setting.RetentionPolicy{
+			{Job: retentionRecordingSamples, OrgID: 2, MaxAge: 72 * time.Hour},
+		}}
+
+		affected, err := srv.deleteExpiredRecordingSamples(ctx)
+		require.NoError(t, err)
+		require.Equal(t, int64(0), affected)
+		require.Equal(t, int64(1), count(2))
+	})
+
+	t.Run("built-in job deletes the samples of all organizations older than the retention", func(t *testing.T) {
+		cfg := setting.NewCfg()
+		cfg.UnifiedAlerting.RecordingRules.SQL = setting.RecordingRuleSQLSettings{Enabled: true, Retention: 24 * time.Hour}
+		srv := &CleanUpService{log: log.New("cleanup"), Cfg: cfg, store: store}
+
+		affected, err := srv.deleteExpiredRecordingSamples(ctx)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), affected)
+		require.Equal(t, int64(1), count(1))
+		require.Equal(t, int64(0), count(2))
+	})
+}
+
+func TestIntegrationDashboardVersionRetention(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	store := db.InitTestDB(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	dash := &dashboards.Dashboard{OrgID: 1, UID: "dash", Title: "dash", Slug: "dash", Version: 5, Created: now, Updated: now, Data: simplejson.New()}
+	err := store.WithDbSession(ctx, func(sess *db.Session) error {
+		if _, err := sess.Insert(dash); err != nil {
+			return err
+		}
+		for v := 1; v <= 5; v++ {
+			if _, err := sess.Insert(&dashver.DashboardVersion{DashboardID: dash.ID, Version: v, Created: now.Add(-48 * time.Hour), Data: simplejson.New()}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	versions := func() []int {
+		var v []int
+		err := store.WithDbSession(ctx, func(sess *db.Session) error {
+			return sess.SQL("SELECT version FROM dashboard_version WHERE dashboard_id = ? ORDER BY version", dash.ID).Find(&v)
+		})
+		require.NoError(t, err)
+		return v
+	}
+
+	job := newRetentionJobs(store, nil, 3)[retentionDashboardVersions]
+	affected, err := job.apply(ctx, 1, now.Add(-24*time.Hour), false)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), affected)
+	require.Equal(t, []int{3, 4, 5}, versions(), "the most recent versions to keep should be kept")
+
+	job = newRetentionJobs(store, nil, 0)[retentionDashboardVersions]
+	affected, err = job.apply(ctx, 1, now.Add(-24*time.Hour), false)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), affected)
+	require.Equal(t, []int{5}, versions(), "the current version should be kept")
+}
@@ -32,7 +32,7 @@ type DashboardService interface {
 	GetAllDashboards(ctx context.Context) ([]*Dashboard, error)
 	SoftDeleteDashboard(ctx context.Context, orgID int64, dashboardUid string) error
 	RestoreDashboard(ctx context.Context, dashboard *Dashboard, user identity.Requester, optionalFolderUID string) error
-	CleanUpDeletedDashboards(ctx context.Context) (int64, error)
+	CleanUpDeletedDashboards(ctx context.Context, excludedOrgIDs []int64) (int64, error)
 	GetSoftDeletedDashboard(ctx context.Context, orgID int64, uid string) (*Dashboard, error)
 }
 
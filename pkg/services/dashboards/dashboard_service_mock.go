@@ -46,9 +46,9 @@ func (_m *FakeDashboardService) BuildSaveDashboardCommand(ctx context.Context, d
 	return r0, r1
 }
 
-// CleanUpDeletedDashboards provides a mock function with given fields: ctx
-func (_m *FakeDashboardService) CleanUpDeletedDashboards(ctx context.Context) (int64, error) {
-	ret := _m.Called(ctx)
+// CleanUpDeletedDashboards provides a mock function with given fields: ctx, excludedOrgIDs
+func (_m *FakeDashboardService) CleanUpDeletedDashboards(ctx context.Context, excludedOrgIDs []int64) (int64, error) {
+	ret := _m.Called(ctx, excludedOrgIDs)
 
 	if len(ret) == 0 {
 		panic("no return value specified for CleanUpDeletedDashboards")
@@ -56,17 +56,17 @@ func (_m *FakeDashboardService) CleanUpDeletedDashboards(ctx context.Context) (i
 
 	var r0 int64
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context) (int64, error)); ok {
-		return rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) (int64, error)); ok {
+		return rf(ctx, excludedOrgIDs)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
-		r0 = rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) int64); ok {
+		r0 = rf(ctx, excludedOrgIDs)
 	} else {
 		r0 = ret.Get(0).(int64)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
-		r1 = rf(ctx)
+	if rf, ok := ret.Get(1).(func(context.Context, []int64) error); ok {
+		r1 = rf(ctx, excludedOrgIDs)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -906,7 +906,9 @@ func (dr *DashboardServiceImpl) DeleteInFolders(ctx context.Context, orgID int64
 
 func (dr *DashboardServiceImpl) Kind() string { return entity.StandardKindDashboard }
 
-func (dr *DashboardServiceImpl) CleanUpDeletedDashboards(ctx context.Context) (int64, error) {
+// CleanUpDeletedDashboards permanently deletes the dashboards that have been in the trash for too long, except those
+// of the excluded organizations.
+func (dr *DashboardServiceImpl) CleanUpDeletedDashboards(ctx context.Context, excludedOrgIDs []int64) (int64, error) {
 	ctx, span := tracer.Start(ctx, "dashboards.service.CleanUpDeletedDashboards")
 	defer span.End()
 
@@ -916,6 +918,9 @@ func (dr *DashboardServiceImpl) CleanUpDeletedDashboards(ctx context.Context) (i
 		return 0, err
 	}
 	for _, dashboard := range deletedDashboards {
+		if slices.Contains(excludedOrgIDs, dashboard.OrgID) {
+			continue
+		}
 		err = dr.DeleteDashboard(ctx, dashboard.ID, dashboard.UID, dashboard.OrgID)
 		if err != nil {
 			dr.log.Warn("Failed to cleanup deleted dashboard", "dashboardUid", dashboard.UID, "error", err)
@@ -526,6 +526,27 @@ func TestDeleteDashboard(t *testing.T) {
 	})
 }
 
+func TestCleanUpDeletedDashboards(t *testing.T) {
+	fakeStore := dashboards.FakeDashboardStore{}
+	defer fakeStore.AssertExpectations(t)
+	service := &DashboardServiceImpl{
+		cfg:            setting.NewCfg(),
+		dashboardStore: &fakeStore,
+		features:       featuremgmt.WithFeatures(),
+	}
+
+	fakeStore.On("GetSoftDeletedExpiredDashboards", mock.Anything, daysInTrash).Return([]*dashboards.Dashboard{
+		{ID: 1, UID: "uid1", OrgID: 1},
+		{ID: 2, UID: "uid2", OrgID: 2},
+	}, nil).Once()
+	fakeStore.On("GetProvisionedDataByDashboardID", mock.Anything, int64(1)).Return(nil, nil).Once()
+	fakeStore.On("DeleteDashboard", mock.Anything, &dashboards.DeleteDashboardCommand{OrgID: 1, ID: 1, UID: "uid1"}).Return(nil).Once()
+
+	deleted, err := service.CleanUpDeletedDashboards(context.Background(), []int64{2})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+}
+
 func TestUnstructuredToLegacyDashboard(t *testing.T) {
 	fake := usertest.NewUserServiceFake()
 	fake.ExpectedUser = &user.User{ID: 10, UID: "useruid"}
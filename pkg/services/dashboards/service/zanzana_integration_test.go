@@ -88,7 +88,8 @@ func TestIntegrationDashboardServiceZanzana(t *testing.T) {
 
 		// Sync Grafana DB with zanzana (migrate data)
 		tracer := tracing.InitializeTracerForTest()
-		lock := serverlock.ProvideService(db, tracer)
+		lock, err := serverlock.ProvideService(db, tracer, cfg)
+		require.NoError(t, err)
 		zanzanaSyncronizer := dualwrite.NewZanzanaReconciler(cfg, zclient, db, lock)
 		err = zanzanaSyncronizer.ReconcileSync(context.Background())
 		require.NoError(t, err)
@@ -116,6 +116,26 @@ func testIntegrationGetDashboardVersion(t *testing.T, fn getStore) {
 		require.Nil(t, err)
 		assert.Equal(t, 2, len(res))
 	})
+
+	t.Run("Keep the versions of excluded organizations", func(t *testing.T) {
+		otherDash := insertTestDashboard(t, ss, "test dash 44", 2, "", false, "diff-all")
+		updateTestDashboard(t, ss, otherDash, map[string]any{
+			"tags": "different-tag",
+		})
+
+		ids, err := dashVerStore.GetBatch(context.Background(), &dashver.DeleteExpiredVersionsCommand{}, 100, 1)
+		require.NoError(t, err)
+		all := len(ids)
+		require.Greater(t, all, 0)
+
+		ids, err = dashVerStore.GetBatch(context.Background(), &dashver.DeleteExpiredVersionsCommand{ExcludedOrgIDs: []int64{2}}, 100, 1)
+		require.NoError(t, err)
+		assert.Len(t, ids, all-1)
+
+		ids, err = dashVerStore.GetBatch(context.Background(), &dashver.DeleteExpiredVersionsCommand{ExcludedOrgIDs: []int64{1, 2}}, 100, 1)
+		require.NoError(t, err)
+		assert.Empty(t, ids)
+	})
 }
 
 func getDashboard(t *testing.T, sqlStore db.DB, dashboard *dashboards.Dashboard) error {
@@ -46,10 +46,20 @@ func (ss *sqlStore) GetBatch(ctx context.Context, cmd *dashver.DeleteExpiredVers
 				GROUP BY dashboard_id
 			) AS vtd
 			WHERE dashboard_version.dashboard_id=vtd.dashboard_id
-			AND version < vtd.min + vtd.count - ?
+			AND version < vtd.min + vtd.count - ?`
+		args := []any{versionsToKeep}
+		if len(cmd.ExcludedOrgIDs) > 0 {
+			versionIdsToDeleteQuery += `
+			AND dashboard_version.dashboard_id NOT IN (SELECT id FROM dashboard WHERE org_id IN (?` + strings.Repeat(",?", len(cmd.ExcludedOrgIDs)-1) + `))`
+			for _, orgID := range cmd.ExcludedOrgIDs {
+				args = append(args, orgID)
+			}
+		}
+		versionIdsToDeleteQuery += `
 			LIMIT ?`
+		args = append(args, perBatch)
 
-		err := sess.SQL(versionIdsToDeleteQuery, versionsToKeep, perBatch).Find(&versionIds)
+		err := sess.SQL(versionIdsToDeleteQuery, args...).Find(&versionIds)
 		return err
 	})
 	return versionIds, err
@@ -56,7 +56,9 @@ type GetDashboardVersionQuery struct {
 }
 
 type DeleteExpiredVersionsCommand struct {
-	DeletedRows int64
+	// ExcludedOrgIDs are the organizations whose dashboard versions are left alone.
+	ExcludedOrgIDs []int64
+	DeletedRows    int64
 }
 
 type ListDashboardVersionsQuery struct {
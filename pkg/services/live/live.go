@@ -170,6 +170,25 @@ func ProvideService(plugCtxProvider *plugincontext.Provider, cfg *setting.Cfg, r
 
 	g.ManagedStreamRunner = managedStreamRunner
 
+	pipelineStorage := pipeline.NewSQLStorage(g.SQLStore, g.SecretsService)
+	g.pipelineStorage = pipelineStorage
+	if g.Cfg.LivePipelineEnabled {
+		channelRuleGetter := pipeline.NewCacheSegmentedTree(&pipeline.StorageRuleBuilder{
+			Node:                 node,
+			ManagedStream:        g.ManagedStreamRunner,
+			FrameStorage:         pipeline.NewFrameStorage(),
+			Storage:              pipelineStorage,
+			ChannelHandlerGetter: g,
+			SecretsService:       g.SecretsService,
+		})
+		// Rebuild the rules of an organization as soon as they change on this instance or on another one.
+		pipelineStorage.Subscribe(channelRuleGetter.Reload)
+		g.Pipeline, err = pipeline.New(channelRuleGetter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	g.contextGetter = liveplugin.NewContextGetter(g.PluginContextProvider, g.DataSourceCache)
 	pipelinedChannelLocalPublisher := liveplugin.NewChannelLocalPublisher(node, g.Pipeline)
 	numLocalSubscribersGetter := liveplugin.NewNumLocalSubscribersGetter(node)
@@ -463,6 +482,12 @@ func (g *GrafanaLive) Run(ctx context.Context) error {
 		}
 	})
 
+	if s, ok := g.pipelineStorage.(*pipeline.SQLStorage); ok {
+		eGroup.Go(func() error {
+			return s.Run(eCtx)
+		})
+	}
+
 	if g.runStreamManager != nil {
 		// Only run stream manager if GrafanaLive properly initialized.
 		eGroup.Go(func() error {
@@ -59,6 +59,20 @@ func (s *CacheSegmentedTree) fillOrg(orgID int64) error {
 	return nil
 }
 
+// Reload rebuilds the rule tree of the organization if it was already built. It is meant to be
+// called when the channel rules or write configs of the organization change.
+func (s *CacheSegmentedTree) Reload(orgID int64) {
+	s.radixMu.RLock()
+	_, ok := s.radix[orgID]
+	s.radixMu.RUnlock()
+	if !ok {
+		return
+	}
+	if err := s.fillOrg(orgID); err != nil {
+		logger.Error("Error reloading orgId", "error", err, "orgId", orgID)
+	}
+}
+
 func (s *CacheSegmentedTree) Get(orgID int64, channel string) (*LiveChannelRule, bool, error) {
 	s.radixMu.RLock()
 	_, ok := s.radix[orgID]
@@ -0,0 +1,453 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// sqlStoragePollInterval is how often SQLStorage checks whether another Grafana instance changed the pipeline.
+const sqlStoragePollInterval = 5 * time.Second
+
+type channelRuleRow struct {
+	ID       int64  `xorm:"pk autoincr 'id'"`
+	OrgID    int64  `xorm:"org_id"`
+	Pattern  string `xorm:"pattern"`
+	Settings string `xorm:"settings"`
+	Updated  int64  `xorm:"updated"`
+}
+
+func (channelRuleRow) TableName() string {
+	return "live_channel_rule"
+}
+
+type writeConfigRow struct {
+	ID             int64  `xorm:"pk autoincr 'id'"`
+	OrgID          int64  `xorm:"org_id"`
+	UID            string `xorm:"uid"`
+	Settings       string `xorm:"settings"`
+	SecureSettings string `xorm:"secure_settings"`
+	Updated        int64  `xorm:"updated"`
+}
+
+func (writeConfigRow) TableName() string {
+	return "live_write_config"
+}
+
+type pipelineVersionRow struct {
+	OrgID   int64 `xorm:"pk 'org_id'"`
+	Version int64 `xorm:"'version'"`
+}
+
+func (pipelineVersionRow) TableName() string {
+	return "live_pipeline_version"
+}
+
+// SQLStorage keeps channel rules and write configs in the Grafana database, so that they are
+// shared by all Grafana instances. Every change increments a version of the organization, which
+// Run polls to notify the subscribers of changes made by other instances.
+type SQLStorage struct {
+	store          db.DB
+	secretsService secrets.Service
+
+	mu          sync.Mutex
+	subscribers []func(orgID int64)
+	versions    map[int64]int64
+	polled      bool
+}
+
+func NewSQLStorage(store db.DB, secretsService secrets.Service) *SQLStorage {
+	return &SQLStorage{
+		store:          store,
+		secretsService: secretsService,
+		versions:       map[int64]int64{},
+	}
+}
+
+// Subscribe registers a function called with the organization ID every time the channel rules or
+// write configs of the organization change, on this instance or on another one.
+func (s *SQLStorage) Subscribe(fn func(orgID int64)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, fn)
+}
+
+// Run polls for changes made by other Grafana instances until the context is cancelled.
+func (s *SQLStorage) Run(ctx context.Context) error {
+	ticker := time.NewTicker(sqlStoragePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := s.poll(ctx); err != nil {
+				logger.Error("Error polling live pipeline changes", "error", err)
+			}
+		}
+	}
+}
+
+func (s *SQLStorage) poll(ctx context.Context) error {
+	s.mu.Lock()
+	idle := len(s.subscribers) == 0
+	s.mu.Unlock()
+	if idle {
+		return nil
+	}
+
+	var rows []pipelineVersionRow
+	err := s.store.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Find(&rows)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	var changed []int64
+	for _, r := range rows {
+		if s.polled && s.versions[r.OrgID] != r.Version {
+			changed = append(changed, r.OrgID)
+		}
+		s.versions[r.OrgID] = r.Version
+	}
+	s.polled = true
+	s.mu.Unlock()
+
+	for _, orgID := range changed {
+		s.notify(orgID)
+	}
+	return nil
+}
+
+func (s *SQLStorage) notify(orgID int64) {
+	s.mu.Lock()
+	subscribers := make([]func(int64), len(s.subscribers))
+	copy(subscribers, s.subscribers)
+	s.mu.Unlock()
+	for _, fn := range subscribers {
+		fn(orgID)
+	}
+}
+
+// ensureVersion creates the version of the organization if it doesn't exist yet. When two first writes
+// race to create it, the one that loses gets a unique constraint violation, which is ignored.
+func (s *SQLStorage) ensureVersion(ctx context.Context, orgID int64) error {
+	return s.store.WithDbSession(ctx, func(sess *db.Session) error {
+		exists, err := sess.Exist(&pipelineVersionRow{OrgID: orgID})
+		if err != nil || exists {
+			return err
+		}
+		_, err = sess.Insert(&pipelineVersionRow{OrgID: orgID})
+		if err != nil && s.store.GetDialect().IsUniqueConstraintViolation(err) {
+			return nil
+		}
+		return err
+	})
+}
+
+// update runs fn in a transaction, increments the version of the organization and notifies the subscribers.
+func (s *SQLStorage) update(ctx context.Context, orgID int64, fn func(sess *db.Session) error) error {
+	if err := s.ensureVersion(ctx, orgID); err != nil {
+		return err
+	}
+
+	var version int64
+	err := s.store.InTransaction(ctx, func(ctx context.Context) error {
+		return s.store.WithDbSession(ctx, func(sess *db.Session) error {
+			if err := fn(sess); err != nil {
+				return err
+			}
+			if _, err := sess.Exec("UPDATE live_pipeline_version SET version = version + 1 WHERE org_id = ?", orgID); err != nil {
+				return err
+			}
+			row := pipelineVersionRow{}
+			if _, err := sess.ID(orgID).Get(&row); err != nil {
+				return err
+			}
+			version = row.Version
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.versions[orgID] = version
+	s.mu.Unlock()
+	s.notify(orgID)
+	return nil
+}
+
+func (s *SQLStorage) ListWriteConfigs(ctx context.Context, orgID int64) ([]WriteConfig, error) {
+	var rows []writeConfigRow
+	err := s.store.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Where("org_id = ?", orgID).Asc("uid").Find(&rows)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't read write configs: %w", err)
+	}
+	configs := make([]WriteConfig, 0, len(rows))
+	for _, r := range rows {
+		c, err := writeConfigFromRow(r)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, c)
+	}
+	return configs, nil
+}
+
+func (s *SQLStorage) GetWriteConfig(ctx context.Context, orgID int64, cmd WriteConfigGetCmd) (WriteConfig, bool, error) {
+	var row writeConfigRow
+	var ok bool
+	err := s.store.WithDbSession(ctx, func(sess *db.Session) error {
+		var err error
+		ok, err = sess.Where("org_id = ? AND uid = ?", orgID, cmd.UID).Get(&row)
+		return err
+	})
+	if err != nil {
+		return WriteConfig{}, false, fmt.Errorf("can't read write config: %w", err)
+	}
+	if !ok {
+		return WriteConfig{}, false, nil
+	}
+	c, err := writeConfigFromRow(row)
+	return c, err == nil, err
+}
+
+func (s *SQLStorage) CreateWriteConfig(ctx context.Context, orgID int64, cmd WriteConfigCreateCmd) (WriteConfig, error) {
+	if cmd.UID == "" {
+		cmd.UID = util.GenerateShortUID()
+	}
+	backend, row, err := s.writeConfigRow(ctx, orgID, cmd.UID, cmd.Settings, cmd.SecureSettings)
+	if err != nil {
+		return WriteConfig{}, err
+	}
+	err = s.update(ctx, orgID, func(sess *db.Session) error {
+		exists, err := sess.Where("org_id = ? AND uid = ?", orgID, backend.UID).Exist(&writeConfigRow{})
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("backend already exists in org: %s", backend.UID)
+		}
+		_, err = sess.Insert(&row)
+		return err
+	})
+	return backend, err
+}
+
+func (s *SQLStorage) UpdateWriteConfig(ctx context.Context, orgID int64, cmd WriteConfigUpdateCmd) (WriteConfig, error) {
+	backend, row, err := s.writeConfigRow(ctx, orgID, cmd.UID, cmd.Settings, cmd.SecureSettings)
+	if err != nil {
+		return WriteConfig{}, err
+	}
+	err = s.update(ctx, orgID, func(sess *db.Session) error {
+		exists, err := sess.Where("org_id = ? AND uid = ?", orgID, backend.UID).Exist(&writeConfigRow{})
+		if err != nil {
+			return err
+		}
+		if exists {
+			_, err = sess.Where("org_id = ? AND uid = ?", orgID, backend.UID).Cols("settings", "secure_settings", "updated").Update(&row)
+			return err
+		}
+		// Like the file storage, create the write config if it does not exist.
+		_, err = sess.Insert(&row)
+		return err
+	})
+	return backend, err
+}
+
+func (s *SQLStorage) DeleteWriteConfig(ctx context.Context, orgID int64, cmd WriteConfigDeleteCmd) error {
+	return s.update(ctx, orgID, func(sess *db.Session) error {
+		n, err := sess.Where("org_id = ? AND uid = ?", orgID, cmd.UID).Delete(&writeConfigRow{})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errors.New("write config not found")
+		}
+		return nil
+	})
+}
+
+func (s *SQLStorage) writeConfigRow(ctx context.Context, orgID int64, uid string, settings WriteSettings, secureSettings map[string]string) (WriteConfig, writeConfigRow, error) {
+	encrypted, err := s.secretsService.EncryptJsonData(ctx, secureSettings, secrets.WithoutScope())
+	if err != nil {
+		return WriteConfig{}, writeConfigRow{}, fmt.Errorf("error encrypting data: %w", err)
+	}
+	backend := WriteConfig{
+		OrgId:          orgID,
+		UID:            uid,
+		Settings:       settings,
+		SecureSettings: encrypted,
+	}
+	if ok, reason := backend.Valid(); !ok {
+		return WriteConfig{}, writeConfigRow{}, fmt.Errorf("invalid write config: %s", reason)
+	}
+	settingsJSON, err := json.Marshal(backend.Settings)
+	if err != nil {
+		return WriteConfig{}, writeConfigRow{}, err
+	}
+	secureJSON, err := json.Marshal(backend.SecureSettings)
+	if err != nil {
+		return WriteConfig{}, writeConfigRow{}, err
+	}
+	return backend, writeConfigRow{
+		OrgID:          orgID,
+		UID:            uid,
+		Settings:       string(settingsJSON),
+		SecureSettings: string(secureJSON),
+		Updated:        time.Now().Unix(),
+	}, nil
+}
+
+func writeConfigFromRow(row writeConfigRow) (WriteConfig, error) {
+	c := WriteConfig{
+		OrgId: row.OrgID,
+		UID:   row.UID,
+	}
+	if err := json.Unmarshal([]byte(row.Settings), &c.Settings); err != nil {
+		return WriteConfig{}, fmt.Errorf("can't unmarshal write config %s settings: %w", row.UID, err)
+	}
+	if row.SecureSettings != "" {
+		if err := json.Unmarshal([]byte(row.SecureSettings), &c.SecureSettings); err != nil {
+			return WriteConfig{}, fmt.Errorf("can't unmarshal write config %s secure settings: %w", row.UID, err)
+		}
+	}
+	return c, nil
+}
+
+func (s *SQLStorage) ListChannelRules(ctx context.Context, orgID int64) ([]ChannelRule, error) {
+	var rules []ChannelRule
+	err := s.store.WithDbSession(ctx, func(sess *db.Session) error {
+		var err error
+		rules, err = listChannelRules(sess, orgID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't read channel rules: %w", err)
+	}
+	return rules, nil
+}
+
+func listChannelRules(sess *db.Session, orgID int64) ([]ChannelRule, error) {
+	var rows []channelRuleRow
+	if err := sess.Where("org_id = ?", orgID).Asc("pattern").Find(&rows); err != nil {
+		return nil, err
+	}
+	rules := make([]ChannelRule, 0, len(rows))
+	for _, r := range rows {
+		rule := ChannelRule{
+			OrgId:   r.OrgID,
+			Pattern: r.Pattern,
+		}
+		if err := json.Unmarshal([]byte(r.Settings), &rule.Settings); err != nil {
+			return nil, fmt.Errorf("can't unmarshal channel rule %s settings: %w", r.Pattern, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (s *SQLStorage) CreateChannelRule(ctx context.Context, orgID int64, cmd ChannelRuleCreateCmd) (ChannelRule, error) {
+	rule, row, err := channelRuleRowFromCmd(orgID, cmd.Pattern, cmd.Settings)
+	if err != nil {
+		return rule, err
+	}
+	err = s.update(ctx, orgID, func(sess *db.Session) error {
+		exists, err := sess.Where("org_id = ? AND pattern = ?", orgID, rule.Pattern).Exist(&channelRuleRow{})
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("pattern already exists in org: %s", rule.Pattern)
+		}
+		if _, err := sess.Insert(&row); err != nil {
+			return err
+		}
+		return checkStoredRulesValid(sess, orgID)
+	})
+	return rule, err
+}
+
+func (s *SQLStorage) UpdateChannelRule(ctx context.Context, orgID int64, cmd ChannelRuleUpdateCmd) (ChannelRule, error) {
+	rule, row, err := channelRuleRowFromCmd(orgID, cmd.Pattern, cmd.Settings)
+	if err != nil {
+		return rule, err
+	}
+	err = s.update(ctx, orgID, func(sess *db.Session) error {
+		exists, err := sess.Where("org_id = ? AND pattern = ?", orgID, rule.Pattern).Exist(&channelRuleRow{})
+		if err != nil {
+			return err
+		}
+		if exists {
+			_, err = sess.Where("org_id = ? AND pattern = ?", orgID, rule.Pattern).Cols("settings", "updated").Update(&row)
+		} else {
+			// Like the file storage, create the rule if it does not exist.
+			_, err = sess.Insert(&row)
+		}
+		if err != nil {
+			return err
+		}
+		return checkStoredRulesValid(sess, orgID)
+	})
+	return rule, err
+}
+
+func (s *SQLStorage) DeleteChannelRule(ctx context.Context, orgID int64, cmd ChannelRuleDeleteCmd) error {
+	return s.update(ctx, orgID, func(sess *db.Session) error {
+		n, err := sess.Where("org_id = ? AND pattern = ?", orgID, cmd.Pattern).Delete(&channelRuleRow{})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errors.New("rule not found")
+		}
+		return nil
+	})
+}
+
+func channelRuleRowFromCmd(orgID int64, pattern string, settings ChannelRuleSettings) (ChannelRule, channelRuleRow, error) {
+	rule := ChannelRule{
+		OrgId:    orgID,
+		Pattern:  pattern,
+		Settings: settings,
+	}
+	if ok, reason := rule.Valid(); !ok {
+		return rule, channelRuleRow{}, fmt.Errorf("invalid channel rule: %s", reason)
+	}
+	settingsJSON, err := json.Marshal(rule.Settings)
+	if err != nil {
+		return rule, channelRuleRow{}, err
+	}
+	return rule, channelRuleRow{
+		OrgID:    orgID,
+		Pattern:  pattern,
+		Settings: string(settingsJSON),
+		Updated:  time.Now().Unix(),
+	}, nil
+}
+
+// checkStoredRulesValid checks that the rules of the organization, including the ones changed in the
+// current transaction, can be added to the same rule tree.
+func checkStoredRulesValid(sess *db.Session, orgID int64) error {
+	rules, err := listChannelRules(sess, orgID)
+	if err != nil {
+		return err
+	}
+	if ok, reason := checkRulesValid(orgID, rules); !ok {
+		return errors.New(reason)
+	}
+	return nil
+}
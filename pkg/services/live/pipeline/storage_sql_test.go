@@ -0,0 +1,204 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/secrets/fakes"
+	"github.com/grafana/grafana/pkg/tests/testsuite"
+)
+
+func TestMain(m *testing.M) {
+	testsuite.Run(m)
+}
+
+func TestIntegrationSQLStorage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	store := db.InitTestDB(t)
+	ctx := context.Background()
+	// Two storages sharing a database act as two Grafana instances.
+	s1 := NewSQLStorage(store, fakes.NewFakeSecretsService())
+	s2 := NewSQLStorage(store, fakes.NewFakeSecretsService())
+
+	var local, remote []int64
+	s1.Subscribe(func(orgID int64) { local = append(local, orgID) })
+	s2.Subscribe(func(orgID int64) { remote = append(remote, orgID) })
+	require.NoError(t, s1.poll(ctx))
+	require.NoError(t, s2.poll(ctx))
+
+	t.Run("channel rules", func(t *testing.T) {
+		_, err := s1.CreateChannelRule(ctx, 1, ChannelRuleCreateCmd{
+			Pattern:  "stream/telegraf/:metric",
+			Settings: ChannelRuleSettings{Converter: &ConverterConfig{Type: ConverterTypeInfluxAuto}},
+		})
+		require.NoError(t, err)
+		_, err = s1.CreateChannelRule(ctx, 1, ChannelRuleCreateCmd{Pattern: "stream/telegraf/:metric"})
+		require.ErrorContains(t, err, "pattern already exists")
+		_, err = s1.CreateChannelRule(ctx, 1, ChannelRuleCreateCmd{Pattern: "stream/telegraf/:other"})
+		require.Error(t, err, "conflicting patterns should not be saved")
+
+		_, err = s1.UpdateChannelRule(ctx, 1, ChannelRuleUpdateCmd{Pattern: "stream/telegraf/:metric"})
+		require.NoError(t, err)
+		_, err = s1.UpdateChannelRule(ctx, 2, ChannelRuleUpdateCmd{Pattern: "stream/telegraf/:metric"})
+		require.NoError(t, err, "updating a missing rule should create it")
+
+		rules, err := s2.ListChannelRules(ctx, 1)
+		require.NoError(t, err)
+		require.Equal(t, []ChannelRule{{OrgId: 1, Pattern: "stream/telegraf/:metric"}}, rules)
+
+		require.NoError(t, s1.DeleteChannelRule(ctx, 2, ChannelRuleDeleteCmd{Pattern: "stream/telegraf/:metric"}))
+		require.ErrorContains(t, s1.DeleteChannelRule(ctx, 2, ChannelRuleDeleteCmd{Pattern: "stream/telegraf/:metric"}), "rule not found")
+		rules, err = s1.ListChannelRules(ctx, 2)
+		require.NoError(t, err)
+		require.Empty(t, rules)
+	})
+
+	t.Run("write configs", func(t *testing.T) {
+		created, err := s1.CreateWriteConfig(ctx, 1, WriteConfigCreateCmd{
+			Settings:       WriteSettings{Endpoint: "http://localhost:9090/api/v1/write"},
+			SecureSettings: map[string]string{"basicAuthPassword": "secret"},
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, created.UID)
+
+		got, ok, err := s2.GetWriteConfig(ctx, 1, WriteConfigGetCmd{UID: created.UID})
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, created, got)
+
+		_, ok, err = s2.GetWriteConfig(ctx, 2, WriteConfigGetCmd{UID: created.UID})
+		require.NoError(t, err)
+		require.False(t, ok, "write configs should not be shared between organizations")
+
+		_, err = s1.UpdateWriteConfig(ctx, 1, WriteConfigUpdateCmd{
+			UID:      created.UID,
+			Settings: WriteSettings{Endpoint: "http://remote:9090/api/v1/write"},
+		})
+		require.NoError(t, err)
+		configs, err := s2.ListWriteConfigs(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, configs, 1)
+		require.Equal(t, "http://remote:9090/api/v1/write", configs[0].Settings.Endpoint)
+
+		require.NoError(t, s1.DeleteWriteConfig(ctx, 1, WriteConfigDeleteCmd{UID: created.UID}))
+		require.ErrorContains(t, s1.DeleteWriteConfig(ctx, 1, WriteConfigDeleteCmd{UID: created.UID}), "write config not found")
+	})
+
+	t.Run("notifies subscribers of changes", func(t *testing.T) {
+		require.Equal(t, []int64{1, 1, 2, 2, 1, 1, 1}, local, "only successful changes should be notified")
+
+		require.Empty(t, remote)
+		require.NoError(t, s2.poll(ctx))
+		require.ElementsMatch(t, []int64{1, 2}, remote)
+
+		remote = nil
+		require.NoError(t, s2.poll(ctx))
+		require.Empty(t, remote, "unchanged organizations should not be notified")
+
+		// Changes made by an instance are not notified again when it polls.
+		local = nil
+		require.NoError(t, s1.poll(ctx))
+		require.Empty(t, local)
+	})
+}
+
+func TestIntegrationSQLStorage_ConcurrentFirstWrites(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	store := db.InitTestDB(t)
+	ctx := context.Background()
+
+	const writers = 4
+	var wg sync.WaitGroup
+	errs := make([]error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s := NewSQLStorage(store, fakes.NewFakeSecretsService())
+			_, errs[i] = s.CreateChannelRule(ctx, 1, ChannelRuleCreateCmd{Pattern: fmt.Sprintf("stream/test/%d", i)})
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err, "the first writes of an organization should not conflict on its version")
+	}
+
+	var row pipelineVersionRow
+	err := store.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.ID(int64(1)).Get(&row)
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(writers), row.Version)
+}
+
+type countingBuilder struct {
+	builds map[int64]int
+}
+
+func (b *countingBuilder) BuildRules(_ context.Context, orgID int64) ([]*LiveChannelRule, error) {
+	b.builds[orgID]++
+	return nil, nil
+}
+
+func TestCacheSegmentedTree_Reload(t *testing.T) {
+	b := &countingBuilder{builds: map[int64]int{}}
+	s := NewCacheSegmentedTree(b)
+
+	s.Reload(1)
+	require.Zero(t, b.builds[1], "organizations that were never used should not be built")
+
+	_, _, err := s.Get(1, "stream/test")
+	require.NoError(t, err)
+	require.Equal(t, 1, b.builds[1])
+
+	s.Reload(1)
+	require.Equal(t, 2, b.builds[1])
+}
+
+func TestIntegrationSQLStorage_ReloadsRules(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	store := db.InitTestDB(t)
+	ctx := context.Background()
+	newInstance := func() (*SQLStorage, *CacheSegmentedTree) {
+		s := NewSQLStorage(store, fakes.NewFakeSecretsService())
+		tree := NewCacheSegmentedTree(&StorageRuleBuilder{Storage: s})
+		s.Subscribe(tree.Reload)
+		require.NoError(t, s.poll(ctx))
+		return s, tree
+	}
+	s1, tree1 := newInstance()
+	s2, tree2 := newInstance()
+
+	for _, tree := range []*CacheSegmentedTree{tree1, tree2} {
+		_, ok, err := tree.Get(1, "stream/test")
+		require.NoError(t, err)
+		require.False(t, ok)
+	}
+
+	_, err := s1.CreateChannelRule(ctx, 1, ChannelRuleCreateCmd{Pattern: "stream/test"})
+	require.NoError(t, err)
+
+	_, ok, err := tree1.Get(1, "stream/test")
+	require.NoError(t, err)
+	require.True(t, ok, "the rule tree of the instance making the change should be reloaded")
+
+	require.NoError(t, s2.poll(ctx))
+	_, ok, err = tree2.Get(1, "stream/test")
+	require.NoError(t, err)
+	require.True(t, ok, "the rule tree of other instances should be reloaded when they poll")
+}
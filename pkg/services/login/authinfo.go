@@ -31,6 +31,7 @@ const (
 	SAMLAuthModule         = "auth.saml"
 	LDAPAuthModule         = "ldap"
 	AuthProxyAuthModule    = "authproxy"
+	MTLSAuthModule         = "mtls"
 	JWTModule              = "jwt"
 	ExtendedJWTModule      = "extendedjwt"
 	RenderModule           = "render"
@@ -48,6 +49,7 @@ const (
 	SAMLLabel = "SAML"
 	LDAPLabel = "LDAP"
 	JWTLabel  = "JWT"
+	MTLSLabel = "mTLS"
 	// OAuth provider labels
 	AuthProxyLabel    = "Auth Proxy"
 	AzureADLabel      = "AzureAD"
@@ -78,6 +80,8 @@ func IsExternallySynced(cfg *setting.Cfg, authModule string, oauthInfo *social.O
 		return !cfg.LDAPSkipOrgRoleSync
 	case JWTModule:
 		return !cfg.JWTAuth.SkipOrgRoleSync
+	case MTLSAuthModule:
+		return !cfg.MTLSAuth.SkipOrgRoleSync
 	}
 	switch authModule {
 	case GoogleAuthModule, OktaAuthModule, AzureADAuthModule, GitLabAuthModule, GithubAuthModule, GrafanaComAuthModule, GenericOAuthModule:
@@ -100,6 +104,8 @@ func IsGrafanaAdminExternallySynced(cfg *setting.Cfg, oauthInfo *social.OAuthInf
 	switch authModule {
 	case JWTModule:
 		return cfg.JWTAuth.AllowAssignGrafanaAdmin
+	case MTLSAuthModule:
+		return cfg.MTLSAuth.AllowAssignGrafanaAdmin
 	case SAMLAuthModule:
 		return cfg.SAMLRoleValuesGrafanaAdmin != ""
 	case LDAPAuthModule:
@@ -117,6 +123,8 @@ func IsProviderEnabled(cfg *setting.Cfg, authModule string, oauthInfo *social.OA
 		return cfg.LDAPAuthEnabled
 	case JWTModule:
 		return cfg.JWTAuth.Enabled
+	case MTLSAuthModule:
+		return cfg.MTLSAuth.Enabled
 	case GoogleAuthModule, OktaAuthModule, AzureADAuthModule, GitLabAuthModule, GithubAuthModule, GrafanaComAuthModule, GenericOAuthModule:
 		if oauthInfo == nil {
 			return false
@@ -149,6 +157,8 @@ func GetAuthProviderLabel(authModule string) string {
 		return JWTLabel
 	case AuthProxyAuthModule:
 		return AuthProxyLabel
+	case MTLSAuthModule:
+		return MTLSLabel
 	case GenericOAuthModule:
 		return GenericOAuthLabel
 	default:
@@ -2,14 +2,19 @@ package loginattempt
 
 import (
 	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/infra/log"
 )
 
 type Service interface {
 	// Add adds a new login attempt record for provided username
 	Add(ctx context.Context, username, IPAddress string) error
-	// Validate checks if username has to many login attempts inside a window.
-	// Will return true if provided username do not have too many attempts.
-	Validate(ctx context.Context, username string) (bool, error)
+	// Validate checks if username, or the IP address, has too many login attempts inside a window,
+	// or is locked. Will return true if the login attempt can be performed.
+	Validate(ctx context.Context, username, IPAddress string) (bool, error)
 	// Reset resets all login attempts attached to username
 	Reset(ctx context.Context, username string) error
 }
@@ -20,3 +25,74 @@ type LoginAttempt struct {
 	IpAddress string
 	Created   int64
 }
+
+// RemoteAddr returns the IP address of the client of a request. Any client can set the X-Real-IP and
+// X-Forwarded-For headers, they are only read when the peer is one of the trusted proxies.
+func RemoteAddr(r *http.Request, trustedProxies []*net.IPNet) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !ContainsIP(trustedProxies, host) {
+		return host
+	}
+
+	if realIP := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); realIP != nil {
+		return realIP.String()
+	}
+	// Each proxy appends its peer, the client is the last address not added by a trusted proxy.
+	addr := host
+	forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if ip == nil {
+			break
+		}
+		addr = ip.String()
+		if !ContainsIP(trustedProxies, addr) {
+			break
+		}
+	}
+	return addr
+}
+
+// ParseNetworks parses a list of IP addresses and networks in CIDR notation. Invalid entries are ignored.
+func ParseNetworks(entries []string, logger log.Logger) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				logger.Warn("Ignoring invalid IP address", "entry", entry)
+				continue
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			logger.Warn("Ignoring invalid network", "entry", entry, "error", err)
+			continue
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// ContainsIP returns true if the IP address is in one of the networks.
+func ContainsIP(networks []*net.IPNet, addr string) bool {
+	ip := net.ParseIP(strings.Trim(addr, "[]"))
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package loginattempt
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+func TestRemoteAddr(t *testing.T) {
+	trustedProxies := ParseNetworks([]string{"10.0.0.1", "10.1.0.0/16"}, log.NewNopLogger())
+
+	tests := []struct {
+		desc       string
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		{
+			desc:       "should use the peer without forwarded headers",
+			remoteAddr: "192.0.2.1:1234",
+			expected:   "192.0.2.1",
+		},
+		{
+			desc:       "should ignore forwarded headers of untrusted peers",
+			remoteAddr: "192.0.2.1:1234",
+			headers:    map[string]string{"X-Real-IP": "10.2.0.1", "X-Forwarded-For": "10.2.0.2"},
+			expected:   "192.0.2.1",
+		},
+		{
+			desc:       "should use X-Real-IP of trusted proxies",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Real-IP": "198.51.100.1", "X-Forwarded-For": "198.51.100.2"},
+			expected:   "198.51.100.1",
+		},
+		{
+			desc:       "should use the last address not added by a trusted proxy",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.1, 198.51.100.1, 10.1.2.3"},
+			expected:   "198.51.100.1",
+		},
+		{
+			desc:       "should stop at invalid forwarded addresses",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1, not-an-ip"},
+			expected:   "10.0.0.1",
+		},
+		{
+			desc:       "should strip the brackets of IPv6 peers",
+			remoteAddr: "[2001:db8::1]:1234",
+			expected:   "2001:db8::1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/login", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			assert.Equal(t, tt.expected, RemoteAddr(r, trustedProxies))
+		})
+	}
+}
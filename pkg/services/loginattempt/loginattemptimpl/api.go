@@ -0,0 +1,60 @@
+package loginattemptimpl
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+)
+
+type LockoutDTO struct {
+	Kind        LockoutKind `json:"kind"`
+	Value       string      `json:"value"`
+	Lockouts    int         `json:"lockouts"`
+	LockedUntil time.Time   `json:"lockedUntil"`
+}
+
+func (s *Service) registerAPIEndpoints(routeRegister routing.RouteRegister) {
+	routeRegister.Group("/api/admin/login-lockouts", func(r routing.RouteRegister) {
+		r.Get("/", routing.Wrap(s.getLockoutsHandler))
+		r.Delete("/", routing.Wrap(s.deleteLockoutHandler))
+	}, middleware.ReqGrafanaAdmin)
+}
+
+func (s *Service) getLockoutsHandler(c *contextmodel.ReqContext) response.Response {
+	lockouts, err := s.store.GetActiveLockouts(c.Req.Context(), GetActiveLockoutsQuery{Now: s.now()})
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get login lockouts", err)
+	}
+
+	result := make([]LockoutDTO, 0, len(lockouts))
+	for _, l := range lockouts {
+		result = append(result, LockoutDTO{
+			Kind:        l.Kind,
+			Value:       l.Value,
+			Lockouts:    l.Lockouts,
+			LockedUntil: time.Unix(l.LockedUntil, 0),
+		})
+	}
+	return response.JSON(http.StatusOK, result)
+}
+
+func (s *Service) deleteLockoutHandler(c *contextmodel.ReqContext) response.Response {
+	username, ip := c.Query("username"), c.Query("ip")
+	if (username == "") == (ip == "") {
+		return response.Error(http.StatusBadRequest, "Either username or ip is required", nil)
+	}
+
+	kind, value := LockoutKindUsername, username
+	if ip != "" {
+		kind, value = LockoutKindIP, ip
+	}
+	if err := s.Unlock(c.Req.Context(), kind, value); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to unlock", err)
+	}
+	s.logger.FromContext(c.Req.Context()).Info("Login lockout removed", "kind", kind, "value", value, "by", c.SignedInUser.GetID())
+	return response.Success("Login lockout removed")
+}
@@ -2,31 +2,43 @@ package loginattemptimpl
 
 import (
 	"context"
+	"net"
 	"strings"
 	"time"
 
+	"github.com/grafana/grafana/pkg/api/routing"
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/serverlock"
+	"github.com/grafana/grafana/pkg/services/loginattempt"
 	"github.com/grafana/grafana/pkg/setting"
 )
 
 const loginAttemptsWindow = time.Minute * 5
 
-func ProvideService(db db.DB, cfg *setting.Cfg, lock *serverlock.ServerLockService) *Service {
-	return &Service{
-		&xormStore{db: db, now: time.Now},
-		cfg,
-		lock,
-		log.New("login_attempt"),
+func ProvideService(db db.DB, cfg *setting.Cfg, lock *serverlock.ServerLockService, routeRegister routing.RouteRegister) *Service {
+	logger := log.New("login_attempt")
+	s := &Service{
+		store:     &xormStore{db: db, now: time.Now},
+		cfg:       cfg,
+		lock:      lock,
+		logger:    logger,
+		allowlist: loginattempt.ParseNetworks(cfg.BruteForceLoginProtectionIPAllowlist, logger),
+		now:       time.Now,
 	}
+	if routeRegister != nil {
+		s.registerAPIEndpoints(routeRegister)
+	}
+	return s
 }
 
 type Service struct {
-	store  store
-	cfg    *setting.Cfg
-	lock   *serverlock.ServerLockService
-	logger log.Logger
+	store     store
+	cfg       *setting.Cfg
+	lock      *serverlock.ServerLockService
+	logger    log.Logger
+	allowlist []*net.IPNet
+	now       func() time.Time
 }
 
 func (s *Service) Run(ctx context.Context) error {
@@ -51,25 +63,62 @@ func (s *Service) Add(ctx context.Context, username, IPAddress string) error {
 		return nil
 	}
 
+	// Attempts from trusted networks don't lock their users.
+	if loginattempt.ContainsIP(s.allowlist, IPAddress) {
+		return nil
+	}
+
+	username = strings.ToLower(username)
 	_, err := s.store.CreateLoginAttempt(ctx, CreateLoginAttemptCommand{
-		Username:  strings.ToLower(username),
+		Username:  username,
 		IpAddress: IPAddress,
 	})
-	return err
+	if err != nil {
+		return err
+	}
+
+	since := s.now().Add(-loginAttemptsWindow)
+	count, err := s.store.GetUserLoginAttemptCount(ctx, GetUserLoginAttemptCountQuery{Username: username, Since: since})
+	if err != nil {
+		return err
+	}
+	if count >= s.cfg.BruteForceLoginProtectionMaxAttempts {
+		if err := s.lockout(ctx, LockoutKindUsername, username); err != nil {
+			return err
+		}
+	}
+
+	if s.cfg.BruteForceLoginProtectionIPMaxAttempts <= 0 || IPAddress == "" {
+		return nil
+	}
+	count, err = s.store.GetIPLoginAttemptCount(ctx, GetIPLoginAttemptCountQuery{IPAddress: IPAddress, Since: since})
+	if err != nil {
+		return err
+	}
+	if count >= s.cfg.BruteForceLoginProtectionIPMaxAttempts {
+		return s.lockout(ctx, LockoutKindIP, IPAddress)
+	}
+	return nil
 }
 
 func (s *Service) Reset(ctx context.Context, username string) error {
-	return s.store.DeleteLoginAttempts(ctx, DeleteLoginAttemptsCommand{strings.ToLower(username)})
+	return s.store.DeleteLoginAttempts(ctx, DeleteLoginAttemptsCommand{Username: strings.ToLower(username)})
 }
 
-func (s *Service) Validate(ctx context.Context, username string) (bool, error) {
+func (s *Service) Validate(ctx context.Context, username, IPAddress string) (bool, error) {
 	if s.cfg.DisableBruteForceLoginProtection {
 		return true, nil
 	}
 
+	if loginattempt.ContainsIP(s.allowlist, IPAddress) {
+		return true, nil
+	}
+
+	username = strings.ToLower(username)
+	since := s.now().Add(-loginAttemptsWindow)
 	loginAttemptCountQuery := GetUserLoginAttemptCountQuery{
-		Username: strings.ToLower(username),
-		Since:    time.Now().Add(-loginAttemptsWindow),
+		Username: username,
+		Since:    since,
 	}
 
 	count, err := s.store.GetUserLoginAttemptCount(ctx, loginAttemptCountQuery)
@@ -81,7 +130,77 @@ func (s *Service) Validate(ctx context.Context, username string) (bool, error) {
 		return false, nil
 	}
 
-	return true, nil
+	if s.cfg.BruteForceLoginProtectionIPMaxAttempts > 0 && IPAddress != "" {
+		count, err := s.store.GetIPLoginAttemptCount(ctx, GetIPLoginAttemptCountQuery{IPAddress: IPAddress, Since: since})
+		if err != nil {
+			return false, err
+		}
+		if count >= s.cfg.BruteForceLoginProtectionIPMaxAttempts {
+			return false, nil
+		}
+	}
+
+	lockouts, err := s.store.GetActiveLockouts(ctx, GetActiveLockoutsQuery{
+		Username:  username,
+		IPAddress: IPAddress,
+		Now:       s.now(),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return len(lockouts) == 0, nil
+}
+
+// Unlock removes the lockout and the login attempts of a username or an IP address.
+func (s *Service) Unlock(ctx context.Context, kind LockoutKind, value string) error {
+	cmd := DeleteLoginAttemptsCommand{IPAddress: value}
+	if kind == LockoutKindUsername {
+		value = strings.ToLower(value)
+		cmd = DeleteLoginAttemptsCommand{Username: value}
+	}
+	if err := s.store.DeleteLoginAttempts(ctx, cmd); err != nil {
+		return err
+	}
+	return s.store.DeleteLockout(ctx, DeleteLockoutCommand{Kind: kind, Value: value})
+}
+
+// lockout locks a username or an IP address. The lockout duration doubles for each consecutive lockout,
+// and consecutive lockouts are forgotten after the max lockout duration without lockout.
+func (s *Service) lockout(ctx context.Context, kind LockoutKind, value string) error {
+	now := s.now()
+	lockout, err := s.store.GetLockout(ctx, kind, value)
+	if err != nil {
+		return err
+	}
+	if lockout == nil {
+		lockout = &Lockout{Kind: kind, Value: value}
+	}
+
+	lockedUntil := time.Unix(lockout.LockedUntil, 0)
+	if now.Before(lockedUntil) {
+		// Already locked.
+		return nil
+	}
+	if now.After(lockedUntil.Add(s.cfg.BruteForceLoginProtectionMaxLockoutDuration)) {
+		lockout.Lockouts = 0
+	}
+
+	lockout.Lockouts++
+	lockout.LockedUntil = now.Add(s.lockoutDuration(lockout.Lockouts)).Unix()
+	s.logger.FromContext(ctx).Warn("Too many failed login attempts, locking", "kind", kind, "value", value, "lockouts", lockout.Lockouts, "lockedUntil", time.Unix(lockout.LockedUntil, 0))
+	return s.store.SaveLockout(ctx, lockout)
+}
+
+func (s *Service) lockoutDuration(lockouts int) time.Duration {
+	duration := s.cfg.BruteForceLoginProtectionLockoutDuration
+	for i := 1; i < lockouts && duration < s.cfg.BruteForceLoginProtectionMaxLockoutDuration; i++ {
+		duration *= 2
+	}
+	if duration > s.cfg.BruteForceLoginProtectionMaxLockoutDuration {
+		return s.cfg.BruteForceLoginProtectionMaxLockoutDuration
+	}
+	return duration
 }
 
 func (s *Service) cleanup(ctx context.Context) {
@@ -94,6 +213,16 @@ func (s *Service) cleanup(ctx context.Context) {
 		} else {
 			s.logger.Debug("Deleted expired login attempts", "rows affected", deletedLogs)
 		}
+
+		// Lockouts are kept until consecutive lockouts are forgotten.
+		expired := DeleteExpiredLockoutsCommand{
+			ExpiredBefore: time.Now().Add(-s.cfg.BruteForceLoginProtectionMaxLockoutDuration),
+		}
+		if deleted, err := s.store.DeleteExpiredLockouts(ctx, expired); err != nil {
+			s.logger.Error("Problem deleting expired login lockouts", "error", err.Error())
+		} else {
+			s.logger.Debug("Deleted expired login lockouts", "rows affected", deleted)
+		}
 	})
 
 	if err != nil {
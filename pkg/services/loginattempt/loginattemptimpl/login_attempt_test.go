@@ -2,11 +2,17 @@ package loginattemptimpl
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/loginattempt"
 	"github.com/grafana/grafana/pkg/setting"
 )
@@ -74,9 +80,10 @@ func TestService_Validate(t *testing.T) {
 					ExpectedErr:   tt.expectedErr,
 				},
 				cfg: cfg,
+				now: time.Now,
 			}
 
-			ok, err := service.Validate(context.Background(), "test")
+			ok, err := service.Validate(context.Background(), "test", "10.0.0.1")
 			assert.Equal(t, tt.expected, ok)
 			assert.Equal(t, tt.expectedErr, err)
 		})
@@ -89,7 +96,7 @@ func TestLoginAttempts(t *testing.T) {
 	cfg.DisableBruteForceLoginProtection = false
 	cfg.BruteForceLoginProtectionMaxAttempts = 5
 	db := db.InitTestDB(t)
-	service := ProvideService(db, cfg, nil)
+	service := ProvideService(db, cfg, nil, nil)
 
 	// add multiple login attempts with different uppercases, they all should be counted as the same user
 	_ = service.Add(ctx, "admin", "[::1]")
@@ -104,11 +111,148 @@ func TestLoginAttempts(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, int64(6), count)
 
-	ok, err := service.Validate(ctx, "admin")
+	ok, err := service.Validate(ctx, "admin", "[::1]")
 	assert.False(t, ok)
 	assert.Nil(t, err)
 }
 
+func TestLoginAttempts_IPAddress(t *testing.T) {
+	ctx := context.Background()
+	cfg := setting.NewCfg()
+	cfg.BruteForceLoginProtectionMaxAttempts = 5
+	cfg.BruteForceLoginProtectionIPMaxAttempts = 3
+	cfg.BruteForceLoginProtectionLockoutDuration = 5 * time.Minute
+	cfg.BruteForceLoginProtectionMaxLockoutDuration = time.Hour
+	cfg.BruteForceLoginProtectionIPAllowlist = []string{"10.0.0.0/8", "192.168.1.10"}
+	service := ProvideService(db.InitTestDB(t), cfg, nil, nil)
+
+	// attempts against different users from the same address lock the address
+	for _, username := range []string{"admin", "root", "grafana"} {
+		require.NoError(t, service.Add(ctx, username, "172.16.0.1"))
+	}
+
+	ok, err := service.Validate(ctx, "viewer", "172.16.0.1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// the users aren't locked from other addresses
+	ok, err = service.Validate(ctx, "admin", "172.16.0.2")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	lockouts, err := service.store.GetActiveLockouts(ctx, GetActiveLockoutsQuery{Now: time.Now()})
+	require.NoError(t, err)
+	require.Len(t, lockouts, 1)
+	assert.Equal(t, LockoutKindIP, lockouts[0].Kind)
+	assert.Equal(t, "172.16.0.1", lockouts[0].Value)
+
+	require.NoError(t, service.Unlock(ctx, LockoutKindIP, "172.16.0.1"))
+	ok, err = service.Validate(ctx, "viewer", "172.16.0.1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// attempts from trusted networks are ignored
+	for i := 0; i < 10; i++ {
+		require.NoError(t, service.Add(ctx, "admin", "10.1.2.3"))
+		require.NoError(t, service.Add(ctx, "admin", "192.168.1.10"))
+	}
+	ok, err = service.Validate(ctx, "admin", "172.16.0.2")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestLoginAttempts_ForwardedHeaders(t *testing.T) {
+	ctx := context.Background()
+	cfg := setting.NewCfg()
+	cfg.BruteForceLoginProtectionMaxAttempts = 100
+	cfg.BruteForceLoginProtectionIPMaxAttempts = 3
+	cfg.BruteForceLoginProtectionLockoutDuration = 5 * time.Minute
+	cfg.BruteForceLoginProtectionMaxLockoutDuration = time.Hour
+	cfg.BruteForceLoginProtectionIPAllowlist = []string{"10.0.0.0/8"}
+	cfg.BruteForceLoginProtectionTrustedProxies = []string{"172.16.0.1"}
+	service := ProvideService(db.InitTestDB(t), cfg, nil, nil)
+	trustedProxies := loginattempt.ParseNetworks(cfg.BruteForceLoginProtectionTrustedProxies, log.NewNopLogger())
+
+	request := func(remoteAddr, forwardedFor string) string {
+		r := httptest.NewRequest(http.MethodPost, "/login", nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+		return loginattempt.RemoteAddr(r, trustedProxies)
+	}
+
+	// a client spoofing an allowlisted address, or a new address for each attempt, is still locked
+	for i, forwardedFor := range []string{"10.0.0.1", "198.51.100.1", "198.51.100.2"} {
+		addr := request(fmt.Sprintf("192.0.2.1:%d", 1000+i), forwardedFor)
+		require.Equal(t, "192.0.2.1", addr)
+		require.NoError(t, service.Add(ctx, "admin", addr))
+	}
+	ok, err := service.Validate(ctx, "viewer", request("192.0.2.1:2000", "10.0.0.1"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// the addresses forwarded by a trusted proxy are the clients
+	addr := request("172.16.0.1:1234", "10.0.0.1")
+	require.Equal(t, "10.0.0.1", addr)
+	ok, err = service.Validate(ctx, "viewer", addr)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestLoginAttempts_ProgressiveLockout(t *testing.T) {
+	ctx := context.Background()
+	cfg := setting.NewCfg()
+	cfg.BruteForceLoginProtectionMaxAttempts = 2
+	cfg.BruteForceLoginProtectionLockoutDuration = 5 * time.Minute
+	cfg.BruteForceLoginProtectionMaxLockoutDuration = 15 * time.Minute
+	service := ProvideService(db.InitTestDB(t), cfg, nil, nil)
+
+	now := time.Now().Truncate(time.Second)
+	service.now = func() time.Time { return now }
+	service.store.(*xormStore).now = service.now
+
+	lockedFor := func() time.Duration {
+		t.Helper()
+		lockout, err := service.store.GetLockout(ctx, LockoutKindUsername, "admin")
+		require.NoError(t, err)
+		require.NotNil(t, lockout)
+		return time.Unix(lockout.LockedUntil, 0).Sub(now)
+	}
+	fail := func() {
+		t.Helper()
+		require.NoError(t, service.Add(ctx, "admin", "172.16.0.1"))
+		require.NoError(t, service.Add(ctx, "Admin", "172.16.0.1"))
+	}
+
+	fail()
+	assert.Equal(t, 5*time.Minute, lockedFor())
+
+	// consecutive lockouts double the duration, up to the max
+	now = now.Add(6 * time.Minute)
+	ok, err := service.Validate(ctx, "admin", "172.16.0.1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	fail()
+	assert.Equal(t, 10*time.Minute, lockedFor())
+
+	now = now.Add(11 * time.Minute)
+	fail()
+	assert.Equal(t, 15*time.Minute, lockedFor())
+
+	// lockouts are forgotten after the max lockout duration without lockout
+	now = now.Add(31 * time.Minute)
+	fail()
+	assert.Equal(t, 5*time.Minute, lockedFor())
+
+	ok, err = service.Validate(ctx, "admin", "172.16.0.2")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, service.Unlock(ctx, LockoutKindUsername, "ADMIN"))
+	ok, err = service.Validate(ctx, "admin", "172.16.0.2")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
 var _ store = new(fakeStore)
 
 type fakeStore struct {
@@ -132,3 +276,27 @@ func (f fakeStore) DeleteOldLoginAttempts(ctx context.Context, command DeleteOld
 func (f fakeStore) DeleteLoginAttempts(ctx context.Context, cmd DeleteLoginAttemptsCommand) error {
 	return f.ExpectedErr
 }
+
+func (f fakeStore) GetIPLoginAttemptCount(ctx context.Context, query GetIPLoginAttemptCountQuery) (int64, error) {
+	return 0, f.ExpectedErr
+}
+
+func (f fakeStore) GetLockout(ctx context.Context, kind LockoutKind, value string) (*Lockout, error) {
+	return nil, f.ExpectedErr
+}
+
+func (f fakeStore) SaveLockout(ctx context.Context, lockout *Lockout) error {
+	return f.ExpectedErr
+}
+
+func (f fakeStore) GetActiveLockouts(ctx context.Context, query GetActiveLockoutsQuery) ([]*Lockout, error) {
+	return nil, f.ExpectedErr
+}
+
+func (f fakeStore) DeleteLockout(ctx context.Context, cmd DeleteLockoutCommand) error {
+	return f.ExpectedErr
+}
+
+func (f fakeStore) DeleteExpiredLockouts(ctx context.Context, cmd DeleteExpiredLockoutsCommand) (int64, error) {
+	return 0, f.ExpectedErr
+}
@@ -14,10 +14,56 @@ type GetUserLoginAttemptCountQuery struct {
 	Since    time.Time
 }
 
+type GetIPLoginAttemptCountQuery struct {
+	IPAddress string
+	Since     time.Time
+}
+
 type DeleteOldLoginAttemptsCommand struct {
 	OlderThan time.Time
 }
 
+// DeleteLoginAttemptsCommand deletes the login attempts of Username, or of IPAddress if set.
 type DeleteLoginAttemptsCommand struct {
-	Username string
+	Username  string
+	IPAddress string
+}
+
+type LockoutKind string
+
+const (
+	LockoutKindUsername LockoutKind = "username"
+	LockoutKindIP       LockoutKind = "ip"
+)
+
+// Lockout is the lockout of a username or an IP address after too many failed login attempts.
+type Lockout struct {
+	ID    int64       `xorm:"pk autoincr 'id'"`
+	Kind  LockoutKind `xorm:"kind"`
+	Value string      `xorm:"value"`
+	// Lockouts is the number of consecutive lockouts, doubling the lockout duration.
+	Lockouts    int   `xorm:"lockouts"`
+	LockedUntil int64 `xorm:"locked_until"`
+	Updated     int64 `xorm:"updated"`
+}
+
+func (Lockout) TableName() string {
+	return "login_lockout"
+}
+
+// GetActiveLockoutsQuery returns the lockouts of Username and IPAddress, or all the lockouts if both are
+// empty, that are active at Now.
+type GetActiveLockoutsQuery struct {
+	Username  string
+	IPAddress string
+	Now       time.Time
+}
+
+type DeleteLockoutCommand struct {
+	Kind  LockoutKind
+	Value string
+}
+
+type DeleteExpiredLockoutsCommand struct {
+	ExpiredBefore time.Time
 }
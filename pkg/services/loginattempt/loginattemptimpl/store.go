@@ -18,6 +18,13 @@ type store interface {
 	DeleteOldLoginAttempts(ctx context.Context, cmd DeleteOldLoginAttemptsCommand) (int64, error)
 	DeleteLoginAttempts(ctx context.Context, cmd DeleteLoginAttemptsCommand) error
 	GetUserLoginAttemptCount(ctx context.Context, query GetUserLoginAttemptCountQuery) (int64, error)
+	GetIPLoginAttemptCount(ctx context.Context, query GetIPLoginAttemptCountQuery) (int64, error)
+	// GetLockout returns the lockout of a username or IP address, or nil if there is none.
+	GetLockout(ctx context.Context, kind LockoutKind, value string) (*Lockout, error)
+	SaveLockout(ctx context.Context, lockout *Lockout) error
+	GetActiveLockouts(ctx context.Context, query GetActiveLockoutsQuery) ([]*Lockout, error)
+	DeleteLockout(ctx context.Context, cmd DeleteLockoutCommand) error
+	DeleteExpiredLockouts(ctx context.Context, cmd DeleteExpiredLockoutsCommand) (int64, error)
 }
 
 func (xs *xormStore) CreateLoginAttempt(ctx context.Context, cmd CreateLoginAttemptCommand) (result loginattempt.LoginAttempt, err error) {
@@ -58,6 +65,10 @@ func (xs *xormStore) DeleteOldLoginAttempts(ctx context.Context, cmd DeleteOldLo
 
 func (xs *xormStore) DeleteLoginAttempts(ctx context.Context, cmd DeleteLoginAttemptsCommand) error {
 	return xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		if cmd.IPAddress != "" {
+			_, err := sess.Exec("DELETE FROM login_attempt WHERE ip_address = ?", cmd.IPAddress)
+			return err
+		}
 		_, err := sess.Exec("DELETE FROM login_attempt WHERE username = ?", cmd.Username)
 		return err
 	})
@@ -82,3 +93,80 @@ func (xs *xormStore) GetUserLoginAttemptCount(ctx context.Context, query GetUser
 
 	return total, err
 }
+
+func (xs *xormStore) GetIPLoginAttemptCount(ctx context.Context, query GetIPLoginAttemptCountQuery) (int64, error) {
+	var total int64
+	err := xs.db.WithDbSession(ctx, func(dbSession *db.Session) error {
+		var queryErr error
+		total, queryErr = dbSession.
+			Where("ip_address = ?", query.IPAddress).
+			And("created >= ?", query.Since.Unix()).
+			Count(new(loginattempt.LoginAttempt))
+		return queryErr
+	})
+	return total, err
+}
+
+func (xs *xormStore) GetLockout(ctx context.Context, kind LockoutKind, value string) (*Lockout, error) {
+	var lockout Lockout
+	var exists bool
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		var err error
+		exists, err = sess.Where("kind = ? AND value = ?", kind, value).Get(&lockout)
+		return err
+	})
+	if err != nil || !exists {
+		return nil, err
+	}
+	return &lockout, nil
+}
+
+func (xs *xormStore) SaveLockout(ctx context.Context, lockout *Lockout) error {
+	return xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		lockout.Updated = xs.now().Unix()
+		if lockout.ID == 0 {
+			_, err := sess.Insert(lockout)
+			return err
+		}
+		_, err := sess.ID(lockout.ID).AllCols().Update(lockout)
+		return err
+	})
+}
+
+func (xs *xormStore) GetActiveLockouts(ctx context.Context, query GetActiveLockoutsQuery) ([]*Lockout, error) {
+	lockouts := make([]*Lockout, 0)
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		q := sess.Where("locked_until > ?", query.Now.Unix())
+		switch {
+		case query.Username != "" && query.IPAddress != "":
+			q = q.And("((kind = ? AND value = ?) OR (kind = ? AND value = ?))",
+				LockoutKindUsername, query.Username, LockoutKindIP, query.IPAddress)
+		case query.Username != "":
+			q = q.And("kind = ? AND value = ?", LockoutKindUsername, query.Username)
+		case query.IPAddress != "":
+			q = q.And("kind = ? AND value = ?", LockoutKindIP, query.IPAddress)
+		}
+		return q.OrderBy("locked_until DESC").Find(&lockouts)
+	})
+	return lockouts, err
+}
+
+func (xs *xormStore) DeleteLockout(ctx context.Context, cmd DeleteLockoutCommand) error {
+	return xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Exec("DELETE FROM login_lockout WHERE kind = ? AND value = ?", cmd.Kind, cmd.Value)
+		return err
+	})
+}
+
+func (xs *xormStore) DeleteExpiredLockouts(ctx context.Context, cmd DeleteExpiredLockoutsCommand) (int64, error) {
+	var deletedRows int64
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		result, err := sess.Exec("DELETE FROM login_lockout WHERE locked_until < ?", cmd.ExpiredBefore.Unix())
+		if err != nil {
+			return err
+		}
+		deletedRows, err = result.RowsAffected()
+		return err
+	})
+	return deletedRows, err
+}
@@ -444,7 +444,7 @@ func TestValidateRuleNode_NoUID(t *testing.T) {
 			limits: allowRecording(limits),
 			rule: func() *apimodels.PostableExtendedRuleNode {
 				r := validRule()
-				r.GrafanaManagedAlert.Record = &apimodels.Record{Metric: "some_metric", From: "A"}
+				r.GrafanaManagedAlert.Record = &apimodels.Record{Metric: "some_metric", From: "A", Target: "influxdb"}
 				r.GrafanaManagedAlert.Condition = ""
 				r.GrafanaManagedAlert.NoDataState = ""
 				r.GrafanaManagedAlert.ExecErrState = ""
@@ -477,6 +477,7 @@ func TestValidateRuleNode_NoUID(t *testing.T) {
 				// Recording fields
 				require.Equal(t, api.GrafanaManagedAlert.Record.From, alert.Record.From)
 				require.Equal(t, api.GrafanaManagedAlert.Record.Metric, alert.Record.Metric)
+				require.Equal(t, api.GrafanaManagedAlert.Record.Target, alert.Record.Target)
 			},
 		},
 		{
@@ -720,6 +721,21 @@ func TestValidateRuleNodeFailures_NoUID(t *testing.T) {
 			},
 			expErr: "NOTEXIST does not exist",
 		},
+		{
+			name:   "rejects recording rule with unknown target",
+			limits: allowRecording(limits),
+			rule: func() *apimodels.PostableExtendedRuleNode {
+				r := validRule()
+				r.GrafanaManagedAlert.Record = &apimodels.Record{Metric: "my_metric", From: "A", Target: "graphite"}
+				r.GrafanaManagedAlert.Condition = ""
+				r.GrafanaManagedAlert.NoDataState = ""
+				r.GrafanaManagedAlert.ExecErrState = ""
+				r.GrafanaManagedAlert.NotificationSettings = nil
+				r.ApiRuleNode.For = nil
+				return &r
+			},
+			expErr: "unknown recording rule target",
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -10,6 +10,7 @@ import (
 
 	"github.com/benbjohnson/clock"
 	amv2 "github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/common/model"
 
 	"github.com/grafana/alerting/models"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
@@ -20,11 +21,13 @@ import (
 	"github.com/grafana/grafana/pkg/apimachinery/identity"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/tracing"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
 	"github.com/grafana/grafana/pkg/services/dashboards"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/folder"
+	"github.com/grafana/grafana/pkg/services/ngalert/accesscontrol"
 	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
 	"github.com/grafana/grafana/pkg/services/ngalert/backtesting"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
@@ -44,6 +47,7 @@ type TestingApiSrv struct {
 	DatasourceCache datasources.CacheService
 	log             log.Logger
 	authz           RuleAccessControlService
+	accessControl   ac.AccessControl
 	evaluator       eval.EvaluatorFactory
 	cfg             *setting.UnifiedAlertingSettings
 	backtesting     *backtesting.Engine
@@ -234,6 +238,13 @@ func (srv TestingApiSrv) BacktestAlertRule(c *contextmodel.ReqContext, cmd apimo
 	if err != nil {
 		return ErrResp(400, err, "")
 	}
+	execErrState := ngmodels.AlertingErrState
+	if cmd.ExecErrState != "" {
+		execErrState, err = ngmodels.ErrStateFromString(string(cmd.ExecErrState))
+		if err != nil {
+			return ErrResp(400, err, "")
+		}
+	}
 	forInterval := time.Duration(cmd.For)
 	if forInterval < 0 {
 		return ErrResp(400, nil, "Bad For interval")
@@ -248,6 +259,21 @@ func (srv TestingApiSrv) BacktestAlertRule(c *contextmodel.ReqContext, cmd apimo
 	if err := srv.authz.AuthorizeDatasourceAccessForRule(c.Req.Context(), c.SignedInUser, &ngmodels.AlertRule{Data: queries}); err != nil {
 		return errorToResponse(err)
 	}
+	if cmd.Notifications {
+		// The notifications are routed through the notification policies of the organization, which the user must be
+		// able to read.
+		evaluator := ac.EvalAny(
+			ac.EvalPermission(ac.ActionAlertingNotificationsRead),
+			ac.EvalPermission(ac.ActionAlertingRoutesRead),
+		)
+		hasAccess, err := srv.accessControl.Evaluate(c.Req.Context(), c.SignedInUser, evaluator)
+		if err != nil {
+			return errorToResponse(err)
+		}
+		if !hasAccess {
+			return errorToResponse(accesscontrol.NewAuthorizationErrorWithPermissions("read the notification policies", evaluator))
+		}
+	}
 
 	rule := &ngmodels.AlertRule{
 		// ID:             0,
@@ -267,11 +293,28 @@ func (srv TestingApiSrv) BacktestAlertRule(c *contextmodel.ReqContext, cmd apimo
 		Data:            queries,
 		IntervalSeconds: intervalSeconds,
 		NoDataState:     noDataState,
+		ExecErrState:    execErrState,
 		For:             forInterval,
 		Annotations:     cmd.Annotations,
 		Labels:          cmd.Labels,
 	}
 
+	folderTitle := ""
+	if cmd.FolderUID != "" {
+		folder, err := srv.folderService.GetNamespaceByUID(c.Req.Context(), cmd.FolderUID, c.SignedInUser.GetOrgID(), c.SignedInUser)
+		if err != nil {
+			return toNamespaceErrorResponse(dashboards.ErrFolderAccessDenied)
+		}
+		rule.NamespaceUID = folder.UID
+		if !srv.cfg.ReservedLabels.IsReservedLabelDisabled(models.FolderTitleLabel) {
+			folderTitle = folder.Title
+		}
+	}
+
+	if cmd.Notifications {
+		return srv.backtestNotifications(c, rule, folderTitle, cmd.From, cmd.To)
+	}
+
 	result, err := srv.backtesting.Test(c.Req.Context(), c.SignedInUser, rule, cmd.From, cmd.To)
 	if err != nil {
 		if errors.Is(err, backtesting.ErrInvalidInputData) {
@@ -286,3 +329,49 @@ func (srv TestingApiSrv) BacktestAlertRule(c *contextmodel.ReqContext, cmd apimo
 	}
 	return response.JSON(http.StatusOK, body)
 }
+
+func (srv TestingApiSrv) backtestNotifications(c *contextmodel.ReqContext, rule *ngmodels.AlertRule, folderTitle string, from, to time.Time) response.Response {
+	result, err := srv.backtesting.TestNotifications(c.Req.Context(), c.SignedInUser, rule, folderTitle, from, to)
+	if err != nil {
+		if errors.Is(err, backtesting.ErrInvalidInputData) {
+			return ErrResp(400, err, "Failed to evaluate")
+		}
+		return ErrResp(500, err, "Failed to evaluate")
+	}
+
+	states, err := data.FrameToJSON(result.States, data.IncludeAll)
+	if err != nil {
+		return ErrResp(500, err, "Failed to convert frame to JSON")
+	}
+	body := apimodels.BacktestNotificationsResult{
+		States:        states,
+		Notifications: make([]apimodels.BacktestNotification, 0, len(result.Notifications)),
+	}
+	for _, n := range result.Notifications {
+		notification := apimodels.BacktestNotification{
+			Time:        n.Time,
+			Receiver:    n.Receiver,
+			GroupLabels: make(map[string]string, len(n.GroupLabels)),
+			Alerts:      make([]apimodels.BacktestNotifiedAlert, 0, len(n.Alerts)),
+		}
+		for k, v := range n.GroupLabels {
+			notification.GroupLabels[string(k)] = string(v)
+		}
+		for _, a := range n.Alerts {
+			alert := apimodels.BacktestNotifiedAlert{
+				Labels:   make(map[string]string, len(a.Labels)),
+				StartsAt: a.StartsAt,
+				Status:   string(model.AlertFiring),
+			}
+			if a.Resolved {
+				alert.Status = string(model.AlertResolved)
+			}
+			for k, v := range a.Labels {
+				alert.Labels[string(k)] = string(v)
+			}
+			notification.Alerts = append(notification.Alerts, alert)
+		}
+		body.Notifications = append(body.Notifications, notification)
+	}
+	return response.JSON(http.StatusOK, body)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
@@ -23,9 +24,11 @@ import (
 	"github.com/grafana/grafana/pkg/services/folder"
 	"github.com/grafana/grafana/pkg/services/ngalert/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/backtesting"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval/eval_mocks"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/notifier"
 	fakes2 "github.com/grafana/grafana/pkg/services/ngalert/tests/fakes"
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/web"
@@ -391,6 +394,57 @@ func TestRouteEvalQueries(t *testing.T) {
 	})
 }
 
+func TestBacktestAlertRuleNotifications(t *testing.T) {
+	rc := &contextmodel.ReqContext{
+		Context: &web.Context{
+			Req: &http.Request{},
+		},
+		SignedInUser: &user.SignedInUser{
+			OrgID: 1,
+		},
+	}
+	query := models.GenerateAlertQuery()
+	queryPermission := ac.Permission{Action: datasources.ActionQuery, Scope: datasources.ScopeProvider.GetResourceScopeUID(query.DatasourceUID)}
+
+	newSrv := func(t *testing.T, permissions ...ac.Permission) *TestingApiSrv {
+		srv := createTestingApiSrv(t, nil, acMock.New().WithPermissions(permissions), nil, featuremgmt.WithFeatures(featuremgmt.FlagAlertingBacktesting), nil)
+		// The Alertmanager is not running, so there are no notification policies to route the alerts with.
+		srv.backtesting = backtesting.NewEngine(nil, nil, tracing.InitializeTracerForTest(), (*notifier.MultiOrgAlertmanager)(nil))
+		return srv
+	}
+	cmd := func(srv *TestingApiSrv) definitions.BacktestConfig {
+		from := time.Now().Add(-time.Hour)
+		return definitions.BacktestConfig{
+			From:          from,
+			To:            from.Add(10 * srv.cfg.BaseInterval),
+			Interval:      model.Duration(srv.cfg.BaseInterval),
+			Condition:     query.RefID,
+			Data:          ApiAlertQueriesFromAlertQueries([]models.AlertQuery{query}),
+			Title:         "test",
+			NoDataState:   definitions.NoData,
+			Notifications: true,
+		}
+	}
+
+	t.Run("should return Forbidden if user cannot read the notification policies", func(t *testing.T) {
+		srv := newSrv(t, queryPermission)
+
+		response := srv.BacktestAlertRule(rc, cmd(srv))
+
+		require.Equal(t, http.StatusForbidden, response.Status())
+	})
+
+	for _, action := range []string{ac.ActionAlertingNotificationsRead, ac.ActionAlertingRoutesRead} {
+		t.Run("should route the alerts if user has "+action, func(t *testing.T) {
+			srv := newSrv(t, queryPermission, ac.Permission{Action: action})
+
+			response := srv.BacktestAlertRule(rc, cmd(srv))
+
+			require.Equal(t, http.StatusBadRequest, response.Status(), "the notification policies are not available")
+		})
+	}
+}
+
 func createTestingApiSrv(t *testing.T, ds *fakes.FakeCacheService, ac *acMock.Mock, evaluator eval.EvaluatorFactory, featureManager featuremgmt.FeatureToggles, ruleStore RuleStore) *TestingApiSrv {
 	if ac == nil {
 		ac = acMock.New()
@@ -399,6 +453,7 @@ func createTestingApiSrv(t *testing.T, ds *fakes.FakeCacheService, ac *acMock.Mo
 	return &TestingApiSrv{
 		DatasourceCache: ds,
 		authz:           accesscontrol.NewRuleService(ac),
+		accessControl:   ac,
 		evaluator:       evaluator,
 		cfg:             config(t),
 		tracer:          tracing.InitializeTracerForTest(),
@@ -517,6 +517,11 @@ type Record struct {
 	// required: true
 	// example: A
 	From string `json:"from" yaml:"from"`
+	// Name of the writer the recorded metric is sent to. One of prometheus, influxdb, otlp or sql.
+	// Uses the default writer if empty.
+	// required: false
+	// example: influxdb
+	Target string `json:"target,omitempty" yaml:"target,omitempty"`
 }
 
 // swagger:model
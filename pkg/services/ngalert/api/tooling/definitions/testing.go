@@ -229,8 +229,43 @@ type BacktestConfig struct {
 	Labels      map[string]string `json:"labels,omitempty"`
 	Annotations map[string]string `json:"annotations,omitempty"`
 
-	NoDataState NoDataState `json:"no_data_state"`
+	NoDataState  NoDataState         `json:"no_data_state"`
+	ExecErrState ExecutionErrorState `json:"exec_err_state,omitempty"`
+
+	// The UID of the folder of the rule. It sets the grafana_folder label of the alerts, which
+	// notification policies often match on.
+	FolderUID string `json:"folder_uid,omitempty"`
+
+	// If true, the alerts are routed through the notification policies of the organization
+	// and the response is a BacktestNotificationsResult. Mute and active time intervals of
+	// the notification policies are not applied. Requires the alert.notifications:read or
+	// alert.notifications.routes:read permission.
+	Notifications bool `json:"notifications,omitempty"`
 }
 
 // swagger:model
 type BacktestResult data.Frame
+
+// swagger:model
+type BacktestNotificationsResult struct {
+	// The evaluation states, in the same format as BacktestResult
+	States json.RawMessage `json:"states"`
+	// The notifications that would have been sent, in time order
+	Notifications []BacktestNotification `json:"notifications"`
+}
+
+// swagger:model
+type BacktestNotification struct {
+	Time        time.Time               `json:"time"`
+	Receiver    string                  `json:"receiver"`
+	GroupLabels map[string]string       `json:"groupLabels"`
+	Alerts      []BacktestNotifiedAlert `json:"alerts"`
+}
+
+// swagger:model
+type BacktestNotifiedAlert struct {
+	Labels   map[string]string `json:"labels"`
+	StartsAt time.Time         `json:"startsAt"`
+	// Either firing or resolved
+	Status string `json:"status"`
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/benbjohnson/clock"
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
 
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 
@@ -42,11 +43,23 @@ type stateManager interface {
 type Engine struct {
 	evalFactory        eval.EvaluatorFactory
 	createStateManager func() stateManager
+	policies           notificationPolicyProvider
+	appUrl             *url.URL
 }
 
-func NewEngine(appUrl *url.URL, evalFactory eval.EvaluatorFactory, tracer tracing.Tracer) *Engine {
+// Result is the outcome of a backtest that replays notifications.
+type Result struct {
+	// States is the frame of evaluation states, as returned by Test.
+	States *data.Frame
+	// Notifications are the notifications that would have been sent, in time order.
+	Notifications []Notification
+}
+
+func NewEngine(appUrl *url.URL, evalFactory eval.EvaluatorFactory, tracer tracing.Tracer, policies notificationPolicyProvider) *Engine {
 	return &Engine{
 		evalFactory: evalFactory,
+		policies:    policies,
+		appUrl:      appUrl,
 		createStateManager: func() stateManager {
 			cfg := state.ManagerCfg{
 				Metrics:       nil,
@@ -63,7 +76,39 @@ func NewEngine(appUrl *url.URL, evalFactory eval.EvaluatorFactory, tracer tracin
 	}
 }
 
+// Test evaluates the rule over the time range and returns a frame with the state of each alert instance at each evaluation.
 func (e *Engine) Test(ctx context.Context, user identity.Requester, rule *models.AlertRule, from, to time.Time) (*data.Frame, error) {
+	return e.run(ctx, user, rule, from, to, nil, nil)
+}
+
+// TestNotifications is like Test, but also routes the alerts that the state manager would have sent through
+// the notification policies of the rule's organization, and returns the notifications each contact point
+// would have received. If folderTitle is not empty, the alerts have the grafana_folder label like the alerts of
+// the scheduler. Mute and active time intervals are not applied.
+func (e *Engine) TestNotifications(ctx context.Context, user identity.Requester, rule *models.AlertRule, folderTitle string, from, to time.Time) (*Result, error) {
+	route, err := routeForOrg(ctx, e.policies, rule.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	simulator := newNotificationSimulator(route)
+	// The rule labels added by the scheduler, such as alertname and grafana_folder, are often used by notification policies.
+	extraLabels := state.GetRuleExtraLabels(logger, rule, folderTitle, folderTitle != "")
+
+	frame, err := e.run(ctx, user, rule, from, to, extraLabels, func(now time.Time, transitions state.StateTransitions) {
+		alerts := make([]*amv2.PostableAlert, 0, len(transitions))
+		for _, t := range transitions {
+			alerts = append(alerts, state.StateToPostableAlert(t, e.appUrl))
+		}
+		simulator.receive(now, alerts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	simulator.advance(to)
+	return &Result{States: frame, Notifications: simulator.notifications}, nil
+}
+
+func (e *Engine) run(ctx context.Context, user identity.Requester, rule *models.AlertRule, from, to time.Time, extraLabels data.Labels, send func(now time.Time, transitions state.StateTransitions)) (*data.Frame, error) {
 	ruleCtx := models.WithRuleKey(ctx, rule.GetKey())
 	logger := logger.FromContext(ctx)
 
@@ -97,7 +142,13 @@ func (e *Engine) Test(ctx context.Context, user identity.Requester, rule *models
 			logger.Info("Unexpected evaluation. Skipping", "from", from, "to", to, "interval", rule.IntervalSeconds, "evaluationTime", currentTime, "evaluationIndex", idx, "expectedEvaluations", length)
 			return nil
 		}
-		states := stateManager.ProcessEvalResults(ruleCtx, currentTime, rule, results, nil, nil)
+		var sender state.Sender
+		if send != nil {
+			sender = func(_ context.Context, transitions state.StateTransitions) {
+				send(currentTime, transitions)
+			}
+		}
+		states := stateManager.ProcessEvalResults(ruleCtx, currentTime, rule, results, extraLabels, sender)
 		tsField.Set(idx, currentTime)
 		for _, s := range states {
 			field, ok := valueFields[s.CacheID]
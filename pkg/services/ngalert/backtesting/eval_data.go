@@ -16,7 +16,7 @@ import (
 type dataEvaluator struct {
 	refID              string
 	data               []mathexp.Series
-	downsampleFunction mathexp.ReducerID
+	downsampleFunction mathexp.Downsampler
 	upsampleFunction   mathexp.Upsampler
 }
 
@@ -32,7 +32,7 @@ func newDataEvaluator(refID string, frame *data.Frame) (*dataEvaluator, error) {
 	return &dataEvaluator{
 		refID:              refID,
 		data:               series,
-		downsampleFunction: mathexp.ReducerLast,
+		downsampleFunction: mathexp.DownsamplerLast,
 		upsampleFunction:   mathexp.UpsamplerPad,
 	}, nil
 }
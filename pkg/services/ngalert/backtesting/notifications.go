@@ -0,0 +1,210 @@
+package backtesting
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/common/model"
+
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+)
+
+// notificationPolicyProvider gives access to the Alertmanager configuration of an organization.
+type notificationPolicyProvider interface {
+	GetAlertmanagerConfiguration(ctx context.Context, org int64, withAutogen bool) (apimodels.GettableUserConfig, error)
+}
+
+// Notification is a notification that the Alertmanager would have sent to a contact point.
+type Notification struct {
+	Time        time.Time
+	Receiver    string
+	GroupLabels model.LabelSet
+	Alerts      []NotifiedAlert
+}
+
+// NotifiedAlert is an alert included in a notification.
+type NotifiedAlert struct {
+	Labels   model.LabelSet
+	StartsAt time.Time
+	Resolved bool
+}
+
+// notificationSimulator replays the grouping and timing of the Alertmanager dispatcher (group_wait,
+// group_interval and repeat_interval) for the alerts sent by the state manager. Mute and active time
+// intervals are not taken into account.
+type notificationSimulator struct {
+	route         *dispatch.Route
+	groups        map[string]*aggregationGroup
+	notifications []Notification
+}
+
+type simulatedAlert struct {
+	labels   model.LabelSet
+	startsAt time.Time
+	endsAt   time.Time
+}
+
+func (a *simulatedAlert) resolvedAt(now time.Time) bool {
+	return !a.endsAt.IsZero() && !a.endsAt.After(now)
+}
+
+type aggregationGroup struct {
+	route        *dispatch.Route
+	labels       model.LabelSet
+	alerts       map[model.Fingerprint]*simulatedAlert
+	next         time.Time
+	lastNotified time.Time
+	lastFiring   map[model.Fingerprint]struct{}
+	lastResolved map[model.Fingerprint]struct{}
+}
+
+func newNotificationSimulator(route *dispatch.Route) *notificationSimulator {
+	return &notificationSimulator{
+		route:  route,
+		groups: map[string]*aggregationGroup{},
+	}
+}
+
+// receive processes the alerts sent to the Alertmanager at the given time. Flushes that were due before are run first.
+func (s *notificationSimulator) receive(now time.Time, alerts []*amv2.PostableAlert) {
+	s.advance(now)
+	for _, pa := range alerts {
+		lset := make(model.LabelSet, len(pa.Labels))
+		for k, v := range pa.Labels {
+			lset[model.LabelName(k)] = model.LabelValue(v)
+		}
+		alert := &simulatedAlert{
+			labels:   lset,
+			startsAt: time.Time(pa.StartsAt),
+			endsAt:   time.Time(pa.EndsAt),
+		}
+		for _, r := range s.route.Match(lset) {
+			groupLabels := groupLabels(r, lset)
+			key := r.Key() + ":" + groupLabels.String()
+			g, ok := s.groups[key]
+			if !ok {
+				g = &aggregationGroup{
+					route:  r,
+					labels: groupLabels,
+					alerts: map[model.Fingerprint]*simulatedAlert{},
+					next:   now.Add(r.RouteOpts.GroupWait),
+				}
+				s.groups[key] = g
+			}
+			g.alerts[lset.Fingerprint()] = alert
+		}
+	}
+}
+
+// advance runs all flushes that are due up to and including the given time, in time order.
+func (s *notificationSimulator) advance(to time.Time) {
+	for {
+		var due *aggregationGroup
+		var dueKey string
+		for key, g := range s.groups {
+			if g.next.After(to) {
+				continue
+			}
+			if due == nil || g.next.Before(due.next) || (g.next.Equal(due.next) && key < dueKey) {
+				due, dueKey = g, key
+			}
+		}
+		if due == nil {
+			return
+		}
+		if n, ok := due.flush(); ok {
+			s.notifications = append(s.notifications, n)
+		}
+		if len(due.alerts) == 0 {
+			delete(s.groups, dueKey)
+		}
+	}
+}
+
+// flush sends a notification for the group if its alerts changed since the last notification or if the repeat
+// interval has passed, and schedules the next flush.
+func (g *aggregationGroup) flush() (Notification, bool) {
+	now := g.next
+	g.next = now.Add(g.route.RouteOpts.GroupInterval)
+
+	firing := map[model.Fingerprint]struct{}{}
+	resolved := map[model.Fingerprint]struct{}{}
+	for fp, a := range g.alerts {
+		if a.resolvedAt(now) {
+			resolved[fp] = struct{}{}
+		} else {
+			firing[fp] = struct{}{}
+		}
+	}
+
+	needsUpdate := !isSubset(firing, g.lastFiring) ||
+		!isSubset(resolved, g.lastResolved) ||
+		(len(firing) > 0 && !g.lastNotified.IsZero() && !now.Before(g.lastNotified.Add(g.route.RouteOpts.RepeatInterval)))
+	if g.lastNotified.IsZero() && len(firing) == 0 {
+		// nothing was ever sent about these alerts, so there is nothing to resolve
+		needsUpdate = false
+	}
+
+	var n Notification
+	if needsUpdate {
+		n = Notification{
+			Time:        now,
+			Receiver:    g.route.RouteOpts.Receiver,
+			GroupLabels: g.labels,
+			Alerts:      make([]NotifiedAlert, 0, len(g.alerts)),
+		}
+		for fp, a := range g.alerts {
+			_, isResolved := resolved[fp]
+			n.Alerts = append(n.Alerts, NotifiedAlert{Labels: a.labels, StartsAt: a.startsAt, Resolved: isResolved})
+		}
+		sort.Slice(n.Alerts, func(i, j int) bool { return n.Alerts[i].Labels.String() < n.Alerts[j].Labels.String() })
+		g.lastNotified = now
+		g.lastFiring = firing
+		g.lastResolved = resolved
+	}
+
+	for fp := range resolved {
+		delete(g.alerts, fp)
+	}
+	return n, needsUpdate
+}
+
+func isSubset(set, of map[model.Fingerprint]struct{}) bool {
+	for fp := range set {
+		if _, ok := of[fp]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func groupLabels(r *dispatch.Route, lset model.LabelSet) model.LabelSet {
+	groupLabels := model.LabelSet{}
+	for ln, lv := range lset {
+		if _, ok := r.RouteOpts.GroupBy[ln]; ok || r.RouteOpts.GroupByAll {
+			groupLabels[ln] = lv
+		}
+	}
+	return groupLabels
+}
+
+// routeForOrg builds the notification policy tree of the organization.
+func routeForOrg(ctx context.Context, provider notificationPolicyProvider, orgID int64) (*dispatch.Route, error) {
+	// The provider can be a nil pointer of a concrete type if the Alertmanager is not running.
+	if provider == nil || reflect.ValueOf(provider).Kind() == reflect.Pointer && reflect.ValueOf(provider).IsNil() {
+		return nil, fmt.Errorf("%w: notification policies are not available", ErrInvalidInputData)
+	}
+	cfg, err := provider.GetAlertmanagerConfiguration(ctx, orgID, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the notification policies: %w", err)
+	}
+	if cfg.AlertmanagerConfig.Route == nil {
+		return nil, fmt.Errorf("%w: the organization has no notification policies", ErrInvalidInputData)
+	}
+	return dispatch.NewRoute(cfg.AlertmanagerConfig.Route.AsAMRoute(), nil), nil
+}
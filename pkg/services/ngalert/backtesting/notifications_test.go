@@ -0,0 +1,193 @@
+package backtesting
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func testPolicies(t *testing.T) *apimodels.Route {
+	t.Helper()
+	matcher, err := labels.NewMatcher(labels.MatchEqual, "team", "a")
+	require.NoError(t, err)
+	groupWait := model.Duration(30 * time.Second)
+	groupInterval := model.Duration(5 * time.Minute)
+	repeatInterval := model.Duration(time.Hour)
+	return &apimodels.Route{
+		Receiver:       "default",
+		GroupByStr:     []string{"alertname"},
+		GroupBy:        []model.LabelName{"alertname"},
+		GroupWait:      &groupWait,
+		GroupInterval:  &groupInterval,
+		RepeatInterval: &repeatInterval,
+		Routes: []*apimodels.Route{
+			{Receiver: "team-a", ObjectMatchers: apimodels.ObjectMatchers{matcher}},
+		},
+	}
+}
+
+func postableAlert(labels map[string]string, startsAt, endsAt time.Time) *amv2.PostableAlert {
+	return &amv2.PostableAlert{
+		StartsAt: strfmt.DateTime(startsAt),
+		EndsAt:   strfmt.DateTime(endsAt),
+		Alert:    amv2.Alert{Labels: labels},
+	}
+}
+
+func TestNotificationSimulator(t *testing.T) {
+	t0 := time.Unix(0, 0).UTC()
+	route := dispatch.NewRoute(testPolicies(t).AsAMRoute(), nil)
+	a1 := map[string]string{"alertname": "rule", "team": "a", "host": "1"}
+	a2 := map[string]string{"alertname": "rule", "team": "a", "host": "2"}
+	b1 := map[string]string{"alertname": "rule", "team": "b"}
+
+	s := newNotificationSimulator(route)
+	s.receive(t0, []*amv2.PostableAlert{
+		postableAlert(a1, t0, t0.Add(time.Hour)),
+		postableAlert(b1, t0, t0.Add(time.Hour)),
+	})
+	s.receive(t0.Add(time.Minute), []*amv2.PostableAlert{postableAlert(a2, t0.Add(time.Minute), t0.Add(time.Hour))})
+	s.receive(t0.Add(6*time.Minute), []*amv2.PostableAlert{postableAlert(a1, t0, t0.Add(6*time.Minute))})
+	s.advance(t0.Add(20 * time.Minute))
+
+	type sent struct {
+		at       time.Duration
+		receiver string
+		firing   int
+		resolved int
+	}
+	var actual []sent
+	for _, n := range s.notifications {
+		require.Equal(t, model.LabelSet{"alertname": "rule"}, n.GroupLabels)
+		firing, resolved := 0, 0
+		for _, a := range n.Alerts {
+			if a.Resolved {
+				resolved++
+			} else {
+				firing++
+			}
+		}
+		actual = append(actual, sent{n.Time.Sub(t0), n.Receiver, firing, resolved})
+	}
+
+	require.Equal(t, []sent{
+		// group_wait after the first alerts
+		{30 * time.Second, "team-a", 1, 0},
+		{30 * time.Second, "default", 1, 0},
+		// a new alert joined the group, sent at the next group_interval
+		{5*time.Minute + 30*time.Second, "team-a", 2, 0},
+		// an alert was resolved
+		{10*time.Minute + 30*time.Second, "team-a", 1, 1},
+	}, actual)
+
+	t.Run("alerts that are not sent again expire", func(t *testing.T) {
+		s.advance(t0.Add(2 * time.Hour))
+		last := s.notifications[len(s.notifications)-1]
+		require.Equal(t, "default", last.Receiver)
+		require.True(t, last.Alerts[0].Resolved, "the alert expired at its end time")
+	})
+}
+
+type fakePolicyProvider struct {
+	route *apimodels.Route
+}
+
+func (f *fakePolicyProvider) GetAlertmanagerConfiguration(_ context.Context, _ int64, _ bool) (apimodels.GettableUserConfig, error) {
+	return apimodels.GettableUserConfig{
+		AlertmanagerConfig: apimodels.GettableApiAlertingConfig{
+			Config: apimodels.Config{Route: f.route},
+		},
+	}, nil
+}
+
+func TestEngineTestNotifications(t *testing.T) {
+	t0 := time.Unix(0, 0).UTC()
+	interval := 10 * time.Second
+	rule := &models.AlertRule{
+		OrgID:           1,
+		UID:             "backtesting-test",
+		Title:           "rule",
+		Condition:       "A",
+		IntervalSeconds: int64(interval.Seconds()),
+		For:             20 * time.Second,
+		NoDataState:     models.NoData,
+		ExecErrState:    models.ErrorErrState,
+		Labels:          map[string]string{"team": "a"},
+	}
+
+	evaluator := &fakeBacktestingEvaluator{
+		evalCallback: func(now time.Time) (eval.Results, error) {
+			s := eval.Normal
+			if now.Before(t0.Add(2 * time.Minute)) {
+				s = eval.Alerting
+			}
+			return eval.Results{{Instance: data.Labels{"host": "1"}, State: s, EvaluatedAt: now}}, nil
+		},
+	}
+	backtestingEvaluatorFactory = func(ctx context.Context, evalFactory eval.EvaluatorFactory, user identity.Requester, condition models.Condition, r eval.AlertingResultsReader) (backtestingEvaluator, error) {
+		return evaluator, nil
+	}
+	t.Cleanup(func() {
+		backtestingEvaluatorFactory = newBacktestingEvaluator
+	})
+
+	engine := NewEngine(nil, nil, tracing.InitializeTracerForTest(), &fakePolicyProvider{route: testPolicies(t)})
+	result, err := engine.TestNotifications(context.Background(), nil, rule, "", t0, t0.Add(15*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, result.States.Fields, 2)
+
+	require.Len(t, result.Notifications, 2)
+	firing := result.Notifications[0]
+	require.Equal(t, "team-a", firing.Receiver)
+	// pending for 20s, then group_wait
+	require.Equal(t, t0.Add(20*time.Second+30*time.Second), firing.Time)
+	require.Equal(t, model.LabelValue("rule"), firing.Alerts[0].Labels["alertname"])
+	require.False(t, firing.Alerts[0].Resolved)
+
+	resolved := result.Notifications[1]
+	require.Equal(t, t0.Add(20*time.Second+30*time.Second+5*time.Minute), resolved.Time)
+	require.True(t, resolved.Alerts[0].Resolved)
+
+	t.Run("alerts have the folder label", func(t *testing.T) {
+		matcher, err := labels.NewMatcher(labels.MatchEqual, models.FolderTitleLabel, "Team folder")
+		require.NoError(t, err)
+		route := testPolicies(t)
+		route.Routes = []*apimodels.Route{{Receiver: "folder", ObjectMatchers: apimodels.ObjectMatchers{matcher}}}
+
+		engine := NewEngine(nil, nil, tracing.InitializeTracerForTest(), &fakePolicyProvider{route: route})
+		result, err := engine.TestNotifications(context.Background(), nil, rule, "Team folder", t0, t0.Add(15*time.Minute))
+		require.NoError(t, err)
+		require.NotEmpty(t, result.Notifications)
+		require.Equal(t, "folder", result.Notifications[0].Receiver)
+		require.Equal(t, model.LabelValue("Team folder"), result.Notifications[0].Alerts[0].Labels[models.FolderTitleLabel])
+	})
+
+	t.Run("fails without notification policies", func(t *testing.T) {
+		for name, provider := range map[string]notificationPolicyProvider{
+			"nil provider":       nil,
+			"nil typed provider": (*fakePolicyProvider)(nil),
+			"no route":           &fakePolicyProvider{},
+		} {
+			t.Run(name, func(t *testing.T) {
+				engine := NewEngine(nil, nil, tracing.InitializeTracerForTest(), provider)
+				_, err := engine.TestNotifications(context.Background(), nil, rule, "", t0, t0.Add(time.Minute))
+				require.ErrorIs(t, err, ErrInvalidInputData)
+			})
+		}
+	})
+}
@@ -6,8 +6,12 @@ import (
 )
 
 type RemoteWriter struct {
-	WritesTotal   *prometheus.CounterVec
-	WriteDuration *prometheus.HistogramVec
+	WritesTotal            *prometheus.CounterVec
+	WriteDuration          *prometheus.HistogramVec
+	WriteRetriesTotal      *prometheus.CounterVec
+	WriteQueueDepth        *prometheus.GaugeVec
+	WriteQueueReplaysTotal *prometheus.CounterVec
+	WriteQueueDroppedTotal *prometheus.CounterVec
 }
 
 func NewRemoteWriterMetrics(r prometheus.Registerer) *RemoteWriter {
@@ -26,5 +30,29 @@ func NewRemoteWriterMetrics(r prometheus.Registerer) *RemoteWriter {
 				Help:      "Histogram of remote write durations.",
 				Buckets:   prometheus.DefBuckets,
 			}, []string{"org", "backend"}),
+		WriteRetriesTotal: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "remote_writer_write_retries_total",
+			Help:      "The total number of remote writes retried after an unexpected failure.",
+		}, []string{"org", "backend"}),
+		WriteQueueDepth: promauto.With(r).NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "remote_writer_queue_depth",
+			Help:      "The number of failed remote writes waiting to be replayed.",
+		}, []string{"backend"}),
+		WriteQueueReplaysTotal: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "remote_writer_queue_replays_total",
+			Help:      "The total number of queued remote writes that were replayed successfully.",
+		}, []string{"backend"}),
+		WriteQueueDroppedTotal: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "remote_writer_queue_dropped_total",
+			Help:      "The total number of queued remote writes that were dropped, either because the queue was full or because the write was rejected.",
+		}, []string{"backend", "reason"}),
 	}
 }
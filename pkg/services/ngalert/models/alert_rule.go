@@ -878,6 +878,8 @@ type Record struct {
 	Metric string
 	// From contains a query RefID, indicating which expression node is the output of the recording rule.
 	From string
+	// Target is the name of the writer the results are sent to. The default writer is used if it is empty.
+	Target string `json:",omitempty"`
 }
 
 func (r *Record) Fingerprint() data.Fingerprint {
@@ -892,6 +894,10 @@ func (r *Record) Fingerprint() data.Fingerprint {
 
 	writeString(r.Metric)
 	writeString(r.From)
+	if r.Target != "" {
+		// only add the target if it is set, so the fingerprint of existing rules does not change.
+		writeString(r.Target)
+	}
 	return data.Fingerprint(h.Sum64())
 }
 
@@ -0,0 +1,337 @@
+package historian
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+	history_model "github.com/grafana/grafana/pkg/services/ngalert/state/historian/model"
+)
+
+// sqlInsertBatchSize keeps the number of parameters of an insert below the limits of the databases.
+const sqlInsertBatchSize = 50
+
+// stateHistoryEntry is a row of the alert_state_history table.
+type stateHistoryEntry struct {
+	ID            int64  `xorm:"pk autoincr 'id'"`
+	OrgID         int64  `xorm:"org_id"`
+	RuleUID       string `xorm:"rule_uid"`
+	FolderUID     string `xorm:"folder_uid"`
+	DashboardUID  string `xorm:"dashboard_uid"`
+	PanelID       int64  `xorm:"panel_id"`
+	Fingerprint   string `xorm:"fingerprint"`
+	PreviousState string `xorm:"previous_state"`
+	CurrentState  string `xorm:"current_state"`
+	ErrorMessage  string `xorm:"error_message"`
+	StateValues   string `xorm:"state_values"`
+	EvaluatedAt   int64  `xorm:"evaluated_at"`
+}
+
+func (stateHistoryEntry) TableName() string {
+	return "alert_state_history"
+}
+
+// stateHistoryLabel is a row of the alert_state_history_label table. The labels of an alert instance are stored
+// once per fingerprint, and are shared by all its transitions.
+type stateHistoryLabel struct {
+	ID          int64  `xorm:"pk autoincr 'id'"`
+	OrgID       int64  `xorm:"org_id"`
+	Fingerprint string `xorm:"fingerprint"`
+	Name        string `xorm:"name"`
+	Value       string `xorm:"value"`
+}
+
+func (stateHistoryLabel) TableName() string {
+	return "alert_state_history_label"
+}
+
+// SQLBackend is a state.Historian that records state history to the Grafana database.
+type SQLBackend struct {
+	db        db.DB
+	clock     clock.Clock
+	metrics   *metrics.Historian
+	log       log.Logger
+	ac        AccessControl
+	ruleStore RuleStore
+}
+
+func NewSQLBackend(logger log.Logger, db db.DB, metrics *metrics.Historian, ruleStore RuleStore, ac AccessControl) *SQLBackend {
+	return &SQLBackend{
+		db:        db,
+		clock:     clock.New(),
+		metrics:   metrics,
+		log:       logger,
+		ac:        ac,
+		ruleStore: ruleStore,
+	}
+}
+
+// Record writes a number of state transitions for a given rule to the database.
+func (h *SQLBackend) Record(ctx context.Context, rule history_model.RuleMeta, states []state.StateTransition) <-chan error {
+	logger := h.log.FromContext(ctx)
+	entries, labels := statesToHistoryEntries(rule, states, logger)
+
+	errCh := make(chan error, 1)
+	if len(entries) == 0 {
+		close(errCh)
+		return errCh
+	}
+
+	// This is a new background job, so let's create a brand new context for it.
+	// We want it to be isolated, i.e. we don't want grafana shutdowns to interrupt this work
+	// immediately but rather try to flush writes.
+	writeCtx := context.Background()
+	writeCtx, cancel := context.WithTimeout(writeCtx, StateHistoryWriteTimeout)
+	writeCtx = history_model.WithRuleData(writeCtx, rule)
+	writeCtx = trace.ContextWithSpan(writeCtx, trace.SpanFromContext(ctx))
+
+	go func(ctx context.Context) {
+		defer cancel()
+		defer close(errCh)
+		logger := h.log.FromContext(ctx)
+		logger.Debug("Saving state history batch", "samples", len(entries))
+		org := fmt.Sprint(rule.OrgID)
+		h.metrics.WritesTotal.WithLabelValues(org, "sql").Inc()
+		h.metrics.TransitionsTotal.WithLabelValues(org).Add(float64(len(entries)))
+
+		if err := h.recordEntries(ctx, rule.OrgID, entries, labels); err != nil {
+			logger.Error("Failed to save alert state history batch", "error", err)
+			h.metrics.WritesFailed.WithLabelValues(org, "sql").Inc()
+			h.metrics.TransitionsFailed.WithLabelValues(org).Add(float64(len(entries)))
+			errCh <- fmt.Errorf("failed to save alert state history batch: %w", err)
+			return
+		}
+		logger.Debug("Done saving alert state history batch", "samples", len(entries))
+	}(writeCtx)
+	return errCh
+}
+
+// statesToHistoryEntries returns the rows of the transitions to record, and the labels of the alert instances by
+// fingerprint.
+func statesToHistoryEntries(rule history_model.RuleMeta, states []state.StateTransition, logger log.Logger) ([]stateHistoryEntry, map[string]data.Labels) {
+	entries := make([]stateHistoryEntry, 0, len(states))
+	labels := make(map[string]data.Labels)
+	for _, state := range states {
+		if !shouldRecord(state) {
+			continue
+		}
+
+		values, err := valuesAsDataBlob(state.State).MarshalJSON()
+		if err != nil {
+			logger.Error("Failed to construct history record for state, skipping", "error", err)
+			continue
+		}
+
+		sanitizedLabels := removePrivateLabels(state.Labels)
+		fingerprint := labelFingerprint(sanitizedLabels)
+		labels[fingerprint] = sanitizedLabels
+
+		entry := stateHistoryEntry{
+			OrgID:         rule.OrgID,
+			RuleUID:       rule.UID,
+			FolderUID:     rule.NamespaceUID,
+			DashboardUID:  rule.DashboardUID,
+			PanelID:       rule.PanelID,
+			Fingerprint:   fingerprint,
+			PreviousState: state.PreviousFormatted(),
+			CurrentState:  state.Formatted(),
+			StateValues:   string(values),
+			EvaluatedAt:   state.State.LastEvaluationTime.UnixMilli(),
+		}
+		if state.State.State == eval.Error {
+			entry.ErrorMessage = state.Error.Error()
+		}
+		entries = append(entries, entry)
+	}
+	return entries, labels
+}
+
+func (h *SQLBackend) recordEntries(ctx context.Context, orgID int64, entries []stateHistoryEntry, labels map[string]data.Labels) error {
+	return h.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		for start := 0; start < len(entries); start += sqlInsertBatchSize {
+			batch := entries[start:min(start+sqlInsertBatchSize, len(entries))]
+			if _, err := sess.InsertMulti(&batch); err != nil {
+				return fmt.Errorf("failed to insert state history: %w", err)
+			}
+		}
+
+		// The labels are inserted after the transitions, so that they are not collected by the cleanup of the labels
+		// without transitions in the meantime.
+		for fingerprint, lbls := range labels {
+			exists, err := sess.Table("alert_state_history_label").Where("org_id = ? AND fingerprint = ?", orgID, fingerprint).Exist()
+			if err != nil {
+				return fmt.Errorf("failed to find state history labels: %w", err)
+			}
+			if exists || len(lbls) == 0 {
+				continue
+			}
+
+			rows := make([]stateHistoryLabel, 0, len(lbls))
+			for name, value := range lbls {
+				rows = append(rows, stateHistoryLabel{OrgID: orgID, Fingerprint: fingerprint, Name: name, Value: value})
+			}
+			if _, err := sess.InsertMulti(&rows); err != nil {
+				return fmt.Errorf("failed to insert state history labels: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// Query retrieves state history entries from the database and formats the results into a dataframe like the
+// Loki backend does.
+func (h *SQLBackend) Query(ctx context.Context, query models.HistoryQuery) (*data.Frame, error) {
+	uids, err := getFolderUIDsForFilter(ctx, h.ac, h.ruleStore, query)
+	if err != nil {
+		return nil, err
+	}
+
+	now := h.clock.Now().UTC()
+	if query.To.IsZero() {
+		query.To = now
+	}
+	if query.From.IsZero() {
+		query.From = now.Add(-defaultQueryRange)
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maximumPageSize {
+		limit = maximumPageSize
+	}
+
+	entries := make([]stateHistoryEntry, 0)
+	labels := make(map[string]data.Labels)
+	err = h.db.WithDbSession(ctx, func(sess *db.Session) error {
+		q := sess.Table("alert_state_history").
+			Where("org_id = ?", query.OrgID).
+			And("evaluated_at >= ? AND evaluated_at <= ?", query.From.UnixMilli(), query.To.UnixMilli())
+		if query.RuleUID != "" {
+			q = q.And("rule_uid = ?", query.RuleUID)
+		}
+		if query.DashboardUID != "" {
+			q = q.And("dashboard_uid = ?", query.DashboardUID)
+		}
+		if query.PanelID != 0 {
+			q = q.And("panel_id = ?", query.PanelID)
+		}
+		if len(uids) > 0 {
+			args := make([]any, 0, len(uids))
+			for _, uid := range uids {
+				args = append(args, uid)
+			}
+			q = q.In("folder_uid", args...)
+		}
+
+		names := make([]string, 0, len(query.Labels))
+		for name := range query.Labels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			q = q.And("fingerprint IN (SELECT fingerprint FROM alert_state_history_label WHERE org_id = ? AND name = ? AND value = ?)", query.OrgID, name, query.Labels[name])
+		}
+
+		// The most recent transitions are kept when the limit is reached.
+		if err := q.Desc("evaluated_at", "id").Limit(limit).Find(&entries); err != nil {
+			return fmt.Errorf("failed to query state history: %w", err)
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+
+		fingerprints := make(map[string]struct{})
+		args := make([]any, 0)
+		for _, e := range entries {
+			if _, ok := fingerprints[e.Fingerprint]; !ok {
+				fingerprints[e.Fingerprint] = struct{}{}
+				args = append(args, e.Fingerprint)
+			}
+		}
+		rows := make([]stateHistoryLabel, 0)
+		if err := sess.Table("alert_state_history_label").Where("org_id = ?", query.OrgID).In("fingerprint", args...).Find(&rows); err != nil {
+			return fmt.Errorf("failed to query state history labels: %w", err)
+		}
+		for _, row := range rows {
+			if labels[row.Fingerprint] == nil {
+				labels[row.Fingerprint] = make(data.Labels)
+			}
+			labels[row.Fingerprint][row.Name] = row.Value
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return historyEntriesToFrame(entries, labels)
+}
+
+// historyEntriesToFrame converts the rows of the most recent transitions first to a frame of the transitions
+// sorted by time.
+func historyEntriesToFrame(entries []stateHistoryEntry, labels map[string]data.Labels) (*data.Frame, error) {
+	frame := data.NewFrame("states")
+	lbls := data.Labels(map[string]string{})
+
+	times := make([]time.Time, 0, len(entries))
+	lines := make([]json.RawMessage, 0, len(entries))
+	streamLabels := make([]json.RawMessage, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+
+		values, err := simplejson.NewJson([]byte(e.StateValues))
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal state values: %w", err)
+		}
+		instanceLabels := labels[e.Fingerprint]
+		if instanceLabels == nil {
+			instanceLabels = data.Labels{}
+		}
+		line, err := json.Marshal(LokiEntry{
+			SchemaVersion:  1,
+			Previous:       e.PreviousState,
+			Current:        e.CurrentState,
+			Error:          e.ErrorMessage,
+			Values:         values,
+			DashboardUID:   e.DashboardUID,
+			PanelID:        e.PanelID,
+			Fingerprint:    e.Fingerprint,
+			RuleUID:        e.RuleUID,
+			InstanceLabels: instanceLabels,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize entry: %w", err)
+		}
+		lblsJson, err := json.Marshal(map[string]string{
+			StateHistoryLabelKey: StateHistoryLabelValue,
+			OrgIDLabel:           fmt.Sprint(e.OrgID),
+			FolderUIDLabel:       e.FolderUID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize stream labels: %w", err)
+		}
+
+		times = append(times, time.UnixMilli(e.EvaluatedAt))
+		lines = append(lines, line)
+		streamLabels = append(streamLabels, lblsJson)
+	}
+
+	frame.Fields = append(frame.Fields, data.NewField(dfTime, lbls, times))
+	frame.Fields = append(frame.Fields, data.NewField(dfLine, lbls, lines))
+	frame.Fields = append(frame.Fields, data.NewField(dfLabels, lbls, streamLabels))
+	return frame, nil
+}
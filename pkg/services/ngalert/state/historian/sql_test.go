@@ -0,0 +1,192 @@
+package historian
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/folder"
+	acfakes "github.com/grafana/grafana/pkg/services/ngalert/accesscontrol/fakes"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+	history_model "github.com/grafana/grafana/pkg/services/ngalert/state/historian/model"
+	"github.com/grafana/grafana/pkg/services/ngalert/tests/fakes"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/tests/testsuite"
+)
+
+func TestMain(m *testing.M) {
+	testsuite.Run(m)
+}
+
+func TestIntegrationSQLBackend(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	usr := &user.SignedInUser{Name: "test-user", OrgID: 1}
+	now := time.Now().Truncate(time.Millisecond)
+
+	createSQLBackend := func(t *testing.T, canReadAll bool) *SQLBackend {
+		ac := &acfakes.FakeRuleService{}
+		ac.CanReadAllRulesFunc = func(ctx context.Context, requester identity.Requester) (bool, error) {
+			return canReadAll, nil
+		}
+		ac.HasAccessInFolderFunc = func(ctx context.Context, requester identity.Requester, namespaced models.Namespaced) (bool, error) {
+			return true, nil
+		}
+		rules := fakes.NewRuleStore(t)
+		rules.Folders = map[int64][]*folder.Folder{
+			1: {{UID: "my-folder", OrgID: 1}},
+		}
+		rules.Rules = map[int64][]*models.AlertRule{1: {}}
+		met := metrics.NewHistorianMetrics(prometheus.NewRegistry(), metrics.Subsystem)
+		return NewSQLBackend(log.NewNopLogger(), db.InitTestDB(t), met, rules, ac)
+	}
+
+	transition := func(current eval.State, labels data.Labels, evaluatedAt time.Time) state.StateTransition {
+		return state.StateTransition{
+			PreviousState: eval.Normal,
+			State: &state.State{
+				State:              current,
+				Labels:             labels,
+				LastEvaluationTime: evaluatedAt,
+				Values:             map[string]float64{"A": 1},
+			},
+		}
+	}
+
+	record := func(t *testing.T, h *SQLBackend, rule history_model.RuleMeta, states ...state.StateTransition) {
+		t.Helper()
+		require.NoError(t, <-h.Record(ctx, rule, states))
+	}
+
+	entries := func(t *testing.T, frame *data.Frame) []LokiEntry {
+		t.Helper()
+		require.Len(t, frame.Fields, 3)
+		result := make([]LokiEntry, 0, frame.Rows())
+		for i := 0; i < frame.Rows(); i++ {
+			var entry LokiEntry
+			require.NoError(t, json.Unmarshal(frame.Fields[1].At(i).(json.RawMessage), &entry))
+			result = append(result, entry)
+		}
+		return result
+	}
+
+	t.Run("recorded transitions are queryable", func(t *testing.T) {
+		h := createSQLBackend(t, true)
+		rule := createTestRule()
+		errored := transition(eval.Error, data.Labels{"a": "b", "__private__": "x"}, now.Add(-time.Minute))
+		errored.Error = errors.New("test-error")
+		record(t, h, rule, transition(eval.Alerting, data.Labels{"a": "b"}, now.Add(-2*time.Minute)), errored)
+		// the labels of the instance are stored once
+		record(t, h, rule, transition(eval.Alerting, data.Labels{"a": "b"}, now))
+
+		frame, err := h.Query(ctx, models.HistoryQuery{OrgID: 1, RuleUID: rule.UID, SignedInUser: usr})
+		require.NoError(t, err)
+		require.Equal(t, 3, frame.Rows())
+		require.Equal(t, now.Add(-2*time.Minute), frame.Fields[0].At(0).(time.Time))
+
+		result := entries(t, frame)
+		require.Equal(t, "Normal", result[0].Previous)
+		require.Equal(t, "Alerting", result[0].Current)
+		require.Equal(t, map[string]string{"a": "b"}, result[0].InstanceLabels)
+		require.Equal(t, rule.UID, result[0].RuleUID)
+		require.Equal(t, rule.DashboardUID, result[0].DashboardUID)
+		require.Equal(t, float64(1), result[0].Values.Get("A").MustFloat64())
+		require.Equal(t, "test-error", result[1].Error)
+		require.Equal(t, result[0].Fingerprint, result[1].Fingerprint)
+
+		var streamLabels map[string]string
+		require.NoError(t, json.Unmarshal(frame.Fields[2].At(0).(json.RawMessage), &streamLabels))
+		require.Equal(t, map[string]string{StateHistoryLabelKey: StateHistoryLabelValue, OrgIDLabel: "1", FolderUIDLabel: "my-folder"}, streamLabels)
+
+		var count int64
+		err = h.db.WithDbSession(ctx, func(sess *db.Session) error {
+			count, err = sess.Table("alert_state_history_label").Count()
+			return err
+		})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), count)
+	})
+
+	t.Run("unchanged states are not recorded", func(t *testing.T) {
+		h := createSQLBackend(t, true)
+		unchanged := transition(eval.Normal, data.Labels{"a": "b"}, now)
+
+		require.NoError(t, <-h.Record(ctx, createTestRule(), []state.StateTransition{unchanged}))
+
+		frame, err := h.Query(ctx, models.HistoryQuery{OrgID: 1, SignedInUser: usr})
+		require.NoError(t, err)
+		require.Equal(t, 0, frame.Rows())
+	})
+
+	t.Run("queries are filtered", func(t *testing.T) {
+		h := createSQLBackend(t, true)
+		rule := createTestRule()
+		other := createTestRule()
+		other.UID = "other-rule"
+		other.DashboardUID = ""
+		other.PanelID = 0
+		record(t, h, rule,
+			transition(eval.Alerting, data.Labels{"team": "a", "env": "prod"}, now.Add(-3*time.Hour)),
+			transition(eval.Alerting, data.Labels{"team": "b", "env": "prod"}, now.Add(-2*time.Hour)),
+		)
+		record(t, h, other, transition(eval.Alerting, data.Labels{"team": "a", "env": "dev"}, now.Add(-time.Hour)))
+
+		testCases := []struct {
+			name     string
+			query    models.HistoryQuery
+			expected []string
+		}{
+			{"by rule", models.HistoryQuery{RuleUID: other.UID}, []string{"dev"}},
+			{"by dashboard", models.HistoryQuery{DashboardUID: rule.DashboardUID, PanelID: rule.PanelID}, []string{"prod", "prod"}},
+			{"by label", models.HistoryQuery{Labels: map[string]string{"team": "a"}}, []string{"prod", "dev"}},
+			{"by labels", models.HistoryQuery{Labels: map[string]string{"team": "a", "env": "dev"}}, []string{"dev"}},
+			{"by time range", models.HistoryQuery{From: now.Add(-150 * time.Minute), To: now}, []string{"prod", "dev"}},
+			{"keeping the most recent", models.HistoryQuery{Limit: 2}, []string{"prod", "dev"}},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.query.OrgID = 1
+				tc.query.SignedInUser = usr
+				frame, err := h.Query(ctx, tc.query)
+				require.NoError(t, err)
+
+				envs := make([]string, 0)
+				for _, e := range entries(t, frame) {
+					envs = append(envs, e.InstanceLabels["env"])
+				}
+				require.Equal(t, tc.expected, envs)
+			})
+		}
+	})
+
+	t.Run("only the history of the folders the user can read is returned", func(t *testing.T) {
+		h := createSQLBackend(t, false)
+		rule := createTestRule()
+		hidden := createTestRule()
+		hidden.UID = "hidden-rule"
+		hidden.NamespaceUID = "hidden-folder"
+		record(t, h, rule, transition(eval.Alerting, data.Labels{"a": "b"}, now))
+		record(t, h, hidden, transition(eval.Alerting, data.Labels{"a": "c"}, now))
+
+		frame, err := h.Query(ctx, models.HistoryQuery{OrgID: 1, SignedInUser: usr})
+		require.NoError(t, err)
+		result := entries(t, frame)
+		require.Len(t, result, 1)
+		require.Equal(t, rule.UID, result[0].RuleUID)
+	})
+}
@@ -0,0 +1,65 @@
+package writer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBodySize is the maximum number of bytes of the response body that are included in write errors.
+const maxErrorBodySize = 1024
+
+// HTTPWriteError is returned by the HTTP-based writers when the server does not accept a write.
+type HTTPWriteError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPWriteError) Error() string {
+	return fmt.Sprintf("server returned HTTP status %d: %s", e.StatusCode, e.Body)
+}
+
+// postWrite sends the body to the URL and returns the status code of the response, or an error
+// if the request failed or the server did not accept the write.
+func postWrite(ctx context.Context, client *http.Client, url string, headers http.Header, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header = headers.Clone()
+	req.Header.Set("User-Agent", "grafana-recording-rule")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, errors.Join(ErrUnexpectedWriteFailure, err)
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	if res.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return res.StatusCode, nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBodySize))
+	return res.StatusCode, checkHTTPWriteError(&HTTPWriteError{
+		StatusCode: res.StatusCode,
+		Body:       strings.TrimSpace(string(msg)),
+	})
+}
+
+// checkHTTPWriteError tells apart the writes that the server rejected because of the data from unexpected failures,
+// in the same way as checkWriteError does for Prometheus remote write.
+func checkHTTPWriteError(writeErr *HTTPWriteError) error {
+	switch writeErr.StatusCode {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return errors.Join(ErrRejectedWrite, writeErr)
+	default:
+		// 5xx, 429 and all other statuses are not the fault of the data.
+		return errors.Join(ErrUnexpectedWriteFailure, writeErr)
+	}
+}
@@ -0,0 +1,167 @@
+package writer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const influxDBBackendType = "influxdb"
+
+// influxDBValueField is the name of the field that holds the value of the recorded metric.
+const influxDBValueField = "value"
+
+var (
+	influxDBMeasurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `, "\n", `\n`)
+	influxDBTagEscaper         = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `, "\n", `\n`)
+)
+
+// InfluxDBWriter writes recording rule results to the InfluxDB v2 write API using the line protocol.
+// Each metric becomes a measurement with its labels as tags and a single "value" field.
+type InfluxDBWriter struct {
+	client   *http.Client
+	writeURL string
+	headers  http.Header
+	clock    clock.Clock
+	logger   log.Logger
+	metrics  *metrics.RemoteWriter
+}
+
+func NewInfluxDBWriter(
+	settings setting.RecordingRuleInfluxDBSettings,
+	httpClientProvider HttpClientProvider,
+	clock clock.Clock,
+	l log.Logger,
+	metrics *metrics.RemoteWriter,
+) (*InfluxDBWriter, error) {
+	writeURL, err := influxDBWriteURL(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	cl, err := httpClientProvider.New()
+	if err != nil {
+		return nil, err
+	}
+	cl.Timeout = settings.Timeout
+
+	headers := make(http.Header)
+	headers.Set("Content-Type", "text/plain; charset=utf-8")
+	if settings.Token != "" {
+		headers.Set("Authorization", "Token "+settings.Token)
+	}
+
+	return &InfluxDBWriter{
+		client:   cl,
+		writeURL: writeURL,
+		headers:  headers,
+		clock:    clock,
+		logger:   l,
+		metrics:  metrics,
+	}, nil
+}
+
+func influxDBWriteURL(settings setting.RecordingRuleInfluxDBSettings) (string, error) {
+	u, err := url.Parse(settings.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid URL: %q is not an absolute URL", settings.URL)
+	}
+	if settings.Org == "" {
+		return "", fmt.Errorf("org is required")
+	}
+	if settings.Bucket == "" {
+		return "", fmt.Errorf("bucket is required")
+	}
+	if settings.Timeout <= 0 {
+		return "", fmt.Errorf("timeout must be greater than 0")
+	}
+
+	u = u.JoinPath("api", "v2", "write")
+	q := u.Query()
+	q.Set("org", settings.Org)
+	q.Set("bucket", settings.Bucket)
+	q.Set("precision", "ms")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Write writes the given frames to the InfluxDB write endpoint.
+func (w InfluxDBWriter) Write(ctx context.Context, name string, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error {
+	l := w.logger.FromContext(ctx)
+	lvs := []string{fmt.Sprint(orgID), influxDBBackendType}
+
+	points, err := PointsFromFrames(name, t, frames, extraLabels)
+	if err != nil {
+		return errors.Join(ErrBadFrame, err)
+	}
+
+	var body strings.Builder
+	for _, p := range points {
+		// The line protocol has no representation for these values.
+		if math.IsNaN(p.Metric.V) || math.IsInf(p.Metric.V, 0) {
+			l.Debug("Skipping point that cannot be written to InfluxDB", "name", name, "value", p.Metric.V)
+			continue
+		}
+		writeInfluxDBLine(&body, p)
+	}
+	if body.Len() == 0 {
+		return nil
+	}
+
+	l.Debug("Writing metric", "name", name)
+	writeStart := w.clock.Now()
+	statusCode, writeErr := postWrite(ctx, w.client, w.writeURL, w.headers, []byte(body.String()))
+	w.metrics.WriteDuration.WithLabelValues(lvs...).Observe(w.clock.Now().Sub(writeStart).Seconds())
+
+	lvs = append(lvs, fmt.Sprint(statusCode))
+	w.metrics.WritesTotal.WithLabelValues(lvs...).Inc()
+
+	return writeErr
+}
+
+// writeInfluxDBLine appends the point to the builder in the line protocol.
+func writeInfluxDBLine(b *strings.Builder, p Point) {
+	b.WriteString(influxDBMeasurementEscaper.Replace(p.Name))
+
+	keys := make([]string, 0, len(p.Labels))
+	for k, v := range p.Labels {
+		// InfluxDB does not accept tags with empty values.
+		if v == "" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	// InfluxDB performs best when tags are sorted by key.
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteByte(',')
+		b.WriteString(influxDBTagEscaper.Replace(k))
+		b.WriteByte('=')
+		b.WriteString(influxDBTagEscaper.Replace(p.Labels[k]))
+	}
+
+	b.WriteByte(' ')
+	b.WriteString(influxDBValueField)
+	b.WriteByte('=')
+	b.WriteString(strconv.FormatFloat(p.Metric.V, 'g', -1, 64))
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatInt(p.Metric.T.UnixMilli(), 10))
+	b.WriteByte('\n')
+}
@@ -0,0 +1,107 @@
+package writer
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestInfluxDBWriteURL(t *testing.T) {
+	valid := setting.RecordingRuleInfluxDBSettings{
+		URL:     "http://localhost:8086/influx",
+		Org:     "my org",
+		Bucket:  "recorded",
+		Timeout: time.Second,
+	}
+
+	u, err := influxDBWriteURL(valid)
+	require.NoError(t, err)
+	require.Equal(t, "http://localhost:8086/influx/api/v2/write?bucket=recorded&org=my+org&precision=ms", u)
+
+	for name, modify := range map[string]func(s *setting.RecordingRuleInfluxDBSettings){
+		"relative url":   func(s *setting.RecordingRuleInfluxDBSettings) { s.URL = "localhost" },
+		"missing org":    func(s *setting.RecordingRuleInfluxDBSettings) { s.Org = "" },
+		"missing bucket": func(s *setting.RecordingRuleInfluxDBSettings) { s.Bucket = "" },
+		"timeout is 0":   func(s *setting.RecordingRuleInfluxDBSettings) { s.Timeout = 0 },
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := valid
+			modify(&s)
+			_, err := influxDBWriteURL(s)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestWriteInfluxDBLine(t *testing.T) {
+	var b strings.Builder
+	writeInfluxDBLine(&b, Point{
+		Name:   "my metric",
+		Labels: map[string]string{"b": "x=1,y", "a": "with space", "empty": ""},
+		Metric: Metric{T: time.UnixMilli(1700000000123), V: 1.5},
+	})
+	require.Equal(t, "my\\ metric,a=with\\ space,b=x\\=1\\,y value=1.5 1700000000123\n", b.String())
+}
+
+func TestInfluxDBWriter_Write(t *testing.T) {
+	var status int
+	var lastBody, lastAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v2/write", r.URL.Path)
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		lastBody = string(b)
+		lastAuth = r.Header.Get("Authorization")
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+
+	writer, err := NewInfluxDBWriter(setting.RecordingRuleInfluxDBSettings{
+		URL:     srv.URL,
+		Token:   "secret",
+		Org:     "org",
+		Bucket:  "bucket",
+		Timeout: time.Second,
+	}, httpclient.NewProvider(), clock.New(), log.New("test"), metrics.NewRemoteWriterMetrics(prometheus.NewRegistry()))
+	require.NoError(t, err)
+
+	now := time.UnixMilli(1700000000000)
+	frames := frameGenFromLabels(t, data.FrameTypeNumericWide, []map[string]string{{"foo": "1"}})
+
+	t.Run("writes the points in line protocol", func(t *testing.T) {
+		status = http.StatusNoContent
+		err := writer.Write(context.Background(), "test", now, frames, 1, map[string]string{"extra": "label"})
+		require.NoError(t, err)
+		require.Equal(t, "Token secret", lastAuth)
+		require.Regexp(t, `^test,extra=label,foo=1 value=\S+ 1700000000000\n$`, lastBody)
+	})
+
+	t.Run("rejected write", func(t *testing.T) {
+		status = http.StatusBadRequest
+		err := writer.Write(context.Background(), "test", now, frames, 1, nil)
+		require.ErrorIs(t, err, ErrRejectedWrite)
+	})
+
+	t.Run("unexpected failure", func(t *testing.T) {
+		status = http.StatusServiceUnavailable
+		err := writer.Write(context.Background(), "test", now, frames, 1, nil)
+		require.ErrorIs(t, err, ErrUnexpectedWriteFailure)
+		var httpErr *HTTPWriteError
+		require.ErrorAs(t, err, &httpErr)
+		require.Equal(t, http.StatusServiceUnavailable, httpErr.StatusCode)
+	})
+}
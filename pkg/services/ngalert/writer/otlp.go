@@ -0,0 +1,127 @@
+package writer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const otlpBackendType = "otlp"
+
+// otlpScopeName is the name of the instrumentation scope of the metrics written by recording rules.
+const otlpScopeName = "grafana-recording-rule"
+
+// OTLPWriter writes recording rule results to an OTLP/HTTP metrics endpoint.
+// Each metric is sent as a gauge with one data point per series, using the labels as attributes.
+type OTLPWriter struct {
+	client  *http.Client
+	url     string
+	headers http.Header
+	clock   clock.Clock
+	logger  log.Logger
+	metrics *metrics.RemoteWriter
+}
+
+func NewOTLPWriter(
+	settings setting.RecordingRuleOTLPSettings,
+	httpClientProvider HttpClientProvider,
+	clock clock.Clock,
+	l log.Logger,
+	metrics *metrics.RemoteWriter,
+) (*OTLPWriter, error) {
+	u, err := url.Parse(settings.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL: %q is not an absolute URL", settings.URL)
+	}
+	if settings.Timeout <= 0 {
+		return nil, fmt.Errorf("timeout must be greater than 0")
+	}
+
+	cl, err := httpClientProvider.New()
+	if err != nil {
+		return nil, err
+	}
+	cl.Timeout = settings.Timeout
+
+	headers := make(http.Header)
+	for k, v := range settings.CustomHeaders {
+		headers.Add(k, v)
+	}
+	headers.Set("Content-Type", "application/x-protobuf")
+
+	return &OTLPWriter{
+		client:  cl,
+		url:     u.String(),
+		headers: headers,
+		clock:   clock,
+		logger:  l,
+		metrics: metrics,
+	}, nil
+}
+
+// Write writes the given frames to the OTLP metrics endpoint.
+func (w OTLPWriter) Write(ctx context.Context, name string, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error {
+	l := w.logger.FromContext(ctx)
+	lvs := []string{fmt.Sprint(orgID), otlpBackendType}
+
+	points, err := PointsFromFrames(name, t, frames, extraLabels)
+	if err != nil {
+		return errors.Join(ErrBadFrame, err)
+	}
+	if len(points) == 0 {
+		return nil
+	}
+
+	body, err := pmetricotlp.NewExportRequestFromMetrics(otlpMetricsFromPoints(name, points)).MarshalProto()
+	if err != nil {
+		return errors.Join(ErrBadFrame, err)
+	}
+
+	l.Debug("Writing metric", "name", name)
+	writeStart := w.clock.Now()
+	statusCode, writeErr := postWrite(ctx, w.client, w.url, w.headers, body)
+	w.metrics.WriteDuration.WithLabelValues(lvs...).Observe(w.clock.Now().Sub(writeStart).Seconds())
+
+	lvs = append(lvs, fmt.Sprint(statusCode))
+	w.metrics.WritesTotal.WithLabelValues(lvs...).Inc()
+
+	return writeErr
+}
+
+func otlpMetricsFromPoints(name string, points []Point) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	sm := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName(otlpScopeName)
+
+	m := sm.Metrics().AppendEmpty()
+	m.SetName(name)
+	dps := m.SetEmptyGauge().DataPoints()
+	dps.EnsureCapacity(len(points))
+	for _, p := range points {
+		dp := dps.AppendEmpty()
+		dp.SetTimestamp(pcommon.NewTimestampFromTime(p.Metric.T))
+		dp.SetDoubleValue(p.Metric.V)
+		attrs := dp.Attributes()
+		attrs.EnsureCapacity(len(p.Labels))
+		for k, v := range p.Labels {
+			attrs.PutStr(k, v)
+		}
+	}
+	return md
+}
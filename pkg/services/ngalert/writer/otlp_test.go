@@ -0,0 +1,65 @@
+package writer
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestOTLPWriter_Write(t *testing.T) {
+	received := make(chan pmetricotlp.ExportRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		require.Equal(t, "tenant", r.Header.Get("X-Scope-OrgID"))
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		req := pmetricotlp.NewExportRequest()
+		require.NoError(t, req.UnmarshalProto(b))
+		received <- req
+	}))
+	t.Cleanup(srv.Close)
+
+	_, err := NewOTLPWriter(setting.RecordingRuleOTLPSettings{URL: "/v1/metrics", Timeout: time.Second}, httpclient.NewProvider(), clock.New(), log.New("test"), nil)
+	require.Error(t, err, "relative URLs are not accepted")
+
+	writer, err := NewOTLPWriter(setting.RecordingRuleOTLPSettings{
+		URL:           srv.URL + "/v1/metrics",
+		CustomHeaders: map[string]string{"X-Scope-OrgID": "tenant"},
+		Timeout:       time.Second,
+	}, httpclient.NewProvider(), clock.New(), log.New("test"), metrics.NewRemoteWriterMetrics(prometheus.NewRegistry()))
+	require.NoError(t, err)
+
+	now := time.UnixMilli(1700000000000)
+	frames := frameGenFromLabels(t, data.FrameTypeNumericWide, []map[string]string{{"foo": "1"}, {"foo": "2"}})
+	require.NoError(t, writer.Write(context.Background(), "test", now, frames, 1, map[string]string{"extra": "label"}))
+
+	req := <-received
+	md := req.Metrics()
+	require.Equal(t, 1, md.MetricCount())
+	m := md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+	require.Equal(t, "test", m.Name())
+	require.Equal(t, pmetric.MetricTypeGauge, m.Type())
+	require.Equal(t, 2, m.Gauge().DataPoints().Len())
+	for i := 0; i < m.Gauge().DataPoints().Len(); i++ {
+		dp := m.Gauge().DataPoints().At(i)
+		require.Equal(t, now, dp.Timestamp().AsTime().Local())
+		extra, ok := dp.Attributes().Get("extra")
+		require.True(t, ok)
+		require.Equal(t, "label", extra.Str())
+	}
+}
@@ -0,0 +1,370 @@
+package writer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/serverlock"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	// replayInterval is how often the queue checks whether it is time to replay the queued writes.
+	replayInterval = time.Second
+	// pendingRefreshInterval is how often an empty queue checks the database for writes queued by other instances.
+	pendingRefreshInterval = time.Minute
+	// replayLockTimeout is after how long the replay lock of an instance is considered dead.
+	replayLockTimeout = 10 * time.Minute
+	// maxReplayDuration bounds a replay so that it ends well before its lock is considered dead.
+	maxReplayDuration = replayLockTimeout / 2
+)
+
+// Values of the reason label of the dropped writes metric.
+const (
+	droppedQueueFull = "queue_full"
+	droppedRejected  = "rejected"
+)
+
+// ReplayLock makes sure that a single Grafana instance replays the queue of a target at a time.
+// It is implemented by serverlock.ServerLockService.
+type ReplayLock interface {
+	LockExecuteAndRelease(ctx context.Context, actionName string, maxInterval time.Duration, fn func(ctx context.Context)) error
+}
+
+// QueuedWrite is a row of the alert_recording_rule_write_queue table.
+type QueuedWrite struct {
+	ID          int64  `xorm:"pk autoincr 'id'"`
+	Target      string `xorm:"target"`
+	OrgID       int64  `xorm:"org_id"`
+	Metric      string `xorm:"metric"`
+	Timestamp   int64  `xorm:"timestamp"`
+	ExtraLabels string `xorm:"extra_labels"`
+	Frames      string `xorm:"frames"`
+	CreatedAt   int64  `xorm:"created_at"`
+}
+
+func (QueuedWrite) TableName() string {
+	return "alert_recording_rule_write_queue"
+}
+
+// QueuedWriter sends writes to a target and, when the target is unavailable, keeps them in the Grafana database
+// until it recovers. Queued writes are replayed in the order they were made, and new writes are queued behind
+// them for as long as the queue is not empty, so that the samples of a series reach the target in order.
+// The queue is shared by all the Grafana instances using the database, and is replayed by one instance at a time.
+type QueuedWriter struct {
+	target         string
+	writer         Writer
+	store          db.DB
+	lock           ReplayLock
+	maxBatches     int64
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	clock          clock.Clock
+	logger         log.Logger
+	metrics        *metrics.RemoteWriter
+
+	mtx         sync.Mutex
+	pending     int64
+	backoff     time.Duration
+	nextAttempt time.Time
+	nextRefresh time.Time
+}
+
+func NewQueuedWriter(
+	target string,
+	w Writer,
+	store db.DB,
+	lock ReplayLock,
+	settings setting.RecordingRuleSettings,
+	clock clock.Clock,
+	l log.Logger,
+	metrics *metrics.RemoteWriter,
+) *QueuedWriter {
+	return &QueuedWriter{
+		target:         target,
+		writer:         w,
+		store:          store,
+		lock:           lock,
+		maxBatches:     settings.WriteQueue.MaxBatches,
+		initialBackoff: settings.WriteRetryBackoff,
+		maxBackoff:     settings.WriteQueue.MaxBackoff,
+		clock:          clock,
+		logger:         l.New("target", target),
+		metrics:        metrics,
+	}
+}
+
+// Write writes the frames to the target. If the target is unavailable, or if there are older writes waiting
+// to be replayed, the write is queued instead and no error is returned.
+func (w *QueuedWriter) Write(ctx context.Context, name string, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error {
+	w.mtx.Lock()
+	queued := w.pending > 0
+	w.mtx.Unlock()
+
+	var writeErr error
+	if !queued {
+		writeErr = w.writer.Write(ctx, name, t, frames, orgID, extraLabels)
+		if writeErr == nil || !errors.Is(writeErr, ErrUnexpectedWriteFailure) {
+			return writeErr
+		}
+	}
+
+	if err := w.enqueue(ctx, name, t, frames, orgID, extraLabels); err != nil {
+		return errors.Join(writeErr, fmt.Errorf("failed to queue the write: %w", err))
+	}
+	if writeErr != nil {
+		w.logger.FromContext(ctx).Warn("Write failed, it will be replayed when the target recovers", "error", writeErr)
+	}
+	return nil
+}
+
+func (w *QueuedWriter) enqueue(ctx context.Context, name string, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error {
+	framesJSON, err := json.Marshal(&frames)
+	if err != nil {
+		return err
+	}
+	labelsJSON, err := json.Marshal(extraLabels)
+	if err != nil {
+		return err
+	}
+	row := QueuedWrite{
+		Target:      w.target,
+		OrgID:       orgID,
+		Metric:      name,
+		Timestamp:   t.UnixMilli(),
+		ExtraLabels: string(labelsJSON),
+		Frames:      string(framesJSON),
+		CreatedAt:   w.clock.Now().Unix(),
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	var dropped int64
+	err = w.store.InTransaction(ctx, func(ctx context.Context) error {
+		return w.store.WithDbSession(ctx, func(sess *db.Session) error {
+			if _, err := sess.Insert(&row); err != nil {
+				return err
+			}
+			count, err := sess.Where("target = ?", w.target).Count(&QueuedWrite{})
+			if err != nil {
+				return err
+			}
+			if w.maxBatches <= 0 || count <= w.maxBatches {
+				w.pending = count
+				return nil
+			}
+			// The queue is full, drop the oldest writes.
+			var oldest []int64
+			if err := sess.Table(QueuedWrite{}.TableName()).Where("target = ?", w.target).Asc("id").Limit(int(count - w.maxBatches)).Cols("id").Find(&oldest); err != nil {
+				return err
+			}
+			if _, err := sess.In("id", oldest).Delete(&QueuedWrite{}); err != nil {
+				return err
+			}
+			dropped = int64(len(oldest))
+			w.pending = count - dropped
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if dropped > 0 {
+		w.logger.FromContext(ctx).Warn("Write queue is full, dropped the oldest writes", "dropped", dropped, "maxBatches", w.maxBatches)
+		w.metrics.WriteQueueDroppedTotal.WithLabelValues(w.target, droppedQueueFull).Add(float64(dropped))
+	}
+	w.metrics.WriteQueueDepth.WithLabelValues(w.target).Set(float64(w.pending))
+	return nil
+}
+
+// Run replays the queued writes until the context is cancelled.
+func (w *QueuedWriter) Run(ctx context.Context) error {
+	if err := w.loadPending(ctx); err != nil {
+		w.logger.Error("Failed to read the write queue", "error", err)
+	}
+
+	ticker := w.clock.Ticker(replayInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			w.replay(ctx)
+		}
+	}
+}
+
+func (w *QueuedWriter) loadPending(ctx context.Context) error {
+	return w.store.WithDbSession(ctx, func(sess *db.Session) error {
+		count, err := sess.Where("target = ?", w.target).Count(&QueuedWrite{})
+		if err != nil {
+			return err
+		}
+		w.mtx.Lock()
+		defer w.mtx.Unlock()
+		w.pending = count
+		w.metrics.WriteQueueDepth.WithLabelValues(w.target).Set(float64(count))
+		return nil
+	})
+}
+
+// replayLockName returns the name of the server lock held while replaying the queue of the target.
+func (w *QueuedWriter) replayLockName() string {
+	return "recording-rules-write-queue-" + w.target
+}
+
+// replay replays the queued writes while holding the replay lock of the target. The database is checked for writes
+// queued by other instances every pendingRefreshInterval while the queue looks empty.
+func (w *QueuedWriter) replay(ctx context.Context) {
+	now := w.clock.Now()
+	w.mtx.Lock()
+	refresh := w.pending == 0 && !now.Before(w.nextRefresh)
+	if refresh {
+		w.nextRefresh = now.Add(pendingRefreshInterval)
+	}
+	w.mtx.Unlock()
+	if refresh {
+		if err := w.loadPending(ctx); err != nil {
+			w.logger.Error("Failed to read the write queue", "error", err)
+		}
+	}
+
+	w.mtx.Lock()
+	skip := w.pending == 0 || now.Before(w.nextAttempt)
+	w.mtx.Unlock()
+	if skip {
+		return
+	}
+
+	err := w.lock.LockExecuteAndRelease(ctx, w.replayLockName(), replayLockTimeout, w.replayLocked)
+	var lockedErr *serverlock.ServerLockExistsError
+	if errors.As(err, &lockedErr) {
+		// Another instance is replaying the queue, check again later without increasing the backoff.
+		w.logger.Debug("Write queue is being replayed by another instance")
+		w.mtx.Lock()
+		w.nextAttempt = now.Add(w.initialBackoff)
+		w.mtx.Unlock()
+		return
+	}
+	if err != nil {
+		w.logger.Error("Failed to lock the write queue", "error", err)
+		w.scheduleRetry()
+	}
+}
+
+// replayLocked writes the queued writes to the target, oldest first, until the queue is empty, the target fails,
+// or the replay has lasted maxReplayDuration.
+func (w *QueuedWriter) replayLocked(ctx context.Context) {
+	deadline := w.clock.Now().Add(maxReplayDuration)
+	for ctx.Err() == nil && w.clock.Now().Before(deadline) {
+		row, ok, err := w.oldest(ctx)
+		if err != nil {
+			w.logger.Error("Failed to read the write queue", "error", err)
+			w.scheduleRetry()
+			return
+		}
+		if !ok {
+			return
+		}
+
+		err = w.replayWrite(ctx, row)
+		if errors.Is(err, ErrUnexpectedWriteFailure) {
+			w.logger.Debug("Target is still unavailable", "error", err)
+			w.scheduleRetry()
+			return
+		}
+		if err != nil {
+			// Writing it again would fail the same way.
+			w.logger.Warn("Dropping queued write that was rejected", "metric", row.Metric, "org", row.OrgID, "error", err)
+			w.metrics.WriteQueueDroppedTotal.WithLabelValues(w.target, droppedRejected).Inc()
+		} else {
+			w.metrics.WriteQueueReplaysTotal.WithLabelValues(w.target).Inc()
+		}
+
+		if err := w.delete(ctx, row.ID); err != nil {
+			w.logger.Error("Failed to delete replayed write from the queue", "error", err)
+			w.scheduleRetry()
+			return
+		}
+		w.mtx.Lock()
+		w.backoff = 0
+		w.mtx.Unlock()
+	}
+}
+
+func (w *QueuedWriter) replayWrite(ctx context.Context, row QueuedWrite) error {
+	var frames data.Frames
+	if err := json.Unmarshal([]byte(row.Frames), &frames); err != nil {
+		return errors.Join(ErrBadFrame, err)
+	}
+	var extraLabels map[string]string
+	if err := json.Unmarshal([]byte(row.ExtraLabels), &extraLabels); err != nil {
+		return errors.Join(ErrBadFrame, err)
+	}
+	return w.writer.Write(ctx, row.Metric, time.UnixMilli(row.Timestamp), frames, row.OrgID, extraLabels)
+}
+
+// oldest returns the oldest queued write. If the queue is empty, new writes stop being queued.
+func (w *QueuedWriter) oldest(ctx context.Context) (QueuedWrite, bool, error) {
+	// Hold the lock so that a write queued concurrently is not missed when the queue is found empty.
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	var row QueuedWrite
+	var ok bool
+	err := w.store.WithDbSession(ctx, func(sess *db.Session) error {
+		var err error
+		ok, err = sess.Where("target = ?", w.target).Asc("id").Get(&row)
+		return err
+	})
+	if err == nil && !ok {
+		w.pending = 0
+		w.metrics.WriteQueueDepth.WithLabelValues(w.target).Set(0)
+	}
+	return row, ok, err
+}
+
+func (w *QueuedWriter) delete(ctx context.Context, id int64) error {
+	err := w.store.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.ID(id).Delete(&QueuedWrite{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.pending > 0 {
+		w.pending--
+	}
+	w.metrics.WriteQueueDepth.WithLabelValues(w.target).Set(float64(w.pending))
+	return nil
+}
+
+// scheduleRetry doubles the backoff, up to the maximum, and delays the next replay by it.
+func (w *QueuedWriter) scheduleRetry() {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	switch {
+	case w.backoff == 0:
+		w.backoff = w.initialBackoff
+	case w.backoff < w.maxBackoff:
+		w.backoff *= 2
+	}
+	if w.maxBackoff > 0 && w.backoff > w.maxBackoff {
+		w.backoff = w.maxBackoff
+	}
+	w.nextAttempt = w.clock.Now().Add(w.backoff)
+}
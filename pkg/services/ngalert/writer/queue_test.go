@@ -0,0 +1,167 @@
+package writer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/serverlock"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestIntegrationQueuedWriter(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	store := db.InitTestDB(t)
+	lock, err := serverlock.ProvideService(store, tracing.InitializeTracerForTest(), setting.NewCfg())
+	require.NoError(t, err)
+	clk := clock.NewMock()
+	m := metrics.NewRemoteWriterMetrics(prometheus.NewRegistry())
+	settings := setting.RecordingRuleSettings{
+		WriteRetryBackoff: time.Second,
+		WriteQueue: setting.RecordingRuleWriteQueueSettings{
+			Enabled:    true,
+			MaxBatches: 3,
+			MaxBackoff: 4 * time.Second,
+		},
+	}
+
+	var targetErr error
+	var written []time.Time
+	w := NewQueuedWriter("fake", FakeWriter{
+		WriteFunc: func(ctx context.Context, name string, ts time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error {
+			if targetErr != nil {
+				return targetErr
+			}
+			require.Equal(t, "test", name)
+			require.Equal(t, map[string]string{"extra": "label"}, extraLabels)
+			_, err := PointsFromFrames(name, ts, frames, extraLabels)
+			require.NoError(t, err)
+			written = append(written, ts)
+			return nil
+		},
+	}, store, lock, settings, clk, log.New("test"), m)
+
+	ctx := context.Background()
+	frames := frameGenFromLabels(t, data.FrameTypeNumericWide, []map[string]string{{"foo": "1"}})
+	write := func(t *testing.T, sec int64) {
+		t.Helper()
+		require.NoError(t, w.Write(ctx, "test", time.Unix(sec, 0), frames, 1, map[string]string{"extra": "label"}))
+	}
+	depth := func() float64 {
+		return testutil.ToFloat64(m.WriteQueueDepth.WithLabelValues("fake"))
+	}
+
+	t.Run("writes directly when the target is available", func(t *testing.T) {
+		write(t, 1)
+		require.Equal(t, []time.Time{time.Unix(1, 0)}, written)
+		require.Zero(t, depth())
+	})
+
+	t.Run("queues writes while the target is unavailable", func(t *testing.T) {
+		written = nil
+		targetErr = errors.Join(ErrUnexpectedWriteFailure, errors.New("connection refused"))
+		write(t, 2)
+		write(t, 3)
+		require.Empty(t, written)
+		require.Equal(t, 2.0, depth())
+
+		// the target is still down, so the replay backs off
+		w.replay(ctx)
+		require.Equal(t, 2.0, depth())
+		require.Equal(t, clk.Now().Add(time.Second), w.nextAttempt)
+		w.replay(ctx)
+		require.Equal(t, clk.Now().Add(time.Second), w.nextAttempt, "replay should wait for the backoff")
+		clk.Add(time.Second)
+		w.replay(ctx)
+		require.Equal(t, clk.Now().Add(2*time.Second), w.nextAttempt)
+	})
+
+	t.Run("new writes are queued behind older ones", func(t *testing.T) {
+		targetErr = nil
+		write(t, 4)
+		require.Empty(t, written)
+		require.Equal(t, 3.0, depth())
+	})
+
+	t.Run("drops the oldest writes when the queue is full", func(t *testing.T) {
+		write(t, 5)
+		require.Equal(t, 3.0, depth())
+		require.Equal(t, 1.0, testutil.ToFloat64(m.WriteQueueDroppedTotal.WithLabelValues("fake", droppedQueueFull)))
+	})
+
+	t.Run("replays in order once the target recovers", func(t *testing.T) {
+		clk.Add(2 * time.Second)
+		w.replay(ctx)
+		require.Equal(t, []time.Time{time.Unix(3, 0), time.Unix(4, 0), time.Unix(5, 0)}, written)
+		require.Zero(t, depth())
+		require.Equal(t, 3.0, testutil.ToFloat64(m.WriteQueueReplaysTotal.WithLabelValues("fake")))
+
+		write(t, 6)
+		require.Equal(t, time.Unix(6, 0), written[len(written)-1])
+		require.Zero(t, depth())
+	})
+
+	t.Run("drops rejected writes", func(t *testing.T) {
+		targetErr = ErrUnexpectedWriteFailure
+		write(t, 7)
+		targetErr = ErrRejectedWrite
+		clk.Add(time.Minute)
+		w.replay(ctx)
+		require.Zero(t, depth())
+		require.Equal(t, 1.0, testutil.ToFloat64(m.WriteQueueDroppedTotal.WithLabelValues("fake", droppedRejected)))
+	})
+
+	t.Run("does not queue rejected writes", func(t *testing.T) {
+		targetErr = ErrRejectedWrite
+		err := w.Write(ctx, "test", time.Unix(8, 0), frames, 1, nil)
+		require.ErrorIs(t, err, ErrRejectedWrite)
+		require.Zero(t, depth())
+	})
+
+	t.Run("loads the queue on start", func(t *testing.T) {
+		targetErr = ErrUnexpectedWriteFailure
+		write(t, 9)
+
+		restarted := NewQueuedWriter("fake", FakeWriter{}, store, lock, settings, clk, log.New("test"), m)
+		require.NoError(t, restarted.loadPending(ctx))
+		require.Equal(t, int64(1), restarted.pending)
+	})
+
+	t.Run("a single instance replays the queue", func(t *testing.T) {
+		clk.Add(time.Minute)
+		targetErr = nil
+		written = nil
+		other := NewQueuedWriter("fake", w.writer, store, lock, settings, clk, log.New("test"), m)
+
+		// the other instance finds the write queued by w, but w holds the replay lock
+		err := lock.LockExecuteAndRelease(ctx, w.replayLockName(), time.Minute, func(ctx context.Context) {
+			other.replay(ctx)
+		})
+		require.NoError(t, err)
+		require.Empty(t, written)
+		require.Equal(t, int64(1), other.pending)
+
+		clk.Add(time.Second)
+		other.replay(ctx)
+		require.Equal(t, []time.Time{time.Unix(9, 0)}, written)
+
+		// w still counts the write it queued, but does not replay it again
+		w.replay(ctx)
+		require.Equal(t, []time.Time{time.Unix(9, 0)}, written)
+		require.Zero(t, w.pending)
+	})
+}
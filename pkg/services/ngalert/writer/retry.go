@@ -0,0 +1,90 @@
+package writer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+)
+
+// RetryingWriter retries the writes that failed with ErrUnexpectedWriteFailure. Rejected writes and
+// frames that cannot be read are returned immediately because writing them again would fail the same way.
+// The retries run in the background so that the backoff does not delay the evaluation of the rule.
+type RetryingWriter struct {
+	writer      Writer
+	backend     string
+	maxAttempts int
+	backoff     time.Duration
+	clock       clock.Clock
+	logger      log.Logger
+	metrics     *metrics.RemoteWriter
+
+	retries sync.WaitGroup
+}
+
+func NewRetryingWriter(w Writer, backend string, maxAttempts int, backoff time.Duration, clock clock.Clock, l log.Logger, metrics *metrics.RemoteWriter) *RetryingWriter {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &RetryingWriter{
+		writer:      w,
+		backend:     backend,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		clock:       clock,
+		logger:      l,
+		metrics:     metrics,
+	}
+}
+
+// Write makes the first attempt to write the frames. If it fails with ErrUnexpectedWriteFailure and more
+// attempts are allowed, the write is retried in the background and no error is returned.
+func (w *RetryingWriter) Write(ctx context.Context, name string, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error {
+	err := w.writer.Write(ctx, name, t, frames, orgID, extraLabels)
+	if err == nil || !errors.Is(err, ErrUnexpectedWriteFailure) || w.maxAttempts == 1 {
+		return err
+	}
+
+	w.logger.FromContext(ctx).Warn("Write failed, retrying in the background", "backend", w.backend, "error", err)
+	w.retries.Add(1)
+	go func() {
+		defer w.retries.Done()
+		w.retry(ctx, name, t, frames, orgID, extraLabels, err)
+	}()
+	return nil
+}
+
+// retry makes the remaining attempts of a write that failed, waiting for the backoff before each of them.
+func (w *RetryingWriter) retry(ctx context.Context, name string, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string, err error) {
+	l := w.logger.FromContext(ctx)
+	backoff := w.backoff
+	for attempt := 2; attempt <= w.maxAttempts; attempt++ {
+		if backoff > 0 {
+			select {
+			case <-ctx.Done():
+				l.Warn("Write retries cancelled", "backend", w.backend, "error", errors.Join(err, ctx.Err()))
+				return
+			case <-w.clock.After(backoff):
+			}
+			backoff *= 2
+		}
+		w.metrics.WriteRetriesTotal.WithLabelValues(fmt.Sprint(orgID), w.backend).Inc()
+		l.Debug("Retrying write", "backend", w.backend, "attempt", attempt, "error", err)
+		err = w.writer.Write(ctx, name, t, frames, orgID, extraLabels)
+		if err == nil {
+			return
+		}
+		if !errors.Is(err, ErrUnexpectedWriteFailure) {
+			l.Error("Write failed", "backend", w.backend, "attempt", attempt, "error", err)
+			return
+		}
+	}
+	l.Error("Write failed, giving up", "backend", w.backend, "attempts", w.maxAttempts, "error", err)
+}
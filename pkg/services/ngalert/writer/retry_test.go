@@ -0,0 +1,98 @@
+package writer
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+)
+
+func TestRetryingWriter(t *testing.T) {
+	newWriter := func(maxAttempts int, backoff time.Duration, clk clock.Clock, errs ...error) (w *RetryingWriter, attempts *atomic.Int32, m *metrics.RemoteWriter) {
+		attempts = &atomic.Int32{}
+		m = metrics.NewRemoteWriterMetrics(prometheus.NewRegistry())
+		w = NewRetryingWriter(FakeWriter{
+			WriteFunc: func(ctx context.Context, name string, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error {
+				n := int(attempts.Add(1))
+				if n > len(errs) {
+					return nil
+				}
+				return errs[n-1]
+			},
+		}, "fake", maxAttempts, backoff, clk, log.New("test"), m)
+		return w, attempts, m
+	}
+
+	t.Run("retries unexpected failures in the background", func(t *testing.T) {
+		w, attempts, m := newWriter(3, 0, clock.New(), ErrUnexpectedWriteFailure, ErrUnexpectedWriteFailure)
+
+		err := w.Write(context.Background(), "test", time.Now(), nil, 1, nil)
+		require.NoError(t, err)
+		w.retries.Wait()
+		require.Equal(t, int32(3), attempts.Load())
+		require.Equal(t, 2.0, testutil.ToFloat64(m.WriteRetriesTotal.WithLabelValues("1", "fake")))
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		w, attempts, _ := newWriter(3, 0, clock.New(), ErrUnexpectedWriteFailure, ErrUnexpectedWriteFailure, ErrUnexpectedWriteFailure, ErrUnexpectedWriteFailure)
+
+		err := w.Write(context.Background(), "test", time.Now(), nil, 1, nil)
+		require.NoError(t, err)
+		w.retries.Wait()
+		require.Equal(t, int32(3), attempts.Load())
+	})
+
+	t.Run("returns the error if a single attempt is allowed", func(t *testing.T) {
+		w, attempts, _ := newWriter(1, 0, clock.New(), ErrUnexpectedWriteFailure)
+
+		err := w.Write(context.Background(), "test", time.Now(), nil, 1, nil)
+		require.ErrorIs(t, err, ErrUnexpectedWriteFailure)
+		require.Equal(t, int32(1), attempts.Load())
+	})
+
+	t.Run("does not retry rejected writes", func(t *testing.T) {
+		w, attempts, m := newWriter(3, 0, clock.New(), errors.Join(ErrRejectedWrite, errors.New("bad label")))
+
+		err := w.Write(context.Background(), "test", time.Now(), nil, 1, nil)
+		require.ErrorIs(t, err, ErrRejectedWrite)
+		w.retries.Wait()
+		require.Equal(t, int32(1), attempts.Load())
+		require.Zero(t, testutil.ToFloat64(m.WriteRetriesTotal.WithLabelValues("1", "fake")))
+	})
+
+	t.Run("waits for the backoff without blocking the write", func(t *testing.T) {
+		clk := clock.NewMock()
+		w, attempts, _ := newWriter(2, time.Second, clk, ErrUnexpectedWriteFailure, ErrUnexpectedWriteFailure)
+
+		err := w.Write(context.Background(), "test", time.Now(), nil, 1, nil)
+		require.NoError(t, err)
+		require.Equal(t, int32(1), attempts.Load())
+
+		require.Eventually(t, func() bool {
+			clk.Add(time.Second)
+			return attempts.Load() == 2
+		}, time.Second, 10*time.Millisecond)
+		w.retries.Wait()
+	})
+
+	t.Run("stops retrying when the context is cancelled", func(t *testing.T) {
+		w, attempts, _ := newWriter(2, time.Hour, clock.NewMock(), ErrUnexpectedWriteFailure)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		err := w.Write(ctx, "test", time.Now(), nil, 1, nil)
+		require.NoError(t, err)
+		cancel()
+		w.retries.Wait()
+		require.Equal(t, int32(1), attempts.Load())
+	})
+}
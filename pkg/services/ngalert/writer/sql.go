@@ -0,0 +1,110 @@
+package writer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+const sqlBackendType = "sql"
+
+// Values of the status_code label of the write metrics for the SQL writer.
+const (
+	sqlWriteSucceeded = "ok"
+	sqlWriteFailed    = "error"
+)
+
+// RecordingRuleSample is a row of the alert_recording_rule_sample table.
+type RecordingRuleSample struct {
+	ID         int64   `xorm:"pk autoincr 'id'"`
+	OrgID      int64   `xorm:"org_id"`
+	Metric     string  `xorm:"metric"`
+	Labels     string  `xorm:"labels"`
+	LabelsHash string  `xorm:"labels_hash"`
+	Timestamp  int64   `xorm:"timestamp"`
+	Value      float64 `xorm:"value"`
+}
+
+func (RecordingRuleSample) TableName() string {
+	return "alert_recording_rule_sample"
+}
+
+// SQLWriter writes recording rule results to the alert_recording_rule_sample table of the Grafana database.
+type SQLWriter struct {
+	store   db.DB
+	clock   clock.Clock
+	logger  log.Logger
+	metrics *metrics.RemoteWriter
+}
+
+func NewSQLWriter(store db.DB, clock clock.Clock, l log.Logger, metrics *metrics.RemoteWriter) *SQLWriter {
+	return &SQLWriter{
+		store:   store,
+		clock:   clock,
+		logger:  l,
+		metrics: metrics,
+	}
+}
+
+// Write inserts the given frames into the samples table.
+func (w *SQLWriter) Write(ctx context.Context, name string, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error {
+	l := w.logger.FromContext(ctx)
+	lvs := []string{fmt.Sprint(orgID), sqlBackendType}
+
+	points, err := PointsFromFrames(name, t, frames, extraLabels)
+	if err != nil {
+		return errors.Join(ErrBadFrame, err)
+	}
+	if len(points) == 0 {
+		return nil
+	}
+
+	samples := make([]RecordingRuleSample, 0, len(points))
+	for _, p := range points {
+		labels := data.Labels(p.Labels)
+		lbls, err := json.Marshal(labels)
+		if err != nil {
+			return errors.Join(ErrBadFrame, err)
+		}
+		samples = append(samples, RecordingRuleSample{
+			OrgID:      orgID,
+			Metric:     p.Name,
+			Labels:     string(lbls),
+			LabelsHash: labels.Fingerprint().String(),
+			Timestamp:  p.Metric.T.UnixMilli(),
+			Value:      p.Metric.V,
+		})
+	}
+
+	l.Debug("Writing metric", "name", name)
+	writeStart := w.clock.Now()
+	writeErr := w.store.InTransaction(ctx, func(ctx context.Context) error {
+		return w.store.WithDbSession(ctx, func(sess *db.Session) error {
+			_, err := sess.BulkInsert(RecordingRuleSample{}.TableName(), samples, sqlstore.NativeSettingsForDialect(w.store.GetDialect()))
+			return err
+		})
+	})
+	w.metrics.WriteDuration.WithLabelValues(lvs...).Observe(w.clock.Now().Sub(writeStart).Seconds())
+
+	status := sqlWriteSucceeded
+	if writeErr != nil {
+		status = sqlWriteFailed
+	}
+	lvs = append(lvs, status)
+	w.metrics.WritesTotal.WithLabelValues(lvs...).Inc()
+
+	if writeErr != nil {
+		return errors.Join(ErrUnexpectedWriteFailure, writeErr)
+	}
+	return nil
+}
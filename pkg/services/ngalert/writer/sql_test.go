@@ -0,0 +1,48 @@
+package writer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/tests/testsuite"
+)
+
+func TestMain(m *testing.M) {
+	testsuite.Run(m)
+}
+
+func TestIntegrationSQLWriter_Write(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	store := db.InitTestDB(t)
+	writer := NewSQLWriter(store, clock.New(), log.New("test"), metrics.NewRemoteWriterMetrics(prometheus.NewRegistry()))
+
+	now := time.UnixMilli(1700000000000)
+	frames := frameGenFromLabels(t, data.FrameTypeNumericWide, []map[string]string{{"foo": "1"}, {"foo": "2"}})
+	require.NoError(t, writer.Write(context.Background(), "test", now, frames, 1, map[string]string{"extra": "label"}))
+
+	var samples []RecordingRuleSample
+	err := store.WithDbSession(context.Background(), func(sess *db.Session) error {
+		return sess.Where("org_id = ?", 1).Asc("labels").Find(&samples)
+	})
+	require.NoError(t, err)
+	require.Len(t, samples, 2)
+	for i, s := range samples {
+		require.Equal(t, "test", s.Metric)
+		require.Equal(t, now.UnixMilli(), s.Timestamp)
+		labels := data.Labels{"extra": "label", "foo": []string{"1", "2"}[i]}
+		require.JSONEq(t, `{"extra":"label","foo":"`+labels["foo"]+`"}`, s.Labels)
+		require.Equal(t, labels.Fingerprint().String(), s.LabelsHash)
+	}
+}
@@ -0,0 +1,101 @@
+package writer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"golang.org/x/sync/errgroup"
+)
+
+// Names of the writers that a recording rule can select as its target.
+const (
+	TargetPrometheus = "prometheus"
+	TargetInfluxDB   = "influxdb"
+	TargetOTLP       = "otlp"
+	TargetSQL        = "sql"
+)
+
+var ErrUnknownTarget = errors.New("recording rule target is not configured")
+
+// IsKnownTarget returns true if the target is the name of one of the writers. The writer might not be configured.
+func IsKnownTarget(target string) bool {
+	switch target {
+	case TargetPrometheus, TargetInfluxDB, TargetOTLP, TargetSQL:
+		return true
+	}
+	return false
+}
+
+// Writer writes the result of a recording rule to a storage backend.
+type Writer interface {
+	Write(ctx context.Context, name string, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error
+}
+
+type targetContextKey struct{}
+
+// WithTarget returns a context that makes Router send writes to the writer of the given target.
+// The default target is used if the target is empty.
+func WithTarget(ctx context.Context, target string) context.Context {
+	return context.WithValue(ctx, targetContextKey{}, target)
+}
+
+// TargetFromContext returns the target set with WithTarget, or an empty string if there is none.
+func TargetFromContext(ctx context.Context) string {
+	target, _ := ctx.Value(targetContextKey{}).(string)
+	return target
+}
+
+// Router sends each write to the writer of the target selected in the context.
+type Router struct {
+	defaultTarget string
+	writers       map[string]Writer
+}
+
+func NewRouter(defaultTarget string, writers map[string]Writer) (*Router, error) {
+	if _, ok := writers[defaultTarget]; !ok {
+		return nil, fmt.Errorf("default target %q is not configured, configured targets: %v", defaultTarget, targetNames(writers))
+	}
+	return &Router{
+		defaultTarget: defaultTarget,
+		writers:       writers,
+	}, nil
+}
+
+// Write writes the given frames to the writer of the target selected in the context.
+func (r *Router) Write(ctx context.Context, name string, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error {
+	target := TargetFromContext(ctx)
+	if target == "" {
+		target = r.defaultTarget
+	}
+	w, ok := r.writers[target]
+	if !ok {
+		return errors.Join(ErrRejectedWrite, fmt.Errorf("%w: %q", ErrUnknownTarget, target))
+	}
+	return w.Write(ctx, name, t, frames, orgID, extraLabels)
+}
+
+// Run runs the background tasks of the writers, such as replaying queued writes, until the context is cancelled.
+func (r *Router) Run(ctx context.Context) error {
+	g, ctx := errgroup.WithContext(ctx)
+	for _, w := range r.writers {
+		if runner, ok := w.(interface{ Run(context.Context) error }); ok {
+			g.Go(func() error {
+				return runner.Run(ctx)
+			})
+		}
+	}
+	return g.Wait()
+}
+
+func targetNames(writers map[string]Writer) []string {
+	names := make([]string, 0, len(writers))
+	for name := range writers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,41 @@
+package writer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter(t *testing.T) {
+	var written []string
+	fakeWriter := func(target string) Writer {
+		return FakeWriter{
+			WriteFunc: func(ctx context.Context, name string, t time.Time, frames data.Frames, orgID int64, extraLabels map[string]string) error {
+				written = append(written, target)
+				return nil
+			},
+		}
+	}
+	writers := map[string]Writer{
+		TargetPrometheus: fakeWriter(TargetPrometheus),
+		TargetSQL:        fakeWriter(TargetSQL),
+	}
+
+	_, err := NewRouter(TargetOTLP, writers)
+	require.ErrorContains(t, err, "default target \"otlp\" is not configured")
+
+	router, err := NewRouter(TargetPrometheus, writers)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, router.Write(ctx, "test", time.Now(), nil, 1, nil))
+	require.NoError(t, router.Write(WithTarget(ctx, TargetSQL), "test", time.Now(), nil, 1, nil))
+	require.Equal(t, []string{TargetPrometheus, TargetSQL}, written)
+
+	err = router.Write(WithTarget(ctx, TargetInfluxDB), "test", time.Now(), nil, 1, nil)
+	require.ErrorIs(t, err, ErrUnknownTarget)
+	require.ErrorIs(t, err, ErrRejectedWrite)
+}
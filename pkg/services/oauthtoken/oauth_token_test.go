@@ -25,6 +25,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/oauth2"
 )
 
@@ -291,11 +292,13 @@ func TestIntegration_TryTokenRefresh(t *testing.T) {
 			socialConnector := socialtest.NewMockSocialConnector(t)
 
 			store := db.InitTestDB(t)
+			serverLock, err := serverlock.ProvideService(store, tracing.InitializeTracerForTest(), setting.NewCfg())
+			require.NoError(t, err)
 
 			env := environment{
 				sessionService:  authtest.NewMockUserAuthTokenService(t),
 				authInfoService: &authinfotest.FakeService{},
-				serverLock:      serverlock.ProvideService(store, tracing.InitializeTracerForTest()),
+				serverLock:      serverLock,
 				socialConnector: socialConnector,
 				socialService: &socialtest.FakeSocialService{
 					ExpectedConnector: socialConnector,
@@ -571,10 +574,12 @@ func TestIntegration_TryTokenRefresh_WithExternalSessions(t *testing.T) {
 			socialConnector := socialtest.NewMockSocialConnector(t)
 
 			store := db.InitTestDB(t)
+			serverLock, err := serverlock.ProvideService(store, tracing.InitializeTracerForTest(), setting.NewCfg())
+			require.NoError(t, err)
 
 			env := environment{
 				sessionService:  authtest.NewMockUserAuthTokenService(t),
-				serverLock:      serverlock.ProvideService(store, tracing.InitializeTracerForTest()),
+				serverLock:      serverLock,
 				socialConnector: socialConnector,
 				socialService: &socialtest.FakeSocialService{
 					ExpectedConnector: socialConnector,
@@ -3,6 +3,7 @@ package queryhistory
 import (
 	"context"
 	"strconv"
+	"strings"
 
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/services/user"
@@ -305,16 +306,25 @@ func (s QueryHistoryService) unstarQuery(ctx context.Context, user *user.SignedI
 	return dto, nil
 }
 
-func (s QueryHistoryService) deleteStaleQueries(ctx context.Context, olderThan int64) (int, error) {
+func (s QueryHistoryService) deleteStaleQueries(ctx context.Context, olderThan int64, excludedOrgIDs []int64) (int, error) {
 	var rowsCount int64
 
 	err := s.store.WithDbSession(ctx, func(session *db.Session) error {
+		args := []any{strconv.FormatInt(olderThan, 10)}
+		excludedOrgs := ""
+		if len(excludedOrgIDs) > 0 {
+			excludedOrgs = " AND query_history.org_id NOT IN (?" + strings.Repeat(",?", len(excludedOrgIDs)-1) + ")"
+			for _, orgID := range excludedOrgIDs {
+				args = append(args, orgID)
+			}
+		}
+
 		uids_sql := `SELECT uid FROM (
 			SELECT uid FROM query_history
 			LEFT JOIN query_history_star
 			ON query_history_star.query_uid = query_history.uid
 			WHERE query_history_star.query_uid IS NULL
-			AND query_history.created_at <= ?
+			AND query_history.created_at <= ?` + excludedOrgs + `
 			ORDER BY query_history.id ASC
 			LIMIT 10000
 		) AS q`
@@ -327,12 +337,12 @@ func (s QueryHistoryService) deleteStaleQueries(ctx context.Context, olderThan i
 			FROM query_history
 			WHERE uid IN (` + uids_sql + `)`
 
-		_, err := session.Exec(details_sql, strconv.FormatInt(olderThan, 10))
+		_, err := session.Exec(append([]any{details_sql}, args...)...)
 		if err != nil {
 			return err
 		}
 
-		res, err := session.Exec(sql, strconv.FormatInt(olderThan, 10))
+		res, err := session.Exec(append([]any{sql}, args...)...)
 		if err != nil {
 			return err
 		}
@@ -17,7 +17,7 @@ func TestIntegrationDeleteStaleQueryFromQueryHistory(t *testing.T) {
 	testScenarioWithQueryInQueryHistory(t, "Stale query history can be deleted",
 		func(t *testing.T, sc scenarioContext) {
 			olderThan := sc.service.now().Unix() + 60
-			rowsDeleted, err := sc.service.DeleteStaleQueriesInQueryHistory(context.Background(), olderThan)
+			rowsDeleted, err := sc.service.DeleteStaleQueriesInQueryHistory(context.Background(), olderThan, nil)
 			require.NoError(t, err)
 			require.Equal(t, 1, rowsDeleted)
 		})
@@ -29,7 +29,15 @@ func TestIntegrationDeleteStaleQueryFromQueryHistory(t *testing.T) {
 			require.Equal(t, 200, resp.Status())
 
 			olderThan := sc.service.now().Unix() + 60
-			rowsDeleted, err := sc.service.DeleteStaleQueriesInQueryHistory(context.Background(), olderThan)
+			rowsDeleted, err := sc.service.DeleteStaleQueriesInQueryHistory(context.Background(), olderThan, nil)
+			require.NoError(t, err)
+			require.Equal(t, 0, rowsDeleted)
+		})
+
+	testScenarioWithQueryInQueryHistory(t, "Stale query history of excluded organizations is not deleted",
+		func(t *testing.T, sc scenarioContext) {
+			olderThan := sc.service.now().Unix() + 60
+			rowsDeleted, err := sc.service.DeleteStaleQueriesInQueryHistory(context.Background(), olderThan, []int64{testOrgID})
 			require.NoError(t, err)
 			require.Equal(t, 0, rowsDeleted)
 		})
@@ -37,7 +45,7 @@ func TestIntegrationDeleteStaleQueryFromQueryHistory(t *testing.T) {
 	testScenarioWithQueryInQueryHistory(t, "Not stale query history is not deleted",
 		func(t *testing.T, sc scenarioContext) {
 			olderThan := sc.service.now().Unix() - 60
-			rowsDeleted, err := sc.service.DeleteStaleQueriesInQueryHistory(context.Background(), olderThan)
+			rowsDeleted, err := sc.service.DeleteStaleQueriesInQueryHistory(context.Background(), olderThan, nil)
 			require.NoError(t, err)
 			require.Equal(t, 0, rowsDeleted)
 		})
@@ -55,7 +63,7 @@ func TestIntegrationDeleteStaleQueryFromQueryHistory(t *testing.T) {
 			require.NoError(t, err)
 
 			olderThan := sc.service.now().Unix() + 60
-			rowsDeleted, err := sc.service.DeleteStaleQueriesInQueryHistory(context.Background(), olderThan)
+			rowsDeleted, err := sc.service.DeleteStaleQueriesInQueryHistory(context.Background(), olderThan, nil)
 			require.NoError(t, err)
 			require.Equal(t, 1, rowsDeleted)
 
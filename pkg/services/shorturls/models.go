@@ -26,6 +26,8 @@ type ShortUrl struct {
 
 type DeleteShortUrlCommand struct {
 	OlderThan time.Time
+	// ExcludedOrgIDs are the organizations whose short URLs are left alone.
+	ExcludedOrgIDs []int64
 
 	NumDeleted int64
 }
@@ -89,4 +89,21 @@ func TestShortURLService(t *testing.T) {
 		require.True(t, shorturls.ErrShortURLNotFound.Is(err))
 		require.Nil(t, shortURL)
 	})
+
+	t.Run("Stale short URLs of excluded organizations are kept", func(t *testing.T) {
+		service := ShortURLService{SQLStore: &sqlStore{db: store}}
+		otherUser := *user
+		otherUser.OrgID = 2
+
+		staleShortURL, err := service.CreateShortURL(context.Background(), &otherUser, "mock/path?other=true")
+		require.NoError(t, err)
+
+		cmd := shorturls.DeleteShortUrlCommand{OlderThan: time.Unix(staleShortURL.CreatedAt, 0), ExcludedOrgIDs: []int64{2}}
+		err = service.DeleteStaleShortURLs(context.Background(), &cmd)
+		require.NoError(t, err)
+		require.Equal(t, int64(0), cmd.NumDeleted)
+
+		_, err = service.GetShortURLByUID(context.Background(), &otherUser, staleShortURL.Uid)
+		require.NoError(t, err)
+	})
 }
@@ -2,6 +2,7 @@ package shorturlimpl
 
 import (
 	"context"
+	"strings"
 
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/services/shorturls"
@@ -60,8 +61,15 @@ func (s sqlStore) Insert(ctx context.Context, shortURL *shorturls.ShortUrl) erro
 func (s sqlStore) Delete(ctx context.Context, cmd *shorturls.DeleteShortUrlCommand) error {
 	return s.db.WithTransactionalDbSession(ctx, func(session *db.Session) error {
 		var rawSql = "DELETE FROM short_url WHERE created_at <= ? AND (last_seen_at IS NULL OR last_seen_at = 0)"
+		args := []any{cmd.OlderThan.Unix()}
+		if len(cmd.ExcludedOrgIDs) > 0 {
+			rawSql += " AND org_id NOT IN (?" + strings.Repeat(",?", len(cmd.ExcludedOrgIDs)-1) + ")"
+			for _, orgID := range cmd.ExcludedOrgIDs {
+				args = append(args, orgID)
+			}
+		}
 
-		if result, err := session.Exec(rawSql, cmd.OlderThan.Unix()); err != nil {
+		if result, err := session.Exec(append([]any{rawSql}, args...)...); err != nil {
 			return err
 		} else if cmd.NumDeleted, err = result.RowsAffected(); err != nil {
 			return err
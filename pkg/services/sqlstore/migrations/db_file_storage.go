@@ -68,3 +68,57 @@ func addDbFileStorageMigration(mg *migrator.Migrator) {
 	mg.AddMigration("migrate contents column to mediumblob for MySQL", migrator.NewRawSQLMigration("").
 		Mysql("ALTER TABLE file MODIFY contents MEDIUMBLOB;"))
 }
+
+func addDbFileVersionsMigration(mg *migrator.Migrator) {
+	fileVersionTable := migrator.Table{
+		Name: "file_version",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "path", Type: migrator.DB_NVarchar, Length: 1024, Nullable: false},
+			{Name: "path_hash", Type: migrator.DB_NVarchar, Length: 64, Nullable: false},
+			{Name: "version", Type: migrator.DB_NVarchar, Length: 64, Nullable: false},
+
+			// sha256 hash of the contents, referencing the file_content table
+			{Name: "contents_hash", Type: migrator.DB_NVarchar, Length: 64, Nullable: false},
+			{Name: "size", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "mime_type", Type: migrator.DB_NVarchar, Length: 255, Nullable: false},
+
+			// JSON encoded properties of the file
+			{Name: "properties", Type: migrator.DB_Text, Nullable: false},
+			{Name: "created", Type: migrator.DB_DateTime, Nullable: false},
+
+			// set when the file is moved to the trash
+			{Name: "trashed", Type: migrator.DB_DateTime, Nullable: true},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"path_hash", "version"}, Type: migrator.UniqueIndex},
+			{Cols: []string{"contents_hash"}},
+			{Cols: []string{"trashed"}},
+		},
+	}
+
+	mg.AddMigration("create file_version table", migrator.NewAddTableMigration(fileVersionTable))
+	mg.AddMigration("file_version table idx: path_hash version", migrator.NewAddIndexMigration(fileVersionTable, fileVersionTable.Indices[0]))
+	mg.AddMigration("file_version table idx: contents_hash", migrator.NewAddIndexMigration(fileVersionTable, fileVersionTable.Indices[1]))
+	mg.AddMigration("file_version table idx: trashed", migrator.NewAddIndexMigration(fileVersionTable, fileVersionTable.Indices[2]))
+
+	fileContentTable := migrator.Table{
+		Name: "file_content",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "contents_hash", Type: migrator.DB_NVarchar, Length: 64, Nullable: false},
+			{Name: "contents", Type: migrator.DB_Blob, Nullable: false},
+			{Name: "size", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "created", Type: migrator.DB_DateTime, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"contents_hash"}, Type: migrator.UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create file_content table", migrator.NewAddTableMigration(fileContentTable))
+	mg.AddMigration("file_content table idx: contents_hash", migrator.NewAddIndexMigration(fileContentTable, fileContentTable.Indices[0]))
+
+	mg.AddMigration("migrate file_content contents column to mediumblob for MySQL", migrator.NewRawSQLMigration("").
+		Mysql("ALTER TABLE file_content MODIFY contents MEDIUMBLOB;"))
+}
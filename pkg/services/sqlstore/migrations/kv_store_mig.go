@@ -32,3 +32,10 @@ func addKVStoreMySQLValueTypeLongTextMigration(mg *Migrator) {
 	mg.AddMigration("alter kv_store.value to longtext", NewRawSQLMigration("").
 		Mysql("ALTER TABLE kv_store MODIFY value LONGTEXT NOT NULL;"))
 }
+
+// addKVStoreBlobKeyMigration adds the key of the value in the blob bucket, for the values stored in a bucket.
+func addKVStoreBlobKeyMigration(mg *Migrator) {
+	mg.AddMigration("add blob_key column to kv_store", NewAddColumnMigration(Table{Name: "kv_store"}, &Column{
+		Name: "blob_key", Type: DB_NVarchar, Length: 255, Nullable: true,
+	}))
+}
@@ -39,4 +39,27 @@ func addLoginAttemptMigrations(mg *Migrator) {
 		"username":   "username",
 		"ip_address": "ip_address",
 	})
+
+	mg.AddMigration("add index login_attempt.ip_address", NewAddIndexMigration(loginAttemptV2, &Index{
+		Cols: []string{"ip_address"},
+	}))
+
+	loginLockoutV1 := Table{
+		Name: "login_lockout",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "kind", Type: DB_NVarchar, Length: 16, Nullable: false},
+			{Name: "value", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "lockouts", Type: DB_Int, Nullable: false},
+			{Name: "locked_until", Type: DB_BigInt, Nullable: false},
+			{Name: "updated", Type: DB_BigInt, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"kind", "value"}, Type: UniqueIndex},
+			{Cols: []string{"locked_until"}},
+		},
+	}
+
+	mg.AddMigration("create login lockout table", NewAddTableMigration(loginLockoutV1))
+	addTableIndicesMigrations(mg, "v1", loginLockoutV1)
 }
@@ -141,4 +141,15 @@ func (oss *OSSMigrations) AddMigration(mg *Migrator) {
 	externalsession.AddMigration(mg)
 
 	accesscontrol.AddReceiverCreateScopeMigration(mg)
+
+	ualert.AddRecordingRuleSampleTable(mg)
+
+	ualert.AddRecordingRuleWriteQueueTable(mg)
+
+	addLivePipelineMigrations(mg)
+	addTOTPMigrations(mg)
+	addKVStoreBlobKeyMigration(mg)
+	addDbFileVersionsMigration(mg)
+
+	ualert.AddStateHistoryTables(mg)
 }
@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func addTOTPMigrations(mg *Migrator) {
+	userTOTPV1 := Table{
+		Name: "user_totp",
+		Columns: []*Column{
+			{Name: "user_id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true},
+			{Name: "secret", Type: DB_Text, Nullable: false},
+			{Name: "enabled", Type: DB_Bool, Nullable: false},
+			{Name: "last_step", Type: DB_BigInt, Nullable: false},
+			{Name: "created", Type: DB_BigInt, Nullable: false},
+			{Name: "updated", Type: DB_BigInt, Nullable: false},
+		},
+	}
+
+	mg.AddMigration("create user_totp table v1", NewAddTableMigration(userTOTPV1))
+
+	recoveryCodeV1 := Table{
+		Name: "user_totp_recovery_code",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "code_hash", Type: DB_NVarchar, Length: 64, Nullable: false},
+			{Name: "created", Type: DB_BigInt, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"user_id", "code_hash"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create user_totp_recovery_code table v1", NewAddTableMigration(recoveryCodeV1))
+	mg.AddMigration("add unique index user_totp_recovery_code.user_id-code_hash", NewAddIndexMigration(recoveryCodeV1, recoveryCodeV1.Indices[0]))
+
+	orgPolicyV1 := Table{
+		Name: "org_totp_policy",
+		Columns: []*Column{
+			{Name: "org_id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true},
+			{Name: "required", Type: DB_Bool, Nullable: false},
+			{Name: "updated", Type: DB_BigInt, Nullable: false},
+		},
+	}
+
+	mg.AddMigration("create org_totp_policy table v1", NewAddTableMigration(orgPolicyV1))
+}
@@ -0,0 +1,29 @@
+package ualert
+
+import "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+
+// AddRecordingRuleSampleTable adds the table that recording rules write their results to when they use the SQL target.
+func AddRecordingRuleSampleTable(mg *migrator.Migrator) {
+	sampleTable := migrator.Table{
+		Name: "alert_recording_rule_sample",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "metric", Type: migrator.DB_NVarchar, Length: DefaultFieldMaxLength, Nullable: false},
+			{Name: "labels", Type: migrator.DB_Text, Nullable: false},
+			{Name: "labels_hash", Type: migrator.DB_NVarchar, Length: DefaultFieldMaxLength, Nullable: false},
+			{Name: "timestamp", Type: migrator.DB_BigInt, Nullable: false}, // Unix milliseconds.
+			{Name: "value", Type: migrator.DB_Double, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"org_id", "metric", "timestamp"}},
+			{Cols: []string{"org_id", "labels_hash", "timestamp"}},
+			{Cols: []string{"timestamp"}},
+		},
+	}
+
+	mg.AddMigration("create alert_recording_rule_sample table", migrator.NewAddTableMigration(sampleTable))
+	mg.AddMigration("add index on org_id, metric, timestamp to alert_recording_rule_sample table", migrator.NewAddIndexMigration(sampleTable, sampleTable.Indices[0]))
+	mg.AddMigration("add index on org_id, labels_hash, timestamp to alert_recording_rule_sample table", migrator.NewAddIndexMigration(sampleTable, sampleTable.Indices[1]))
+	mg.AddMigration("add index on timestamp to alert_recording_rule_sample table", migrator.NewAddIndexMigration(sampleTable, sampleTable.Indices[2]))
+}
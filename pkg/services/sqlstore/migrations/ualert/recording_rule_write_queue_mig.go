@@ -0,0 +1,26 @@
+package ualert
+
+import "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+
+// AddRecordingRuleWriteQueueTable adds the table that keeps the recording rule writes that failed until they can be replayed.
+func AddRecordingRuleWriteQueueTable(mg *migrator.Migrator) {
+	queueTable := migrator.Table{
+		Name: "alert_recording_rule_write_queue",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "target", Type: migrator.DB_NVarchar, Length: DefaultFieldMaxLength, Nullable: false},
+			{Name: "org_id", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "metric", Type: migrator.DB_NVarchar, Length: DefaultFieldMaxLength, Nullable: false},
+			{Name: "timestamp", Type: migrator.DB_BigInt, Nullable: false}, // Unix milliseconds.
+			{Name: "extra_labels", Type: migrator.DB_Text, Nullable: false},
+			{Name: "frames", Type: migrator.DB_LongText, Nullable: false},
+			{Name: "created_at", Type: migrator.DB_BigInt, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"target", "id"}},
+		},
+	}
+
+	mg.AddMigration("create alert_recording_rule_write_queue table", migrator.NewAddTableMigration(queueTable))
+	mg.AddMigration("add index on target, id to alert_recording_rule_write_queue table", migrator.NewAddIndexMigration(queueTable, queueTable.Indices[0]))
+}
@@ -0,0 +1,54 @@
+package ualert
+
+import "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+
+// AddStateHistoryTables adds the tables of the SQL state history backend. The labels of the alert instances are
+// stored once per fingerprint in alert_state_history_label.
+func AddStateHistoryTables(mg *migrator.Migrator) {
+	historyTable := migrator.Table{
+		Name: "alert_state_history",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "rule_uid", Type: migrator.DB_NVarchar, Length: DefaultFieldMaxLength, Nullable: false},
+			{Name: "folder_uid", Type: migrator.DB_NVarchar, Length: DefaultFieldMaxLength, Nullable: false},
+			{Name: "dashboard_uid", Type: migrator.DB_NVarchar, Length: 40, Nullable: true},
+			{Name: "panel_id", Type: migrator.DB_BigInt, Nullable: true},
+			{Name: "fingerprint", Type: migrator.DB_NVarchar, Length: 16, Nullable: false},
+			{Name: "previous_state", Type: migrator.DB_NVarchar, Length: DefaultFieldMaxLength, Nullable: false},
+			{Name: "current_state", Type: migrator.DB_NVarchar, Length: DefaultFieldMaxLength, Nullable: false},
+			{Name: "error_message", Type: migrator.DB_Text, Nullable: true},
+			{Name: "state_values", Type: migrator.DB_Text, Nullable: false},
+			{Name: "evaluated_at", Type: migrator.DB_BigInt, Nullable: false}, // Unix milliseconds.
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"org_id", "evaluated_at"}},
+			{Cols: []string{"org_id", "rule_uid", "evaluated_at"}},
+			{Cols: []string{"org_id", "fingerprint"}},
+		},
+	}
+
+	mg.AddMigration("create alert_state_history table", migrator.NewAddTableMigration(historyTable))
+	mg.AddMigration("add index on org_id, evaluated_at to alert_state_history table", migrator.NewAddIndexMigration(historyTable, historyTable.Indices[0]))
+	mg.AddMigration("add index on org_id, rule_uid, evaluated_at to alert_state_history table", migrator.NewAddIndexMigration(historyTable, historyTable.Indices[1]))
+	mg.AddMigration("add index on org_id, fingerprint to alert_state_history table", migrator.NewAddIndexMigration(historyTable, historyTable.Indices[2]))
+
+	labelTable := migrator.Table{
+		Name: "alert_state_history_label",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "fingerprint", Type: migrator.DB_NVarchar, Length: 16, Nullable: false},
+			{Name: "name", Type: migrator.DB_NVarchar, Length: DefaultFieldMaxLength, Nullable: false},
+			{Name: "value", Type: migrator.DB_Text, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"org_id", "fingerprint", "name"}, Type: migrator.UniqueIndex},
+			{Cols: []string{"org_id", "name"}},
+		},
+	}
+
+	mg.AddMigration("create alert_state_history_label table", migrator.NewAddTableMigration(labelTable))
+	mg.AddMigration("add unique index on org_id, fingerprint, name to alert_state_history_label table", migrator.NewAddIndexMigration(labelTable, labelTable.Indices[0]))
+	mg.AddMigration("add index on org_id, name to alert_state_history_label table", migrator.NewAddIndexMigration(labelTable, labelTable.Indices[1]))
+}
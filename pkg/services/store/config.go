@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/grafana/grafana/pkg/infra/filestorage"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/setting"
 )
@@ -107,6 +108,9 @@ type StorageGitConfig struct {
 
 type StorageSQLConfig struct {
 	// SQLStorage will prefix all paths with orgId for isolation between orgs
+
+	// Versioning keeps the versions and the deleted files when set
+	Versioning *filestorage.VersioningOptions `json:"-"`
 }
 
 type StorageS3Config struct {
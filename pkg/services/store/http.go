@@ -7,9 +7,11 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/filestorage"
 	"github.com/grafana/grafana/pkg/middleware"
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
 	"github.com/grafana/grafana/pkg/util"
@@ -21,6 +23,9 @@ func UploadErrorToStatusCode(err error) int {
 	case errors.Is(err, ErrStorageNotFound):
 		return 404
 
+	case errors.Is(err, filestorage.ErrVersionNotFound), errors.Is(err, filestorage.ErrNotInTrash):
+		return 404
+
 	case errors.Is(err, ErrUnsupportedStorage):
 		return 400
 
@@ -46,6 +51,10 @@ func (s *standardStorageService) RegisterHTTPRoutes(storageRoute routing.RouteRe
 	storageRoute.Get("/list/*", routing.Wrap(s.list))
 	storageRoute.Get("/read/*", routing.Wrap(s.read))
 	storageRoute.Get("/options/*", routing.Wrap(s.getOptions))
+	storageRoute.Get("/versions/*", routing.Wrap(s.listVersions))
+	storageRoute.Get("/readVersion/*", routing.Wrap(s.readVersion))
+	storageRoute.Get("/trash/", routing.Wrap(s.listTrash))
+	storageRoute.Get("/trash/*", routing.Wrap(s.listTrash))
 
 	// Write paths
 	reqGrafanaAdmin := middleware.ReqGrafanaAdmin
@@ -54,6 +63,8 @@ func (s *standardStorageService) RegisterHTTPRoutes(storageRoute routing.RouteRe
 	storageRoute.Post("/upload", reqGrafanaAdmin, routing.Wrap(s.doUpload))
 	storageRoute.Post("/createFolder", reqGrafanaAdmin, routing.Wrap(s.doCreateFolder))
 	storageRoute.Post("/deleteFolder", reqGrafanaAdmin, routing.Wrap(s.doDeleteFolder))
+	storageRoute.Post("/restoreVersion/*", reqGrafanaAdmin, routing.Wrap(s.doRestoreVersion))
+	storageRoute.Post("/restoreFromTrash/*", reqGrafanaAdmin, routing.Wrap(s.doRestoreFromTrash))
 	storageRoute.Get("/config", reqGrafanaAdmin, routing.Wrap(s.getConfig))
 }
 
@@ -283,3 +294,112 @@ func (s *standardStorageService) getConfig(c *contextmodel.ReqContext) response.
 	}
 	return response.JSON(http.StatusOK, roots)
 }
+
+type fileVersionInfo struct {
+	Version    string            `json:"version"`
+	Path       string            `json:"path"`
+	MimeType   string            `json:"mimeType"`
+	Size       int64             `json:"size"`
+	Hash       string            `json:"hash"`
+	Properties map[string]string `json:"properties,omitempty"`
+	Created    time.Time         `json:"created"`
+}
+
+type trashedFileInfo struct {
+	fileVersionInfo
+	Deleted time.Time `json:"deleted"`
+}
+
+func newFileVersionInfo(v filestorage.FileVersion) fileVersionInfo {
+	return fileVersionInfo{
+		Version:    v.Version,
+		Path:       v.FullPath,
+		MimeType:   v.MimeType,
+		Size:       v.Size,
+		Hash:       v.ContentsHash,
+		Properties: v.Properties,
+		Created:    v.Created,
+	}
+}
+
+func (s *standardStorageService) listVersions(c *contextmodel.ReqContext) response.Response {
+	scope, path := getPathAndScope(c)
+	versions, err := s.ListVersions(c.Req.Context(), c.SignedInUser, scope+"/"+path)
+	if err != nil {
+		return response.Error(UploadErrorToStatusCode(err), "failed to list the versions: "+err.Error(), err)
+	}
+
+	rsp := make([]fileVersionInfo, 0, len(versions))
+	for _, v := range versions {
+		rsp = append(rsp, newFileVersionInfo(*v))
+	}
+	return response.JSON(http.StatusOK, rsp)
+}
+
+func (s *standardStorageService) readVersion(c *contextmodel.ReqContext) response.Response {
+	// full path is api/storage/readVersion/upload/example.jpg?version=..., but we only want the part after readVersion
+	scope, path := getPathAndScope(c)
+	version := c.Query("version")
+	if version == "" {
+		return response.Error(http.StatusBadRequest, "empty version", nil)
+	}
+
+	file, err := s.GetVersion(c.Req.Context(), c.SignedInUser, scope+"/"+path, version)
+	if err != nil {
+		return response.Error(UploadErrorToStatusCode(err), "failed to read the version: "+err.Error(), err)
+	}
+
+	// set the correct content type for svg
+	if strings.HasSuffix(path, ".svg") {
+		c.Resp.Header().Set("Content-Type", "image/svg+xml")
+	}
+	return response.Respond(http.StatusOK, file.Contents)
+}
+
+func (s *standardStorageService) doRestoreVersion(c *contextmodel.ReqContext) response.Response {
+	scope, path := getPathAndScope(c)
+	cmd := struct {
+		Version string `json:"version"`
+	}{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	if cmd.Version == "" {
+		return response.Error(http.StatusBadRequest, "empty version", nil)
+	}
+
+	if err := s.RestoreVersion(c.Req.Context(), c.SignedInUser, scope+"/"+path, cmd.Version); err != nil {
+		return response.Error(UploadErrorToStatusCode(err), "failed to restore the version: "+err.Error(), err)
+	}
+	return response.JSON(http.StatusOK, map[string]any{
+		"message": "Version restored",
+		"success": true,
+		"path":    scope + "/" + path,
+	})
+}
+
+func (s *standardStorageService) listTrash(c *contextmodel.ReqContext) response.Response {
+	params := web.Params(c.Req)
+	trashed, err := s.ListTrash(c.Req.Context(), c.SignedInUser, params["*"])
+	if err != nil {
+		return response.Error(UploadErrorToStatusCode(err), "failed to list the trash: "+err.Error(), err)
+	}
+
+	rsp := make([]trashedFileInfo, 0, len(trashed))
+	for _, f := range trashed {
+		rsp = append(rsp, trashedFileInfo{fileVersionInfo: newFileVersionInfo(f.FileVersion), Deleted: f.Deleted})
+	}
+	return response.JSON(http.StatusOK, rsp)
+}
+
+func (s *standardStorageService) doRestoreFromTrash(c *contextmodel.ReqContext) response.Response {
+	scope, path := getPathAndScope(c)
+	if err := s.RestoreFromTrash(c.Req.Context(), c.SignedInUser, scope+"/"+path); err != nil {
+		return response.Error(UploadErrorToStatusCode(err), "failed to restore the file: "+err.Error(), err)
+	}
+	return response.JSON(http.StatusOK, map[string]any{
+		"message": "File restored",
+		"success": true,
+		"path":    scope + "/" + path,
+	})
+}
@@ -8,11 +8,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/grafana/grafana/pkg/api/routing"
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/infra/filestorage"
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/serverlock"
 	"github.com/grafana/grafana/pkg/registry"
 	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
@@ -34,6 +37,8 @@ var ErrStorageNotFound = errors.New("storage not found")
 var ErrAccessDenied = errors.New("access denied")
 var ErrOnlyDashboardSaveSupported = errors.New("only dashboard save is currently supported")
 
+const trashPurgeInterval = time.Hour
+
 const RootPublicStatic = "public-static"
 const RootResources = "resources"
 const RootContent = "content"
@@ -76,6 +81,21 @@ type StorageService interface {
 
 	CreateFolder(ctx context.Context, user *user.SignedInUser, cmd *CreateFolderCmd) error
 
+	// ListVersions lists the versions of a file, the most recent first
+	ListVersions(ctx context.Context, user *user.SignedInUser, path string) ([]*filestorage.FileVersion, error)
+
+	// GetVersion reads a version of a file
+	GetVersion(ctx context.Context, user *user.SignedInUser, path string, version string) (*filestorage.File, error)
+
+	// RestoreVersion makes a version the current version of a file
+	RestoreVersion(ctx context.Context, user *user.SignedInUser, path string, version string) error
+
+	// ListTrash lists the deleted files of a folder and its subfolders
+	ListTrash(ctx context.Context, user *user.SignedInUser, folderPath string) ([]*filestorage.TrashedFile, error)
+
+	// RestoreFromTrash restores a deleted file
+	RestoreFromTrash(ctx context.Context, user *user.SignedInUser, path string) error
+
 	validateUploadRequest(ctx context.Context, user *user.SignedInUser, req *UploadRequest, storagePath string) validationResult
 
 	// sanitizeUploadRequest sanitizes the upload request and converts it into a command accepted by the FileStorage API
@@ -89,6 +109,22 @@ type standardStorageService struct {
 	authService  storageAuthService
 	quotaService quota.Service
 	systemUsers  SystemUsersFilterProvider
+	versioning   *filestorage.VersioningOptions
+	serverLock   *serverlock.ServerLockService
+}
+
+// newSQLConfig returns the configuration of the SQL storages, with the versioning options when the versioning is enabled.
+func newSQLConfig(cfg *setting.Cfg) *StorageSQLConfig {
+	if cfg == nil || !cfg.Storage.VersioningEnabled {
+		return &StorageSQLConfig{}
+	}
+
+	return &StorageSQLConfig{
+		Versioning: &filestorage.VersioningOptions{
+			MaxVersions:    cfg.Storage.MaxVersions,
+			TrashRetention: cfg.Storage.TrashRetention,
+		},
+	}
 }
 
 func ProvideService(
@@ -97,6 +133,7 @@ func ProvideService(
 	cfg *setting.Cfg,
 	quotaService quota.Service,
 	systemUsersService SystemUsers,
+	serverLock *serverlock.ServerLockService,
 ) (StorageService, error) {
 	settings, err := LoadStorageConfig(cfg, features)
 	if err != nil {
@@ -170,19 +207,19 @@ func ProvideService(
 		storages = append(storages,
 			newSQLStorage(RootStorageMeta{
 				Builtin: true,
-			}, RootContent, "Content", "Content root", &StorageSQLConfig{}, sql, orgId, false))
+			}, RootContent, "Content", "Content root", newSQLConfig(cfg), sql, orgId, false))
 
 		// Custom upload files
 		storages = append(storages,
 			newSQLStorage(RootStorageMeta{
 				Builtin: true,
-			}, RootResources, "Resources", "Upload custom resource files", &StorageSQLConfig{}, sql, orgId, false))
+			}, RootResources, "Resources", "Upload custom resource files", newSQLConfig(cfg), sql, orgId, false))
 
 		// System settings
 		storages = append(storages,
 			newSQLStorage(RootStorageMeta{
 				Builtin: true,
-			}, RootSystem, "System", "Grafana system storage", &StorageSQLConfig{}, sql, orgId, false))
+			}, RootSystem, "System", "Grafana system storage", newSQLConfig(cfg), sql, orgId, false))
 
 		return storages
 	}
@@ -256,6 +293,7 @@ func ProvideService(
 	s := newStandardStorageService(sql, globalRoots, initializeOrgStorages, authService, cfg, systemUsersService)
 	s.quotaService = quotaService
 	s.cfg = settings
+	s.serverLock = serverLock
 
 	defaultLimits, err := readQuotaConfig(cfg)
 	if err != nil {
@@ -321,12 +359,41 @@ func newStandardStorageService(
 		tree:        res,
 		authService: authService,
 		systemUsers: systemUsers,
+		versioning:  newSQLConfig(cfg).Versioning,
 	}
 }
 
 func (s *standardStorageService) Run(ctx context.Context) error {
 	grafanaStorageLogger.Info("Storage starting")
-	return nil
+	if s.versioning == nil || s.versioning.TrashRetention <= 0 {
+		return nil
+	}
+
+	// the trash of all the SQL storages is purged from the root of the database storage
+	store := filestorage.NewVersionedDbStorage(grafanaStorageLogger, s.sql, nil, filestorage.Delimiter, *s.versioning)
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+	for {
+		s.purgeTrash(ctx, store)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeTrash purges the trash once per interval, from a single instance.
+func (s *standardStorageService) purgeTrash(ctx context.Context, store filestorage.VersionedFileStorage) {
+	err := s.serverLock.LockAndExecute(ctx, "purge storage trash", trashPurgeInterval, func(ctx context.Context) {
+		if _, err := store.PurgeTrash(ctx); err != nil {
+			grafanaStorageLogger.Error("Failed to purge the trash", "error", err)
+		}
+	})
+	if err != nil {
+		grafanaStorageLogger.Error("Failed to lock and execute the purge of the trash", "error", err)
+	}
 }
 
 func getOrgId(user *user.SignedInUser) int64 {
@@ -545,6 +612,125 @@ func (s *standardStorageService) Delete(ctx context.Context, user *user.SignedIn
 	return nil
 }
 
+// getVersionedRoot returns the versioned storage of a path, and the path in the storage. Only the SQL storages
+// with versioning enabled keep versions.
+func (s *standardStorageService) getVersionedRoot(user *user.SignedInUser, path string, write bool) (filestorage.VersionedFileStorage, string, error) {
+	root, storagePath := s.tree.getRoot(getOrgId(user), path)
+	if root == nil {
+		return nil, "", ErrStorageNotFound
+	}
+
+	store, ok := root.Store().(filestorage.VersionedFileStorage)
+	if !ok || (write && root.Meta().ReadOnly) {
+		return nil, "", ErrUnsupportedStorage
+	}
+	return store, storagePath, nil
+}
+
+func (s *standardStorageService) ListVersions(ctx context.Context, user *user.SignedInUser, path string) ([]*filestorage.FileVersion, error) {
+	guardian := s.authService.newGuardian(ctx, user, getFirstSegment(path))
+	if !guardian.canView(path) {
+		return nil, ErrAccessDenied
+	}
+
+	store, storagePath, err := s.getVersionedRoot(user, path, false)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := store.ListVersions(ctx, storagePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range versions {
+		v.FullPath = path
+	}
+	return versions, nil
+}
+
+func (s *standardStorageService) GetVersion(ctx context.Context, user *user.SignedInUser, path string, version string) (*filestorage.File, error) {
+	guardian := s.authService.newGuardian(ctx, user, getFirstSegment(path))
+	if !guardian.canView(path) {
+		return nil, ErrAccessDenied
+	}
+
+	store, storagePath, err := s.getVersionedRoot(user, path, false)
+	if err != nil {
+		return nil, err
+	}
+
+	file, found, err := store.GetVersion(ctx, storagePath, version)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, filestorage.ErrVersionNotFound
+	}
+	return file, nil
+}
+
+func (s *standardStorageService) RestoreVersion(ctx context.Context, user *user.SignedInUser, path string, version string) error {
+	guardian := s.authService.newGuardian(ctx, user, getFirstSegment(path))
+	if !guardian.canWrite(path) {
+		return ErrAccessDenied
+	}
+
+	store, storagePath, err := s.getVersionedRoot(user, path, true)
+	if err != nil {
+		return err
+	}
+
+	grafanaStorageLogger.Info("Restoring a file version", "path", path, "version", version)
+	return store.RestoreVersion(ctx, storagePath, version)
+}
+
+func (s *standardStorageService) ListTrash(ctx context.Context, user *user.SignedInUser, folderPath string) ([]*filestorage.TrashedFile, error) {
+	guardian := s.authService.newGuardian(ctx, user, getFirstSegment(folderPath))
+
+	store, storagePath, err := s.getVersionedRoot(user, folderPath, false)
+	if err != nil {
+		return nil, err
+	}
+	if storagePath == "" {
+		storagePath = filestorage.Delimiter
+	}
+
+	trashed, err := store.ListTrash(ctx, storagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// the paths of the storage are relative to the storage root
+	rootPath := strings.TrimSuffix(strings.TrimSuffix(folderPath, filestorage.Delimiter), strings.TrimSuffix(storagePath, filestorage.Delimiter))
+	files := make([]*filestorage.TrashedFile, 0, len(trashed))
+	for _, f := range trashed {
+		f.FullPath = rootPath + f.FullPath
+		if guardian.canView(f.FullPath) {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+func (s *standardStorageService) RestoreFromTrash(ctx context.Context, user *user.SignedInUser, path string) error {
+	if err := s.checkFileQuota(ctx, path); err != nil {
+		return err
+	}
+
+	guardian := s.authService.newGuardian(ctx, user, getFirstSegment(path))
+	if !guardian.canWrite(path) {
+		return ErrAccessDenied
+	}
+
+	store, storagePath, err := s.getVersionedRoot(user, path, true)
+	if err != nil {
+		return err
+	}
+
+	grafanaStorageLogger.Info("Restoring a file from the trash", "path", path)
+	return store.RestoreFromTrash(ctx, storagePath)
+}
+
 func (s *standardStorageService) write(ctx context.Context, user *user.SignedInUser, req *WriteValueRequest) (*WriteValueResponse, error) {
 	guardian := s.authService.newGuardian(ctx, user, getFirstSegment(req.Path))
 	if !guardian.canWrite(req.Path) {
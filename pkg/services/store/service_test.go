@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/experimental"
 	"github.com/stretchr/testify/mock"
@@ -15,6 +16,8 @@ import (
 
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/infra/filestorage"
+	"github.com/grafana/grafana/pkg/infra/serverlock"
+	"github.com/grafana/grafana/pkg/infra/tracing"
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/quota/quotatest"
 	"github.com/grafana/grafana/pkg/services/user"
@@ -557,3 +560,133 @@ func TestShadowingExistingFolderByNestedContentRoot(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, 1, rowLen) // just a single "nested" folder
 }
+
+func TestVersionsAndTrash(t *testing.T) {
+	ctx := context.Background()
+	testDB := db.InitTestDB(t)
+	versioning := &filestorage.VersioningOptions{MaxVersions: 10}
+	sqlStorage := newSQLStorage(RootStorageMeta{}, RootResources, "Resources", "dummy descr", &StorageSQLConfig{Versioning: versioning}, testDB, 1, false)
+
+	newStore := func(authService storageAuthService) *standardStorageService {
+		store := newStandardStorageService(testDB, []storageRuntime{sqlStorage}, func(orgId int64) []storageRuntime {
+			return make([]storageRuntime, 0)
+		}, authService, cfg, nil)
+		store.quotaService = quotatest.New(false, nil)
+		return store
+	}
+	store := newStore(allowAllAuthService)
+
+	for _, contents := range []string{"first", "second"} {
+		err := sqlStorage.Store().Upsert(ctx, &filestorage.UpsertFileCommand{Path: "/img/a.txt", MimeType: "text/plain", Contents: []byte(contents)})
+		require.NoError(t, err)
+	}
+
+	versions, err := store.ListVersions(ctx, dummyUser, "resources/img/a.txt")
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	require.Equal(t, "resources/img/a.txt", versions[1].FullPath)
+
+	file, err := store.GetVersion(ctx, dummyUser, "resources/img/a.txt", versions[1].Version)
+	require.NoError(t, err)
+	require.Equal(t, "first", string(file.Contents))
+
+	_, err = store.GetVersion(ctx, dummyUser, "resources/img/a.txt", "unknown")
+	require.ErrorIs(t, err, filestorage.ErrVersionNotFound)
+
+	require.NoError(t, store.RestoreVersion(ctx, dummyUser, "resources/img/a.txt", versions[1].Version))
+	file, err = store.Read(ctx, dummyUser, "resources/img/a.txt")
+	require.NoError(t, err)
+	require.Equal(t, "first", string(file.Contents))
+
+	require.NoError(t, store.Delete(ctx, dummyUser, "resources/img/a.txt"))
+	for _, folder := range []string{"resources", "resources/img"} {
+		trashed, err := store.ListTrash(ctx, dummyUser, folder)
+		require.NoError(t, err)
+		require.Len(t, trashed, 1)
+		require.Equal(t, "resources/img/a.txt", trashed[0].FullPath)
+	}
+
+	t.Run("users without permissions can't read or restore versions", func(t *testing.T) {
+		store := newStore(denyAllAuthService)
+
+		_, err := store.ListVersions(ctx, dummyUser, "resources/img/a.txt")
+		require.ErrorIs(t, err, ErrAccessDenied)
+		_, err = store.GetVersion(ctx, dummyUser, "resources/img/a.txt", versions[1].Version)
+		require.ErrorIs(t, err, ErrAccessDenied)
+		require.ErrorIs(t, store.RestoreVersion(ctx, dummyUser, "resources/img/a.txt", versions[1].Version), ErrAccessDenied)
+		require.ErrorIs(t, store.RestoreFromTrash(ctx, dummyUser, "resources/img/a.txt"), ErrAccessDenied)
+
+		trashed, err := store.ListTrash(ctx, dummyUser, "resources")
+		require.NoError(t, err)
+		require.Empty(t, trashed)
+	})
+
+	t.Run("viewers can't restore files", func(t *testing.T) {
+		store := newStore(newStaticStorageAuthService(func(ctx context.Context, user *user.SignedInUser, storageName string) map[string]filestorage.PathFilter {
+			return map[string]filestorage.PathFilter{
+				ActionFilesRead:   allowAllPathFilter,
+				ActionFilesWrite:  denyAllPathFilter,
+				ActionFilesDelete: denyAllPathFilter,
+			}
+		}))
+
+		trashed, err := store.ListTrash(ctx, dummyUser, "resources")
+		require.NoError(t, err)
+		require.Len(t, trashed, 1)
+		require.ErrorIs(t, store.RestoreFromTrash(ctx, dummyUser, "resources/img/a.txt"), ErrAccessDenied)
+	})
+
+	require.NoError(t, store.RestoreFromTrash(ctx, dummyUser, "resources/img/a.txt"))
+	file, err = store.Read(ctx, dummyUser, "resources/img/a.txt")
+	require.NoError(t, err)
+	require.Equal(t, "first", string(file.Contents))
+	require.ErrorIs(t, store.RestoreFromTrash(ctx, dummyUser, "resources/img/a.txt"), filestorage.ErrNotInTrash)
+
+	t.Run("storages without versioning don't support versions", func(t *testing.T) {
+		store := newStandardStorageService(testDB, []storageRuntime{publicStaticFilesStorage}, func(orgId int64) []storageRuntime {
+			return make([]storageRuntime, 0)
+		}, allowAllAuthService, cfg, nil)
+
+		_, err := store.ListVersions(ctx, dummyUser, "public/maps/countries.geojson")
+		require.ErrorIs(t, err, ErrUnsupportedStorage)
+	})
+}
+
+func TestPurgeTrashFromSingleInstance(t *testing.T) {
+	ctx := context.Background()
+	testDB := db.InitTestDB(t)
+	versioning := filestorage.VersioningOptions{TrashRetention: time.Nanosecond}
+	sqlStorage := newSQLStorage(RootStorageMeta{}, RootResources, "Resources", "dummy descr", &StorageSQLConfig{Versioning: &versioning}, testDB, 1, false)
+	serverLock, err := serverlock.ProvideService(testDB, tracing.InitializeTracerForTest(), setting.NewCfg())
+	require.NoError(t, err)
+
+	deleteFile := func(path string) {
+		t.Helper()
+		require.NoError(t, sqlStorage.Store().Upsert(ctx, &filestorage.UpsertFileCommand{Path: path, MimeType: "text/plain", Contents: []byte(path)}))
+		require.NoError(t, sqlStorage.Store().Delete(ctx, path))
+	}
+	trashed := func() int {
+		t.Helper()
+		files, err := sqlStorage.Store().(filestorage.VersionedFileStorage).ListTrash(ctx, filestorage.Delimiter)
+		require.NoError(t, err)
+		return len(files)
+	}
+	newInstance := func() (*standardStorageService, filestorage.VersionedFileStorage) {
+		store := newStandardStorageService(testDB, []storageRuntime{sqlStorage}, func(orgId int64) []storageRuntime {
+			return make([]storageRuntime, 0)
+		}, allowAllAuthService, cfg, nil)
+		store.serverLock = serverLock
+		return store, filestorage.NewVersionedDbStorage(grafanaStorageLogger, testDB, nil, filestorage.Delimiter, versioning)
+	}
+
+	deleteFile("/a.txt")
+	instance1, store1 := newInstance()
+	instance1.purgeTrash(ctx, store1)
+	require.Equal(t, 0, trashed())
+
+	// the other instances don't purge the trash until the next interval
+	deleteFile("/b.txt")
+	instance2, store2 := newInstance()
+	instance2.purgeTrash(ctx, store2)
+	require.Equal(t, 1, trashed())
+}
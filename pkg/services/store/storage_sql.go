@@ -53,9 +53,15 @@ func newSQLStorage(meta RootStorageMeta, prefix string, name string, descr strin
 	}
 
 	s := &rootStorageSQL{}
-	s.store = filestorage.NewDbStorage(
-		grafanaStorageLogger,
-		sql, nil, getDbStoragePathPrefix(orgId, prefix))
+	if cfg.Versioning != nil {
+		s.store = filestorage.NewVersionedDbStorage(
+			grafanaStorageLogger,
+			sql, nil, getDbStoragePathPrefix(orgId, prefix), *cfg.Versioning)
+	} else {
+		s.store = filestorage.NewDbStorage(
+			grafanaStorageLogger,
+			sql, nil, getDbStoragePathPrefix(orgId, prefix))
+	}
 
 	meta.Ready = true
 	s.meta = meta
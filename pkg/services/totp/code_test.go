@@ -0,0 +1,69 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the SHA1 secret of the test vectors of RFC 6238.
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestGenerateCode(t *testing.T) {
+	// The RFC 6238 test vectors, truncated to 6 digits.
+	for unix, expected := range map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	} {
+		code, err := GenerateCode(rfcSecret, Step(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, expected, code, unix)
+	}
+}
+
+func TestValidateCode(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+
+	code, err := GenerateCode(secret, Step(now))
+	require.NoError(t, err)
+
+	step, ok := ValidateCode(secret, code, now, 0)
+	require.True(t, ok)
+	assert.Equal(t, Step(now), step)
+
+	t.Run("accepts codes of the adjacent periods", func(t *testing.T) {
+		_, ok := ValidateCode(secret, code, now.Add(30*time.Second), 0)
+		assert.True(t, ok)
+		_, ok = ValidateCode(secret, code, now.Add(-30*time.Second), 0)
+		assert.True(t, ok)
+		_, ok = ValidateCode(secret, code, now.Add(90*time.Second), 0)
+		assert.False(t, ok)
+	})
+
+	t.Run("rejects codes already used", func(t *testing.T) {
+		_, ok := ValidateCode(secret, code, now, step)
+		assert.False(t, ok)
+	})
+
+	t.Run("rejects malformed codes", func(t *testing.T) {
+		_, ok := ValidateCode(secret, "12345", now, 0)
+		assert.False(t, ok)
+		_, ok = ValidateCode(secret, "abcdef", now, 0)
+		assert.False(t, ok)
+	})
+}
+
+func TestKeyURI(t *testing.T) {
+	assert.Equal(t,
+		"otpauth://totp/Grafana:admin@example.com?algorithm=SHA1&digits=6&issuer=Grafana&period=30&secret=JBSWY3DPEHPK3PXP",
+		KeyURI("Grafana", "admin@example.com", "JBSWY3DPEHPK3PXP"))
+}
@@ -0,0 +1,61 @@
+package totp
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+	"github.com/grafana/grafana/pkg/services/authn"
+)
+
+var (
+	ErrNotEnrolled     = errutil.BadRequest("totp.not-enrolled", errutil.WithPublicMessage("Two-factor authentication is not enabled"))
+	ErrAlreadyEnrolled = errutil.Conflict("totp.already-enrolled", errutil.WithPublicMessage("Two-factor authentication is already enabled"))
+	ErrInvalidCode     = errutil.BadRequest("totp.invalid-code", errutil.WithPublicMessage("Invalid two-factor authentication code"))
+	ErrRequiredByOrg   = errutil.Forbidden("totp.required-by-org", errutil.WithPublicMessage("Two-factor authentication is required by your organization"))
+)
+
+type Service interface {
+	// GetStatus returns the two-factor authentication status of a user.
+	GetStatus(ctx context.Context, userID int64) (*Status, error)
+	// Enroll starts the enrollment of a user and returns the secret to add to an authenticator app.
+	// The enrollment is completed by Confirm.
+	Enroll(ctx context.Context, userID int64, account string) (*Enrollment, error)
+	// Confirm completes the enrollment of a user with a code of the authenticator app and returns
+	// new recovery codes.
+	Confirm(ctx context.Context, userID int64, code string) ([]string, error)
+	// Disable disables two-factor authentication for a user after checking a code.
+	Disable(ctx context.Context, userID int64, code string) error
+	// RegenerateRecoveryCodes replaces the recovery codes of a user after checking a code.
+	RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) ([]string, error)
+	// Reset removes the second factor of a user, for users who lost both their authenticator app
+	// and their recovery codes.
+	Reset(ctx context.Context, userID int64) error
+	// Verify checks a code of the authenticator app, or a recovery code, of a user. Recovery codes
+	// can only be used once.
+	Verify(ctx context.Context, userID int64, code string) error
+	// IsRequired returns true if an organization of the user requires two-factor authentication.
+	IsRequired(ctx context.Context, userID int64) (bool, error)
+	GetOrgPolicy(ctx context.Context, orgID int64) (*OrgPolicy, error)
+	SetOrgPolicy(ctx context.Context, policy OrgPolicy) error
+	// LoginHook is an authn.PostAuthHookFn requiring a second factor from the users authenticated by
+	// the password of a local account.
+	LoginHook(ctx context.Context, id *authn.Identity, r *authn.Request) error
+}
+
+type Status struct {
+	Enabled bool `json:"enabled"`
+	// Required is true if an organization of the user requires two-factor authentication.
+	Required               bool  `json:"required"`
+	RecoveryCodesRemaining int64 `json:"recoveryCodesRemaining"`
+}
+
+type Enrollment struct {
+	Secret string `json:"secret"`
+	// URL is the otpauth:// URL of the secret, to show as a QR code.
+	URL string `json:"url"`
+}
+
+type OrgPolicy struct {
+	OrgID    int64 `json:"orgId"`
+	Required bool  `json:"required"`
+}
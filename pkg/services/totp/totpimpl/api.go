@@ -0,0 +1,175 @@
+package totpimpl
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/grafana/authlib/claims"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/totp"
+	"github.com/grafana/grafana/pkg/util"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+type codeCommand struct {
+	Code string `json:"code" binding:"Required"`
+}
+
+type loginSecretCommand struct {
+	User string `json:"user" binding:"Required"`
+}
+
+type orgPolicyCommand struct {
+	Required bool `json:"required"`
+}
+
+func (s *Service) registerAPIEndpoints() {
+	s.routeRegister.Group("/api/user/totp", func(r routing.RouteRegister) {
+		r.Get("/", middleware.ReqSignedInNoAnonymous, routing.Wrap(s.getStatusHandler))
+		r.Post("/enroll", middleware.ReqSignedInNoAnonymous, routing.Wrap(s.enrollHandler))
+		r.Post("/confirm", middleware.ReqSignedInNoAnonymous, routing.Wrap(s.confirmHandler))
+		r.Post("/recovery-codes", middleware.ReqSignedInNoAnonymous, routing.Wrap(s.regenerateRecoveryCodesHandler))
+		r.Post("/disable", middleware.ReqSignedInNoAnonymous, routing.Wrap(s.disableHandler))
+	})
+	s.routeRegister.Post("/api/login/totp/secret", routing.Wrap(s.loginSecretHandler))
+	s.routeRegister.Delete("/api/admin/users/:id/totp", middleware.ReqGrafanaAdmin, routing.Wrap(s.resetHandler))
+	s.routeRegister.Group("/api/org/totp-policy", func(r routing.RouteRegister) {
+		r.Get("/", middleware.ReqOrgAdmin, routing.Wrap(s.getOrgPolicyHandler))
+		r.Put("/", middleware.ReqOrgAdmin, routing.Wrap(s.setOrgPolicyHandler))
+	})
+}
+
+// userID returns the ID of the signed in user. Only users have a second factor.
+func userID(c *contextmodel.ReqContext) (int64, response.Response) {
+	if !c.SignedInUser.IsIdentityType(claims.TypeUser) {
+		return 0, response.Error(http.StatusForbidden, "Two-factor authentication is only available to users", nil)
+	}
+	id, err := c.SignedInUser.GetInternalID()
+	if err != nil {
+		return 0, response.Error(http.StatusInternalServerError, "Failed to get user ID", err)
+	}
+	return id, nil
+}
+
+func (s *Service) getStatusHandler(c *contextmodel.ReqContext) response.Response {
+	id, errResp := userID(c)
+	if errResp != nil {
+		return errResp
+	}
+	status, err := s.GetStatus(c.Req.Context(), id)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to get two-factor authentication status", err)
+	}
+	return response.JSON(http.StatusOK, status)
+}
+
+func (s *Service) enrollHandler(c *contextmodel.ReqContext) response.Response {
+	id, errResp := userID(c)
+	if errResp != nil {
+		return errResp
+	}
+	enrollment, err := s.Enroll(c.Req.Context(), id, c.SignedInUser.GetLogin())
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to enroll two-factor authentication", err)
+	}
+	return response.JSON(http.StatusOK, enrollment)
+}
+
+func (s *Service) confirmHandler(c *contextmodel.ReqContext) response.Response {
+	id, errResp := userID(c)
+	if errResp != nil {
+		return errResp
+	}
+	cmd := codeCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	codes, err := s.Confirm(c.Req.Context(), id, cmd.Code)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to confirm two-factor authentication", err)
+	}
+	return response.JSON(http.StatusOK, util.DynMap{"recoveryCodes": codes})
+}
+
+func (s *Service) regenerateRecoveryCodesHandler(c *contextmodel.ReqContext) response.Response {
+	id, errResp := userID(c)
+	if errResp != nil {
+		return errResp
+	}
+	cmd := codeCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	codes, err := s.RegenerateRecoveryCodes(c.Req.Context(), id, cmd.Code)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to generate recovery codes", err)
+	}
+	return response.JSON(http.StatusOK, util.DynMap{"recoveryCodes": codes})
+}
+
+func (s *Service) disableHandler(c *contextmodel.ReqContext) response.Response {
+	id, errResp := userID(c)
+	if errResp != nil {
+		return errResp
+	}
+	cmd := codeCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	if err := s.Disable(c.Req.Context(), id, cmd.Code); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to disable two-factor authentication", err)
+	}
+	return response.Success("Two-factor authentication disabled")
+}
+
+// loginSecretHandler returns a new secret for the users required to enroll a second factor on login.
+// The secret is only stored once the user logs in with it.
+func (s *Service) loginSecretHandler(c *contextmodel.ReqContext) response.Response {
+	cmd := loginSecretCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to generate secret", err)
+	}
+	return response.JSON(http.StatusOK, totp.Enrollment{
+		Secret: secret,
+		URL:    totp.KeyURI(s.cfg.TOTPAuth.Issuer, cmd.User, secret),
+	})
+}
+
+func (s *Service) resetHandler(c *contextmodel.ReqContext) response.Response {
+	id, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "id is invalid", err)
+	}
+	if err := s.Reset(c.Req.Context(), id); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to reset two-factor authentication", err)
+	}
+	s.log.FromContext(c.Req.Context()).Info("Second factor reset", "userId", id, "by", c.SignedInUser.GetID())
+	return response.Success("Two-factor authentication reset")
+}
+
+func (s *Service) getOrgPolicyHandler(c *contextmodel.ReqContext) response.Response {
+	policy, err := s.GetOrgPolicy(c.Req.Context(), c.SignedInUser.GetOrgID())
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get two-factor authentication policy", err)
+	}
+	return response.JSON(http.StatusOK, policy)
+}
+
+func (s *Service) setOrgPolicyHandler(c *contextmodel.ReqContext) response.Response {
+	cmd := orgPolicyCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	policy := totp.OrgPolicy{OrgID: c.SignedInUser.GetOrgID(), Required: cmd.Required}
+	if err := s.SetOrgPolicy(c.Req.Context(), policy); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to update two-factor authentication policy", err)
+	}
+	return response.JSON(http.StatusOK, policy)
+}
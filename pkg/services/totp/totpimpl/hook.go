@@ -0,0 +1,107 @@
+package totpimpl
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/loginattempt"
+	"github.com/grafana/grafana/pkg/services/totp"
+)
+
+var (
+	errCodeRequired       = errutil.Unauthorized("totp.code-required", errutil.WithPublicMessage("Two-factor authentication code required"))
+	errEnrollmentRequired = errutil.Unauthorized("totp.enrollment-required", errutil.WithPublicMessage("Two-factor authentication enrollment required"))
+	errInvalidLoginCode   = errutil.Unauthorized("totp.invalid-login-code", errutil.WithPublicMessage("Invalid two-factor authentication code"))
+	errBasicAuth          = errutil.Unauthorized("totp.basic-auth", errutil.WithPublicMessage("Basic authentication is not supported with two-factor authentication, use a service account token"))
+)
+
+// LoginHook requires a second factor from the users authenticated by the password of a local account.
+//
+// Users enrolled log in with a code of their authenticator app, or a recovery code. Users required to
+// enroll by an organization enroll when logging in, with a secret from POST /api/login/totp/secret and a
+// code of this secret. Basic auth requests of these users are rejected.
+func (s *Service) LoginHook(ctx context.Context, id *authn.Identity, r *authn.Request) error {
+	// Sessions were created by a login that already checked the second factor.
+	if id.AuthenticatedBy != login.PasswordAuthModule || id.SessionToken != nil {
+		return nil
+	}
+
+	userID, err := id.GetInternalID()
+	if err != nil {
+		return err
+	}
+
+	t, err := s.store.Get(ctx, userID)
+	if err != nil && !errors.Is(err, totp.ErrNotEnrolled) {
+		return err
+	}
+	enrolled := t != nil && t.Enabled
+	isLogin := r.GetMeta(authn.MetaKeyIsLogin) != ""
+
+	if !enrolled {
+		required, err := s.store.IsRequired(ctx, userID)
+		if err != nil || !required {
+			return err
+		}
+		if !isLogin {
+			return errEnrollmentRequired.Errorf("user %d must enroll a second factor", userID)
+		}
+		return s.enrollOnLogin(ctx, userID, r)
+	}
+
+	if !isLogin {
+		return errBasicAuth.Errorf("user %d has a second factor", userID)
+	}
+
+	code := r.GetMeta(authn.MetaKeyTOTPCode)
+	if code == "" {
+		return errCodeRequired.Errorf("user %d has a second factor", userID)
+	}
+	if err := s.Verify(ctx, userID, code); err != nil {
+		if errors.Is(err, totp.ErrInvalidCode) {
+			s.addLoginAttempt(ctx, r)
+			return errInvalidLoginCode.Errorf("invalid code: %w", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// enrollOnLogin enrolls a user with the secret of the login request, once the user proved that the
+// secret was added to an authenticator app with a code. The recovery codes are added to the metadata
+// of the request, for the login response.
+func (s *Service) enrollOnLogin(ctx context.Context, userID int64, r *authn.Request) error {
+	secret, code := r.GetMeta(authn.MetaKeyTOTPSecret), r.GetMeta(authn.MetaKeyTOTPCode)
+	if secret == "" || code == "" {
+		return errEnrollmentRequired.Errorf("user %d must enroll a second factor", userID)
+	}
+	step, ok := totp.ValidateCode(secret, code, s.now(), 0)
+	if !ok {
+		s.addLoginAttempt(ctx, r)
+		return errInvalidLoginCode.Errorf("invalid enrollment code")
+	}
+	if err := s.save(ctx, userID, secret, true, step); err != nil {
+		return err
+	}
+	codes, err := s.generateRecoveryCodes(ctx, userID)
+	if err != nil {
+		return err
+	}
+	r.SetMeta(authn.MetaKeyTOTPRecoveryCodes, strings.Join(codes, " "))
+	s.log.FromContext(ctx).Info("User enrolled a second factor on login", "userId", userID)
+	return nil
+}
+
+func (s *Service) addLoginAttempt(ctx context.Context, r *authn.Request) {
+	addr := ""
+	if r.HTTPRequest != nil {
+		addr = loginattempt.RemoteAddr(r.HTTPRequest, s.trustedProxies)
+	}
+	if err := s.loginAttempts.Add(ctx, r.GetMeta(authn.MetaKeyUsername), addr); err != nil {
+		s.log.FromContext(ctx).Warn("Failed to record login attempt", "error", err)
+	}
+}
@@ -0,0 +1,244 @@
+package totpimpl
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/loginattempt"
+	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/services/totp"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+var _ totp.Service = (*Service)(nil)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type Service struct {
+	cfg           *setting.Cfg
+	store         store
+	secrets       secrets.Service
+	loginAttempts loginattempt.Service
+	// trustedProxies are allowed to set the client address of login attempts.
+	trustedProxies []*net.IPNet
+	routeRegister  routing.RouteRegister
+	now            func() time.Time
+	log            log.Logger
+}
+
+func ProvideService(cfg *setting.Cfg, sqlStore db.DB, secretsService secrets.Service, loginAttempts loginattempt.Service, routeRegister routing.RouteRegister) *Service {
+	logger := log.New("totp")
+	s := &Service{
+		cfg:            cfg,
+		store:          &xormStore{db: sqlStore, now: time.Now},
+		secrets:        secretsService,
+		loginAttempts:  loginAttempts,
+		trustedProxies: loginattempt.ParseNetworks(cfg.BruteForceLoginProtectionTrustedProxies, logger),
+		routeRegister:  routeRegister,
+		now:            time.Now,
+		log:            logger,
+	}
+	if cfg.TOTPAuth.Enabled {
+		s.registerAPIEndpoints()
+	}
+	return s
+}
+
+func (s *Service) GetStatus(ctx context.Context, userID int64) (*totp.Status, error) {
+	status := &totp.Status{}
+	t, err := s.store.Get(ctx, userID)
+	if err != nil && !errors.Is(err, totp.ErrNotEnrolled) {
+		return nil, err
+	}
+	if t != nil && t.Enabled {
+		status.Enabled = true
+		if status.RecoveryCodesRemaining, err = s.store.CountRecoveryCodes(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	if status.Required, err = s.store.IsRequired(ctx, userID); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func (s *Service) Enroll(ctx context.Context, userID int64, account string) (*totp.Enrollment, error) {
+	t, err := s.store.Get(ctx, userID)
+	if err != nil && !errors.Is(err, totp.ErrNotEnrolled) {
+		return nil, err
+	}
+	if t != nil && t.Enabled {
+		return nil, totp.ErrAlreadyEnrolled.Errorf("user %d already has a second factor", userID)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.save(ctx, userID, secret, false, 0); err != nil {
+		return nil, err
+	}
+	return &totp.Enrollment{
+		Secret: secret,
+		URL:    totp.KeyURI(s.cfg.TOTPAuth.Issuer, account, secret),
+	}, nil
+}
+
+func (s *Service) Confirm(ctx context.Context, userID int64, code string) ([]string, error) {
+	t, err := s.store.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if t.Enabled {
+		return nil, totp.ErrAlreadyEnrolled.Errorf("user %d already has a second factor", userID)
+	}
+
+	secret, err := s.decryptSecret(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := totp.ValidateCode(secret, code, s.now(), 0)
+	if !ok {
+		return nil, totp.ErrInvalidCode.Errorf("invalid code")
+	}
+	if err := s.store.Enable(ctx, userID, step); err != nil {
+		return nil, err
+	}
+	return s.generateRecoveryCodes(ctx, userID)
+}
+
+func (s *Service) Disable(ctx context.Context, userID int64, code string) error {
+	required, err := s.store.IsRequired(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if required {
+		return totp.ErrRequiredByOrg.Errorf("an organization of user %d requires a second factor", userID)
+	}
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+	return s.store.Delete(ctx, userID)
+}
+
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) ([]string, error) {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	return s.generateRecoveryCodes(ctx, userID)
+}
+
+func (s *Service) Reset(ctx context.Context, userID int64) error {
+	return s.store.Delete(ctx, userID)
+}
+
+func (s *Service) Verify(ctx context.Context, userID int64, code string) error {
+	t, err := s.store.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !t.Enabled {
+		return totp.ErrNotEnrolled.Errorf("the enrollment of user %d isn't confirmed", userID)
+	}
+
+	secret, err := s.decryptSecret(ctx, t)
+	if err != nil {
+		return err
+	}
+	if step, ok := totp.ValidateCode(secret, code, s.now(), t.LastStep); ok {
+		used, err := s.store.UseStep(ctx, userID, step)
+		if err != nil {
+			return err
+		}
+		if used {
+			return nil
+		}
+		// A concurrent request used the code.
+		return totp.ErrInvalidCode.Errorf("code already used")
+	}
+
+	used, err := s.store.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if used {
+		s.log.FromContext(ctx).Info("Recovery code used", "userId", userID)
+		return nil
+	}
+	return totp.ErrInvalidCode.Errorf("invalid code")
+}
+
+func (s *Service) IsRequired(ctx context.Context, userID int64) (bool, error) {
+	return s.store.IsRequired(ctx, userID)
+}
+
+func (s *Service) GetOrgPolicy(ctx context.Context, orgID int64) (*totp.OrgPolicy, error) {
+	return s.store.GetOrgPolicy(ctx, orgID)
+}
+
+func (s *Service) SetOrgPolicy(ctx context.Context, policy totp.OrgPolicy) error {
+	return s.store.SetOrgPolicy(ctx, policy)
+}
+
+func (s *Service) save(ctx context.Context, userID int64, secret string, enabled bool, step int64) error {
+	encrypted, err := s.secrets.Encrypt(ctx, []byte(secret), secrets.WithoutScope())
+	if err != nil {
+		return err
+	}
+	return s.store.Save(ctx, &userTOTP{
+		UserID:   userID,
+		Secret:   base64.StdEncoding.EncodeToString(encrypted),
+		Enabled:  enabled,
+		LastStep: step,
+	})
+}
+
+func (s *Service) decryptSecret(ctx context.Context, t *userTOTP) (string, error) {
+	encrypted, err := base64.StdEncoding.DecodeString(t.Secret)
+	if err != nil {
+		return "", err
+	}
+	secret, err := s.secrets.Decrypt(ctx, encrypted)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+func (s *Service) generateRecoveryCodes(ctx context.Context, userID int64) ([]string, error) {
+	codes := make([]string, 0, s.cfg.TOTPAuth.RecoveryCodes)
+	hashes := make([]string, 0, s.cfg.TOTPAuth.RecoveryCodes)
+	for i := 0; i < s.cfg.TOTPAuth.RecoveryCodes; i++ {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+		code = code[:8] + "-" + code[8:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	if err := s.store.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode hashes a recovery code, ignoring case, spaces and dashes. Recovery codes are
+// random, so they don't need a slow hash.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,215 @@
+package totpimpl
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/authlib/claims"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/loginattempt/loginattempttest"
+	"github.com/grafana/grafana/pkg/services/secrets/fakes"
+	"github.com/grafana/grafana/pkg/services/totp"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/tests/testsuite"
+)
+
+func TestMain(m *testing.M) {
+	testsuite.Run(m)
+}
+
+func setupTestService(t *testing.T) (*Service, db.DB, *loginattempttest.MockLoginAttemptService, *time.Time) {
+	t.Helper()
+	sqlStore := db.InitTestDB(t)
+	cfg := setting.NewCfg()
+	cfg.TOTPAuth = setting.AuthTOTPSettings{Enabled: true, Issuer: "Grafana", RecoveryCodes: 3}
+	loginAttempts := &loginattempttest.MockLoginAttemptService{}
+
+	now := time.Unix(1700000000, 0)
+	s := ProvideService(cfg, sqlStore, fakes.NewFakeSecretsService(), loginAttempts, routing.NewRouteRegister())
+	s.now = func() time.Time { return now }
+	return s, sqlStore, loginAttempts, &now
+}
+
+func currentCode(t *testing.T, secret string, now time.Time) string {
+	t.Helper()
+	code, err := totp.GenerateCode(secret, totp.Step(now))
+	require.NoError(t, err)
+	return code
+}
+
+func enroll(t *testing.T, s *Service, userID int64, now time.Time) (string, []string) {
+	t.Helper()
+	ctx := context.Background()
+	enrollment, err := s.Enroll(ctx, userID, "user@example.org")
+	require.NoError(t, err)
+	codes, err := s.Confirm(ctx, userID, currentCode(t, enrollment.Secret, now))
+	require.NoError(t, err)
+	return enrollment.Secret, codes
+}
+
+func TestIntegrationTOTPService(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+
+	t.Run("enroll, confirm and verify", func(t *testing.T) {
+		s, _, _, now := setupTestService(t)
+
+		enrollment, err := s.Enroll(ctx, 1, "admin")
+		require.NoError(t, err)
+		assert.Contains(t, enrollment.URL, "otpauth://totp/Grafana:admin?")
+
+		status, err := s.GetStatus(ctx, 1)
+		require.NoError(t, err)
+		assert.False(t, status.Enabled)
+
+		_, err = s.Confirm(ctx, 1, "000000")
+		require.ErrorIs(t, err, totp.ErrInvalidCode)
+
+		codes, err := s.Confirm(ctx, 1, currentCode(t, enrollment.Secret, *now))
+		require.NoError(t, err)
+		assert.Len(t, codes, 3)
+
+		status, err = s.GetStatus(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, &totp.Status{Enabled: true, RecoveryCodesRemaining: 3}, status)
+
+		_, err = s.Enroll(ctx, 1, "admin")
+		require.ErrorIs(t, err, totp.ErrAlreadyEnrolled)
+
+		// The code used to confirm can't be used again.
+		err = s.Verify(ctx, 1, currentCode(t, enrollment.Secret, *now))
+		require.ErrorIs(t, err, totp.ErrInvalidCode)
+
+		*now = now.Add(30 * time.Second)
+		code := currentCode(t, enrollment.Secret, *now)
+		require.NoError(t, s.Verify(ctx, 1, code))
+		require.ErrorIs(t, s.Verify(ctx, 1, code), totp.ErrInvalidCode)
+	})
+
+	t.Run("recovery codes can be used once", func(t *testing.T) {
+		s, _, _, now := setupTestService(t)
+		_, codes := enroll(t, s, 1, *now)
+
+		require.NoError(t, s.Verify(ctx, 1, codes[0]))
+		require.ErrorIs(t, s.Verify(ctx, 1, codes[0]), totp.ErrInvalidCode)
+
+		status, err := s.GetStatus(ctx, 1)
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, status.RecoveryCodesRemaining)
+	})
+
+	t.Run("disable is blocked by the org policy", func(t *testing.T) {
+		s, sqlStore, _, now := setupTestService(t)
+		secret, _ := enroll(t, s, 1, *now)
+		addOrgUser(t, sqlStore, 1, 1)
+		require.NoError(t, s.SetOrgPolicy(ctx, totp.OrgPolicy{OrgID: 1, Required: true}))
+
+		*now = now.Add(30 * time.Second)
+		err := s.Disable(ctx, 1, currentCode(t, secret, *now))
+		require.ErrorIs(t, err, totp.ErrRequiredByOrg)
+
+		require.NoError(t, s.SetOrgPolicy(ctx, totp.OrgPolicy{OrgID: 1, Required: false}))
+		require.NoError(t, s.Disable(ctx, 1, currentCode(t, secret, *now)))
+
+		status, err := s.GetStatus(ctx, 1)
+		require.NoError(t, err)
+		assert.False(t, status.Enabled)
+	})
+}
+
+func TestIntegrationTOTPLoginHook(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+
+	identity := func(authenticatedBy string) *authn.Identity {
+		return &authn.Identity{ID: "1", Type: claims.TypeUser, AuthenticatedBy: authenticatedBy}
+	}
+	loginRequest := func(meta map[string]string) *authn.Request {
+		r := &authn.Request{}
+		r.SetMeta(authn.MetaKeyIsLogin, "true")
+		r.SetMeta(authn.MetaKeyUsername, "admin")
+		for k, v := range meta {
+			r.SetMeta(k, v)
+		}
+		return r
+	}
+
+	t.Run("users without second factor log in with their password", func(t *testing.T) {
+		s, _, _, _ := setupTestService(t)
+		require.NoError(t, s.LoginHook(ctx, identity(login.PasswordAuthModule), loginRequest(nil)))
+	})
+
+	t.Run("enrolled users need a code", func(t *testing.T) {
+		s, _, loginAttempts, now := setupTestService(t)
+		secret, _ := enroll(t, s, 1, *now)
+		*now = now.Add(30 * time.Second)
+
+		err := s.LoginHook(ctx, identity(login.PasswordAuthModule), loginRequest(nil))
+		require.ErrorIs(t, err, errCodeRequired)
+
+		err = s.LoginHook(ctx, identity(login.PasswordAuthModule), loginRequest(map[string]string{authn.MetaKeyTOTPCode: "000000"}))
+		require.ErrorIs(t, err, errInvalidLoginCode)
+		assert.True(t, loginAttempts.AddCalled)
+
+		err = s.LoginHook(ctx, identity(login.PasswordAuthModule), loginRequest(map[string]string{authn.MetaKeyTOTPCode: currentCode(t, secret, *now)}))
+		require.NoError(t, err)
+
+		// Basic auth can't provide a code.
+		err = s.LoginHook(ctx, identity(login.PasswordAuthModule), &authn.Request{})
+		require.ErrorIs(t, err, errBasicAuth)
+
+		// Other authentication methods are left alone.
+		require.NoError(t, s.LoginHook(ctx, identity(login.LDAPAuthModule), loginRequest(nil)))
+	})
+
+	t.Run("users required by their org enroll on login", func(t *testing.T) {
+		s, sqlStore, _, now := setupTestService(t)
+		addOrgUser(t, sqlStore, 1, 1)
+		require.NoError(t, s.SetOrgPolicy(ctx, totp.OrgPolicy{OrgID: 1, Required: true}))
+
+		err := s.LoginHook(ctx, identity(login.PasswordAuthModule), loginRequest(nil))
+		require.ErrorIs(t, err, errEnrollmentRequired)
+
+		secret, err := totp.GenerateSecret()
+		require.NoError(t, err)
+		r := loginRequest(map[string]string{
+			authn.MetaKeyTOTPSecret: secret,
+			authn.MetaKeyTOTPCode:   currentCode(t, secret, *now),
+		})
+		require.NoError(t, s.LoginHook(ctx, identity(login.PasswordAuthModule), r))
+
+		status, err := s.GetStatus(ctx, 1)
+		require.NoError(t, err)
+		assert.True(t, status.Enabled)
+		assert.True(t, status.Required)
+
+		// The login returns the recovery codes of the second factor.
+		codes := strings.Fields(r.GetMeta(authn.MetaKeyTOTPRecoveryCodes))
+		require.Len(t, codes, s.cfg.TOTPAuth.RecoveryCodes)
+		assert.Equal(t, int64(len(codes)), status.RecoveryCodesRemaining)
+		*now = now.Add(30 * time.Second)
+		require.NoError(t, s.LoginHook(ctx, identity(login.PasswordAuthModule), loginRequest(map[string]string{authn.MetaKeyTOTPCode: codes[0]})))
+	})
+}
+
+func addOrgUser(t *testing.T, sqlStore db.DB, orgID, userID int64) {
+	t.Helper()
+	err := sqlStore.WithDbSession(context.Background(), func(sess *db.Session) error {
+		_, err := sess.Exec("INSERT INTO org_user (org_id, user_id, role, created, updated) VALUES (?, ?, ?, ?, ?)",
+			orgID, userID, "Viewer", time.Now(), time.Now())
+		return err
+	})
+	require.NoError(t, err)
+}
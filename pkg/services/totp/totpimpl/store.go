@@ -0,0 +1,214 @@
+package totpimpl
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/totp"
+)
+
+type userTOTP struct {
+	UserID int64 `xorm:"pk 'user_id'"`
+	// Secret is encrypted by the secrets service.
+	Secret  string `xorm:"secret"`
+	Enabled bool   `xorm:"enabled"`
+	// LastStep is the time step of the last code used, codes can't be used twice.
+	LastStep int64 `xorm:"last_step"`
+	Created  int64 `xorm:"created"`
+	Updated  int64 `xorm:"updated"`
+}
+
+func (userTOTP) TableName() string {
+	return "user_totp"
+}
+
+type recoveryCode struct {
+	ID       int64  `xorm:"pk autoincr 'id'"`
+	UserID   int64  `xorm:"user_id"`
+	CodeHash string `xorm:"code_hash"`
+	Created  int64  `xorm:"created"`
+}
+
+func (recoveryCode) TableName() string {
+	return "user_totp_recovery_code"
+}
+
+type orgPolicy struct {
+	OrgID    int64 `xorm:"pk 'org_id'"`
+	Required bool  `xorm:"required"`
+	Updated  int64 `xorm:"updated"`
+}
+
+func (orgPolicy) TableName() string {
+	return "org_totp_policy"
+}
+
+type store interface {
+	Get(ctx context.Context, userID int64) (*userTOTP, error)
+	// Save creates or replaces the second factor of a user, and deletes its recovery codes.
+	Save(ctx context.Context, t *userTOTP) error
+	Enable(ctx context.Context, userID, step int64) error
+	// UseStep records the use of a code of the time step, and returns false if a code of this or
+	// a later time step was already used.
+	UseStep(ctx context.Context, userID, step int64) (bool, error)
+	Delete(ctx context.Context, userID int64) error
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, hashes []string) error
+	// UseRecoveryCode deletes a recovery code and returns false if it doesn't exist.
+	UseRecoveryCode(ctx context.Context, userID int64, hash string) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userID int64) (int64, error)
+	GetOrgPolicy(ctx context.Context, orgID int64) (*totp.OrgPolicy, error)
+	SetOrgPolicy(ctx context.Context, policy totp.OrgPolicy) error
+	IsRequired(ctx context.Context, userID int64) (bool, error)
+}
+
+type xormStore struct {
+	db  db.DB
+	now func() time.Time
+}
+
+func (s *xormStore) Get(ctx context.Context, userID int64) (*userTOTP, error) {
+	var t userTOTP
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		exists, err := sess.Where("user_id = ?", userID).Get(&t)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return totp.ErrNotEnrolled.Errorf("user %d has no second factor", userID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (s *xormStore) Save(ctx context.Context, t *userTOTP) error {
+	return s.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		now := s.now().Unix()
+		t.Created, t.Updated = now, now
+		if _, err := sess.Exec("DELETE FROM user_totp WHERE user_id = ?", t.UserID); err != nil {
+			return err
+		}
+		if _, err := sess.Exec("DELETE FROM user_totp_recovery_code WHERE user_id = ?", t.UserID); err != nil {
+			return err
+		}
+		_, err := sess.Insert(t)
+		return err
+	})
+}
+
+func (s *xormStore) Enable(ctx context.Context, userID, step int64) error {
+	return s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Exec("UPDATE user_totp SET enabled = ?, last_step = ?, updated = ? WHERE user_id = ?", true, step, s.now().Unix(), userID)
+		return err
+	})
+}
+
+func (s *xormStore) UseStep(ctx context.Context, userID, step int64) (bool, error) {
+	var used bool
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		res, err := sess.Exec("UPDATE user_totp SET last_step = ? WHERE user_id = ? AND last_step < ?", step, userID, step)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		used = n == 1
+		return err
+	})
+	return used, err
+}
+
+func (s *xormStore) Delete(ctx context.Context, userID int64) error {
+	return s.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		if _, err := sess.Exec("DELETE FROM user_totp_recovery_code WHERE user_id = ?", userID); err != nil {
+			return err
+		}
+		_, err := sess.Exec("DELETE FROM user_totp WHERE user_id = ?", userID)
+		return err
+	})
+}
+
+func (s *xormStore) ReplaceRecoveryCodes(ctx context.Context, userID int64, hashes []string) error {
+	return s.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		if _, err := sess.Exec("DELETE FROM user_totp_recovery_code WHERE user_id = ?", userID); err != nil {
+			return err
+		}
+		codes := make([]*recoveryCode, 0, len(hashes))
+		now := s.now().Unix()
+		for _, h := range hashes {
+			codes = append(codes, &recoveryCode{UserID: userID, CodeHash: h, Created: now})
+		}
+		_, err := sess.InsertMulti(codes)
+		return err
+	})
+}
+
+func (s *xormStore) UseRecoveryCode(ctx context.Context, userID int64, hash string) (bool, error) {
+	var used bool
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		res, err := sess.Exec("DELETE FROM user_totp_recovery_code WHERE user_id = ? AND code_hash = ?", userID, hash)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		used = n == 1
+		return err
+	})
+	return used, err
+}
+
+func (s *xormStore) CountRecoveryCodes(ctx context.Context, userID int64) (int64, error) {
+	var count int64
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		var err error
+		count, err = sess.Where("user_id = ?", userID).Count(&recoveryCode{})
+		return err
+	})
+	return count, err
+}
+
+func (s *xormStore) GetOrgPolicy(ctx context.Context, orgID int64) (*totp.OrgPolicy, error) {
+	policy := &totp.OrgPolicy{OrgID: orgID}
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		var p orgPolicy
+		exists, err := sess.Where("org_id = ?", orgID).Get(&p)
+		if err != nil {
+			return err
+		}
+		policy.Required = exists && p.Required
+		return nil
+	})
+	return policy, err
+}
+
+func (s *xormStore) SetOrgPolicy(ctx context.Context, policy totp.OrgPolicy) error {
+	return s.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		p := orgPolicy{OrgID: policy.OrgID, Required: policy.Required, Updated: s.now().Unix()}
+		exists, err := sess.Where("org_id = ?", policy.OrgID).Exist(&orgPolicy{})
+		if err != nil {
+			return err
+		}
+		if exists {
+			_, err = sess.Where("org_id = ?", policy.OrgID).Cols("required", "updated").Update(&p)
+			return err
+		}
+		_, err = sess.Insert(&p)
+		return err
+	})
+}
+
+func (s *xormStore) IsRequired(ctx context.Context, userID int64) (bool, error) {
+	var count int64
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		var err error
+		count, err = sess.Table("org_user").
+			Join("INNER", "org_totp_policy", "org_totp_policy.org_id = org_user.org_id").
+			Where("org_user.user_id = ? AND org_totp_policy.required = ?", userID, true).
+			Count()
+		return err
+	})
+	return count > 0, err
+}
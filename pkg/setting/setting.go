@@ -157,6 +157,18 @@ type Cfg struct {
 	DisableInitAdminCreation             bool
 	DisableBruteForceLoginProtection     bool
 	BruteForceLoginProtectionMaxAttempts int64
+	// BruteForceLoginProtectionIPMaxAttempts is the number of failed login attempts from an IP address,
+	// for any username, before the address is locked. Zero disables the limit.
+	BruteForceLoginProtectionIPMaxAttempts int64
+	// BruteForceLoginProtectionLockoutDuration is the duration of the first lockout, doubled for each
+	// consecutive lockout up to BruteForceLoginProtectionMaxLockoutDuration.
+	BruteForceLoginProtectionLockoutDuration    time.Duration
+	BruteForceLoginProtectionMaxLockoutDuration time.Duration
+	// BruteForceLoginProtectionTrustedProxies is the list of proxies allowed to set the client
+	// address of login attempts with the X-Real-IP and X-Forwarded-For headers.
+	BruteForceLoginProtectionTrustedProxies []string
+	// BruteForceLoginProtectionIPAllowlist is the list of trusted networks, never locked.
+	BruteForceLoginProtectionIPAllowlist []string
 	CookieSecure                         bool
 	CookieSameSiteDisabled               bool
 	CookieSameSiteMode                   http.SameSite
@@ -271,6 +283,10 @@ type Cfg struct {
 
 	PasswordlessMagicLinkAuth AuthPasswordlessMagicLinkSettings
 
+	TOTPAuth AuthTOTPSettings
+
+	MTLSAuth AuthMTLSSettings
+
 	// SSO Settings Auth
 	SSOSettingsReloadInterval        time.Duration
 	SSOSettingsConfigurableProviders map[string]bool
@@ -293,6 +309,12 @@ type Cfg struct {
 	// DistributedCache
 	RemoteCacheOptions *RemoteCacheSettings
 
+	// ServerLock
+	ServerLock ServerLockSettings
+
+	// KVStore
+	KVStore KVStoreSettings
+
 	ViewersCanEdit  bool
 	EditorsCanAdmin bool
 
@@ -394,6 +416,12 @@ type Cfg struct {
 
 	Quota QuotaSettings
 
+	// Cleanup
+	Cleanup CleanupSettings
+
+	// Query caching
+	QueryCaching QueryCachingSettings
+
 	// User settings
 	AllowUserSignUp            bool
 	AllowUserOrgCreate         bool
@@ -412,6 +440,14 @@ type Cfg struct {
 
 	// ExpressionsEnabled specifies whether expressions are enabled.
 	ExpressionsEnabled bool
+	// SQLExpressionMaxInputRows is the maximum number of rows loaded into a SQL expression.
+	SQLExpressionMaxInputRows int64
+	// SQLExpressionMaxOutputRows is the maximum number of rows a SQL expression may return.
+	SQLExpressionMaxOutputRows int64
+	// SQLExpressionMaxMemoryBytes is the maximum size of the in-memory database of a SQL expression.
+	SQLExpressionMaxMemoryBytes int64
+	// SQLExpressionTimeout is the maximum duration of a SQL expression.
+	SQLExpressionTimeout time.Duration
 
 	ImageUploadProvider string
 
@@ -430,6 +466,9 @@ type Cfg struct {
 	// LiveAllowedOrigins is a set of origins accepted by Live. If not provided
 	// then Live uses AppURL as the only allowed origin.
 	LiveAllowedOrigins []string
+	// LivePipelineEnabled enables the Live pipeline, which processes the data published to the channels
+	// matching the channel rules stored in the database.
+	LivePipelineEnabled bool
 
 	// Grafana.com URL, used for OAuth redirect.
 	GrafanaComURL string
@@ -765,6 +804,10 @@ func (cfg *Cfg) readAnnotationSettings() error {
 func (cfg *Cfg) readExpressionsSettings() {
 	expressions := cfg.Raw.Section("expressions")
 	cfg.ExpressionsEnabled = expressions.Key("enabled").MustBool(true)
+	cfg.SQLExpressionMaxInputRows = expressions.Key("sql_max_input_rows").MustInt64(100000)
+	cfg.SQLExpressionMaxOutputRows = expressions.Key("sql_max_output_rows").MustInt64(100000)
+	cfg.SQLExpressionMaxMemoryBytes = expressions.Key("sql_max_memory_mb").MustInt64(256) * 1024 * 1024
+	cfg.SQLExpressionTimeout = expressions.Key("sql_timeout").MustDuration(10 * time.Second)
 }
 
 type AnnotationCleanupSettings struct {
@@ -1253,8 +1296,10 @@ func (cfg *Cfg) parseINIFile(iniFile *ini.File) error {
 	cfg.readAuthJWTSettings()
 	cfg.readAuthExtJWTSettings()
 	cfg.readAuthProxySettings()
+	cfg.readAuthMTLSSettings()
 	cfg.readSessionConfig()
 	cfg.readPasswordlessMagicLinkSettings()
+	cfg.readTOTPSettings()
 	if err := cfg.readSmtpSettings(); err != nil {
 		return err
 	}
@@ -1264,6 +1309,14 @@ func (cfg *Cfg) parseINIFile(iniFile *ini.File) error {
 
 	cfg.readQuotaSettings()
 
+	if err := cfg.readCleanupSettings(); err != nil {
+		return err
+	}
+
+	if err := cfg.readQueryCachingSettings(); err != nil {
+		return err
+	}
+
 	cfg.readExpressionsSettings()
 	if err := cfg.readGrafanaEnvironmentMetrics(); err != nil {
 		return err
@@ -1317,6 +1370,8 @@ func (cfg *Cfg) parseINIFile(iniFile *ini.File) error {
 	cfg.GeomapEnableCustomBaseLayers = geomapSection.Key("enable_custom_baselayers").MustBool(true)
 
 	cfg.readRemoteCacheSettings()
+	cfg.readServerLockSettings()
+	cfg.readKVStoreSettings()
 	cfg.readDateFormats()
 	cfg.readGrafanaJavascriptAgentConfig()
 
@@ -1517,6 +1572,14 @@ func readSecuritySettings(iniFile *ini.File, cfg *Cfg) error {
 	if cfg.BruteForceLoginProtectionMaxAttempts <= 0 {
 		cfg.BruteForceLoginProtectionMaxAttempts = 1
 	}
+	cfg.BruteForceLoginProtectionIPMaxAttempts = security.Key("brute_force_login_protection_ip_max_attempts").MustInt64(0)
+	cfg.BruteForceLoginProtectionLockoutDuration = security.Key("brute_force_login_protection_lockout_duration").MustDuration(5 * time.Minute)
+	cfg.BruteForceLoginProtectionMaxLockoutDuration = security.Key("brute_force_login_protection_max_lockout_duration").MustDuration(time.Hour)
+	if cfg.BruteForceLoginProtectionMaxLockoutDuration < cfg.BruteForceLoginProtectionLockoutDuration {
+		cfg.BruteForceLoginProtectionMaxLockoutDuration = cfg.BruteForceLoginProtectionLockoutDuration
+	}
+	cfg.BruteForceLoginProtectionIPAllowlist = util.SplitString(security.Key("brute_force_login_protection_ip_allowlist").String())
+	cfg.BruteForceLoginProtectionTrustedProxies = util.SplitString(security.Key("brute_force_login_protection_trusted_proxies").String())
 
 	CookieSecure = security.Key("cookie_secure").MustBool(false)
 	cfg.CookieSecure = CookieSecure
@@ -1968,6 +2031,7 @@ func (cfg *Cfg) readLiveSettings(iniFile *ini.File) error {
 	cfg.LiveHAPrefix = section.Key("ha_prefix").MustString("")
 	cfg.LiveHAEngineAddress = section.Key("ha_engine_address").MustString("127.0.0.1:6379")
 	cfg.LiveHAEnginePassword = section.Key("ha_engine_password").MustString("")
+	cfg.LivePipelineEnabled = section.Key("pipeline_enabled").MustBool(false)
 
 	allowedOrigins := section.Key("allowed_origins").MustString("")
 	origins := strings.Split(allowedOrigins, ",")
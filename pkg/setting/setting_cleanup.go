@@ -0,0 +1,61 @@
+package setting
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+)
+
+// CleanupSettings configures the background job that deletes old data.
+type CleanupSettings struct {
+	// DryRun makes the retention policies report what they would delete instead of deleting it.
+	// The built-in cleanup jobs do not run in dry-run mode.
+	DryRun bool
+	// RetentionPolicies are the per-organization retention policies, sorted by job and organization.
+	RetentionPolicies []RetentionPolicy
+}
+
+// RetentionPolicy limits how long the data of a cleanup job is kept for an organization.
+type RetentionPolicy struct {
+	Job    string
+	OrgID  int64
+	MaxAge time.Duration
+}
+
+func (cfg *Cfg) readCleanupSettings() error {
+	cleanup := cfg.Raw.Section("cleanup")
+	cfg.Cleanup.DryRun = cleanup.Key("dry_run").MustBool(false)
+
+	policies := make([]RetentionPolicy, 0)
+	// Keys have the format <job>.<org id>, for example annotations.2 = 30d
+	for _, key := range cfg.Raw.Section("cleanup.retention").Keys() {
+		job, org, ok := strings.Cut(key.Name(), ".")
+		if !ok || job == "" {
+			return fmt.Errorf("invalid retention policy %q: the key must have the format <job>.<org id>", key.Name())
+		}
+		orgID, err := strconv.ParseInt(org, 10, 64)
+		if err != nil || orgID <= 0 {
+			return fmt.Errorf("invalid retention policy %q: %q is not an organization ID", key.Name(), org)
+		}
+		maxAge, err := gtime.ParseDuration(key.Value())
+		if err != nil {
+			return fmt.Errorf("invalid retention policy %q: %w", key.Name(), err)
+		}
+		if maxAge <= 0 {
+			return fmt.Errorf("invalid retention policy %q: the maximum age must be greater than 0", key.Name())
+		}
+		policies = append(policies, RetentionPolicy{Job: job, OrgID: orgID, MaxAge: maxAge})
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		if policies[i].Job != policies[j].Job {
+			return policies[i].Job < policies[j].Job
+		}
+		return policies[i].OrgID < policies[j].OrgID
+	})
+	cfg.Cleanup.RetentionPolicies = policies
+	return nil
+}
@@ -0,0 +1,54 @@
+package setting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/ini.v1"
+)
+
+func TestReadCleanupSettings(t *testing.T) {
+	t.Run("will load retention policies sorted by job and organization", func(t *testing.T) {
+		f := ini.Empty()
+		cfg := NewCfg()
+		cfg.Raw = f
+		_, err := f.Section("cleanup").NewKey("dry_run", "true")
+		require.NoError(t, err)
+		s := f.Section("cleanup.retention")
+		_, err = s.NewKey("query_history.3", "7d")
+		require.NoError(t, err)
+		_, err = s.NewKey("annotations.2", "12h")
+		require.NoError(t, err)
+		_, err = s.NewKey("annotations.1", "30d")
+		require.NoError(t, err)
+
+		require.NoError(t, cfg.readCleanupSettings())
+
+		require.True(t, cfg.Cleanup.DryRun)
+		require.Equal(t, []RetentionPolicy{
+			{Job: "annotations", OrgID: 1, MaxAge: 30 * 24 * time.Hour},
+			{Job: "annotations", OrgID: 2, MaxAge: 12 * time.Hour},
+			{Job: "query_history", OrgID: 3, MaxAge: 7 * 24 * time.Hour},
+		}, cfg.Cleanup.RetentionPolicies)
+	})
+
+	t.Run("will return error for invalid policies", func(t *testing.T) {
+		for key, value := range map[string]string{
+			"annotations":     "30d",
+			"annotations.org": "30d",
+			"annotations.0":   "30d",
+			"annotations.1":   "forever",
+			".1":              "30d",
+			"annotations.2":   "0s",
+		} {
+			f := ini.Empty()
+			cfg := NewCfg()
+			cfg.Raw = f
+			_, err := f.Section("cleanup.retention").NewKey(key, value)
+			require.NoError(t, err)
+
+			require.Error(t, cfg.readCleanupSettings(), key)
+		}
+	})
+}
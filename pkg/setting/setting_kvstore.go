@@ -0,0 +1,35 @@
+package setting
+
+const (
+	KVStoreTypeDatabase = "database"
+	KVStoreTypeBlob     = "blob"
+)
+
+// KVStoreSettings configures where the values of the key/value store are stored.
+type KVStoreSettings struct {
+	// Type is either "database" or "blob".
+	Type string
+	// BlobURL is the URL of the blob bucket, such as s3://bucket?region=us-east-1, gs://bucket or file:///path.
+	BlobURL string
+	// BlobPrefix is prepended to the keys of the values in the bucket.
+	BlobPrefix string
+}
+
+func (cfg *Cfg) readKVStoreSettings() {
+	section := cfg.Raw.Section("kvstore")
+	settings := KVStoreSettings{
+		Type:       valueAsString(section, "type", KVStoreTypeDatabase),
+		BlobURL:    valueAsString(section, "blob_url", ""),
+		BlobPrefix: valueAsString(section, "blob_prefix", "kvstore/"),
+	}
+
+	if settings.Type != KVStoreTypeBlob {
+		settings.Type = KVStoreTypeDatabase
+	}
+	if settings.Type == KVStoreTypeBlob && settings.BlobURL == "" {
+		cfg.Logger.Warn("No blob_url configured for the kvstore, falling back to the database")
+		settings.Type = KVStoreTypeDatabase
+	}
+
+	cfg.KVStore = settings
+}
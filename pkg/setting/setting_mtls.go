@@ -0,0 +1,74 @@
+package setting
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// AuthMTLSSettings configures the authentication of requests by verified client certificates.
+type AuthMTLSSettings struct {
+	Enabled bool
+	// ClientCACert is the PEM file of the certificate authorities issuing client certificates.
+	ClientCACert string
+	// HeaderName is the header with the URL encoded PEM client certificate, set by a proxy
+	// terminating TLS in front of Grafana.
+	HeaderName string
+	// Whitelist is the list of proxies allowed to set HeaderName.
+	Whitelist string
+
+	// The attribute paths are JMESPath expressions evaluated against the certificate.
+	LoginAttributePath          string
+	EmailAttributePath          string
+	NameAttributePath           string
+	ServiceAccountAttributePath string
+	RoleAttributePath           string
+	RoleAttributeStrict         bool
+	OrgAttributePath            string
+	OrgMapping                  []string
+	AllowAssignGrafanaAdmin     bool
+	SkipOrgRoleSync             bool
+	AutoSignUp                  bool
+}
+
+func (cfg *Cfg) readAuthMTLSSettings() {
+	section := cfg.SectionWithEnvOverrides("auth.mtls")
+	cfg.MTLSAuth = AuthMTLSSettings{
+		Enabled:                     section.Key("enabled").MustBool(false),
+		ClientCACert:                section.Key("client_ca_cert").MustString(""),
+		HeaderName:                  section.Key("header_name").MustString(""),
+		Whitelist:                   section.Key("whitelist").MustString(""),
+		LoginAttributePath:          section.Key("login_attribute_path").MustString("subject.commonName"),
+		EmailAttributePath:          section.Key("email_attribute_path").MustString("emailAddresses[0]"),
+		NameAttributePath:           section.Key("name_attribute_path").MustString(""),
+		ServiceAccountAttributePath: section.Key("service_account_attribute_path").MustString(""),
+		RoleAttributePath:           section.Key("role_attribute_path").MustString(""),
+		RoleAttributeStrict:         section.Key("role_attribute_strict").MustBool(false),
+		OrgAttributePath:            section.Key("org_attribute_path").MustString(""),
+		OrgMapping:                  util.SplitString(section.Key("org_mapping").MustString("")),
+		AllowAssignGrafanaAdmin:     section.Key("allow_assign_grafana_admin").MustBool(false),
+		SkipOrgRoleSync:             section.Key("skip_org_role_sync").MustBool(false),
+		AutoSignUp:                  section.Key("auto_sign_up").MustBool(true),
+	}
+}
+
+// ClientCAs reads the certificate authorities of ClientCACert.
+func (s AuthMTLSSettings) ClientCAs() (*x509.CertPool, error) {
+	if s.ClientCACert == "" {
+		return nil, errors.New("client_ca_cert is required for client certificate authentication")
+	}
+	// nolint:gosec
+	// We can ignore the gosec G304 warning since the path comes from the configuration.
+	data, err := os.ReadFile(s.ClientCACert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificate found in %q", s.ClientCACert)
+	}
+	return pool, nil
+}
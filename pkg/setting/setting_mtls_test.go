@@ -0,0 +1,114 @@
+package setting
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadAuthMTLSSettings(t *testing.T) {
+	t.Run("reads the header and the whitelist of proxies", func(t *testing.T) {
+		cfg, err := NewCfgFromBytes([]byte(`
+[auth.mtls]
+enabled = true
+client_ca_cert = /etc/grafana/ca.pem
+header_name = X-Client-Cert
+whitelist = 10.0.0.1, 10.0.1.0/24
+`))
+		require.NoError(t, err)
+
+		assert.True(t, cfg.MTLSAuth.Enabled)
+		assert.Equal(t, "/etc/grafana/ca.pem", cfg.MTLSAuth.ClientCACert)
+		assert.Equal(t, "X-Client-Cert", cfg.MTLSAuth.HeaderName)
+		assert.Equal(t, "10.0.0.1, 10.0.1.0/24", cfg.MTLSAuth.Whitelist)
+	})
+
+	t.Run("client certificates are only read from TLS connections by default", func(t *testing.T) {
+		cfg, err := NewCfgFromBytes([]byte(`
+[auth.mtls]
+enabled = true
+`))
+		require.NoError(t, err)
+
+		assert.Empty(t, cfg.MTLSAuth.HeaderName)
+		assert.Empty(t, cfg.MTLSAuth.Whitelist)
+		assert.Equal(t, "subject.commonName", cfg.MTLSAuth.LoginAttributePath)
+		assert.Equal(t, "emailAddresses[0]", cfg.MTLSAuth.EmailAttributePath)
+		assert.True(t, cfg.MTLSAuth.AutoSignUp)
+	})
+
+	t.Run("the header and the whitelist can be set by environment variables", func(t *testing.T) {
+		t.Setenv("GF_AUTH_MTLS_HEADER_NAME", "X-SSL-Client-Cert")
+		t.Setenv("GF_AUTH_MTLS_WHITELIST", "192.168.0.1")
+
+		cfg, err := NewCfgFromBytes([]byte(`
+[auth.mtls]
+header_name = X-Client-Cert
+whitelist = 10.0.0.1
+`))
+		require.NoError(t, err)
+
+		assert.Equal(t, "X-SSL-Client-Cert", cfg.MTLSAuth.HeaderName)
+		assert.Equal(t, "192.168.0.1", cfg.MTLSAuth.Whitelist)
+	})
+}
+
+func TestAuthMTLSSettings_ClientCAs(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("reads the certificate authorities", func(t *testing.T) {
+		path := filepath.Join(dir, "ca.pem")
+		require.NoError(t, os.WriteFile(path, testCACertificate(t), 0600))
+
+		pool, err := AuthMTLSSettings{ClientCACert: path}.ClientCAs()
+		require.NoError(t, err)
+		assert.False(t, pool.Equal(x509.NewCertPool()))
+	})
+
+	t.Run("requires a certificate authority", func(t *testing.T) {
+		_, err := AuthMTLSSettings{}.ClientCAs()
+		require.Error(t, err)
+	})
+
+	t.Run("fails when the file has no certificate", func(t *testing.T) {
+		path := filepath.Join(dir, "empty.pem")
+		require.NoError(t, os.WriteFile(path, []byte("not a certificate"), 0600))
+
+		_, err := AuthMTLSSettings{ClientCACert: path}.ClientCAs()
+		require.ErrorContains(t, err, "no certificate found")
+	})
+
+	t.Run("fails when the file is missing", func(t *testing.T) {
+		_, err := AuthMTLSSettings{ClientCACert: filepath.Join(dir, "missing.pem")}.ClientCAs()
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func testCACertificate(t *testing.T) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -0,0 +1,55 @@
+package setting
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+)
+
+// QueryCachingSettings configures the cache of data source query and resource responses.
+type QueryCachingSettings struct {
+	Enabled bool
+	// TTL is how long query responses are cached for, unless the data source has its own TTL.
+	TTL time.Duration
+	// ResourcesTTL is how long resource responses are cached for. 0 disables the caching of resources.
+	ResourcesTTL time.Duration
+	// MaxValueSize is the maximum size in bytes of a cached response. Bigger responses are not cached.
+	MaxValueSize int
+	// DataSourceTTLs overrides TTL for data sources, by data source UID or plugin ID. 0 disables caching.
+	DataSourceTTLs map[string]time.Duration
+}
+
+// DataSourceTTL returns how long the query responses of a data source are cached for. The data
+// source UID takes precedence over its plugin ID.
+func (s QueryCachingSettings) DataSourceTTL(uid, pluginID string) time.Duration {
+	if ttl, ok := s.DataSourceTTLs[uid]; ok {
+		return ttl
+	}
+	if ttl, ok := s.DataSourceTTLs[pluginID]; ok {
+		return ttl
+	}
+	return s.TTL
+}
+
+func (cfg *Cfg) readQueryCachingSettings() error {
+	section := cfg.Raw.Section("caching")
+	cfg.QueryCaching.Enabled = section.Key("enabled").MustBool(false)
+	cfg.QueryCaching.TTL = section.Key("ttl").MustDuration(5 * time.Minute)
+	cfg.QueryCaching.ResourcesTTL = section.Key("resources_ttl").MustDuration(5 * time.Minute)
+	cfg.QueryCaching.MaxValueSize = section.Key("max_value_size").MustInt(10 * 1024 * 1024)
+
+	ttls := make(map[string]time.Duration)
+	for _, key := range cfg.Raw.Section("caching.datasource_ttl").Keys() {
+		ttl, err := gtime.ParseDuration(key.Value())
+		if err != nil {
+			return fmt.Errorf("invalid [caching.datasource_ttl] %s: %w", key.Name(), err)
+		}
+		if ttl < 0 {
+			return fmt.Errorf("invalid [caching.datasource_ttl] %s: the TTL can't be negative", key.Name())
+		}
+		ttls[key.Name()] = ttl
+	}
+	cfg.QueryCaching.DataSourceTTLs = ttls
+	return nil
+}
@@ -0,0 +1,56 @@
+package setting
+
+import (
+	dstls "github.com/grafana/dskit/crypto/tls"
+)
+
+const (
+	ServerLockTypeDatabase = "database"
+	ServerLockTypeRedis    = "redis"
+)
+
+// ServerLockSettings configures the backend of the locks shared by the Grafana instances of a HA setup.
+type ServerLockSettings struct {
+	// Type is either "database" or "redis".
+	Type string
+
+	RedisClusterModeEnabled bool
+	// RedisAddr is a comma separated list of addresses in cluster mode.
+	RedisAddr     string
+	RedisUsername string
+	RedisPassword string
+	RedisDB       int
+	// RedisPrefix is prepended to the keys of the locks.
+	RedisPrefix     string
+	RedisTLSEnabled bool
+	RedisTLSConfig  dstls.ClientConfig
+}
+
+func (cfg *Cfg) readServerLockSettings() {
+	section := cfg.Raw.Section("server_lock")
+	settings := ServerLockSettings{
+		Type:                    valueAsString(section, "type", ServerLockTypeDatabase),
+		RedisClusterModeEnabled: section.Key("redis_cluster_mode_enabled").MustBool(false),
+		RedisAddr:               valueAsString(section, "redis_address", ""),
+		RedisUsername:           valueAsString(section, "redis_username", ""),
+		RedisPassword:           valueAsString(section, "redis_password", ""),
+		RedisDB:                 section.Key("redis_db").MustInt(0),
+		RedisPrefix:             valueAsString(section, "redis_prefix", "grafana:serverlock:"),
+		RedisTLSEnabled:         section.Key("redis_tls_enabled").MustBool(false),
+	}
+	settings.RedisTLSConfig.CertPath = valueAsString(section, "redis_tls_cert_path", "")
+	settings.RedisTLSConfig.KeyPath = valueAsString(section, "redis_tls_key_path", "")
+	settings.RedisTLSConfig.CAPath = valueAsString(section, "redis_tls_ca_path", "")
+	settings.RedisTLSConfig.ServerName = valueAsString(section, "redis_tls_server_name", "")
+	settings.RedisTLSConfig.InsecureSkipVerify = section.Key("redis_tls_insecure_skip_verify").MustBool(false)
+
+	if settings.Type != ServerLockTypeRedis {
+		settings.Type = ServerLockTypeDatabase
+	}
+	if settings.Type == ServerLockTypeRedis && settings.RedisAddr == "" {
+		cfg.Logger.Warn("No redis address configured for server locks, falling back to the database")
+		settings.Type = ServerLockTypeDatabase
+	}
+
+	cfg.ServerLock = settings
+}
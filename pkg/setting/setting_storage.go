@@ -1,16 +1,28 @@
 package setting
 
 import (
+	"time"
+
 	"gopkg.in/ini.v1"
 )
 
 type StorageSettings struct {
 	AllowUnsanitizedSvgUpload bool
+
+	// VersioningEnabled keeps the versions and the deleted files of the files stored in the database
+	VersioningEnabled bool
+	// MaxVersions is the number of versions kept for each file, 0 keeps all the versions
+	MaxVersions int
+	// TrashRetention is the duration deleted files are kept in the trash, 0 keeps them until they are restored
+	TrashRetention time.Duration
 }
 
 func readStorageSettings(iniFile *ini.File) StorageSettings {
 	s := StorageSettings{}
 	storageSection := iniFile.Section("storage")
 	s.AllowUnsanitizedSvgUpload = storageSection.Key("allow_unsanitized_svg_upload").MustBool(false)
+	s.VersioningEnabled = storageSection.Key("versioning_enabled").MustBool(false)
+	s.MaxVersions = max(storageSection.Key("max_versions").MustInt(20), 0)
+	s.TrashRetention = max(storageSection.Key("trash_retention").MustDuration(30*24*time.Hour), 0)
 	return s
 }
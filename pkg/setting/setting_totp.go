@@ -0,0 +1,22 @@
+package setting
+
+// AuthTOTPSettings configures the TOTP second factor of the users logging in with the password of a local account.
+type AuthTOTPSettings struct {
+	Enabled bool
+	// Issuer is the name of the account shown in authenticator apps.
+	Issuer string
+	// RecoveryCodes is the number of recovery codes generated for a user.
+	RecoveryCodes int
+}
+
+func (cfg *Cfg) readTOTPSettings() {
+	section := cfg.SectionWithEnvOverrides("auth.totp")
+	cfg.TOTPAuth = AuthTOTPSettings{
+		Enabled:       section.Key("enabled").MustBool(false),
+		Issuer:        section.Key("issuer").MustString("Grafana"),
+		RecoveryCodes: section.Key("recovery_codes").MustInt(10),
+	}
+	if cfg.TOTPAuth.RecoveryCodes <= 0 {
+		cfg.TOTPAuth.RecoveryCodes = 10
+	}
+}
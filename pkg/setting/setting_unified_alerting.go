@@ -61,11 +61,18 @@ const (
 	// with intervals that are not exactly divided by this number not to be evaluated
 	SchedulerBaseInterval = 10 * time.Second
 	// DefaultRuleEvaluationInterval indicates a default interval of for how long a rule should be evaluated to change state from Pending to Alerting
-	DefaultRuleEvaluationInterval  = SchedulerBaseInterval * 6 // == 60 seconds
-	stateHistoryDefaultEnabled     = true
-	lokiDefaultMaxQueryLength      = 721 * time.Hour // 30d1h, matches the default value in Loki
-	defaultRecordingRequestTimeout = 10 * time.Second
-	lokiDefaultMaxQuerySize        = 65536 // 64kb
+	DefaultRuleEvaluationInterval   = SchedulerBaseInterval * 6 // == 60 seconds
+	stateHistoryDefaultEnabled      = true
+	lokiDefaultMaxQueryLength       = 721 * time.Hour // 30d1h, matches the default value in Loki
+	defaultRecordingRequestTimeout  = 10 * time.Second
+	defaultRecordingWriteAttempts   = 3
+	defaultRecordingWriteBackoff    = time.Second
+	defaultRecordingTarget          = "prometheus"
+	defaultRecordingQueueBatches    = 10000
+	defaultRecordingQueueBackoff    = 5 * time.Minute
+	lokiDefaultMaxQuerySize         = 65536 // 64kb
+	stateHistoryDefaultSQLRetention = 720 * time.Hour
+	defaultRecordingSQLRetention    = 720 * time.Hour
 )
 
 type UnifiedAlertingSettings struct {
@@ -138,6 +145,52 @@ type RecordingRuleSettings struct {
 	BasicAuthPassword string
 	CustomHeaders     map[string]string
 	Timeout           time.Duration
+
+	// DefaultTarget is the writer used by recording rules that do not select a target.
+	DefaultTarget string
+	// MaxWriteAttempts is the number of times a write is attempted before giving up. Only unexpected failures are retried.
+	MaxWriteAttempts int
+	// WriteRetryBackoff is the time to wait before the first retry. It doubles after every attempt.
+	WriteRetryBackoff time.Duration
+
+	InfluxDB   RecordingRuleInfluxDBSettings
+	OTLP       RecordingRuleOTLPSettings
+	SQL        RecordingRuleSQLSettings
+	WriteQueue RecordingRuleWriteQueueSettings
+}
+
+// RecordingRuleWriteQueueSettings configures the queue in the Grafana database that keeps the writes that failed
+// because a target was unavailable, and replays them in order once it recovers.
+type RecordingRuleWriteQueueSettings struct {
+	Enabled bool
+	// MaxBatches is the maximum number of writes queued per target. The oldest writes are dropped when it is exceeded.
+	MaxBatches int64
+	// MaxBackoff is the maximum time to wait between two replay attempts while the target is unavailable.
+	MaxBackoff time.Duration
+}
+
+// RecordingRuleInfluxDBSettings configures writing recording rule results to an InfluxDB v2 compatible endpoint
+// using the line protocol.
+type RecordingRuleInfluxDBSettings struct {
+	URL     string
+	Token   string
+	Org     string
+	Bucket  string
+	Timeout time.Duration
+}
+
+// RecordingRuleOTLPSettings configures writing recording rule results to an OTLP/HTTP metrics endpoint.
+type RecordingRuleOTLPSettings struct {
+	URL           string
+	CustomHeaders map[string]string
+	Timeout       time.Duration
+}
+
+// RecordingRuleSQLSettings configures writing recording rule results to a table in the Grafana database.
+type RecordingRuleSQLSettings struct {
+	Enabled bool
+	// Retention is how long the written samples are kept. Zero keeps them forever.
+	Retention time.Duration
 }
 
 // RemoteAlertmanagerSettings contains the configuration needed
@@ -177,6 +230,8 @@ type UnifiedAlertingStateHistorySettings struct {
 	MultiPrimary          string
 	MultiSecondaries      []string
 	ExternalLabels        map[string]string
+	// SQLRetention is how long the state history recorded by the "sql" backend is kept. Zero keeps it forever.
+	SQLRetention time.Duration
 }
 
 // IsEnabled returns true if UnifiedAlertingSettings.Enabled is either nil or true.
@@ -430,6 +485,7 @@ func (cfg *Cfg) ReadUnifiedAlertingSettings(iniFile *ini.File) error {
 		MultiPrimary:          stateHistory.Key("primary").MustString(""),
 		MultiSecondaries:      splitTrim(stateHistory.Key("secondaries").MustString(""), ","),
 		ExternalLabels:        stateHistoryLabels.KeysHash(),
+		SQLRetention:          stateHistory.Key("sql_retention").MustDuration(stateHistoryDefaultSQLRetention),
 	}
 	uaCfg.StateHistory = uaCfgStateHistory
 
@@ -440,6 +496,9 @@ func (cfg *Cfg) ReadUnifiedAlertingSettings(iniFile *ini.File) error {
 		BasicAuthUsername: rr.Key("basic_auth_username").MustString(""),
 		BasicAuthPassword: rr.Key("basic_auth_password").MustString(""),
 		Timeout:           rr.Key("timeout").MustDuration(defaultRecordingRequestTimeout),
+		DefaultTarget:     rr.Key("default_target").MustString(defaultRecordingTarget),
+		MaxWriteAttempts:  rr.Key("max_write_attempts").MustInt(defaultRecordingWriteAttempts),
+		WriteRetryBackoff: rr.Key("write_retry_backoff").MustDuration(defaultRecordingWriteBackoff),
 	}
 
 	rrHeaders := iniFile.Section("recording_rules.custom_headers")
@@ -449,6 +508,39 @@ func (cfg *Cfg) ReadUnifiedAlertingSettings(iniFile *ini.File) error {
 		uaCfgRecordingRules.CustomHeaders[key.Name()] = key.Value()
 	}
 
+	rrInflux := iniFile.Section("recording_rules.influxdb")
+	uaCfgRecordingRules.InfluxDB = RecordingRuleInfluxDBSettings{
+		URL:     rrInflux.Key("url").MustString(""),
+		Token:   rrInflux.Key("token").MustString(""),
+		Org:     rrInflux.Key("org").MustString(""),
+		Bucket:  rrInflux.Key("bucket").MustString(""),
+		Timeout: rrInflux.Key("timeout").MustDuration(defaultRecordingRequestTimeout),
+	}
+
+	rrOTLP := iniFile.Section("recording_rules.otlp")
+	uaCfgRecordingRules.OTLP = RecordingRuleOTLPSettings{
+		URL:     rrOTLP.Key("url").MustString(""),
+		Timeout: rrOTLP.Key("timeout").MustDuration(defaultRecordingRequestTimeout),
+	}
+	rrOTLPHeadersKeys := iniFile.Section("recording_rules.otlp.custom_headers").Keys()
+	uaCfgRecordingRules.OTLP.CustomHeaders = make(map[string]string, len(rrOTLPHeadersKeys))
+	for _, key := range rrOTLPHeadersKeys {
+		uaCfgRecordingRules.OTLP.CustomHeaders[key.Name()] = key.Value()
+	}
+
+	rrSQL := iniFile.Section("recording_rules.sql")
+	uaCfgRecordingRules.SQL = RecordingRuleSQLSettings{
+		Enabled:   rrSQL.Key("enabled").MustBool(false),
+		Retention: rrSQL.Key("retention").MustDuration(defaultRecordingSQLRetention),
+	}
+
+	rrQueue := iniFile.Section("recording_rules.write_queue")
+	uaCfgRecordingRules.WriteQueue = RecordingRuleWriteQueueSettings{
+		Enabled:    rrQueue.Key("enabled").MustBool(false),
+		MaxBatches: rrQueue.Key("max_batches").MustInt64(defaultRecordingQueueBatches),
+		MaxBackoff: rrQueue.Key("max_backoff").MustDuration(defaultRecordingQueueBackoff),
+	}
+
 	uaCfg.RecordingRules = uaCfgRecordingRules
 
 	uaCfg.MaxStateSaveConcurrency = ua.Key("max_state_save_concurrency").MustInt(1)
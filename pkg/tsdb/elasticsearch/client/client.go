@@ -38,6 +38,7 @@ type DatasourceInfo struct {
 	Interval                   string
 	MaxConcurrentShardRequests int64
 	IncludeFrozen              bool
+	MaxRows                    int
 }
 
 type ConfiguredFields struct {
@@ -51,6 +52,8 @@ type Client interface {
 	GetConfiguredFields() ConfiguredFields
 	ExecuteMultisearch(r *MultiSearchRequest) (*MultiSearchResponse, error)
 	MultiSearch() *MultiSearchRequestBuilder
+	OpenPointInTime(timeRange backend.TimeRange, keepAlive string) (string, error)
+	ClosePointInTime(id string) error
 }
 
 // NewClient creates a new elasticsearch client
@@ -97,7 +100,7 @@ func (c *baseClientImpl) executeBatchRequest(uriPath, uriQuery string, requests
 	if err != nil {
 		return nil, err
 	}
-	return c.executeRequest(http.MethodPost, uriPath, uriQuery, bytes)
+	return c.executeRequest(http.MethodPost, uriPath, uriQuery, "application/x-ndjson", bytes)
 }
 
 func (c *baseClientImpl) encodeBatchRequests(requests []*multiRequest) ([]byte, error) {
@@ -129,7 +132,7 @@ func (c *baseClientImpl) encodeBatchRequests(requests []*multiRequest) ([]byte,
 	return payload.Bytes(), nil
 }
 
-func (c *baseClientImpl) executeRequest(method, uriPath, uriQuery string, body []byte) (*http.Response, error) {
+func (c *baseClientImpl) executeRequest(method, uriPath, uriQuery, contentType string, body []byte) (*http.Response, error) {
 	c.logger.Debug("Sending request to Elasticsearch", "url", c.ds.URL)
 	u, err := url.Parse(c.ds.URL)
 	if err != nil {
@@ -139,16 +142,16 @@ func (c *baseClientImpl) executeRequest(method, uriPath, uriQuery string, body [
 	u.RawQuery = uriQuery
 
 	var req *http.Request
-	if method == http.MethodPost {
-		req, err = http.NewRequestWithContext(c.ctx, http.MethodPost, u.String(), bytes.NewBuffer(body))
-	} else {
+	if method == http.MethodGet {
 		req, err = http.NewRequestWithContext(c.ctx, http.MethodGet, u.String(), nil)
+	} else {
+		req, err = http.NewRequestWithContext(c.ctx, method, u.String(), bytes.NewBuffer(body))
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Content-Type", contentType)
 
 	//nolint:bodyclose
 	resp, err := c.ds.HTTPClient.Do(req)
@@ -243,6 +246,10 @@ func (c *baseClientImpl) createMultiSearchRequests(searchRequests []*SearchReque
 			body:     searchReq,
 			interval: searchReq.Interval,
 		}
+		if searchReq.PointInTime != nil {
+			// the indices are those of the point in time
+			mr.header = map[string]any{"search_type": "query_then_fetch"}
+		}
 
 		multiRequests = append(multiRequests, &mr)
 	}
@@ -264,3 +271,60 @@ func (c *baseClientImpl) getMultiSearchQueryParameters() string {
 func (c *baseClientImpl) MultiSearch() *MultiSearchRequestBuilder {
 	return NewMultiSearchRequestBuilder()
 }
+
+// OpenPointInTime opens a point in time on the indices of the time range, to paginate through the documents of
+// searches with search_after. It returns the id of the point in time.
+func (c *baseClientImpl) OpenPointInTime(timeRange backend.TimeRange, keepAlive string) (string, error) {
+	indices, err := c.indexPattern.GetIndices(timeRange)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("keep_alive", keepAlive)
+	params.Set("ignore_unavailable", "true")
+	res, err := c.executeRequest(http.MethodPost, path.Join(strings.Join(indices, ","), "_pit"), params.Encode(), "application/json", nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			c.logger.Warn("Failed to close response body", "error", err)
+		}
+	}()
+
+	if res.StatusCode/100 != 2 {
+		err := fmt.Errorf("failed to open point in time, status: %d", res.StatusCode)
+		if backend.ErrorSourceFromHTTPStatus(res.StatusCode) == backend.ErrorSourceDownstream {
+			return "", backend.DownstreamError(err)
+		}
+		return "", err
+	}
+
+	var pit struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&pit); err != nil {
+		return "", fmt.Errorf("failed to decode point in time: %w", err)
+	}
+	return pit.ID, nil
+}
+
+// ClosePointInTime closes a point in time before it expires, to free its resources
+func (c *baseClientImpl) ClosePointInTime(id string) error {
+	body, err := json.Marshal(map[string]string{"id": id})
+	if err != nil {
+		return err
+	}
+	res, err := c.executeRequest(http.MethodDelete, "_pit", "", "application/json", body)
+	if err != nil {
+		return err
+	}
+	if err := res.Body.Close(); err != nil {
+		c.logger.Warn("Failed to close response body", "error", err)
+	}
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to close point in time, status: %d", res.StatusCode)
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -286,6 +287,86 @@ func TestClient_Index(t *testing.T) {
 	}
 }
 
+func TestClient_PointInTime(t *testing.T) {
+	var requests []*http.Request
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		buf, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		requests = append(requests, r)
+		bodies = append(bodies, string(buf))
+
+		switch r.URL.Path {
+		case "/_msearch":
+			_, err = rw.Write([]byte(`{"responses": [{"hits": {"hits": []}, "pit_id": "pit-2", "status": 200}]}`))
+		case "/_pit":
+			_, err = rw.Write([]byte(`{"succeeded": true, "num_freed": 1}`))
+		default:
+			_, err = rw.Write([]byte(`{"id": "pit-1"}`))
+		}
+		require.NoError(t, err)
+	}))
+	t.Cleanup(ts.Close)
+
+	ds := DatasourceInfo{
+		URL:                        ts.URL,
+		HTTPClient:                 ts.Client(),
+		Database:                   "[metrics-]YYYY.MM.DD",
+		ConfiguredFields:           ConfiguredFields{TimeField: "@timestamp"},
+		Interval:                   "Daily",
+		MaxConcurrentShardRequests: 6,
+	}
+	timeRange := backend.TimeRange{
+		From: time.Date(2018, 5, 14, 17, 50, 0, 0, time.UTC),
+		To:   time.Date(2018, 5, 15, 17, 55, 0, 0, time.UTC),
+	}
+
+	c, err := NewClient(context.Background(), &ds, log.New())
+	require.NoError(t, err)
+
+	t.Run("Opens a point in time on the indices of the time range", func(t *testing.T) {
+		requests, bodies = nil, nil
+		id, err := c.OpenPointInTime(timeRange, "1m")
+		require.NoError(t, err)
+		assert.Equal(t, "pit-1", id)
+
+		require.Len(t, requests, 1)
+		assert.Equal(t, http.MethodPost, requests[0].Method)
+		assert.Equal(t, "/metrics-2018.05.14,metrics-2018.05.15/_pit", requests[0].URL.Path)
+		assert.Equal(t, "ignore_unavailable=true&keep_alive=1m", requests[0].URL.RawQuery)
+	})
+
+	t.Run("Searches on a point in time without indices", func(t *testing.T) {
+		requests, bodies = nil, nil
+		msb := c.MultiSearch()
+		msb.Search(15*time.Second, timeRange).Size(10).PointInTime("pit-1", "1m")
+		ms, err := msb.Build()
+		require.NoError(t, err)
+
+		res, err := c.ExecuteMultisearch(ms)
+		require.NoError(t, err)
+		assert.Equal(t, "pit-2", res.Responses[0].PitID)
+
+		require.Len(t, bodies, 1)
+		lines := strings.Split(strings.TrimSpace(bodies[0]), "\n")
+		require.Len(t, lines, 2)
+		assert.JSONEq(t, `{"search_type": "query_then_fetch"}`, lines[0])
+		body, err := simplejson.NewJson([]byte(lines[1]))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"id": "pit-1", "keep_alive": "1m"}, body.Get("pit").MustMap())
+	})
+
+	t.Run("Closes a point in time", func(t *testing.T) {
+		requests, bodies = nil, nil
+		require.NoError(t, c.ClosePointInTime("pit-2"))
+
+		require.Len(t, requests, 1)
+		assert.Equal(t, http.MethodDelete, requests[0].Method)
+		assert.Equal(t, "/_pit", requests[0].URL.Path)
+		assert.JSONEq(t, `{"id": "pit-2"}`, bodies[0])
+	})
+}
+
 func createMultisearchForTest(t *testing.T, c Client, timeRange backend.TimeRange) (*MultiSearchRequest, error) {
 	t.Helper()
 
@@ -20,6 +20,13 @@ type SearchRequest struct {
 	Aggs        AggArray
 	CustomProps map[string]interface{}
 	TimeRange   backend.TimeRange
+	PointInTime *PointInTime
+}
+
+// PointInTime represents the point in time a search request is executed on
+type PointInTime struct {
+	ID        string `json:"id"`
+	KeepAlive string `json:"keep_alive"`
 }
 
 // MarshalJSON returns the JSON encoding of the request.
@@ -37,6 +44,10 @@ func (r *SearchRequest) MarshalJSON() ([]byte, error) {
 
 	root["query"] = r.Query
 
+	if r.PointInTime != nil {
+		root["pit"] = r.PointInTime
+	}
+
 	if len(r.Aggs) > 0 {
 		root["aggs"] = r.Aggs
 	}
@@ -54,6 +65,7 @@ type SearchResponse struct {
 	Error        map[string]interface{} `json:"error"`
 	Aggregations map[string]interface{} `json:"aggregations"`
 	Hits         *SearchResponseHits    `json:"hits"`
+	PitID        string                 `json:"pit_id"`
 }
 
 // MultiSearchRequest represents a multi search request
@@ -245,6 +257,64 @@ type TermsAggregation struct {
 	Missing     *string                `json:"missing,omitempty"`
 }
 
+// MultiTermsAggregation represents a multi terms aggregation
+type MultiTermsAggregation struct {
+	Terms       []MultiTermsField      `json:"terms"`
+	Size        int                    `json:"size"`
+	Order       map[string]interface{} `json:"order"`
+	MinDocCount *int                   `json:"min_doc_count,omitempty"`
+}
+
+// MultiTermsField represents a field of a multi terms aggregation
+type MultiTermsField struct {
+	Field string `json:"field"`
+}
+
+// CompositeAggregation represents a composite aggregation
+type CompositeAggregation struct {
+	Sources []*CompositeSource     `json:"sources"`
+	Size    int                    `json:"size"`
+	After   map[string]interface{} `json:"after,omitempty"`
+}
+
+// CompositeSource represents a values source of a composite aggregation
+type CompositeSource struct {
+	Name             string
+	Type             string
+	Field            string
+	Interval         int
+	FixedInterval    string
+	CalendarInterval string
+	MissingBucket    bool
+}
+
+// MarshalJSON returns the JSON encoding of the composite source
+func (s *CompositeSource) MarshalJSON() ([]byte, error) {
+	source := map[string]interface{}{
+		"field": s.Field,
+	}
+	if s.Interval > 0 {
+		source["interval"] = s.Interval
+	}
+	if s.FixedInterval != "" {
+		source["fixed_interval"] = s.FixedInterval
+	}
+	if s.CalendarInterval != "" {
+		source["calendar_interval"] = s.CalendarInterval
+	}
+	if s.MissingBucket {
+		source["missing_bucket"] = true
+	}
+
+	root := map[string]interface{}{
+		s.Name: map[string]interface{}{
+			s.Type: source,
+		},
+	}
+
+	return json.Marshal(root)
+}
+
 // NestedAggregation represents a nested aggregation
 type NestedAggregation struct {
 	Path string `json:"path"`
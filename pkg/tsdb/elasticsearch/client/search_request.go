@@ -34,6 +34,7 @@ type SearchRequestBuilder struct {
 	aggBuilders  []AggBuilder
 	customProps  map[string]any
 	timeRange    backend.TimeRange
+	pointInTime  *PointInTime
 }
 
 // NewSearchRequestBuilder create a new search request builder
@@ -57,6 +58,7 @@ func (b *SearchRequestBuilder) Build() (*SearchRequest, error) {
 		Size:        b.size,
 		Sort:        b.sort,
 		CustomProps: b.customProps,
+		PointInTime: b.pointInTime,
 	}
 
 	if b.queryBuilder != nil {
@@ -145,6 +147,12 @@ func (b *SearchRequestBuilder) AddSearchAfter(value any) *SearchRequestBuilder {
 	return b
 }
 
+// PointInTime sets the point in time the search request is executed on
+func (b *SearchRequestBuilder) PointInTime(id string, keepAlive string) *SearchRequestBuilder {
+	b.pointInTime = &PointInTime{ID: id, KeepAlive: keepAlive}
+	return b
+}
+
 // Query creates and return a query builder
 func (b *SearchRequestBuilder) Query() *QueryBuilder {
 	if b.queryBuilder == nil {
@@ -160,6 +168,47 @@ func (b *SearchRequestBuilder) Agg() AggBuilder {
 	return aggBuilder
 }
 
+// NextPage returns the search request of the page of documents following the document with the given sort values.
+// The request must be executed on a point in time, whose id can change with every response. The aggregations are
+// only computed for the first page.
+func (r *SearchRequest) NextPage(pitID string, searchAfter []any, size int) *SearchRequest {
+	next := *r
+	next.Size = size
+	next.Aggs = nil
+	next.PointInTime = &PointInTime{ID: pitID, KeepAlive: r.PointInTime.KeepAlive}
+	next.CustomProps = make(map[string]any, len(r.CustomProps))
+	for key, value := range r.CustomProps {
+		next.CustomProps[key] = value
+	}
+	next.CustomProps["search_after"] = searchAfter
+	return &next
+}
+
+// NextCompositePage returns the search request of the page of buckets following the given after key of the
+// top-level composite aggregation with the given key.
+func (r *SearchRequest) NextCompositePage(key string, after map[string]any) *SearchRequest {
+	next := *r
+	next.Aggs = make(AggArray, 0, len(r.Aggs))
+	for _, agg := range r.Aggs {
+		composite, ok := agg.Aggregation.Aggregation.(*CompositeAggregation)
+		if agg.Key != key || !ok {
+			next.Aggs = append(next.Aggs, agg)
+			continue
+		}
+		page := *composite
+		page.After = after
+		next.Aggs = append(next.Aggs, &Agg{
+			Key: agg.Key,
+			Aggregation: &aggContainer{
+				Type:        agg.Aggregation.Type,
+				Aggregation: &page,
+				Aggs:        agg.Aggregation.Aggs,
+			},
+		})
+	}
+	return &next
+}
+
 // MultiSearchRequestBuilder represents a builder which can build a multi search request
 type MultiSearchRequestBuilder struct {
 	requestBuilders []*SearchRequestBuilder
@@ -305,6 +354,8 @@ type AggBuilder interface {
 	Histogram(key, field string, fn func(a *HistogramAgg, b AggBuilder)) AggBuilder
 	DateHistogram(key, field string, fn func(a *DateHistogramAgg, b AggBuilder)) AggBuilder
 	Terms(key, field string, fn func(a *TermsAggregation, b AggBuilder)) AggBuilder
+	MultiTerms(key string, fields []string, fn func(a *MultiTermsAggregation, b AggBuilder)) AggBuilder
+	Composite(key string, sources []*CompositeSource, fn func(a *CompositeAggregation, b AggBuilder)) AggBuilder
 	Nested(key, path string, fn func(a *NestedAggregation, b AggBuilder)) AggBuilder
 	Filters(key string, fn func(a *FiltersAggregation, b AggBuilder)) AggBuilder
 	GeoHashGrid(key, field string, fn func(a *GeoHashGridAggregation, b AggBuilder)) AggBuilder
@@ -416,6 +467,55 @@ func (b *aggBuilderImpl) Terms(key, field string, fn func(a *TermsAggregation, b
 	return b
 }
 
+func (b *aggBuilderImpl) MultiTerms(key string, fields []string, fn func(a *MultiTermsAggregation, b AggBuilder)) AggBuilder {
+	innerAgg := &MultiTermsAggregation{
+		Terms: make([]MultiTermsField, 0, len(fields)),
+		Order: make(map[string]any),
+	}
+	for _, field := range fields {
+		innerAgg.Terms = append(innerAgg.Terms, MultiTermsField{Field: field})
+	}
+	aggDef := newAggDef(key, &aggContainer{
+		Type:        "multi_terms",
+		Aggregation: innerAgg,
+	})
+
+	if fn != nil {
+		builder := newAggBuilder()
+		aggDef.builders = append(aggDef.builders, builder)
+		fn(innerAgg, builder)
+	}
+
+	if orderBy, exists := innerAgg.Order[termsOrderTerm]; exists {
+		innerAgg.Order["_key"] = orderBy
+		delete(innerAgg.Order, termsOrderTerm)
+	}
+
+	b.aggDefs = append(b.aggDefs, aggDef)
+
+	return b
+}
+
+func (b *aggBuilderImpl) Composite(key string, sources []*CompositeSource, fn func(a *CompositeAggregation, b AggBuilder)) AggBuilder {
+	innerAgg := &CompositeAggregation{
+		Sources: sources,
+	}
+	aggDef := newAggDef(key, &aggContainer{
+		Type:        "composite",
+		Aggregation: innerAgg,
+	})
+
+	if fn != nil {
+		builder := newAggBuilder()
+		aggDef.builders = append(aggDef.builders, builder)
+		fn(innerAgg, builder)
+	}
+
+	b.aggDefs = append(b.aggDefs, aggDef)
+
+	return b
+}
+
 func (b *aggBuilderImpl) Nested(key, field string, fn func(a *NestedAggregation, b AggBuilder)) AggBuilder {
 	innerAgg := &NestedAggregation{
 		Path: field,
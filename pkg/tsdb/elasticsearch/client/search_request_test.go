@@ -402,6 +402,113 @@ func TestSearchRequest(t *testing.T) {
 			})
 		})
 	})
+	t.Run("When requesting the next page of a search on a point in time", func(t *testing.T) {
+		b := setup()
+		b.Size(100)
+		b.Sort(SortOrderDesc, timeField, "boolean")
+		b.AddDocValueField(timeField)
+		b.PointInTime("pit-1", "1m")
+		b.Agg().DateHistogram("1", timeField, func(a *DateHistogramAgg, ab AggBuilder) {})
+		sr, err := b.Build()
+		require.Nil(t, err)
+
+		next := sr.NextPage("pit-2", []any{1526406600000, 5}, 50)
+
+		t.Run("Should not change the first page", func(t *testing.T) {
+			require.Equal(t, 100, sr.Size)
+			require.Equal(t, "pit-1", sr.PointInTime.ID)
+			require.Len(t, sr.Aggs, 1)
+			require.Nil(t, sr.CustomProps["search_after"])
+		})
+
+		t.Run("When marshal to JSON should generate correct json", func(t *testing.T) {
+			body, err := json.Marshal(next)
+			require.Nil(t, err)
+			json, err := simplejson.NewJson(body)
+			require.Nil(t, err)
+			require.Equal(t, 50, json.Get("size").MustInt())
+			require.Equal(t, "pit-2", json.GetPath("pit", "id").MustString())
+			require.Equal(t, "1m", json.GetPath("pit", "keep_alive").MustString())
+			require.Len(t, json.Get("search_after").MustArray(), 2)
+			require.Equal(t, int64(1526406600000), json.Get("search_after").GetIndex(0).MustInt64())
+			require.Equal(t, int64(5), json.Get("search_after").GetIndex(1).MustInt64())
+			require.Equal(t, "desc", json.GetPath("sort", timeField, "order").MustString())
+			require.Nil(t, json.Get("aggs").Interface())
+		})
+	})
+
+	t.Run("and adding multi terms agg", func(t *testing.T) {
+		b := setup()
+		b.Agg().MultiTerms("1", []string{"@hostname", "@app"}, func(a *MultiTermsAggregation, ib AggBuilder) {
+			a.Size = 10
+			a.Order[termsOrderTerm] = "asc"
+			ib.DateHistogram("2", "@timestamp", nil)
+		})
+
+		sr, err := b.Build()
+		require.Nil(t, err)
+
+		t.Run("When marshal to JSON should generate correct json", func(t *testing.T) {
+			body, err := json.Marshal(sr)
+			require.Nil(t, err)
+			json, err := simplejson.NewJson(body)
+			require.Nil(t, err)
+
+			multiTerms := json.GetPath("aggs", "1", "multi_terms")
+			require.Len(t, multiTerms.Get("terms").MustArray(), 2)
+			require.Equal(t, "@hostname", multiTerms.Get("terms").GetIndex(0).Get("field").MustString())
+			require.Equal(t, "@app", multiTerms.Get("terms").GetIndex(1).Get("field").MustString())
+			require.Equal(t, 10, multiTerms.Get("size").MustInt())
+			require.Equal(t, "asc", multiTerms.GetPath("order", "_key").MustString())
+			require.Equal(t, "@timestamp", json.GetPath("aggs", "1", "aggs", "2", "date_histogram", "field").MustString())
+		})
+	})
+
+	t.Run("and adding composite agg", func(t *testing.T) {
+		b := setup()
+		b.Agg().Composite("1", []*CompositeSource{
+			{Name: "@hostname", Type: "terms", Field: "@hostname", MissingBucket: true},
+			{Name: "@timestamp", Type: "date_histogram", Field: "@timestamp", FixedInterval: "1h"},
+		}, func(a *CompositeAggregation, ib AggBuilder) {
+			a.Size = 100
+			ib.Metric("2", "avg", "@value", nil)
+		})
+
+		sr, err := b.Build()
+		require.Nil(t, err)
+		next := sr.NextCompositePage("1", map[string]any{"@hostname": "server-1", "@timestamp": 1526406600000})
+
+		t.Run("When marshal to JSON should generate correct json", func(t *testing.T) {
+			body, err := json.Marshal(sr)
+			require.Nil(t, err)
+			json, err := simplejson.NewJson(body)
+			require.Nil(t, err)
+
+			composite := json.GetPath("aggs", "1", "composite")
+			require.Equal(t, 100, composite.Get("size").MustInt())
+			require.Nil(t, composite.Get("after").Interface())
+			sources := composite.Get("sources")
+			require.Len(t, sources.MustArray(), 2)
+			require.Equal(t, "@hostname", sources.GetIndex(0).GetPath("@hostname", "terms", "field").MustString())
+			require.True(t, sources.GetIndex(0).GetPath("@hostname", "terms", "missing_bucket").MustBool())
+			require.Equal(t, "1h", sources.GetIndex(1).GetPath("@timestamp", "date_histogram", "fixed_interval").MustString())
+			require.Nil(t, sources.GetIndex(1).GetPath("@timestamp", "date_histogram", "missing_bucket").Interface())
+			require.Equal(t, "@value", json.GetPath("aggs", "1", "aggs", "2", "avg", "field").MustString())
+		})
+
+		t.Run("Next page should resume after the after key", func(t *testing.T) {
+			body, err := json.Marshal(next)
+			require.Nil(t, err)
+			json, err := simplejson.NewJson(body)
+			require.Nil(t, err)
+
+			after := json.GetPath("aggs", "1", "composite", "after")
+			require.Equal(t, "server-1", after.Get("@hostname").MustString())
+			require.Equal(t, int64(1526406600000), after.Get("@timestamp").MustInt64())
+			require.Equal(t, "@value", json.GetPath("aggs", "1", "aggs", "2", "avg", "field").MustString())
+			require.Nil(t, sr.Aggs[0].Aggregation.Aggregation.(*CompositeAggregation).After)
+		})
+	})
 }
 
 func TestMultiSearchRequest(t *testing.T) {
@@ -11,6 +11,7 @@ import (
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	es "github.com/grafana/grafana/pkg/tsdb/elasticsearch/client"
@@ -18,6 +19,11 @@ import (
 
 const (
 	defaultSize = 500
+	// documentPageSize is the maximum number of documents of a search request, the default max_result_window of
+	// the indices. Raw data, raw document and logs queries of more documents are paginated.
+	documentPageSize = 10000
+	// pointInTimeKeepAlive is how long the point in time of paginated queries is kept between two pages
+	pointInTimeKeepAlive = "1m"
 )
 
 type elasticsearchDataQuery struct {
@@ -26,9 +32,10 @@ type elasticsearchDataQuery struct {
 	logger               log.Logger
 	ctx                  context.Context
 	keepLabelsInResponse bool
+	maxRows              int
 }
 
-var newElasticsearchDataQuery = func(ctx context.Context, client es.Client, req *backend.QueryDataRequest, logger log.Logger) *elasticsearchDataQuery {
+var newElasticsearchDataQuery = func(ctx context.Context, client es.Client, req *backend.QueryDataRequest, logger log.Logger, maxRows int) *elasticsearchDataQuery {
 	_, fromAlert := req.Headers[headerFromAlert]
 	fromExpression := req.GetHTTPHeader(headerFromExpression) != ""
 
@@ -40,6 +47,7 @@ var newElasticsearchDataQuery = func(ctx context.Context, client es.Client, req
 		// To maintain backward compatibility, it is necessary to keep labels in responses for alerting and expressions queries.
 		// Historically, these labels have been used in alerting rules and transformations.
 		keepLabelsInResponse: fromAlert || fromExpression,
+		maxRows:              maxRows,
 	}
 }
 
@@ -57,10 +65,14 @@ func (e *elasticsearchDataQuery) execute() (*backend.QueryDataResponse, error) {
 
 	ms := e.client.MultiSearch()
 
-	for _, q := range queries {
+	// the points in time of paginated queries are closed once they are opened, and their id can change with every
+	// page. The queries and the requests share their index.
+	pitIDs := make([]string, len(queries))
+	defer func() { e.closePointsInTime(pitIDs) }()
+	for i, q := range queries {
 		from := q.TimeRange.From.UnixNano() / int64(time.Millisecond)
 		to := q.TimeRange.To.UnixNano() / int64(time.Millisecond)
-		if err := e.processQuery(q, ms, from, to); err != nil {
+		if pitIDs[i], err = e.processQuery(q, ms, from, to); err != nil {
 			mq, _ := json.Marshal(q)
 			e.logger.Error("Failed to process query to multisearch request builder", "error", err, "query", string(mq), "queriesLength", len(queries), "duration", time.Since(start), "stage", es.StagePrepareRequest)
 			response.Responses[q.RefID] = backend.ErrorResponseWithErrorSource(err)
@@ -96,13 +108,184 @@ func (e *elasticsearchDataQuery) execute() (*backend.QueryDataResponse, error) {
 		return response, nil
 	}
 
-	return parseResponse(e.ctx, res.Responses, queries, e.client.GetConfiguredFields(), e.keepLabelsInResponse, e.logger)
+	notices := make(map[string]string)
+	for i, q := range queries {
+		if i >= len(res.Responses) || i >= len(req.Requests) {
+			continue
+		}
+		switch {
+		case isLogsQuery(q) || isDocumentQuery(q):
+			if req.Requests[i].PointInTime != nil {
+				pitIDs[i] = e.fetchDocumentPages(q, req.Requests[i], res.Responses[i])
+			}
+			notices[q.RefID] = e.documentTruncationNotice(q, req.Requests[i], res.Responses[i])
+		case isCompositeQuery(q):
+			if e.fetchCompositePages(q, req.Requests[i], res.Responses[i]) {
+				notices[q.RefID] = maxRowsNotice(e.maxRows)
+			}
+		}
+	}
+
+	result, err := parseResponse(e.ctx, res.Responses, queries, e.client.GetConfiguredFields(), e.keepLabelsInResponse, e.logger)
+	if err != nil {
+		return result, err
+	}
+	for refID, notice := range notices {
+		if notice != "" {
+			addTruncatedNotice(result.Responses[refID], notice)
+		}
+	}
+	return result, nil
+}
+
+// fetchDocumentPages fetches the pages of documents following the first page of a paginated query with
+// search_after, and appends their documents to the response of the first page. It returns the latest id of the
+// point in time.
+func (e *elasticsearchDataQuery) fetchDocumentPages(q *Query, first *es.SearchRequest, res *es.SearchResponse) string {
+	pitID := first.PointInTime.ID
+	limit := e.documentLimit(q)
+	page := res
+	for res.Error == nil && page.Hits != nil && len(page.Hits.Hits) == first.Size && len(res.Hits.Hits) < limit {
+		searchAfter, ok := page.Hits.Hits[len(page.Hits.Hits)-1]["sort"].([]any)
+		if !ok {
+			break
+		}
+		if page.PitID != "" {
+			pitID = page.PitID
+		}
+
+		next := first.NextPage(pitID, searchAfter, min(documentPageSize, limit-len(res.Hits.Hits)))
+		pageRes, err := e.client.ExecuteMultisearch(&es.MultiSearchRequest{Requests: []*es.SearchRequest{next}})
+		if err != nil || len(pageRes.Responses) == 0 {
+			e.logger.Warn("Failed to fetch page of documents", "error", err, "refId", q.RefID)
+			res.Error = map[string]any{"reason": "failed to fetch all the documents"}
+			return pitID
+		}
+		page = pageRes.Responses[0]
+		if page.Error != nil {
+			res.Error = page.Error
+			return pitID
+		}
+		if page.Hits != nil {
+			res.Hits.Hits = append(res.Hits.Hits, page.Hits.Hits...)
+		}
+		first = next
+	}
+	if page.PitID != "" {
+		pitID = page.PitID
+	}
+	return pitID
+}
+
+// fetchCompositePages fetches the pages of buckets following the first page of the composite aggregation of a query
+// with its after key, and appends their buckets to the response of the first page. It returns whether the buckets
+// were truncated to the maximum number of rows.
+func (e *elasticsearchDataQuery) fetchCompositePages(q *Query, first *es.SearchRequest, res *es.SearchResponse) bool {
+	bucketAgg := q.BucketAggs[0]
+	agg, ok := res.Aggregations[bucketAgg.ID].(map[string]any)
+	if res.Error != nil || !ok {
+		return false
+	}
+
+	size := termsSize(bucketAgg)
+	buckets, _ := agg["buckets"].([]any)
+	truncated := false
+	page := agg
+	for {
+		afterKey, hasAfterKey := page["after_key"].(map[string]any)
+		pageBuckets, _ := page["buckets"].([]any)
+		if !hasAfterKey || len(pageBuckets) < size {
+			break
+		}
+		if e.maxRows > 0 && len(buckets) >= e.maxRows {
+			truncated = true
+			break
+		}
+
+		next := first.NextCompositePage(bucketAgg.ID, afterKey)
+		pageRes, err := e.client.ExecuteMultisearch(&es.MultiSearchRequest{Requests: []*es.SearchRequest{next}})
+		if err != nil || len(pageRes.Responses) == 0 {
+			e.logger.Warn("Failed to fetch page of buckets", "error", err, "refId", q.RefID)
+			res.Error = map[string]any{"reason": "failed to fetch all the buckets"}
+			return false
+		}
+		if pageRes.Responses[0].Error != nil {
+			res.Error = pageRes.Responses[0].Error
+			return false
+		}
+		if page, ok = pageRes.Responses[0].Aggregations[bucketAgg.ID].(map[string]any); !ok {
+			break
+		}
+		pageBuckets, _ = page["buckets"].([]any)
+		buckets = append(buckets, pageBuckets...)
+	}
+
+	if e.maxRows > 0 && len(buckets) > e.maxRows {
+		buckets = buckets[:e.maxRows]
+		truncated = true
+	}
+	agg["buckets"] = buckets
+	return truncated
+}
+
+// closePointsInTime closes the points in time of the paginated queries. They would otherwise be kept until their
+// keep alive expires.
+func (e *elasticsearchDataQuery) closePointsInTime(ids []string) {
+	for _, id := range ids {
+		if id == "" {
+			continue
+		}
+		if err := e.client.ClosePointInTime(id); err != nil {
+			e.logger.Warn("Failed to close point in time", "error", err)
+		}
+	}
+}
+
+// documentTruncationNotice returns the notice of a query whose documents were truncated, or an empty string if they
+// were not truncated. The documents are truncated to the first page if the point in time to fetch the following
+// pages couldn't be opened, and to the maximum number of rows otherwise.
+func (e *elasticsearchDataQuery) documentTruncationNotice(q *Query, first *es.SearchRequest, res *es.SearchResponse) string {
+	if res.Error != nil || res.Hits == nil {
+		return ""
+	}
+	limit := e.documentLimit(q)
+	if first.PointInTime == nil && first.Size < limit {
+		if len(res.Hits.Hits) < first.Size {
+			return ""
+		}
+		return fmt.Sprintf("Results are limited to the first %d rows, the following rows could not be fetched.", first.Size)
+	}
+	if limit < requestedDocumentLimit(q) && len(res.Hits.Hits) == limit {
+		return maxRowsNotice(e.maxRows)
+	}
+	return ""
+}
+
+func maxRowsNotice(maxRows int) string {
+	return fmt.Sprintf("Results are limited to %d rows by the data source settings.", maxRows)
 }
 
-func (e *elasticsearchDataQuery) processQuery(q *Query, ms *es.MultiSearchRequestBuilder, from, to int64) error {
+// addTruncatedNotice adds a notice to the frames of the response of a query whose results were truncated.
+func addTruncatedNotice(res backend.DataResponse, notice string) {
+	if len(res.Frames) == 0 {
+		return
+	}
+	frame := res.Frames[0]
+	if frame.Meta == nil {
+		frame.Meta = &data.FrameMeta{}
+	}
+	frame.Meta.Notices = append(frame.Meta.Notices, data.Notice{
+		Severity: data.NoticeSeverityWarning,
+		Text:     notice,
+	})
+}
+
+// processQuery adds the request of a query to the multisearch request. It returns the id of the point in time
+// opened to paginate the documents of the query, which must be closed, or an empty string.
+func (e *elasticsearchDataQuery) processQuery(q *Query, ms *es.MultiSearchRequestBuilder, from, to int64) (string, error) {
 	err := isQueryWithError(q)
 	if err != nil {
-		return backend.DownstreamError(fmt.Errorf("received invalid query. %w", err))
+		return "", backend.DownstreamError(fmt.Errorf("received invalid query. %w", err))
 	}
 
 	defaultTimeField := e.client.GetConfiguredFields().TimeField
@@ -112,16 +295,32 @@ func (e *elasticsearchDataQuery) processQuery(q *Query, ms *es.MultiSearchReques
 	filters.AddDateRangeFilter(defaultTimeField, to, from, es.DateFormatEpochMS)
 	filters.AddQueryStringFilter(q.RawQuery, true)
 
-	if isLogsQuery(q) {
-		processLogsQuery(q, b, from, to, defaultTimeField)
-	} else if isDocumentQuery(q) {
-		processDocumentQuery(q, b, from, to, defaultTimeField)
+	if isLogsQuery(q) || isDocumentQuery(q) {
+		if isLogsQuery(q) {
+			processLogsQuery(q, b, from, to, defaultTimeField)
+		} else {
+			processDocumentQuery(q, b, from, to, defaultTimeField)
+		}
+
+		limit := e.documentLimit(q)
+		b.Size(min(limit, documentPageSize))
+		if limit > documentPageSize {
+			// The documents of the following pages are fetched with search_after once the first page is received.
+			// If the point in time can't be opened, only the first page is returned.
+			pitID, err := e.client.OpenPointInTime(q.TimeRange, pointInTimeKeepAlive)
+			if err != nil {
+				e.logger.Warn("Failed to open point in time, the documents are limited to the first page", "error", err, "refId", q.RefID)
+				return "", nil
+			}
+			b.PointInTime(pitID, pointInTimeKeepAlive)
+			return pitID, nil
+		}
 	} else {
 		// Otherwise, it is a time series query and we process it
 		processTimeSeriesQuery(q, b, from, to, defaultTimeField)
 	}
 
-	return nil
+	return "", nil
 }
 
 func setFloatPath(settings *simplejson.Json, path ...string) {
@@ -240,11 +439,7 @@ func addHistogramAgg(aggBuilder es.AggBuilder, bucketAgg *BucketAgg) es.AggBuild
 
 func addTermsAgg(aggBuilder es.AggBuilder, bucketAgg *BucketAgg, metrics []*MetricAgg) es.AggBuilder {
 	aggBuilder.Terms(bucketAgg.ID, bucketAgg.Field, func(a *es.TermsAggregation, b es.AggBuilder) {
-		if size, err := bucketAgg.Settings.Get("size").Int(); err == nil {
-			a.Size = size
-		} else {
-			a.Size = stringToIntWithDefaultValue(bucketAgg.Settings.Get("size").MustString(), defaultSize)
-		}
+		a.Size = termsSize(bucketAgg)
 
 		if minDocCount, err := bucketAgg.Settings.Get("min_doc_count").Int(); err == nil {
 			a.MinDocCount = &minDocCount
@@ -253,31 +448,99 @@ func addTermsAgg(aggBuilder es.AggBuilder, bucketAgg *BucketAgg, metrics []*Metr
 			a.Missing = &missing
 		}
 
-		if orderBy, err := bucketAgg.Settings.Get("orderBy").String(); err == nil {
-			/*
-			   The format for extended stats and percentiles is {metricId}[bucket_path]
-			   for everything else it's just {metricId}, _count, _term, or _key
-			*/
-			metricIdRegex := regexp.MustCompile(`^(\d+)`)
-			metricId := metricIdRegex.FindString(orderBy)
-
-			if len(metricId) > 0 {
-				for _, m := range metrics {
-					if m.ID == metricId {
-						if m.Type == "count" {
-							a.Order["_count"] = bucketAgg.Settings.Get("order").MustString("desc")
-						} else {
-							a.Order[orderBy] = bucketAgg.Settings.Get("order").MustString("desc")
-							b.Metric(m.ID, m.Type, m.Field, nil)
-						}
-						break
-					}
+		addTermsOrder(a.Order, b, bucketAgg, metrics)
+
+		aggBuilder = b
+	})
+
+	return aggBuilder
+}
+
+func addMultiTermsAgg(aggBuilder es.AggBuilder, bucketAgg *BucketAgg, metrics []*MetricAgg) es.AggBuilder {
+	fields := bucketAgg.Settings.Get("fields").MustStringArray()
+	aggBuilder.MultiTerms(bucketAgg.ID, fields, func(a *es.MultiTermsAggregation, b es.AggBuilder) {
+		a.Size = termsSize(bucketAgg)
+
+		if minDocCount, err := bucketAgg.Settings.Get("min_doc_count").Int(); err == nil {
+			a.MinDocCount = &minDocCount
+		}
+
+		addTermsOrder(a.Order, b, bucketAgg, metrics)
+
+		aggBuilder = b
+	})
+
+	return aggBuilder
+}
+
+func termsSize(bucketAgg *BucketAgg) int {
+	if size, err := bucketAgg.Settings.Get("size").Int(); err == nil {
+		return size
+	}
+	return stringToIntWithDefaultValue(bucketAgg.Settings.Get("size").MustString(), defaultSize)
+}
+
+func addTermsOrder(order map[string]any, b es.AggBuilder, bucketAgg *BucketAgg, metrics []*MetricAgg) {
+	orderBy, err := bucketAgg.Settings.Get("orderBy").String()
+	if err != nil {
+		return
+	}
+
+	/*
+	   The format for extended stats and percentiles is {metricId}[bucket_path]
+	   for everything else it's just {metricId}, _count, _term, or _key
+	*/
+	metricIdRegex := regexp.MustCompile(`^(\d+)`)
+	metricId := metricIdRegex.FindString(orderBy)
+
+	if len(metricId) > 0 {
+		for _, m := range metrics {
+			if m.ID == metricId {
+				if m.Type == "count" {
+					order["_count"] = bucketAgg.Settings.Get("order").MustString("desc")
+				} else {
+					order[orderBy] = bucketAgg.Settings.Get("order").MustString("desc")
+					b.Metric(m.ID, m.Type, m.Field, nil)
 				}
+				break
+			}
+		}
+	} else {
+		order[orderBy] = bucketAgg.Settings.Get("order").MustString("desc")
+	}
+}
+
+// addCompositeAgg adds a composite aggregation, whose sources are named by their fields. The following pages of
+// buckets are fetched with the after key once the first page is received.
+func addCompositeAgg(aggBuilder es.AggBuilder, bucketAgg *BucketAgg) es.AggBuilder {
+	sources := make([]*es.CompositeSource, 0)
+	for _, s := range bucketAgg.Settings.Get("sources").MustArray() {
+		settings := simplejson.NewFromAny(s)
+		source := &es.CompositeSource{
+			Name:          settings.Get("field").MustString(),
+			Type:          settings.Get("type").MustString(termsType),
+			Field:         settings.Get("field").MustString(),
+			MissingBucket: settings.Get("missing_bucket").MustBool(false),
+		}
+		switch source.Type {
+		case histogramType:
+			source.Interval = stringToIntWithDefaultValue(settings.Get("interval").MustString(), 1000)
+		case dateHistType:
+			interval := settings.Get("interval").MustString("auto")
+			if slices.Contains(es.GetCalendarIntervals(), interval) {
+				source.CalendarInterval = interval
+			} else if interval == "auto" {
+				// see addDateHistogramAgg
+				source.FixedInterval = "$__interval_msms"
 			} else {
-				a.Order[orderBy] = bucketAgg.Settings.Get("order").MustString("desc")
+				source.FixedInterval = interval
 			}
 		}
+		sources = append(sources, source)
+	}
 
+	aggBuilder.Composite(bucketAgg.ID, sources, func(a *es.CompositeAggregation, b es.AggBuilder) {
+		a.Size = termsSize(bucketAgg)
 		aggBuilder = b
 	})
 
@@ -336,6 +599,25 @@ func getPipelineAggField(m *MetricAgg) string {
 	return pipelineAggField
 }
 
+// requestedDocumentLimit returns the number of documents requested by a raw data, raw document or logs query
+func requestedDocumentLimit(q *Query) int {
+	metric := q.Metrics[0]
+	if isLogsQuery(q) {
+		return stringToIntWithDefaultValue(metric.Settings.Get("limit").MustString(), defaultSize)
+	}
+	return stringToIntWithDefaultValue(metric.Settings.Get("size").MustString(), defaultSize)
+}
+
+// documentLimit returns the number of documents fetched for a raw data, raw document or logs query, capped by the
+// maximum number of rows of the data source.
+func (e *elasticsearchDataQuery) documentLimit(q *Query) int {
+	limit := requestedDocumentLimit(q)
+	if e.maxRows > 0 {
+		limit = min(limit, e.maxRows)
+	}
+	return limit
+}
+
 func isQueryWithError(query *Query) error {
 	if len(query.BucketAggs) == 0 {
 		// If no aggregations, only document and logs queries are valid
@@ -343,6 +625,27 @@ func isQueryWithError(query *Query) error {
 			return fmt.Errorf("invalid query, missing metrics and aggregations")
 		}
 	}
+	for i, bucketAgg := range query.BucketAggs {
+		switch bucketAgg.Type {
+		case multiTermsType:
+			if len(bucketAgg.Settings.Get("fields").MustStringArray()) < 2 {
+				return fmt.Errorf("invalid query, multi terms aggregation requires at least 2 fields")
+			}
+		case compositeType:
+			if i > 0 {
+				return fmt.Errorf("invalid query, composite aggregation must be the first bucket aggregation")
+			}
+			sources := bucketAgg.Settings.Get("sources").MustArray()
+			if len(sources) == 0 {
+				return fmt.Errorf("invalid query, composite aggregation requires at least 1 source")
+			}
+			for _, source := range sources {
+				if simplejson.NewFromAny(source).Get("field").MustString() == "" {
+					return fmt.Errorf("invalid query, missing field of composite aggregation source")
+				}
+			}
+		}
+	}
 	return nil
 }
 
@@ -354,6 +657,10 @@ func isDocumentQuery(query *Query) bool {
 	return isRawDataQuery(query) || isRawDocumentQuery(query)
 }
 
+func isCompositeQuery(query *Query) bool {
+	return len(query.BucketAggs) > 0 && query.BucketAggs[0].Type == compositeType
+}
+
 func isRawDataQuery(query *Query) bool {
 	return query.Metrics[0].Type == rawDataType
 }
@@ -375,7 +682,6 @@ func processLogsQuery(q *Query, b *es.SearchRequestBuilder, from, to int64, defa
 	// We need to add timeField as field with standardized time format to not receive
 	// invalid formats that elasticsearch can parse, but our frontend can't (e.g. yyyy_MM_dd_HH_mm_ss)
 	b.AddTimeFieldWithStandardizedFormat(defaultTimeField)
-	b.Size(stringToIntWithDefaultValue(metric.Settings.Get("limit").MustString(), defaultSize))
 	b.AddHighlight()
 
 	// This is currently used only for log context query to get
@@ -403,7 +709,6 @@ func processLogsQuery(q *Query, b *es.SearchRequestBuilder, from, to int64, defa
 }
 
 func processDocumentQuery(q *Query, b *es.SearchRequestBuilder, from, to int64, defaultTimeField string) {
-	metric := q.Metrics[0]
 	b.Sort(es.SortOrderDesc, defaultTimeField, "boolean")
 	b.Sort(es.SortOrderDesc, "_doc", "")
 	b.AddDocValueField(defaultTimeField)
@@ -412,7 +717,6 @@ func processDocumentQuery(q *Query, b *es.SearchRequestBuilder, from, to int64,
 		// invalid formats that elasticsearch can parse, but our frontend can't (e.g. yyyy_MM_dd_HH_mm_ss)
 		b.AddTimeFieldWithStandardizedFormat(defaultTimeField)
 	}
-	b.Size(stringToIntWithDefaultValue(metric.Settings.Get("size").MustString(), defaultSize))
 }
 
 func processTimeSeriesQuery(q *Query, b *es.SearchRequestBuilder, from, to int64, defaultTimeField string) {
@@ -432,6 +736,10 @@ func processTimeSeriesQuery(q *Query, b *es.SearchRequestBuilder, from, to int64
 			aggBuilder = addFiltersAgg(aggBuilder, bucketAgg)
 		case termsType:
 			aggBuilder = addTermsAgg(aggBuilder, bucketAgg, q.Metrics)
+		case multiTermsType:
+			aggBuilder = addMultiTermsAgg(aggBuilder, bucketAgg, q.Metrics)
+		case compositeType:
+			aggBuilder = addCompositeAgg(aggBuilder, bucketAgg)
 		case geohashGridType:
 			aggBuilder = addGeoHashGridAgg(aggBuilder, bucketAgg)
 		case nestedType:
@@ -3,11 +3,14 @@ package elasticsearch
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -148,6 +151,106 @@ func TestExecuteElasticsearchDataQuery(t *testing.T) {
 			require.Equal(t, avgAgg.Aggregation.Aggregation.(*es.MetricAggregation).Field, "@value")
 		})
 
+		t.Run("With multi terms agg and order by metric agg", func(t *testing.T) {
+			c := newFakeClient()
+			_, err := executeElasticsearchDataQuery(c, `{
+				"bucketAggs": [
+					{
+						"type": "multi_terms",
+						"id": "2",
+						"settings": { "fields": ["@host", "@app"], "size": "5", "order": "asc", "orderBy": "5", "min_doc_count": "1" }
+					},
+					{ "type": "date_histogram", "field": "@timestamp", "id": "3" }
+				],
+				"metrics": [
+					{"type": "count", "id": "1" },
+					{"type": "avg", "field": "@value", "id": "5" }
+				]
+			}`, from, to)
+			require.NoError(t, err)
+			sr := c.multisearchRequests[0].Requests[0]
+
+			firstLevel := sr.Aggs[0]
+			require.Equal(t, "2", firstLevel.Key)
+			require.Equal(t, "multi_terms", firstLevel.Aggregation.Type)
+			multiTermsAgg := firstLevel.Aggregation.Aggregation.(*es.MultiTermsAggregation)
+			require.Equal(t, []es.MultiTermsField{{Field: "@host"}, {Field: "@app"}}, multiTermsAgg.Terms)
+			require.Equal(t, 5, multiTermsAgg.Size)
+			require.Equal(t, 1, *multiTermsAgg.MinDocCount)
+			require.Equal(t, "asc", multiTermsAgg.Order["5"])
+
+			avgAggOrderBy := firstLevel.Aggregation.Aggs[0]
+			require.Equal(t, "5", avgAggOrderBy.Key)
+			require.Equal(t, "avg", avgAggOrderBy.Aggregation.Type)
+
+			secondLevel := firstLevel.Aggregation.Aggs[1]
+			require.Equal(t, "3", secondLevel.Key)
+			require.Equal(t, "date_histogram", secondLevel.Aggregation.Type)
+		})
+
+		t.Run("With multi terms agg of a single field", func(t *testing.T) {
+			c := newFakeClient()
+			res, err := executeElasticsearchDataQuery(c, `{
+				"bucketAggs": [{ "type": "multi_terms", "id": "2", "settings": { "fields": ["@host"] } }],
+				"metrics": [{"type": "count", "id": "1" }]
+			}`, from, to)
+			require.NoError(t, err)
+			require.ErrorContains(t, res.Responses["A"].Error, "multi terms aggregation requires at least 2 fields")
+			require.Empty(t, c.multisearchRequests)
+		})
+
+		t.Run("With composite agg", func(t *testing.T) {
+			c := newFakeClient()
+			_, err := executeElasticsearchDataQuery(c, `{
+				"bucketAggs": [
+					{
+						"type": "composite",
+						"id": "2",
+						"settings": {
+							"size": "100",
+							"sources": [
+								{ "field": "@host", "missing_bucket": true },
+								{ "field": "@value", "type": "histogram", "interval": "10" },
+								{ "field": "@timestamp", "type": "date_histogram", "interval": "1M" }
+							]
+						}
+					}
+				],
+				"metrics": [{"type": "avg", "field": "@value", "id": "1" }]
+			}`, from, to)
+			require.NoError(t, err)
+			sr := c.multisearchRequests[0].Requests[0]
+
+			firstLevel := sr.Aggs[0]
+			require.Equal(t, "2", firstLevel.Key)
+			require.Equal(t, "composite", firstLevel.Aggregation.Type)
+			compositeAgg := firstLevel.Aggregation.Aggregation.(*es.CompositeAggregation)
+			require.Equal(t, 100, compositeAgg.Size)
+			require.Nil(t, compositeAgg.After)
+			require.Equal(t, []*es.CompositeSource{
+				{Name: "@host", Type: "terms", Field: "@host", MissingBucket: true},
+				{Name: "@value", Type: "histogram", Field: "@value", Interval: 10},
+				{Name: "@timestamp", Type: "date_histogram", Field: "@timestamp", CalendarInterval: "1M"},
+			}, compositeAgg.Sources)
+
+			avgAgg := firstLevel.Aggregation.Aggs[0]
+			require.Equal(t, "1", avgAgg.Key)
+			require.Equal(t, "avg", avgAgg.Aggregation.Type)
+		})
+
+		t.Run("With composite agg after another bucket agg", func(t *testing.T) {
+			c := newFakeClient()
+			res, err := executeElasticsearchDataQuery(c, `{
+				"bucketAggs": [
+					{ "type": "terms", "field": "@app", "id": "2" },
+					{ "type": "composite", "id": "3", "settings": { "sources": [{ "field": "@host" }] } }
+				],
+				"metrics": [{"type": "count", "id": "1" }]
+			}`, from, to)
+			require.NoError(t, err)
+			require.ErrorContains(t, res.Responses["A"].Error, "composite aggregation must be the first bucket aggregation")
+		})
+
 		t.Run("With term agg and order by count metric agg", func(t *testing.T) {
 			c := newFakeClient()
 			_, err := executeElasticsearchDataQuery(c, `{
@@ -1436,6 +1539,256 @@ func TestExecuteElasticsearchDataQuery(t *testing.T) {
 	})
 }
 
+func TestDocumentPagination(t *testing.T) {
+	from := time.Date(2018, 5, 15, 17, 50, 0, 0, time.UTC)
+	to := time.Date(2018, 5, 15, 17, 55, 0, 0, time.UTC)
+
+	hits := func(start, count int) *es.SearchResponse {
+		res := &es.SearchResponse{Hits: &es.SearchResponseHits{}, PitID: fmt.Sprintf("pit-%d", start)}
+		for i := start; i < start+count; i++ {
+			res.Hits.Hits = append(res.Hits.Hits, map[string]any{
+				"_id":     fmt.Sprint(i),
+				"_source": map[string]any{"value": float64(i)},
+				"sort":    []any{float64(i), float64(i)},
+			})
+		}
+		return res
+	}
+
+	execute := func(c *fakeClient, size string, maxRows int) *backend.QueryDataResponse {
+		req := &backend.QueryDataRequest{
+			Queries: []backend.DataQuery{
+				{
+					JSON:      json.RawMessage(fmt.Sprintf(`{"metrics": [{ "id": "1", "type": "raw_data", "settings": {"size": %q}}]}`, size)),
+					TimeRange: backend.TimeRange{From: from, To: to},
+					RefID:     "A",
+				},
+			},
+		}
+		res, err := newElasticsearchDataQuery(context.Background(), c, req, log.New(), maxRows).execute()
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("Queries of more documents than a page are paginated", func(t *testing.T) {
+		c := newFakeClient()
+		c.multiSearchResponses = []*es.MultiSearchResponse{
+			{Responses: []*es.SearchResponse{hits(0, documentPageSize)}},
+			{Responses: []*es.SearchResponse{hits(documentPageSize, documentPageSize)}},
+			{Responses: []*es.SearchResponse{hits(2*documentPageSize, 10)}},
+		}
+
+		res := execute(c, "25000", defaultMaxRows)
+		require.NoError(t, res.Responses["A"].Error)
+		frame := res.Responses["A"].Frames[0]
+		require.Equal(t, 2*documentPageSize+10, frame.Rows())
+		require.True(t, frame.Meta == nil || len(frame.Meta.Notices) == 0)
+
+		require.Len(t, c.multisearchRequests, 3)
+		first := c.multisearchRequests[0].Requests[0]
+		require.Equal(t, documentPageSize, first.Size)
+		require.Equal(t, &es.PointInTime{ID: "pit-id", KeepAlive: pointInTimeKeepAlive}, first.PointInTime)
+
+		second := c.multisearchRequests[1].Requests[0]
+		require.Equal(t, documentPageSize, second.Size)
+		require.Equal(t, "pit-0", second.PointInTime.ID)
+		require.Equal(t, []any{float64(documentPageSize - 1), float64(documentPageSize - 1)}, second.CustomProps["search_after"])
+
+		third := c.multisearchRequests[2].Requests[0]
+		require.Equal(t, 5000, third.Size)
+		require.Equal(t, fmt.Sprintf("pit-%d", documentPageSize), third.PointInTime.ID)
+
+		require.Equal(t, []string{pointInTimeKeepAlive}, c.openedPointsInTime)
+		require.Equal(t, []string{fmt.Sprintf("pit-%d", 2*documentPageSize)}, c.closedPointsInTime)
+	})
+
+	t.Run("Queries of documents of a single page are not paginated", func(t *testing.T) {
+		c := newFakeClient()
+		c.multiSearchResponse = &es.MultiSearchResponse{Responses: []*es.SearchResponse{hits(0, 1000)}}
+
+		res := execute(c, "1000", defaultMaxRows)
+		require.NoError(t, res.Responses["A"].Error)
+		require.Len(t, c.multisearchRequests, 1)
+		require.Nil(t, c.multisearchRequests[0].Requests[0].PointInTime)
+		require.Empty(t, c.openedPointsInTime)
+		require.Empty(t, c.closedPointsInTime)
+	})
+
+	t.Run("Documents are limited to the maximum number of rows", func(t *testing.T) {
+		c := newFakeClient()
+		c.multiSearchResponse = &es.MultiSearchResponse{Responses: []*es.SearchResponse{hits(0, 100)}}
+
+		res := execute(c, "5000", 100)
+		require.NoError(t, res.Responses["A"].Error)
+		require.Equal(t, 100, c.multisearchRequests[0].Requests[0].Size)
+
+		frame := res.Responses["A"].Frames[0]
+		require.Equal(t, 100, frame.Rows())
+		require.Equal(t, []data.Notice{{
+			Severity: data.NoticeSeverityWarning,
+			Text:     "Results are limited to 100 rows by the data source settings.",
+		}}, frame.Meta.Notices)
+	})
+
+	t.Run("Documents are limited to the first page if the point in time can't be opened", func(t *testing.T) {
+		c := newFakeClient()
+		c.openPointInTimeError = errors.New("point in time is not supported")
+		c.multiSearchResponse = &es.MultiSearchResponse{Responses: []*es.SearchResponse{hits(0, documentPageSize)}}
+
+		res := execute(c, "25000", defaultMaxRows)
+		require.NoError(t, res.Responses["A"].Error)
+		require.Len(t, c.multisearchRequests, 1)
+		require.Nil(t, c.multisearchRequests[0].Requests[0].PointInTime)
+
+		frame := res.Responses["A"].Frames[0]
+		require.Equal(t, documentPageSize, frame.Rows())
+		require.Equal(t, []data.Notice{{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("Results are limited to the first %d rows, the following rows could not be fetched.", documentPageSize),
+		}}, frame.Meta.Notices)
+		require.Empty(t, c.closedPointsInTime)
+	})
+
+	t.Run("Points in time are closed if a following query fails", func(t *testing.T) {
+		c := newFakeClient()
+		req := &backend.QueryDataRequest{
+			Queries: []backend.DataQuery{
+				{
+					JSON:      json.RawMessage(`{"metrics": [{ "id": "1", "type": "raw_data", "settings": {"size": "25000"}}]}`),
+					TimeRange: backend.TimeRange{From: from, To: to},
+					RefID:     "A",
+				},
+				{
+					JSON:      json.RawMessage(`{"metrics": [{ "id": "1", "type": "count"}]}`),
+					TimeRange: backend.TimeRange{From: from, To: to},
+					RefID:     "B",
+				},
+			},
+		}
+		res, err := newElasticsearchDataQuery(context.Background(), c, req, log.New(), defaultMaxRows).execute()
+		require.NoError(t, err)
+		require.Error(t, res.Responses["B"].Error)
+		require.Empty(t, c.multisearchRequests)
+		require.Equal(t, []string{"pit-id"}, c.closedPointsInTime)
+	})
+
+	t.Run("Errors of the following pages are returned", func(t *testing.T) {
+		c := newFakeClient()
+		c.multiSearchResponses = []*es.MultiSearchResponse{
+			{Responses: []*es.SearchResponse{hits(0, documentPageSize)}},
+			{Responses: []*es.SearchResponse{{Error: map[string]any{"reason": "point in time expired"}}}},
+		}
+
+		res := execute(c, "25000", defaultMaxRows)
+		require.ErrorContains(t, res.Responses["A"].Error, "point in time expired")
+		require.Equal(t, []string{"pit-0"}, c.closedPointsInTime)
+	})
+}
+
+func TestCompositePagination(t *testing.T) {
+	from := time.Date(2018, 5, 15, 17, 50, 0, 0, time.UTC)
+	to := time.Date(2018, 5, 15, 17, 55, 0, 0, time.UTC)
+
+	buckets := func(start, count int) *es.SearchResponse {
+		var b []any
+		for i := start; i < start+count; i++ {
+			b = append(b, map[string]any{"key": map[string]any{"@host": fmt.Sprintf("host-%d", i)}, "doc_count": float64(i)})
+		}
+		agg := map[string]any{"buckets": b}
+		if count > 0 {
+			agg["after_key"] = map[string]any{"@host": fmt.Sprintf("host-%d", start+count-1)}
+		}
+		return &es.SearchResponse{Aggregations: map[string]any{"2": agg}}
+	}
+
+	execute := func(c *fakeClient, maxRows int) *backend.QueryDataResponse {
+		req := &backend.QueryDataRequest{
+			Queries: []backend.DataQuery{
+				{
+					JSON: json.RawMessage(`{
+						"bucketAggs": [{ "type": "composite", "id": "2", "settings": { "size": "10", "sources": [{ "field": "@host" }] } }],
+						"metrics": [{ "type": "count", "id": "1" }]
+					}`),
+					TimeRange: backend.TimeRange{From: from, To: to},
+					RefID:     "A",
+				},
+			},
+		}
+		res, err := newElasticsearchDataQuery(context.Background(), c, req, log.New(), maxRows).execute()
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("Buckets of the following pages are fetched with the after key", func(t *testing.T) {
+		c := newFakeClient()
+		c.multiSearchResponses = []*es.MultiSearchResponse{
+			{Responses: []*es.SearchResponse{buckets(0, 10)}},
+			{Responses: []*es.SearchResponse{buckets(10, 10)}},
+			{Responses: []*es.SearchResponse{buckets(20, 5)}},
+		}
+
+		res := execute(c, defaultMaxRows)
+		require.NoError(t, res.Responses["A"].Error)
+		frame := res.Responses["A"].Frames[0]
+		require.Equal(t, 25, frame.Rows())
+		require.True(t, frame.Meta == nil || len(frame.Meta.Notices) == 0)
+
+		require.Len(t, c.multisearchRequests, 3)
+		first := c.multisearchRequests[0].Requests[0].Aggs[0].Aggregation.Aggregation.(*es.CompositeAggregation)
+		require.Nil(t, first.After)
+		second := c.multisearchRequests[1].Requests[0].Aggs[0].Aggregation.Aggregation.(*es.CompositeAggregation)
+		require.Equal(t, map[string]any{"@host": "host-9"}, second.After)
+		third := c.multisearchRequests[2].Requests[0].Aggs[0].Aggregation.Aggregation.(*es.CompositeAggregation)
+		require.Equal(t, map[string]any{"@host": "host-19"}, third.After)
+	})
+
+	t.Run("Buckets are limited to the maximum number of rows", func(t *testing.T) {
+		c := newFakeClient()
+		c.multiSearchResponses = []*es.MultiSearchResponse{
+			{Responses: []*es.SearchResponse{buckets(0, 10)}},
+			{Responses: []*es.SearchResponse{buckets(10, 10)}},
+		}
+
+		res := execute(c, 15)
+		require.NoError(t, res.Responses["A"].Error)
+		require.Len(t, c.multisearchRequests, 2)
+
+		frame := res.Responses["A"].Frames[0]
+		require.Equal(t, 15, frame.Rows())
+		require.Equal(t, []data.Notice{{
+			Severity: data.NoticeSeverityWarning,
+			Text:     "Results are limited to 15 rows by the data source settings.",
+		}}, frame.Meta.Notices)
+	})
+
+	t.Run("Buckets are not limited without maximum number of rows", func(t *testing.T) {
+		c := newFakeClient()
+		c.multiSearchResponses = []*es.MultiSearchResponse{
+			{Responses: []*es.SearchResponse{buckets(0, 10)}},
+			{Responses: []*es.SearchResponse{buckets(10, 5)}},
+		}
+
+		res := execute(c, 0)
+		require.NoError(t, res.Responses["A"].Error)
+		require.Len(t, c.multisearchRequests, 2)
+
+		frame := res.Responses["A"].Frames[0]
+		require.Equal(t, 15, frame.Rows())
+		require.True(t, frame.Meta == nil || len(frame.Meta.Notices) == 0)
+	})
+
+	t.Run("Errors of the following pages are returned", func(t *testing.T) {
+		c := newFakeClient()
+		c.multiSearchResponses = []*es.MultiSearchResponse{
+			{Responses: []*es.SearchResponse{buckets(0, 10)}},
+			{Responses: []*es.SearchResponse{{Error: map[string]any{"reason": "too many buckets"}}}},
+		}
+
+		res := execute(c, defaultMaxRows)
+		require.ErrorContains(t, res.Responses["A"].Error, "too many buckets")
+	})
+}
+
 func TestSettingsCasting(t *testing.T) {
 	from := time.Date(2018, 5, 15, 17, 50, 0, 0, time.UTC)
 	to := time.Date(2018, 5, 15, 17, 55, 0, 0, time.UTC)
@@ -1801,9 +2154,14 @@ func TestSettingsCasting(t *testing.T) {
 type fakeClient struct {
 	configuredFields    es.ConfiguredFields
 	multiSearchResponse *es.MultiSearchResponse
-	multiSearchError    error
-	builder             *es.MultiSearchRequestBuilder
-	multisearchRequests []*es.MultiSearchRequest
+	// multiSearchResponses are returned in order before multiSearchResponse
+	multiSearchResponses []*es.MultiSearchResponse
+	multiSearchError     error
+	builder              *es.MultiSearchRequestBuilder
+	multisearchRequests  []*es.MultiSearchRequest
+	openedPointsInTime   []string
+	openPointInTimeError error
+	closedPointsInTime   []string
 }
 
 func newFakeClient() *fakeClient {
@@ -1826,9 +2184,27 @@ func (c *fakeClient) GetConfiguredFields() es.ConfiguredFields {
 
 func (c *fakeClient) ExecuteMultisearch(r *es.MultiSearchRequest) (*es.MultiSearchResponse, error) {
 	c.multisearchRequests = append(c.multisearchRequests, r)
+	if len(c.multiSearchResponses) > 0 {
+		res := c.multiSearchResponses[0]
+		c.multiSearchResponses = c.multiSearchResponses[1:]
+		return res, c.multiSearchError
+	}
 	return c.multiSearchResponse, c.multiSearchError
 }
 
+func (c *fakeClient) OpenPointInTime(timeRange backend.TimeRange, keepAlive string) (string, error) {
+	if c.openPointInTimeError != nil {
+		return "", c.openPointInTimeError
+	}
+	c.openedPointsInTime = append(c.openedPointsInTime, keepAlive)
+	return "pit-id", nil
+}
+
+func (c *fakeClient) ClosePointInTime(id string) error {
+	c.closedPointsInTime = append(c.closedPointsInTime, id)
+	return nil
+}
+
 func (c *fakeClient) MultiSearch() *es.MultiSearchRequestBuilder {
 	c.builder = es.NewMultiSearchRequestBuilder()
 	return c.builder
@@ -1860,6 +2236,6 @@ func executeElasticsearchDataQuery(c es.Client, body string, from, to time.Time)
 			},
 		},
 	}
-	query := newElasticsearchDataQuery(context.Background(), c, &dataRequest, log.New())
+	query := newElasticsearchDataQuery(context.Background(), c, &dataRequest, log.New(), defaultMaxRows)
 	return query.execute()
 }
@@ -30,6 +30,9 @@ const (
 	headerFromAlert = "FromAlert"
 	// this is the default value for the maxConcurrentShardRequests setting - it should be in sync with the default value in the datasource config settings
 	defaultMaxConcurrentShardRequests = int64(5)
+	// this is the default value for the maxRows setting, the maximum number of documents of raw data, raw document
+	// and logs queries - it should be in sync with the default value in the datasource config settings
+	defaultMaxRows = 100000
 )
 
 type Service struct {
@@ -67,7 +70,7 @@ func queryData(ctx context.Context, req *backend.QueryDataRequest, dsInfo *es.Da
 	if err != nil {
 		return &backend.QueryDataResponse{}, err
 	}
-	query := newElasticsearchDataQuery(ctx, client, req, logger)
+	query := newElasticsearchDataQuery(ctx, client, req, logger, dsInfo.MaxRows)
 	return query.execute()
 }
 
@@ -151,6 +154,23 @@ func newInstanceSettings(httpClientProvider *httpclient.Provider) datasource.Ins
 			includeFrozen = false
 		}
 
+		var maxRows int
+		switch v := jsonData["maxRows"].(type) {
+		case float64:
+			maxRows = int(v)
+		case string:
+			maxRows, err = strconv.Atoi(v)
+			if err != nil {
+				maxRows = defaultMaxRows
+			}
+		default:
+			maxRows = defaultMaxRows
+		}
+
+		if maxRows <= 0 {
+			maxRows = defaultMaxRows
+		}
+
 		configuredFields := es.ConfiguredFields{
 			TimeField:       timeField,
 			LogLevelField:   logLevelField,
@@ -166,6 +186,7 @@ func newInstanceSettings(httpClientProvider *httpclient.Provider) datasource.Ins
 			ConfiguredFields:           configuredFields,
 			Interval:                   interval,
 			IncludeFrozen:              includeFrozen,
+			MaxRows:                    maxRows,
 		}
 		return model, nil
 	}
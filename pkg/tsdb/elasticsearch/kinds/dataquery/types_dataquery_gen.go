@@ -11,14 +11,23 @@ package dataquery
 
 // Defines values for BucketAggregationType.
 const (
+	BucketAggregationTypeComposite     BucketAggregationType = "composite"
 	BucketAggregationTypeDateHistogram BucketAggregationType = "date_histogram"
 	BucketAggregationTypeFilters       BucketAggregationType = "filters"
 	BucketAggregationTypeGeohashGrid   BucketAggregationType = "geohash_grid"
 	BucketAggregationTypeHistogram     BucketAggregationType = "histogram"
+	BucketAggregationTypeMultiTerms    BucketAggregationType = "multi_terms"
 	BucketAggregationTypeNested        BucketAggregationType = "nested"
 	BucketAggregationTypeTerms         BucketAggregationType = "terms"
 )
 
+// Defines values for CompositeSourceType.
+const (
+	CompositeSourceTypeDateHistogram CompositeSourceType = "date_histogram"
+	CompositeSourceTypeHistogram     CompositeSourceType = "histogram"
+	CompositeSourceTypeTerms         CompositeSourceType = "terms"
+)
+
 // Defines values for ExtendedStatMetaType.
 const (
 	ExtendedStatMetaTypeAvg                     ExtendedStatMetaType = "avg"
@@ -143,6 +152,31 @@ type BucketScript struct {
 	Type MetricAggregationType `json:"type"`
 }
 
+// Composite defines model for Composite.
+type Composite struct {
+	BaseBucketAggregation
+	Id       string                `json:"id"`
+	Settings *any                  `json:"settings,omitempty"`
+	Type     BucketAggregationType `json:"type"`
+}
+
+// CompositeSettings defines model for CompositeSettings.
+type CompositeSettings struct {
+	Size    *string           `json:"size,omitempty"`
+	Sources []CompositeSource `json:"sources,omitempty"`
+}
+
+// CompositeSource defines model for CompositeSource.
+type CompositeSource struct {
+	Field         string               `json:"field"`
+	Interval      *string              `json:"interval,omitempty"`
+	MissingBucket *bool                `json:"missing_bucket,omitempty"`
+	Type          *CompositeSourceType `json:"type,omitempty"`
+}
+
+// CompositeSourceType defines model for CompositeSourceType.
+type CompositeSourceType string
+
 // Count defines model for Count.
 type Count struct {
 	BaseMetricAggregation
@@ -456,6 +490,23 @@ type MovingFunction struct {
 	Type MetricAggregationType `json:"type"`
 }
 
+// MultiTerms defines model for MultiTerms.
+type MultiTerms struct {
+	BaseBucketAggregation
+	Id       string                `json:"id"`
+	Settings *any                  `json:"settings,omitempty"`
+	Type     BucketAggregationType `json:"type"`
+}
+
+// MultiTermsSettings defines model for MultiTermsSettings.
+type MultiTermsSettings struct {
+	Fields      []string    `json:"fields,omitempty"`
+	MinDocCount *string     `json:"min_doc_count,omitempty"`
+	Order       *TermsOrder `json:"order,omitempty"`
+	OrderBy     *string     `json:"orderBy,omitempty"`
+	Size        *string     `json:"size,omitempty"`
+}
+
 // Nested defines model for Nested.
 type Nested struct {
 	BucketAggregationWithField
@@ -36,6 +36,8 @@ const (
 	histogramType   = "histogram"
 	filtersType     = "filters"
 	termsType       = "terms"
+	multiTermsType  = "multi_terms"
+	compositeType   = "composite"
 	geohashGridType = "geohash_grid"
 	//  Document types
 	rawDocumentType = "raw_document"
@@ -439,14 +441,21 @@ func processBuckets(aggs map[string]interface{}, target *Query,
 					newProps[k] = v
 				}
 
-				if key, err := bucket.Get("key").String(); err == nil {
-					newProps[aggDef.Field] = key
-				} else if key, err := bucket.Get("key").Int64(); err == nil {
-					newProps[aggDef.Field] = strconv.FormatInt(key, 10)
-				}
+				if isMultiKeyAgg(aggDef) {
+					names, keys := bucketKeys(aggDef, bucket)
+					for i, name := range names {
+						newProps[name] = bucketKeyToString(keys[i])
+					}
+				} else {
+					if key, err := bucket.Get("key").String(); err == nil {
+						newProps[aggDef.Field] = key
+					} else if key, err := bucket.Get("key").Int64(); err == nil {
+						newProps[aggDef.Field] = strconv.FormatInt(key, 10)
+					}
 
-				if key, err := bucket.Get("key_as_string").String(); err == nil {
-					newProps[aggDef.Field] = key
+					if key, err := bucket.Get("key_as_string").String(); err == nil {
+						newProps[aggDef.Field] = key
+					}
 				}
 				err = processBuckets(bucket.MustMap(), target, queryResult, newProps, depth+1)
 				if err != nil {
@@ -748,12 +757,14 @@ func processAggregationDocs(esAgg *simplejson.Json, aggDef *BucketAgg, target *Q
 	propKeys := createPropKeys(props)
 	frames := data.Frames{}
 	fields := createFields(queryResult.Frames, propKeys)
+	buckets := esAgg.Get("buckets").MustArray()
 
-	for _, v := range esAgg.Get("buckets").MustArray() {
+	for _, v := range buckets {
 		bucket := simplejson.NewFromAny(v)
 		var values []interface{}
 
-		found := false
+		names, keys := bucketKeys(aggDef, bucket)
+		found := make([]bool, len(names))
 		for _, field := range fields {
 			for _, propKey := range propKeys {
 				if field.Name == propKey {
@@ -761,32 +772,23 @@ func processAggregationDocs(esAgg *simplejson.Json, aggDef *BucketAgg, target *Q
 					field.Append(&value)
 				}
 			}
-			if field.Name == aggDef.Field {
-				found = true
-				if key, err := bucket.Get("key").String(); err == nil {
-					field.Append(&key)
-				} else {
-					f, err := bucket.Get("key").Float64()
-					if err != nil {
+			for i, name := range names {
+				if field.Name == name {
+					found[i] = true
+					if err := appendBucketKey(field, keys[i]); err != nil {
 						return fmt.Errorf("error appending bucket key to existing field with name %s: %w", field.Name, err)
 					}
-					field.Append(&f)
 				}
 			}
 		}
 
-		if !found {
-			var aggDefField *data.Field
-			if key, err := bucket.Get("key").String(); err == nil {
-				aggDefField = extractDataField(aggDef.Field, &key)
-				aggDefField.Append(&key)
-			} else {
-				f, err := bucket.Get("key").Float64()
-				if err != nil {
-					return fmt.Errorf("error appending bucket key to new field with name %s: %w", aggDef.Field, err)
-				}
-				aggDefField = extractDataField(aggDef.Field, &f)
-				aggDefField.Append(&f)
+		for i, name := range names {
+			if found[i] {
+				continue
+			}
+			aggDefField := newBucketKeyField(aggDef, name, i, buckets)
+			if err := appendBucketKey(aggDefField, keys[i]); err != nil {
+				return fmt.Errorf("error appending bucket key to new field with name %s: %w", name, err)
 			}
 			fields = append(fields, aggDefField)
 		}
@@ -818,6 +820,88 @@ func processAggregationDocs(esAgg *simplejson.Json, aggDef *BucketAgg, target *Q
 	return nil
 }
 
+// isMultiKeyAgg returns whether the keys of the buckets of an aggregation have a value for each of its fields
+func isMultiKeyAgg(aggDef *BucketAgg) bool {
+	return aggDef.Type == multiTermsType || aggDef.Type == compositeType
+}
+
+// bucketKeys returns the names and the values of the key of a bucket. The keys of multi terms buckets are arrays of
+// the values of their fields, and the keys of composite buckets are objects with the values of their sources.
+func bucketKeys(aggDef *BucketAgg, bucket *simplejson.Json) ([]string, []*simplejson.Json) {
+	switch aggDef.Type {
+	case multiTermsType:
+		names := aggDef.Settings.Get("fields").MustStringArray()
+		keys := make([]*simplejson.Json, len(names))
+		for i := range names {
+			keys[i] = bucket.Get("key").GetIndex(i)
+		}
+		return names, keys
+	case compositeType:
+		var names []string
+		var keys []*simplejson.Json
+		for _, source := range aggDef.Settings.Get("sources").MustArray() {
+			name := simplejson.NewFromAny(source).Get("field").MustString()
+			names = append(names, name)
+			keys = append(keys, bucket.Get("key").Get(name))
+		}
+		return names, keys
+	default:
+		return []string{aggDef.Field}, []*simplejson.Json{bucket.Get("key")}
+	}
+}
+
+func bucketKeyToString(key *simplejson.Json) string {
+	if s, err := key.String(); err == nil {
+		return s
+	}
+	if i, err := key.Int64(); err == nil {
+		return strconv.FormatInt(i, 10)
+	}
+	if f, err := key.Float64(); err == nil {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	if b, err := key.Bool(); err == nil {
+		return strconv.FormatBool(b)
+	}
+	return ""
+}
+
+// newBucketKeyField returns the field of the values of a key of the buckets. The type of the field is the type of
+// the first value of the key that isn't null, since composite buckets can have null keys.
+func newBucketKeyField(aggDef *BucketAgg, name string, index int, buckets []interface{}) *data.Field {
+	for _, v := range buckets {
+		_, keys := bucketKeys(aggDef, simplejson.NewFromAny(v))
+		key := keys[index]
+		if key.Interface() == nil {
+			continue
+		}
+		if _, err := key.String(); err != nil {
+			return extractDataField(name, new(float64))
+		}
+		break
+	}
+	return extractDataField(name, new(string))
+}
+
+// appendBucketKey appends a value of the key of a bucket to a field of bucket keys
+func appendBucketKey(field *data.Field, key *simplejson.Json) error {
+	if key.Interface() == nil {
+		field.Extend(1)
+		return nil
+	}
+	if field.Type() == data.FieldTypeNullableString {
+		value := bucketKeyToString(key)
+		field.Append(&value)
+		return nil
+	}
+	f, err := key.Float64()
+	if err != nil {
+		return err
+	}
+	field.Append(&f)
+	return nil
+}
+
 func extractDataField(name string, v interface{}) *data.Field {
 	var field *data.Field
 	switch v.(type) {
@@ -1327,6 +1327,143 @@ func TestProcessBuckets(t *testing.T) {
 		})
 	})
 
+	t.Run("Multi terms", func(t *testing.T) {
+		t.Run("Multi terms agg without date histogram", func(t *testing.T) {
+			targets := map[string]string{
+				"A": `{
+					"metrics": [{ "type": "max", "field": "counter", "id": "1" }],
+					"bucketAggs": [{ "type": "multi_terms", "id": "2", "settings": { "fields": ["host", "status"] } }]
+				}`,
+			}
+			response := `{
+				"responses": [{
+					"aggregations": {
+						"2": {
+							"buckets": [
+								{ "key": ["server-1", 200], "key_as_string": "server-1|200", "doc_count": 10, "1": { "value": 299 } },
+								{ "key": ["server-2", 500], "key_as_string": "server-2|500", "doc_count": 2, "1": { "value": 300 } }
+							]
+						}
+					}
+				}]
+			}`
+
+			result, err := parseTestResponse(targets, response, false)
+			require.NoError(t, err)
+			frames := result.Responses["A"].Frames
+			require.Len(t, frames, 1)
+			requireFrameLength(t, frames[0], 2)
+			require.Len(t, frames[0].Fields, 3)
+
+			f1 := frames[0].Fields[0]
+			f2 := frames[0].Fields[1]
+			f3 := frames[0].Fields[2]
+
+			require.Equal(t, "host", f1.Name)
+			require.Equal(t, "status", f2.Name)
+			require.Equal(t, "Max", f3.Name)
+
+			requireStringAt(t, "server-1", f1, 0)
+			requireStringAt(t, "server-2", f1, 1)
+			requireFloatAt(t, 200, f2, 0)
+			requireFloatAt(t, 500, f2, 1)
+			requireFloatAt(t, 299, f3, 0)
+			requireFloatAt(t, 300, f3, 1)
+		})
+
+		t.Run("Multi terms agg with date histogram", func(t *testing.T) {
+			targets := map[string]string{
+				"A": `{
+					"metrics": [{ "type": "count", "id": "1" }],
+					"bucketAggs": [
+						{ "type": "multi_terms", "id": "2", "settings": { "fields": ["host", "status"] } },
+						{ "type": "date_histogram", "field": "@timestamp", "id": "3" }
+					]
+				}`,
+			}
+			response := `{
+				"responses": [{
+					"aggregations": {
+						"2": {
+							"buckets": [
+								{
+									"key": ["server-1", 200],
+									"doc_count": 4,
+									"3": { "buckets": [{ "doc_count": 1, "key": 1000 }, { "doc_count": 3, "key": 2000 }] }
+								},
+								{
+									"key": ["server-2", 500],
+									"doc_count": 10,
+									"3": { "buckets": [{ "doc_count": 2, "key": 1000 }, { "doc_count": 8, "key": 2000 }] }
+								}
+							]
+						}
+					}
+				}]
+			}`
+
+			result, err := parseTestResponse(targets, response, true)
+			require.NoError(t, err)
+			frames := result.Responses["A"].Frames
+			require.Len(t, frames, 2)
+
+			require.Equal(t, data.Labels{"host": "server-1", "status": "200"}, frames[0].Fields[1].Labels)
+			require.Equal(t, data.Labels{"host": "server-2", "status": "500"}, frames[1].Fields[1].Labels)
+			requireFloatAt(t, 3, frames[0].Fields[1], 1)
+			requireFloatAt(t, 8, frames[1].Fields[1], 1)
+		})
+	})
+
+	t.Run("Composite", func(t *testing.T) {
+		t.Run("Composite agg with missing bucket", func(t *testing.T) {
+			targets := map[string]string{
+				"A": `{
+					"metrics": [{ "type": "count", "id": "1" }],
+					"bucketAggs": [{
+						"type": "composite",
+						"id": "2",
+						"settings": { "sources": [{ "field": "host", "missing_bucket": true }, { "field": "bytes", "type": "histogram", "interval": "100" }] }
+					}]
+				}`,
+			}
+			response := `{
+				"responses": [{
+					"aggregations": {
+						"2": {
+							"after_key": { "host": "server-1", "bytes": 200 },
+							"buckets": [
+								{ "key": { "host": null, "bytes": 100 }, "doc_count": 3 },
+								{ "key": { "host": "server-1", "bytes": 200 }, "doc_count": 5 }
+							]
+						}
+					}
+				}]
+			}`
+
+			result, err := parseTestResponse(targets, response, false)
+			require.NoError(t, err)
+			frames := result.Responses["A"].Frames
+			require.Len(t, frames, 1)
+			requireFrameLength(t, frames[0], 2)
+			require.Len(t, frames[0].Fields, 3)
+
+			f1 := frames[0].Fields[0]
+			f2 := frames[0].Fields[1]
+			f3 := frames[0].Fields[2]
+
+			require.Equal(t, "host", f1.Name)
+			require.Equal(t, "bytes", f2.Name)
+			require.Equal(t, "Count", f3.Name)
+
+			require.Nil(t, f1.At(0))
+			requireStringAt(t, "server-1", f1, 1)
+			requireFloatAt(t, 100, f2, 0)
+			requireFloatAt(t, 200, f2, 1)
+			requireFloatAt(t, 3, f3, 0)
+			requireFloatAt(t, 5, f3, 1)
+		})
+	})
+
 	t.Run("Top metrics", func(t *testing.T) {
 		t.Run("Top metrics 2 frames", func(t *testing.T) {
 			query := []byte(`
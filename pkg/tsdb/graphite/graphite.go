@@ -18,6 +18,7 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/backend/datasource"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/patrickmn/go-cache"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 
@@ -51,6 +52,9 @@ type datasourceInfo struct {
 	HTTPClient *http.Client
 	URL        string
 	Id         int64
+
+	// resourceCache caches the responses of the resource calls. It is nil if the responses are not cached.
+	resourceCache *cache.Cache
 }
 
 func newInstanceSettings(httpClientProvider httpclient.Provider) datasource.InstanceFactoryFunc {
@@ -69,6 +73,8 @@ func newInstanceSettings(httpClientProvider httpclient.Provider) datasource.Inst
 			HTTPClient: client,
 			URL:        settings.URL,
 			Id:         settings.ID,
+			// The responses are cached per user, see resourceCacheKey
+			resourceCache: newResourceCache(),
 		}
 
 		return model, nil
@@ -127,6 +133,9 @@ func (s *Service) QueryData(ctx context.Context, req *backend.QueryDataRequest)
 			return &result, errors.New("no query target found for the alert rule")
 		}
 	}
+	if len(targetList) == 0 {
+		return backend.NewQueryDataResponse(), nil
+	}
 	formData["target"] = targetList
 
 	if setting.Env == setting.Dev {
@@ -201,11 +210,23 @@ func (s *Service) processQueries(logger log.Logger, queries []backend.DataQuery)
 	origRefIds := make(map[string]string, 0)
 	targets := make([]string, 0)
 
+	models := make([]*simplejson.Json, 0, len(queries))
+	refTargets := make(map[string]string, len(queries))
 	for _, query := range queries {
 		model, err := simplejson.NewJson(query.JSON)
 		if err != nil {
 			return nil, nil, nil, err
 		}
+		models = append(models, model)
+		refTargets[query.RefID] = model.Get(TargetModelField).MustString()
+	}
+
+	for i, query := range queries {
+		model := models[i]
+		// Hidden queries are only sent to resolve the references of the other queries, like the frontend does
+		if model.Get("hide").MustBool() {
+			continue
+		}
 		logger.Debug("Graphite", "query", model)
 		currTarget := ""
 		if fullTarget, err := model.Get(TargetFullModelField).String(); err == nil {
@@ -218,6 +239,10 @@ func (s *Service) processQueries(logger log.Logger, queries []backend.DataQuery)
 			emptyQueries = append(emptyQueries, fmt.Sprintf("Query: %v has no target", model))
 			continue
 		}
+		currTarget, err := resolveTargetRefs(currTarget, refTargets, map[string]bool{query.RefID: true})
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("query %s: %w", query.RefID, err)
+		}
 		target := fixIntervalFormat(currTarget)
 
 		// This is a somewhat inglorious way to ensure we can associate results with the right query
@@ -235,6 +260,37 @@ func (s *Service) processQueries(logger log.Logger, queries []backend.DataQuery)
 	return targets, emptyQueries, origRefIds, nil
 }
 
+// targetRefRegex matches the references to the targets of other queries, e.g. #A, that the query editor allows
+// to nest series.
+var targetRefRegex = regexp.MustCompile(`#([A-Z])`)
+
+// resolveTargetRefs replaces the references to other queries in a target with their targets, like the frontend
+// does. The referenced targets can reference other queries too, visiting holds the queries being resolved to
+// detect circular references.
+func resolveTargetRefs(target string, refTargets map[string]string, visiting map[string]bool) (string, error) {
+	var err error
+	resolved := targetRefRegex.ReplaceAllStringFunc(target, func(match string) string {
+		refID := match[1:]
+		refTarget, ok := refTargets[refID]
+		switch {
+		case err != nil:
+			return match
+		case !ok:
+			err = fmt.Errorf("referenced query %s is not part of the request", refID)
+			return match
+		case visiting[refID]:
+			err = fmt.Errorf("circular reference to query %s", refID)
+			return match
+		}
+
+		visiting[refID] = true
+		refTarget, err = resolveTargetRefs(refTarget, refTargets, visiting)
+		delete(visiting, refID)
+		return refTarget
+	})
+	return resolved, err
+}
+
 func (s *Service) parseResponse(logger log.Logger, res *http.Response) ([]TargetResponseDTO, error) {
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
@@ -295,7 +351,9 @@ func (s *Service) toDataFrames(logger log.Logger, response *http.Response, origR
 
 		tags := make(map[string]string)
 		for name, value := range series.Tags {
-			if name == "name" {
+			// The name of series without alias is the metric name, unless they are tagged series. Keep the metric
+			// name of tagged series to not repeat their tags in the name label.
+			if name == "name" && !isTaggedSeriesName(target, value) {
 				value = target
 			}
 			switch value := value.(type) {
@@ -334,6 +392,13 @@ func (s *Service) createRequest(ctx context.Context, l log.Logger, dsInfo *datas
 	return req, err
 }
 
+// isTaggedSeriesName returns whether the name of a series is the name of a tagged series with the given metric
+// name, e.g. cpu;host=a for a series returned by seriesByTag('name=cpu') without alias.
+func isTaggedSeriesName(seriesName string, metricName any) bool {
+	name, ok := metricName.(string)
+	return ok && strings.HasPrefix(seriesName, name+";")
+}
+
 func fixIntervalFormat(target string) string {
 	rMinute := regexp.MustCompile(`'(\d+)m'`)
 	target = rMinute.ReplaceAllStringFunc(target, func(m string) string {
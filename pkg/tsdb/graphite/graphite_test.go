@@ -125,6 +125,65 @@ func TestProcessQueries(t *testing.T) {
 		assert.Equal(t, expectedInvalid, invalids[0])
 	})
 
+	t.Run("Resolves references to other queries", func(t *testing.T) {
+		queries := []backend.DataQuery{
+			{
+				RefID: "A",
+				JSON:  []byte(`{"target": "app.*.requests.count"}`),
+			},
+			{
+				RefID: "B",
+				JSON:  []byte(`{"target": "summarize(#A, '1m')"}`),
+			},
+			{
+				RefID: "C",
+				JSON:  []byte(`{"target": "divideSeries(#B, #A)"}`),
+			},
+			{
+				RefID: "D",
+				JSON:  []byte(`{"target": "scale(#A, 2)", "targetFull": "scale(app.*.errors.count, 2)"}`),
+			},
+		}
+		targets, invalids, _, err := service.processQueries(log, queries)
+		require.NoError(t, err)
+		assert.Empty(t, invalids)
+		require.Len(t, targets, 4)
+		assert.Equal(t, "aliasSub(summarize(app.*.requests.count, '1min'),\"(^.*$)\",\"\\1 B\")", targets[1])
+		assert.Equal(t, "aliasSub(divideSeries(summarize(app.*.requests.count, '1min'), app.*.requests.count),\"(^.*$)\",\"\\1 C\")", targets[2])
+		assert.Equal(t, "aliasSub(scale(app.*.errors.count, 2),\"(^.*$)\",\"\\1 D\")", targets[3])
+	})
+
+	t.Run("Resolves references to hidden queries without requesting them", func(t *testing.T) {
+		queries := []backend.DataQuery{
+			{
+				RefID: "A",
+				JSON:  []byte(`{"target": "sumSeries(#B)"}`),
+			},
+			{
+				RefID: "B",
+				JSON:  []byte(`{"target": "app.*.requests.count", "hide": true}`),
+			},
+		}
+		targets, invalids, mapping, err := service.processQueries(log, queries)
+		require.NoError(t, err)
+		assert.Empty(t, invalids)
+		assert.Equal(t, map[string]string{"A": "A"}, mapping)
+		assert.Equal(t, []string{"aliasSub(sumSeries(app.*.requests.count),\"(^.*$)\",\"\\1 A\")"}, targets)
+	})
+
+	t.Run("Returns an error for invalid references to other queries", func(t *testing.T) {
+		_, _, _, err := service.processQueries(log, []backend.DataQuery{
+			{RefID: "A", JSON: []byte(`{"target": "scale(#B, 2)"}`)},
+		})
+		assert.EqualError(t, err, "query A: referenced query B is not part of the request")
+
+		_, _, _, err = service.processQueries(log, []backend.DataQuery{
+			{RefID: "A", JSON: []byte(`{"target": "scale(#B, 2)"}`)},
+			{RefID: "B", JSON: []byte(`{"target": "scale(#A, 2)"}`)},
+		})
+		assert.EqualError(t, err, "query A: circular reference to query A")
+	})
+
 	t.Run("QueryData with no valid queries returns an error", func(t *testing.T) {
 		queries := []backend.DataQuery{
 			{
@@ -275,6 +334,43 @@ func TestConvertResponses(t *testing.T) {
 		}
 	})
 
+	t.Run("Converts response of tagged series to data frames", func(*testing.T) {
+		body := `
+		[
+			{
+				"target": "cpu;host=a A",
+				"tags": { "name": "cpu", "host": "a" },
+				"datapoints": [[50, 1]]
+			},
+			{
+				"target": "a A",
+				"tags": { "name": "cpu", "host": "a" },
+				"datapoints": [[50, 1]]
+			}
+		]`
+		a := 50.0
+		expectedFrames := data.Frames{
+			data.NewFrame("A",
+				data.NewField("time", nil, []time.Time{time.Unix(1, 0).UTC()}),
+				data.NewField("value", data.Labels{"name": "cpu", "host": "a"}, []*float64{&a}).SetConfig(&data.FieldConfig{DisplayNameFromDS: "cpu;host=a"}),
+			),
+			data.NewFrame("A",
+				data.NewField("time", nil, []time.Time{time.Unix(1, 0).UTC()}),
+				data.NewField("value", data.Labels{"name": "a", "host": "a"}, []*float64{&a}).SetConfig(&data.FieldConfig{DisplayNameFromDS: "a"}),
+			),
+		}
+
+		httpResponse := &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body))}
+		dataFrames, err := service.toDataFrames(logger, httpResponse, map[string]string{})
+
+		require.NoError(t, err)
+		if !reflect.DeepEqual(expectedFrames, dataFrames) {
+			expectedFramesJSON, _ := json.Marshal(expectedFrames)
+			dataFramesJSON, _ := json.Marshal(dataFrames)
+			t.Errorf("Data frames should have been equal but was, expected:\n%s\nactual:\n%s", expectedFramesJSON, dataFramesJSON)
+		}
+	})
+
 	t.Run("Chokes on response with invalid target name", func(*testing.T) {
 		body := `
 		[
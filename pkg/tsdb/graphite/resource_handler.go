@@ -0,0 +1,158 @@
+package graphite
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
+	"github.com/patrickmn/go-cache"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// resourceCacheExpiration is how long the responses of the metric and tag lookups are cached. The same lookups are
+// repeated by every variable and query editor, and the metrics and tags of Graphite change rarely.
+const resourceCacheExpiration = time.Minute
+
+// maxResourceCacheItems caps the number of responses cached per data source, since every combination of parameters
+// and user is cached separately.
+const maxResourceCacheItems = 1000
+
+// userHeaders are the forwarded headers that identify the user. Their values are part of the cache key, so that
+// responses are only shared by the requests of the same user.
+var userHeaders = []string{"Cookie", "X-Grafana-User", "X-Grafana-Id"}
+
+// credentialHeaders are the forwarded headers with the credentials of the user. The responses of requests made with
+// them are not cached.
+var credentialHeaders = []string{"Authorization", "X-Id-Token"}
+
+func (s *Service) CallResource(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	return httpadapter.New(s.newResourceMux()).CallResource(ctx, req, sender)
+}
+
+// newResourceMux returns the routes of the resource calls. The routes have the paths of the Graphite API and only
+// forward the parameters of their API.
+func (s *Service) newResourceMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics/find", s.handleResourceReq("metrics/find", "query", "from", "until"))
+	mux.HandleFunc("GET /tags/autoComplete/tags", s.handleResourceReq("tags/autoComplete/tags", "expr", "tagPrefix", "limit"))
+	mux.HandleFunc("GET /tags/autoComplete/values", s.handleResourceReq("tags/autoComplete/values", "expr", "tag", "valuePrefix", "limit"))
+	return mux
+}
+
+func (s *Service) handleResourceReq(resourcePath string, params ...string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		logger := logger.FromContext(ctx)
+
+		dsInfo, err := s.getDSInfo(ctx, backend.PluginConfigFromContext(ctx))
+		if err != nil {
+			logger.Warn("Failed to get data source info", "error", err)
+			http.Error(rw, "failed to get data source info", http.StatusInternalServerError)
+			return
+		}
+
+		query := req.URL.Query()
+		values := url.Values{}
+		for _, param := range params {
+			if v, ok := query[param]; ok {
+				values[param] = v
+			}
+		}
+
+		status, body, err := s.doResourceRequest(ctx, dsInfo, resourcePath, values, req.Header)
+		if err != nil {
+			logger.Warn("Graphite resource request failed", "path", resourcePath, "error", err)
+			http.Error(rw, err.Error(), http.StatusBadGateway)
+			return
+		}
+		if status/100 == 2 {
+			rw.Header().Set("Content-Type", "application/json")
+		}
+		rw.WriteHeader(status)
+		_, _ = rw.Write(body)
+	}
+}
+
+// doResourceRequest requests a path of the Graphite API and returns the status and body of the response. Successful
+// responses are cached per user, unless the request forwards the credentials of the user.
+func (s *Service) doResourceRequest(ctx context.Context, dsInfo *datasourceInfo, resourcePath string, params url.Values, header http.Header) (int, []byte, error) {
+	cacheKey, cacheable := resourceCacheKey(resourcePath, params, header)
+	cacheable = cacheable && dsInfo.resourceCache != nil
+	if cacheable {
+		if body, ok := dsInfo.resourceCache.Get(cacheKey); ok {
+			return http.StatusOK, body.([]byte), nil
+		}
+	}
+
+	u, err := url.Parse(dsInfo.URL)
+	if err != nil {
+		return 0, nil, err
+	}
+	u.Path = path.Join(u.Path, resourcePath)
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	ctx, span := s.tracer.Start(ctx, "graphite resource")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("path", resourcePath),
+		attribute.Int64("datasource_id", dsInfo.Id),
+	)
+	s.tracer.Inject(ctx, req.Header, span)
+
+	res, err := dsInfo.HTTPClient.Do(req)
+	if res != nil {
+		span.SetAttributes(attribute.Int("graphite.response.code", res.StatusCode))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, nil, err
+	}
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			logger.Warn("Failed to close response body", "error", err)
+		}
+	}()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	if res.StatusCode/100 == 2 && cacheable && dsInfo.resourceCache.ItemCount() < maxResourceCacheItems {
+		dsInfo.resourceCache.Set(cacheKey, body, cache.DefaultExpiration)
+	}
+	return res.StatusCode, body, nil
+}
+
+// resourceCacheKey returns the cache key of a resource request, and false if its response must not be cached.
+func resourceCacheKey(resourcePath string, params url.Values, header http.Header) (string, bool) {
+	for _, name := range credentialHeaders {
+		if header.Get(name) != "" {
+			return "", false
+		}
+	}
+	key := resourcePath + "?" + params.Encode()
+	for _, name := range userHeaders {
+		if values := header.Values(name); len(values) > 0 {
+			key += "\n" + name + ": " + strings.Join(values, ", ")
+		}
+	}
+	return key, true
+}
+
+func newResourceCache() *cache.Cache {
+	return cache.New(resourceCacheExpiration, resourceCacheExpiration*5)
+}
@@ -0,0 +1,146 @@
+package graphite
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
+)
+
+func TestResourceRequests(t *testing.T) {
+	var requests []*http.Request
+	graphite := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if r.URL.Query().Get("query") == "fail.*" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("invalid query"))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"text": "a"}]`))
+	}))
+	defer graphite.Close()
+
+	newService := func(cached bool) *Service {
+		dsInfo := datasourceInfo{HTTPClient: graphite.Client(), URL: graphite.URL}
+		if cached {
+			dsInfo.resourceCache = newResourceCache()
+		}
+		return &Service{im: resourceInstanceManager{dsInfo: dsInfo}, tracer: tracing.InitializeTracerForTest()}
+	}
+
+	callWithHeader := func(t *testing.T, s *Service, path string, header http.Header) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header = header
+		s.newResourceMux().ServeHTTP(rec, req)
+		return rec
+	}
+	call := func(t *testing.T, s *Service, path string) *httptest.ResponseRecorder {
+		t.Helper()
+		return callWithHeader(t, s, path, http.Header{})
+	}
+
+	t.Run("requests are forwarded with the parameters of the API", func(t *testing.T) {
+		requests = nil
+		s := newService(false)
+
+		rec := call(t, s, "/tags/autoComplete/values?expr=a%3Db&expr=c%3Dd&tag=e&valuePrefix=f&other=g")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `[{"text": "a"}]`, rec.Body.String())
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		require.Len(t, requests, 1)
+		assert.Equal(t, "/tags/autoComplete/values", requests[0].URL.Path)
+		assert.Equal(t, "expr=a%3Db&expr=c%3Dd&tag=e&valuePrefix=f", requests[0].URL.RawQuery)
+	})
+
+	t.Run("successful responses are cached", func(t *testing.T) {
+		requests = nil
+		s := newService(true)
+
+		call(t, s, "/metrics/find?query=a.*&from=1&until=2")
+		rec := call(t, s, "/metrics/find?query=a.*&from=1&until=2")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `[{"text": "a"}]`, rec.Body.String())
+		require.Len(t, requests, 1)
+
+		call(t, s, "/metrics/find?query=b.*&from=1&until=2")
+		require.Len(t, requests, 2)
+	})
+
+	t.Run("errors are returned and not cached", func(t *testing.T) {
+		requests = nil
+		s := newService(true)
+
+		call(t, s, "/metrics/find?query=fail.*")
+		rec := call(t, s, "/metrics/find?query=fail.*")
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "invalid query", rec.Body.String())
+		require.Len(t, requests, 2)
+	})
+
+	t.Run("responses are cached per user", func(t *testing.T) {
+		requests = nil
+		s := newService(true)
+
+		callWithHeader(t, s, "/metrics/find?query=a.*", http.Header{"X-Grafana-User": {"a"}})
+		callWithHeader(t, s, "/metrics/find?query=a.*", http.Header{"X-Grafana-User": {"a"}})
+		require.Len(t, requests, 1)
+
+		callWithHeader(t, s, "/metrics/find?query=a.*", http.Header{"X-Grafana-User": {"b"}})
+		callWithHeader(t, s, "/metrics/find?query=a.*", http.Header{"Cookie": {"session=b"}})
+		require.Len(t, requests, 3)
+	})
+
+	t.Run("responses are not cached if the credentials of the users are forwarded", func(t *testing.T) {
+		requests = nil
+		s := newService(true)
+
+		callWithHeader(t, s, "/metrics/find?query=a.*", http.Header{"Authorization": {"Bearer a"}})
+		callWithHeader(t, s, "/metrics/find?query=a.*", http.Header{"Authorization": {"Bearer a"}})
+		require.Len(t, requests, 2)
+	})
+
+	t.Run("responses are not cached once the cache is full", func(t *testing.T) {
+		requests = nil
+		s := newService(true)
+		dsInfo, err := s.getDSInfo(context.Background(), backend.PluginContext{})
+		require.NoError(t, err)
+		for i := 0; i < maxResourceCacheItems; i++ {
+			dsInfo.resourceCache.Set(strconv.Itoa(i), []byte{}, cache.DefaultExpiration)
+		}
+
+		call(t, s, "/metrics/find?query=a.*")
+		call(t, s, "/metrics/find?query=a.*")
+		require.Len(t, requests, 2)
+	})
+
+	t.Run("unknown paths are not forwarded", func(t *testing.T) {
+		requests = nil
+		rec := call(t, newService(false), "/render?target=a")
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.Empty(t, requests)
+	})
+}
+
+type resourceInstanceManager struct {
+	dsInfo datasourceInfo
+}
+
+func (f resourceInstanceManager) Get(_ context.Context, _ backend.PluginContext) (instancemgmt.Instance, error) {
+	return f.dsInfo, nil
+}
+
+func (f resourceInstanceManager) Do(_ context.Context, _ backend.PluginContext, _ instancemgmt.InstanceCallbackFunc) error {
+	return nil
+}
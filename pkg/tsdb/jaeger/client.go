@@ -0,0 +1,169 @@
+package jaeger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+type JaegerClient struct {
+	logger     log.Logger
+	url        string
+	httpClient *http.Client
+}
+
+func New(url string, hc *http.Client, logger log.Logger) (JaegerClient, error) {
+	client := JaegerClient{
+		logger:     logger,
+		url:        url,
+		httpClient: hc,
+	}
+	return client, nil
+}
+
+// Services returns list of services
+func (j *JaegerClient) Services(ctx context.Context) ([]string, error) {
+	services := []string{}
+	err := j.get(ctx, nil, &services, "api", "services")
+	return services, err
+}
+
+// Operations returns list of operations for the given service
+func (j *JaegerClient) Operations(ctx context.Context, serviceName string) ([]string, error) {
+	operations := []string{}
+	if serviceName == "" {
+		return operations, backend.DownstreamError(errors.New("invalid/empty serviceName"))
+	}
+
+	err := j.get(ctx, nil, &operations, "api", "services", serviceName, "operations")
+	return operations, err
+}
+
+// SearchParams are the parameters of a trace search. Times are in microseconds.
+type SearchParams struct {
+	Service   string
+	Operation string
+	// Tags is a JSON object of the tags the spans must have.
+	Tags        string
+	MinDuration string
+	MaxDuration string
+	Limit       int
+	Start       int64
+	End         int64
+}
+
+// Search returns the traces matching the search parameters
+func (j *JaegerClient) Search(ctx context.Context, p SearchParams) ([]TraceResponse, error) {
+	traces := []TraceResponse{}
+	if p.Service == "" {
+		return traces, backend.DownstreamError(errors.New("invalid/empty serviceName"))
+	}
+
+	params := url.Values{}
+	params.Set("service", p.Service)
+	params.Set("start", strconv.FormatInt(p.Start, 10))
+	params.Set("end", strconv.FormatInt(p.End, 10))
+	params.Set("lookback", "custom")
+	if p.Operation != "" {
+		params.Set("operation", p.Operation)
+	}
+	if p.Tags != "" {
+		params.Set("tags", p.Tags)
+	}
+	if p.MinDuration != "" {
+		params.Set("minDuration", p.MinDuration)
+	}
+	if p.MaxDuration != "" {
+		params.Set("maxDuration", p.MaxDuration)
+	}
+	if p.Limit > 0 {
+		params.Set("limit", strconv.Itoa(p.Limit))
+	}
+
+	err := j.get(ctx, params, &traces, "api", "traces")
+	return traces, err
+}
+
+// Trace returns trace for the given traceId. The time range is only sent if start and end are set. Times are in
+// microseconds.
+func (j *JaegerClient) Trace(ctx context.Context, traceID string, start, end int64) (TraceResponse, error) {
+	trace := TraceResponse{}
+	if traceID == "" {
+		return trace, backend.DownstreamError(errors.New("invalid/empty traceId"))
+	}
+
+	var params url.Values
+	if start > 0 && end > 0 {
+		params = url.Values{}
+		params.Set("start", strconv.FormatInt(start, 10))
+		params.Set("end", strconv.FormatInt(end, 10))
+	}
+
+	traces := []TraceResponse{}
+	if err := j.get(ctx, params, &traces, "api", "traces", traceID); err != nil {
+		return trace, err
+	}
+	if len(traces) == 0 {
+		return trace, backend.DownstreamError(fmt.Errorf("trace %s not found", traceID))
+	}
+	return traces[0], nil
+}
+
+// get requests a path of the API and decodes the data of the response into v. The segments of the path are escaped.
+func (j *JaegerClient) get(ctx context.Context, params url.Values, v any, segments ...string) error {
+	u, err := url.Parse(j.url)
+	if err != nil {
+		return backend.DownstreamError(fmt.Errorf("failed to parse url: %w", err))
+	}
+	escaped := make([]string, 0, len(segments))
+	for _, s := range segments {
+		escaped = append(escaped, url.PathEscape(s))
+	}
+	u.RawPath = strings.TrimSuffix(u.EscapedPath(), "/") + "/" + strings.Join(escaped, "/")
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + strings.Join(segments, "/")
+	if len(params) > 0 {
+		u.RawQuery = params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	res, err := j.httpClient.Do(req)
+	if err != nil {
+		if backend.IsDownstreamHTTPError(err) {
+			return backend.DownstreamError(err)
+		}
+		return err
+	}
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			j.logger.Error("Failed to close response body", "error", err)
+		}
+	}()
+
+	body := response[json.RawMessage]{}
+	decodeErr := json.NewDecoder(res.Body).Decode(&body)
+	if res.StatusCode/100 != 2 {
+		err := fmt.Errorf("request failed with status %s", res.Status)
+		if decodeErr == nil && len(body.Errors) > 0 {
+			err = fmt.Errorf("%w: %s", err, body.Errors[0].Msg)
+		}
+		return backend.DownstreamError(err)
+	}
+	if decodeErr != nil {
+		return backend.DownstreamError(fmt.Errorf("failed to decode response: %w", decodeErr))
+	}
+	if len(body.Data) == 0 || string(body.Data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(body.Data, v)
+}
@@ -0,0 +1,168 @@
+package jaeger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJaegerClient_Services(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockResponse   string
+		mockStatusCode int
+		expectedResult []string
+		expectError    bool
+	}{
+		{
+			name:           "Successful response",
+			mockResponse:   `{"data": ["service1", "service2"], "total": 2}`,
+			mockStatusCode: http.StatusOK,
+			expectedResult: []string{"service1", "service2"},
+		},
+		{
+			name:           "No services",
+			mockResponse:   `{"data": null, "total": 0}`,
+			mockStatusCode: http.StatusOK,
+			expectedResult: []string{},
+		},
+		{
+			name:           "Non-200 response",
+			mockResponse:   `{"data": null, "errors": [{"code": 500, "msg": "storage unavailable"}]}`,
+			mockStatusCode: http.StatusInternalServerError,
+			expectedResult: []string{},
+			expectError:    true,
+		},
+		{
+			name:           "Invalid JSON response",
+			mockResponse:   `{invalid json`,
+			mockStatusCode: http.StatusOK,
+			expectedResult: []string{},
+			expectError:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/api/services", r.URL.Path)
+				w.WriteHeader(tt.mockStatusCode)
+				_, _ = w.Write([]byte(tt.mockResponse))
+			}))
+			defer server.Close()
+
+			client, _ := New(server.URL, server.Client(), log.New())
+			services, err := client.Services(context.Background())
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.True(t, backend.IsDownstreamError(err))
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedResult, services)
+		})
+	}
+}
+
+func TestJaegerClient_Operations(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/jaeger/api/services/my%2Fservice/operations", r.URL.EscapedPath())
+		_, _ = w.Write([]byte(`{"data": ["op1", "op2"]}`))
+	}))
+	defer server.Close()
+
+	client, _ := New(server.URL+"/jaeger/", server.Client(), log.New())
+
+	operations, err := client.Operations(context.Background(), "my/service")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"op1", "op2"}, operations)
+
+	_, err = client.Operations(context.Background(), "")
+	assert.Error(t, err)
+}
+
+func TestJaegerClient_Search(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/traces", r.URL.Path)
+		q := r.URL.Query()
+		assert.Equal(t, "service1", q.Get("service"))
+		assert.Equal(t, "op1", q.Get("operation"))
+		assert.Equal(t, `{"error":"true"}`, q.Get("tags"))
+		assert.Equal(t, "10ms", q.Get("minDuration"))
+		assert.Empty(t, q.Get("maxDuration"))
+		assert.Equal(t, "20", q.Get("limit"))
+		assert.Equal(t, "1000", q.Get("start"))
+		assert.Equal(t, "2000", q.Get("end"))
+		assert.Equal(t, "custom", q.Get("lookback"))
+		_, _ = w.Write([]byte(`{"data": [{"traceID": "abc", "spans": [], "processes": {}}]}`))
+	}))
+	defer server.Close()
+
+	client, _ := New(server.URL, server.Client(), log.New())
+	traces, err := client.Search(context.Background(), SearchParams{
+		Service:     "service1",
+		Operation:   "op1",
+		Tags:        `{"error":"true"}`,
+		MinDuration: "10ms",
+		Limit:       20,
+		Start:       1000,
+		End:         2000,
+	})
+	require.NoError(t, err)
+	require.Len(t, traces, 1)
+	assert.Equal(t, "abc", traces[0].TraceID)
+
+	_, err = client.Search(context.Background(), SearchParams{})
+	assert.Error(t, err)
+}
+
+func TestJaegerClient_Trace(t *testing.T) {
+	t.Run("returns the trace", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/traces/abc", r.URL.Path)
+			assert.Equal(t, "1000", r.URL.Query().Get("start"))
+			assert.Equal(t, "2000", r.URL.Query().Get("end"))
+			_, _ = w.Write([]byte(`{"data": [{"traceID": "abc", "spans": [{"spanID": "1"}], "processes": {}}]}`))
+		}))
+		defer server.Close()
+
+		client, _ := New(server.URL, server.Client(), log.New())
+		trace, err := client.Trace(context.Background(), "abc", 1000, 2000)
+		require.NoError(t, err)
+		assert.Equal(t, "abc", trace.TraceID)
+		assert.Len(t, trace.Spans, 1)
+	})
+
+	t.Run("the time range is optional", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Empty(t, r.URL.RawQuery)
+			_, _ = w.Write([]byte(`{"data": [{"traceID": "abc"}]}`))
+		}))
+		defer server.Close()
+
+		client, _ := New(server.URL, server.Client(), log.New())
+		_, err := client.Trace(context.Background(), "abc", 0, 0)
+		require.NoError(t, err)
+	})
+
+	t.Run("missing traces are downstream errors", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"data": null, "errors": [{"code": 404, "msg": "trace not found"}]}`))
+		}))
+		defer server.Close()
+
+		client, _ := New(server.URL, server.Client(), log.New())
+		_, err := client.Trace(context.Background(), "abc", 0, 0)
+		require.Error(t, err)
+		assert.True(t, backend.IsDownstreamError(err))
+		assert.Contains(t, err.Error(), "trace not found")
+	})
+}
@@ -0,0 +1,95 @@
+package jaeger
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func (s *Service) registerResourceRoutes() *http.ServeMux {
+	router := http.NewServeMux()
+	router.HandleFunc("GET /services", s.withDatasourceHandlerFunc(getServicesHandler))
+	router.HandleFunc("GET /services/{service}/operations", s.withDatasourceHandlerFunc(getOperationsHandler))
+	router.HandleFunc("GET /traces", s.withDatasourceHandlerFunc(getTracesHandler))
+	router.HandleFunc("GET /trace/{traceId}", s.withDatasourceHandlerFunc(getTraceHandler))
+	return router
+}
+
+func (s *Service) withDatasourceHandlerFunc(getHandler func(d *datasourceInfo) http.HandlerFunc) func(rw http.ResponseWriter, r *http.Request) {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		client, err := s.getDSInfo(r.Context(), backend.PluginConfigFromContext(r.Context()))
+		if err != nil {
+			logger.FromContext(r.Context()).Warn("An error occurred while getting the data source information", "error", err)
+			writeResponse(nil, errors.New("error getting data source information from context"), rw)
+			return
+		}
+		h := getHandler(client)
+		h.ServeHTTP(rw, r)
+	}
+}
+
+func getServicesHandler(ds *datasourceInfo) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		services, err := ds.JaegerClient.Services(r.Context())
+		writeResponse(services, err, rw)
+	}
+}
+
+func getOperationsHandler(ds *datasourceInfo) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		service := strings.TrimSpace(r.PathValue("service"))
+		operations, err := ds.JaegerClient.Operations(r.Context(), service)
+		writeResponse(operations, err, rw)
+	}
+}
+
+func getTracesHandler(ds *datasourceInfo) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		params := SearchParams{
+			Service:     strings.TrimSpace(q.Get("service")),
+			Operation:   strings.TrimSpace(q.Get("operation")),
+			Tags:        q.Get("tags"),
+			MinDuration: q.Get("minDuration"),
+			MaxDuration: q.Get("maxDuration"),
+		}
+		// invalid numbers are ignored, Jaeger applies its defaults
+		params.Limit, _ = strconv.Atoi(q.Get("limit"))
+		params.Start, _ = strconv.ParseInt(q.Get("start"), 10, 64)
+		params.End, _ = strconv.ParseInt(q.Get("end"), 10, 64)
+		traces, err := ds.JaegerClient.Search(r.Context(), params)
+		writeResponse(traces, err, rw)
+	}
+}
+
+func getTraceHandler(ds *datasourceInfo) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		traceID := strings.TrimSpace(r.PathValue("traceId"))
+		start, _ := strconv.ParseInt(r.URL.Query().Get("start"), 10, 64)
+		end, _ := strconv.ParseInt(r.URL.Query().Get("end"), 10, 64)
+		trace, err := ds.JaegerClient.Trace(r.Context(), traceID, start, end)
+		writeResponse(trace, err, rw)
+	}
+}
+
+func writeResponse(res any, err error, rw http.ResponseWriter) {
+	if err != nil {
+		// This is used for resource calls, we don't need to add actual error message, but we should log it
+		logger.Warn("An error occurred while doing a resource call", "error", err)
+		http.Error(rw, "An error occurred within the plugin", http.StatusInternalServerError)
+		return
+	}
+	b, err := json.Marshal(res)
+	if err != nil {
+		// This is used for resource calls, we don't need to add actual error message, but we should log it
+		logger.Warn("An error occurred while processing response from resource call", "error", err)
+		http.Error(rw, "An error occurred within the plugin", http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	_, _ = rw.Write(b)
+}
@@ -0,0 +1,169 @@
+package jaeger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-logfmt/logfmt"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func queryData(ctx context.Context, dsInfo *datasourceInfo, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	response := backend.NewQueryDataResponse()
+	logger := dsInfo.JaegerClient.logger.FromContext(ctx)
+
+	for _, q := range req.Queries {
+		query, err := loadQuery(q)
+		if err != nil {
+			response.Responses[q.RefID] = errorResponse(err)
+			continue
+		}
+
+		switch query.QueryType {
+		case jaegerQueryTypeUpload, jaegerQueryTypeDependencyGraph:
+			logger.Debug("query type is not supported in backend mode", "queryType", query.QueryType)
+			response.Responses[q.RefID] = backend.DataResponse{
+				Error:       fmt.Errorf("unsupported query type %s. only available in frontend mode", query.QueryType),
+				ErrorSource: backend.ErrorSourcePlugin,
+			}
+		case jaegerQueryTypeSearch:
+			response.Responses[q.RefID] = search(ctx, dsInfo, q, query)
+		default:
+			response.Responses[q.RefID] = traceByID(ctx, dsInfo, q, query)
+		}
+	}
+	return response, nil
+}
+
+func search(ctx context.Context, dsInfo *datasourceInfo, q backend.DataQuery, query jaegerQuery) backend.DataResponse {
+	if query.Service == "" {
+		return errorResponse(backend.DownstreamError(errors.New("you must select a service")))
+	}
+
+	tags, err := convertTagsLogfmt(query.Tags)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	params := SearchParams{
+		Service:     query.Service,
+		Operation:   query.Operation,
+		Tags:        tags,
+		MinDuration: query.MinDuration,
+		MaxDuration: query.MaxDuration,
+		Limit:       query.Limit,
+		Start:       q.TimeRange.From.UnixMicro(),
+		End:         q.TimeRange.To.UnixMicro(),
+	}
+	if params.Operation == allOperations {
+		params.Operation = ""
+	}
+
+	traces, err := dsInfo.JaegerClient.Search(ctx, params)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return backend.DataResponse{
+		Frames: []*data.Frame{transformSearchResponse(traces, dsInfo.Settings)},
+	}
+}
+
+func traceByID(ctx context.Context, dsInfo *datasourceInfo, q backend.DataQuery, query jaegerQuery) backend.DataResponse {
+	traceID := strings.TrimSpace(query.Query)
+	if traceID == "" {
+		return backend.DataResponse{}
+	}
+
+	var start, end int64
+	if dsInfo.JSONData.TraceIdTimeParams.Enabled {
+		start, end = q.TimeRange.From.UnixMicro(), q.TimeRange.To.UnixMicro()
+	}
+	trace, err := dsInfo.JaegerClient.Trace(ctx, traceID, start, end)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	frame, err := transformTraceResponse(trace, q.RefID)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return backend.DataResponse{
+		Frames: []*data.Frame{frame},
+	}
+}
+
+func errorResponse(err error) backend.DataResponse {
+	es := backend.ErrorSourcePlugin
+	if backend.IsDownstreamError(err) {
+		es = backend.ErrorSourceDownstream
+	}
+	return backend.DataResponse{
+		Error:       err,
+		ErrorSource: es,
+	}
+}
+
+type jaegerQueryType string
+
+const (
+	// the query type is empty for trace ID queries
+	jaegerQueryTypeSearch          jaegerQueryType = "search"
+	jaegerQueryTypeUpload          jaegerQueryType = "upload"
+	jaegerQueryTypeDependencyGraph jaegerQueryType = "dependencyGraph"
+)
+
+// allOperations is the operation selected in the search form to search all the operations of a service
+const allOperations = "All"
+
+type jaegerQuery struct {
+	QueryType   jaegerQueryType `json:"queryType,omitempty"`
+	Service     string          `json:"service,omitempty"`
+	Operation   string          `json:"operation,omitempty"`
+	Query       string          `json:"query,omitempty"`
+	Tags        string          `json:"tags,omitempty"`
+	MinDuration string          `json:"minDuration,omitempty"`
+	MaxDuration string          `json:"maxDuration,omitempty"`
+	Limit       int             `json:"limit,omitempty"`
+}
+
+func loadQuery(backendQuery backend.DataQuery) (jaegerQuery, error) {
+	var query jaegerQuery
+	err := json.Unmarshal(backendQuery.JSON, &query)
+	if err != nil {
+		return query, backend.DownstreamError(fmt.Errorf("error while parsing the query json. %w", err))
+	}
+	return query, err
+}
+
+// convertTagsLogfmt converts the tags of a search written in logfmt to the JSON object expected by Jaeger.
+func convertTagsLogfmt(tags string) (string, error) {
+	if strings.TrimSpace(tags) == "" {
+		return "", nil
+	}
+
+	result := make(map[string]string)
+	d := logfmt.NewDecoder(strings.NewReader(tags))
+	for d.ScanRecord() {
+		for d.ScanKeyval() {
+			// keys without value are flags, like in the frontend
+			value := "true"
+			if d.Value() != nil {
+				value = string(d.Value())
+			}
+			result[string(d.Key())] = value
+		}
+	}
+	if err := d.Err(); err != nil {
+		return "", backend.DownstreamError(fmt.Errorf("invalid tags: %w", err))
+	}
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
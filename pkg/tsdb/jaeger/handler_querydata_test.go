@@ -0,0 +1,179 @@
+package jaeger
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTrace = `{
+	"traceID": "abc",
+	"spans": [
+		{
+			"traceID": "abc",
+			"spanID": "child",
+			"processID": "p2",
+			"operationName": "query",
+			"references": [
+				{"refType": "CHILD_OF", "traceID": "abc", "spanID": "root"},
+				{"refType": "FOLLOWS_FROM", "traceID": "other", "spanID": "linked"}
+			],
+			"startTime": 1700000000500000,
+			"duration": 1500,
+			"tags": [
+				{"key": "span.kind", "type": "string", "value": "client"},
+				{"key": "otel.status_code", "type": "string", "value": "ERROR"},
+				{"key": "otel.status_description", "type": "string", "value": "timeout"},
+				{"key": "otel.scope.name", "type": "string", "value": "db"},
+				{"key": "db.system", "type": "string", "value": "postgresql"}
+			],
+			"logs": [
+				{"timestamp": 1700000000501000, "fields": [{"key": "event", "type": "string", "value": "exception"}, {"key": "message", "type": "string", "value": "boom"}]}
+			]
+		},
+		{
+			"traceID": "abc",
+			"spanID": "root",
+			"processID": "p1",
+			"operationName": "GET /api",
+			"references": [],
+			"startTime": 1700000000000000,
+			"duration": 3000,
+			"tags": [],
+			"logs": []
+		}
+	],
+	"processes": {
+		"p1": {"serviceName": "frontend", "tags": [{"key": "hostname", "type": "string", "value": "host1"}]},
+		"p2": {"serviceName": "backend", "tags": []}
+	}
+}`
+
+func TestQueryData(t *testing.T) {
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		switch r.URL.Path {
+		case "/api/traces/abc":
+			_, _ = w.Write([]byte(`{"data": [` + testTrace + `]}`))
+		case "/api/traces":
+			_, _ = w.Write([]byte(`{"data": [` + testTrace + `]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, _ := New(server.URL, server.Client(), log.New())
+	dsInfo := &datasourceInfo{
+		JaegerClient: client,
+		Settings:     backend.DataSourceInstanceSettings{UID: "jaeger-uid", Name: "Jaeger"},
+	}
+	timeRange := backend.TimeRange{From: time.UnixMilli(1000), To: time.UnixMilli(2000)}
+
+	query := func(t *testing.T, q string) backend.DataResponse {
+		t.Helper()
+		requests = nil
+		res, err := queryData(context.Background(), dsInfo, &backend.QueryDataRequest{
+			Queries: []backend.DataQuery{{RefID: "A", JSON: []byte(q), TimeRange: timeRange}},
+		})
+		require.NoError(t, err)
+		return res.Responses["A"]
+	}
+
+	t.Run("trace ID queries return the trace", func(t *testing.T) {
+		res := query(t, `{"query": " abc "}`)
+		require.NoError(t, res.Error)
+		require.Len(t, res.Frames, 1)
+		require.Len(t, requests, 1)
+		assert.Empty(t, requests[0].URL.RawQuery, "the time range should only be sent if enabled")
+
+		frame := res.Frames[0]
+		assert.Equal(t, data.VisTypeTrace, string(frame.Meta.PreferredVisualization))
+		require.Equal(t, 2, frame.Rows())
+
+		row := func(name string) any {
+			field, _ := frame.FieldByName(name)
+			require.NotNil(t, field, name)
+			return field.At(0)
+		}
+		assert.Equal(t, "abc", row("traceID"))
+		assert.Equal(t, "child", row("spanID"))
+		assert.Equal(t, "root", row("parentSpanID"))
+		assert.Equal(t, "query", row("operationName"))
+		assert.Equal(t, "backend", row("serviceName"))
+		assert.Equal(t, "client", row("kind"))
+		assert.Equal(t, statusCodeError, row("statusCode"))
+		assert.Equal(t, "timeout", row("statusMessage"))
+		assert.Equal(t, "db", row("instrumentationLibraryName"))
+		assert.Equal(t, float64(1700000000500), row("startTime"))
+		assert.Equal(t, 1.5, row("duration"))
+		assert.JSONEq(t, `[{"key": "db.system", "value": "postgresql"}]`, string(row("tags").(json.RawMessage)))
+		assert.JSONEq(t, `[{"spanID": "linked", "traceID": "other", "tags": [{"key": "refType", "value": "FOLLOWS_FROM"}]}]`, string(row("references").(json.RawMessage)))
+		assert.JSONEq(t, `[{"timestamp": 1700000000501, "name": "exception", "fields": [{"key": "message", "value": "boom"}]}]`, string(row("logs").(json.RawMessage)))
+
+		serviceTags, _ := frame.FieldByName("serviceTags")
+		assert.JSONEq(t, `[{"key": "hostname", "value": "host1"}]`, string(serviceTags.At(1).(json.RawMessage)))
+	})
+
+	t.Run("trace ID queries send the time range if enabled", func(t *testing.T) {
+		dsInfo.JSONData.TraceIdTimeParams.Enabled = true
+		defer func() { dsInfo.JSONData.TraceIdTimeParams.Enabled = false }()
+
+		res := query(t, `{"query": "abc"}`)
+		require.NoError(t, res.Error)
+		require.Len(t, requests, 1)
+		assert.Equal(t, "1000000", requests[0].URL.Query().Get("start"))
+		assert.Equal(t, "2000000", requests[0].URL.Query().Get("end"))
+	})
+
+	t.Run("search queries return a table of the traces", func(t *testing.T) {
+		res := query(t, `{"queryType": "search", "service": "frontend", "operation": "All", "tags": "error=true http.status_code=\"500\""}`)
+		require.NoError(t, res.Error)
+		require.Len(t, requests, 1)
+		q := requests[0].URL.Query()
+		assert.Equal(t, "frontend", q.Get("service"))
+		assert.Empty(t, q.Get("operation"))
+		assert.JSONEq(t, `{"error": "true", "http.status_code": "500"}`, q.Get("tags"))
+		assert.Equal(t, "1000000", q.Get("start"))
+
+		require.Len(t, res.Frames, 1)
+		frame := res.Frames[0]
+		require.Equal(t, 1, frame.Rows())
+		assert.Equal(t, "abc", frame.Fields[0].At(0))
+		assert.Equal(t, "frontend: GET /api", frame.Fields[1].At(0))
+		assert.Equal(t, time.UnixMicro(1700000000000000), frame.Fields[2].At(0))
+		assert.Equal(t, int64(501500), frame.Fields[3].At(0))
+		require.Len(t, frame.Fields[0].Config.Links, 1)
+		assert.Equal(t, "jaeger-uid", frame.Fields[0].Config.Links[0].Internal.DatasourceUID)
+	})
+
+	t.Run("search queries require a service", func(t *testing.T) {
+		res := query(t, `{"queryType": "search"}`)
+		require.Error(t, res.Error)
+		assert.Equal(t, backend.ErrorSourceDownstream, res.ErrorSource)
+		assert.Empty(t, requests)
+	})
+
+	t.Run("upload queries are not supported", func(t *testing.T) {
+		res := query(t, `{"queryType": "upload"}`)
+		require.Error(t, res.Error)
+		assert.Empty(t, requests)
+	})
+
+	t.Run("empty trace ID queries return no data", func(t *testing.T) {
+		res := query(t, `{"query": ""}`)
+		require.NoError(t, res.Error)
+		assert.Empty(t, res.Frames)
+		assert.Empty(t, requests)
+	})
+}
@@ -0,0 +1,309 @@
+package jaeger
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Tags of the Jaeger spans holding the OpenTelemetry span fields that have their own column in the trace frame.
+const (
+	tagSpanKind          = "span.kind"
+	tagStatusCode        = "otel.status_code"
+	tagStatusDescription = "otel.status_description"
+	tagLibraryName       = "otel.library.name"
+	tagLibraryVersion    = "otel.library.version"
+	tagScopeName         = "otel.scope.name"
+	tagScopeVersion      = "otel.scope.version"
+	tagTraceState        = "w3c.tracestate"
+)
+
+// Status codes of the OpenTelemetry spans, used by the statusCode column.
+const (
+	statusCodeUnset int64 = 0
+	statusCodeOk    int64 = 1
+	statusCodeError int64 = 2
+)
+
+type KeyValue struct {
+	Value any    `json:"value"`
+	Key   string `json:"key"`
+}
+
+type TraceLogRow struct {
+	// Millisecond epoch time
+	Timestamp float64     `json:"timestamp"`
+	Fields    []*KeyValue `json:"fields"`
+	Name      string      `json:"name,omitempty"`
+}
+
+type TraceReference struct {
+	SpanID  string      `json:"spanID"`
+	TraceID string      `json:"traceID"`
+	Tags    []*KeyValue `json:"tags"`
+}
+
+// transformTraceResponse converts a trace to a frame with the same fields as the trace frames of Tempo.
+func transformTraceResponse(trace TraceResponse, refID string) (*data.Frame, error) {
+	frame := data.NewFrame(refID,
+		data.NewField("traceID", nil, []string{}),
+		data.NewField("spanID", nil, []string{}),
+		data.NewField("parentSpanID", nil, []string{}),
+		data.NewField("operationName", nil, []string{}),
+		data.NewField("serviceName", nil, []string{}),
+		data.NewField("kind", nil, []string{}),
+		data.NewField("statusCode", nil, []int64{}),
+		data.NewField("statusMessage", nil, []string{}),
+		data.NewField("instrumentationLibraryName", nil, []string{}),
+		data.NewField("instrumentationLibraryVersion", nil, []string{}),
+		data.NewField("traceState", nil, []string{}),
+		data.NewField("serviceTags", nil, []json.RawMessage{}),
+		data.NewField("startTime", nil, []float64{}),
+		data.NewField("duration", nil, []float64{}),
+		data.NewField("logs", nil, []json.RawMessage{}),
+		data.NewField("references", nil, []json.RawMessage{}),
+		data.NewField("tags", nil, []json.RawMessage{}),
+	)
+	frame.Meta = &data.FrameMeta{
+		PreferredVisualization: data.VisTypeTrace,
+		Custom: map[string]any{
+			"traceFormat": "jaeger",
+		},
+	}
+
+	for _, span := range trace.Spans {
+		row, err := spanToSpanRow(span, trace.Processes)
+		if err != nil {
+			return nil, err
+		}
+		frame.AppendRow(row...)
+	}
+	return frame, nil
+}
+
+func spanToSpanRow(span Span, processes map[string]TraceProcess) ([]any, error) {
+	parentSpanID := ""
+	references := make([]*TraceReference, 0, len(span.References))
+	for _, ref := range span.References {
+		if parentSpanID == "" && ref.RefType == "CHILD_OF" {
+			parentSpanID = ref.SpanID
+			continue
+		}
+		references = append(references, &TraceReference{
+			SpanID:  ref.SpanID,
+			TraceID: ref.TraceID,
+			Tags:    []*KeyValue{{Key: "refType", Value: ref.RefType}},
+		})
+	}
+
+	process, ok := processes[span.ProcessID]
+	serviceName := process.ServiceName
+	if !ok || serviceName == "" {
+		serviceName = "unknown"
+	}
+
+	kind := ""
+	statusCode := statusCodeUnset
+	statusMessage := ""
+	libraryName := ""
+	libraryVersion := ""
+	traceState := ""
+	tags := make([]*KeyValue, 0, len(span.Tags))
+	for _, tag := range span.Tags {
+		switch tag.Key {
+		case tagSpanKind:
+			kind = fmt.Sprint(tag.Value)
+		case tagStatusCode:
+			switch strings.ToUpper(fmt.Sprint(tag.Value)) {
+			case "OK":
+				statusCode = statusCodeOk
+			case "ERROR":
+				statusCode = statusCodeError
+			}
+		case tagStatusDescription:
+			statusMessage = fmt.Sprint(tag.Value)
+		case tagLibraryName, tagScopeName:
+			libraryName = fmt.Sprint(tag.Value)
+		case tagLibraryVersion, tagScopeVersion:
+			libraryVersion = fmt.Sprint(tag.Value)
+		case tagTraceState:
+			traceState = fmt.Sprint(tag.Value)
+		default:
+			tags = append(tags, &KeyValue{Key: tag.Key, Value: tag.Value})
+		}
+	}
+
+	serviceTags := make([]*KeyValue, 0, len(process.Tags))
+	for _, tag := range process.Tags {
+		serviceTags = append(serviceTags, &KeyValue{Key: tag.Key, Value: tag.Value})
+	}
+	serviceTagsJson, err := json.Marshal(serviceTags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal service tags: %w", err)
+	}
+
+	spanTags, err := json.Marshal(tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal span tags: %w", err)
+	}
+
+	logs, err := json.Marshal(spanLogsToLogs(span.Logs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal span logs: %w", err)
+	}
+
+	refs, err := json.Marshal(references)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal span references: %w", err)
+	}
+
+	// Order matters (look at dataframe order)
+	return []any{
+		span.TraceID,
+		span.SpanID,
+		parentSpanID,
+		span.OperationName,
+		serviceName,
+		kind,
+		statusCode,
+		statusMessage,
+		libraryName,
+		libraryVersion,
+		traceState,
+		json.RawMessage(serviceTagsJson),
+		float64(span.StartTime) / 1000,
+		float64(span.Duration) / 1000,
+		json.RawMessage(logs),
+		json.RawMessage(refs),
+		json.RawMessage(spanTags),
+	}, nil
+}
+
+func spanLogsToLogs(spanLogs []TraceLog) []*TraceLogRow {
+	logs := make([]*TraceLogRow, 0, len(spanLogs))
+	for _, l := range spanLogs {
+		log := &TraceLogRow{
+			Timestamp: float64(l.Timestamp) / 1000,
+			Fields:    make([]*KeyValue, 0, len(l.Fields)),
+		}
+		for _, f := range l.Fields {
+			// the event field names OpenTelemetry events
+			if f.Key == "event" && log.Name == "" {
+				log.Name = fmt.Sprint(f.Value)
+				continue
+			}
+			log.Fields = append(log.Fields, &KeyValue{Key: f.Key, Value: f.Value})
+		}
+		logs = append(logs, log)
+	}
+	return logs
+}
+
+// transformSearchResponse converts the traces found by a search to a table of the traces, the most recent first.
+// The trace IDs link to the trace query of the data source.
+func transformSearchResponse(traces []TraceResponse, settings backend.DataSourceInstanceSettings) *data.Frame {
+	traceIDField := data.NewField("traceID", nil, []string{})
+	traceIDField.Config = &data.FieldConfig{
+		Unit:              "string",
+		DisplayNameFromDS: "Trace ID",
+		Links: []data.DataLink{
+			{
+				Title: "Trace: ${__value.raw}",
+				URL:   "",
+				Internal: &data.InternalDataLink{
+					DatasourceUID:  settings.UID,
+					DatasourceName: settings.Name,
+					Query: map[string]any{
+						"query": "${__value.raw}",
+					},
+				},
+			},
+		},
+	}
+	traceNameField := data.NewField("traceName", nil, []string{})
+	traceNameField.Config = &data.FieldConfig{DisplayNameFromDS: "Trace name"}
+	startTimeField := data.NewField("startTime", nil, []time.Time{})
+	startTimeField.Config = &data.FieldConfig{DisplayNameFromDS: "Start time"}
+	durationField := data.NewField("duration", nil, []int64{})
+	durationField.Config = &data.FieldConfig{DisplayNameFromDS: "Duration", Unit: "µs"}
+
+	frame := data.NewFrame("Traces", traceIDField, traceNameField, startTimeField, durationField)
+	frame.Meta = &data.FrameMeta{
+		PreferredVisualization: data.VisTypeTable,
+	}
+
+	type traceRow struct {
+		traceID   string
+		traceName string
+		startTime int64
+		duration  int64
+	}
+	rows := make([]traceRow, 0, len(traces))
+	for _, trace := range traces {
+		if len(trace.Spans) == 0 {
+			continue
+		}
+
+		start, end := trace.Spans[0].StartTime, int64(0)
+		for _, span := range trace.Spans {
+			start = min(start, span.StartTime)
+			end = max(end, span.StartTime+span.Duration)
+		}
+		rows = append(rows, traceRow{
+			traceID:   trace.TraceID,
+			traceName: getTraceName(trace),
+			startTime: start,
+			duration:  end - start,
+		})
+	}
+
+	// Show the most recent traces
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].startTime > rows[j].startTime
+	})
+	for _, row := range rows {
+		frame.AppendRow(row.traceID, row.traceName, time.UnixMicro(row.startTime), row.duration)
+	}
+	return frame
+}
+
+// getTraceName returns the name of the root span of a trace, like the Jaeger UI does. The root span is the span
+// without references to another span of the trace, preferring the span with the fewest references and then the
+// earliest span.
+func getTraceName(trace TraceResponse) string {
+	spanIDs := make(map[string]bool, len(trace.Spans))
+	for _, span := range trace.Spans {
+		spanIDs[span.SpanID] = true
+	}
+
+	var root *Span
+	for i := range trace.Spans {
+		span := &trace.Spans[i]
+		hasInternalRef := false
+		for _, ref := range span.References {
+			if ref.TraceID == span.TraceID && spanIDs[ref.SpanID] {
+				hasInternalRef = true
+				break
+			}
+		}
+		if hasInternalRef {
+			continue
+		}
+
+		if root == nil ||
+			len(span.References) < len(root.References) ||
+			(len(span.References) == len(root.References) && span.StartTime < root.StartTime) {
+			root = span
+		}
+	}
+
+	if root == nil {
+		return ""
+	}
+	return trace.Processes[root.ProcessID].ServiceName + ": " + root.OperationName
+}
@@ -0,0 +1,62 @@
+package jaeger
+
+// Types of the responses of the Jaeger HTTP API, used by the Jaeger UI.
+// https://github.com/jaegertracing/jaeger/blob/main/model/json/model.go
+
+type KeyValueType struct {
+	Key   string `json:"key"`
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+type TraceLog struct {
+	// Microseconds since the epoch
+	Timestamp int64          `json:"timestamp"`
+	Fields    []KeyValueType `json:"fields"`
+}
+
+type TraceSpanReference struct {
+	RefType string `json:"refType"`
+	SpanID  string `json:"spanID"`
+	TraceID string `json:"traceID"`
+}
+
+type Span struct {
+	TraceID       string               `json:"traceID"`
+	SpanID        string               `json:"spanID"`
+	ProcessID     string               `json:"processID"`
+	OperationName string               `json:"operationName"`
+	References    []TraceSpanReference `json:"references"`
+	// Times are in microseconds
+	StartTime   int64          `json:"startTime"`
+	Duration    int64          `json:"duration"`
+	Logs        []TraceLog     `json:"logs"`
+	Tags        []KeyValueType `json:"tags"`
+	Warnings    []string       `json:"warnings"`
+	Flags       int            `json:"flags"`
+	StackTraces []string       `json:"stackTraces"`
+}
+
+type TraceProcess struct {
+	ServiceName string         `json:"serviceName"`
+	Tags        []KeyValueType `json:"tags"`
+}
+
+type TraceResponse struct {
+	Processes map[string]TraceProcess `json:"processes"`
+	TraceID   string                  `json:"traceID"`
+	Warnings  []string                `json:"warnings"`
+	Spans     []Span                  `json:"spans"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Msg     string `json:"msg"`
+	TraceID string `json:"traceID,omitempty"`
+}
+
+// response is the envelope of all the responses of the API.
+type response[T any] struct {
+	Data   T               `json:"data"`
+	Errors []responseError `json:"errors"`
+}
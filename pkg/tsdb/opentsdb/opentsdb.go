@@ -8,35 +8,47 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/datasource"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/patrickmn/go-cache"
 
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/infra/httpclient"
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/tracing"
 	"github.com/grafana/grafana/pkg/setting"
 )
 
 var logger = log.New("tsdb.opentsdb")
 
 type Service struct {
-	im instancemgmt.InstanceManager
+	im     instancemgmt.InstanceManager
+	tracer tracing.Tracer
 }
 
-func ProvideService(httpClientProvider httpclient.Provider) *Service {
+func ProvideService(httpClientProvider httpclient.Provider, tracer tracing.Tracer) *Service {
 	return &Service{
-		im: datasource.NewInstanceManager(newInstanceSettings(httpClientProvider)),
+		im:     datasource.NewInstanceManager(newInstanceSettings(httpClientProvider)),
+		tracer: tracer,
 	}
 }
 
 type datasourceInfo struct {
 	HTTPClient *http.Client
 	URL        string
+	// TSDBVersion is the version of OpenTSDB selected in the settings: 1 is <=2.1, 2 is 2.2 and 3 is 2.3 and later
+	TSDBVersion int
+
+	// resourceCache caches the responses of the resource calls. It is nil if the responses are not cached.
+	resourceCache *cache.Cache
 }
 
 type DsAccess string
@@ -53,9 +65,21 @@ func newInstanceSettings(httpClientProvider httpclient.Provider) datasource.Inst
 			return nil, err
 		}
 
+		jsonData := struct {
+			TSDBVersion int `json:"tsdbVersion"`
+		}{}
+		if len(settings.JSONData) > 0 {
+			if err := json.Unmarshal(settings.JSONData, &jsonData); err != nil {
+				return nil, fmt.Errorf("error reading settings: %w", err)
+			}
+		}
+
 		model := &datasourceInfo{
-			HTTPClient: client,
-			URL:        settings.URL,
+			HTTPClient:  client,
+			URL:         settings.URL,
+			TSDBVersion: max(jsonData.TSDBVersion, 1),
+			// The responses are cached per user, see resourceCacheKey
+			resourceCache: newResourceCache(),
 		}
 
 		return model, nil
@@ -74,9 +98,18 @@ func (s *Service) QueryData(ctx context.Context, req *backend.QueryDataRequest)
 	tsdbQuery.Start = q.TimeRange.From.UnixNano() / int64(time.Millisecond)
 	tsdbQuery.End = q.TimeRange.To.UnixNano() / int64(time.Millisecond)
 
+	dsInfo, err := s.getDSInfo(ctx, req.PluginContext)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, query := range req.Queries {
-		metric := s.buildMetric(query)
-		tsdbQuery.Queries = append(tsdbQuery.Queries, metric)
+		if metric := s.buildMetric(query, dsInfo); metric != nil {
+			tsdbQuery.Queries = append(tsdbQuery.Queries, metric)
+		}
+	}
+	if len(tsdbQuery.Queries) == 0 {
+		return backend.NewQueryDataResponse(), nil
 	}
 
 	// TODO: Don't use global variable
@@ -84,11 +117,6 @@ func (s *Service) QueryData(ctx context.Context, req *backend.QueryDataRequest)
 		logger.Debug("OpenTsdb request", "params", tsdbQuery)
 	}
 
-	dsInfo, err := s.getDSInfo(ctx, req.PluginContext)
-	if err != nil {
-		return nil, err
-	}
-
 	request, err := s.createRequest(ctx, logger, dsInfo, tsdbQuery)
 	if err != nil {
 		return &backend.QueryDataResponse{}, err
@@ -193,7 +221,9 @@ func (s *Service) parseResponse(logger log.Logger, res *http.Response, myRefID s
 	return resp, nil
 }
 
-func (s *Service) buildMetric(query backend.DataQuery) map[string]any {
+// buildMetric converts a query to an OpenTSDB sub query with the options of the query editor. It returns nil for
+// queries without metric, which the query editor doesn't send either.
+func (s *Service) buildMetric(query backend.DataQuery, dsInfo *datasourceInfo) map[string]any {
 	metric := make(map[string]any)
 
 	model, err := simplejson.NewJson(query.JSON)
@@ -203,6 +233,9 @@ func (s *Service) buildMetric(query backend.DataQuery) map[string]any {
 
 	// Setting metric and aggregator
 	metric["metric"] = model.Get("metric").MustString()
+	if metric["metric"] == "" {
+		return nil
+	}
 	metric["aggregator"] = model.Get("aggregator").MustString()
 
 	// Setting downsampling options
@@ -210,14 +243,22 @@ func (s *Service) buildMetric(query backend.DataQuery) map[string]any {
 	if !disableDownsampling {
 		downsampleInterval := model.Get("downsampleInterval").MustString()
 		if downsampleInterval == "" {
-			downsampleInterval = "1m" // default value for blank
+			// like the query editor, default to the interval of the query
+			downsampleInterval = "1m"
+			if query.Interval > 0 {
+				downsampleInterval = gtime.FormatInterval(query.Interval)
+			}
+		}
+		if fractionalSecondsRegex.MatchString(downsampleInterval) {
+			// OpenTSDB doesn't support fractional intervals
+			seconds, _ := strconv.ParseFloat(strings.TrimSuffix(downsampleInterval, "s"), 64)
+			downsampleInterval = strconv.FormatFloat(seconds*1000, 'f', -1, 64) + "ms"
 		}
-		downsample := downsampleInterval + "-" + model.Get("downsampleAggregator").MustString()
-		if model.Get("downsampleFillPolicy").MustString() != "none" {
-			metric["downsample"] = downsample + "-" + model.Get("downsampleFillPolicy").MustString()
-		} else {
-			metric["downsample"] = downsample
+		downsample := downsampleInterval + "-" + model.Get("downsampleAggregator").MustString("avg")
+		if fillPolicy := model.Get("downsampleFillPolicy").MustString(); fillPolicy != "" && fillPolicy != "none" {
+			downsample += "-" + fillPolicy
 		}
+		metric["downsample"] = downsample
 	}
 
 	// Setting rate options
@@ -226,17 +267,19 @@ func (s *Service) buildMetric(query backend.DataQuery) map[string]any {
 		rateOptions := make(map[string]any)
 		rateOptions["counter"] = model.Get("isCounter").MustBool()
 
-		counterMax, counterMaxCheck := model.CheckGet("counterMax")
+		// the query editor stores the counter options as strings
+		counterMax, counterMaxCheck := numberOption(model, "counterMax")
 		if counterMaxCheck {
-			rateOptions["counterMax"] = counterMax.MustFloat64()
+			rateOptions["counterMax"] = counterMax
 		}
 
-		resetValue, resetValueCheck := model.CheckGet("counterResetValue")
+		resetValue, resetValueCheck := numberOption(model, "counterResetValue")
 		if resetValueCheck {
-			rateOptions["resetValue"] = resetValue.MustFloat64()
+			rateOptions["resetValue"] = resetValue
 		}
 
-		if !counterMaxCheck && (!resetValueCheck || resetValue.MustFloat64() == 0) {
+		// dropResets is only supported since OpenTSDB 2.2
+		if dsInfo.TSDBVersion >= 2 && !counterMaxCheck && (!resetValueCheck || resetValue == 0) {
 			rateOptions["dropResets"] = true
 		}
 
@@ -255,9 +298,32 @@ func (s *Service) buildMetric(query backend.DataQuery) map[string]any {
 		metric["filters"] = filters.MustArray()
 	}
 
+	if model.Get("explicitTags").MustBool() {
+		metric["explicitTags"] = true
+	}
+
 	return metric
 }
 
+var fractionalSecondsRegex = regexp.MustCompile(`^\d*\.\d+s$`)
+
+// numberOption returns an option of the query stored as a number or as a string. Empty strings are unset options.
+func numberOption(model *simplejson.Json, key string) (float64, bool) {
+	value, ok := model.CheckGet(key)
+	if !ok {
+		return 0, false
+	}
+	if str, err := value.String(); err == nil {
+		if str == "" {
+			return 0, false
+		}
+		n, err := strconv.ParseFloat(str, 64)
+		return n, err == nil
+	}
+	n, err := value.Float64()
+	return n, err == nil
+}
+
 func (s *Service) getDSInfo(ctx context.Context, pluginCtx backend.PluginContext) (*datasourceInfo, error) {
 	i, err := s.im.Get(ctx, pluginCtx)
 	if err != nil {
@@ -132,7 +132,7 @@ func TestOpenTsdbExecutor(t *testing.T) {
 			),
 		}
 
-		metric := service.buildMetric(query)
+		metric := service.buildMetric(query, &datasourceInfo{})
 
 		require.Len(t, metric, 3)
 		require.Equal(t, "cpu.average.percent", metric["metric"])
@@ -154,7 +154,7 @@ func TestOpenTsdbExecutor(t *testing.T) {
 			),
 		}
 
-		metric := service.buildMetric(query)
+		metric := service.buildMetric(query, &datasourceInfo{})
 
 		require.Len(t, metric, 2)
 		require.Equal(t, "cpu.average.percent", metric["metric"])
@@ -175,7 +175,7 @@ func TestOpenTsdbExecutor(t *testing.T) {
 			),
 		}
 
-		metric := service.buildMetric(query)
+		metric := service.buildMetric(query, &datasourceInfo{})
 
 		require.Len(t, metric, 3)
 		require.Equal(t, "cpu.average.percent", metric["metric"])
@@ -201,7 +201,7 @@ func TestOpenTsdbExecutor(t *testing.T) {
 			),
 		}
 
-		metric := service.buildMetric(query)
+		metric := service.buildMetric(query, &datasourceInfo{})
 
 		require.Len(t, metric, 3)
 		require.Equal(t, "cpu.average.percent", metric["metric"])
@@ -232,7 +232,7 @@ func TestOpenTsdbExecutor(t *testing.T) {
 			),
 		}
 
-		metric := service.buildMetric(query)
+		metric := service.buildMetric(query, &datasourceInfo{})
 
 		require.Len(t, metric, 5)
 		require.Equal(t, "cpu.average.percent", metric["metric"])
@@ -267,7 +267,7 @@ func TestOpenTsdbExecutor(t *testing.T) {
 			),
 		}
 
-		metric := service.buildMetric(query)
+		metric := service.buildMetric(query, &datasourceInfo{})
 
 		require.Len(t, metric, 5)
 		require.Equal(t, "cpu.average.percent", metric["metric"])
@@ -286,4 +286,61 @@ func TestOpenTsdbExecutor(t *testing.T) {
 		require.Equal(t, float64(45), metricRateOptions["counterMax"])
 		require.Equal(t, float64(60), metricRateOptions["resetValue"])
 	})
+	t.Run("Build metric with the options of the query editor", func(t *testing.T) {
+		query := backend.DataQuery{
+			Interval: 30 * time.Second,
+			JSON: []byte(`
+					{
+						"metric": "cpu.average.percent",
+						"aggregator": "avg",
+						"downsampleAggregator": "max",
+						"downsampleFillPolicy": "",
+						"shouldComputeRate": true,
+						"isCounter": true,
+						"counterMax": "",
+						"counterResetValue": "0",
+						"explicitTags": true
+					}`,
+			),
+		}
+
+		metric := service.buildMetric(query, &datasourceInfo{TSDBVersion: 2})
+
+		require.Equal(t, "30s-max", metric["downsample"])
+		require.True(t, metric["explicitTags"].(bool))
+		require.Equal(t, map[string]any{"counter": true, "resetValue": float64(0), "dropResets": true}, metric["rateOptions"])
+
+		metric = service.buildMetric(query, &datasourceInfo{TSDBVersion: 1})
+		require.Equal(t, map[string]any{"counter": true, "resetValue": float64(0)}, metric["rateOptions"])
+	})
+
+	t.Run("Build metric with counter options stored as strings", func(t *testing.T) {
+		query := backend.DataQuery{
+			JSON: []byte(`
+					{
+						"metric": "cpu.average.percent",
+						"aggregator": "avg",
+						"downsampleInterval": "0.5s",
+						"downsampleAggregator": "avg",
+						"shouldComputeRate": true,
+						"isCounter": true,
+						"counterMax": "45",
+						"counterResetValue": "60"
+					}`,
+			),
+		}
+
+		metric := service.buildMetric(query, &datasourceInfo{TSDBVersion: 3})
+
+		require.Equal(t, "500ms-avg", metric["downsample"])
+		require.Equal(t, map[string]any{"counter": true, "counterMax": float64(45), "resetValue": float64(60)}, metric["rateOptions"])
+	})
+
+	t.Run("Build metric without metric", func(t *testing.T) {
+		query := backend.DataQuery{
+			JSON: []byte(`{"aggregator": "avg"}`),
+		}
+
+		require.Nil(t, service.buildMetric(query, &datasourceInfo{}))
+	})
 }
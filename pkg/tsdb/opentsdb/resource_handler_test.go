@@ -0,0 +1,146 @@
+package opentsdb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
+)
+
+func TestResourceRequests(t *testing.T) {
+	var requests []*http.Request
+	opentsdb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if r.URL.Query().Get("q") == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("failed"))
+			return
+		}
+		_, _ = w.Write([]byte(`["a", "b"]`))
+	}))
+	defer opentsdb.Close()
+
+	newService := func(cached bool) *Service {
+		dsInfo := &datasourceInfo{HTTPClient: opentsdb.Client(), URL: opentsdb.URL}
+		if cached {
+			dsInfo.resourceCache = newResourceCache()
+		}
+		return &Service{im: resourceInstanceManager{dsInfo: dsInfo}, tracer: tracing.InitializeTracerForTest()}
+	}
+
+	callWithHeader := func(t *testing.T, s *Service, path string, header http.Header) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header = header
+		s.newResourceMux().ServeHTTP(rec, req)
+		return rec
+	}
+	call := func(t *testing.T, s *Service, path string) *httptest.ResponseRecorder {
+		t.Helper()
+		return callWithHeader(t, s, path, http.Header{})
+	}
+
+	t.Run("suggestions are forwarded with the parameters of the API", func(t *testing.T) {
+		requests = nil
+		rec := call(t, newService(false), "/api/suggest?type=tagk&q=ho&max=10&other=a")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `["a", "b"]`, rec.Body.String())
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		require.Len(t, requests, 1)
+		assert.Equal(t, "/api/suggest", requests[0].URL.Path)
+		assert.Equal(t, "max=10&q=ho&type=tagk", requests[0].URL.RawQuery)
+	})
+
+	t.Run("invalid suggest types are rejected", func(t *testing.T) {
+		requests = nil
+		rec := call(t, newService(false), "/api/suggest?type=other")
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Empty(t, requests)
+	})
+
+	t.Run("successful responses are cached", func(t *testing.T) {
+		requests = nil
+		s := newService(true)
+
+		call(t, s, "/api/aggregators")
+		rec := call(t, s, "/api/aggregators")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `["a", "b"]`, rec.Body.String())
+		require.Len(t, requests, 1)
+		assert.Equal(t, "/api/aggregators", requests[0].URL.Path)
+
+		call(t, s, "/api/suggest?type=metrics&q=a")
+		call(t, s, "/api/suggest?type=metrics&q=b")
+		require.Len(t, requests, 3)
+	})
+
+	t.Run("errors are returned and not cached", func(t *testing.T) {
+		requests = nil
+		s := newService(true)
+
+		call(t, s, "/api/suggest?type=metrics&q=fail")
+		rec := call(t, s, "/api/suggest?type=metrics&q=fail")
+		require.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "failed", rec.Body.String())
+		require.Len(t, requests, 2)
+	})
+
+	t.Run("responses are cached per user", func(t *testing.T) {
+		requests = nil
+		s := newService(true)
+
+		callWithHeader(t, s, "/api/suggest?type=metrics&q=a", http.Header{"X-Grafana-User": {"a"}})
+		callWithHeader(t, s, "/api/suggest?type=metrics&q=a", http.Header{"X-Grafana-User": {"a"}})
+		require.Len(t, requests, 1)
+
+		callWithHeader(t, s, "/api/suggest?type=metrics&q=a", http.Header{"X-Grafana-User": {"b"}})
+		callWithHeader(t, s, "/api/suggest?type=metrics&q=a", http.Header{"Cookie": {"session=b"}})
+		require.Len(t, requests, 3)
+	})
+
+	t.Run("responses are not cached if the credentials of the users are forwarded", func(t *testing.T) {
+		requests = nil
+		s := newService(true)
+
+		callWithHeader(t, s, "/api/suggest?type=metrics&q=a", http.Header{"Authorization": {"Bearer a"}})
+		callWithHeader(t, s, "/api/suggest?type=metrics&q=a", http.Header{"Authorization": {"Bearer a"}})
+		require.Len(t, requests, 2)
+	})
+
+	t.Run("responses are not cached once the cache is full", func(t *testing.T) {
+		requests = nil
+		s := newService(true)
+		dsInfo, err := s.getDSInfo(context.Background(), backend.PluginContext{})
+		require.NoError(t, err)
+		for i := 0; i < maxResourceCacheItems; i++ {
+			dsInfo.resourceCache.Set(strconv.Itoa(i), []byte{}, cache.DefaultExpiration)
+		}
+
+		call(t, s, "/api/suggest?type=metrics&q=a")
+		call(t, s, "/api/suggest?type=metrics&q=a")
+		require.Len(t, requests, 2)
+	})
+}
+
+type resourceInstanceManager struct {
+	dsInfo *datasourceInfo
+}
+
+func (f resourceInstanceManager) Get(_ context.Context, _ backend.PluginContext) (instancemgmt.Instance, error) {
+	return f.dsInfo, nil
+}
+
+func (f resourceInstanceManager) Do(_ context.Context, _ backend.PluginContext, _ instancemgmt.InstanceCallbackFunc) error {
+	return nil
+}
@@ -11,12 +11,12 @@ import { isBucketAggregationWithField } from './aggregations';
 import { changeBucketAggregationField, changeBucketAggregationType } from './state/actions';
 import { bucketAggregationConfig } from './utils';
 
-const bucketAggOptions: Array<SelectableValue<BucketAggregationType>> = Object.entries(bucketAggregationConfig).map(
-  ([key, { label }]) => ({
+const bucketAggOptions: Array<SelectableValue<BucketAggregationType>> = Object.entries(bucketAggregationConfig)
+  .filter(([_, { hideFromEditor }]) => !hideFromEditor)
+  .map(([key, { label }]) => ({
     label,
     value: key as BucketAggregationType,
-  })
-);
+  }));
 
 const toOption = (bucketAgg: BucketAggregation) => ({
   label: bucketAggregationConfig[bucketAgg.type].label,
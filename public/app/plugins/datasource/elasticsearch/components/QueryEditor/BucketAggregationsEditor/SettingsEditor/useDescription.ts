@@ -61,6 +61,16 @@ export const useDescription = (bucketAgg: BucketAggregation): string => {
       return `Filter Queries (${filters!.length})`;
     }
 
+    case 'multi_terms': {
+      const fields = bucketAgg.settings?.fields || [];
+      return `Fields: ${fields.join(', ')}`;
+    }
+
+    case 'composite': {
+      const sources = bucketAgg.settings?.sources || [];
+      return `Sources: ${sources.map((source) => source.field).join(', ')}`;
+    }
+
     case 'geohash_grid': {
       const precision = parseInt(bucketAgg.settings?.precision || defaultGeoHashPrecisionString, 10);
 
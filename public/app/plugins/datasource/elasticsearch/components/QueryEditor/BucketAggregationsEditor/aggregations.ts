@@ -10,6 +10,8 @@ export const BUCKET_AGGREGATION_TYPES: BucketAggregationType[] = [
   'date_histogram',
   'histogram',
   'terms',
+  'multi_terms',
+  'composite',
   'filters',
   'geohash_grid',
   'nested',
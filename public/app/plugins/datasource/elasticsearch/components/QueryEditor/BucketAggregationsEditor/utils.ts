@@ -16,6 +16,27 @@ export const bucketAggregationConfig: BucketsConfiguration = {
       orderBy: '_term',
     },
   },
+  multi_terms: {
+    label: 'Multi Terms',
+    requiresField: false,
+    hideFromEditor: true,
+    defaultSettings: {
+      fields: [],
+      min_doc_count: '1',
+      size: '10',
+      order: 'desc',
+      orderBy: '_count',
+    },
+  },
+  composite: {
+    label: 'Composite',
+    requiresField: false,
+    hideFromEditor: true,
+    defaultSettings: {
+      sources: [],
+      size: '500',
+    },
+  },
   filters: {
     label: 'Filters',
     requiresField: false,
@@ -94,6 +94,21 @@ export const ElasticDetails = ({ value, onChange }: Props) => {
         />
       </InlineField>
 
+      <InlineField
+        label="Max rows"
+        htmlFor="es_config_maxRows"
+        labelWidth={29}
+        tooltip="Maximum number of documents returned by raw data and logs queries. Defaults to 100000."
+      >
+        <Input
+          id="es_config_maxRows"
+          value={value.jsonData.maxRows || ''}
+          onChange={jsonDataChangeHandler('maxRows', value, onChange)}
+          placeholder="100000"
+          width={24}
+        />
+      </InlineField>
+
       <InlineField
         label="Min time interval"
         htmlFor="es_config_minTimeInterval"
@@ -38,10 +38,10 @@ composableKinds: DataQuery: {
 				// List of metric aggregations
 				metrics?: [...#MetricAggregation]
 
-				#BucketAggregation: #DateHistogram | #Histogram | #Terms | #Filters | #GeoHashGrid | #Nested @cuetsy(kind="type")
+				#BucketAggregation: #DateHistogram | #Histogram | #Terms | #MultiTerms | #Composite | #Filters | #GeoHashGrid | #Nested @cuetsy(kind="type")
 				#MetricAggregation: #Count | #PipelineMetricAggregation | #MetricAggregationWithSettings     @cuetsy(kind="type")
 
-				#BucketAggregationType: "terms" | "filters" | "geohash_grid" | "date_histogram" | "histogram" | "nested" @cuetsy(kind="type")
+				#BucketAggregationType: "terms" | "multi_terms" | "composite" | "filters" | "geohash_grid" | "date_histogram" | "histogram" | "nested" @cuetsy(kind="type")
 
 				#BaseBucketAggregation: {
 					id:        string
@@ -101,6 +101,40 @@ composableKinds: DataQuery: {
 					missing?:       string
 				} @cuetsy(kind="interface")
 
+				#MultiTerms: {
+					#BaseBucketAggregation
+					type:      #BucketAggregationType & "multi_terms"
+					settings?: #MultiTermsSettings
+				} @cuetsy(kind="interface")
+
+				#MultiTermsSettings: {
+					fields?: [...string]
+					order?:         #TermsOrder
+					size?:          string
+					min_doc_count?: string
+					orderBy?:       string
+				} @cuetsy(kind="interface")
+
+				#Composite: {
+					#BaseBucketAggregation
+					type:      #BucketAggregationType & "composite"
+					settings?: #CompositeSettings
+				} @cuetsy(kind="interface")
+
+				#CompositeSettings: {
+					sources?: [...#CompositeSource]
+					size?: string
+				} @cuetsy(kind="interface")
+
+				#CompositeSourceType: "terms" | "histogram" | "date_histogram" @cuetsy(kind="type")
+
+				#CompositeSource: {
+					field:           string
+					type?:           #CompositeSourceType
+					interval?:       string
+					missing_bucket?: bool
+				} @cuetsy(kind="interface")
+
 				#Filters: {
 					#BaseBucketAggregation
 					type:      #BucketAggregationType & "filters"
@@ -59,6 +59,7 @@ export interface ElasticsearchOptions extends DataSourceJsonData {
   interval?: Interval;
   timeInterval: string;
   maxConcurrentShardRequests?: number;
+  maxRows?: number;
   logMessageField?: string;
   logLevelField?: string;
   dataLinks?: DataLinkConfig[];
@@ -91,6 +92,10 @@ interface MetricConfiguration<T extends MetricAggregationType> {
 type BucketConfiguration<T extends BucketAggregationType> = {
   label: string;
   requiresField: boolean;
+  /**
+   * Aggregations whose settings can't be edited in the query editor yet are not offered as a bucket aggregation type.
+   */
+  hideFromEditor?: boolean;
   defaultSettings: Extract<BucketAggregation, { type: T }>['settings'];
 };
 